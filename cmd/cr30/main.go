@@ -19,6 +19,7 @@ import (
 	"github.com/itohio/EasyRobot/cmd/display/destination"
 	"github.com/itohio/EasyRobot/x/devices"
 	"github.com/itohio/EasyRobot/x/devices/cr30"
+	"github.com/itohio/EasyRobot/x/devices/record"
 	"github.com/itohio/EasyRobot/x/marshaller/types"
 	"github.com/itohio/EasyRobot/x/math/colorscience"
 	"github.com/itohio/EasyRobot/x/math/mat"
@@ -41,6 +42,9 @@ var (
 	scale     = flag.Bool("scale", false, "Scale Y-axis to fit data range (default: 0 to max)")
 	bars      = flag.Bool("bars", false, "Draw spectrum as colored vertical bars (requires -display)")
 	verbose   = flag.Int("v", 0, "Verbose output level: 0=warn/error, 1=info, 2=debug (can also use -v -v or -vv)")
+	recordTo  = flag.String("record", "", "Record serial traffic to a file for later replay")
+	replayOf  = flag.String("replay", "", "Replay a recorded session instead of opening -port")
+	speed     = flag.Float64("speed", 1, "Replay speed multiplier (0 = as fast as possible)")
 )
 
 const (
@@ -84,7 +88,7 @@ func main() {
 		slog.SetLogLoggerLevel(slog.LevelDebug) // -vv or -v=2: debug and above
 	}
 
-	if *port == "" {
+	if *port == "" && *replayOf == "" {
 		slog.Error("Serial port required", "flag", "-port")
 		flag.Usage()
 		os.Exit(1)
@@ -93,17 +97,25 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	// Connect to serial port
+	// Connect to serial port (or a recorded session)
 	config := devices.DefaultSerialConfig()
 	config.BaudRate = *baud
-	ser, err := devices.NewSerialWithConfig(*port, config)
+	ser, closeSerial, err := record.OpenSerial(record.Session{
+		Port:   *port,
+		Config: config,
+		Record: *recordTo,
+		Replay: *replayOf,
+		Speed:  *speed,
+	})
 	if err != nil {
 		slog.Error("Failed to open serial port", "port", *port, "baud", *baud, "err", err)
 		os.Exit(1)
 	}
 	defer func() {
 		slog.Info("Closing serial port")
-		ser.Close()
+		if err := closeSerial(); err != nil {
+			slog.Error("Failed to close serial port", "err", err)
+		}
 	}()
 
 	// Create CR30 device
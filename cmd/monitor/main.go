@@ -12,6 +12,7 @@ import (
 	"time"
 
 	devio "github.com/itohio/EasyRobot/x/devices"
+	"github.com/itohio/EasyRobot/x/devices/record"
 	"github.com/itohio/EasyRobot/x/math/protocol/peg"
 )
 
@@ -26,6 +27,10 @@ var (
 	showText        = flag.Bool("text", false, "Show printable text alongside hex output (like hex viewers)")
 	precisionDigits = flag.Int("precision", 2, "Decimal digits when printing float/derived values")
 	maxPacketLen    = flag.Int("maxlen", 2048, "Maximum packet length in bytes (safety cap)")
+	modbusMode      = flag.Bool("modbus", false, "Decode Modbus RTU frames (CRC16 framing) instead of a pattern")
+	recordTo        = flag.String("record", "", "Record serial traffic to a file for later replay")
+	replayOf        = flag.String("replay", "", "Replay a recorded session instead of opening -port")
+	replaySpeed     = flag.Float64("speed", 1, "Replay speed multiplier (0 = as fast as possible)")
 )
 
 // PacketProcessor handles packet processing logic
@@ -110,6 +115,10 @@ func runCaptureMode(ctx context.Context, ser devio.Serial, printer *Printer) err
 		}
 		if err != nil {
 			if err == io.EOF {
+				if *replayOf != "" {
+					slog.Info("Replay finished")
+					return nil
+				}
 				slog.Debug("EOF received (continuing)")
 				time.Sleep(10 * time.Millisecond)
 				continue
@@ -139,6 +148,10 @@ func processSerialStream(ctx context.Context, ser devio.Serial, processor *Packe
 		}
 		if err != nil {
 			if err == io.EOF {
+				if *replayOf != "" {
+					slog.Info("Replay finished")
+					return nil
+				}
 				slog.Debug("EOF received (continuing)", "packets", packetCount)
 				time.Sleep(10 * time.Millisecond)
 				continue
@@ -286,10 +299,20 @@ func printFormatGuide() {
 	fmt.Println("    AA*L%%10u%%cc           -> 0xAA, skip 1, length, array of 10 uint8, CRC16")
 	fmt.Println()
 	fmt.Println("FLAGS:")
-	fmt.Println("  -crc                    : Discard packets with invalid CRC (default: warn only)")
+	fmt.Println("  -port DEVICE             : Serial port device (e.g., /dev/ttyUSB0 or COM3)")
+	fmt.Println("  -baud N                  : Serial port baud rate (default: 115200)")
+	fmt.Println("  -header PATTERN          : Packet pattern (default: AA)")
+	fmt.Println("  -crc                     : Discard packets with invalid CRC (default: warn only)")
 	fmt.Println("  -bytes-per-line N        : Number of bytes per line in hex output (default: 64)")
 	fmt.Println("  -capture                 : Capture all raw data and print as hex to stdout")
 	fmt.Println("  -text                    : Show printable text alongside hex output (like hex viewers)")
+	fmt.Println("  -precision N             : Decimal digits when printing float/derived values (default: 2)")
+	fmt.Println("  -maxlen N                : Maximum packet length in bytes (default: 2048)")
+	fmt.Println("  -modbus                  : Decode Modbus RTU requests/responses (ignores -header)")
+	fmt.Println("  -record FILE             : Record serial traffic to FILE for later replay")
+	fmt.Println("  -replay FILE             : Replay a recorded session instead of opening -port")
+	fmt.Println("  -speed X                 : Replay speed multiplier (default: 1, 0 = as fast as possible)")
+	fmt.Println("  -h                       : Print this guide")
 	fmt.Println()
 	fmt.Println("NOTES:")
 	fmt.Println("  - Exact matches (hex bytes) must always match for packet detection")
@@ -309,7 +332,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *serialPort == "" {
+	if *serialPort == "" && *replayOf == "" {
 		slog.Error("Serial port required", "flag", "--serial")
 		flag.Usage()
 		os.Exit(1)
@@ -320,14 +343,22 @@ func main() {
 
 	config := devio.DefaultSerialConfig()
 	config.BaudRate = *baudRate
-	ser, err := devio.NewSerialWithConfig(*serialPort, config)
+	ser, closeSerial, err := record.OpenSerial(record.Session{
+		Port:   *serialPort,
+		Config: config,
+		Record: *recordTo,
+		Replay: *replayOf,
+		Speed:  *replaySpeed,
+	})
 	if err != nil {
 		slog.Error("Failed to open serial port", "port", *serialPort, "baud", *baudRate, "err", err)
 		os.Exit(1)
 	}
 	defer func() {
 		slog.Info("Closing serial port", "port", *serialPort)
-		ser.Close()
+		if err := closeSerial(); err != nil {
+			slog.Error("Failed to close serial port", "err", err)
+		}
 	}()
 
 	if *captureMode {
@@ -340,6 +371,17 @@ func main() {
 		return
 	}
 
+	if *modbusMode {
+		slog.Info("Modbus RTU mode enabled", "port", *serialPort, "baud", *baudRate)
+		fmt.Println("Sniffing Modbus RTU frames (press Ctrl+C to stop)...")
+		printer := NewPrinter(NewCRCValidator(), *precisionDigits)
+		if err := processModbusStream(ctx, ser, printer); err != nil {
+			slog.Error("Modbus stream processing failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Use pattern as-is; max length can be set via $N syntax or via state.SetMaxLength()
 	pattern := *headerHex
 
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	devio "github.com/itohio/EasyRobot/x/devices"
+	"github.com/itohio/EasyRobot/x/devices/modbus"
+)
+
+// processModbusStream sniffs a Modbus RTU bus, reusing the modbus package framing
+// (function-code lengths + CRC16) instead of a PEG pattern.
+func processModbusStream(ctx context.Context, ser devio.Serial, printer *Printer) error {
+	buf := make([]byte, 1024)
+	var scanner modbus.Scanner
+	packetCount := 0
+
+	emit := func(f modbus.Frame, raw []byte, isResponse bool) {
+		packetCount++
+		fmt.Println("\n========================================")
+		kind := "request"
+		if isResponse {
+			kind = "response"
+		}
+		fmt.Printf("Modbus %s #%d (length: %d bytes) [CRC VALID]\n", kind, packetCount, len(raw))
+		printer.PrintHexCompact(raw, *bytesPerLine, *showText)
+		printModbusFrame(f, isResponse)
+		fmt.Println("========================================")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping", "reason", "context cancelled", "packets", packetCount)
+			return nil
+		default:
+		}
+
+		n, err := ser.Read(buf)
+		if n > 0 {
+			scanner.Feed(buf[:n], emit)
+		}
+		if err != nil {
+			if err == io.EOF {
+				if *replayOf != "" {
+					slog.Info("Replay finished")
+					return nil
+				}
+				slog.Debug("EOF received (continuing)", "packets", packetCount)
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			slog.Warn("Read error", "err", err, "packets", packetCount)
+		}
+	}
+}
+
+func printModbusFrame(f modbus.Frame, isResponse bool) {
+	fmt.Println("Decoded values:")
+	fmt.Printf("  slave=%d function=0x%02X (%s)\n", f.Slave, f.Function, modbusFunctionName(f.Function))
+
+	if f.IsException() {
+		if len(f.Data) > 0 {
+			fmt.Printf("  exception=0x%02X\n", f.Data[0])
+		}
+		return
+	}
+
+	switch {
+	case !isResponse && len(f.Data) >= 4:
+		fmt.Printf("  address=%d value/quantity=%d\n", binary.BigEndian.Uint16(f.Data[0:]), binary.BigEndian.Uint16(f.Data[2:]))
+		if len(f.Data) > 5 {
+			fmt.Printf("  byte_count=%d\n", f.Data[4])
+			printModbusRegisters(f.Function, f.Data[5:])
+		}
+	case isResponse && (f.Function == modbus.FuncWriteSingleCoil || f.Function == modbus.FuncWriteSingleRegister ||
+		f.Function == modbus.FuncWriteMultipleCoils || f.Function == modbus.FuncWriteMultipleRegisters):
+		if len(f.Data) >= 4 {
+			fmt.Printf("  address=%d value/quantity=%d\n", binary.BigEndian.Uint16(f.Data[0:]), binary.BigEndian.Uint16(f.Data[2:]))
+		}
+	case isResponse && len(f.Data) >= 1:
+		fmt.Printf("  byte_count=%d\n", f.Data[0])
+		printModbusRegisters(f.Function, f.Data[1:])
+	}
+}
+
+func printModbusRegisters(fn byte, data []byte) {
+	switch fn {
+	case modbus.FuncReadHoldingRegisters, modbus.FuncReadInputRegisters, modbus.FuncWriteMultipleRegisters:
+		for i := 0; i+1 < len(data); i += 2 {
+			v := binary.BigEndian.Uint16(data[i:])
+			fmt.Printf("  reg[%d]=%d (0x%04X, int16=%d)\n", i/2, v, v, modbus.Int16(v))
+		}
+	default:
+		for i, b := range data {
+			fmt.Printf("  bits[%d..%d]=%08b\n", i*8, i*8+7, b)
+		}
+	}
+}
+
+func modbusFunctionName(fn byte) string {
+	switch fn &^ 0x80 {
+	case modbus.FuncReadCoils:
+		return "read coils"
+	case modbus.FuncReadDiscreteInputs:
+		return "read discrete inputs"
+	case modbus.FuncReadHoldingRegisters:
+		return "read holding registers"
+	case modbus.FuncReadInputRegisters:
+		return "read input registers"
+	case modbus.FuncWriteSingleCoil:
+		return "write single coil"
+	case modbus.FuncWriteSingleRegister:
+		return "write single register"
+	case modbus.FuncWriteMultipleCoils:
+		return "write multiple coils"
+	case modbus.FuncWriteMultipleRegisters:
+		return "write multiple registers"
+	default:
+		return "unknown"
+	}
+}
@@ -5,12 +5,15 @@ go 1.25
 require (
 	github.com/chewxy/math32 v1.11.1
 	github.com/itohio/dndm v0.0.0
+	github.com/klauspost/compress v1.18.0
 	github.com/mattn/go-tflite v1.0.5
 	github.com/mr-tron/base58 v1.2.0
+	github.com/pierrec/lz4/v4 v4.1.8
 	github.com/rs/zerolog v1.23.0
 	github.com/stretchr/testify v1.11.1
 	github.com/vladimirvivien/go4vl v0.3.0
 	gocv.io/x/gocv v0.42.0
+	golang.org/x/crypto v0.43.0
 	golang.org/x/sys v0.37.0
 	google.golang.org/protobuf v1.36.10
 	gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,25 @@
+package concurrency
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Parallel splits [0, n) into one range per CPU and runs them concurrently.
+func Parallel(n int, fn func(lo, hi int)) {
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := range workers {
+		lo, hi := i*n/workers, (i+1)*n/workers
+		Submit(func() {
+			defer wg.Done()
+			fn(lo, hi)
+		})
+	}
+	wg.Wait()
+}
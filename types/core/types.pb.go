@@ -175,6 +175,7 @@ type Layer struct {
 	CanLearn      bool                   `protobuf:"varint,3,opt,name=can_learn,json=canLearn,proto3" json:"can_learn,omitempty"`
 	InputShape    []int32                `protobuf:"varint,4,rep,packed,name=input_shape,json=inputShape,proto3" json:"input_shape,omitempty"`
 	Parameters    map[int32]*Parameter   `protobuf:"bytes,5,rep,name=parameters,proto3" json:"parameters,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Config        map[string]string      `protobuf:"bytes,6,rep,name=config,proto3" json:"config,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Constructor arguments of the registered layer type
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -244,6 +245,13 @@ func (x *Layer) GetParameters() map[int32]*Parameter {
 	return nil
 }
 
+func (x *Layer) GetConfig() map[string]string {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
 // Model represents a neural network model
 type Model struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -252,6 +260,7 @@ type Model struct {
 	CanLearn      bool                   `protobuf:"varint,3,opt,name=can_learn,json=canLearn,proto3" json:"can_learn,omitempty"`
 	Layers        []*Layer               `protobuf:"bytes,5,rep,name=layers,proto3" json:"layers,omitempty"`
 	Parameters    map[int32]*Parameter   `protobuf:"bytes,6,rep,name=parameters,proto3" json:"parameters,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	InputShape    []int32                `protobuf:"varint,7,rep,packed,name=input_shape,json=inputShape,proto3" json:"input_shape,omitempty"` // Input shape the model was initialized with
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -321,6 +330,13 @@ func (x *Model) GetParameters() map[int32]*Parameter {
 	return nil
 }
 
+func (x *Model) GetInputShape() []int32 {
+	if x != nil {
+		return x.InputShape
+	}
+	return nil
+}
+
 // Matrix represents a 2D matrix
 type Matrix struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -516,6 +532,8 @@ func (x *Slice) GetDataBytes() []byte {
 type Value struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	Kind  string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"` // "tensor", "layer", "model", "matrix", "vector", "slice"
+	// Payload selected by kind
+	//
 	// Types that are valid to be assigned to Data:
 	//
 	//	*Value_Tensor
@@ -692,7 +710,7 @@ var file_types_core_types_proto_rawDesc = string([]byte{
 	0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x54, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x52, 0x04, 0x67,
 	0x72, 0x61, 0x64, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x73, 0x5f,
 	0x67, 0x72, 0x61, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x72, 0x65, 0x71, 0x75,
-	0x69, 0x72, 0x65, 0x73, 0x47, 0x72, 0x61, 0x64, 0x22, 0x9a, 0x02, 0x0a, 0x05, 0x4c, 0x61, 0x79,
+	0x69, 0x72, 0x65, 0x73, 0x47, 0x72, 0x61, 0x64, 0x22, 0x96, 0x03, 0x0a, 0x05, 0x4c, 0x61, 0x79,
 	0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
 	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x61,
@@ -704,84 +722,94 @@ var file_types_core_types_proto_rawDesc = string([]byte{
 	0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79,
 	0x70, 0x65, 0x73, 0x2e, 0x4c, 0x61, 0x79, 0x65, 0x72, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65,
 	0x74, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x70, 0x61, 0x72, 0x61, 0x6d,
-	0x65, 0x74, 0x65, 0x72, 0x73, 0x1a, 0x5e, 0x0a, 0x0f, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74,
-	0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x35, 0x0a, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x65, 0x61, 0x73, 0x79,
-	0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73,
-	0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
-	0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xae, 0x02, 0x0a, 0x05, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12,
-	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
-	0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x61, 0x6e, 0x5f, 0x6c,
-	0x65, 0x61, 0x72, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x63, 0x61, 0x6e, 0x4c,
-	0x65, 0x61, 0x72, 0x6e, 0x12, 0x33, 0x0a, 0x06, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x73, 0x18, 0x05,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74,
-	0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x4c, 0x61, 0x79, 0x65,
-	0x72, 0x52, 0x06, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x73, 0x12, 0x4b, 0x0a, 0x0a, 0x70, 0x61, 0x72,
-	0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2b, 0x2e,
-	0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74,
-	0x79, 0x70, 0x65, 0x73, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d,
-	0x65, 0x74, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x70, 0x61, 0x72, 0x61,
-	0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x1a, 0x5e, 0x0a, 0x0f, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65,
+	0x65, 0x74, 0x65, 0x72, 0x73, 0x12, 0x3f, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18,
+	0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f,
+	0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x4c, 0x61, 0x79,
+	0x65, 0x72, 0x2e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x1a, 0x5e, 0x0a, 0x0f, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65,
 	0x74, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
 	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x35, 0x0a, 0x05, 0x76,
 	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x65, 0x61, 0x73,
 	0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65,
 	0x73, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x52, 0x05, 0x76, 0x61, 0x6c,
-	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x44, 0x0a, 0x06, 0x4d, 0x61, 0x74, 0x72, 0x69, 0x78,
-	0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04,
-	0x72, 0x6f, 0x77, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x6c, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61,
-	0x18, 0x03, 0x20, 0x03, 0x28, 0x01, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x1c, 0x0a, 0x06,
-	0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x01, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0xa6, 0x01, 0x0a, 0x05, 0x53,
-	0x6c, 0x69, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61,
-	0x5f, 0x66, 0x33, 0x32, 0x18, 0x03, 0x20, 0x03, 0x28, 0x02, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61,
-	0x46, 0x33, 0x32, 0x12, 0x19, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x66, 0x36, 0x34, 0x18,
-	0x04, 0x20, 0x03, 0x28, 0x01, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x46, 0x36, 0x34, 0x12, 0x19,
-	0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x69, 0x33, 0x32, 0x18, 0x05, 0x20, 0x03, 0x28, 0x05,
-	0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x49, 0x33, 0x32, 0x12, 0x19, 0x0a, 0x08, 0x64, 0x61, 0x74,
-	0x61, 0x5f, 0x69, 0x36, 0x34, 0x18, 0x06, 0x20, 0x03, 0x28, 0x03, 0x52, 0x07, 0x64, 0x61, 0x74,
-	0x61, 0x49, 0x36, 0x34, 0x12, 0x1d, 0x0a, 0x0a, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x62, 0x79, 0x74,
-	0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x64, 0x61, 0x74, 0x61, 0x42, 0x79,
-	0x74, 0x65, 0x73, 0x22, 0xea, 0x02, 0x0a, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x12, 0x0a,
-	0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6b, 0x69, 0x6e,
-	0x64, 0x12, 0x36, 0x0a, 0x06, 0x74, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x1c, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f,
-	0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x54, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x48,
-	0x00, 0x52, 0x06, 0x74, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x12, 0x33, 0x0a, 0x05, 0x6c, 0x61, 0x79,
-	0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x39, 0x0a, 0x0b, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0xcf, 0x02, 0x0a, 0x05, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x61, 0x6e, 0x5f, 0x6c, 0x65, 0x61, 0x72, 0x6e,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x63, 0x61, 0x6e, 0x4c, 0x65, 0x61, 0x72, 0x6e,
+	0x12, 0x33, 0x0a, 0x06, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x1b, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72,
+	0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x4c, 0x61, 0x79, 0x65, 0x72, 0x52, 0x06, 0x6c,
+	0x61, 0x79, 0x65, 0x72, 0x73, 0x12, 0x4b, 0x0a, 0x0a, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74,
+	0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2b, 0x2e, 0x65, 0x61, 0x73, 0x79,
+	0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73,
+	0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65,
+	0x72, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x5f, 0x73, 0x68, 0x61, 0x70,
+	0x65, 0x18, 0x07, 0x20, 0x03, 0x28, 0x05, 0x52, 0x0a, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x53, 0x68,
+	0x61, 0x70, 0x65, 0x1a, 0x5e, 0x0a, 0x0f, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x35, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f,
+	0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x50,
+	0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x22, 0x44, 0x0a, 0x06, 0x4d, 0x61, 0x74, 0x72, 0x69, 0x78, 0x12, 0x12, 0x0a,
+	0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x72, 0x6f, 0x77,
+	0x73, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x04, 0x63, 0x6f, 0x6c, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x01, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x1c, 0x0a, 0x06, 0x56, 0x65, 0x63,
+	0x74, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x01, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0xa6, 0x01, 0x0a, 0x05, 0x53, 0x6c, 0x69, 0x63,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x66, 0x33,
+	0x32, 0x18, 0x03, 0x20, 0x03, 0x28, 0x02, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x46, 0x33, 0x32,
+	0x12, 0x19, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x66, 0x36, 0x34, 0x18, 0x04, 0x20, 0x03,
+	0x28, 0x01, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x46, 0x36, 0x34, 0x12, 0x19, 0x0a, 0x08, 0x64,
+	0x61, 0x74, 0x61, 0x5f, 0x69, 0x33, 0x32, 0x18, 0x05, 0x20, 0x03, 0x28, 0x05, 0x52, 0x07, 0x64,
+	0x61, 0x74, 0x61, 0x49, 0x33, 0x32, 0x12, 0x19, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x69,
+	0x36, 0x34, 0x18, 0x06, 0x20, 0x03, 0x28, 0x03, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x49, 0x36,
+	0x34, 0x12, 0x1d, 0x0a, 0x0a, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x64, 0x61, 0x74, 0x61, 0x42, 0x79, 0x74, 0x65, 0x73,
+	0x22, 0xea, 0x02, 0x0a, 0x05, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x69,
+	0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x36,
+	0x0a, 0x06, 0x74, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c,
+	0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e,
+	0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x54, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x48, 0x00, 0x52, 0x06,
+	0x74, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x12, 0x33, 0x0a, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f,
+	0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x4c, 0x61, 0x79,
+	0x65, 0x72, 0x48, 0x00, 0x52, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x12, 0x33, 0x0a, 0x05, 0x6d,
+	0x6f, 0x64, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x65, 0x61, 0x73,
+	0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65,
+	0x73, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x48, 0x00, 0x52, 0x05, 0x6d, 0x6f, 0x64, 0x65, 0x6c,
+	0x12, 0x36, 0x0a, 0x06, 0x6d, 0x61, 0x74, 0x72, 0x69, 0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1c, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72,
+	0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x4d, 0x61, 0x74, 0x72, 0x69, 0x78, 0x48, 0x00,
+	0x52, 0x06, 0x6d, 0x61, 0x74, 0x72, 0x69, 0x78, 0x12, 0x36, 0x0a, 0x06, 0x76, 0x65, 0x63, 0x74,
+	0x6f, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72,
 	0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e,
-	0x4c, 0x61, 0x79, 0x65, 0x72, 0x48, 0x00, 0x52, 0x05, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x12, 0x33,
-	0x0a, 0x05, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e,
-	0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74,
-	0x79, 0x70, 0x65, 0x73, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x48, 0x00, 0x52, 0x05, 0x6d, 0x6f,
-	0x64, 0x65, 0x6c, 0x12, 0x36, 0x0a, 0x06, 0x6d, 0x61, 0x74, 0x72, 0x69, 0x78, 0x18, 0x05, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e,
-	0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x4d, 0x61, 0x74, 0x72, 0x69,
-	0x78, 0x48, 0x00, 0x52, 0x06, 0x6d, 0x61, 0x74, 0x72, 0x69, 0x78, 0x12, 0x36, 0x0a, 0x06, 0x76,
-	0x65, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x65, 0x61,
-	0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70,
-	0x65, 0x73, 0x2e, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x48, 0x00, 0x52, 0x06, 0x76, 0x65, 0x63,
-	0x74, 0x6f, 0x72, 0x12, 0x33, 0x0a, 0x05, 0x73, 0x6c, 0x69, 0x63, 0x65, 0x18, 0x07, 0x20, 0x01,
-	0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63,
-	0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x53, 0x6c, 0x69, 0x63, 0x65, 0x48,
-	0x00, 0x52, 0x05, 0x73, 0x6c, 0x69, 0x63, 0x65, 0x42, 0x06, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61,
-	0x42, 0xc0, 0x01, 0x0a, 0x18, 0x63, 0x6f, 0x6d, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62,
-	0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x42, 0x0a, 0x54,
-	0x79, 0x70, 0x65, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x26, 0x67, 0x69, 0x74,
-	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x69, 0x74, 0x6f, 0x68, 0x69, 0x6f, 0x2f, 0x45,
-	0x61, 0x73, 0x79, 0x52, 0x6f, 0x62, 0x6f, 0x74, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2f, 0x63,
-	0x6f, 0x72, 0x65, 0xa2, 0x02, 0x03, 0x45, 0x43, 0x54, 0xaa, 0x02, 0x14, 0x45, 0x61, 0x73, 0x79,
-	0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x43, 0x6f, 0x72, 0x65, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x73,
-	0xca, 0x02, 0x14, 0x45, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x5c, 0x43, 0x6f, 0x72,
-	0x65, 0x5c, 0x54, 0x79, 0x70, 0x65, 0x73, 0xe2, 0x02, 0x20, 0x45, 0x61, 0x73, 0x79, 0x72, 0x6f,
-	0x62, 0x6f, 0x74, 0x5c, 0x43, 0x6f, 0x72, 0x65, 0x5c, 0x54, 0x79, 0x70, 0x65, 0x73, 0x5c, 0x47,
-	0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x16, 0x45, 0x61, 0x73,
-	0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x3a, 0x3a, 0x43, 0x6f, 0x72, 0x65, 0x3a, 0x3a, 0x54, 0x79,
-	0x70, 0x65, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x48, 0x00, 0x52, 0x06, 0x76, 0x65, 0x63, 0x74, 0x6f, 0x72,
+	0x12, 0x33, 0x0a, 0x05, 0x73, 0x6c, 0x69, 0x63, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1b, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e, 0x63, 0x6f, 0x72, 0x65,
+	0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x53, 0x6c, 0x69, 0x63, 0x65, 0x48, 0x00, 0x52, 0x05,
+	0x73, 0x6c, 0x69, 0x63, 0x65, 0x42, 0x06, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x42, 0xc0, 0x01,
+	0x0a, 0x18, 0x63, 0x6f, 0x6d, 0x2e, 0x65, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x2e,
+	0x63, 0x6f, 0x72, 0x65, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x42, 0x0a, 0x54, 0x79, 0x70, 0x65,
+	0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x26, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x69, 0x74, 0x6f, 0x68, 0x69, 0x6f, 0x2f, 0x45, 0x61, 0x73, 0x79,
+	0x52, 0x6f, 0x62, 0x6f, 0x74, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2f, 0x63, 0x6f, 0x72, 0x65,
+	0xa2, 0x02, 0x03, 0x45, 0x43, 0x54, 0xaa, 0x02, 0x14, 0x45, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62,
+	0x6f, 0x74, 0x2e, 0x43, 0x6f, 0x72, 0x65, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x73, 0xca, 0x02, 0x14,
+	0x45, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74, 0x5c, 0x43, 0x6f, 0x72, 0x65, 0x5c, 0x54,
+	0x79, 0x70, 0x65, 0x73, 0xe2, 0x02, 0x20, 0x45, 0x61, 0x73, 0x79, 0x72, 0x6f, 0x62, 0x6f, 0x74,
+	0x5c, 0x43, 0x6f, 0x72, 0x65, 0x5c, 0x54, 0x79, 0x70, 0x65, 0x73, 0x5c, 0x47, 0x50, 0x42, 0x4d,
+	0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x16, 0x45, 0x61, 0x73, 0x79, 0x72, 0x6f,
+	0x62, 0x6f, 0x74, 0x3a, 0x3a, 0x43, 0x6f, 0x72, 0x65, 0x3a, 0x3a, 0x54, 0x79, 0x70, 0x65, 0x73,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 })
 
 var (
@@ -796,7 +824,7 @@ func file_types_core_types_proto_rawDescGZIP() []byte {
 	return file_types_core_types_proto_rawDescData
 }
 
-var file_types_core_types_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_types_core_types_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_types_core_types_proto_goTypes = []any{
 	(*Tensor)(nil),    // 0: easyrobot.core.types.Tensor
 	(*Parameter)(nil), // 1: easyrobot.core.types.Parameter
@@ -807,27 +835,29 @@ var file_types_core_types_proto_goTypes = []any{
 	(*Slice)(nil),     // 6: easyrobot.core.types.Slice
 	(*Value)(nil),     // 7: easyrobot.core.types.Value
 	nil,               // 8: easyrobot.core.types.Layer.ParametersEntry
-	nil,               // 9: easyrobot.core.types.Model.ParametersEntry
+	nil,               // 9: easyrobot.core.types.Layer.ConfigEntry
+	nil,               // 10: easyrobot.core.types.Model.ParametersEntry
 }
 var file_types_core_types_proto_depIdxs = []int32{
 	0,  // 0: easyrobot.core.types.Parameter.data:type_name -> easyrobot.core.types.Tensor
 	0,  // 1: easyrobot.core.types.Parameter.grad:type_name -> easyrobot.core.types.Tensor
 	8,  // 2: easyrobot.core.types.Layer.parameters:type_name -> easyrobot.core.types.Layer.ParametersEntry
-	2,  // 3: easyrobot.core.types.Model.layers:type_name -> easyrobot.core.types.Layer
-	9,  // 4: easyrobot.core.types.Model.parameters:type_name -> easyrobot.core.types.Model.ParametersEntry
-	0,  // 5: easyrobot.core.types.Value.tensor:type_name -> easyrobot.core.types.Tensor
-	2,  // 6: easyrobot.core.types.Value.layer:type_name -> easyrobot.core.types.Layer
-	3,  // 7: easyrobot.core.types.Value.model:type_name -> easyrobot.core.types.Model
-	4,  // 8: easyrobot.core.types.Value.matrix:type_name -> easyrobot.core.types.Matrix
-	5,  // 9: easyrobot.core.types.Value.vector:type_name -> easyrobot.core.types.Vector
-	6,  // 10: easyrobot.core.types.Value.slice:type_name -> easyrobot.core.types.Slice
-	1,  // 11: easyrobot.core.types.Layer.ParametersEntry.value:type_name -> easyrobot.core.types.Parameter
-	1,  // 12: easyrobot.core.types.Model.ParametersEntry.value:type_name -> easyrobot.core.types.Parameter
-	13, // [13:13] is the sub-list for method output_type
-	13, // [13:13] is the sub-list for method input_type
-	13, // [13:13] is the sub-list for extension type_name
-	13, // [13:13] is the sub-list for extension extendee
-	0,  // [0:13] is the sub-list for field type_name
+	9,  // 3: easyrobot.core.types.Layer.config:type_name -> easyrobot.core.types.Layer.ConfigEntry
+	2,  // 4: easyrobot.core.types.Model.layers:type_name -> easyrobot.core.types.Layer
+	10, // 5: easyrobot.core.types.Model.parameters:type_name -> easyrobot.core.types.Model.ParametersEntry
+	0,  // 6: easyrobot.core.types.Value.tensor:type_name -> easyrobot.core.types.Tensor
+	2,  // 7: easyrobot.core.types.Value.layer:type_name -> easyrobot.core.types.Layer
+	3,  // 8: easyrobot.core.types.Value.model:type_name -> easyrobot.core.types.Model
+	4,  // 9: easyrobot.core.types.Value.matrix:type_name -> easyrobot.core.types.Matrix
+	5,  // 10: easyrobot.core.types.Value.vector:type_name -> easyrobot.core.types.Vector
+	6,  // 11: easyrobot.core.types.Value.slice:type_name -> easyrobot.core.types.Slice
+	1,  // 12: easyrobot.core.types.Layer.ParametersEntry.value:type_name -> easyrobot.core.types.Parameter
+	1,  // 13: easyrobot.core.types.Model.ParametersEntry.value:type_name -> easyrobot.core.types.Parameter
+	14, // [14:14] is the sub-list for method output_type
+	14, // [14:14] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_types_core_types_proto_init() }
@@ -849,7 +879,7 @@ func file_types_core_types_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_types_core_types_proto_rawDesc), len(file_types_core_types_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
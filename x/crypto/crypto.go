@@ -11,7 +11,11 @@ import (
 type PrivKey ed25519.PrivateKey
 type PubKey ed25519.PublicKey
 
-const PublicKeySize = ed25519.PublicKeySize
+const (
+	PublicKeySize  = ed25519.PublicKeySize
+	PrivateKeySize = ed25519.PrivateKeySize
+	SignatureSize  = ed25519.SignatureSize
+)
 
 func New() (PubKey, PrivKey) {
 	pub, priv, _ := FromSeed(rand.Reader)
@@ -23,13 +27,7 @@ func FromSeed(src io.Reader) (PubKey, PrivKey, error) {
 	if err != nil {
 		return PubKey{}, PrivKey{}, err
 	}
-
-	var pub PubKey
-	var priv PrivKey
-	copy(pub[:], pb)
-	copy(priv[:], pr)
-
-	return pub, priv, nil
+	return PubKey(pb), PrivKey(pr), nil
 }
 
 func FromReader(src io.Reader) (PubKey, PrivKey, error) {
@@ -46,6 +44,21 @@ func FromReader(src io.Reader) (PubKey, PrivKey, error) {
 	return pub, priv, nil
 }
 
+// Public returns the public key of k.
+func (k PrivKey) Public() PubKey {
+	return PubKey(ed25519.PrivateKey(k).Public().(ed25519.PublicKey))
+}
+
+// Sign signs msg with k, which must be a full Ed25519 private key.
+func (k PrivKey) Sign(msg []byte) []byte {
+	return ed25519.Sign(ed25519.PrivateKey(k), msg)
+}
+
+// Verify reports whether sig is a valid signature of msg by k.
+func (k PubKey) Verify(msg, sig []byte) bool {
+	return len(k) == PublicKeySize && ed25519.Verify(ed25519.PublicKey(k), msg, sig)
+}
+
 func (k PrivKey) String() string {
 	return b58.Encode([]byte(k))
 }
@@ -0,0 +1,25 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	pub, priv := New()
+	if len(pub) != PublicKeySize || !bytes.Equal(priv.Public(), pub) {
+		t.Fatalf("New returned a %d byte public key not matching the private key", len(pub))
+	}
+	msg := []byte("calibration")
+	sig := priv.Sign(msg)
+	if len(sig) != SignatureSize || !pub.Verify(msg, sig) {
+		t.Fatal("signature does not verify")
+	}
+	msg[0] ^= 1
+	if pub.Verify(msg, sig) {
+		t.Fatal("signature of a modified message verifies")
+	}
+	if PubKey(nil).Verify(msg, sig) {
+		t.Fatal("empty key verifies")
+	}
+}
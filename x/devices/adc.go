@@ -0,0 +1,8 @@
+package devices
+
+// ADC represents an analog input channel. It is implemented by machine.ADC in TinyGo.
+// Configuration is done by concrete implementations, not through this interface.
+type ADC interface {
+	// Get returns the current reading scaled to the full 16-bit range (0..65535).
+	Get() uint16
+}
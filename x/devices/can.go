@@ -0,0 +1,70 @@
+package devices
+
+// CAN identifier flags and masks (compatible with Linux SocketCAN can_id layout).
+const (
+	// CANExtendedFlag marks a 29-bit extended frame identifier.
+	CANExtendedFlag uint32 = 0x80000000
+	// CANRemoteFlag marks a remote transmission request frame.
+	CANRemoteFlag uint32 = 0x40000000
+	// CANErrorFlag marks an error frame.
+	CANErrorFlag uint32 = 0x20000000
+
+	// CANStandardMask masks a standard 11-bit identifier.
+	CANStandardMask uint32 = 0x000007FF
+	// CANExtendedMask masks an extended 29-bit identifier.
+	CANExtendedMask uint32 = 0x1FFFFFFF
+)
+
+// CANFrame represents a classic CAN 2.0 frame with up to 8 data bytes.
+type CANFrame struct {
+	// ID is the 11-bit or 29-bit frame identifier (without flags).
+	ID uint32
+
+	// Extended indicates a 29-bit identifier.
+	Extended bool
+
+	// Remote indicates a remote transmission request (no data).
+	Remote bool
+
+	// Len is the number of valid bytes in Data (0..8).
+	Len uint8
+
+	// Data holds the frame payload.
+	Data [8]byte
+}
+
+// NewCANFrame creates a standard data frame with the given identifier and payload.
+// Payloads longer than 8 bytes are truncated.
+func NewCANFrame(id uint32, data []byte) CANFrame {
+	f := CANFrame{ID: id & CANExtendedMask, Extended: id > CANStandardMask}
+	f.Len = uint8(copy(f.Data[:], data))
+	return f
+}
+
+// Payload returns the valid portion of Data.
+func (f *CANFrame) Payload() []byte {
+	n := f.Len
+	if n > 8 {
+		n = 8
+	}
+	return f.Data[:n]
+}
+
+// CANFilter accepts frames whose identifier matches ID under Mask.
+// A frame is accepted when (frame.ID & Mask) == (ID & Mask).
+type CANFilter struct {
+	ID   uint32
+	Mask uint32
+}
+
+// CAN represents a CAN bus interface.
+// It can be implemented by Linux SocketCAN, SPI controllers such as MCP2515,
+// or USB-to-CAN adapters.
+type CAN interface {
+	// WriteFrame transmits a single frame.
+	WriteFrame(f CANFrame) error
+
+	// ReadFrame blocks until a frame is received and stores it in f.
+	// Implementations return ErrTimeout if a configured read timeout expires.
+	ReadFrame(f *CANFrame) error
+}
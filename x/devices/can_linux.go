@@ -0,0 +1,209 @@
+//go:build !tinygo && linux
+
+package devices
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// canFrameSize is sizeof(struct can_frame) on Linux.
+const canFrameSize = 16
+
+// CANConfig holds configuration for SocketCAN interfaces.
+type CANConfig struct {
+	// ReadTimeout limits how long ReadFrame blocks. Zero blocks forever.
+	ReadTimeout time.Duration
+
+	// Filters restricts received frames. Empty accepts all frames.
+	Filters []CANFilter
+
+	// Loopback enables reception of frames sent by other sockets on the same host.
+	// Default: true (kernel default).
+	Loopback bool
+}
+
+// DefaultCANConfig returns a default CAN configuration.
+func DefaultCANConfig() CANConfig {
+	return CANConfig{
+		ReadTimeout: 0,
+		Loopback:    true,
+	}
+}
+
+// LinuxCAN implements CAN interface using Linux SocketCAN raw sockets.
+type LinuxCAN struct {
+	fd     int
+	iface  string
+	config CANConfig
+}
+
+// Ensure LinuxCAN implements CAN interface
+var _ CAN = (*LinuxCAN)(nil)
+
+// NewCAN opens a SocketCAN interface by name.
+// Common interfaces:
+//   - can0, can1 - Hardware CAN controllers (e.g. MCP2515 HAT, USB2CAN)
+//   - vcan0 - Virtual CAN for testing without hardware:
+//     ip link add dev vcan0 type vcan && ip link set up vcan0
+func NewCAN(iface string) (*LinuxCAN, error) {
+	return NewCANWithConfig(iface, DefaultCANConfig())
+}
+
+// NewCANWithConfig opens a SocketCAN interface with custom configuration.
+func NewCANWithConfig(iface string, config CANConfig) (*LinuxCAN, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find CAN interface %s: %w", iface, err)
+	}
+
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW, unix.CAN_RAW)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CAN socket: %w", err)
+	}
+
+	c := &LinuxCAN{fd: fd, iface: iface, config: config}
+
+	if !config.Loopback {
+		if err := unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_LOOPBACK, 0); err != nil {
+			unix.Close(fd)
+			return nil, fmt.Errorf("failed to disable CAN loopback: %w", err)
+		}
+	}
+
+	if len(config.Filters) > 0 {
+		if err := c.SetFilters(config.Filters); err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+	}
+
+	if config.ReadTimeout > 0 {
+		if err := c.SetReadTimeout(config.ReadTimeout); err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to bind CAN socket to %s: %w", iface, err)
+	}
+
+	return c, nil
+}
+
+// SetFilters replaces the kernel receive filters. Empty filters accept all frames.
+func (c *LinuxCAN) SetFilters(filters []CANFilter) error {
+	kf := make([]unix.CanFilter, len(filters))
+	for i, f := range filters {
+		kf[i] = unix.CanFilter{Id: f.ID, Mask: f.Mask}
+	}
+	if len(kf) == 0 {
+		kf = []unix.CanFilter{{Id: 0, Mask: 0}}
+	}
+	if err := unix.SetsockoptCanRawFilter(c.fd, unix.SOL_CAN_RAW, unix.CAN_RAW_FILTER, kf); err != nil {
+		return fmt.Errorf("failed to set CAN filters: %w", err)
+	}
+	c.config.Filters = filters
+	return nil
+}
+
+// SetReadTimeout sets the ReadFrame timeout. Zero blocks forever.
+func (c *LinuxCAN) SetReadTimeout(d time.Duration) error {
+	tv := unix.NsecToTimeval(d.Nanoseconds())
+	if err := unix.SetsockoptTimeval(c.fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return fmt.Errorf("failed to set CAN read timeout: %w", err)
+	}
+	c.config.ReadTimeout = d
+	return nil
+}
+
+// WriteFrame transmits a single frame.
+func (c *LinuxCAN) WriteFrame(f CANFrame) error {
+	var buf [canFrameSize]byte
+	encodeCANFrame(buf[:], f)
+	n, err := unix.Write(c.fd, buf[:])
+	if err != nil {
+		return fmt.Errorf("CAN write failed: %w", err)
+	}
+	if n != canFrameSize {
+		return fmt.Errorf("CAN write incomplete: wrote %d of %d bytes", n, canFrameSize)
+	}
+	return nil
+}
+
+// ReadFrame blocks until a frame is received. Returns ErrTimeout if the read timeout expires.
+func (c *LinuxCAN) ReadFrame(f *CANFrame) error {
+	var buf [canFrameSize]byte
+	for {
+		n, err := unix.Read(c.fd, buf[:])
+		if err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EWOULDBLOCK) {
+				return ErrTimeout
+			}
+			return fmt.Errorf("CAN read failed: %w", err)
+		}
+		if n != canFrameSize {
+			return fmt.Errorf("CAN read incomplete: read %d of %d bytes", n, canFrameSize)
+		}
+		break
+	}
+	decodeCANFrame(buf[:], f)
+	return nil
+}
+
+// Close closes the CAN socket.
+func (c *LinuxCAN) Close() error {
+	return unix.Close(c.fd)
+}
+
+// Interface returns the network interface name.
+func (c *LinuxCAN) Interface() string {
+	return c.iface
+}
+
+// encodeCANFrame serializes f into struct can_frame layout.
+func encodeCANFrame(buf []byte, f CANFrame) {
+	id := f.ID
+	if f.Extended {
+		id = (id & CANExtendedMask) | CANExtendedFlag
+	} else {
+		id &= CANStandardMask
+	}
+	if f.Remote {
+		id |= CANRemoteFlag
+	}
+	n := f.Len
+	if n > 8 {
+		n = 8
+	}
+	binary.NativeEndian.PutUint32(buf[0:4], id)
+	buf[4] = n
+	copy(buf[8:16], f.Data[:])
+}
+
+// decodeCANFrame deserializes struct can_frame layout into f.
+func decodeCANFrame(buf []byte, f *CANFrame) {
+	id := binary.NativeEndian.Uint32(buf[0:4])
+	f.Extended = id&CANExtendedFlag != 0
+	f.Remote = id&CANRemoteFlag != 0
+	if f.Extended {
+		f.ID = id & CANExtendedMask
+	} else {
+		f.ID = id & CANStandardMask
+	}
+	f.Len = buf[4]
+	if f.Len > 8 {
+		f.Len = 8
+	}
+	copy(f.Data[:], buf[8:16])
+}
@@ -0,0 +1,57 @@
+//go:build !tinygo && linux
+
+package devices
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCANFrameCodec(t *testing.T) {
+	in := CANFrame{ID: 0x1ABCDEF, Extended: true, Len: 3, Data: [8]byte{1, 2, 3}}
+	var buf [canFrameSize]byte
+	encodeCANFrame(buf[:], in)
+
+	var out CANFrame
+	decodeCANFrame(buf[:], &out)
+	if out != in {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", out, in)
+	}
+}
+
+// TestCANVirtualLoopback requires a virtual CAN interface:
+//
+//	ip link add dev vcan0 type vcan && ip link set up vcan0
+func TestCANVirtualLoopback(t *testing.T) {
+	if _, err := net.InterfaceByName("vcan0"); err != nil {
+		t.Skip("vcan0 not available")
+	}
+
+	cfg := DefaultCANConfig()
+	cfg.ReadTimeout = time.Second
+	rx, err := NewCANWithConfig("vcan0", cfg)
+	if err != nil {
+		t.Skipf("cannot open vcan0: %v", err)
+	}
+	defer rx.Close()
+
+	tx, err := NewCAN("vcan0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Close()
+
+	want := NewCANFrame(0x123, []byte{0xDE, 0xAD, 0xBE, 0xEF})
+	if err := tx.WriteFrame(want); err != nil {
+		t.Fatal(err)
+	}
+
+	var got CANFrame
+	if err := rx.ReadFrame(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !tinygo && !linux
+
+package devices
+
+import "errors"
+
+// StubCAN is a stub implementation that returns errors.
+// This allows code to compile on platforms without SocketCAN support.
+type StubCAN struct{}
+
+// NewCAN creates a stub CAN bus that always returns errors.
+func NewCAN(iface string) (*StubCAN, error) {
+	return &StubCAN{}, errors.New("CAN not supported on this platform")
+}
+
+// WriteFrame always returns an error.
+func (c *StubCAN) WriteFrame(f CANFrame) error {
+	return errors.New("CAN not supported on this platform")
+}
+
+// ReadFrame always returns an error.
+func (c *StubCAN) ReadFrame(f *CANFrame) error {
+	return errors.New("CAN not supported on this platform")
+}
+
+// Close does nothing.
+func (c *StubCAN) Close() error {
+	return nil
+}
@@ -0,0 +1,5 @@
+//go:build tinygo
+
+package devices
+
+// Use device-specific CAN directly (e.g. MCP2515 over SPI)
@@ -0,0 +1,224 @@
+package canopen
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// fakeBus emulates a single CANopen SDO server with an object dictionary.
+type fakeBus struct {
+	node    uint8
+	rx      chan devices.CANFrame
+	sent    chan devices.CANFrame
+	objects map[uint32][]byte
+
+	// segmented transfer state
+	upload   []byte
+	download []byte
+	mux      uint32
+}
+
+func newFakeBus(node uint8) *fakeBus {
+	return &fakeBus{
+		node:    node,
+		rx:      make(chan devices.CANFrame, 16),
+		sent:    make(chan devices.CANFrame, 16),
+		objects: make(map[uint32][]byte),
+	}
+}
+
+func key(index uint16, sub uint8) uint32 { return uint32(index)<<8 | uint32(sub) }
+
+func (b *fakeBus) ReadFrame(f *devices.CANFrame) error {
+	select {
+	case *f = <-b.rx:
+		return nil
+	case <-time.After(10 * time.Millisecond):
+		return devices.ErrTimeout
+	}
+}
+
+func (b *fakeBus) WriteFrame(f devices.CANFrame) error {
+	if f.ID != cobSDORx+uint32(b.node) {
+		b.sent <- f
+		return nil
+	}
+	req := f.Data
+	var resp [8]byte
+	switch req[0] >> 5 {
+	case sdoCCSUploadInitiate:
+		b.mux = key(binary.LittleEndian.Uint16(req[1:3]), req[3])
+		obj, ok := b.objects[b.mux]
+		copy(resp[1:4], req[1:4])
+		if !ok {
+			resp[0] = sdoCSAbort << 5
+			binary.LittleEndian.PutUint32(resp[4:], AbortNoObject)
+		} else if len(obj) <= 4 {
+			resp[0] = sdoSCSUploadInitiate<<5 | byte(4-len(obj))<<2 | 0x03
+			copy(resp[4:], obj)
+		} else {
+			resp[0] = sdoSCSUploadInitiate<<5 | 0x01
+			binary.LittleEndian.PutUint32(resp[4:], uint32(len(obj)))
+			b.upload = obj
+		}
+	case sdoCCSUploadSegment:
+		toggle := (req[0] >> 4) & 1
+		n := copy(resp[1:], b.upload)
+		b.upload = b.upload[n:]
+		resp[0] = toggle<<4 | byte(7-n)<<1
+		if len(b.upload) == 0 {
+			resp[0] |= 0x01
+		}
+	case sdoCCSDownloadInitiate:
+		b.mux = key(binary.LittleEndian.Uint16(req[1:3]), req[3])
+		copy(resp[1:4], req[1:4])
+		resp[0] = sdoSCSDownloadInitiate << 5
+		if req[0]&0x02 != 0 {
+			size := 4 - int((req[0]>>2)&0x03)
+			b.objects[b.mux] = append([]byte(nil), req[4:4+size]...)
+		} else {
+			b.download = b.download[:0]
+		}
+	case sdoCCSDownloadSegment:
+		toggle := (req[0] >> 4) & 1
+		size := 7 - int((req[0]>>1)&0x07)
+		b.download = append(b.download, req[1:1+size]...)
+		if req[0]&0x01 != 0 {
+			b.objects[b.mux] = append([]byte(nil), b.download...)
+		}
+		resp[0] = sdoSCSDownloadSegment<<5 | toggle<<4
+	default:
+		return nil
+	}
+	b.rx <- devices.NewCANFrame(cobSDOTx+uint32(b.node), resp[:])
+	return nil
+}
+
+func newTestNetwork(t *testing.T, bus *fakeBus) *Network {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	n := New(ctx, bus)
+	n.Start()
+	return n
+}
+
+func TestSDOExpedited(t *testing.T) {
+	bus := newFakeBus(5)
+	net := newTestNetwork(t, bus)
+	node := net.Node(5)
+	defer node.Close()
+
+	if err := node.WriteUint32(0x6081, 0, 1234); err != nil {
+		t.Fatal(err)
+	}
+	v, err := node.ReadUint32(0x6081, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 1234 {
+		t.Fatalf("got %d, want 1234", v)
+	}
+
+	if err := node.WriteUint8(0x6060, 0, 3); err != nil {
+		t.Fatal(err)
+	}
+	b, err := node.ReadUint8(0x6060, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != 3 {
+		t.Fatalf("got %d, want 3", b)
+	}
+}
+
+func TestSDOSegmented(t *testing.T) {
+	bus := newFakeBus(7)
+	net := newTestNetwork(t, bus)
+	node := net.Node(7)
+	defer node.Close()
+
+	want := []byte("EasyRobot CANopen drive")
+	if err := node.Write(0x1008, 0, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := node.Read(0x1008, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSDOAbort(t *testing.T) {
+	bus := newFakeBus(3)
+	net := newTestNetwork(t, bus)
+	node := net.Node(3)
+	defer node.Close()
+
+	_, err := node.Read(0x2000, 1)
+	var abort *SDOAbortError
+	if !errors.As(err, &abort) {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+	if abort.Code != AbortNoObject || abort.Index != 0x2000 || abort.SubIndex != 1 {
+		t.Fatalf("unexpected abort: %+v", abort)
+	}
+}
+
+func TestNMTAndPDO(t *testing.T) {
+	bus := newFakeBus(2)
+	net := newTestNetwork(t, bus)
+	node := net.Node(2)
+	defer node.Close()
+
+	if err := node.NMT(NMTStart); err != nil {
+		t.Fatal(err)
+	}
+	f := <-bus.sent
+	if f.ID != 0 || !bytes.Equal(f.Payload(), []byte{byte(NMTStart), 2}) {
+		t.Fatalf("unexpected NMT frame %+v", f)
+	}
+
+	if err := node.WriteRPDO(1, []byte{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	f = <-bus.sent
+	if f.ID != 0x202 {
+		t.Fatalf("unexpected RPDO COB-ID 0x%X", f.ID)
+	}
+
+	states := make(chan NMTState, 1)
+	node.OnHeartbeat(func(s NMTState) { states <- s })
+	pdos := make(chan []byte, 1)
+	if _, err := node.OnTPDO(2, func(b []byte) { pdos <- append([]byte(nil), b...) }); err != nil {
+		t.Fatal(err)
+	}
+
+	bus.rx <- devices.NewCANFrame(0x702, []byte{byte(StateOperational)})
+	bus.rx <- devices.NewCANFrame(0x282, []byte{9, 8, 7})
+
+	select {
+	case s := <-states:
+		if s != StateOperational {
+			t.Fatalf("got state %v", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for heartbeat")
+	}
+	select {
+	case b := <-pdos:
+		if !bytes.Equal(b, []byte{9, 8, 7}) {
+			t.Fatalf("got TPDO %v", b)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for TPDO")
+	}
+}
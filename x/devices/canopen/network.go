@@ -0,0 +1,145 @@
+// Package canopen implements a minimal CANopen (CiA 301) master on top of devices.CAN.
+//
+// It provides NMT node control, heartbeat monitoring, SDO client transfers
+// (expedited and segmented) and raw PDO exchange using the predefined
+// connection set.
+package canopen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// Predefined connection set function codes (COB-ID bases).
+const (
+	cobNMT       uint32 = 0x000
+	cobSync      uint32 = 0x080
+	cobEmergency uint32 = 0x080
+	cobTPDO1     uint32 = 0x180
+	cobRPDO1     uint32 = 0x200
+	cobSDOTx     uint32 = 0x580 // server -> client
+	cobSDORx     uint32 = 0x600 // client -> server
+	cobHeartbeat uint32 = 0x700
+)
+
+// Handler receives frames dispatched by the network.
+type Handler func(f devices.CANFrame)
+
+// Network multiplexes a CAN bus between CANopen nodes.
+// A single read loop dispatches received frames to subscribers by COB-ID.
+type Network struct {
+	bus    devices.CAN
+	ctx    context.Context
+	cancel func()
+
+	mu       sync.Mutex
+	handlers map[uint32]map[int]Handler
+	nextID   int
+
+	writeMu   sync.Mutex
+	startOnce sync.Once
+}
+
+// New creates a CANopen network over bus. The read loop starts in Start and stops when ctx is done.
+func New(ctx context.Context, bus devices.CAN) *Network {
+	cctx, cancel := context.WithCancel(ctx)
+	return &Network{
+		bus:      bus,
+		ctx:      cctx,
+		cancel:   cancel,
+		handlers: make(map[uint32]map[int]Handler),
+	}
+}
+
+// Start starts the internal read loop.
+func (n *Network) Start() {
+	n.startOnce.Do(func() {
+		go n.readLoop()
+	})
+}
+
+// Close stops the internal read loop.
+func (n *Network) Close() {
+	if n.cancel != nil {
+		n.cancel()
+	}
+}
+
+// Send transmits a frame on the bus.
+func (n *Network) Send(f devices.CANFrame) error {
+	n.writeMu.Lock()
+	defer n.writeMu.Unlock()
+	return n.bus.WriteFrame(f)
+}
+
+// Subscribe registers fn for frames with the given COB-ID and returns an unsubscribe function.
+func (n *Network) Subscribe(cobID uint32, fn Handler) (unsubscribe func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	hs, ok := n.handlers[cobID]
+	if !ok {
+		hs = make(map[int]Handler)
+		n.handlers[cobID] = hs
+	}
+	id := n.nextID
+	n.nextID++
+	hs[id] = fn
+
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.handlers[cobID], id)
+	}
+}
+
+// Sync transmits a SYNC object.
+func (n *Network) Sync() error {
+	return n.Send(devices.NewCANFrame(cobSync, nil))
+}
+
+// Node returns a handle for the remote node with the given id (1..127).
+func (n *Network) Node(id uint8, opts ...NodeOption) *Node {
+	return newNode(n, id, opts...)
+}
+
+func (n *Network) readLoop() {
+	var f devices.CANFrame
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		default:
+		}
+		if err := n.bus.ReadFrame(&f); err != nil {
+			if errors.Is(err, devices.ErrTimeout) {
+				continue
+			}
+			// back off on transient errors
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		n.dispatch(f)
+	}
+}
+
+func (n *Network) dispatch(f devices.CANFrame) {
+	if f.Extended {
+		return
+	}
+	n.mu.Lock()
+	hs := n.handlers[f.ID]
+	fns := make([]Handler, 0, len(hs))
+	for _, h := range hs {
+		fns = append(fns, h)
+	}
+	n.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(f)
+	}
+}
@@ -0,0 +1,57 @@
+package canopen
+
+import (
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// NMTCommand is a network management command specifier.
+type NMTCommand uint8
+
+const (
+	NMTStart              NMTCommand = 0x01
+	NMTStop               NMTCommand = 0x02
+	NMTPreOperational     NMTCommand = 0x80
+	NMTResetNode          NMTCommand = 0x81
+	NMTResetCommunication NMTCommand = 0x82
+)
+
+// NMTState is a node state reported in heartbeat messages.
+type NMTState uint8
+
+const (
+	StateBootUp         NMTState = 0x00
+	StateStopped        NMTState = 0x04
+	StateOperational    NMTState = 0x05
+	StatePreOperational NMTState = 0x7F
+)
+
+// String returns the state name.
+func (s NMTState) String() string {
+	switch s {
+	case StateBootUp:
+		return "boot-up"
+	case StateStopped:
+		return "stopped"
+	case StateOperational:
+		return "operational"
+	case StatePreOperational:
+		return "pre-operational"
+	default:
+		return "unknown"
+	}
+}
+
+// NMT sends a network management command to node. Node 0 addresses all nodes.
+func (n *Network) NMT(cmd NMTCommand, node uint8) error {
+	return n.Send(devices.NewCANFrame(cobNMT, []byte{byte(cmd), node}))
+}
+
+// OnHeartbeat registers fn to be called for each heartbeat from node and returns an unsubscribe function.
+func (n *Network) OnHeartbeat(node uint8, fn func(NMTState)) (unsubscribe func()) {
+	return n.Subscribe(cobHeartbeat+uint32(node), func(f devices.CANFrame) {
+		if f.Len < 1 {
+			return
+		}
+		fn(NMTState(f.Data[0] & 0x7F))
+	})
+}
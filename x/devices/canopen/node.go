@@ -0,0 +1,73 @@
+package canopen
+
+import (
+	"sync"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// NodeOption configures a Node.
+type NodeOption func(*Node)
+
+// WithSDOTimeout sets the SDO response timeout (default: 500ms).
+func WithSDOTimeout(d time.Duration) NodeOption {
+	return func(n *Node) {
+		n.sdoTimeout = d
+	}
+}
+
+// Node is a handle to a remote CANopen node.
+type Node struct {
+	net        *Network
+	id         uint8
+	sdoTimeout time.Duration
+
+	// sdoMu serializes SDO transfers; CANopen allows one transfer per SDO channel.
+	sdoMu sync.Mutex
+	sdoCh chan devices.CANFrame
+	unsub func()
+}
+
+func newNode(net *Network, id uint8, opts ...NodeOption) *Node {
+	n := &Node{
+		net:        net,
+		id:         id,
+		sdoTimeout: 500 * time.Millisecond,
+		sdoCh:      make(chan devices.CANFrame, 1),
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	n.unsub = net.Subscribe(cobSDOTx+uint32(id), func(f devices.CANFrame) {
+		select {
+		case n.sdoCh <- f:
+		default:
+			// drop unsolicited responses
+		}
+	})
+	return n
+}
+
+// ID returns the node id.
+func (n *Node) ID() uint8 {
+	return n.id
+}
+
+// Close releases the node's SDO subscription.
+func (n *Node) Close() {
+	if n.unsub != nil {
+		n.unsub()
+		n.unsub = nil
+	}
+}
+
+// NMT sends a network management command to this node.
+func (n *Node) NMT(cmd NMTCommand) error {
+	return n.net.NMT(cmd, n.id)
+}
+
+// OnHeartbeat registers fn to be called for each heartbeat from this node.
+func (n *Node) OnHeartbeat(fn func(NMTState)) (unsubscribe func()) {
+	return n.net.OnHeartbeat(n.id, fn)
+}
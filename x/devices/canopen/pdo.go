@@ -0,0 +1,52 @@
+package canopen
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// TPDOCobID returns the default COB-ID of transmit PDO num (1..4) for node id.
+// TPDOs are transmitted by the node and received by the master.
+func TPDOCobID(num int, id uint8) uint32 {
+	return cobTPDO1 + uint32(num-1)*0x100 + uint32(id)
+}
+
+// RPDOCobID returns the default COB-ID of receive PDO num (1..4) for node id.
+// RPDOs are received by the node and transmitted by the master.
+func RPDOCobID(num int, id uint8) uint32 {
+	return cobRPDO1 + uint32(num-1)*0x100 + uint32(id)
+}
+
+// WriteRPDO transmits up to 8 bytes of process data to the node's receive PDO num (1..4).
+// Data layout must match the node's RPDO mapping.
+func (n *Node) WriteRPDO(num int, data []byte) error {
+	if num < 1 || num > 4 {
+		return fmt.Errorf("canopen: invalid RPDO number %d: %w", num, devices.ErrInvalidValue)
+	}
+	if len(data) > 8 {
+		return fmt.Errorf("canopen: RPDO payload %d bytes: %w", len(data), devices.ErrInvalidSize)
+	}
+	return n.net.Send(devices.NewCANFrame(RPDOCobID(num, n.id), data))
+}
+
+// OnTPDO registers fn to be called with the payload of the node's transmit PDO num (1..4).
+// The payload slice is only valid during the callback.
+func (n *Node) OnTPDO(num int, fn func(data []byte)) (unsubscribe func(), err error) {
+	if num < 1 || num > 4 {
+		return nil, fmt.Errorf("canopen: invalid TPDO number %d: %w", num, devices.ErrInvalidValue)
+	}
+	return n.net.Subscribe(TPDOCobID(num, n.id), func(f devices.CANFrame) {
+		fn(f.Payload())
+	}), nil
+}
+
+// OnEmergency registers fn to be called with EMCY error code, error register and manufacturer data.
+func (n *Node) OnEmergency(fn func(code uint16, register uint8, data []byte)) (unsubscribe func()) {
+	return n.net.Subscribe(cobEmergency+uint32(n.id), func(f devices.CANFrame) {
+		if f.Len < 3 {
+			return
+		}
+		fn(uint16(f.Data[0])|uint16(f.Data[1])<<8, f.Data[2], f.Data[3:f.Len])
+	})
+}
@@ -0,0 +1,297 @@
+package canopen
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// SDO command specifiers.
+const (
+	sdoCCSDownloadSegment  = 0
+	sdoCCSDownloadInitiate = 1
+	sdoCCSUploadInitiate   = 2
+	sdoCCSUploadSegment    = 3
+	sdoCSAbort             = 4
+
+	sdoSCSUploadSegment    = 0
+	sdoSCSDownloadSegment  = 1
+	sdoSCSUploadInitiate   = 2
+	sdoSCSDownloadInitiate = 3
+)
+
+// Common SDO abort codes (CiA 301).
+const (
+	AbortToggleBit        uint32 = 0x05030000
+	AbortTimeout          uint32 = 0x05040000
+	AbortInvalidCommand   uint32 = 0x05040001
+	AbortUnsupported      uint32 = 0x06010000
+	AbortReadOnly         uint32 = 0x06010002
+	AbortWriteOnly        uint32 = 0x06010001
+	AbortNoObject         uint32 = 0x06020000
+	AbortLengthMismatch   uint32 = 0x06070010
+	AbortNoSubIndex       uint32 = 0x06090011
+	AbortGeneral          uint32 = 0x08000000
+	AbortDataNotAvailable uint32 = 0x08000024
+)
+
+// SDOAbortError is returned when the server aborts a transfer.
+type SDOAbortError struct {
+	Index    uint16
+	SubIndex uint8
+	Code     uint32
+}
+
+func (e *SDOAbortError) Error() string {
+	return fmt.Sprintf("canopen: SDO abort 0x%04X:%02X code 0x%08X", e.Index, e.SubIndex, e.Code)
+}
+
+// Read performs an SDO upload of object index:sub and returns its raw bytes.
+// Both expedited and segmented transfers are supported.
+func (n *Node) Read(index uint16, sub uint8) ([]byte, error) {
+	n.sdoMu.Lock()
+	defer n.sdoMu.Unlock()
+
+	var req [8]byte
+	req[0] = sdoCCSUploadInitiate << 5
+	putMux(req[:], index, sub)
+
+	resp, err := n.sdoRequest(req[:], index, sub)
+	if err != nil {
+		return nil, err
+	}
+	if resp[0]>>5 != sdoSCSUploadInitiate {
+		return nil, n.abort(index, sub, AbortInvalidCommand)
+	}
+
+	expedited := resp[0]&0x02 != 0
+	sizeIndicated := resp[0]&0x01 != 0
+	if expedited {
+		size := 4
+		if sizeIndicated {
+			size = 4 - int((resp[0]>>2)&0x03)
+		}
+		out := make([]byte, size)
+		copy(out, resp[4:4+size])
+		return out, nil
+	}
+
+	total := -1
+	if sizeIndicated {
+		total = int(binary.LittleEndian.Uint32(resp[4:8]))
+	}
+	out := make([]byte, 0, max(total, 0))
+	toggle := byte(0)
+	for {
+		var seg [8]byte
+		seg[0] = sdoCCSUploadSegment<<5 | toggle<<4
+		resp, err := n.sdoRequest(seg[:], index, sub)
+		if err != nil {
+			return nil, err
+		}
+		if resp[0]>>5 != sdoSCSUploadSegment {
+			return nil, n.abort(index, sub, AbortInvalidCommand)
+		}
+		if (resp[0]>>4)&0x01 != toggle {
+			return nil, n.abort(index, sub, AbortToggleBit)
+		}
+		size := 7 - int((resp[0]>>1)&0x07)
+		out = append(out, resp[1:1+size]...)
+		if resp[0]&0x01 != 0 {
+			break
+		}
+		toggle ^= 1
+	}
+	if total >= 0 && len(out) != total {
+		return nil, fmt.Errorf("canopen: SDO upload 0x%04X:%02X size mismatch: got %d, expected %d", index, sub, len(out), total)
+	}
+	return out, nil
+}
+
+// Write performs an SDO download of data to object index:sub.
+// Payloads up to 4 bytes use an expedited transfer, longer payloads are segmented.
+func (n *Node) Write(index uint16, sub uint8, data []byte) error {
+	n.sdoMu.Lock()
+	defer n.sdoMu.Unlock()
+
+	var req [8]byte
+	putMux(req[:], index, sub)
+
+	if len(data) > 0 && len(data) <= 4 {
+		req[0] = sdoCCSDownloadInitiate<<5 | byte(4-len(data))<<2 | 0x03
+		copy(req[4:], data)
+		resp, err := n.sdoRequest(req[:], index, sub)
+		if err != nil {
+			return err
+		}
+		if resp[0]>>5 != sdoSCSDownloadInitiate {
+			return n.abort(index, sub, AbortInvalidCommand)
+		}
+		return nil
+	}
+
+	req[0] = sdoCCSDownloadInitiate<<5 | 0x01
+	binary.LittleEndian.PutUint32(req[4:], uint32(len(data)))
+	resp, err := n.sdoRequest(req[:], index, sub)
+	if err != nil {
+		return err
+	}
+	if resp[0]>>5 != sdoSCSDownloadInitiate {
+		return n.abort(index, sub, AbortInvalidCommand)
+	}
+
+	toggle := byte(0)
+	for off := 0; ; {
+		var seg [8]byte
+		size := copy(seg[1:], data[off:])
+		off += size
+		last := off >= len(data)
+		seg[0] = sdoCCSDownloadSegment<<5 | toggle<<4 | byte(7-size)<<1
+		if last {
+			seg[0] |= 0x01
+		}
+		resp, err := n.sdoRequest(seg[:], index, sub)
+		if err != nil {
+			return err
+		}
+		if resp[0]>>5 != sdoSCSDownloadSegment {
+			return n.abort(index, sub, AbortInvalidCommand)
+		}
+		if (resp[0]>>4)&0x01 != toggle {
+			return n.abort(index, sub, AbortToggleBit)
+		}
+		if last {
+			return nil
+		}
+		toggle ^= 1
+	}
+}
+
+// ReadUint8 reads an UNSIGNED8 object.
+func (n *Node) ReadUint8(index uint16, sub uint8) (uint8, error) {
+	b, err := n.readSized(index, sub, 1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// ReadUint16 reads an UNSIGNED16 object.
+func (n *Node) ReadUint16(index uint16, sub uint8) (uint16, error) {
+	b, err := n.readSized(index, sub, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// ReadUint32 reads an UNSIGNED32 object.
+func (n *Node) ReadUint32(index uint16, sub uint8) (uint32, error) {
+	b, err := n.readSized(index, sub, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// ReadInt32 reads an INTEGER32 object.
+func (n *Node) ReadInt32(index uint16, sub uint8) (int32, error) {
+	v, err := n.ReadUint32(index, sub)
+	return int32(v), err
+}
+
+// ReadFloat32 reads a REAL32 object.
+func (n *Node) ReadFloat32(index uint16, sub uint8) (float32, error) {
+	v, err := n.ReadUint32(index, sub)
+	return math.Float32frombits(v), err
+}
+
+// WriteUint8 writes an UNSIGNED8 object.
+func (n *Node) WriteUint8(index uint16, sub uint8, v uint8) error {
+	return n.Write(index, sub, []byte{v})
+}
+
+// WriteUint16 writes an UNSIGNED16 object.
+func (n *Node) WriteUint16(index uint16, sub uint8, v uint16) error {
+	return n.Write(index, sub, binary.LittleEndian.AppendUint16(nil, v))
+}
+
+// WriteUint32 writes an UNSIGNED32 object.
+func (n *Node) WriteUint32(index uint16, sub uint8, v uint32) error {
+	return n.Write(index, sub, binary.LittleEndian.AppendUint32(nil, v))
+}
+
+// WriteInt32 writes an INTEGER32 object.
+func (n *Node) WriteInt32(index uint16, sub uint8, v int32) error {
+	return n.WriteUint32(index, sub, uint32(v))
+}
+
+// WriteFloat32 writes a REAL32 object.
+func (n *Node) WriteFloat32(index uint16, sub uint8, v float32) error {
+	return n.WriteUint32(index, sub, math.Float32bits(v))
+}
+
+func (n *Node) readSized(index uint16, sub uint8, size int) ([]byte, error) {
+	b, err := n.Read(index, sub)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < size {
+		return nil, fmt.Errorf("canopen: SDO 0x%04X:%02X returned %d bytes, expected %d", index, sub, len(b), size)
+	}
+	return b, nil
+}
+
+// sdoRequest sends req and waits for the matching server response.
+func (n *Node) sdoRequest(req []byte, index uint16, sub uint8) ([8]byte, error) {
+	var resp [8]byte
+
+	// discard stale responses from a previous (timed out) transfer
+	select {
+	case <-n.sdoCh:
+	default:
+	}
+
+	if err := n.net.Send(devices.NewCANFrame(cobSDORx+uint32(n.id), req)); err != nil {
+		return resp, err
+	}
+
+	timer := time.NewTimer(n.sdoTimeout)
+	defer timer.Stop()
+
+	select {
+	case f := <-n.sdoCh:
+		resp = f.Data
+		if resp[0]>>5 == sdoCSAbort {
+			return resp, &SDOAbortError{
+				Index:    binary.LittleEndian.Uint16(resp[1:3]),
+				SubIndex: resp[3],
+				Code:     binary.LittleEndian.Uint32(resp[4:8]),
+			}
+		}
+		return resp, nil
+	case <-timer.C:
+		_ = n.abort(index, sub, AbortTimeout)
+		return resp, fmt.Errorf("canopen: SDO 0x%04X:%02X on node %d: %w", index, sub, n.id, devices.ErrTimeout)
+	case <-n.net.ctx.Done():
+		return resp, n.net.ctx.Err()
+	}
+}
+
+// abort sends an SDO abort to the server and returns the corresponding error.
+func (n *Node) abort(index uint16, sub uint8, code uint32) error {
+	var req [8]byte
+	req[0] = sdoCSAbort << 5
+	putMux(req[:], index, sub)
+	binary.LittleEndian.PutUint32(req[4:], code)
+	_ = n.net.Send(devices.NewCANFrame(cobSDORx+uint32(n.id), req[:]))
+	return &SDOAbortError{Index: index, SubIndex: sub, Code: code}
+}
+
+func putMux(b []byte, index uint16, sub uint8) {
+	binary.LittleEndian.PutUint16(b[1:3], index)
+	b[3] = sub
+}
@@ -0,0 +1,216 @@
+// Package gnss provides a GNSS receiver driver over devices.Serial.
+//
+// It parses NMEA 0183 sentences (GGA, RMC, GSA, GSV, VTG) and u-blox UBX
+// binary messages (NAV-PVT, ACK, configuration) from a single stream and
+// produces timestamped fixes with accuracy estimates. Fixes can be projected
+// into a local ENU frame and used as position measurements for kalman/ekalman.
+package gnss
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	devio "github.com/itohio/EasyRobot/x/devices"
+)
+
+// Config holds configuration for a GNSS receiver.
+type Config struct {
+	// UBX enables u-blox configuration on Configure(true): NAV-PVT output is
+	// enabled at every navigation solution.
+	UBX bool
+
+	// Rate is the navigation solution period sent with CFG-RATE (UBX only).
+	// Zero leaves the receiver setting unchanged.
+	Rate time.Duration
+
+	// UERE is used to derive accuracy from DOP for NMEA-only receivers.
+	// Default: DefaultUERE
+	UERE float64
+}
+
+// DefaultConfig returns a default configuration (NMEA only).
+func DefaultConfig() Config {
+	return Config{UERE: DefaultUERE}
+}
+
+// Device is a streaming GNSS receiver.
+type Device struct {
+	ser    devio.Serial
+	config Config
+	ctx    context.Context
+	cancel func()
+
+	parser  Parser
+	builder *FixBuilder
+
+	mu         sync.Mutex
+	fix        Fix
+	hasFix     bool
+	satellites map[int]SatInfo
+	onFix      func(Fix)
+	onMessage  func(any)
+	acks       chan UBXAck
+
+	writeMu   sync.Mutex
+	startOnce sync.Once
+}
+
+// New creates a new GNSS receiver. The internal read loop starts in Configure and stops when ctx is done.
+func New(ctx context.Context, ser devio.Serial, config Config) *Device {
+	cctx, cancel := context.WithCancel(ctx)
+	b := NewFixBuilder()
+	if config.UERE > 0 {
+		b.UERE = config.UERE
+	}
+	return &Device{
+		ser:        ser,
+		config:     config,
+		ctx:        cctx,
+		cancel:     cancel,
+		builder:    b,
+		satellites: make(map[int]SatInfo),
+		acks:       make(chan UBXAck, 4),
+	}
+}
+
+// Configure starts the internal read loop. If init is true and UBX is enabled,
+// the receiver is configured to output NAV-PVT (and the navigation rate is set).
+func (d *Device) Configure(init bool) error {
+	d.startOnce.Do(func() {
+		go d.readLoop()
+	})
+	if !init || !d.config.UBX {
+		return nil
+	}
+	if d.config.Rate > 0 {
+		if err := d.SendUBX(CfgRate(d.config.Rate, 1)); err != nil {
+			return err
+		}
+	}
+	return d.SendUBX(CfgMsg(ClassNAV, IDNavPVT, 1))
+}
+
+// Close stops the internal read loop.
+func (d *Device) Close() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// OnFix registers a callback invoked for every new fix.
+func (d *Device) OnFix(fn func(Fix)) {
+	d.mu.Lock()
+	d.onFix = fn
+	d.mu.Unlock()
+}
+
+// OnMessage registers a callback invoked for every parsed message (NMEA or UBX).
+func (d *Device) OnMessage(fn func(any)) {
+	d.mu.Lock()
+	d.onMessage = fn
+	d.mu.Unlock()
+}
+
+// Fix returns the latest fix and whether any fix was received.
+func (d *Device) Fix() (Fix, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.fix, d.hasFix
+}
+
+// Satellites returns the satellites in view reported by GSV sentences.
+func (d *Device) Satellites() []SatInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]SatInfo, 0, len(d.satellites))
+	for _, s := range d.satellites {
+		out = append(out, s)
+	}
+	return out
+}
+
+// SendUBX writes a UBX message to the receiver.
+func (d *Device) SendUBX(m UBX) error {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	_, err := d.ser.Write(m.Encode())
+	return err
+}
+
+// SendUBXAck writes a CFG message and waits for its ACK-ACK/ACK-NAK.
+func (d *Device) SendUBXAck(ctx context.Context, m UBX) error {
+	if err := d.SendUBX(m); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ack := <-d.acks:
+			if ack.Class != m.Class || ack.ID != m.ID {
+				continue
+			}
+			if !ack.Ack {
+				return devio.ErrInvalidResponse
+			}
+			return nil
+		}
+	}
+}
+
+func (d *Device) readLoop() {
+	tmp := make([]byte, 512)
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		default:
+		}
+		n, err := d.ser.Read(tmp)
+		if n > 0 {
+			d.parser.Feed(tmp[:n], d.handle)
+		}
+		if err != nil {
+			if err == io.EOF {
+				return
+			}
+			// continue on transient errors
+		}
+		if n == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
+
+func (d *Device) handle(msg any) {
+	d.mu.Lock()
+	onMessage := d.onMessage
+	switch m := msg.(type) {
+	case *GSV:
+		for _, s := range m.Satellites {
+			d.satellites[s.PRN] = s
+		}
+	case *UBXAck:
+		select {
+		case d.acks <- *m:
+		default:
+		}
+	}
+	fix, ok := d.builder.Add(msg)
+	var onFix func(Fix)
+	if ok {
+		d.fix = fix
+		d.hasFix = true
+		onFix = d.onFix
+	}
+	d.mu.Unlock()
+
+	if onMessage != nil {
+		onMessage(msg)
+	}
+	if onFix != nil {
+		onFix(fix)
+	}
+}
@@ -0,0 +1,79 @@
+package gnss
+
+import "math"
+
+// WGS84 ellipsoid parameters.
+const (
+	wgs84A  = 6378137.0
+	wgs84F  = 1 / 298.257223563
+	wgs84E2 = wgs84F * (2 - wgs84F)
+)
+
+// LocalFrame is a local tangent plane (east-north-up) anchored at a geodetic origin.
+type LocalFrame struct {
+	Lat, Lon, Alt float64 // origin (degrees, metres)
+
+	x0, y0, z0 float64 // origin in ECEF
+	sinLat     float64
+	cosLat     float64
+	sinLon     float64
+	cosLon     float64
+}
+
+// NewLocalFrame creates an ENU frame with origin at lat/lon (degrees) and alt (metres).
+func NewLocalFrame(lat, lon, alt float64) *LocalFrame {
+	f := &LocalFrame{Lat: lat, Lon: lon, Alt: alt}
+	f.x0, f.y0, f.z0 = GeodeticToECEF(lat, lon, alt)
+	f.sinLat, f.cosLat = math.Sincos(lat * math.Pi / 180)
+	f.sinLon, f.cosLon = math.Sincos(lon * math.Pi / 180)
+	return f
+}
+
+// NewLocalFrameFromFix creates an ENU frame anchored at the fix position.
+func NewLocalFrameFromFix(fix Fix) *LocalFrame {
+	return NewLocalFrame(fix.Lat, fix.Lon, fix.Alt)
+}
+
+// ToENU converts geodetic coordinates to east, north, up metres relative to the origin.
+func (f *LocalFrame) ToENU(lat, lon, alt float64) (e, n, u float64) {
+	x, y, z := GeodeticToECEF(lat, lon, alt)
+	dx, dy, dz := x-f.x0, y-f.y0, z-f.z0
+	e = -f.sinLon*dx + f.cosLon*dy
+	n = -f.sinLat*f.cosLon*dx - f.sinLat*f.sinLon*dy + f.cosLat*dz
+	u = f.cosLat*f.cosLon*dx + f.cosLat*f.sinLon*dy + f.sinLat*dz
+	return
+}
+
+// ToGeodetic converts east, north, up metres relative to the origin back to geodetic coordinates.
+func (f *LocalFrame) ToGeodetic(e, n, u float64) (lat, lon, alt float64) {
+	dx := -f.sinLon*e - f.sinLat*f.cosLon*n + f.cosLat*f.cosLon*u
+	dy := f.cosLon*e - f.sinLat*f.sinLon*n + f.cosLat*f.sinLon*u
+	dz := f.cosLat*n + f.sinLat*u
+	return ECEFToGeodetic(f.x0+dx, f.y0+dy, f.z0+dz)
+}
+
+// GeodeticToECEF converts WGS84 geodetic coordinates (degrees, metres) to ECEF metres.
+func GeodeticToECEF(lat, lon, alt float64) (x, y, z float64) {
+	sinLat, cosLat := math.Sincos(lat * math.Pi / 180)
+	sinLon, cosLon := math.Sincos(lon * math.Pi / 180)
+	n := wgs84A / math.Sqrt(1-wgs84E2*sinLat*sinLat)
+	x = (n + alt) * cosLat * cosLon
+	y = (n + alt) * cosLat * sinLon
+	z = (n*(1-wgs84E2) + alt) * sinLat
+	return
+}
+
+// ECEFToGeodetic converts ECEF metres to WGS84 geodetic coordinates (degrees, metres)
+// using Bowring's iterative method.
+func ECEFToGeodetic(x, y, z float64) (lat, lon, alt float64) {
+	lon = math.Atan2(y, x)
+	p := math.Hypot(x, y)
+	phi := math.Atan2(z, p*(1-wgs84E2))
+	for i := 0; i < 5; i++ {
+		sinPhi := math.Sin(phi)
+		n := wgs84A / math.Sqrt(1-wgs84E2*sinPhi*sinPhi)
+		alt = p/math.Cos(phi) - n
+		phi = math.Atan2(z, p*(1-wgs84E2*n/(n+alt)))
+	}
+	return phi * 180 / math.Pi, lon * 180 / math.Pi, alt
+}
@@ -0,0 +1,214 @@
+package gnss
+
+import (
+	"math"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// FixType is the navigation fix dimension.
+type FixType int
+
+const (
+	FixNone          FixType = 0 // UBX: no fix
+	FixDeadReckoning FixType = 1 // UBX only (NMEA GSA uses 1 for "no fix")
+	Fix2D            FixType = 2
+	Fix3D            FixType = 3
+	FixGNSSDR        FixType = 4 // UBX: GNSS + dead reckoning
+	FixTimeOnly      FixType = 5
+)
+
+const knotsToMPS = 1852.0 / 3600.0
+
+// DefaultUERE is the default user equivalent range error (m, 1-sigma) used to
+// derive accuracy from DOP when the receiver does not report it directly.
+const DefaultUERE = 2.5
+
+// Fix is a timestamped navigation solution.
+type Fix struct {
+	// Timestamp is the local time the solution was received.
+	Timestamp time.Time
+	// Time is the UTC time of the solution (zero if unknown).
+	Time time.Time
+
+	Valid   bool
+	Type    FixType
+	Quality Quality
+	NumSats int
+
+	Lat, Lon float64 // degrees
+	Alt      float64 // metres above mean sea level
+
+	// Accuracy estimates (metres, 1-sigma)
+	HorizontalAccuracy float64
+	VerticalAccuracy   float64
+	SpeedAccuracy      float64 // m/s
+
+	HDOP, VDOP, PDOP float64
+
+	Speed             float64 // ground speed (m/s)
+	Course            float64 // course over ground (deg true)
+	VelN, VelE, VelD  float64 // m/s (NED); zero if unknown
+	HasVelocity3D     bool    // VelN/VelE/VelD are set
+	HasAccuracyReport bool    // accuracy reported by receiver rather than derived from DOP
+}
+
+// ENU returns the fix position in the local east-north-up frame as a 3 element vector.
+func (f *Fix) ENU(frame *LocalFrame) vec.Vector {
+	e, n, u := frame.ToENU(f.Lat, f.Lon, f.Alt)
+	return vec.NewFrom(float32(e), float32(n), float32(u))
+}
+
+// VelocityENU returns the velocity in the local east-north-up frame.
+// When only ground speed and course are known, the vertical component is zero.
+func (f *Fix) VelocityENU() vec.Vector {
+	if f.HasVelocity3D {
+		return vec.NewFrom(float32(f.VelE), float32(f.VelN), float32(-f.VelD))
+	}
+	rad := f.Course * math.Pi / 180
+	return vec.NewFrom(float32(f.Speed*math.Sin(rad)), float32(f.Speed*math.Cos(rad)), 0)
+}
+
+// Covariance returns the 3x3 ENU position covariance (m²) derived from the accuracy estimates.
+// It can be used directly as the measurement noise R of kalman/ekalman position updates.
+func (f *Fix) Covariance() mat.Matrix {
+	h := float32(f.HorizontalAccuracy * f.HorizontalAccuracy)
+	v := float32(f.VerticalAccuracy * f.VerticalAccuracy)
+	m := mat.New(3, 3)
+	m[0][0], m[1][1], m[2][2] = h, h, v
+	return m
+}
+
+// FixBuilder merges NMEA sentences (GGA, RMC, GSA, VTG) and UBX NAV-PVT into fixes.
+type FixBuilder struct {
+	// UERE is used to derive accuracy from DOP for NMEA-only receivers.
+	UERE float64
+
+	// Now returns the local timestamp for fixes (default: time.Now).
+	Now func() time.Time
+
+	date time.Time
+	rmc  *RMC
+	gsa  *GSA
+	vtg  *VTG
+}
+
+// NewFixBuilder creates a fix builder with default UERE.
+func NewFixBuilder() *FixBuilder {
+	return &FixBuilder{UERE: DefaultUERE, Now: time.Now}
+}
+
+// Add consumes a parsed message and returns a fix when one is complete.
+// NMEA fixes are emitted on GGA using the most recent RMC/GSA/VTG data;
+// UBX fixes are emitted on every NAV-PVT.
+func (b *FixBuilder) Add(msg any) (Fix, bool) {
+	switch m := msg.(type) {
+	case *RMC:
+		b.rmc = m
+		if !m.Time.IsZero() {
+			y, mo, d := m.Time.Date()
+			b.date = time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)
+		}
+	case *GSA:
+		b.gsa = m
+	case *VTG:
+		b.vtg = m
+	case *GGA:
+		return b.fromGGA(m), true
+	case *NavPVT:
+		return b.fromNavPVT(m), true
+	}
+	return Fix{}, false
+}
+
+func (b *FixBuilder) fromGGA(m *GGA) Fix {
+	f := Fix{
+		Timestamp: b.now(),
+		Valid:     m.Quality != QualityInvalid,
+		Quality:   m.Quality,
+		NumSats:   m.NumSats,
+		Lat:       m.Lat,
+		Lon:       m.Lon,
+		Alt:       m.Altitude,
+		HDOP:      m.HDOP,
+		Type:      Fix3D,
+	}
+	if !b.date.IsZero() {
+		f.Time = b.date.Add(m.Time)
+	}
+	if b.gsa != nil {
+		f.Type = b.gsa.FixType
+		if f.Type == 1 { // NMEA: no fix
+			f.Type = FixNone
+		}
+		f.PDOP, f.VDOP = b.gsa.PDOP, b.gsa.VDOP
+		if b.gsa.HDOP > 0 {
+			f.HDOP = b.gsa.HDOP
+		}
+	}
+	if b.vtg != nil {
+		f.Speed = b.vtg.SpeedKnots * knotsToMPS
+		f.Course = b.vtg.CourseTrue
+	} else if b.rmc != nil {
+		f.Speed = b.rmc.SpeedKnots * knotsToMPS
+		f.Course = b.rmc.Course
+	}
+
+	uere := b.UERE
+	if uere <= 0 {
+		uere = DefaultUERE
+	}
+	f.HorizontalAccuracy = f.HDOP * uere
+	vdop := f.VDOP
+	if vdop == 0 {
+		vdop = 1.5 * f.HDOP // typical vertical/horizontal DOP ratio
+	}
+	f.VerticalAccuracy = vdop * uere
+	return f
+}
+
+func (b *FixBuilder) fromNavPVT(m *NavPVT) Fix {
+	f := Fix{
+		Timestamp:          b.now(),
+		Valid:              m.FixOK && m.FixType >= Fix2D,
+		Type:               m.FixType,
+		NumSats:            m.NumSV,
+		Lat:                m.Lat,
+		Lon:                m.Lon,
+		Alt:                m.HMSL,
+		HorizontalAccuracy: m.HAcc,
+		VerticalAccuracy:   m.VAcc,
+		SpeedAccuracy:      m.SAcc,
+		PDOP:               m.PDOP,
+		Speed:              m.GSpeed,
+		Course:             m.HeadMot,
+		VelN:               m.VelN,
+		VelE:               m.VelE,
+		VelD:               m.VelD,
+		HasVelocity3D:      true,
+		HasAccuracyReport:  true,
+	}
+	switch {
+	case m.CarrSoln == 2:
+		f.Quality = QualityRTKFixed
+	case m.CarrSoln == 1:
+		f.Quality = QualityRTKFloat
+	case m.DiffSoln:
+		f.Quality = QualityDGPS
+	case f.Valid:
+		f.Quality = QualityGPS
+	}
+	if m.ValidDate && m.ValidTime {
+		f.Time = m.Time
+	}
+	return f
+}
+
+func (b *FixBuilder) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,223 @@
+package gnss
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubSerial struct {
+	mu      sync.Mutex
+	data    []byte
+	written []byte
+}
+
+func (s *stubSerial) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := copy(p, s.data)
+	s.data = s.data[n:]
+	return n, nil
+}
+
+func (s *stubSerial) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written = append(s.written, p...)
+	return len(p), nil
+}
+
+func (s *stubSerial) Buffered() int { return 0 }
+
+func near(a, b, eps float64) bool { return math.Abs(a-b) <= eps }
+
+func TestParseGGA(t *testing.T) {
+	msg, err := ParseNMEA("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gga := msg.(*GGA)
+	if !near(gga.Lat, 48.1173, 1e-4) || !near(gga.Lon, 11.516667, 1e-5) {
+		t.Fatalf("got lat %v lon %v", gga.Lat, gga.Lon)
+	}
+	if gga.Quality != QualityGPS || gga.NumSats != 8 || gga.HDOP != 0.9 || gga.Altitude != 545.4 {
+		t.Fatalf("unexpected GGA %+v", gga)
+	}
+	if gga.Time != 12*time.Hour+35*time.Minute+19*time.Second {
+		t.Fatalf("got time %v", gga.Time)
+	}
+
+	if _, err := ParseNMEA("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48"); err != ErrInvalidChecksum {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+}
+
+func TestParseOtherSentences(t *testing.T) {
+	cases := map[string]func(any) bool{
+		"GPRMC,123519,A,4807.038,S,01131.000,W,022.4,084.4,230394,003.1,W": func(m any) bool {
+			r := m.(*RMC)
+			return r.Valid && r.Lat < 0 && r.Lon < 0 && r.SpeedKnots == 22.4 && r.MagVar == -3.1 &&
+				r.Time.Equal(time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC))
+		},
+		"GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1": func(m any) bool {
+			g := m.(*GSA)
+			return g.FixType == Fix3D && len(g.PRNs) == 5 && g.PDOP == 2.5 && g.VDOP == 2.1
+		},
+		"GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,": func(m any) bool {
+			g := m.(*GSV)
+			return g.InView == 8 && len(g.Satellites) == 4 && g.Satellites[3].SNR == -1 && g.Satellites[2].Azimuth == 344
+		},
+		"GNVTG,054.7,T,034.4,M,005.5,N,010.2,K,A": func(m any) bool {
+			v := m.(*VTG)
+			return v.Talker == "GN" && v.CourseTrue == 54.7 && v.SpeedKmh == 10.2 && v.Mode == 'A'
+		},
+	}
+	for body, check := range cases {
+		msg, err := ParseNMEA(string(AppendNMEA(nil, body)))
+		if err != nil {
+			t.Fatalf("%s: %v", body, err)
+		}
+		if !check(msg) {
+			t.Fatalf("%s: unexpected %+v", body, msg)
+		}
+	}
+}
+
+func buildNavPVT(lat, lon, hmsl float64, hAcc, vAcc uint32) UBX {
+	p := make([]byte, navPVTSize)
+	le := binary.LittleEndian
+	le.PutUint32(p[0:], 1000)
+	le.PutUint16(p[4:], 2024)
+	p[6], p[7], p[8], p[9], p[10] = 5, 17, 10, 20, 30
+	p[11] = 0x03
+	p[20] = byte(Fix3D)
+	p[21] = 0x01
+	p[23] = 12
+	le.PutUint32(p[24:], uint32(int32(lon*1e7)))
+	le.PutUint32(p[28:], uint32(int32(lat*1e7)))
+	le.PutUint32(p[36:], uint32(int32(hmsl*1e3)))
+	le.PutUint32(p[40:], hAcc)
+	le.PutUint32(p[44:], vAcc)
+	velN, velE := int32(1000), int32(-2000) // mm/s
+	le.PutUint32(p[48:], uint32(velN))
+	le.PutUint32(p[52:], uint32(velE))
+	le.PutUint16(p[76:], 150)
+	return UBX{Class: ClassNAV, ID: IDNavPVT, Payload: p}
+}
+
+func TestParserMixedStream(t *testing.T) {
+	var stream []byte
+	stream = append(stream, 0x00, 0xFF) // garbage
+	stream = AppendNMEA(stream, "GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W")
+	stream = append(stream, buildNavPVT(54.6872, 25.2797, 112.5, 1500, 2500).Encode()...)
+	stream = append(stream, "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n"...)
+	stream = append(stream, "$GPGGA,broken*00\r\n"...)
+
+	var p Parser
+	b := NewFixBuilder()
+	var fixes []Fix
+	// feed in small chunks
+	for i := 0; i < len(stream); i += 7 {
+		end := min(i+7, len(stream))
+		p.Feed(stream[i:end], func(msg any) {
+			if f, ok := b.Add(msg); ok {
+				fixes = append(fixes, f)
+			}
+		})
+	}
+	if len(fixes) != 2 {
+		t.Fatalf("got %d fixes, want 2", len(fixes))
+	}
+	if p.Errors != 1 {
+		t.Fatalf("got %d errors, want 1", p.Errors)
+	}
+
+	ubx := fixes[0]
+	if !ubx.Valid || !ubx.HasAccuracyReport || ubx.HorizontalAccuracy != 1.5 || ubx.VerticalAccuracy != 2.5 {
+		t.Fatalf("unexpected UBX fix %+v", ubx)
+	}
+	if !near(ubx.Lat, 54.6872, 1e-6) || ubx.Time != time.Date(2024, 5, 17, 10, 20, 30, 0, time.UTC) {
+		t.Fatalf("unexpected UBX fix position/time %+v", ubx)
+	}
+	if v := ubx.VelocityENU(); v[0] != -2 || v[1] != 1 {
+		t.Fatalf("unexpected ENU velocity %v", v)
+	}
+
+	nmea := fixes[1]
+	if !nmea.Valid || !near(nmea.HorizontalAccuracy, 0.9*DefaultUERE, 1e-9) {
+		t.Fatalf("unexpected NMEA fix %+v", nmea)
+	}
+	if !near(nmea.Speed, 22.4*knotsToMPS, 1e-9) {
+		t.Fatalf("got speed %v", nmea.Speed)
+	}
+	if nmea.Time != time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC) {
+		t.Fatalf("got time %v", nmea.Time)
+	}
+	cov := nmea.Covariance()
+	if !near(float64(cov[0][0]), nmea.HorizontalAccuracy*nmea.HorizontalAccuracy, 1e-4) {
+		t.Fatalf("unexpected covariance %v", cov)
+	}
+}
+
+func TestLocalFrame(t *testing.T) {
+	frame := NewLocalFrame(54.6872, 25.2797, 100)
+
+	e, n, u := frame.ToENU(54.6872, 25.2797, 100)
+	if !near(e, 0, 1e-6) || !near(n, 0, 1e-6) || !near(u, 0, 1e-6) {
+		t.Fatalf("origin not at zero: %v %v %v", e, n, u)
+	}
+
+	// ~111.3 km per degree latitude; 0.001 deg north is ~111 m
+	_, n, _ = frame.ToENU(54.6882, 25.2797, 100)
+	if !near(n, 111.36, 0.5) {
+		t.Fatalf("got north %v", n)
+	}
+
+	lat, lon, alt := frame.ToGeodetic(120, -45, 3)
+	e, n, u = frame.ToENU(lat, lon, alt)
+	if !near(e, 120, 1e-4) || !near(n, -45, 1e-4) || !near(u, 3, 1e-4) {
+		t.Fatalf("round trip mismatch: %v %v %v", e, n, u)
+	}
+}
+
+func TestDevice(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	ser := &stubSerial{}
+	cfg := DefaultConfig()
+	cfg.UBX = true
+	cfg.Rate = 200 * time.Millisecond
+	dev := New(ctx, ser, cfg)
+	defer dev.Close()
+
+	fixes := make(chan Fix, 1)
+	dev.OnFix(func(f Fix) { fixes <- f })
+	if err := dev.Configure(true); err != nil {
+		t.Fatal(err)
+	}
+
+	ser.mu.Lock()
+	if len(ser.written) == 0 {
+		ser.mu.Unlock()
+		t.Fatal("expected UBX configuration to be written")
+	}
+	ser.data = append(ser.data, buildNavPVT(54.6872, 25.2797, 112.5, 800, 1200).Encode()...)
+	ser.mu.Unlock()
+
+	select {
+	case f := <-fixes:
+		frame := NewLocalFrameFromFix(f)
+		if pos := f.ENU(frame); pos[0] != 0 || pos[1] != 0 {
+			t.Fatalf("expected origin, got %v", pos)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for fix")
+	}
+	if _, ok := dev.Fix(); !ok {
+		t.Fatal("expected latest fix")
+	}
+}
@@ -0,0 +1,338 @@
+package gnss
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSentence = errors.New("gnss: invalid NMEA sentence")
+	ErrInvalidChecksum = errors.New("gnss: invalid checksum")
+	ErrUnsupported     = errors.New("gnss: unsupported message")
+)
+
+// Quality is the GGA fix quality indicator.
+type Quality int
+
+const (
+	QualityInvalid   Quality = 0
+	QualityGPS       Quality = 1
+	QualityDGPS      Quality = 2
+	QualityPPS       Quality = 3
+	QualityRTKFixed  Quality = 4
+	QualityRTKFloat  Quality = 5
+	QualityEstimated Quality = 6
+	QualityManual    Quality = 7
+	QualitySimulated Quality = 8
+)
+
+// GGA is global positioning system fix data.
+type GGA struct {
+	Talker      string
+	Time        time.Duration // time of day (UTC)
+	Lat, Lon    float64       // degrees, negative for S/W
+	Quality     Quality
+	NumSats     int
+	HDOP        float64
+	Altitude    float64 // metres above mean sea level
+	GeoidSep    float64 // metres
+	DGPSAge     float64 // seconds
+	DGPSStation string
+}
+
+// RMC is the recommended minimum specific GNSS data.
+type RMC struct {
+	Talker     string
+	Time       time.Time // UTC date and time
+	Valid      bool
+	Lat, Lon   float64
+	SpeedKnots float64
+	Course     float64 // degrees true
+	MagVar     float64 // degrees, negative for W
+	Mode       byte
+}
+
+// GSA is GNSS DOP and active satellites.
+type GSA struct {
+	Talker           string
+	Auto             bool
+	FixType          FixType
+	PRNs             []int
+	PDOP, HDOP, VDOP float64
+}
+
+// SatInfo describes a satellite in view.
+type SatInfo struct {
+	PRN       int
+	Elevation int // degrees
+	Azimuth   int // degrees
+	SNR       int // dB-Hz, -1 if not tracked
+}
+
+// GSV is GNSS satellites in view (one sentence of a sequence).
+type GSV struct {
+	Talker     string
+	Total      int
+	Number     int
+	InView     int
+	Satellites []SatInfo
+}
+
+// VTG is course over ground and ground speed.
+type VTG struct {
+	Talker     string
+	CourseTrue float64
+	CourseMag  float64
+	SpeedKnots float64
+	SpeedKmh   float64
+	Mode       byte
+}
+
+// ParseNMEA parses a single NMEA 0183 sentence (with or without trailing CR/LF).
+// Returns one of *GGA, *RMC, *GSA, *GSV, *VTG.
+func ParseNMEA(sentence string) (any, error) {
+	s := strings.TrimRight(sentence, "\r\n")
+	if len(s) < 7 || (s[0] != '$' && s[0] != '!') {
+		return nil, ErrInvalidSentence
+	}
+	body := s[1:]
+	if star := strings.LastIndexByte(body, '*'); star >= 0 {
+		sum, err := strconv.ParseUint(body[star+1:], 16, 8)
+		if err != nil {
+			return nil, ErrInvalidChecksum
+		}
+		body = body[:star]
+		if byte(sum) != nmeaChecksum(body) {
+			return nil, ErrInvalidChecksum
+		}
+	}
+
+	fields := strings.Split(body, ",")
+	if len(fields[0]) < 5 {
+		return nil, ErrInvalidSentence
+	}
+	talker, kind := fields[0][:len(fields[0])-3], fields[0][len(fields[0])-3:]
+	p := &fieldParser{fields: fields}
+
+	var msg any
+	switch kind {
+	case "GGA":
+		msg = parseGGA(talker, p)
+	case "RMC":
+		msg = parseRMC(talker, p)
+	case "GSA":
+		msg = parseGSA(talker, p)
+	case "GSV":
+		msg = parseGSV(talker, p)
+	case "VTG":
+		msg = parseVTG(talker, p)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, fields[0])
+	}
+	if p.err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSentence, fields[0], p.err)
+	}
+	return msg, nil
+}
+
+// nmeaChecksum XORs all bytes between '$' and '*'.
+func nmeaChecksum(body string) byte {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return sum
+}
+
+// AppendNMEA wraps body (without '$' and checksum) into a complete sentence with CR/LF.
+func AppendNMEA(dst []byte, body string) []byte {
+	return fmt.Appendf(dst, "$%s*%02X\r\n", body, nmeaChecksum(body))
+}
+
+func parseGGA(talker string, p *fieldParser) *GGA {
+	return &GGA{
+		Talker:      talker,
+		Time:        p.timeOfDay(1),
+		Lat:         p.latLon(2, 3),
+		Lon:         p.latLon(4, 5),
+		Quality:     Quality(p.int(6)),
+		NumSats:     p.int(7),
+		HDOP:        p.float(8),
+		Altitude:    p.float(9),
+		GeoidSep:    p.float(11),
+		DGPSAge:     p.float(13),
+		DGPSStation: p.str(14),
+	}
+}
+
+func parseRMC(talker string, p *fieldParser) *RMC {
+	tod := p.timeOfDay(1)
+	m := &RMC{
+		Talker:     talker,
+		Valid:      p.str(2) == "A",
+		Lat:        p.latLon(3, 4),
+		Lon:        p.latLon(5, 6),
+		SpeedKnots: p.float(7),
+		Course:     p.float(8),
+		MagVar:     p.float(10),
+		Mode:       p.char(12),
+	}
+	if p.str(11) == "W" {
+		m.MagVar = -m.MagVar
+	}
+	if d := p.str(9); len(d) == 6 {
+		day, _ := strconv.Atoi(d[0:2])
+		month, _ := strconv.Atoi(d[2:4])
+		year, _ := strconv.Atoi(d[4:6])
+		// two digit year: pivot at 1980 (start of GPS time)
+		if year < 80 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+		m.Time = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Add(tod)
+	}
+	return m
+}
+
+func parseGSA(talker string, p *fieldParser) *GSA {
+	m := &GSA{
+		Talker:  talker,
+		Auto:    p.str(1) == "A",
+		FixType: FixType(p.int(2)),
+		PDOP:    p.float(15),
+		HDOP:    p.float(16),
+		VDOP:    p.float(17),
+	}
+	for i := 3; i <= 14; i++ {
+		if p.str(i) != "" {
+			m.PRNs = append(m.PRNs, p.int(i))
+		}
+	}
+	return m
+}
+
+func parseGSV(talker string, p *fieldParser) *GSV {
+	m := &GSV{
+		Talker: talker,
+		Total:  p.int(1),
+		Number: p.int(2),
+		InView: p.int(3),
+	}
+	for i := 4; i < len(p.fields); i += 4 {
+		if p.str(i) == "" {
+			continue
+		}
+		snr := -1
+		if p.str(i+3) != "" {
+			snr = p.int(i + 3)
+		}
+		m.Satellites = append(m.Satellites, SatInfo{
+			PRN:       p.int(i),
+			Elevation: p.int(i + 1),
+			Azimuth:   p.int(i + 2),
+			SNR:       snr,
+		})
+	}
+	return m
+}
+
+func parseVTG(talker string, p *fieldParser) *VTG {
+	return &VTG{
+		Talker:     talker,
+		CourseTrue: p.float(1),
+		CourseMag:  p.float(3),
+		SpeedKnots: p.float(5),
+		SpeedKmh:   p.float(7),
+		Mode:       p.char(9),
+	}
+}
+
+// fieldParser extracts typed values from comma separated fields.
+// Empty or missing fields yield zero values; malformed values set err.
+type fieldParser struct {
+	fields []string
+	err    error
+}
+
+func (p *fieldParser) str(i int) string {
+	if i >= len(p.fields) {
+		return ""
+	}
+	return p.fields[i]
+}
+
+func (p *fieldParser) char(i int) byte {
+	s := p.str(i)
+	if s == "" {
+		return 0
+	}
+	return s[0]
+}
+
+func (p *fieldParser) int(i int) int {
+	s := p.str(i)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	return v
+}
+
+func (p *fieldParser) float(i int) float64 {
+	s := p.str(i)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	return v
+}
+
+// timeOfDay parses hhmmss.ss.
+func (p *fieldParser) timeOfDay(i int) time.Duration {
+	s := p.str(i)
+	if len(s) < 6 {
+		return 0
+	}
+	h, err1 := strconv.Atoi(s[0:2])
+	m, err2 := strconv.Atoi(s[2:4])
+	sec, err3 := strconv.ParseFloat(s[4:], 64)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return 0
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second))
+}
+
+// latLon parses ddmm.mmmm / dddmm.mmmm with hemisphere.
+func (p *fieldParser) latLon(i, hemi int) float64 {
+	s := p.str(i)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return 0
+	}
+	deg := float64(int(v / 100))
+	deg += (v - deg*100) / 60
+	switch p.str(hemi) {
+	case "S", "W":
+		deg = -deg
+	}
+	return deg
+}
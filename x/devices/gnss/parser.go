@@ -0,0 +1,114 @@
+package gnss
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	maxNMEALength = 128  // NMEA limits sentences to 82 chars; allow proprietary extensions
+	maxUBXPayload = 4096 // larger than any message we decode
+)
+
+// Parser is a streaming parser for interleaved NMEA 0183 and UBX binary messages.
+type Parser struct {
+	buf []byte
+
+	// Errors counts sentences/messages dropped due to checksum or format errors.
+	Errors int
+}
+
+// Feed appends data to the parser and calls emit for every decoded message.
+// Messages are *GGA, *RMC, *GSA, *GSV, *VTG, *NavPVT, *UBXAck or *UBX.
+// Unsupported NMEA sentences are skipped silently.
+func (p *Parser) Feed(data []byte, emit func(msg any)) {
+	p.buf = append(p.buf, data...)
+	for len(p.buf) > 0 {
+		consumed := p.consumeOne(emit)
+		if consumed == 0 {
+			break
+		}
+		p.buf = p.buf[consumed:]
+	}
+	// Keep the buffer from growing unbounded while compacting its storage.
+	if cap(p.buf) > 4*maxUBXPayload && len(p.buf) < maxUBXPayload {
+		p.buf = append([]byte(nil), p.buf...)
+	}
+}
+
+// consumeOne decodes at most one message at the start of buf.
+// Returns the number of bytes consumed, or 0 if more data is needed.
+func (p *Parser) consumeOne(emit func(msg any)) int {
+	// Skip to the next candidate start
+	i := 0
+	for i < len(p.buf) && p.buf[i] != '$' && p.buf[i] != ubxSync1 {
+		i++
+	}
+	if i > 0 {
+		return i
+	}
+
+	switch p.buf[0] {
+	case '$':
+		end := bytes.IndexByte(p.buf, '\n')
+		if end < 0 {
+			if len(p.buf) > maxNMEALength {
+				p.Errors++
+				return 1
+			}
+			return 0
+		}
+		// A new sentence start before the terminator means this one was truncated
+		if next := bytes.IndexByte(p.buf[1:end], '$'); next >= 0 {
+			p.Errors++
+			return next + 1
+		}
+		msg, err := ParseNMEA(string(p.buf[:end+1]))
+		switch {
+		case err == nil:
+			emit(msg)
+		case !errors.Is(err, ErrUnsupported):
+			p.Errors++
+		}
+		return end + 1
+
+	default: // UBX
+		if len(p.buf) < 2 {
+			return 0
+		}
+		if p.buf[1] != ubxSync2 {
+			return 1
+		}
+		if len(p.buf) < 6 {
+			return 0
+		}
+		n := int(binary.LittleEndian.Uint16(p.buf[4:6]))
+		if n > maxUBXPayload {
+			p.Errors++
+			return 1
+		}
+		total := 8 + n
+		if len(p.buf) < total {
+			return 0
+		}
+		ckA, ckB := ubxChecksum(p.buf[2 : 6+n])
+		if ckA != p.buf[6+n] || ckB != p.buf[7+n] {
+			p.Errors++
+			return 1
+		}
+		raw := UBX{Class: p.buf[2], ID: p.buf[3], Payload: append([]byte(nil), p.buf[6:6+n]...)}
+		msg, err := ParseUBX(raw)
+		if err != nil {
+			p.Errors++
+		} else {
+			emit(msg)
+		}
+		return total
+	}
+}
+
+// Reset discards buffered data.
+func (p *Parser) Reset() {
+	p.buf = p.buf[:0]
+}
@@ -0,0 +1,172 @@
+package gnss
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// UBX protocol constants.
+const (
+	ubxSync1 = 0xB5
+	ubxSync2 = 0x62
+
+	ClassNAV byte = 0x01
+	ClassACK byte = 0x05
+	ClassCFG byte = 0x06
+
+	IDNavPVT byte = 0x07
+	IDAckNak byte = 0x00
+	IDAckAck byte = 0x01
+	IDCfgMsg byte = 0x01
+	IDCfgRat byte = 0x08
+	IDCfgPrt byte = 0x00
+
+	navPVTSize = 92
+)
+
+var (
+	ErrInvalidUBX = errors.New("gnss: invalid UBX message")
+)
+
+// UBX is a raw UBX message.
+type UBX struct {
+	Class   byte
+	ID      byte
+	Payload []byte
+}
+
+// Encode serializes the message with sync chars, length and Fletcher checksum.
+func (m UBX) Encode() []byte {
+	b := make([]byte, 6, 8+len(m.Payload))
+	b[0], b[1], b[2], b[3] = ubxSync1, ubxSync2, m.Class, m.ID
+	binary.LittleEndian.PutUint16(b[4:6], uint16(len(m.Payload)))
+	b = append(b, m.Payload...)
+	ckA, ckB := ubxChecksum(b[2:])
+	return append(b, ckA, ckB)
+}
+
+// ubxChecksum computes the 8-bit Fletcher checksum over class, id, length and payload.
+func ubxChecksum(b []byte) (byte, byte) {
+	var a, c byte
+	for _, v := range b {
+		a += v
+		c += a
+	}
+	return a, c
+}
+
+// UBXAck is an ACK-ACK or ACK-NAK response for a configuration message.
+type UBXAck struct {
+	Ack   bool
+	Class byte
+	ID    byte
+}
+
+// NavPVT is the UBX-NAV-PVT navigation position velocity time solution.
+type NavPVT struct {
+	ITOW      uint32    // GPS time of week (ms)
+	Time      time.Time // UTC
+	ValidDate bool
+	ValidTime bool
+	TimeAcc   uint32 // ns
+	FixType   FixType
+	FixOK     bool
+	DiffSoln  bool
+	CarrSoln  uint8 // 0 none, 1 float, 2 fixed
+	NumSV     int
+	Lon, Lat  float64 // degrees
+	Height    float64 // height above ellipsoid (m)
+	HMSL      float64 // height above mean sea level (m)
+	HAcc      float64 // horizontal accuracy estimate (m)
+	VAcc      float64 // vertical accuracy estimate (m)
+	VelN      float64 // m/s
+	VelE      float64 // m/s
+	VelD      float64 // m/s
+	GSpeed    float64 // ground speed (m/s)
+	HeadMot   float64 // heading of motion (deg)
+	SAcc      float64 // speed accuracy (m/s)
+	HeadAcc   float64 // heading accuracy (deg)
+	PDOP      float64
+}
+
+// ParseUBX decodes a UBX message payload into a typed message.
+// Returns *NavPVT or *UBXAck, or the raw *UBX for other messages.
+func ParseUBX(m UBX) (any, error) {
+	switch {
+	case m.Class == ClassNAV && m.ID == IDNavPVT:
+		return parseNavPVT(m.Payload)
+	case m.Class == ClassACK && (m.ID == IDAckAck || m.ID == IDAckNak):
+		if len(m.Payload) < 2 {
+			return nil, ErrInvalidUBX
+		}
+		return &UBXAck{Ack: m.ID == IDAckAck, Class: m.Payload[0], ID: m.Payload[1]}, nil
+	default:
+		return &m, nil
+	}
+}
+
+func parseNavPVT(p []byte) (*NavPVT, error) {
+	if len(p) < navPVTSize {
+		return nil, ErrInvalidUBX
+	}
+	le := binary.LittleEndian
+	valid := p[11]
+	flags := p[21]
+	m := &NavPVT{
+		ITOW:      le.Uint32(p[0:]),
+		ValidDate: valid&0x01 != 0,
+		ValidTime: valid&0x02 != 0,
+		TimeAcc:   le.Uint32(p[12:]),
+		FixType:   FixType(p[20]),
+		FixOK:     flags&0x01 != 0,
+		DiffSoln:  flags&0x02 != 0,
+		CarrSoln:  flags >> 6,
+		NumSV:     int(p[23]),
+		Lon:       float64(int32(le.Uint32(p[24:]))) * 1e-7,
+		Lat:       float64(int32(le.Uint32(p[28:]))) * 1e-7,
+		Height:    float64(int32(le.Uint32(p[32:]))) * 1e-3,
+		HMSL:      float64(int32(le.Uint32(p[36:]))) * 1e-3,
+		HAcc:      float64(le.Uint32(p[40:])) * 1e-3,
+		VAcc:      float64(le.Uint32(p[44:])) * 1e-3,
+		VelN:      float64(int32(le.Uint32(p[48:]))) * 1e-3,
+		VelE:      float64(int32(le.Uint32(p[52:]))) * 1e-3,
+		VelD:      float64(int32(le.Uint32(p[56:]))) * 1e-3,
+		GSpeed:    float64(int32(le.Uint32(p[60:]))) * 1e-3,
+		HeadMot:   float64(int32(le.Uint32(p[64:]))) * 1e-5,
+		SAcc:      float64(le.Uint32(p[68:])) * 1e-3,
+		HeadAcc:   float64(le.Uint32(p[72:])) * 1e-5,
+		PDOP:      float64(le.Uint16(p[76:])) * 0.01,
+	}
+	nano := int32(le.Uint32(p[16:]))
+	m.Time = time.Date(int(le.Uint16(p[4:])), time.Month(p[6]), int(p[7]), int(p[8]), int(p[9]), int(p[10]), 0, time.UTC).
+		Add(time.Duration(nano))
+	return m, nil
+}
+
+// CfgRate builds a UBX-CFG-RATE message setting the measurement period and navigation ratio.
+func CfgRate(period time.Duration, navRate uint16) UBX {
+	p := make([]byte, 6)
+	binary.LittleEndian.PutUint16(p[0:], uint16(period/time.Millisecond))
+	binary.LittleEndian.PutUint16(p[2:], navRate)
+	binary.LittleEndian.PutUint16(p[4:], 1) // GPS time reference
+	return UBX{Class: ClassCFG, ID: IDCfgRat, Payload: p}
+}
+
+// CfgMsg builds a UBX-CFG-MSG message setting the output rate of class/id on the current port.
+// rate is the number of navigation solutions per message (0 disables).
+func CfgMsg(class, id, rate byte) UBX {
+	return UBX{Class: ClassCFG, ID: IDCfgMsg, Payload: []byte{class, id, rate}}
+}
+
+// CfgPrtUART builds a UBX-CFG-PRT message configuring UART1 for 8N1 at baud with
+// the given input/output protocol masks (bit 0 = UBX, bit 1 = NMEA).
+func CfgPrtUART(baud uint32, inProto, outProto uint16) UBX {
+	p := make([]byte, 20)
+	p[0] = 1                                         // UART1
+	binary.LittleEndian.PutUint32(p[4:], 0x000008C0) // 8 bit, no parity, 1 stop
+	binary.LittleEndian.PutUint32(p[8:], baud)       // baud rate
+	binary.LittleEndian.PutUint16(p[12:], inProto)   // input protocols
+	binary.LittleEndian.PutUint16(p[14:], outProto)  // output protocols
+	return UBX{Class: ClassCFG, ID: IDCfgPrt, Payload: p}
+}
@@ -0,0 +1,59 @@
+package lidar
+
+import (
+	"fmt"
+
+	"github.com/chewxy/math32"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	matTypes "github.com/itohio/EasyRobot/x/math/mat/types"
+)
+
+// ScanToCloud converts a 2xN scan, as passed to OnRead or filled by Read, into
+// an Nx3 point cloud in meters transformed by pose (sensor to world).
+//
+// Angles follow the LD06 convention: 0° is the sensor's front (x axis) and
+// angles increase clockwise, so the sensor frame is x forward, y left, z up.
+// Points without a return (zero distance) are dropped. Use
+// mat.Matrix4x4{}.Eye().(mat.Matrix4x4) as pose to keep the sensor frame.
+// Scans that are not backed by a mat.Matrix are rejected with an error.
+func ScanToCloud(scan matTypes.Matrix, pose mat.Matrix4x4) (mat.Matrix, error) {
+	if scan == nil || scan.Rows() < 2 {
+		return mat.New(0, 3), nil
+	}
+	m, ok := scan.View().(mat.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("lidar.ScanToCloud: unsupported scan matrix %T", scan.View())
+	}
+	return scanToCloud(m, pose), nil
+}
+
+func scanToCloud(m mat.Matrix, pose mat.Matrix4x4) mat.Matrix {
+	n := len(m[0])
+
+	backing := make([]float32, 0, 3*n)
+	for i := 0; i < n; i++ {
+		d := m[0][i] / 1000
+		if d <= 0 {
+			continue
+		}
+		a := m[1][i] * math32.Pi / 180
+		x, y := d*math32.Cos(a), -d*math32.Sin(a)
+		backing = append(backing,
+			pose[0][0]*x+pose[0][1]*y+pose[0][3],
+			pose[1][0]*x+pose[1][1]*y+pose[1][3],
+			pose[2][0]*x+pose[2][1]*y+pose[2][3],
+		)
+	}
+	return mat.New(len(backing)/3, 3, backing...)
+}
+
+// ReadCloud reads the latest scan of dev and converts it like ScanToCloud.
+func ReadCloud(dev Device, pose mat.Matrix4x4) mat.Matrix {
+	n := dev.GetPointCount()
+	if n <= 0 {
+		return mat.New(0, 3)
+	}
+	scan := mat.New(2, n)
+	n = dev.Read(scan)
+	return scanToCloud(mat.Matrix{scan[0][:n], scan[1][:n]}, pose)
+}
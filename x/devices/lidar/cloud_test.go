@@ -0,0 +1,58 @@
+package lidar
+
+import (
+	"testing"
+
+	"github.com/chewxy/math32"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	matTypes "github.com/itohio/EasyRobot/x/math/mat/types"
+)
+
+// foreignScan is a scan implementation whose view is not a mat.Matrix.
+type foreignScan struct{ mat.Matrix }
+
+func (s foreignScan) View() matTypes.Matrix { return s }
+
+func TestScanToCloud(t *testing.T) {
+	// front at 1 m, no return, 90° clockwise (right) at 2 m
+	scan := mat.New(2, 3,
+		1000, 0, 2000,
+		0, 45, 90)
+
+	identity := mat.Matrix4x4{}.Eye().(mat.Matrix4x4)
+	cloud, err := ScanToCloud(scan, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]float32{{1, 0, 0}, {0, -2, 0}}
+	check := func(got mat.Matrix, want [][]float32) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("got %d points, want %d", len(got), len(want))
+		}
+		for i := range want {
+			for j := range want[i] {
+				if math32.Abs(got[i][j]-want[i][j]) > 1e-5 {
+					t.Fatalf("got %v, want %v", got, want)
+				}
+			}
+		}
+	}
+	check(cloud, want)
+
+	// sensor mounted 0.5 m up, turned 90° to the left, at (10, 0)
+	pose := mat.New4x4(
+		0, -1, 0, 10,
+		1, 0, 0, 0,
+		0, 0, 1, 0.5,
+		0, 0, 0, 1)
+	cloud, err = ScanToCloud(scan, pose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(cloud, [][]float32{{10, 1, 0.5}, {12, 0, 0.5}})
+
+	if _, err := ScanToCloud(foreignScan{scan}, identity); err == nil {
+		t.Error("expected an error for a scan that is not a mat.Matrix")
+	}
+}
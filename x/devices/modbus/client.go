@@ -0,0 +1,327 @@
+package modbus
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// Config holds configuration for a Modbus RTU client.
+type Config struct {
+	// BaudRate of the serial line, used to compute inter-frame timing.
+	// Default: 9600
+	BaudRate int
+
+	// Timeout waiting for a response.
+	// Default: 500ms
+	Timeout time.Duration
+
+	// Retries is the number of additional attempts after a timeout or CRC error.
+	// Exception responses are not retried.
+	// Default: 2
+	Retries int
+
+	// TurnaroundDelay is the wait after a broadcast (slave 0) request.
+	// Default: 100ms
+	TurnaroundDelay time.Duration
+}
+
+// DefaultConfig returns a default configuration.
+func DefaultConfig() Config {
+	return Config{
+		BaudRate:        9600,
+		Timeout:         500 * time.Millisecond,
+		Retries:         2,
+		TurnaroundDelay: 100 * time.Millisecond,
+	}
+}
+
+// Client is a Modbus RTU master.
+type Client struct {
+	mu     sync.Mutex
+	serial devices.Serial
+	config Config
+
+	charTime  time.Duration // time of one 11-bit character
+	frameGap  time.Duration // 3.5 character silent interval
+	lastFrame time.Time     // end of the last bus activity
+	buf       []byte
+}
+
+// New creates a new Modbus RTU client over serial.
+func New(serial devices.Serial, config Config) *Client {
+	if config.BaudRate <= 0 {
+		config.BaudRate = 9600
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 500 * time.Millisecond
+	}
+	if config.Retries < 0 {
+		config.Retries = 0
+	}
+
+	// 1 start + 8 data + parity/stop + 1 stop = 11 bits per character
+	charTime := time.Duration(11 * float64(time.Second) / float64(config.BaudRate))
+	frameGap := charTime * 7 / 2
+	// Spec: fixed 1.75ms gap above 19200 baud
+	if config.BaudRate > 19200 {
+		frameGap = 1750 * time.Microsecond
+	}
+
+	return &Client{
+		serial:   serial,
+		config:   config,
+		charTime: charTime,
+		frameGap: frameGap,
+		buf:      make([]byte, maxADU),
+	}
+}
+
+// ReadCoils reads quantity coils starting at addr (function 0x01).
+func (c *Client) ReadCoils(slave byte, addr, quantity uint16) ([]bool, error) {
+	return c.readBits(slave, FuncReadCoils, addr, quantity)
+}
+
+// ReadDiscreteInputs reads quantity discrete inputs starting at addr (function 0x02).
+func (c *Client) ReadDiscreteInputs(slave byte, addr, quantity uint16) ([]bool, error) {
+	return c.readBits(slave, FuncReadDiscreteInputs, addr, quantity)
+}
+
+// ReadHoldingRegisters reads quantity holding registers starting at addr (function 0x03).
+func (c *Client) ReadHoldingRegisters(slave byte, addr, quantity uint16) ([]uint16, error) {
+	return c.readRegisters(slave, FuncReadHoldingRegisters, addr, quantity)
+}
+
+// ReadInputRegisters reads quantity input registers starting at addr (function 0x04).
+func (c *Client) ReadInputRegisters(slave byte, addr, quantity uint16) ([]uint16, error) {
+	return c.readRegisters(slave, FuncReadInputRegisters, addr, quantity)
+}
+
+// WriteSingleCoil writes a single coil (function 0x05).
+func (c *Client) WriteSingleCoil(slave byte, addr uint16, value bool) error {
+	data := make([]byte, 4)
+	putUint16(data[0:], addr)
+	if value {
+		putUint16(data[2:], 0xFF00)
+	}
+	return c.write(slave, FuncWriteSingleCoil, data)
+}
+
+// WriteSingleRegister writes a single holding register (function 0x06).
+func (c *Client) WriteSingleRegister(slave byte, addr, value uint16) error {
+	data := make([]byte, 4)
+	putUint16(data[0:], addr)
+	putUint16(data[2:], value)
+	return c.write(slave, FuncWriteSingleRegister, data)
+}
+
+// WriteMultipleCoils writes consecutive coils starting at addr (function 0x0F).
+func (c *Client) WriteMultipleCoils(slave byte, addr uint16, values []bool) error {
+	if len(values) == 0 || len(values) > MaxWriteBits {
+		return fmt.Errorf("modbus: coil count %d: %w", len(values), devices.ErrInvalidSize)
+	}
+	packed := packBits(values)
+	data := make([]byte, 5, 5+len(packed))
+	putUint16(data[0:], addr)
+	putUint16(data[2:], uint16(len(values)))
+	data[4] = byte(len(packed))
+	data = append(data, packed...)
+	return c.write(slave, FuncWriteMultipleCoils, data)
+}
+
+// WriteMultipleRegisters writes consecutive holding registers starting at addr (function 0x10).
+func (c *Client) WriteMultipleRegisters(slave byte, addr uint16, values []uint16) error {
+	if len(values) == 0 || len(values) > MaxWriteRegisters {
+		return fmt.Errorf("modbus: register count %d: %w", len(values), devices.ErrInvalidSize)
+	}
+	data := make([]byte, 5+2*len(values))
+	putUint16(data[0:], addr)
+	putUint16(data[2:], uint16(len(values)))
+	data[4] = byte(2 * len(values))
+	for i, v := range values {
+		putUint16(data[5+2*i:], v)
+	}
+	return c.write(slave, FuncWriteMultipleRegisters, data)
+}
+
+func (c *Client) readBits(slave, fn byte, addr, quantity uint16) ([]bool, error) {
+	if quantity == 0 || quantity > MaxReadBits {
+		return nil, fmt.Errorf("modbus: bit count %d: %w", quantity, devices.ErrInvalidSize)
+	}
+	data := make([]byte, 4)
+	putUint16(data[0:], addr)
+	putUint16(data[2:], quantity)
+	resp, err := c.Do(Frame{Slave: slave, Function: fn, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	want := (int(quantity) + 7) / 8
+	if len(resp.Data) < 1 || int(resp.Data[0]) != want || len(resp.Data) != 1+want {
+		return nil, ErrUnexpectedReply
+	}
+	return unpackBits(resp.Data[1:], int(quantity)), nil
+}
+
+func (c *Client) readRegisters(slave, fn byte, addr, quantity uint16) ([]uint16, error) {
+	if quantity == 0 || quantity > MaxReadRegisters {
+		return nil, fmt.Errorf("modbus: register count %d: %w", quantity, devices.ErrInvalidSize)
+	}
+	data := make([]byte, 4)
+	putUint16(data[0:], addr)
+	putUint16(data[2:], quantity)
+	resp, err := c.Do(Frame{Slave: slave, Function: fn, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	want := 2 * int(quantity)
+	if len(resp.Data) < 1 || int(resp.Data[0]) != want || len(resp.Data) != 1+want {
+		return nil, ErrUnexpectedReply
+	}
+	regs := make([]uint16, quantity)
+	for i := range regs {
+		regs[i] = getUint16(resp.Data[1+2*i:])
+	}
+	return regs, nil
+}
+
+func (c *Client) write(slave, fn byte, data []byte) error {
+	resp, err := c.Do(Frame{Slave: slave, Function: fn, Data: data})
+	if err != nil {
+		return err
+	}
+	if slave == 0 {
+		return nil
+	}
+	// Echo of address and value/quantity
+	if len(resp.Data) != 4 || getUint16(resp.Data[0:]) != getUint16(data[0:]) || getUint16(resp.Data[2:]) != getUint16(data[2:]) {
+		return ErrUnexpectedReply
+	}
+	return nil
+}
+
+// Do sends a request frame and returns the response, retrying on timeouts and CRC errors.
+// Broadcast requests (slave 0) return an empty frame after TurnaroundDelay.
+func (c *Client) Do(req Frame) (Frame, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	adu := req.Encode()
+	var lastErr error
+	for attempt := 0; attempt <= c.config.Retries; attempt++ {
+		resp, err := c.transact(req, adu)
+		if err == nil {
+			return resp, nil
+		}
+		var exc *ExceptionError
+		if errors.As(err, &exc) {
+			return Frame{}, err
+		}
+		lastErr = err
+	}
+	return Frame{}, fmt.Errorf("modbus: slave %d function 0x%02X failed after %d attempts: %w", req.Slave, req.Function, c.config.Retries+1, lastErr)
+}
+
+func (c *Client) transact(req Frame, adu []byte) (Frame, error) {
+	c.flush()
+
+	// Wait for the inter-frame silent interval
+	if wait := c.frameGap - time.Since(c.lastFrame); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	if _, err := c.serial.Write(adu); err != nil {
+		return Frame{}, err
+	}
+	// Account for transmit time of the request
+	c.lastFrame = time.Now().Add(time.Duration(len(adu)) * c.charTime)
+
+	if req.Slave == 0 {
+		time.Sleep(c.config.TurnaroundDelay)
+		c.lastFrame = time.Now()
+		return Frame{}, nil
+	}
+
+	raw, err := c.recv()
+	c.lastFrame = time.Now()
+	if err != nil {
+		return Frame{}, err
+	}
+	resp, err := Decode(raw)
+	if err != nil {
+		return Frame{}, err
+	}
+	if resp.Slave != req.Slave || resp.Function&^exceptionFlag != req.Function {
+		return Frame{}, ErrUnexpectedReply
+	}
+	if resp.IsException() {
+		code := byte(0)
+		if len(resp.Data) > 0 {
+			code = resp.Data[0]
+		}
+		return Frame{}, &ExceptionError{Function: req.Function, Code: code}
+	}
+	return resp, nil
+}
+
+// recv reads a response frame using function-code based length detection.
+func (c *Client) recv() ([]byte, error) {
+	deadline := time.Now().Add(c.config.Timeout)
+	read := 0
+	need := 0
+	for {
+		if time.Now().After(deadline) {
+			return nil, devices.ErrTimeout
+		}
+		n, err := c.serial.Read(c.buf[read:])
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if n == 0 {
+			time.Sleep(c.charTime)
+			continue
+		}
+		read += n
+
+		if need == 0 {
+			need = ResponseLength(c.buf[:read])
+			if need < 0 || need > maxADU {
+				return nil, ErrUnexpectedReply
+			}
+		}
+		if need > 0 && read >= need {
+			return append([]byte(nil), c.buf[:need]...), nil
+		}
+	}
+}
+
+// flush discards any stale bytes in the receive buffer.
+func (c *Client) flush() {
+	for {
+		n, _ := c.serial.Read(c.buf)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func packBits(values []bool) []byte {
+	out := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
+
+func unpackBits(b []byte, n int) []bool {
+	out := make([]bool, n)
+	for i := range out {
+		out[i] = b[i/8]&(1<<(i%8)) != 0
+	}
+	return out
+}
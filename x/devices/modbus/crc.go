@@ -0,0 +1,42 @@
+package modbus
+
+// crcTable is the CRC-16/MODBUS lookup table (reflected polynomial 0xA001).
+var crcTable = func() (t [256]uint16) {
+	for i := range t {
+		crc := uint16(i)
+		for j := 0; j < 8; j++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+		t[i] = crc
+	}
+	return
+}()
+
+// CRC16 calculates CRC-16/MODBUS (init 0xFFFF).
+func CRC16(b []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, v := range b {
+		crc = crc>>8 ^ crcTable[byte(crc)^v]
+	}
+	return crc
+}
+
+// AppendCRC appends the CRC (low byte first) to b.
+func AppendCRC(b []byte) []byte {
+	crc := CRC16(b)
+	return append(b, byte(crc), byte(crc>>8))
+}
+
+// CheckCRC reports whether the last two bytes of adu are a valid CRC of the rest.
+func CheckCRC(adu []byte) bool {
+	if len(adu) < 3 {
+		return false
+	}
+	n := len(adu) - 2
+	crc := CRC16(adu[:n])
+	return adu[n] == byte(crc) && adu[n+1] == byte(crc>>8)
+}
@@ -0,0 +1,107 @@
+package modbus
+
+import "math"
+
+// WordOrder defines how 32-bit values are split across two 16-bit registers.
+// Bytes within a register are always big-endian per the Modbus specification.
+type WordOrder int
+
+const (
+	// HighWordFirst stores the most significant word in the lower register address (ABCD).
+	HighWordFirst WordOrder = iota
+	// LowWordFirst stores the least significant word in the lower register address (CDAB).
+	LowWordFirst
+)
+
+// Int16 decodes a signed 16-bit value from a register.
+func Int16(reg uint16) int16 {
+	return int16(reg)
+}
+
+// Uint32 decodes an unsigned 32-bit value from two registers.
+func Uint32(regs []uint16, order WordOrder) uint32 {
+	hi, lo := regs[0], regs[1]
+	if order == LowWordFirst {
+		hi, lo = lo, hi
+	}
+	return uint32(hi)<<16 | uint32(lo)
+}
+
+// Int32 decodes a signed 32-bit value from two registers.
+func Int32(regs []uint16, order WordOrder) int32 {
+	return int32(Uint32(regs, order))
+}
+
+// Float32 decodes an IEEE-754 float from two registers.
+func Float32(regs []uint16, order WordOrder) float32 {
+	return math.Float32frombits(Uint32(regs, order))
+}
+
+// PutUint32 encodes v into two registers.
+func PutUint32(regs []uint16, v uint32, order WordOrder) {
+	hi, lo := uint16(v>>16), uint16(v)
+	if order == LowWordFirst {
+		hi, lo = lo, hi
+	}
+	regs[0], regs[1] = hi, lo
+}
+
+// PutInt32 encodes v into two registers.
+func PutInt32(regs []uint16, v int32, order WordOrder) {
+	PutUint32(regs, uint32(v), order)
+}
+
+// PutFloat32 encodes v into two registers.
+func PutFloat32(regs []uint16, v float32, order WordOrder) {
+	PutUint32(regs, math.Float32bits(v), order)
+}
+
+// ReadInt16 reads a signed 16-bit holding register.
+func (c *Client) ReadInt16(slave byte, addr uint16) (int16, error) {
+	regs, err := c.ReadHoldingRegisters(slave, addr, 1)
+	if err != nil {
+		return 0, err
+	}
+	return Int16(regs[0]), nil
+}
+
+// ReadUint32 reads an unsigned 32-bit value from two holding registers.
+func (c *Client) ReadUint32(slave byte, addr uint16, order WordOrder) (uint32, error) {
+	regs, err := c.ReadHoldingRegisters(slave, addr, 2)
+	if err != nil {
+		return 0, err
+	}
+	return Uint32(regs, order), nil
+}
+
+// ReadInt32 reads a signed 32-bit value from two holding registers.
+func (c *Client) ReadInt32(slave byte, addr uint16, order WordOrder) (int32, error) {
+	regs, err := c.ReadHoldingRegisters(slave, addr, 2)
+	if err != nil {
+		return 0, err
+	}
+	return Int32(regs, order), nil
+}
+
+// ReadFloat32 reads an IEEE-754 float from two holding registers.
+func (c *Client) ReadFloat32(slave byte, addr uint16, order WordOrder) (float32, error) {
+	regs, err := c.ReadHoldingRegisters(slave, addr, 2)
+	if err != nil {
+		return 0, err
+	}
+	return Float32(regs, order), nil
+}
+
+// WriteUint32 writes an unsigned 32-bit value to two holding registers.
+func (c *Client) WriteUint32(slave byte, addr uint16, v uint32, order WordOrder) error {
+	regs := make([]uint16, 2)
+	PutUint32(regs, v, order)
+	return c.WriteMultipleRegisters(slave, addr, regs)
+}
+
+// WriteFloat32 writes an IEEE-754 float to two holding registers.
+func (c *Client) WriteFloat32(slave byte, addr uint16, v float32, order WordOrder) error {
+	regs := make([]uint16, 2)
+	PutFloat32(regs, v, order)
+	return c.WriteMultipleRegisters(slave, addr, regs)
+}
@@ -0,0 +1,219 @@
+// Package modbus implements a Modbus RTU master over devices.Serial.
+//
+// Frames are framed with CRC16 (poly 0xA001) and separated by a 3.5 character
+// silent interval. Function codes 1-6, 15 and 16 are supported.
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Function codes.
+const (
+	FuncReadCoils              byte = 0x01
+	FuncReadDiscreteInputs     byte = 0x02
+	FuncReadHoldingRegisters   byte = 0x03
+	FuncReadInputRegisters     byte = 0x04
+	FuncWriteSingleCoil        byte = 0x05
+	FuncWriteSingleRegister    byte = 0x06
+	FuncWriteMultipleCoils     byte = 0x0F
+	FuncWriteMultipleRegisters byte = 0x10
+
+	exceptionFlag byte = 0x80
+)
+
+// Exception codes.
+const (
+	ExceptionIllegalFunction    byte = 0x01
+	ExceptionIllegalDataAddress byte = 0x02
+	ExceptionIllegalDataValue   byte = 0x03
+	ExceptionSlaveDeviceFailure byte = 0x04
+	ExceptionAcknowledge        byte = 0x05
+	ExceptionSlaveDeviceBusy    byte = 0x06
+)
+
+// Protocol limits (Modbus Application Protocol V1.1b3).
+const (
+	MaxReadBits       = 2000
+	MaxWriteBits      = 1968
+	MaxReadRegisters  = 125
+	MaxWriteRegisters = 123
+
+	maxADU = 256
+)
+
+var (
+	ErrInvalidCRC      = errors.New("modbus: invalid CRC")
+	ErrShortFrame      = errors.New("modbus: frame too short")
+	ErrUnexpectedReply = errors.New("modbus: unexpected reply")
+)
+
+// ExceptionError is returned when a slave responds with an exception.
+type ExceptionError struct {
+	Function byte
+	Code     byte
+}
+
+func (e *ExceptionError) Error() string {
+	return fmt.Sprintf("modbus: exception 0x%02X for function 0x%02X (%s)", e.Code, e.Function, exceptionName(e.Code))
+}
+
+func exceptionName(code byte) string {
+	switch code {
+	case ExceptionIllegalFunction:
+		return "illegal function"
+	case ExceptionIllegalDataAddress:
+		return "illegal data address"
+	case ExceptionIllegalDataValue:
+		return "illegal data value"
+	case ExceptionSlaveDeviceFailure:
+		return "slave device failure"
+	case ExceptionAcknowledge:
+		return "acknowledge"
+	case ExceptionSlaveDeviceBusy:
+		return "slave device busy"
+	default:
+		return "unknown"
+	}
+}
+
+// Frame is a Modbus RTU application data unit without CRC.
+type Frame struct {
+	Slave    byte
+	Function byte
+	Data     []byte
+}
+
+// Encode serializes the frame and appends the CRC.
+func (f Frame) Encode() []byte {
+	b := make([]byte, 0, len(f.Data)+4)
+	b = append(b, f.Slave, f.Function)
+	b = append(b, f.Data...)
+	return AppendCRC(b)
+}
+
+// IsException reports whether the frame is an exception response.
+func (f Frame) IsException() bool {
+	return f.Function&exceptionFlag != 0
+}
+
+// Decode parses an RTU frame, validating its CRC.
+func Decode(adu []byte) (Frame, error) {
+	if len(adu) < 4 {
+		return Frame{}, ErrShortFrame
+	}
+	if !CheckCRC(adu) {
+		return Frame{}, ErrInvalidCRC
+	}
+	return Frame{
+		Slave:    adu[0],
+		Function: adu[1],
+		Data:     append([]byte(nil), adu[2:len(adu)-2]...),
+	}, nil
+}
+
+// RequestLength returns the total length (including CRC) of a request frame
+// from its first bytes. Returns 0 if more bytes are needed to decide and -1
+// for unknown function codes.
+func RequestLength(b []byte) int {
+	if len(b) < 2 {
+		return 0
+	}
+	switch b[1] {
+	case FuncReadCoils, FuncReadDiscreteInputs, FuncReadHoldingRegisters, FuncReadInputRegisters,
+		FuncWriteSingleCoil, FuncWriteSingleRegister:
+		return 8
+	case FuncWriteMultipleCoils, FuncWriteMultipleRegisters:
+		if len(b) < 7 {
+			return 0
+		}
+		return 9 + int(b[6])
+	default:
+		return -1
+	}
+}
+
+// ResponseLength returns the total length (including CRC) of a response frame
+// from its first bytes. Returns 0 if more bytes are needed to decide and -1
+// for unknown function codes.
+func ResponseLength(b []byte) int {
+	if len(b) < 2 {
+		return 0
+	}
+	if b[1]&exceptionFlag != 0 {
+		return 5
+	}
+	switch b[1] {
+	case FuncReadCoils, FuncReadDiscreteInputs, FuncReadHoldingRegisters, FuncReadInputRegisters:
+		if len(b) < 3 {
+			return 0
+		}
+		return 5 + int(b[2])
+	case FuncWriteSingleCoil, FuncWriteSingleRegister, FuncWriteMultipleCoils, FuncWriteMultipleRegisters:
+		return 8
+	default:
+		return -1
+	}
+}
+
+// Scanner extracts RTU frames from a sniffed byte stream containing both
+// requests and responses. Framing is recovered from function-code lengths and CRC.
+type Scanner struct {
+	buf []byte
+}
+
+// Feed appends bytes to the scanner and returns all complete, CRC-valid frames.
+// isResponse reports for each frame whether it was decoded as a response.
+func (s *Scanner) Feed(data []byte, emit func(f Frame, raw []byte, isResponse bool)) {
+	s.buf = append(s.buf, data...)
+	for len(s.buf) >= 4 {
+		reqLen, respLen := RequestLength(s.buf), ResponseLength(s.buf)
+		if reqLen == 0 || respLen == 0 {
+			return
+		}
+		matched := false
+		for _, c := range []struct {
+			n    int
+			resp bool
+		}{{reqLen, false}, {respLen, true}} {
+			if c.n < 4 || c.n > maxADU {
+				continue
+			}
+			if len(s.buf) < c.n {
+				// wait for more data unless the other interpretation already matches
+				continue
+			}
+			if CheckCRC(s.buf[:c.n]) {
+				raw := append([]byte(nil), s.buf[:c.n]...)
+				f, _ := Decode(raw)
+				emit(f, raw, c.resp)
+				s.buf = s.buf[c.n:]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if len(s.buf) < max(reqLen, respLen) && len(s.buf) < maxADU {
+			return
+		}
+		// resynchronize
+		s.buf = s.buf[1:]
+	}
+}
+
+// Reset discards buffered bytes.
+func (s *Scanner) Reset() {
+	s.buf = s.buf[:0]
+}
+
+func putUint16(b []byte, v uint16) {
+	binary.BigEndian.PutUint16(b, v)
+}
+
+func getUint16(b []byte) uint16 {
+	return binary.BigEndian.Uint16(b)
+}
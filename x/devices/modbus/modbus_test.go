@@ -0,0 +1,187 @@
+package modbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+func newTestClient(sim *Simulator) *Client {
+	cfg := DefaultConfig()
+	cfg.BaudRate = 115200
+	cfg.Timeout = 20 * time.Millisecond
+	return New(sim, cfg)
+}
+
+func TestCRC16(t *testing.T) {
+	// Read holding registers slave 1, addr 0, qty 10 -> well-known CRC C5CD
+	adu := AppendCRC([]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A})
+	if adu[6] != 0xC5 || adu[7] != 0xCD {
+		t.Fatalf("got CRC %02X%02X, want C5CD", adu[6], adu[7])
+	}
+	if !CheckCRC(adu) {
+		t.Fatal("CRC check failed")
+	}
+	adu[2] ^= 1
+	if CheckCRC(adu) {
+		t.Fatal("corrupted frame passed CRC check")
+	}
+}
+
+func TestRegisters(t *testing.T) {
+	sim := NewSimulator(1)
+	c := newTestClient(sim)
+
+	if err := c.WriteSingleRegister(1, 10, 0xFFFE); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.ReadInt16(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != -2 {
+		t.Fatalf("got %d, want -2", v)
+	}
+
+	if err := c.WriteMultipleRegisters(1, 100, []uint16{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	regs, err := c.ReadHoldingRegisters(1, 100, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regs[0] != 1 || regs[1] != 2 || regs[2] != 3 {
+		t.Fatalf("got %v", regs)
+	}
+
+	sim.InputRegisters[5] = 42
+	regs, err = c.ReadInputRegisters(1, 5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regs[0] != 42 {
+		t.Fatalf("got %v", regs)
+	}
+}
+
+func TestCoils(t *testing.T) {
+	sim := NewSimulator(2)
+	c := newTestClient(sim)
+
+	want := []bool{true, false, true, true, false, false, false, false, true, true}
+	if err := c.WriteMultipleCoils(2, 20, want); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteSingleCoil(2, 21, true); err != nil {
+		t.Fatal(err)
+	}
+	want[1] = true
+
+	got, err := c.ReadCoils(2, 20, uint16(len(want)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("coil %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	sim.DiscreteInputs[0] = true
+	in, err := c.ReadDiscreteInputs(2, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in[0] {
+		t.Fatal("expected discrete input set")
+	}
+}
+
+func TestTypedDecoding(t *testing.T) {
+	sim := NewSimulator(1)
+	c := newTestClient(sim)
+
+	for _, order := range []WordOrder{HighWordFirst, LowWordFirst} {
+		if err := c.WriteFloat32(1, 0, 3.25, order); err != nil {
+			t.Fatal(err)
+		}
+		f, err := c.ReadFloat32(1, 0, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f != 3.25 {
+			t.Fatalf("order %d: got %v", order, f)
+		}
+
+		if err := c.WriteUint32(1, 2, 0x12345678, order); err != nil {
+			t.Fatal(err)
+		}
+		u, err := c.ReadUint32(1, 2, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != 0x12345678 {
+			t.Fatalf("order %d: got 0x%X", order, u)
+		}
+	}
+
+	// Word order matters
+	regs := []uint16{0x1234, 0x5678}
+	if Uint32(regs, HighWordFirst) != 0x12345678 || Uint32(regs, LowWordFirst) != 0x56781234 {
+		t.Fatal("word order decoding mismatch")
+	}
+}
+
+func TestRetriesAndExceptions(t *testing.T) {
+	sim := NewSimulator(1)
+	sim.HoldingRegisters[0] = 7
+	c := newTestClient(sim)
+
+	sim.DropResponses = 1
+	sim.CorruptResponses = 1
+	regs, err := c.ReadHoldingRegisters(1, 0, 1)
+	if err != nil {
+		t.Fatalf("expected retry to succeed: %v", err)
+	}
+	if regs[0] != 7 {
+		t.Fatalf("got %v", regs)
+	}
+
+	sim.DropResponses = 3
+	_, err = c.ReadHoldingRegisters(1, 0, 1)
+	if !errors.Is(err, devices.ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	_, err = c.ReadHoldingRegisters(1, 1000, 1)
+	var exc *ExceptionError
+	if !errors.As(err, &exc) || exc.Code != ExceptionIllegalDataAddress {
+		t.Fatalf("expected illegal data address exception, got %v", err)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	req := Frame{Slave: 1, Function: FuncReadHoldingRegisters, Data: []byte{0, 0, 0, 2}}.Encode()
+	resp := Frame{Slave: 1, Function: FuncReadHoldingRegisters, Data: []byte{4, 0, 1, 0, 2}}.Encode()
+	stream := append([]byte{0x00, 0x55}, req...)
+	stream = append(stream, resp...)
+
+	var s Scanner
+	var frames []Frame
+	var kinds []bool
+	// feed byte by byte to exercise partial frames
+	for _, b := range stream {
+		s.Feed([]byte{b}, func(f Frame, raw []byte, isResponse bool) {
+			frames = append(frames, f)
+			kinds = append(kinds, isResponse)
+		})
+	}
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	if kinds[0] || !kinds[1] {
+		t.Fatalf("unexpected frame kinds %v", kinds)
+	}
+}
@@ -0,0 +1,187 @@
+package modbus
+
+import (
+	"sync"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// Simulator is an in-process Modbus RTU slave that implements devices.Serial.
+// Requests written to it are answered from its data tables, so a Client can be
+// exercised without hardware.
+type Simulator struct {
+	mu sync.Mutex
+
+	// Address is the slave address the simulator answers to.
+	Address byte
+
+	Coils            map[uint16]bool
+	DiscreteInputs   map[uint16]bool
+	HoldingRegisters map[uint16]uint16
+	InputRegisters   map[uint16]uint16
+
+	// DropResponses makes the simulator ignore the next N requests (simulates timeouts).
+	DropResponses int
+	// CorruptResponses makes the simulator corrupt the CRC of the next N responses.
+	CorruptResponses int
+
+	rx []byte
+	tx []byte
+}
+
+// Ensure Simulator implements devices.Serial interface
+var _ devices.Serial = (*Simulator)(nil)
+
+// NewSimulator creates a simulator for the given slave address with empty tables.
+// Reads of unset addresses return an illegal data address exception.
+func NewSimulator(address byte) *Simulator {
+	return &Simulator{
+		Address:          address,
+		Coils:            make(map[uint16]bool),
+		DiscreteInputs:   make(map[uint16]bool),
+		HoldingRegisters: make(map[uint16]uint16),
+		InputRegisters:   make(map[uint16]uint16),
+	}
+}
+
+// Read returns pending response bytes.
+func (s *Simulator) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := copy(p, s.tx)
+	s.tx = s.tx[n:]
+	return n, nil
+}
+
+// Write accepts request bytes and queues a response once a full frame is received.
+func (s *Simulator) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rx = append(s.rx, p...)
+	for {
+		n := RequestLength(s.rx)
+		if n < 0 {
+			s.rx = s.rx[:0]
+			break
+		}
+		if n == 0 || len(s.rx) < n {
+			break
+		}
+		adu := s.rx[:n]
+		s.rx = s.rx[n:]
+
+		req, err := Decode(adu)
+		if err != nil {
+			continue
+		}
+		if req.Slave != s.Address && req.Slave != 0 {
+			continue
+		}
+		resp := s.handle(req)
+		if req.Slave == 0 {
+			continue
+		}
+		if s.DropResponses > 0 {
+			s.DropResponses--
+			continue
+		}
+		out := resp.Encode()
+		if s.CorruptResponses > 0 {
+			s.CorruptResponses--
+			out[len(out)-1] ^= 0xFF
+		}
+		s.tx = append(s.tx, out...)
+	}
+	return len(p), nil
+}
+
+// Buffered returns the number of pending response bytes.
+func (s *Simulator) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tx)
+}
+
+func (s *Simulator) handle(req Frame) Frame {
+	exception := func(code byte) Frame {
+		return Frame{Slave: req.Slave, Function: req.Function | exceptionFlag, Data: []byte{code}}
+	}
+	if len(req.Data) < 4 {
+		return exception(ExceptionIllegalDataValue)
+	}
+	addr := getUint16(req.Data[0:])
+	value := getUint16(req.Data[2:])
+
+	switch req.Function {
+	case FuncReadCoils, FuncReadDiscreteInputs:
+		table := s.Coils
+		if req.Function == FuncReadDiscreteInputs {
+			table = s.DiscreteInputs
+		}
+		if value == 0 || value > MaxReadBits {
+			return exception(ExceptionIllegalDataValue)
+		}
+		bits := make([]bool, value)
+		for i := range bits {
+			v, ok := table[addr+uint16(i)]
+			if !ok {
+				return exception(ExceptionIllegalDataAddress)
+			}
+			bits[i] = v
+		}
+		packed := packBits(bits)
+		return Frame{Slave: req.Slave, Function: req.Function, Data: append([]byte{byte(len(packed))}, packed...)}
+
+	case FuncReadHoldingRegisters, FuncReadInputRegisters:
+		table := s.HoldingRegisters
+		if req.Function == FuncReadInputRegisters {
+			table = s.InputRegisters
+		}
+		if value == 0 || value > MaxReadRegisters {
+			return exception(ExceptionIllegalDataValue)
+		}
+		data := make([]byte, 1+2*int(value))
+		data[0] = byte(2 * value)
+		for i := 0; i < int(value); i++ {
+			v, ok := table[addr+uint16(i)]
+			if !ok {
+				return exception(ExceptionIllegalDataAddress)
+			}
+			putUint16(data[1+2*i:], v)
+		}
+		return Frame{Slave: req.Slave, Function: req.Function, Data: data}
+
+	case FuncWriteSingleCoil:
+		if value != 0 && value != 0xFF00 {
+			return exception(ExceptionIllegalDataValue)
+		}
+		s.Coils[addr] = value == 0xFF00
+		return Frame{Slave: req.Slave, Function: req.Function, Data: req.Data[:4]}
+
+	case FuncWriteSingleRegister:
+		s.HoldingRegisters[addr] = value
+		return Frame{Slave: req.Slave, Function: req.Function, Data: req.Data[:4]}
+
+	case FuncWriteMultipleCoils:
+		if len(req.Data) < 5 || int(req.Data[4]) != (int(value)+7)/8 || len(req.Data) != 5+int(req.Data[4]) {
+			return exception(ExceptionIllegalDataValue)
+		}
+		for i, v := range unpackBits(req.Data[5:], int(value)) {
+			s.Coils[addr+uint16(i)] = v
+		}
+		return Frame{Slave: req.Slave, Function: req.Function, Data: req.Data[:4]}
+
+	case FuncWriteMultipleRegisters:
+		if len(req.Data) < 5 || int(req.Data[4]) != 2*int(value) || len(req.Data) != 5+int(req.Data[4]) {
+			return exception(ExceptionIllegalDataValue)
+		}
+		for i := 0; i < int(value); i++ {
+			s.HoldingRegisters[addr+uint16(i)] = getUint16(req.Data[5+2*i:])
+		}
+		return Frame{Slave: req.Slave, Function: req.Function, Data: req.Data[:4]}
+
+	default:
+		return exception(ExceptionIllegalFunction)
+	}
+}
@@ -1,6 +1,7 @@
 package motor
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -121,8 +122,105 @@ func (a *MotorArray) TargetSpeeds() []float32 {
 	return speeds
 }
 
+// Motor returns the i-th motor of the array.
+func (a *MotorArray) Motor(i int) *Motor {
+	return a.motors[i]
+}
+
+// MoveTo moves all motors to positions (revolutions) so that they start and
+// arrive together. Each axis' limits are scaled in time to match the slowest
+// axis, keeping the trajectories shape-preserving. Motors are expected to be
+// at rest when the move starts.
+func (a *MotorArray) MoveTo(positions []float32) error {
+	if len(positions) != len(a.motors) {
+		return fmt.Errorf("position count mismatch: got %d, expected %d", len(positions), len(a.motors))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Hold every motor while the move is planned so they all start on the same cycle
+	for _, motor := range a.motors {
+		motor.mu.Lock()
+		defer motor.mu.Unlock()
+	}
+
+	durations := make([]float32, len(a.motors))
+	var longest float32
+	for i, motor := range a.motors {
+		if motor.fault != nil {
+			return fmt.Errorf("motor %d: %w", i, motor.fault)
+		}
+		durations[i] = motor.moveDuration(positions[i])
+		if durations[i] > longest {
+			longest = durations[i]
+		}
+	}
+
+	for i, motor := range a.motors {
+		r := float32(1)
+		if longest > 0 && durations[i] > 0 {
+			r = durations[i] / longest
+		}
+		if err := motor.moveTo(positions[i], r); err != nil {
+			return fmt.Errorf("failed to move motor %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Wait blocks until all motors are in position, one faults or ctx is done.
+func (a *MotorArray) Wait(ctx context.Context) error {
+	for i, motor := range a.motors {
+		if err := motor.Wait(ctx); err != nil {
+			return fmt.Errorf("motor %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Home homes the motors one after another.
+// Motors without a limit pin are skipped.
+func (a *MotorArray) Home(ctx context.Context) error {
+	for i, motor := range a.motors {
+		if motor.config.Limit == nil {
+			continue
+		}
+		if err := motor.Home(ctx); err != nil {
+			return fmt.Errorf("failed to home motor %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Positions returns the current positions for all motors in the array (revolutions).
+func (a *MotorArray) Positions() []float32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	positions := make([]float32, len(a.motors))
+	for i, motor := range a.motors {
+		positions[i] = motor.Position()
+	}
+
+	return positions
+}
+
+// Faults returns the active fault of every motor (nil when healthy).
+func (a *MotorArray) Faults() []error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	faults := make([]error, len(a.motors))
+	for i, motor := range a.motors {
+		faults[i] = motor.Fault()
+	}
+
+	return faults
+}
+
 // Close stops all motors and cleans up resources.
 func (a *MotorArray) Close() error {
 	return a.Disable()
 }
-
@@ -1,15 +1,32 @@
 package motor
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/itohio/EasyRobot/x/devices"
+	vaj "github.com/itohio/EasyRobot/x/math/control/motion"
 	"github.com/itohio/EasyRobot/x/math/control/pid"
 )
 
-// Motor represents a motor with PID speed control.
+// currentRecovery is the time in seconds over which current foldback releases.
+const currentRecovery = 0.1
+
+// homingBackoff divides the homing speed while backing off the limit switch.
+const homingBackoff = 4
+
+// homing is the state of the homing sequence.
+type homing int
+
+const (
+	homingIdle    homing = iota
+	homingSeek           // running towards the limit switch
+	homingRelease        // backing off until the switch releases
+)
+
+// Motor represents a motor with cascaded position, speed and current control.
 // It reads encoder feedback and controls PWM and direction.
 type Motor struct {
 	mu sync.Mutex
@@ -25,10 +42,25 @@ type Motor struct {
 	// PID controller for speed control
 	pid pid.PID1D
 
+	// Position and current loops
+	mode       Mode
+	posPID     pid.PID1D
+	curPID     pid.PID1D
+	currentPID bool // current loop enabled
+	profile    vaj.VAJ1D
+	limits     Limits
+
 	// Current state
 	targetSpeed  float32 // Target speed in RPM (can be negative for reverse)
 	currentSpeed float32 // Current speed in RPM (from encoder)
 	direction    int     // Current direction: 1 = forward, -1 = reverse, 0 = stopped
+	position     float32 // Position in revolutions
+	offset       float32 // Revolutions subtracted from the encoder position
+	current      float32 // Motor current in amps
+	foldback     float32 // Duty scale applied by current limiting
+	stallTime    float32 // Seconds spent driving hard without turning
+	fault        error
+	homing       homing
 
 	// Control
 	enabled bool
@@ -54,20 +86,47 @@ func New(pwm devices.PWMDevice, config Config) (*Motor, error) {
 	}
 
 	m := &Motor{
-		config: config,
-		pwm:    pwm,
-		stopCh: make(chan struct{}),
+		config:   config,
+		pwm:      pwm,
+		stopCh:   make(chan struct{}),
+		foldback: 1,
 	}
 
-	// Initialize PID controller
+	// Initialize PID controllers. With a current loop the speed loop
+	// produces a current setpoint instead of a duty cycle.
+	m.currentPID = config.Current != nil && config.CurrentLimit > 0 &&
+		(config.CurrentGains.P != 0 || config.CurrentGains.I != 0)
+	speedMax := config.MaxOutput
+	if m.currentPID {
+		speedMax = config.CurrentLimit
+		m.curPID = pid.New1D(
+			config.CurrentGains.P,
+			config.CurrentGains.I,
+			config.CurrentGains.D,
+			-config.MaxOutput,
+			config.MaxOutput,
+		)
+	}
 	m.pid = pid.New1D(
 		config.PIDGains.P,
 		config.PIDGains.I,
 		config.PIDGains.D,
-		-config.MaxOutput,
-		config.MaxOutput,
+		-speedMax,
+		speedMax,
 	)
 	m.pid.Reset()
+	m.posPID = pid.New1D(
+		config.PositionGains.P,
+		config.PositionGains.I,
+		config.PositionGains.D,
+		-config.MaxRPM,
+		config.MaxRPM,
+	)
+
+	// Initialize trajectory generator
+	m.limits = m.resolveLimits(config.Limits)
+	m.profile = vaj.New1D(m.limits.Velocity, m.limits.Acceleration, m.limits.Jerk)
+	m.position = m.readPosition()
 
 	// Get PWM channels based on motor type
 	if err := m.setupPWMChannels(); err != nil {
@@ -120,9 +179,32 @@ func validateConfig(config Config) error {
 		return fmt.Errorf("max RPM must be positive")
 	}
 
+	if config.Encoder.CountsPerRevolution() <= 0 {
+		return fmt.Errorf("encoder counts per revolution must be positive")
+	}
+
+	if config.Current != nil && config.CurrentScale == 0 {
+		return fmt.Errorf("current scale is required with current sensing")
+	}
+
 	return nil
 }
 
+// resolveLimits fills in default trajectory limits.
+func (m *Motor) resolveLimits(l Limits) Limits {
+	if l.Velocity <= 0 || l.Velocity > m.config.MaxRPM/60 {
+		l.Velocity = m.config.MaxRPM / 60
+	}
+	if l.Acceleration <= 0 {
+		l.Acceleration = l.Velocity * 4
+	}
+	if l.Jerk <= 0 {
+		// reach full acceleration within one sample: trapezoidal profile
+		l.Jerk = l.Acceleration / m.config.SamplePeriod
+	}
+	return l
+}
+
 // setupPWMChannels sets up PWM channels based on motor type.
 func (m *Motor) setupPWMChannels() error {
 	var err error
@@ -158,12 +240,23 @@ func (m *Motor) setupPWMChannels() error {
 	return nil
 }
 
-// SetSpeed sets the target speed in RPM.
+// SetSpeed sets the target speed in RPM and switches to velocity mode.
 // Positive values = forward, negative values = reverse.
 func (m *Motor) SetSpeed(rpm float32) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.fault != nil {
+		return m.fault
+	}
+	m.mode = ModeVelocity
+	m.homing = homingIdle
+	m.setSpeed(rpm)
+	return nil
+}
+
+// setSpeed sets the speed loop target.
+func (m *Motor) setSpeed(rpm float32) {
 	// Clamp to max RPM
 	if rpm > m.config.MaxRPM {
 		rpm = m.config.MaxRPM
@@ -182,8 +275,6 @@ func (m *Motor) SetSpeed(rpm float32) error {
 	} else {
 		m.direction = 0
 	}
-
-	return nil
 }
 
 // Speed returns the current speed in RPM.
@@ -200,6 +291,234 @@ func (m *Motor) TargetSpeed() float32 {
 	return m.targetSpeed
 }
 
+// MoveTo moves to the position in revolutions along a trajectory bounded by
+// the configured limits and switches to position mode.
+func (m *Motor) MoveTo(position float32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.moveTo(position, 1)
+}
+
+// moveTo starts a move with the limits scaled in time by r (0 < r <= 1).
+func (m *Motor) moveTo(position, r float32) error {
+	if m.fault != nil {
+		return m.fault
+	}
+	if m.mode != ModePosition || m.homing != homingIdle {
+		m.homing = homingIdle
+		m.hold()
+	}
+	m.profile.SetLimits(m.limits.Velocity*r, m.limits.Acceleration*r*r, m.limits.Jerk*r*r*r)
+	m.profile.Target = position
+	return nil
+}
+
+// moveDuration estimates how long a move to position takes under the configured limits.
+func (m *Motor) moveDuration(position float32) float32 {
+	p := vaj.New1D(m.limits.Velocity, m.limits.Acceleration, m.limits.Jerk)
+	start := m.profile.Output
+	if m.mode != ModePosition {
+		start = m.position
+	}
+	return p.Duration(position - start)
+}
+
+// hold switches to position mode and anchors the trajectory at the current position.
+func (m *Motor) hold() {
+	m.mode = ModePosition
+	m.profile.Reset()
+	m.profile.Velocity = m.currentSpeed / 60
+	if m.profile.Velocity > m.limits.Velocity || m.profile.Velocity < -m.limits.Velocity {
+		m.profile.Velocity = 0
+	}
+	m.profile.Input = m.position
+	m.profile.Output = m.position
+	m.profile.Target = m.position
+	m.posPID.Input = m.position
+	m.posPID.Reset()
+	m.setSpeed(0)
+}
+
+// SetLimits changes the trajectory limits used by subsequent moves.
+func (m *Motor) SetLimits(limits Limits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = m.resolveLimits(limits)
+	m.profile.SetLimits(m.limits.Velocity, m.limits.Acceleration, m.limits.Jerk)
+}
+
+// Limits returns the trajectory limits.
+func (m *Motor) Limits() Limits {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.limits
+}
+
+// Mode returns the active control mode.
+func (m *Motor) Mode() Mode {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mode
+}
+
+// Position returns the current position in revolutions.
+func (m *Motor) Position() float32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.position
+}
+
+// TargetPosition returns the final position of the current move in revolutions.
+func (m *Motor) TargetPosition() float32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.profile.Target
+}
+
+// InPosition reports whether the trajectory has finished and the motor is
+// within PositionTolerance of the target.
+func (m *Motor) InPosition() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inPosition()
+}
+
+func (m *Motor) inPosition() bool {
+	if m.mode != ModePosition || m.homing != homingIdle || !m.profile.Done() {
+		return false
+	}
+	return abs(m.position-m.profile.Target) <= m.config.PositionTolerance
+}
+
+// Current returns the last measured motor current in amps.
+func (m *Motor) Current() float32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// Fault returns the active fault (e.g. ErrStalled) or nil.
+func (m *Motor) Fault() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fault
+}
+
+// ClearFault clears the active fault. The motor holds its current position.
+func (m *Motor) ClearFault() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fault = nil
+	m.stallTime = 0
+	m.foldback = 1
+	m.homing = homingIdle
+	m.resetLoops()
+	m.hold()
+}
+
+// Wait blocks until the motor is in position, faults or ctx is done.
+// In velocity mode it returns immediately.
+func (m *Motor) Wait(ctx context.Context) error {
+	period := time.Duration(m.config.SamplePeriod * float32(time.Second))
+	t := time.NewTicker(period)
+	defer t.Stop()
+	for {
+		m.mu.Lock()
+		done := m.inPosition() || (m.mode == ModeVelocity && m.homing == homingIdle)
+		fault := m.fault
+		m.mu.Unlock()
+		if fault != nil {
+			return fault
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Home runs the homing sequence against the Limit pin and waits for it to
+// finish. Afterwards the motor holds HomeOffset in position mode.
+// The motor must be enabled.
+func (m *Motor) Home(ctx context.Context) error {
+	if err := m.startHoming(); err != nil {
+		return err
+	}
+	if err := m.Wait(ctx); err != nil {
+		m.mu.Lock()
+		if m.homing != homingIdle {
+			m.homing = homingIdle
+			m.hold()
+		}
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// startHoming begins the homing sequence.
+func (m *Motor) startHoming() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case m.config.Limit == nil:
+		return fmt.Errorf("limit pin is required for homing")
+	case !m.enabled:
+		return fmt.Errorf("motor is not enabled")
+	case m.fault != nil:
+		return m.fault
+	}
+	m.mode = ModeVelocity
+	m.homing = homingSeek
+	m.setSpeed(m.homingRPM())
+	return nil
+}
+
+func (m *Motor) homingRPM() float32 {
+	if m.config.HomingRPM != 0 {
+		return m.config.HomingRPM
+	}
+	return m.config.MaxRPM / 10
+}
+
+// limitActive reports whether the limit switch is triggered.
+func (m *Motor) limitActive() bool {
+	return m.config.Limit.Get() != m.config.LimitActiveLow
+}
+
+// updateHoming advances the homing sequence.
+func (m *Motor) updateHoming() {
+	switch m.homing {
+	case homingSeek:
+		if m.limitActive() {
+			m.homing = homingRelease
+			m.setSpeed(-m.homingRPM() / homingBackoff)
+		}
+	case homingRelease:
+		if !m.limitActive() {
+			m.homing = homingIdle
+			m.offset += m.position - m.config.HomeOffset
+			m.position = m.config.HomeOffset
+			m.resetLoops()
+			m.hold()
+		}
+	}
+}
+
+// resetLoops clears the integrators of all loops.
+func (m *Motor) resetLoops() {
+	m.pid.Input = m.currentSpeed
+	m.pid.Reset()
+	m.curPID.Input = m.current
+	m.curPID.Reset()
+	m.posPID.Input = m.position
+	m.posPID.Reset()
+}
+
 // Enable starts the motor control loop.
 func (m *Motor) Enable() error {
 	m.mu.Lock()
@@ -210,7 +529,12 @@ func (m *Motor) Enable() error {
 	}
 
 	m.enabled = true
-	m.pid.Reset()
+	m.currentSpeed = float32(m.config.Encoder.RPM())
+	m.position = m.readPosition()
+	m.resetLoops()
+	if m.mode == ModePosition {
+		m.hold()
+	}
 
 	// Start control loop
 	period := time.Duration(m.config.SamplePeriod * float32(time.Second))
@@ -242,6 +566,8 @@ func (m *Motor) Disable() error {
 
 	m.targetSpeed = 0
 	m.direction = 0
+	m.pid.Target = 0
+	m.homing = homingIdle
 
 	// Signal control loop to stop (unlock before closing channel)
 	stopCh := m.stopCh
@@ -265,7 +591,8 @@ func (m *Motor) controlLoop() {
 	}
 }
 
-// update performs one PID update cycle.
+// update performs one control cycle:
+// trajectory -> position PID -> speed PID -> current PID/limit -> PWM.
 func (m *Motor) update() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -273,53 +600,92 @@ func (m *Motor) update() {
 	if !m.enabled {
 		return
 	}
+	dt := m.config.SamplePeriod
 
-	// Read current speed from encoder
+	// Read feedback
 	m.currentSpeed = float32(m.config.Encoder.RPM())
+	m.position = m.readPosition()
+	if m.config.Current != nil {
+		m.current = (float32(m.config.Current.Get()) - m.config.CurrentOffset) * m.config.CurrentScale
+	}
 
-	// Update PID controller
-	m.pid.Input = m.currentSpeed
-	m.pid.Update(m.config.SamplePeriod)
+	if m.fault != nil {
+		m.setPWM(0, 0)
+		return
+	}
 
-	// Get PID output (normalized to [-1, 1])
-	pidOutput := m.pid.Output
+	if m.homing != homingIdle {
+		m.updateHoming()
+	}
+
+	// Position loop: trajectory velocity feed-forward plus position correction
+	if m.mode == ModePosition {
+		m.profile.Update(dt)
+		m.posPID.Target = m.profile.Output
+		m.posPID.Input = m.position
+		m.posPID.Update(dt)
+		m.setSpeed(m.profile.Velocity*60 + m.posPID.Output)
+	}
+
+	// Update speed PID controller
+	m.pid.Input = m.currentSpeed
+	m.pid.Update(dt)
+
+	// Get PID output (normalized to [-1, 1], or amps with a current loop)
+	output := m.pid.Output
+	if m.currentPID {
+		m.curPID.Target = output
+		m.curPID.Input = m.current
+		m.curPID.Update(dt)
+		output = m.curPID.Output
+	} else if m.config.Current != nil && m.config.CurrentLimit > 0 {
+		output *= m.limitCurrent(dt)
+	}
 
-	// Convert to PWM duty cycle (0 to 1)
-	pwmDuty := abs(pidOutput)
+	if m.detectStall(output, dt) {
+		m.fault = ErrStalled
+		m.homing = homingIdle
+		m.setPWM(0, 0)
+		return
+	}
 
 	// Set direction and PWM based on motor type
-	if m.config.Type == TypeABPWM {
-		// TypeABPWM: direction controlled by which PWM channel is active
-		if pidOutput > 0 {
-			// Forward: A=PWM, B=0
-			m.pwmChannelA.Set(pwmDuty)
-			m.pwmChannelB.Set(0)
-		} else if pidOutput < 0 {
-			// Reverse: A=0, B=PWM
-			m.pwmChannelA.Set(0)
-			m.pwmChannelB.Set(pwmDuty)
-		} else {
-			// Stop: A=0, B=0
-			m.pwmChannelA.Set(0)
-			m.pwmChannelB.Set(0)
-		}
+	m.setPWM(output, sign(output))
+}
+
+// readPosition returns the encoder position in revolutions.
+func (m *Motor) readPosition() float32 {
+	enc := m.config.Encoder
+	return float32(float64(enc.Position())/float64(enc.CountsPerRevolution())) - m.offset
+}
+
+// limitCurrent returns the duty scale that folds back output while the
+// current exceeds CurrentLimit and recovers within currentRecovery seconds.
+func (m *Motor) limitCurrent(dt float32) float32 {
+	if over := abs(m.current) / m.config.CurrentLimit; over > 1 {
+		m.foldback /= over
 	} else {
-		// TypeDirPWM or TypeABDirPWM: direction controlled by pin, speed by PWM
-		dir := pidOutput >= 0
-
-		if m.config.Type == TypeDirPWM {
-			// Set direction pin
-			m.config.Dir.Set(dir)
-			// Set PWM
-			m.pwmChannel.Set(pwmDuty)
-		} else { // TypeABDirPWM
-			// Set direction pins (A high = forward, B high = reverse)
-			m.config.PinA.Set(dir)
-			m.config.PinB.Set(!dir)
-			// Set PWM
-			m.pwmChannel.Set(pwmDuty)
-		}
+		// recover slower as the current approaches the limit
+		m.foldback += dt / currentRecovery * (1 - over)
 	}
+	if m.foldback > 1 {
+		m.foldback = 1
+	}
+	return m.foldback
+}
+
+// detectStall reports whether the motor has been driven hard without turning
+// for longer than the configured stall time.
+func (m *Motor) detectStall(output, dt float32) bool {
+	if m.config.Stall.Time <= 0 {
+		return false
+	}
+	if abs(output) >= m.config.Stall.Output && abs(m.currentSpeed) < m.config.Stall.RPM {
+		m.stallTime += dt
+	} else {
+		m.stallTime = 0
+	}
+	return m.stallTime >= m.config.Stall.Time
 }
 
 // setPWM sets PWM and direction directly (for manual control).
@@ -352,6 +718,16 @@ func (m *Motor) setPWM(duty float32, direction int) {
 	}
 }
 
+// sign returns 1, -1 or 0 depending on the sign of x.
+func sign(x float32) int {
+	if x > 0 {
+		return 1
+	} else if x < 0 {
+		return -1
+	}
+	return 0
+}
+
 // abs returns the absolute value of a float32.
 func abs(x float32) float32 {
 	if x < 0 {
@@ -0,0 +1,299 @@
+package motor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+const (
+	noLoadRPM    = 120  // plant speed at full duty
+	stallCurrent = 10   // amps at full duty and standstill
+	currentScale = .001 // amps per ADC count
+	currentZero  = 32768
+	plantCPR     = 4096
+)
+
+// plant simulates a DC motor with a first order speed response and a back-EMF current model.
+type plant struct {
+	rpm, position float64 // RPM, revolutions
+	current       float64
+	blocked       bool
+	limitAt       float64 // limit switch closes below this position
+	dir           bool
+	duty, dutyB   float32
+	cfg           *Config
+}
+
+func (p *plant) step(dt float64) {
+	d := float64(p.duty)
+	switch p.cfg.Type {
+	case TypeDirPWM:
+		if !p.dir {
+			d = -d
+		}
+	case TypeABPWM:
+		d = float64(p.duty - p.dutyB)
+	}
+	p.current = stallCurrent * (d - p.rpm/noLoadRPM)
+	if p.blocked {
+		p.rpm = 0
+		p.current = stallCurrent * d
+		return
+	}
+	const tau = .05
+	p.rpm += (d*noLoadRPM - p.rpm) * dt / tau
+	p.position += p.rpm / 60 * dt
+}
+
+// encoder interface
+func (p *plant) Position() int64            { return int64(p.position * plantCPR) }
+func (p *plant) RPM() float64               { return p.rpm }
+func (p *plant) CountsPerRevolution() int64 { return plantCPR }
+
+// ADC interface
+func (p *plant) Get() uint16 {
+	return uint16(currentZero + p.current/currentScale)
+}
+
+type pin struct {
+	get func() bool
+	set func(bool)
+}
+
+func (p *pin) Get() bool {
+	if p.get != nil {
+		return p.get()
+	}
+	return false
+}
+func (p *pin) Set(v bool) {
+	if p.set != nil {
+		p.set(v)
+	}
+}
+func (p *pin) High() { p.Set(true) }
+func (p *pin) Low()  { p.Set(false) }
+func (p *pin) SetInterrupt(devices.PinChange, func(devices.Pin)) error {
+	return nil
+}
+
+type channel struct{ duty *float32 }
+
+func (c channel) Set(duty float32) error       { *c.duty = duty; return nil }
+func (c channel) SetMicroseconds(uint32) error { return nil }
+func (c channel) Stop() error                  { *c.duty = 0; return nil }
+
+// pwmDevice routes PWM pins to plant duty cycles.
+type pwmDevice struct {
+	channels map[devices.Pin]*float32
+}
+
+func (d *pwmDevice) Channel(p devices.Pin) (devices.PWM, error) {
+	return channel{d.channels[p]}, nil
+}
+func (d *pwmDevice) Configure(uint32) error    { return nil }
+func (d *pwmDevice) SetFrequency(uint32) error { return nil }
+
+// newTestMotor builds a motor wired to a simulated plant.
+func newTestMotor(t *testing.T, pwm *pwmDevice, tweak func(*Config)) (*Motor, *plant) {
+	t.Helper()
+	cfg := DefaultConfig()
+	p := &plant{cfg: &cfg, limitAt: -1e9}
+	cfg.Dir = &pin{set: func(v bool) { p.dir = v }}
+	cfg.PWM = &pin{}
+	cfg.Encoder = p
+	cfg.PIDGains.P = .02
+	cfg.PIDGains.I = .2
+	cfg.PIDGains.D = 0
+	if tweak != nil {
+		tweak(&cfg)
+	}
+	pwm.channels[cfg.PWM] = &p.duty
+	m, err := New(pwm, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	m.enabled = true // the tests drive update() directly
+	m.mu.Unlock()
+	return m, p
+}
+
+// run advances the simulation until done returns true or the time runs out.
+func run(motors []*Motor, plants []*plant, seconds float64, done func() bool) float64 {
+	dt := float64(motors[0].config.SamplePeriod)
+	for t := 0.0; t < seconds; t += dt {
+		for i, m := range motors {
+			plants[i].step(dt)
+			m.update()
+		}
+		if done != nil && done() {
+			return t
+		}
+	}
+	return seconds
+}
+
+func TestSpeed(t *testing.T) {
+	m, p := newTestMotor(t, &pwmDevice{channels: map[devices.Pin]*float32{}}, nil)
+	m.SetSpeed(-60)
+	run([]*Motor{m}, []*plant{p}, 2, nil)
+	if abs(m.Speed()+60) > 1 {
+		t.Fatalf("speed %v, want -60", m.Speed())
+	}
+}
+
+func TestMoveTo(t *testing.T) {
+	m, p := newTestMotor(t, &pwmDevice{channels: map[devices.Pin]*float32{}}, nil)
+	if err := m.MoveTo(3); err != nil {
+		t.Fatal(err)
+	}
+	var overshoot float32
+	took := run([]*Motor{m}, []*plant{p}, 10, func() bool {
+		if pos := m.Position(); pos-3 > overshoot {
+			overshoot = pos - 3
+		}
+		return m.InPosition()
+	})
+	if !m.InPosition() || m.Mode() != ModePosition {
+		t.Fatalf("not in position: %v", m.Position())
+	}
+	expected := m.profile.Duration(3)
+	if took > float64(expected)+.5 {
+		t.Fatalf("move took %vs, profile %vs", took, expected)
+	}
+	if overshoot > .05 {
+		t.Fatalf("overshoot %v", overshoot)
+	}
+}
+
+func TestSynchronizedMove(t *testing.T) {
+	pwm := &pwmDevice{channels: map[devices.Pin]*float32{}}
+	m1, p1 := newTestMotor(t, pwm, nil)
+	m2, p2 := newTestMotor(t, pwm, func(c *Config) { c.Limits.Jerk = 20 })
+	array := &MotorArray{pwm: pwm, motors: []*Motor{m1, m2}}
+	motors, plants := array.motors, []*plant{p1, p2}
+
+	if err := array.MoveTo([]float32{4, -1}); err != nil {
+		t.Fatal(err)
+	}
+	arrived := [2]float64{-1, -1}
+	var now float64
+	run(motors, plants, 10, func() bool {
+		now += float64(m1.config.SamplePeriod)
+		for i, m := range motors {
+			if arrived[i] < 0 && m.profile.Done() {
+				arrived[i] = now
+			}
+		}
+		return arrived[0] >= 0 && arrived[1] >= 0
+	})
+	if arrived[0] < 0 || arrived[1] < 0 {
+		t.Fatalf("motors did not arrive: %v", arrived)
+	}
+	if d := arrived[0] - arrived[1]; d > .05 || d < -.05 {
+		t.Fatalf("arrival times differ: %v", arrived)
+	}
+	run(motors, plants, 1, nil)
+	pos := array.Positions()
+	if abs(pos[0]-4) > .01 || abs(pos[1]+1) > .01 {
+		t.Fatalf("positions %v", pos)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := array.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStall(t *testing.T) {
+	m, p := newTestMotor(t, &pwmDevice{channels: map[devices.Pin]*float32{}}, func(c *Config) {
+		c.Stall = Stall{Output: .5, RPM: 5, Time: .2}
+	})
+	p.blocked = true
+	m.SetSpeed(80)
+	run([]*Motor{m}, []*plant{p}, 1, func() bool { return m.Fault() != nil })
+	if !errors.Is(m.Fault(), ErrStalled) {
+		t.Fatalf("expected stall, got %v", m.Fault())
+	}
+	if p.duty != 0 {
+		t.Fatalf("stalled motor still driven: %v", p.duty)
+	}
+	if err := m.MoveTo(1); !errors.Is(err, ErrStalled) {
+		t.Fatalf("expected stall error, got %v", err)
+	}
+
+	p.blocked = false
+	m.ClearFault()
+	m.MoveTo(1)
+	run([]*Motor{m}, []*plant{p}, 5, m.InPosition)
+	if !m.InPosition() {
+		t.Fatalf("not in position after clearing fault: %v", m.Position())
+	}
+}
+
+func TestHome(t *testing.T) {
+	var p *plant
+	m, p := newTestMotor(t, &pwmDevice{channels: map[devices.Pin]*float32{}}, func(c *Config) {
+		c.Limit = &pin{get: func() bool { return p.position > p.limitAt }}
+		c.LimitActiveLow = true
+		c.HomingRPM = -30
+		c.HomeOffset = -.25
+	})
+	p.position = 2.7
+	p.limitAt = 1.5
+	m.update()
+
+	if err := m.startHoming(); err != nil {
+		t.Fatal(err)
+	}
+	run([]*Motor{m}, []*plant{p}, 10, func() bool { return m.homing == homingIdle })
+	if m.homing != homingIdle {
+		t.Fatal("homing did not finish")
+	}
+	if p.position < 1.5 || p.position > 1.6 {
+		t.Fatalf("homed away from the switch: %v", p.position)
+	}
+	run([]*Motor{m}, []*plant{p}, 1, nil)
+	if abs(m.Position()+.25) > .02 || !m.InPosition() {
+		t.Fatalf("position %v after homing", m.Position())
+	}
+
+	m.MoveTo(.75)
+	run([]*Motor{m}, []*plant{p}, 5, m.InPosition)
+	if abs(float32(p.position)-2.5) > .02 {
+		t.Fatalf("plant at %v, want 2.5", p.position)
+	}
+}
+
+func TestCurrentLimit(t *testing.T) {
+	for _, loop := range []bool{false, true} {
+		m, p := newTestMotor(t, &pwmDevice{channels: map[devices.Pin]*float32{}}, func(c *Config) {
+			c.Current = c.Encoder.(*plant) // the plant also senses current
+			c.CurrentScale = currentScale
+			c.CurrentOffset = currentZero
+			c.CurrentLimit = 2
+			if loop {
+				c.PIDGains.P, c.PIDGains.I = .5, 5
+				c.CurrentGains = Gains{P: .05, I: 5}
+			}
+		})
+		p.blocked = true
+		m.SetSpeed(100)
+		var peak, elapsed float32
+		run([]*Motor{m}, []*plant{p}, 1, func() bool {
+			// allow the loops a moment to settle
+			if elapsed += m.config.SamplePeriod; elapsed > .2 && abs(m.Current()) > peak {
+				peak = abs(m.Current())
+			}
+			return false
+		})
+		if peak > 2.5 || abs(m.Current()) < 1 {
+			t.Fatalf("loop=%v: current %v (peak %v), limit 2", loop, m.Current(), peak)
+		}
+	}
+}
@@ -1,10 +1,60 @@
 package motor
 
 import (
+	"errors"
+
 	"github.com/itohio/EasyRobot/x/devices"
 	"github.com/itohio/EasyRobot/x/devices/encoder"
 )
 
+// ErrStalled is reported when the motor is driven hard but does not turn.
+// The motor stays off until ClearFault is called.
+var ErrStalled = errors.New("motor stalled")
+
+// Ensure encoder.Device implements Encoder
+var _ Encoder = (*encoder.Device)(nil)
+
+// Encoder provides position and speed feedback, e.g. encoder.Device.
+type Encoder interface {
+	Position() int64            // position in counts
+	RPM() float64               // speed in RPM
+	CountsPerRevolution() int64 // counts per output shaft revolution
+}
+
+// Mode selects which loop drives the motor.
+type Mode int
+
+const (
+	// ModeVelocity tracks the speed set with SetSpeed.
+	ModeVelocity Mode = iota
+
+	// ModePosition follows a VAJ1D trajectory to the position set with MoveTo.
+	// The position loop feeds the velocity loop (cascaded control).
+	ModePosition
+)
+
+// Gains holds PID gains.
+type Gains struct {
+	P float32 // Proportional gain
+	I float32 // Integral gain
+	D float32 // Derivative gain
+}
+
+// Limits bounds trajectories in position mode.
+// Units are revolutions per second, per second² and per second³.
+type Limits struct {
+	Velocity     float32 // 0 = MaxRPM/60
+	Acceleration float32 // 0 = reach Velocity in 0.25s
+	Jerk         float32 // 0 = trapezoidal profile
+}
+
+// Stall configures stall detection. Disabled when Time is zero.
+type Stall struct {
+	Output float32 // minimum |PWM duty| considered as driving hard
+	RPM    float32 // speeds below this count as not turning
+	Time   float32 // seconds before the motor is declared stalled
+}
+
 // Type represents the motor driver type (how the motor is connected).
 type Type int
 
@@ -42,8 +92,8 @@ type Config struct {
 	PinA devices.Pin // Pin A (TypeABPWM, TypeABDirPWM)
 	PinB devices.Pin // Pin B (TypeABPWM, TypeABDirPWM)
 
-	// Encoder for feedback
-	Encoder *encoder.Device
+	// Encoder for feedback (typically *encoder.Device)
+	Encoder Encoder
 
 	// PID gains for speed control
 	PIDGains struct {
@@ -60,6 +110,31 @@ type Config struct {
 
 	// Max speed in RPM (for scaling PID output)
 	MaxRPM float32 // Maximum motor speed in RPM (default: 100)
+
+	// Position loop: output is a speed correction in RPM added to the
+	// trajectory velocity (clamped to [-MaxRPM, MaxRPM])
+	PositionGains     Gains
+	Limits            Limits  // Trajectory limits
+	PositionTolerance float32 // InPosition tolerance in revolutions (default: 0.01)
+
+	// Optional current sensing: current = (raw - CurrentOffset) * CurrentScale.
+	// With CurrentGains set the velocity loop outputs a current setpoint
+	// (clamped to [-CurrentLimit, CurrentLimit]) and a current loop drives PWM.
+	// Otherwise PWM duty is folded back while |current| exceeds CurrentLimit.
+	Current       devices.ADC
+	CurrentScale  float32 // Amps per raw count
+	CurrentOffset float32 // Raw reading at zero current
+	CurrentLimit  float32 // Amps (0 = no limit)
+	CurrentGains  Gains
+
+	Stall Stall // Stall detection
+
+	// Homing against a limit switch: Home runs at HomingRPM until Limit
+	// triggers, backs off until it releases, and sets position to HomeOffset.
+	Limit          devices.Pin
+	LimitActiveLow bool    // Limit switch pulls the pin low when triggered
+	HomingRPM      float32 // Signed homing speed (default: MaxRPM/10)
+	HomeOffset     float32 // Position in revolutions at the switch release point
 }
 
 // DefaultConfig returns a default configuration.
@@ -70,5 +145,8 @@ func DefaultConfig() Config {
 		MaxOutput:    1.0,
 		SamplePeriod: 0.01, // 10ms
 		MaxRPM:       100,
+
+		PositionGains:     Gains{P: 60, I: 0, D: 0},
+		PositionTolerance: 0.01,
 	}
 }
@@ -0,0 +1,282 @@
+package odrive
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Config holds configuration for an ODrive axis.
+type Config struct {
+	// Max speed in RPM (setpoints are clamped to [-MaxRPM, MaxRPM])
+	MaxRPM float32
+
+	// Input modes used when switching between speed and position control
+	VelocityInputMode InputMode // default: InputModeVelRamp
+	PositionInputMode InputMode // default: InputModeTrapTraj
+
+	// Optional limits sent on Enable (0 = leave ODrive configuration unchanged)
+	CurrentLimit float32 // Motor current limit in A
+}
+
+// DefaultConfig returns a default configuration.
+func DefaultConfig() Config {
+	return Config{
+		MaxRPM:            3000,
+		VelocityInputMode: InputModeVelRamp,
+		PositionInputMode: InputModeTrapTraj,
+	}
+}
+
+// Axis is a single ODrive axis with speed and position control.
+// It mirrors the speed control API of x/devices/motor (RPM setpoints)
+// and adds position control in revolutions.
+type Axis struct {
+	mu sync.Mutex
+
+	bus    *Bus
+	node   uint8
+	config Config
+
+	mode ControlMode
+
+	// Current state (from heartbeat / encoder estimates)
+	heartbeat       Heartbeat
+	currentSpeed    float32 // RPM
+	currentPosition float32 // revolutions
+	busVoltage      float32
+	busCurrent      float32
+
+	// Setpoints
+	targetSpeed    float32 // RPM
+	targetPosition float32 // revolutions
+
+	enabled bool
+
+	onHeartbeat func(Heartbeat)
+}
+
+func newAxis(bus *Bus, node uint8, config Config) *Axis {
+	if config.VelocityInputMode == InputModeInactive {
+		config.VelocityInputMode = InputModeVelRamp
+	}
+	if config.PositionInputMode == InputModeInactive {
+		config.PositionInputMode = InputModeTrapTraj
+	}
+	return &Axis{
+		bus:    bus,
+		node:   node,
+		config: config,
+		mode:   ControlModeVelocity,
+	}
+}
+
+// Node returns the axis node id.
+func (a *Axis) Node() uint8 {
+	return a.node
+}
+
+// Enable sets the controller mode and enters closed loop control.
+func (a *Axis) Enable() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.config.CurrentLimit > 0 {
+		velLimit := a.config.MaxRPM / 60
+		if err := a.bus.send(a.node, CmdSetLimits, encodeFloatPair(velLimit, a.config.CurrentLimit)); err != nil {
+			return fmt.Errorf("failed to set limits: %w", err)
+		}
+	}
+	if err := a.setModeLocked(a.mode); err != nil {
+		return err
+	}
+	if err := a.bus.send(a.node, CmdSetAxisState, encodeUint32(uint32(AxisStateClosedLoopControl))); err != nil {
+		return fmt.Errorf("failed to enter closed loop control: %w", err)
+	}
+	a.enabled = true
+	return nil
+}
+
+// Disable puts the axis into idle state.
+func (a *Axis) Disable() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.bus.send(a.node, CmdSetAxisState, encodeUint32(uint32(AxisStateIdle))); err != nil {
+		return fmt.Errorf("failed to enter idle state: %w", err)
+	}
+	a.enabled = false
+	a.targetSpeed = 0
+	return nil
+}
+
+// SetSpeed sets the target speed in RPM and switches to velocity control.
+// Positive values = forward, negative values = reverse.
+func (a *Axis) SetSpeed(rpm float32) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if rpm > a.config.MaxRPM {
+		rpm = a.config.MaxRPM
+	} else if rpm < -a.config.MaxRPM {
+		rpm = -a.config.MaxRPM
+	}
+
+	if a.mode != ControlModeVelocity {
+		if err := a.setModeLocked(ControlModeVelocity); err != nil {
+			return err
+		}
+	}
+	if err := a.bus.send(a.node, CmdSetInputVel, encodeFloatPair(rpm/60, 0)); err != nil {
+		return fmt.Errorf("failed to set input velocity: %w", err)
+	}
+	a.targetSpeed = rpm
+	return nil
+}
+
+// SetPosition sets the target position in revolutions and switches to position control.
+func (a *Axis) SetPosition(rev float32) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.mode != ControlModePosition {
+		if err := a.setModeLocked(ControlModePosition); err != nil {
+			return err
+		}
+	}
+	if err := a.bus.send(a.node, CmdSetInputPos, encodeSetInputPos(rev, 0, 0)); err != nil {
+		return fmt.Errorf("failed to set input position: %w", err)
+	}
+	a.targetPosition = rev
+	return nil
+}
+
+// SetTrajectoryLimits configures the trapezoidal trajectory planner
+// (velocity in RPM, acceleration/deceleration in RPM/s).
+func (a *Axis) SetTrajectoryLimits(rpm, accel, decel float32) error {
+	if err := a.bus.send(a.node, CmdSetTrajVelLimit, encodeFloatPair(rpm/60, 0)[:4]); err != nil {
+		return fmt.Errorf("failed to set trajectory velocity limit: %w", err)
+	}
+	if err := a.bus.send(a.node, CmdSetTrajAccelLimits, encodeFloatPair(accel/60, decel/60)); err != nil {
+		return fmt.Errorf("failed to set trajectory acceleration limits: %w", err)
+	}
+	return nil
+}
+
+// Speed returns the current speed in RPM (from encoder estimates).
+func (a *Axis) Speed() float32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.currentSpeed
+}
+
+// TargetSpeed returns the target speed in RPM.
+func (a *Axis) TargetSpeed() float32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.targetSpeed
+}
+
+// Position returns the current position in revolutions (from encoder estimates).
+func (a *Axis) Position() float32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.currentPosition
+}
+
+// TargetPosition returns the target position in revolutions.
+func (a *Axis) TargetPosition() float32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.targetPosition
+}
+
+// Heartbeat returns the latest heartbeat received from the axis.
+func (a *Axis) Heartbeat() Heartbeat {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.heartbeat
+}
+
+// BusVoltageCurrent returns the latest DC bus voltage (V) and current (A).
+func (a *Axis) BusVoltageCurrent() (float32, float32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.busVoltage, a.busCurrent
+}
+
+// OnHeartbeat registers a callback invoked for every heartbeat.
+func (a *Axis) OnHeartbeat(fn func(Heartbeat)) {
+	a.mu.Lock()
+	a.onHeartbeat = fn
+	a.mu.Unlock()
+}
+
+// RequestEstimates requests encoder estimates and bus voltage/current using remote frames.
+// Only needed when cyclic messages are disabled on the ODrive.
+func (a *Axis) RequestEstimates() error {
+	if err := a.bus.request(a.node, CmdGetEncoderEstimates); err != nil {
+		return err
+	}
+	return a.bus.request(a.node, CmdGetBusVoltageCurrent)
+}
+
+// ClearErrors clears axis errors.
+func (a *Axis) ClearErrors() error {
+	return a.bus.send(a.node, CmdClearErrors, nil)
+}
+
+// Estop requests an emergency stop.
+func (a *Axis) Estop() error {
+	return a.bus.send(a.node, CmdEstop, nil)
+}
+
+// Close stops the axis.
+func (a *Axis) Close() error {
+	return a.Disable()
+}
+
+func (a *Axis) setModeLocked(mode ControlMode) error {
+	input := a.config.VelocityInputMode
+	if mode == ControlModePosition {
+		input = a.config.PositionInputMode
+	}
+	if err := a.bus.send(a.node, CmdSetControllerMode, encodeSetControllerMode(mode, input)); err != nil {
+		return fmt.Errorf("failed to set controller mode: %w", err)
+	}
+	a.mode = mode
+	return nil
+}
+
+// handle processes a frame received from the axis.
+func (a *Axis) handle(cmd Command, data []byte) {
+	a.mu.Lock()
+	var cb func(Heartbeat)
+	var hb Heartbeat
+
+	switch cmd {
+	case CmdHeartbeat:
+		if len(data) >= 7 {
+			a.heartbeat = decodeHeartbeat(data)
+			cb, hb = a.onHeartbeat, a.heartbeat
+		}
+	case CmdGetEncoderEstimates:
+		if len(data) >= 8 {
+			a.currentPosition = getFloat32(data[0:4])
+			a.currentSpeed = getFloat32(data[4:8]) * 60
+		}
+	case CmdGetBusVoltageCurrent:
+		if len(data) >= 8 {
+			a.busVoltage = getFloat32(data[0:4])
+			a.busCurrent = getFloat32(data[4:8])
+		}
+	}
+	a.mu.Unlock()
+
+	if cb != nil {
+		cb(hb)
+	}
+}
+
+func encodeUint32(v uint32) []byte {
+	return []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)}
+}
@@ -0,0 +1,110 @@
+package odrive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// Bus multiplexes a CAN bus between ODrive axes.
+// A single read loop dispatches received frames to axes by node id.
+type Bus struct {
+	can    devices.CAN
+	ctx    context.Context
+	cancel func()
+
+	mu   sync.Mutex
+	axes map[uint8]*Axis
+
+	writeMu   sync.Mutex
+	startOnce sync.Once
+}
+
+// NewBus creates an ODrive bus over can. The read loop starts in Start and stops when ctx is done.
+func NewBus(ctx context.Context, can devices.CAN) *Bus {
+	cctx, cancel := context.WithCancel(ctx)
+	return &Bus{
+		can:    can,
+		ctx:    cctx,
+		cancel: cancel,
+		axes:   make(map[uint8]*Axis),
+	}
+}
+
+// Start starts the internal read loop.
+func (b *Bus) Start() {
+	b.startOnce.Do(func() {
+		go b.readLoop()
+	})
+}
+
+// Close stops the internal read loop.
+func (b *Bus) Close() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
+
+// Axis creates (or returns an existing) axis handle for node id.
+func (b *Bus) Axis(node uint8, config Config) (*Axis, error) {
+	if node > 0x3F {
+		return nil, fmt.Errorf("odrive: node id %d out of range: %w", node, devices.ErrInvalidValue)
+	}
+	if config.MaxRPM <= 0 {
+		return nil, fmt.Errorf("max RPM must be positive")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if a, ok := b.axes[node]; ok {
+		return a, nil
+	}
+	a := newAxis(b, node, config)
+	b.axes[node] = a
+	return a, nil
+}
+
+func (b *Bus) send(node uint8, cmd Command, data []byte) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	return b.can.WriteFrame(devices.NewCANFrame(FrameID(node, cmd), data))
+}
+
+func (b *Bus) request(node uint8, cmd Command) error {
+	f := devices.CANFrame{ID: FrameID(node, cmd), Remote: true}
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	return b.can.WriteFrame(f)
+}
+
+func (b *Bus) readLoop() {
+	var f devices.CANFrame
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		default:
+		}
+		if err := b.can.ReadFrame(&f); err != nil {
+			if !errors.Is(err, devices.ErrTimeout) {
+				// back off on transient errors
+				time.Sleep(time.Millisecond)
+			}
+			continue
+		}
+		if f.Extended || f.Remote {
+			continue
+		}
+		node, cmd := SplitFrameID(f.ID)
+		b.mu.Lock()
+		a := b.axes[node]
+		b.mu.Unlock()
+		if a != nil {
+			a.handle(cmd, f.Payload())
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package odrive
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+type fakeCAN struct {
+	rx   chan devices.CANFrame
+	sent chan devices.CANFrame
+}
+
+func newFakeCAN() *fakeCAN {
+	return &fakeCAN{
+		rx:   make(chan devices.CANFrame, 16),
+		sent: make(chan devices.CANFrame, 16),
+	}
+}
+
+func (c *fakeCAN) ReadFrame(f *devices.CANFrame) error {
+	select {
+	case *f = <-c.rx:
+		return nil
+	case <-time.After(10 * time.Millisecond):
+		return devices.ErrTimeout
+	}
+}
+
+func (c *fakeCAN) WriteFrame(f devices.CANFrame) error {
+	c.sent <- f
+	return nil
+}
+
+func expectFrame(t *testing.T, c *fakeCAN, node uint8, cmd Command) devices.CANFrame {
+	t.Helper()
+	select {
+	case f := <-c.sent:
+		n, got := SplitFrameID(f.ID)
+		if n != node || got != cmd {
+			t.Fatalf("expected node %d cmd 0x%02X, got node %d cmd 0x%02X", node, cmd, n, got)
+		}
+		return f
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for cmd 0x%02X", cmd)
+	}
+	return devices.CANFrame{}
+}
+
+func TestFrameID(t *testing.T) {
+	id := FrameID(3, CmdSetInputVel)
+	if id != 0x06D {
+		t.Fatalf("got 0x%03X, want 0x06D", id)
+	}
+	node, cmd := SplitFrameID(id)
+	if node != 3 || cmd != CmdSetInputVel {
+		t.Fatalf("split mismatch: %d 0x%02X", node, cmd)
+	}
+}
+
+func TestAxisSpeedAndPosition(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	can := newFakeCAN()
+	bus := NewBus(ctx, can)
+	bus.Start()
+
+	cfg := DefaultConfig()
+	cfg.MaxRPM = 600
+	axis, err := bus.Axis(1, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := axis.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	f := expectFrame(t, can, 1, CmdSetControllerMode)
+	if ControlMode(binary.LittleEndian.Uint32(f.Data[0:4])) != ControlModeVelocity {
+		t.Fatal("expected velocity control mode")
+	}
+	f = expectFrame(t, can, 1, CmdSetAxisState)
+	if AxisState(f.Data[0]) != AxisStateClosedLoopControl {
+		t.Fatal("expected closed loop control")
+	}
+
+	// Clamped to MaxRPM and converted to turns/s
+	if err := axis.SetSpeed(1200); err != nil {
+		t.Fatal(err)
+	}
+	f = expectFrame(t, can, 1, CmdSetInputVel)
+	if v := getFloat32(f.Data[0:4]); v != 10 {
+		t.Fatalf("got velocity %v turns/s, want 10", v)
+	}
+	if axis.TargetSpeed() != 600 {
+		t.Fatalf("got target speed %v", axis.TargetSpeed())
+	}
+
+	if err := axis.SetPosition(2.5); err != nil {
+		t.Fatal(err)
+	}
+	f = expectFrame(t, can, 1, CmdSetControllerMode)
+	if ControlMode(binary.LittleEndian.Uint32(f.Data[0:4])) != ControlModePosition {
+		t.Fatal("expected position control mode")
+	}
+	if InputMode(binary.LittleEndian.Uint32(f.Data[4:8])) != InputModeTrapTraj {
+		t.Fatal("expected trapezoidal trajectory input mode")
+	}
+	f = expectFrame(t, can, 1, CmdSetInputPos)
+	if p := getFloat32(f.Data[0:4]); p != 2.5 {
+		t.Fatalf("got position %v", p)
+	}
+
+	// Feedback
+	hbs := make(chan Heartbeat, 1)
+	axis.OnHeartbeat(func(hb Heartbeat) { hbs <- hb })
+	can.rx <- devices.NewCANFrame(FrameID(1, CmdGetEncoderEstimates), encodeFloatPair(2.25, 1.5))
+	can.rx <- devices.NewCANFrame(FrameID(1, CmdHeartbeat), []byte{0, 0, 0, 0, byte(AxisStateClosedLoopControl), 0, 1, 0})
+
+	select {
+	case hb := <-hbs:
+		if hb.AxisState != AxisStateClosedLoopControl || !hb.TrajectoryDone {
+			t.Fatalf("unexpected heartbeat %+v", hb)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for heartbeat")
+	}
+	if axis.Position() != 2.25 || axis.Speed() != 90 {
+		t.Fatalf("got position %v speed %v", axis.Position(), axis.Speed())
+	}
+
+	if err := axis.Disable(); err != nil {
+		t.Fatal(err)
+	}
+	f = expectFrame(t, can, 1, CmdSetAxisState)
+	if AxisState(f.Data[0]) != AxisStateIdle {
+		t.Fatal("expected idle state")
+	}
+}
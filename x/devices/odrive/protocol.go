@@ -0,0 +1,144 @@
+// Package odrive implements the ODrive CAN-simple protocol over devices.CAN.
+//
+// Each axis is addressed by a 6-bit node id. Frames use 11-bit identifiers
+// composed as node_id<<5 | command_id with little-endian payloads.
+package odrive
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Command is a CAN-simple command identifier.
+type Command uint8
+
+const (
+	CmdHeartbeat            Command = 0x001
+	CmdEstop                Command = 0x002
+	CmdGetError             Command = 0x003
+	CmdSetAxisNodeID        Command = 0x006
+	CmdSetAxisState         Command = 0x007
+	CmdGetEncoderEstimates  Command = 0x009
+	CmdSetControllerMode    Command = 0x00B
+	CmdSetInputPos          Command = 0x00C
+	CmdSetInputVel          Command = 0x00D
+	CmdSetInputTorque       Command = 0x00E
+	CmdSetLimits            Command = 0x00F
+	CmdSetTrajVelLimit      Command = 0x011
+	CmdSetTrajAccelLimits   Command = 0x012
+	CmdGetIq                Command = 0x014
+	CmdReboot               Command = 0x016
+	CmdGetBusVoltageCurrent Command = 0x017
+	CmdClearErrors          Command = 0x018
+	CmdSetAbsolutePosition  Command = 0x019
+)
+
+// AxisState is the ODrive axis state machine state.
+type AxisState uint8
+
+const (
+	AxisStateUndefined              AxisState = 0
+	AxisStateIdle                   AxisState = 1
+	AxisStateStartupSequence        AxisState = 2
+	AxisStateFullCalibration        AxisState = 3
+	AxisStateMotorCalibration       AxisState = 4
+	AxisStateEncoderIndexSearch     AxisState = 6
+	AxisStateEncoderOffsetCalib     AxisState = 7
+	AxisStateClosedLoopControl      AxisState = 8
+	AxisStateLockinSpin             AxisState = 9
+	AxisStateEncoderDirFind         AxisState = 10
+	AxisStateHoming                 AxisState = 11
+	AxisStateEncoderHallPolarityCal AxisState = 12
+	AxisStateEncoderHallPhaseCal    AxisState = 13
+)
+
+// ControlMode selects the ODrive controller loop.
+type ControlMode uint32
+
+const (
+	ControlModeVoltage  ControlMode = 0
+	ControlModeTorque   ControlMode = 1
+	ControlModeVelocity ControlMode = 2
+	ControlModePosition ControlMode = 3
+)
+
+// InputMode selects how setpoints are filtered by the ODrive.
+type InputMode uint32
+
+const (
+	InputModeInactive    InputMode = 0
+	InputModePassthrough InputMode = 1
+	InputModeVelRamp     InputMode = 2
+	InputModePosFilter   InputMode = 3
+	InputModeTrapTraj    InputMode = 5
+	InputModeTorqueRamp  InputMode = 6
+)
+
+// FrameID returns the 11-bit CAN identifier for cmd addressed to node.
+func FrameID(node uint8, cmd Command) uint32 {
+	return uint32(node&0x3F)<<5 | uint32(cmd&0x1F)
+}
+
+// SplitFrameID returns node id and command from a CAN identifier.
+func SplitFrameID(id uint32) (node uint8, cmd Command) {
+	return uint8((id >> 5) & 0x3F), Command(id & 0x1F)
+}
+
+// Heartbeat is the cyclic status message sent by each axis.
+type Heartbeat struct {
+	AxisError       uint32
+	AxisState       AxisState
+	ProcedureResult uint8
+	TrajectoryDone  bool
+}
+
+func decodeHeartbeat(b []byte) Heartbeat {
+	return Heartbeat{
+		AxisError:       binary.LittleEndian.Uint32(b[0:4]),
+		AxisState:       AxisState(b[4]),
+		ProcedureResult: b[5],
+		TrajectoryDone:  b[6] != 0,
+	}
+}
+
+func putFloat32(b []byte, v float32) {
+	binary.LittleEndian.PutUint32(b, math.Float32bits(v))
+}
+
+func getFloat32(b []byte) float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(b))
+}
+
+func encodeSetControllerMode(control ControlMode, input InputMode) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b[0:4], uint32(control))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(input))
+	return b
+}
+
+// encodeSetInputPos encodes position (turns) with velocity (turns/s) and torque (Nm) feed-forward
+// scaled by 0.001 as defined by the protocol.
+func encodeSetInputPos(pos, velFF, torqueFF float32) []byte {
+	b := make([]byte, 8)
+	putFloat32(b[0:4], pos)
+	binary.LittleEndian.PutUint16(b[4:6], uint16(int16(clampInt16(velFF*1000))))
+	binary.LittleEndian.PutUint16(b[6:8], uint16(int16(clampInt16(torqueFF*1000))))
+	return b
+}
+
+func encodeFloatPair(a, b float32) []byte {
+	out := make([]byte, 8)
+	putFloat32(out[0:4], a)
+	putFloat32(out[4:8], b)
+	return out
+}
+
+func clampInt16(v float32) float32 {
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return v
+}
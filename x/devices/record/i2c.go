@@ -0,0 +1,198 @@
+package record
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// Ensure I2C and I2CReplay implement devices.I2C
+var (
+	_ devices.I2C = (*I2C)(nil)
+	_ devices.I2C = (*I2CReplay)(nil)
+)
+
+// I2C records every transaction on an I2C bus.
+type I2C struct {
+	bus devices.I2C
+	out io.Writer
+
+	mu  sync.Mutex
+	w   *Writer
+	err error
+}
+
+// NewI2C wraps bus and records its transactions to out.
+func NewI2C(bus devices.I2C, out io.Writer) (*I2C, error) {
+	w, err := NewWriter(out, KindI2C, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &I2C{bus: bus, out: out, w: w}, nil
+}
+
+// ReadRegister reads a register from the wrapped bus and records the result.
+func (b *I2C) ReadRegister(addr uint8, r uint8, buf []byte) error {
+	err := b.bus.ReadRegister(addr, r, buf)
+	b.record(Event{Op: OpReadRegister, Addr: uint16(addr), Reg: r, R: buf, Err: errString(err)})
+	return err
+}
+
+// WriteRegister writes a register on the wrapped bus and records the written bytes.
+func (b *I2C) WriteRegister(addr uint8, r uint8, buf []byte) error {
+	err := b.bus.WriteRegister(addr, r, buf)
+	b.record(Event{Op: OpWriteRegister, Addr: uint16(addr), Reg: r, W: buf, Err: errString(err)})
+	return err
+}
+
+// Tx performs a transaction on the wrapped bus and records both buffers.
+func (b *I2C) Tx(addr uint16, w, r []byte) error {
+	err := b.bus.Tx(addr, w, r)
+	b.record(Event{Op: OpTx, Addr: addr, W: w, R: r, Err: errString(err)})
+	return err
+}
+
+// Err returns the first error encountered while writing the recording.
+func (b *I2C) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// Close flushes the recording and closes the output if it is an io.Closer.
+// The wrapped bus is not closed.
+func (b *I2C) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.w.Flush(); err != nil && b.err == nil {
+		b.err = err
+	}
+	if c, ok := b.out.(io.Closer); ok {
+		if err := c.Close(); err != nil && b.err == nil {
+			b.err = err
+		}
+	}
+	return b.err
+}
+
+func (b *I2C) record(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ev.Time = b.w.Since(time.Now())
+	if err := b.w.Write(ev); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+// I2CReplay feeds an I2C recording back to a driver.
+//
+// Transactions are replayed in order; read buffers are filled from the
+// recording. With a non-zero speed, the recorded gap between consecutive
+// transactions is preserved. Once the recording is exhausted every call
+// returns io.EOF.
+type I2CReplay struct {
+	opts   ReplayOptions
+	events []Event
+
+	mu    sync.Mutex
+	pos   int
+	pacer *pacer
+}
+
+// NewI2CReplay creates a replay of an I2C recording.
+func NewI2CReplay(log *Log, opts ReplayOptions) (*I2CReplay, error) {
+	if log.Kind != KindI2C {
+		return nil, ErrWrongKind
+	}
+	b := &I2CReplay{
+		opts:   opts,
+		events: log.Events,
+		pacer:  newPacer(opts.Speed),
+	}
+	if len(b.events) > 0 {
+		b.pacer.anchor(b.events[0].Time)
+	}
+	return b, nil
+}
+
+// OpenI2CReplay loads an I2C recording from r and creates a replay.
+func OpenI2CReplay(r io.Reader, opts ReplayOptions) (*I2CReplay, error) {
+	log, err := loadKind(r, KindI2C)
+	if err != nil {
+		return nil, err
+	}
+	return NewI2CReplay(log, opts)
+}
+
+// ReadRegister replays a recorded register read.
+func (b *I2CReplay) ReadRegister(addr uint8, r uint8, buf []byte) error {
+	return b.replay(OpReadRegister, uint16(addr), r, nil, buf)
+}
+
+// WriteRegister replays a recorded register write.
+func (b *I2CReplay) WriteRegister(addr uint8, r uint8, buf []byte) error {
+	return b.replay(OpWriteRegister, uint16(addr), r, buf, nil)
+}
+
+// Tx replays a recorded transaction.
+func (b *I2CReplay) Tx(addr uint16, w, r []byte) error {
+	return b.replay(OpTx, addr, 0, w, r)
+}
+
+// Done reports whether every recorded transaction has been replayed.
+func (b *I2CReplay) Done() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pos >= len(b.events)
+}
+
+// Close stops the replay; subsequent transactions return io.EOF.
+func (b *I2CReplay) Close() error {
+	b.pacer.close()
+	b.mu.Lock()
+	b.pos = len(b.events)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *I2CReplay) replay(op Op, addr uint16, reg uint8, w, r []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pos >= len(b.events) {
+		return io.EOF
+	}
+	ev := &b.events[b.pos]
+	if b.opts.Strict {
+		if err := ev.match(op, addr, reg, w, len(r)); err != nil {
+			return fmt.Errorf("%w: transaction %d: %v", ErrMismatch, b.pos, err)
+		}
+	}
+	if !b.pacer.wait(ev.Time) {
+		return io.EOF
+	}
+	b.pacer.anchor(ev.Time)
+	b.pos++
+	copy(r, ev.R)
+	return ev.Error()
+}
+
+// match checks that a driver call is identical to the recorded event.
+func (e *Event) match(op Op, addr uint16, reg uint8, w []byte, rlen int) error {
+	switch {
+	case e.Op != op:
+		return fmt.Errorf("got %v, want %v", op, e.Op)
+	case e.Addr != addr:
+		return fmt.Errorf("got address 0x%02X, want 0x%02X", addr, e.Addr)
+	case e.Reg != reg:
+		return fmt.Errorf("got register 0x%02X, want 0x%02X", reg, e.Reg)
+	case !bytes.Equal(e.W, w):
+		return fmt.Errorf("got write % X, want % X", w, e.W)
+	case len(e.R) != rlen:
+		return fmt.Errorf("got read length %d, want %d", rlen, len(e.R))
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+// Package record provides record and replay wrappers for devices.Serial and devices.I2C.
+//
+// Recorders wrap a real bus and log every byte chunk or transaction with a
+// timestamp. Replays implement the same interfaces and feed a recording back
+// into the unmodified driver, either with the original timing or as fast as
+// possible, so field sessions (LD06, XWPFTB, CR30, AS734x, ...) can be
+// reproduced on a desk and in tests.
+//
+// The log format is a small binary stream:
+//
+//	header: "EZRR" | version (1 byte) | kind (1 byte) | start unix nanos (int64 LE)
+//	event:  op (1 byte) | time since start (uvarint ns) | addr (uvarint) | reg (1 byte) |
+//	        len(W) (uvarint) | W | len(R) (uvarint) | R | len(err) (uvarint) | err
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+const (
+	magic   = "EZRR"
+	version = 1
+
+	// maxField limits a single field when decoding to protect against corrupt logs.
+	maxField = 1 << 24
+)
+
+var (
+	ErrInvalidLog = errors.New("record: invalid log")
+	ErrWrongKind  = errors.New("record: log kind mismatch")
+	ErrMismatch   = errors.New("record: replay mismatch")
+)
+
+// Kind identifies the bus type a log was recorded from.
+type Kind byte
+
+const (
+	KindSerial Kind = 'S'
+	KindI2C    Kind = 'I'
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindSerial:
+		return "serial"
+	case KindI2C:
+		return "i2c"
+	default:
+		return fmt.Sprintf("Kind(%d)", byte(k))
+	}
+}
+
+// Op is the recorded operation.
+type Op byte
+
+const (
+	OpRead          Op = 1 // serial Read; R holds the received bytes
+	OpWrite         Op = 2 // serial Write; W holds the sent bytes
+	OpTx            Op = 3 // I2C Tx
+	OpReadRegister  Op = 4 // I2C ReadRegister; R holds the register contents
+	OpWriteRegister Op = 5 // I2C WriteRegister; W holds the written bytes
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpRead:
+		return "read"
+	case OpWrite:
+		return "write"
+	case OpTx:
+		return "tx"
+	case OpReadRegister:
+		return "read-register"
+	case OpWriteRegister:
+		return "write-register"
+	default:
+		return fmt.Sprintf("Op(%d)", byte(o))
+	}
+}
+
+// Event is a single recorded operation.
+type Event struct {
+	Op   Op
+	Time time.Duration // since the start of the recording
+	Addr uint16
+	Reg  uint8
+	W    []byte
+	R    []byte
+	Err  string // empty if the operation succeeded
+}
+
+// Error returns the recorded error, mapping well known messages back to their sentinels.
+func (e *Event) Error() error {
+	if e.Err == "" {
+		return nil
+	}
+	for _, known := range knownErrors {
+		if known.Error() == e.Err {
+			return known
+		}
+	}
+	return errors.New(e.Err)
+}
+
+var knownErrors = []error{
+	io.EOF,
+	io.ErrUnexpectedEOF,
+	devices.ErrTimeout,
+	devices.ErrInvalidResponse,
+	devices.ErrInvalidValue,
+	devices.ErrInvalidState,
+	devices.ErrInvalidSize,
+	devices.ErrNotSupported,
+}
+
+// Log is a decoded recording.
+type Log struct {
+	Kind   Kind
+	Start  time.Time
+	Events []Event
+}
+
+// Duration returns the time of the last event.
+func (l *Log) Duration() time.Duration {
+	if len(l.Events) == 0 {
+		return 0
+	}
+	return l.Events[len(l.Events)-1].Time
+}
+
+// Writer encodes events to an underlying stream.
+type Writer struct {
+	w     *bufio.Writer
+	start time.Time
+	buf   []byte
+}
+
+// NewWriter writes the log header and returns a writer for events of the given kind.
+// start is the reference time for event timestamps.
+func NewWriter(w io.Writer, kind Kind, start time.Time) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	hdr := make([]byte, 0, 14)
+	hdr = append(hdr, magic...)
+	hdr = append(hdr, version, byte(kind))
+	hdr = binary.LittleEndian.AppendUint64(hdr, uint64(start.UnixNano()))
+	if _, err := bw.Write(hdr); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw, start: start}, nil
+}
+
+// Since returns the event time for t relative to the start of the recording.
+func (w *Writer) Since(t time.Time) time.Duration {
+	return t.Sub(w.start)
+}
+
+// Write encodes a single event.
+func (w *Writer) Write(ev Event) error {
+	b := w.buf[:0]
+	b = append(b, byte(ev.Op))
+	t := ev.Time
+	if t < 0 {
+		t = 0
+	}
+	b = binary.AppendUvarint(b, uint64(t))
+	b = binary.AppendUvarint(b, uint64(ev.Addr))
+	b = append(b, ev.Reg)
+	b = binary.AppendUvarint(b, uint64(len(ev.W)))
+	b = append(b, ev.W...)
+	b = binary.AppendUvarint(b, uint64(len(ev.R)))
+	b = append(b, ev.R...)
+	b = binary.AppendUvarint(b, uint64(len(ev.Err)))
+	b = append(b, ev.Err...)
+	w.buf = b
+	_, err := w.w.Write(b)
+	return err
+}
+
+// Flush writes any buffered events to the underlying stream.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// ReadLog decodes a complete recording.
+func ReadLog(r io.Reader) (*Log, error) {
+	br := bufio.NewReader(r)
+	var hdr [14]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLog, err)
+	}
+	if string(hdr[:4]) != magic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidLog)
+	}
+	if hdr[4] != version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidLog, hdr[4])
+	}
+	l := &Log{
+		Kind:  Kind(hdr[5]),
+		Start: time.Unix(0, int64(binary.LittleEndian.Uint64(hdr[6:]))),
+	}
+	for {
+		ev, err := readEvent(br)
+		if err == io.EOF {
+			return l, nil
+		}
+		if err != nil {
+			return l, err
+		}
+		l.Events = append(l.Events, ev)
+	}
+}
+
+func readEvent(br *bufio.Reader) (Event, error) {
+	var ev Event
+	op, err := br.ReadByte()
+	if err != nil {
+		return ev, err // clean EOF between events
+	}
+	ev.Op = Op(op)
+	t, err := binary.ReadUvarint(br)
+	if err != nil {
+		return ev, truncated(err)
+	}
+	ev.Time = time.Duration(t)
+	addr, err := binary.ReadUvarint(br)
+	if err != nil {
+		return ev, truncated(err)
+	}
+	ev.Addr = uint16(addr)
+	if ev.Reg, err = br.ReadByte(); err != nil {
+		return ev, truncated(err)
+	}
+	if ev.W, err = readField(br); err != nil {
+		return ev, err
+	}
+	if ev.R, err = readField(br); err != nil {
+		return ev, err
+	}
+	msg, err := readField(br)
+	if err != nil {
+		return ev, err
+	}
+	ev.Err = string(msg)
+	return ev, nil
+}
+
+func readField(br *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, truncated(err)
+	}
+	if n > maxField {
+		return nil, fmt.Errorf("%w: field too large (%d)", ErrInvalidLog, n)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, truncated(err)
+	}
+	return b, nil
+}
+
+func truncated(err error) error {
+	return fmt.Errorf("%w: truncated event: %v", ErrInvalidLog, err)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
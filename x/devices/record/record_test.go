@@ -0,0 +1,384 @@
+package record_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+	"github.com/itohio/EasyRobot/x/devices/as734x"
+	"github.com/itohio/EasyRobot/x/devices/cr30"
+	"github.com/itohio/EasyRobot/x/devices/lidar"
+	"github.com/itohio/EasyRobot/x/devices/lidar/ld06"
+	"github.com/itohio/EasyRobot/x/devices/lidar/xwpftb"
+	"github.com/itohio/EasyRobot/x/devices/record"
+	matTypes "github.com/itohio/EasyRobot/x/math/mat/types"
+)
+
+// scriptSerial returns queued chunks in order and queues a response for every write.
+type scriptSerial struct {
+	chunks  [][]byte
+	respond func(req []byte) []byte
+}
+
+func (s *scriptSerial) Read(p []byte) (int, error) {
+	if len(s.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, s.chunks[0])
+	s.chunks[0] = s.chunks[0][n:]
+	if len(s.chunks[0]) == 0 {
+		s.chunks = s.chunks[1:]
+	}
+	return n, nil
+}
+
+func (s *scriptSerial) Write(p []byte) (int, error) {
+	if s.respond != nil {
+		s.chunks = append(s.chunks, s.respond(p))
+	}
+	return len(p), nil
+}
+
+func (s *scriptSerial) Buffered() int { return 0 }
+
+func recordSerial(t *testing.T, ser *scriptSerial, fn func(*record.Serial)) *record.Log {
+	t.Helper()
+	var buf bytes.Buffer
+	rec, err := record.NewSerial(ser, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn(rec)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	log, err := record.ReadLog(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log
+}
+
+func TestSerialRequestResponse(t *testing.T) {
+	ser := &scriptSerial{respond: func([]byte) []byte { return []byte("PONG-1234") }}
+	log := recordSerial(t, ser, func(rec *record.Serial) {
+		buf := make([]byte, 16)
+		rec.Write([]byte("PING"))
+		n, _ := rec.Read(buf)
+		if string(buf[:n]) != "PONG-1234" {
+			t.Fatalf("got %q", buf[:n])
+		}
+		if _, err := rec.Read(buf); err != io.EOF {
+			t.Fatalf("expected EOF, got %v", err)
+		}
+	})
+	if len(log.Events) != 3 || log.Events[0].Op != record.OpWrite || log.Events[2].Error() != io.EOF {
+		t.Fatalf("unexpected log %+v", log.Events)
+	}
+
+	replay, err := record.NewSerialReplay(log, record.ReplayOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	// the response is gated on the request
+	if n, err := replay.Read(buf); n != 0 || err != nil {
+		t.Fatalf("read before write: %d %v", n, err)
+	}
+	if _, err := replay.Write([]byte("PONG")); !errors.Is(err, record.ErrMismatch) {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+	if _, err := replay.Write([]byte("PING")); err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	for {
+		n, err := replay.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(got) != "PONG-1234" || !replay.Done() {
+		t.Fatalf("got %q done=%v", got, replay.Done())
+	}
+}
+
+func TestSerialReplayTiming(t *testing.T) {
+	log := &record.Log{Kind: record.KindSerial, Events: []record.Event{
+		{Op: record.OpRead, Time: 10 * time.Millisecond, R: []byte{1}},
+		{Op: record.OpRead, Time: 70 * time.Millisecond, R: []byte{2}},
+	}}
+	readAll := func(speed float64) time.Duration {
+		replay, err := record.NewSerialReplay(log, record.ReplayOptions{Speed: speed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		buf := make([]byte, 8)
+		for {
+			if _, err := replay.Read(buf); err == io.EOF {
+				return time.Since(start)
+			}
+		}
+	}
+	if d := readAll(1); d < 55*time.Millisecond {
+		t.Fatalf("real time replay too fast: %v", d)
+	}
+	if d := readAll(0); d > 20*time.Millisecond {
+		t.Fatalf("fast replay too slow: %v", d)
+	}
+}
+
+// cr30Device answers every CR30 request with a valid packet echoing the command.
+func cr30Device(t *testing.T) *scriptSerial {
+	builder := cr30.NewPacketBuilder()
+	return &scriptSerial{respond: func(req []byte) []byte {
+		payload := make([]byte, 52)
+		copy(payload[5:], "CR30 Colorimeter")
+		copy(payload[35:], "CR30")
+		resp, err := builder.BuildPacket(req[0], req[1], req[2], req[3], payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}}
+}
+
+func TestReplayCR30(t *testing.T) {
+	connect := func(ser devices.Serial) cr30.Info {
+		dev := cr30.New(ser)
+		if err := dev.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		return dev.DeviceInfo()
+	}
+
+	var buf bytes.Buffer
+	rec, err := record.NewSerial(cr30Device(t), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := connect(rec)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if live.Name != "CR30 Colorimeter" {
+		t.Fatalf("unexpected live info %+v", live)
+	}
+
+	replay, err := record.OpenSerialReplay(&buf, record.ReplayOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed := connect(replay); replayed != live {
+		t.Fatalf("replayed info differs: %+v vs %+v", replayed, live)
+	}
+	if !replay.Done() {
+		t.Fatal("expected replay to consume the whole session")
+	}
+}
+
+// as7343Bus is a minimal register map that looks like an AS7343.
+type as7343Bus struct {
+	mu   sync.Mutex
+	regs [256]byte
+}
+
+func newAS7343Bus() *as7343Bus {
+	b := &as7343Bus{}
+	b.regs[0x5A] = 0x81 // ID
+	b.regs[0x90] = 0x40 // STATUS2: AVALID
+	for i := 0; i < 36; i++ {
+		b.regs[0x95+i] = byte(i*7 + 3)
+	}
+	return b
+}
+
+func (b *as7343Bus) ReadRegister(addr uint8, r uint8, buf []byte) error {
+	return b.Tx(uint16(addr), []byte{r}, buf)
+}
+
+func (b *as7343Bus) WriteRegister(addr uint8, r uint8, buf []byte) error {
+	return b.Tx(uint16(addr), append([]byte{r}, buf...), nil)
+}
+
+func (b *as7343Bus) Tx(_ uint16, w, r []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(w) == 0 {
+		return nil
+	}
+	reg := int(w[0])
+	for i, v := range w[1:] {
+		b.regs[(reg+i)&0xFF] = v
+	}
+	for i := range r {
+		r[i] = b.regs[(reg+i)&0xFF]
+	}
+	return nil
+}
+
+func TestReplayAS7343(t *testing.T) {
+	measure := func(bus devices.I2C) []uint16 {
+		dev := as734x.New(bus, 0)
+		if err := dev.Configure(as734x.DefaultConfig()); err != nil {
+			t.Fatal(err)
+		}
+		m, err := dev.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m.Channels
+	}
+
+	var buf bytes.Buffer
+	rec, err := record.NewI2C(newAS7343Bus(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := measure(rec)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := record.ReadLog(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := record.NewI2CReplay(log, record.ReplayOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed := measure(replay); !reflect.DeepEqual(live, replayed) {
+		t.Fatalf("replayed channels differ:\n%v\n%v", live, replayed)
+	}
+	if !replay.Done() {
+		t.Fatal("expected replay to consume every transaction")
+	}
+	if err := replay.Tx(0x39, []byte{0x80}, nil); err != io.EOF {
+		t.Fatalf("expected EOF after replay, got %v", err)
+	}
+
+	replay, _ = record.NewI2CReplay(log, record.ReplayOptions{Strict: true})
+	if err := replay.Tx(0x40, []byte{0x80, 0x01}, nil); !errors.Is(err, record.ErrMismatch) {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+}
+
+// firstScan runs a LiDAR and returns its first complete scan.
+func firstScan(t *testing.T, dev lidar.Device) matTypes.Matrix {
+	t.Helper()
+	scans := make(chan matTypes.Matrix, 1)
+	dev.OnRead(func(m matTypes.Matrix) {
+		select {
+		case scans <- m.Clone():
+		default:
+		}
+	})
+	defer dev.Close()
+	if err := dev.Configure(false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-scans:
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for a scan")
+		return nil
+	}
+}
+
+// replayLidar records a LiDAR streaming packets, replays the session into
+// a fresh driver and checks that both see the same scan.
+func replayLidar(t *testing.T, packets [][]byte, newDevice func(devices.Serial) lidar.Device) {
+	t.Helper()
+	var buf bytes.Buffer
+	rec, err := record.NewSerial(&scriptSerial{chunks: packets}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := firstScan(t, newDevice(rec))
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := record.OpenSerialReplay(&buf, record.ReplayOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := firstScan(t, newDevice(replay))
+	if live.Cols() == 0 || replayed.Cols() != live.Cols() {
+		t.Fatalf("replayed %d points, live %d", replayed.Cols(), live.Cols())
+	}
+	for i := range 2 {
+		if !reflect.DeepEqual(live.Row(i), replayed.Row(i)) {
+			t.Fatalf("replayed row %d differs:\n%v\n%v", i, live.Row(i), replayed.Row(i))
+		}
+	}
+}
+
+func TestReplayLD06(t *testing.T) {
+	// the driver accepts slices of at most speed·n/3000 degrees and completes
+	// a rotation when the end angle wraps around; angles are signed, so the
+	// slices before the wrap are sent as -6° to 0°
+	var packets [][]byte
+	for i := range 6 {
+		start := float64(i - 6)
+		dist := make([]uint16, 12)
+		intensity := make([]uint8, 12)
+		for j := range dist {
+			dist[j] = uint16(1000 + 12*i + j)
+			intensity[j] = 100
+		}
+		packets = append(packets, ld06.BuildMeasurementPacket(360, start, start+1, dist, intensity))
+	}
+	replayLidar(t, packets, func(ser devices.Serial) lidar.Device {
+		return ld06.New(context.Background(), ser, nil, 0, 360)
+	})
+}
+
+// xwpftbFrame builds a 60 byte XWPFTB measurement frame of 16 points.
+func xwpftbFrame(startDeg, endDeg float64, distMm [16]float64) []byte {
+	frame := make([]byte, 60)
+	frame[0], frame[1], frame[2], frame[3] = 0x55, 0xAA, 0x23, 0x10
+	binary.LittleEndian.PutUint16(frame[4:], 3000)
+	binary.LittleEndian.PutUint16(frame[6:], uint16(startDeg*100)+0x2000)
+	for i, d := range distMm {
+		binary.LittleEndian.PutUint16(frame[8+3*i:], uint16(d*10))
+		frame[10+3*i] = 200
+	}
+	binary.LittleEndian.PutUint16(frame[56:], uint16(endDeg*100)+0x2000)
+	var sum uint16
+	for _, b := range frame[:58] {
+		sum += uint16(b)
+	}
+	binary.LittleEndian.PutUint16(frame[58:], sum)
+	return frame
+}
+
+func TestReplayXWPFTB(t *testing.T) {
+	// a rotation is 15 slices
+	var packets [][]byte
+	for i := range 15 {
+		var dist [16]float64
+		for j := range dist {
+			dist[j] = 500 + float64(16*i+j)
+		}
+		start := float64(16 * i)
+		packets = append(packets, xwpftbFrame(start, start+16, dist))
+	}
+	replayLidar(t, packets, func(ser devices.Serial) lidar.Device {
+		return xwpftb.New(context.Background(), ser, nil, 0, 256)
+	})
+}
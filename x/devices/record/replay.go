@@ -0,0 +1,118 @@
+package record
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReplayOptions controls how a recording is fed back.
+type ReplayOptions struct {
+	// Speed scales the recorded timing: 1 replays in real time, 2 twice as fast.
+	// Zero replays as fast as possible.
+	Speed float64
+
+	// Strict makes the replay verify that the driver writes the same bytes
+	// (serial) or performs the same transactions (I2C) as recorded.
+	// Mismatches return ErrMismatch.
+	Strict bool
+}
+
+// DefaultReplayOptions returns options for a real-time, non-strict replay.
+func DefaultReplayOptions() ReplayOptions {
+	return ReplayOptions{Speed: 1}
+}
+
+// Load reads a recording from a file.
+func Load(path string) (*Log, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadLog(f)
+}
+
+// loadKind reads a recording and checks its kind.
+func loadKind(r io.Reader, kind Kind) (*Log, error) {
+	l, err := ReadLog(r)
+	if err != nil {
+		return nil, err
+	}
+	if l.Kind != kind {
+		return nil, ErrWrongKind
+	}
+	return l, nil
+}
+
+// pacer maps recorded event times to wall clock time.
+// The anchor pairs a recorded time with the wall time it was replayed at.
+type pacer struct {
+	speed      float64
+	anchorRec  time.Duration
+	anchorWall time.Time
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func newPacer(speed float64) *pacer {
+	return &pacer{speed: speed, anchorWall: time.Now(), done: make(chan struct{})}
+}
+
+// anchor aligns recorded time rec with the current wall time.
+func (p *pacer) anchor(rec time.Duration) {
+	p.anchorAt(rec, time.Now())
+}
+
+// anchorAt aligns recorded time rec with the given wall time.
+func (p *pacer) anchorAt(rec time.Duration, wall time.Time) {
+	p.anchorRec = rec
+	p.anchorWall = wall
+}
+
+// due returns the wall time the event recorded at rec should be replayed.
+func (p *pacer) due(rec time.Duration) time.Time {
+	if p.speed <= 0 {
+		return time.Time{}
+	}
+	d := float64(rec-p.anchorRec) / p.speed
+	return p.anchorWall.Add(time.Duration(d))
+}
+
+// wait blocks until the event recorded at rec is due. Returns false if closed.
+func (p *pacer) wait(rec time.Duration) bool {
+	d := time.Until(p.due(rec))
+	if d <= 0 {
+		select {
+		case <-p.done:
+			return false
+		default:
+			return true
+		}
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-p.done:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// sleep pauses for at most d. Returns false if closed.
+func (p *pacer) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-p.done:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (p *pacer) close() {
+	p.closeOnce.Do(func() { close(p.done) })
+}
@@ -0,0 +1,275 @@
+package record
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// pollInterval is how long a replayed Read waits for data before returning 0 bytes,
+// similar to a non-blocking serial port polled by a driver read loop.
+const pollInterval = time.Millisecond
+
+// Ensure Serial and SerialReplay implement devices.Serial
+var (
+	_ devices.Serial = (*Serial)(nil)
+	_ devices.Serial = (*SerialReplay)(nil)
+)
+
+// Serial records every Read and Write of a serial port.
+// Empty reads without an error are not recorded.
+type Serial struct {
+	ser devices.Serial
+	out io.Writer
+
+	mu  sync.Mutex
+	w   *Writer
+	err error
+}
+
+// NewSerial wraps ser and records its traffic to out.
+func NewSerial(ser devices.Serial, out io.Writer) (*Serial, error) {
+	w, err := NewWriter(out, KindSerial, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &Serial{ser: ser, out: out, w: w}, nil
+}
+
+// Read reads from the wrapped port and records the received bytes.
+func (s *Serial) Read(p []byte) (int, error) {
+	n, err := s.ser.Read(p)
+	if n > 0 || err != nil {
+		s.record(Event{Op: OpRead, R: p[:n], Err: errString(err)})
+	}
+	return n, err
+}
+
+// Write writes to the wrapped port and records the sent bytes.
+func (s *Serial) Write(p []byte) (int, error) {
+	n, err := s.ser.Write(p)
+	s.record(Event{Op: OpWrite, W: p[:n], Err: errString(err)})
+	return n, err
+}
+
+// Buffered returns the number of bytes buffered by the wrapped port.
+func (s *Serial) Buffered() int {
+	return s.ser.Buffered()
+}
+
+// Err returns the first error encountered while writing the recording.
+func (s *Serial) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close flushes the recording and closes the output if it is an io.Closer.
+// The wrapped port is not closed.
+func (s *Serial) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil && s.err == nil {
+		s.err = err
+	}
+	if c, ok := s.out.(io.Closer); ok {
+		if err := c.Close(); err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+	return s.err
+}
+
+func (s *Serial) record(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ev.Time = s.w.Since(time.Now())
+	if err := s.w.Write(ev); err != nil && s.err == nil {
+		s.err = err
+	}
+}
+
+// SerialReplay feeds a serial recording back to a driver.
+//
+// Recorded writes act as synchronization points: bytes received after a write
+// are only delivered once the driver has performed the corresponding Write,
+// and their timing is measured from that moment. This keeps request/response
+// protocols (e.g. CR30) in step even when the driver runs at a different pace.
+// Read returns io.EOF once the recording is exhausted or the replay is closed.
+type SerialReplay struct {
+	opts   ReplayOptions
+	events []Event
+	writes []int // indices of write events
+
+	mu         sync.Mutex
+	pos        int         // next event
+	pending    []byte      // remainder of a partially consumed read
+	pendingErr error       // error to return once pending is drained
+	nwrites    int         // writes performed by the driver
+	writeWall  []time.Time // wall time of each driver write
+	pacer      *pacer
+	closed     bool
+}
+
+// NewSerialReplay creates a replay of a serial recording.
+func NewSerialReplay(log *Log, opts ReplayOptions) (*SerialReplay, error) {
+	if log.Kind != KindSerial {
+		return nil, ErrWrongKind
+	}
+	s := &SerialReplay{
+		opts:   opts,
+		events: log.Events,
+		pacer:  newPacer(opts.Speed),
+	}
+	for i, ev := range log.Events {
+		if ev.Op == OpWrite {
+			s.writes = append(s.writes, i)
+		}
+	}
+	if len(s.events) > 0 {
+		s.pacer.anchor(s.events[0].Time)
+	}
+	return s, nil
+}
+
+// OpenSerialReplay loads a serial recording from r and creates a replay.
+func OpenSerialReplay(r io.Reader, opts ReplayOptions) (*SerialReplay, error) {
+	log, err := loadKind(r, KindSerial)
+	if err != nil {
+		return nil, err
+	}
+	return NewSerialReplay(log, opts)
+}
+
+// Read delivers recorded bytes once they are due.
+// It returns 0 bytes when the next chunk is not due yet or waits for a driver write.
+func (s *SerialReplay) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, io.EOF
+	}
+	if n, ok, err := s.drainPending(p); ok {
+		s.mu.Unlock()
+		return n, err
+	}
+	for {
+		if s.pos >= len(s.events) {
+			s.mu.Unlock()
+			return 0, io.EOF
+		}
+		ev := &s.events[s.pos]
+		switch ev.Op {
+		case OpWrite:
+			k := sort.SearchInts(s.writes, s.pos)
+			if k >= s.nwrites {
+				// wait for the driver to send the recorded request
+				s.mu.Unlock()
+				s.pacer.sleep(pollInterval)
+				return 0, nil
+			}
+			s.pacer.anchorAt(ev.Time, s.writeWall[k])
+			s.pos++
+		case OpRead:
+			if wait := time.Until(s.pacer.due(ev.Time)); wait > 0 {
+				s.mu.Unlock()
+				if wait > pollInterval {
+					s.pacer.sleep(pollInterval)
+					return 0, nil
+				}
+				if !s.pacer.sleep(wait) {
+					return 0, io.EOF
+				}
+				s.mu.Lock()
+				continue
+			}
+			s.pos++
+			s.pending = ev.R
+			s.pendingErr = ev.Error()
+			n, _, err := s.drainPending(p)
+			s.mu.Unlock()
+			return n, err
+		default:
+			s.pos++
+		}
+	}
+}
+
+// drainPending copies buffered bytes of the current chunk into p.
+func (s *SerialReplay) drainPending(p []byte) (int, bool, error) {
+	if len(s.pending) == 0 && s.pendingErr == nil {
+		return 0, false, nil
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	if len(s.pending) > 0 {
+		return n, true, nil
+	}
+	err := s.pendingErr
+	s.pendingErr = nil
+	return n, true, err
+}
+
+// Write consumes the next recorded write. In strict mode the bytes must match the recording.
+func (s *SerialReplay) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	k := s.nwrites
+	if k >= len(s.writes) {
+		if s.opts.Strict {
+			return 0, fmt.Errorf("%w: unexpected write %d: % X", ErrMismatch, k, p)
+		}
+		return len(p), nil
+	}
+	ev := &s.events[s.writes[k]]
+	if s.opts.Strict && !bytes.Equal(p, ev.W) {
+		return 0, fmt.Errorf("%w: write %d: got % X, want % X", ErrMismatch, k, p, ev.W)
+	}
+	s.nwrites++
+	s.writeWall = append(s.writeWall, time.Now())
+	if err := ev.Error(); err != nil {
+		return len(ev.W), err
+	}
+	return len(p), nil
+}
+
+// Buffered returns the number of bytes that can be read without waiting.
+func (s *SerialReplay) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.pending)
+	if s.pos < len(s.events) {
+		ev := &s.events[s.pos]
+		if ev.Op == OpRead && !time.Now().Before(s.pacer.due(ev.Time)) {
+			n += len(ev.R)
+		}
+	}
+	return n
+}
+
+// Done reports whether every recorded event has been replayed.
+func (s *SerialReplay) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pos >= len(s.events) && len(s.pending) == 0
+}
+
+// Close stops the replay; subsequent reads return io.EOF.
+func (s *SerialReplay) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.pacer.close()
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build !tinygo && (linux || windows)
+
+package record
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+
+	"github.com/itohio/EasyRobot/x/devices"
+)
+
+// Session selects where OpenSerial gets its serial port from: a live port,
+// optionally recorded to a file, or a recorded session replayed in its place.
+type Session struct {
+	// Port and Config open the live serial port.
+	Port   string
+	Config devices.SerialConfig
+
+	// Record, when set, is the file the traffic of the live port is recorded to.
+	Record string
+
+	// Replay, when set, is a recording fed back instead of opening Port.
+	Replay string
+	// Speed is the replay speed multiplier (0 = as fast as possible).
+	Speed float64
+}
+
+// OpenSerial opens the serial port of a session. The returned function closes
+// the port and finishes the recording.
+func OpenSerial(s Session) (devices.Serial, func() error, error) {
+	if s.Replay != "" {
+		log, err := Load(s.Replay)
+		if err != nil {
+			return nil, nil, err
+		}
+		replay, err := NewSerialReplay(log, ReplayOptions{Speed: s.Speed})
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Info("Replaying recorded session", "file", s.Replay, "duration", log.Duration())
+		return replay, replay.Close, nil
+	}
+
+	ser, err := devices.NewSerialWithConfig(s.Port, s.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.Record == "" {
+		return ser, ser.Close, nil
+	}
+
+	f, err := os.Create(s.Record)
+	if err != nil {
+		ser.Close()
+		return nil, nil, err
+	}
+	rec, err := NewSerial(ser, f)
+	if err != nil {
+		f.Close()
+		ser.Close()
+		return nil, nil, err
+	}
+	slog.Info("Recording serial traffic", "file", s.Record)
+	return rec, func() error {
+		return errors.Join(rec.Close(), ser.Close())
+	}, nil
+}
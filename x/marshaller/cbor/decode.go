@@ -0,0 +1,339 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/document"
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// maxDepth bounds the nesting of arrays, maps and tags.
+const maxDepth = 512
+
+// indefinite is the argument of heads with additional information 31.
+const indefinite = math.MaxUint64
+
+// decoder reads documents from an in-memory message. Tensors may share
+// memory with buf.
+type decoder struct {
+	buf   []byte
+	pos   int
+	depth int
+	opts  types.Options
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.buf)-d.pos {
+		return nil, fmt.Errorf("cbor: unexpected end of data at offset %d", d.pos)
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// head reads an initial byte and its argument.
+func (d *decoder) head() (major byte, arg uint64, err error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	major, ai := b[0]>>5, b[0]&31
+	switch {
+	case ai < 24:
+		return major, uint64(ai), nil
+	case ai == 31:
+		return major, indefinite, nil
+	case ai > 27:
+		return 0, 0, fmt.Errorf("cbor: invalid additional information %d at offset %d", ai, d.pos-1)
+	}
+	p, err := d.next(1 << (ai - 24))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, c := range p {
+		arg = arg<<8 | uint64(c)
+	}
+	return major, arg, nil
+}
+
+// length checks that at least min bytes per item remain.
+func (d *decoder) length(n uint64, min int) (int, error) {
+	if n > uint64(len(d.buf)-d.pos)/uint64(min) {
+		return 0, fmt.Errorf("cbor: length %d exceeds the data at offset %d", n, d.pos)
+	}
+	return int(n), nil
+}
+
+// isBreak consumes the break byte ending an indefinite length item.
+func (d *decoder) isBreak() bool {
+	if d.pos < len(d.buf) && d.buf[d.pos] == 0xff {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *decoder) enter() error {
+	if d.depth++; d.depth > maxDepth {
+		return fmt.Errorf("cbor: nesting deeper than %d", maxDepth)
+	}
+	return nil
+}
+
+func (d *decoder) decode() (any, error) {
+	start := d.pos
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if arg == indefinite && (major < majorBytes || major == majorTag) {
+		return nil, fmt.Errorf("cbor: invalid indefinite length item at offset %d", d.pos-1)
+	}
+	switch major {
+	case majorUint:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: negative integer -1-%d out of range", arg)
+		}
+		return -1 - int64(arg), nil
+	case majorBytes, majorText:
+		b, err := d.decodeString(major, arg)
+		if err != nil || major == majorBytes {
+			return b, err
+		}
+		return string(b), nil
+	case majorArray:
+		return d.decodeArray(arg)
+	case majorMap:
+		return d.decodeMap(arg)
+	case majorTag:
+		return d.decodeTag(arg)
+	}
+
+	switch ai := d.buf[start] & 31; {
+	case ai == 25:
+		return rawtensor.HalfToFloat32(uint16(arg)), nil
+	case ai == 26:
+		return math.Float32frombits(uint32(arg)), nil
+	case ai == 27:
+		return math.Float64frombits(arg), nil
+	case arg == 20:
+		return false, nil
+	case arg == 21:
+		return true, nil
+	case arg == 22, arg == 23: // null, undefined
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", arg)
+}
+
+// decodeString returns a copy of a byte or text string, joining the chunks
+// of indefinite length strings.
+func (d *decoder) decodeString(major byte, arg uint64) ([]byte, error) {
+	if arg != indefinite {
+		n, err := d.length(arg, 1)
+		if err != nil {
+			return nil, err
+		}
+		p, err := d.next(n)
+		return append([]byte(nil), p...), err
+	}
+	out := []byte{}
+	for !d.isBreak() {
+		m, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || n == indefinite {
+			return nil, fmt.Errorf("cbor: invalid chunk in indefinite length string at offset %d", d.pos)
+		}
+		size, err := d.length(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		p, err := d.next(size)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p...)
+	}
+	return out, nil
+}
+
+func (d *decoder) decodeArray(arg uint64) (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+	if arg == indefinite {
+		list := []any{}
+		for !d.isBreak() {
+			v, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	n, err := d.length(arg, 1)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]any, n)
+	for i := range list {
+		if list[i], err = d.decode(); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (d *decoder) decodeMap(arg uint64) (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+	n := 0
+	if arg != indefinite {
+		var err error
+		if n, err = d.length(arg, 2); err != nil {
+			return nil, err
+		}
+	}
+	doc := make(document.Document, n)
+	for i := 0; arg == indefinite || i < n; i++ {
+		if arg == indefinite && d.isBreak() {
+			break
+		}
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		switch k := k.(type) {
+		case string:
+			key = k
+		case int64:
+			key = strconv.FormatInt(k, 10)
+		case uint64:
+			key = strconv.FormatUint(k, 10)
+		default:
+			return nil, fmt.Errorf("cbor: unsupported map key %T", k)
+		}
+		if doc[key], err = d.decode(); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// decodeTag decodes tensors and skips the self-described tag; the content
+// of other tags is returned as is.
+func (d *decoder) decodeTag(tag uint64) (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+	switch {
+	case tag == TagMultiDimArray:
+		return d.decodeMultiDimArray()
+	case tag >= TagTypedArrayFirst && tag <= TagTypedArrayLast:
+		data, err := d.typedArrayData()
+		if err != nil {
+			return nil, err
+		}
+		return d.tensor(tag, nil, data)
+	}
+	return d.decode()
+}
+
+func (d *decoder) decodeMultiDimArray() (any, error) {
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != majorArray || n != 2 {
+		return nil, fmt.Errorf("cbor: multi-dimensional array must be [dimensions, elements]")
+	}
+	dims, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	shape, err := document.Ints(dims)
+	if err != nil {
+		return nil, fmt.Errorf("cbor: multi-dimensional array dimensions: %w", err)
+	}
+	major, tag, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != majorTag || tag < TagTypedArrayFirst || tag > TagTypedArrayLast {
+		return nil, fmt.Errorf("cbor: multi-dimensional array elements must be a typed array")
+	}
+	data, err := d.typedArrayData()
+	if err != nil {
+		return nil, err
+	}
+	return d.tensor(tag, shape, data)
+}
+
+// typedArrayData returns the bytes of a typed array without copying them.
+func (d *decoder) typedArrayData() ([]byte, error) {
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != majorBytes || n == indefinite {
+		return nil, fmt.Errorf("cbor: typed array must be a definite length byte string")
+	}
+	size, err := d.length(n, 1)
+	if err != nil {
+		return nil, err
+	}
+	return d.next(size)
+}
+
+// tensor builds a tensor from typed array data; a nil shape is 1-D.
+func (d *decoder) tensor(tag uint64, shape []int, data []byte) (types.Tensor, error) {
+	var elem rawtensor.Elem
+	ll := int(tag & 3)
+	little := tag&4 != 0
+	switch {
+	case tag&16 != 0:
+		elem = rawtensor.Elem{Kind: rawtensor.Float, Size: 2 << ll}
+	case tag&8 != 0:
+		elem = rawtensor.Elem{Kind: rawtensor.Int, Size: 1 << ll}
+	default:
+		elem = rawtensor.Elem{Kind: rawtensor.Uint, Size: 1 << ll}
+	}
+	if len(data)%elem.Size != 0 {
+		return nil, fmt.Errorf("cbor: %d bytes of typed array %d", len(data), tag)
+	}
+	if shape == nil {
+		shape = []int{len(data) / elem.Size}
+	}
+	n := 1
+	for _, dim := range shape {
+		if dim < 0 {
+			return nil, fmt.Errorf("cbor: negative dimension in %v", shape)
+		}
+		if dim != 0 && n*elem.Size > len(data)/dim {
+			return nil, fmt.Errorf("cbor: shape %v exceeds %d bytes", shape, len(data))
+		}
+		n *= dim
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if little || elem.Size == 1 {
+		order = binary.LittleEndian
+	}
+	return document.Tensor(elem, order, types.Shape(shape), data, d.opts)
+}
@@ -0,0 +1,26 @@
+// Package cbor implements CBOR (RFC 8949) marshalling/unmarshalling for
+// EasyRobot domain objects: tensors, mat and vec matrices and vectors,
+// layers, models, graphs and trees (see internal/document for the layout).
+//
+// Every message starts with the self-described CBOR tag 55799 followed by
+// the map {"schema": "easyrobot", "version": 1, "value": ...}. Tensors are
+// RFC 8746 row-major multi-dimensional arrays
+//
+//	40([[dim0, dim1, ...], 85(h'<little endian float32 elements>')])
+//
+// with the typed array tag giving the element type (64 uint8, 72 int8,
+// 77/78/79 int16/32/64, 84/85/86 float16/32/64, all little endian), so any
+// CBOR library exposing tags reads them, e.g. in Python
+//
+//	tag = cbor2.loads(data)["value"]["tensor"]
+//	dims, elements = tag.value
+//	array = numpy.frombuffer(elements.value, "<f4").reshape(dims)
+//
+// The heads in front of the elements are lengthened where needed so that
+// the elements are aligned to their size relative to the start of the
+// message. Marshal writes the elements straight from the tensor storage, and
+// Unmarshal returns tensors sharing memory with the message it read unless
+// a tensor factory or destination type is set. Decoding also accepts big
+// endian typed arrays, standalone typed arrays as 1-D tensors and indefinite
+// length items; other tags are ignored.
+package cbor
@@ -0,0 +1,261 @@
+package cbor
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// CBOR major types.
+const (
+	majorUint = iota
+	majorNegInt
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+// Tags used by the backend.
+const (
+	// TagSelfDescribed starts every message (RFC 8949).
+	TagSelfDescribed = 55799
+	// TagMultiDimArray holds a row-major [dimensions, elements] array (RFC 8746).
+	TagMultiDimArray = 40
+	// TagTypedArrayFirst and TagTypedArrayLast bound the typed array tags (RFC 8746).
+	TagTypedArrayFirst = 64
+	TagTypedArrayLast  = 87
+)
+
+// headSizes are the encoded sizes of a head: the initial byte alone or
+// followed by a 1, 2, 4 or 8 byte argument.
+var headSizes = [...]int{1, 2, 3, 5, 9}
+
+// headSize returns the size of the shortest head holding v.
+func headSize(v uint64) int {
+	switch {
+	case v < 24:
+		return 1
+	case v <= math.MaxUint8:
+		return 2
+	case v <= math.MaxUint16:
+		return 3
+	case v <= math.MaxUint32:
+		return 5
+	}
+	return 9
+}
+
+// encoder writes documents, keeping track of the stream position so that
+// typed array elements can be aligned.
+type encoder struct {
+	w   *bufio.Writer
+	pos int
+	buf [9]byte
+}
+
+func (e *encoder) write(p []byte) error {
+	n, err := e.w.Write(p)
+	e.pos += n
+	return err
+}
+
+// headN writes a head of the given size, which must be able to hold v.
+func (e *encoder) headN(major byte, v uint64, size int) error {
+	if size == 1 {
+		e.buf[0] = major<<5 | byte(v)
+		return e.write(e.buf[:1])
+	}
+	n := size - 1
+	e.buf[0] = major<<5 | byte(24+bits.TrailingZeros(uint(n)))
+	for i := 0; i < n; i++ {
+		e.buf[n-i] = byte(v >> (8 * i))
+	}
+	return e.write(e.buf[:size])
+}
+
+func (e *encoder) head(major byte, v uint64) error {
+	return e.headN(major, v, headSize(v))
+}
+
+func (e *encoder) encode(v any) error {
+	switch x := v.(type) {
+	case nil:
+		return e.head(majorSimple, 22)
+	case bool:
+		if x {
+			return e.head(majorSimple, 21)
+		}
+		return e.head(majorSimple, 20)
+	case int:
+		return e.encodeInt(int64(x))
+	case int8:
+		return e.encodeInt(int64(x))
+	case int16:
+		return e.encodeInt(int64(x))
+	case int32:
+		return e.encodeInt(int64(x))
+	case int64:
+		return e.encodeInt(x)
+	case uint:
+		return e.head(majorUint, uint64(x))
+	case uint8:
+		return e.head(majorUint, uint64(x))
+	case uint16:
+		return e.head(majorUint, uint64(x))
+	case uint32:
+		return e.head(majorUint, uint64(x))
+	case uint64:
+		return e.head(majorUint, x)
+	case float32:
+		return e.headN(majorSimple, uint64(math.Float32bits(x)), 5)
+	case float64:
+		return e.headN(majorSimple, math.Float64bits(x), 9)
+	case string:
+		if err := e.head(majorText, uint64(len(x))); err != nil {
+			return err
+		}
+		_, err := e.w.WriteString(x)
+		e.pos += len(x)
+		return err
+	case []byte:
+		if err := e.head(majorBytes, uint64(len(x))); err != nil {
+			return err
+		}
+		return e.write(x)
+	case []any:
+		if err := e.head(majorArray, uint64(len(x))); err != nil {
+			return err
+		}
+		for _, item := range x {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		if err := e.head(majorMap, uint64(len(x))); err != nil {
+			return err
+		}
+		// length-first order, as in the RFC 8949 deterministic encoding
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		for _, k := range keys {
+			if err := e.encode(k); err != nil {
+				return err
+			}
+			if err := e.encode(x[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case types.Tensor:
+		return e.encodeTensor(x)
+	}
+	return fmt.Errorf("cbor: unsupported type %T", v)
+}
+
+func (e *encoder) encodeInt(n int64) error {
+	if n >= 0 {
+		return e.head(majorUint, uint64(n))
+	}
+	return e.head(majorNegInt, uint64(-1-n))
+}
+
+// typedArrayTag returns the RFC 8746 tag of little endian elements.
+func typedArrayTag(elem rawtensor.Elem) (uint64, error) {
+	ll := uint64(bits.TrailingZeros(uint(elem.Size)))
+	switch {
+	case elem.Kind == rawtensor.Float && (elem.Size == 2 || elem.Size == 4 || elem.Size == 8):
+		return 0b1010100 + ll - 1, nil
+	case elem.Kind == rawtensor.Uint && elem.Size == 1:
+		return 0b1000000, nil
+	case elem.Kind == rawtensor.Int && elem.Size == 1:
+		return 0b1001000, nil
+	case elem.Kind == rawtensor.Uint && elem.Size <= 8 && elem.Size&(elem.Size-1) == 0:
+		return 0b1000100 + ll, nil
+	case elem.Kind == rawtensor.Int && elem.Size <= 8 && elem.Size&(elem.Size-1) == 0:
+		return 0b1001100 + ll, nil
+	}
+	return 0, fmt.Errorf("cbor: no typed array for %v", elem)
+}
+
+// encodeTensor writes tag 40 [[dims...], tag 64..87 bytes]. The elements are
+// aligned to their size relative to the start of the message by using
+// longer than necessary heads for the tag and the arrays in front of them,
+// which decoders must accept.
+func (e *encoder) encodeTensor(t types.Tensor) error {
+	elem, data, err := rawtensor.Bytes(t)
+	if err != nil {
+		return err
+	}
+	tag, err := typedArrayTag(elem)
+	if err != nil {
+		return err
+	}
+	shape := t.Shape()
+	dims := 0
+	for _, d := range shape {
+		if d < 0 {
+			return fmt.Errorf("cbor: negative dimension %d", d)
+		}
+		dims += headSize(uint64(d))
+	}
+	fixed := dims + headSize(tag) + headSize(uint64(len(data)))
+
+	// outer tag, [dims, data] and dims heads
+	best, sizes := -1, [3]int{}
+	for _, a := range headSizes[1:] {
+		for _, b := range headSizes {
+			for _, c := range headSizes {
+				if c < headSize(uint64(len(shape))) {
+					continue
+				}
+				total := a + b + c
+				if (e.pos+total+fixed)%elem.Size == 0 && (best < 0 || total < best) {
+					best, sizes = total, [3]int{a, b, c}
+				}
+			}
+		}
+	}
+	if best < 0 {
+		sizes = [3]int{2, 1, headSize(uint64(len(shape)))}
+	}
+
+	if err := e.headN(majorTag, TagMultiDimArray, sizes[0]); err != nil {
+		return err
+	}
+	if err := e.headN(majorArray, 2, sizes[1]); err != nil {
+		return err
+	}
+	if err := e.headN(majorArray, uint64(len(shape)), sizes[2]); err != nil {
+		return err
+	}
+	for _, d := range shape {
+		if err := e.head(majorUint, uint64(d)); err != nil {
+			return err
+		}
+	}
+	if err := e.head(majorTag, tag); err != nil {
+		return err
+	}
+	if err := e.head(majorBytes, uint64(len(data))); err != nil {
+		return err
+	}
+	return e.write(data)
+}
@@ -0,0 +1,65 @@
+package cbor
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/document"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+)
+
+// Marshaller implements CBOR marshalling.
+type Marshaller struct {
+	opts types.Options
+}
+
+// NewMarshaller creates a new CBOR marshaller.
+func NewMarshaller(opts ...types.Option) *Marshaller {
+	m := &Marshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&m.opts)
+	}
+	return m
+}
+
+// Format returns the format name.
+func (m *Marshaller) Format() string {
+	return "cbor"
+}
+
+// Marshal encodes value to CBOR.
+func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	localOpts := m.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+
+	if value == nil {
+		return types.NewError("marshal", "cbor", "nil value", nil)
+	}
+	version, err := versioning.Target(schemaName, localOpts, SchemaVersion)
+	if err != nil {
+		return types.NewError("marshal", "cbor", "version", err)
+	}
+
+	doc, err := document.Encode(value)
+	if err != nil {
+		return types.NewError("marshal", "cbor", "value conversion", err)
+	}
+
+	enc := &encoder{w: bufio.NewWriter(w)}
+	err = enc.head(majorTag, TagSelfDescribed)
+	if err == nil {
+		err = enc.encode(document.Wrap(doc, version))
+	}
+	if err != nil {
+		return types.NewError("marshal", "cbor", "encoding", err)
+	}
+	if err := enc.w.Flush(); err != nil {
+		return types.NewError("marshal", "cbor", "encoding", err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package cbor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/document"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+	"github.com/itohio/EasyRobot/x/math/graph"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/nn"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+func roundTrip(t *testing.T, value, dst any, opts ...types.Option) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NewMarshaller().Marshal(&buf, value); err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	data := buf.Bytes()
+	if err := NewUnmarshaller(opts...).Unmarshal(bytes.NewReader(data), dst); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	return data
+}
+
+func TestTensorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		shape []int
+		dtype types.DataType
+	}{
+		{"Vector_FP32", []int{5}, types.FP32},
+		{"Tensor_FP32", []int{2, 3, 4}, types.FP32},
+		{"Matrix_FP64", []int{2, 5}, types.FP64},
+		{"Matrix_INT64", []int{3, 2}, types.INT64},
+		{"Matrix_INT32", []int{3, 2}, types.INT32},
+		{"Matrix_INT16", []int{3, 2}, types.INT16},
+		{"Matrix_INT8", []int{3, 2}, types.INT8},
+		{"Image_UINT8", []int{2, 2, 3}, types.UINT8},
+		{"Empty_FP32", []int{0, 3}, types.FP32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tensor.New(tt.dtype, tensor.NewShape(tt.shape...))
+			data := reflect.ValueOf(original.Data())
+			for i := range data.Len() {
+				data.Index(i).Set(reflect.ValueOf(i % 100).Convert(data.Type().Elem()))
+			}
+
+			var result types.Tensor
+			roundTrip(t, original, &result)
+
+			if result.DataType() != original.DataType() {
+				t.Errorf("DataType = %v, want %v", result.DataType(), original.DataType())
+			}
+			if !reflect.DeepEqual([]int(result.Shape()), []int(original.Shape())) {
+				t.Fatalf("Shape = %v, want %v", result.Shape(), original.Shape())
+			}
+			if !reflect.DeepEqual(result.Data(), original.Data()) {
+				t.Errorf("Data = %v, want %v", result.Data(), original.Data())
+			}
+		})
+	}
+}
+
+func TestTensorTypeConversion(t *testing.T) {
+	original := tensor.FromArray(tensor.NewShape(2, 2), []float32{1, 2, 3, 4})
+	var result types.Tensor
+	roundTrip(t, original, &result, types.WithDestinationType(types.FP64))
+	if result.DataType() != types.FP64 {
+		t.Fatalf("DataType = %v, want FP64", result.DataType())
+	}
+	if result.At(1, 1) != 4 {
+		t.Errorf("At(1, 1) = %v, want 4", result.At(1, 1))
+	}
+}
+
+// TestTensorLayout parses a tensor the way a generic CBOR reader would,
+// following RFC 8746.
+func TestTensorLayout(t *testing.T) {
+	var buf bytes.Buffer
+	enc := &encoder{w: bufio.NewWriter(&buf)}
+	if err := enc.encode(tensor.FromArray(tensor.NewShape(2, 3), []float32{1, 2, 3, 4, 5, 6})); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	d := &decoder{buf: buf.Bytes()}
+	expect := func(major byte, arg uint64) {
+		t.Helper()
+		m, a, err := d.head()
+		if err != nil || m != major || a != arg {
+			t.Fatalf("head = %d %d %v, want %d %d", m, a, err, major, arg)
+		}
+	}
+	expect(majorTag, TagMultiDimArray)
+	expect(majorArray, 2)
+	expect(majorArray, 2)
+	expect(majorUint, 2)
+	expect(majorUint, 3)
+	expect(majorTag, 85) // float32, little endian
+	expect(majorBytes, 24)
+	if d.pos%4 != 0 {
+		t.Errorf("elements at offset %d are not aligned", d.pos)
+	}
+	for i := range 6 {
+		if v := math.Float32frombits(binary.LittleEndian.Uint32(d.buf[d.pos+4*i:])); v != float32(i+1) {
+			t.Errorf("element %d = %v", i, v)
+		}
+	}
+}
+
+// TestForeignMessage decodes a message as other encoders may write it:
+// without the self-described tag, with an indefinite length map and a big
+// endian typed array.
+func TestForeignMessage(t *testing.T) {
+	msg := []byte("\xa3" +
+		"\x66schema\x69easyrobot" +
+		"\x67version\x01" +
+		"\x65value\xbf" +
+		"\x64kind\x66tensor" +
+		"\x66tensor\xd8\x28\x82\x81\x02\xd8\x51\x48\x3f\x80\x00\x00\x40\x00\x00\x00" +
+		"\xff")
+	var got types.Tensor
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(msg), &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.DataType() != types.FP32 || got.At(0) != 1 || got.At(1) != 2 {
+		t.Errorf("got %v %v", got.DataType(), got.Data())
+	}
+}
+
+func TestTensorZeroCopy(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMarshaller().Marshal(&buf, tensor.FromArray(tensor.NewShape(4), []float32{1, 2, 3, 4})); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	d := &decoder{buf: data}
+	v, err := d.decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := document.Unwrap(schemaName, v, SchemaVersion, types.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := doc["tensor"].(types.Tensor)
+
+	// overwrite the last element in the message
+	last := bytes.Index(data, binary.LittleEndian.AppendUint32(nil, math.Float32bits(4)))
+	binary.LittleEndian.PutUint32(data[last:], math.Float32bits(42))
+	if got.At(3) != 42 {
+		t.Errorf("tensor does not share memory with the message: At(3) = %v", got.At(3))
+	}
+}
+
+func TestMathRoundTrip(t *testing.T) {
+	m := mat.Matrix3x3{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	var gotM mat.Matrix3x3
+	roundTrip(t, m, &gotM)
+	if gotM != m {
+		t.Errorf("Matrix3x3 = %v, want %v", gotM, m)
+	}
+
+	v := vec.Vector{1, 2, 3}
+	var gotV vec.Vector
+	roundTrip(t, v, &gotV)
+	if !reflect.DeepEqual(gotV, v) {
+		t.Errorf("Vector = %v, want %v", gotV, v)
+	}
+}
+
+func TestModelRoundTrip(t *testing.T) {
+	dense1, err := layers.NewDense(2, 4, layers.UseBias(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dense2, err := layers.NewDense(4, 1, layers.UseBias(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := nn.NewSequentialModelBuilder(tensor.NewShape(2)).
+		AddLayer(dense1).
+		AddLayer(layers.NewTanh("tanh")).
+		AddLayer(dense2).
+		AddLayer(layers.NewSigmoid("sigmoid")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Init(tensor.NewShape(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	var restored types.Model
+	roundTrip(t, model, &restored)
+	if restored.LayerCount() != model.LayerCount() {
+		t.Fatalf("LayerCount = %d, want %d", restored.LayerCount(), model.LayerCount())
+	}
+	for _, x := range [][]float32{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		want, err := model.Forward(tensor.FromArray(tensor.NewShape(2), x))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := restored.Forward(tensor.FromArray(tensor.NewShape(2), x))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.At(0) != want.At(0) {
+			t.Errorf("Forward(%v) = %v, want %v", x, got.At(0), want.At(0))
+		}
+	}
+}
+
+func TestTreeRoundTrip(t *testing.T) {
+	tree := graph.NewGenericTree[any, any]("root")
+	child := tree.AddChild(tree.RootIdx(), "child")
+	tree.AddChild(child, int64(7))
+
+	var got *graph.GenericTree[any, any]
+	roundTrip(t, tree, &got)
+	if got.NodeCount() != 3 || got.GetHeight() != tree.GetHeight() {
+		t.Errorf("decoded %d nodes of height %d, want 3 of height %d", got.NodeCount(), got.GetHeight(), tree.GetHeight())
+	}
+}
+
+func TestSliceRoundTrip(t *testing.T) {
+	var got []int
+	roundTrip(t, []int{3, -1, 4}, &got)
+	if !reflect.DeepEqual(got, []int{3, -1, 4}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestFutureVersion(t *testing.T) {
+	doc, err := document.Encode(tensor.FromArray(tensor.NewShape(2), []float32{1, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	enc := &encoder{w: bufio.NewWriter(&buf)}
+	if err := enc.encode(document.Wrap(doc, SchemaVersion+1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got types.Tensor
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(buf.Bytes()), &got); err != nil {
+		t.Fatalf("best effort decoding failed: %v", err)
+	}
+	err = NewUnmarshaller(versioning.WithStrict(true)).Unmarshal(bytes.NewReader(buf.Bytes()), &got)
+	var future *versioning.FutureVersionError
+	if !errors.As(err, &future) || future.Version != SchemaVersion+1 {
+		t.Fatalf("expected future version error, got %v", err)
+	}
+}
+
+func TestInvalidInput(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMarshaller().Marshal(&buf, tensor.FromArray(tensor.NewShape(2, 2), []float32{1, 2, 3, 4})); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	var got types.Tensor
+	for n := 0; n < len(data); n++ {
+		if err := NewUnmarshaller().Unmarshal(bytes.NewReader(data[:n]), &got); err == nil {
+			t.Errorf("truncated message of %d bytes decoded", n)
+		}
+	}
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(append(data, 0xf6)), &got); err == nil {
+		t.Error("trailing bytes accepted")
+	}
+	// an array nested deeper than maxDepth
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(bytes.Repeat([]byte{0x81}, maxDepth+1)), &got); err == nil {
+		t.Error("deep nesting accepted")
+	}
+}
@@ -0,0 +1,21 @@
+package cbor
+
+import (
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "cbor",
+		Extensions: []string{".cbor"},
+		// self-described CBOR tag 55799
+		Magic: []registry.Magic{{Bytes: "\xd9\xd9\xf7"}},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
@@ -0,0 +1,6 @@
+package cbor
+
+// SchemaVersion is the payload version written by Marshal.
+const SchemaVersion = 1
+
+const schemaName = "cbor"
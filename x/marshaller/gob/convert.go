@@ -4,6 +4,9 @@ import (
 	"fmt"
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+	nntypes "github.com/itohio/EasyRobot/x/math/nn/types"
 	"github.com/itohio/EasyRobot/x/math/tensor"
 )
 
@@ -131,3 +134,32 @@ func gobToParameter(gp gobParameter, tensorFactory func(types.DataType, types.Sh
 
 	return result
 }
+
+func gobToLayerSpec(gl gobLayer, tensorFactory func(types.DataType, types.Shape) types.Tensor) layers.Spec {
+	spec := layers.Spec{
+		Type:       gl.Type,
+		Name:       gl.Name,
+		CanLearn:   gl.CanLearn,
+		Config:     gl.Config,
+		InputShape: gl.InputShape,
+		Parameters: make(map[nntypes.ParamIndex]types.Parameter, len(gl.Parameters)),
+	}
+	for idx, gp := range gl.Parameters {
+		spec.Parameters[nntypes.ParamIndex(idx)] = gobToParameter(gp, tensorFactory)
+	}
+	return spec
+}
+
+// gobToModel rebuilds a Sequential model from its layers.
+func gobToModel(gm gobModel, tensorFactory func(types.DataType, types.Shape) types.Tensor) (*models.Sequential, error) {
+	spec := models.Spec{
+		Name:       gm.Name,
+		CanLearn:   gm.CanLearn,
+		InputShape: gm.InputShape,
+		Layers:     make([]layers.Spec, len(gm.Layers)),
+	}
+	for i, gl := range gm.Layers {
+		spec.Layers[i] = gobToLayerSpec(gl, tensorFactory)
+	}
+	return models.Restore(spec)
+}
@@ -1,7 +1,11 @@
 package gob
 
 import (
+	"reflect"
+
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/tensor"
 )
 
 // Internal structs for gob encoding/decoding.
@@ -26,10 +30,11 @@ type gobParameter struct {
 // gobLayer represents a layer for gob encoding/decoding.
 type gobLayer struct {
 	Name       string
-	Type       string // full type name from reflection
+	Type       string // registered layer type, or full type name from reflection
 	CanLearn   bool
 	InputShape []int
 	Parameters map[int]gobParameter // ParamIndex -> Parameter
+	Config     map[string]string    // constructor arguments of registered layers
 	// Layer-specific data stored as raw bytes
 	ExtraData []byte
 }
@@ -40,6 +45,7 @@ type gobModel struct {
 	Type       string // full type name from reflection
 	CanLearn   bool
 	LayerCount int
+	InputShape []int
 	Layers     []gobLayer
 	Parameters map[int]gobParameter // ParamIndex -> Parameter
 	// Model-specific data stored as raw bytes
@@ -79,10 +85,10 @@ func parameterToGob(p types.Parameter) gobParameter {
 		RequiresGrad: p.RequiresGrad,
 	}
 
-	if !p.Data.Empty() {
+	if !tensor.IsNil(p.Data) {
 		result.Data = tensorToGob(p.Data)
 	}
-	if !p.Grad.Empty() {
+	if !tensor.IsNil(p.Grad) {
 		result.Grad = tensorToGob(p.Grad)
 	}
 
@@ -92,13 +98,20 @@ func parameterToGob(p types.Parameter) gobParameter {
 func layerToGob(layer types.Layer) gobLayer {
 	result := gobLayer{
 		Name:       layer.Name(),
+		Type:       reflect.TypeOf(layer).String(),
 		CanLearn:   layer.CanLearn(),
 		Parameters: make(map[int]gobParameter),
 	}
 
+	// Registered layers carry their constructor arguments
+	if name, ok := layers.TypeName(layer); ok {
+		result.Type = name
+		result.Config = layer.(layers.Configurable).Config()
+	}
+
 	// Get input shape if available
 	input := layer.Input()
-	if !input.Empty() {
+	if !tensor.IsNil(input) {
 		result.InputShape = input.Shape()
 	}
 
@@ -120,6 +133,10 @@ func modelToGob(model types.Model) gobModel {
 		Parameters: make(map[int]gobParameter),
 	}
 
+	if m, ok := model.(interface{ InputShape() tensor.Shape }); ok {
+		result.InputShape = m.InputShape()
+	}
+
 	// Convert layers
 	for i := 0; i < model.LayerCount(); i++ {
 		layer := model.GetLayer(i)
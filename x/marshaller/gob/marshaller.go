@@ -6,6 +6,7 @@ import (
 	"reflect"
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
 )
 
 // Marshaller implements gob-based marshalling.
@@ -41,6 +42,11 @@ func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error
 		return types.NewError("marshal", "gob", "nil value", nil)
 	}
 
+	version, err := versioning.Target(schemaName, localOpts, SchemaVersion)
+	if err != nil {
+		return types.NewError("marshal", "gob", "version", err)
+	}
+
 	encoder := gob.NewEncoder(w)
 
 	// Convert value to gobValue
@@ -49,8 +55,12 @@ func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error
 		return types.NewError("marshal", "gob", "value conversion", err)
 	}
 
-	// Encode gobValue
-	if err := encoder.Encode(gv); err != nil {
+	// Encode gobValue, bare for version 0 readers
+	var payload any = &gobEnvelope{Schema: envelopeName, Version: version, Value: gv}
+	if version == 0 {
+		payload = gv
+	}
+	if err := encoder.Encode(payload); err != nil {
 		return types.NewError("marshal", "gob", "encoding", err)
 	}
 
@@ -75,7 +85,6 @@ func (m *Marshaller) valueToGob(value any) (*gobValue, error) {
 	// Then check for Layer
 	if layer, ok := value.(types.Layer); ok {
 		gl := layerToGob(layer)
-		gl.Type = reflect.TypeOf(value).String()
 		return &gobValue{
 			Kind:  "layer",
 			Layer: &gl,
@@ -0,0 +1,19 @@
+package gob
+
+import (
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "gob",
+		Extensions: []string{".gob"},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
@@ -7,6 +7,8 @@ import (
 	"reflect"
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
 	"github.com/itohio/EasyRobot/x/math/tensor"
 )
 
@@ -39,16 +41,13 @@ func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) err
 		opt.Apply(&localOpts)
 	}
 
-	decoder := gob.NewDecoder(r)
-
-	// Decode gobValue
-	var gv gobValue
-	if err := decoder.Decode(&gv); err != nil {
+	gv, err := decodeVersioned(r, localOpts)
+	if err != nil {
 		return types.NewError("unmarshal", "gob", "decoding", err)
 	}
 
 	// Convert gobValue to actual value
-	value, err := u.gobToValue(&gv, localOpts)
+	value, err := u.gobToValue(gv, localOpts)
 	if err != nil {
 		return types.NewError("unmarshal", "gob", "conversion", err)
 	}
@@ -61,6 +60,38 @@ func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) err
 	return nil
 }
 
+// decodeVersioned decodes an enveloped or a bare version 0 gobValue from the
+// stream and migrates it to SchemaVersion.
+func decodeVersioned(r io.Reader, opts types.Options) (*gobValue, error) {
+	var payload gobPayload
+	if err := gob.NewDecoder(r).Decode(&payload); err != nil {
+		return nil, err
+	}
+	version, value := payload.unwrap()
+	if value == nil {
+		return nil, fmt.Errorf("envelope without value")
+	}
+	if err := versioning.Check(schemaName, version, SchemaVersion, opts); err != nil {
+		return nil, err
+	}
+	if version >= SchemaVersion || !versioning.NeedsMigration(schemaName, version, SchemaVersion) {
+		return value, nil
+	}
+
+	doc, err := versioning.ToDocument(value)
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = versioning.Migrate(schemaName, doc, version, SchemaVersion); err != nil {
+		return nil, err
+	}
+	var gv gobValue
+	if err := versioning.FromDocument(doc, &gv); err != nil {
+		return nil, err
+	}
+	return &gv, nil
+}
+
 func (u *Unmarshaller) gobToValue(gv *gobValue, opts types.Options) (any, error) {
 	if gv == nil {
 		return nil, fmt.Errorf("nil gobValue")
@@ -89,20 +120,14 @@ func (u *Unmarshaller) gobToValue(gv *gobValue, opts types.Options) (any, error)
 		if gv.Layer == nil {
 			return nil, fmt.Errorf("nil layer in gobValue")
 		}
-		// For layers, we cannot fully reconstruct them without type information
-		// This would require a layer factory based on the Type field
-		// For now, we'll return the gobLayer itself
-		// In a full implementation, you'd need a registry of layer constructors
-		return *gv.Layer, nil
+		// Layers are rebuilt from the layer registry by type name
+		return layers.Restore(gobToLayerSpec(*gv.Layer, tensorFactory))
 
 	case "model":
 		if gv.Model == nil {
 			return nil, fmt.Errorf("nil model in gobValue")
 		}
-		// For models, similar to layers, we cannot fully reconstruct them
-		// This would require a model factory based on the Type field
-		// For now, we'll return the gobModel itself
-		return *gv.Model, nil
+		return gobToModel(*gv.Model, tensorFactory)
 
 	case "slice":
 		// For slices, gob already decoded the data
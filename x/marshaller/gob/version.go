@@ -0,0 +1,55 @@
+package gob
+
+import "github.com/itohio/EasyRobot/x/marshaller/versioning"
+
+// SchemaVersion is the payload version written by Marshal. Version 0 is a
+// bare gobValue from before payloads were versioned.
+const SchemaVersion = 1
+
+const (
+	schemaName   = "gob"
+	envelopeName = "easyrobot"
+)
+
+// gobEnvelope wraps every payload with its schema version.
+type gobEnvelope struct {
+	Schema  string // always envelopeName
+	Version int
+	Value   *gobValue
+}
+
+// gobPayload decodes either layout in a single pass. gob matches struct
+// fields by name, so an envelope fills Schema, Version and Value while a bare
+// version 0 gobValue fills the remaining fields, which mirror gobValue.
+type gobPayload struct {
+	Schema  string
+	Version int
+	Value   *gobValue
+
+	Kind      string
+	Tensor    *gobTensor
+	Layer     *gobLayer
+	Model     *gobModel
+	SliceType string
+	SliceData any
+}
+
+// unwrap returns the schema version and value of the payload.
+func (p *gobPayload) unwrap() (int, *gobValue) {
+	if p.Schema == envelopeName {
+		return p.Version, p.Value
+	}
+	return 0, &gobValue{
+		Kind:      p.Kind,
+		Tensor:    p.Tensor,
+		Layer:     p.Layer,
+		Model:     p.Model,
+		SliceType: p.SliceType,
+		SliceData: p.SliceData,
+	}
+}
+
+func init() {
+	// v1 only introduced the envelope
+	versioning.Register(schemaName, 0, 1, nil)
+}
@@ -0,0 +1,142 @@
+package gob
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+func goldenTensor() types.Tensor {
+	return tensor.FromFloat32(tensor.NewShape(2, 3), []float32{0.5, 1, 1.5, -2, 1e-3, 42})
+}
+
+func loadGolden(t *testing.T, name string, opts ...types.Option) (types.Tensor, error) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/golden/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got types.Tensor
+	err = NewUnmarshaller().Unmarshal(bytes.NewReader(data), &got, opts...)
+	return got, err
+}
+
+func checkTensor(t *testing.T, got types.Tensor, shape ...int) {
+	t.Helper()
+	want := goldenTensor()
+	if !got.Shape().Equal(tensor.NewShape(shape...)) {
+		t.Fatalf("shape %v, want %v", got.Shape(), shape)
+	}
+	for i, v := range want.Data().([]float32) {
+		if got.Data().([]float32)[i] != v {
+			t.Fatalf("element %d is %v, want %v", i, got.Data().([]float32)[i], v)
+		}
+	}
+}
+
+func TestGoldenVersions(t *testing.T) {
+	for _, name := range []string{"tensor.v0.gob", "tensor.v1.gob"} {
+		got, err := loadGolden(t, name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		checkTensor(t, got, 2, 3)
+	}
+}
+
+// gob type ids depend on what the process encoded before, so the output is
+// compared structurally rather than with the golden bytes.
+func TestWriteVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMarshaller().Marshal(&buf, goldenTensor()); err != nil {
+		t.Fatal(err)
+	}
+	var env gobEnvelope
+	if err := gob.NewDecoder(&buf).Decode(&env); err != nil || env.Schema != envelopeName || env.Version != SchemaVersion {
+		t.Fatalf("envelope %+v, %v", env, err)
+	}
+
+	buf.Reset()
+	if err := NewMarshaller().Marshal(&buf, goldenTensor(), types.WithFormatVersion("0")); err != nil {
+		t.Fatal(err)
+	}
+	var gv gobValue
+	if err := gob.NewDecoder(&buf).Decode(&gv); err != nil || gv.Kind != "tensor" {
+		t.Fatalf("bare value %+v, %v", gv, err)
+	}
+
+	if err := NewMarshaller().Marshal(&bytes.Buffer{}, goldenTensor(), types.WithFormatVersion("7")); err == nil {
+		t.Error("expected error writing an unknown version")
+	}
+}
+
+func TestMigration(t *testing.T) {
+	// pretend v1 stored tensors transposed
+	versioning.Register(schemaName, 0, 1, func(doc versioning.Document) (versioning.Document, error) {
+		tensor := doc["Tensor"].(versioning.Document)
+		shape := tensor["Shape"].([]int)
+		tensor["Shape"] = []int{shape[1], shape[0]}
+		return doc, nil
+	})
+	t.Cleanup(func() { versioning.Register(schemaName, 0, 1, nil) })
+
+	got, err := loadGolden(t, "tensor.v0.gob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTensor(t, got, 3, 2)
+
+	if got, err = loadGolden(t, "tensor.v1.gob"); err != nil {
+		t.Fatal(err)
+	}
+	checkTensor(t, got, 2, 3)
+}
+
+func TestFutureVersion(t *testing.T) {
+	gv, err := NewMarshaller().valueToGob(goldenTensor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&gobEnvelope{Schema: envelopeName, Version: SchemaVersion + 1, Value: gv}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got types.Tensor
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(buf.Bytes()), &got); err != nil {
+		t.Fatalf("best effort decoding failed: %v", err)
+	}
+	checkTensor(t, got, 2, 3)
+
+	err = NewUnmarshaller(versioning.WithStrict(true)).Unmarshal(bytes.NewReader(buf.Bytes()), &got)
+	var future *versioning.FutureVersionError
+	if !errors.As(err, &future) || future.Version != SchemaVersion+1 {
+		t.Fatalf("expected future version error, got %v", err)
+	}
+}
+
+func TestUnmarshalOpenStream(t *testing.T) {
+	for _, version := range []string{"0", "1"} {
+		var buf bytes.Buffer
+		if err := NewMarshaller().Marshal(&buf, goldenTensor(), types.WithFormatVersion(version)); err != nil {
+			t.Fatal(err)
+		}
+
+		// the writer stays open, so the payload must decode without reading to EOF
+		r, w := io.Pipe()
+		go w.Write(buf.Bytes())
+		var got types.Tensor
+		if err := NewUnmarshaller().Unmarshal(r, &got); err != nil {
+			t.Fatalf("version %s: %v", version, err)
+		}
+		checkTensor(t, got, 2, 3)
+		w.Close()
+	}
+}
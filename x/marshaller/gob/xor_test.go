@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/itohio/EasyRobot/x/marshaller/internal/modeltest"
 	"github.com/itohio/EasyRobot/x/marshaller/types"
 	"github.com/itohio/EasyRobot/x/math/learn"
 	"github.com/itohio/EasyRobot/x/math/nn"
@@ -129,3 +130,11 @@ func TestXORModelParametersRoundTrip(t *testing.T) {
 		t.Logf("Warning: Model prediction may not be fully converged: got %v, expected ~1.0", result)
 	}
 }
+
+func TestXORModelRoundTrip(t *testing.T) {
+	modeltest.ModelRoundTrip(t, NewMarshaller(), NewUnmarshaller())
+}
+
+func TestLayerRegistryRoundTrip(t *testing.T) {
+	modeltest.LayerRegistryRoundTrip(t, NewMarshaller(), NewUnmarshaller())
+}
@@ -0,0 +1,35 @@
+package gocv
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "gocv",
+		Extensions: []string{".png", ".jpg", ".jpeg", ".bmp", ".tif", ".tiff", ".avi", ".mp4", ".mkv", ".mov"},
+		Magic: []registry.Magic{
+			{Bytes: "\x89PNG\r\n\x1a\n"},
+			{Bytes: "\xFF\xD8\xFF"},
+			{Bytes: "BM"},
+			{Offset: 8, Bytes: "AVI "},
+			{Offset: 4, Bytes: "ftyp"},
+		},
+		LoadOptions: func(path string) []types.Option {
+			return []types.Option{WithPath(path)}
+		},
+		SaveOptions: func(path string) []types.Option {
+			return []types.Option{WithImageEncoding(strings.TrimPrefix(filepath.Ext(path), "."))}
+		},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
@@ -0,0 +1,106 @@
+package goimage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+const bmpFileHeaderSize = 14
+
+// bmpMasks are the red, green and blue masks of the BGRX pixels decoded for
+// 32 bit BI_BITFIELDS bitmaps.
+var bmpMasks = [3]uint32{0x00ff0000, 0x0000ff00, 0x000000ff}
+
+// decodeBMP decodes uncompressed 8, 24 and 32 bit Windows bitmaps (BI_RGB,
+// and 32 bit BI_BITFIELDS with the BGRX masks), bottom-up or top-down.
+func decodeBMP(data []byte) (image.Image, error) {
+	if len(data) < bmpFileHeaderSize+40 || data[0] != 'B' || data[1] != 'M' {
+		return nil, fmt.Errorf("goimage: not a BMP image")
+	}
+	le := binary.LittleEndian
+	pixelOffset := int(le.Uint32(data[10:]))
+	dib := data[bmpFileHeaderSize:]
+	dibSize := int(le.Uint32(dib))
+	if dibSize < 40 || bmpFileHeaderSize+dibSize > len(data) {
+		return nil, fmt.Errorf("goimage: unsupported BMP header size %d", dibSize)
+	}
+	width := int(int32(le.Uint32(dib[4:])))
+	height := int(int32(le.Uint32(dib[8:])))
+	bpp := int(le.Uint16(dib[14:]))
+	compression := le.Uint32(dib[16:])
+	colorsUsed := int(le.Uint32(dib[32:]))
+
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("goimage: invalid BMP size %dx%d", width, height)
+	}
+	if compression != 0 && !(compression == 3 && bpp == 32) {
+		return nil, fmt.Errorf("goimage: unsupported BMP compression %d", compression)
+	}
+	if compression == 3 {
+		// the masks follow a 40 byte header and are part of larger ones
+		start := bmpFileHeaderSize + 40
+		if start+12 > len(data) {
+			return nil, fmt.Errorf("goimage: BMP bit masks truncated")
+		}
+		masks := [3]uint32{le.Uint32(data[start:]), le.Uint32(data[start+4:]), le.Uint32(data[start+8:])}
+		if masks != bmpMasks {
+			return nil, fmt.Errorf("goimage: unsupported BMP bit masks %08x %08x %08x", masks[0], masks[1], masks[2])
+		}
+	}
+
+	var palette []color.NRGBA
+	switch bpp {
+	case 8:
+		if colorsUsed == 0 || colorsUsed > 256 {
+			colorsUsed = 256
+		}
+		start := bmpFileHeaderSize + dibSize
+		if start+4*colorsUsed > len(data) {
+			return nil, fmt.Errorf("goimage: BMP palette truncated")
+		}
+		palette = make([]color.NRGBA, 256)
+		for i := 0; i < colorsUsed; i++ {
+			p := data[start+4*i:]
+			palette[i] = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+		}
+	case 24, 32:
+	default:
+		return nil, fmt.Errorf("goimage: unsupported BMP bit depth %d", bpp)
+	}
+
+	stride := (bpp*width + 31) / 32 * 4
+	if pixelOffset < 0 || pixelOffset+stride*height > len(data) {
+		return nil, fmt.Errorf("goimage: BMP pixel data truncated")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		srcY := height - 1 - y
+		if topDown {
+			srcY = y
+		}
+		row := data[pixelOffset+srcY*stride:]
+		dst := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			d := dst[4*x : 4*x+4]
+			switch bpp {
+			case 8:
+				c := palette[row[x]]
+				d[0], d[1], d[2], d[3] = c.R, c.G, c.B, c.A
+			case 24:
+				s := row[3*x:]
+				d[0], d[1], d[2], d[3] = s[2], s[1], s[0], 0xff
+			case 32:
+				s := row[4*x:]
+				d[0], d[1], d[2], d[3] = s[2], s[1], s[0], 0xff
+			}
+		}
+	}
+	return img, nil
+}
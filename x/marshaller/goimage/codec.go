@@ -0,0 +1,97 @@
+package goimage
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+var (
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	jpegMagic = []byte{0xff, 0xd8}
+	bmpMagic  = []byte("BM")
+)
+
+// decodeImage decodes a PNG, JPEG or BMP image, detected from its contents.
+func decodeImage(data []byte) (image.Image, error) {
+	var (
+		img image.Image
+		err error
+	)
+	switch {
+	case bytes.HasPrefix(data, pngMagic):
+		img, err = png.Decode(bytes.NewReader(data))
+	case bytes.HasPrefix(data, jpegMagic):
+		img, err = jpeg.Decode(bytes.NewReader(withHuffmanTables(data)))
+	case bytes.HasPrefix(data, bmpMagic):
+		return decodeBMP(data)
+	default:
+		return nil, fmt.Errorf("goimage: unknown image format")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("goimage: %w", err)
+	}
+	return img, nil
+}
+
+// imageToTensor converts an image to a UINT8 tensor of shape
+// [rows, cols, 3] in BGR channel order, the layout gocv produces for
+// images read in color. Alpha is dropped without compositing, as OpenCV does.
+func imageToTensor(img image.Image) types.Tensor {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	t := tensor.New(types.UINT8, tensor.NewShape(h, w, 3))
+	dst := t.Data().([]uint8)
+
+	switch src := img.(type) {
+	case *image.YCbCr:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				yi := src.YOffset(b.Min.X+x, b.Min.Y+y)
+				ci := src.COffset(b.Min.X+x, b.Min.Y+y)
+				r, g, bl := color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+				d := dst[3*(y*w+x):]
+				d[0], d[1], d[2] = bl, g, r
+			}
+		}
+	case *image.Gray:
+		for y := 0; y < h; y++ {
+			row := src.Pix[y*src.Stride:]
+			for x := 0; x < w; x++ {
+				v := row[x]
+				d := dst[3*(y*w+x):]
+				d[0], d[1], d[2] = v, v, v
+			}
+		}
+	case *image.NRGBA:
+		copyRGBA(dst, src.Pix, src.Stride, w, h)
+	case *image.RGBA:
+		copyRGBA(dst, src.Pix, src.Stride, w, h)
+	default:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+				d := dst[3*(y*w+x):]
+				d[0], d[1], d[2] = c.B, c.G, c.R
+			}
+		}
+	}
+	return t
+}
+
+func copyRGBA(dst, pix []uint8, stride, w, h int) {
+	for y := 0; y < h; y++ {
+		row := pix[y*stride:]
+		for x := 0; x < w; x++ {
+			s := row[4*x:]
+			d := dst[3*(y*w+x):]
+			d[0], d[1], d[2] = s[2], s[1], s[0]
+		}
+	}
+}
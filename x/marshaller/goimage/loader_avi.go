@@ -0,0 +1,176 @@
+package goimage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// aviLoader streams the frames of an MJPEG AVI file. RIFF and LIST chunks
+// (including OpenDML AVIX extensions) are walked in file order; "##dc" and
+// "##db" chunks of any stream are decoded as JPEG frames. The frame period
+// from the main header gives each frame its presentation time.
+type aviLoader struct {
+	path       string
+	baseName   string
+	file       *os.File
+	r          *bufio.Reader
+	size, pos  int64 // file size and read offset, bounding the chunk sizes
+	usPerFrame uint32
+	index      int
+}
+
+func newAVILoader(path string) (*aviLoader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("goimage: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("goimage: %w", err)
+	}
+	r := bufio.NewReader(f)
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil || string(header[:4]) != "RIFF" || string(header[8:]) != "AVI " {
+		f.Close()
+		return nil, fmt.Errorf("goimage: %s is not an AVI file", path)
+	}
+	base := filepath.Base(path)
+	return &aviLoader{
+		path:     path,
+		baseName: strings.TrimSuffix(base, filepath.Ext(base)),
+		file:     f,
+		r:        r,
+		size:     info.Size(),
+		pos:      int64(len(header)),
+	}, nil
+}
+
+func (l *aviLoader) Next(ctx context.Context) (frameItem, bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return frameItem{}, false, ctx.Err()
+		default:
+		}
+
+		var header [8]byte
+		if n, err := io.ReadFull(l.r, header[:]); err != nil {
+			if n == 0 && err == io.EOF {
+				return frameItem{}, false, nil
+			}
+			return frameItem{}, false, l.corrupt(err)
+		}
+		l.pos += int64(len(header))
+		id := string(header[:4])
+		size := binary.LittleEndian.Uint32(header[4:])
+
+		if id == "RIFF" || id == "LIST" {
+			// descend: the list type is followed by the list's chunks
+			if err := l.skip(4); err != nil {
+				return frameItem{}, false, err
+			}
+			continue
+		}
+
+		if int64(size) > l.size-l.pos {
+			return frameItem{}, false, fmt.Errorf("goimage: %s: %q chunk of %d bytes overruns the file: %w", l.path, id, size, io.ErrUnexpectedEOF)
+		}
+		// writers may drop the pad byte of the last chunk
+		padded := min(int64(size)+int64(size&1), l.size-l.pos)
+		switch {
+		case id == "avih" && size >= 4:
+			var period [4]byte
+			if err := l.read(period[:]); err != nil {
+				return frameItem{}, false, err
+			}
+			l.usPerFrame = binary.LittleEndian.Uint32(period[:])
+			if err := l.skip(padded - 4); err != nil {
+				return frameItem{}, false, err
+			}
+		case len(id) == 4 && (id[2:] == "dc" || id[2:] == "db") && size > 0:
+			data := make([]byte, padded)
+			if err := l.read(data); err != nil {
+				return frameItem{}, false, err
+			}
+			item, err := l.frame(data[:size])
+			return item, err == nil, err
+		default:
+			if err := l.skip(padded); err != nil {
+				return frameItem{}, false, err
+			}
+		}
+	}
+}
+
+// read fills buf from the chunk being read.
+func (l *aviLoader) read(buf []byte) error {
+	if _, err := io.ReadFull(l.r, buf); err != nil {
+		return l.corrupt(err)
+	}
+	l.pos += int64(len(buf))
+	return nil
+}
+
+// skip discards n bytes of the chunk being read.
+func (l *aviLoader) skip(n int64) error {
+	if _, err := l.r.Discard(int(n)); err != nil {
+		return l.corrupt(err)
+	}
+	l.pos += n
+	return nil
+}
+
+// corrupt reports a read failing inside a chunk; the file ends only between
+// chunks.
+func (l *aviLoader) corrupt(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("goimage: %s: %w", l.path, err)
+}
+
+func (l *aviLoader) frame(data []byte) (frameItem, error) {
+	if !bytes.HasPrefix(data, jpegMagic) {
+		return frameItem{}, fmt.Errorf("goimage: %s: only Motion JPEG AVI files are supported", l.path)
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return frameItem{}, fmt.Errorf("goimage: %s frame %d: %w", l.path, l.index, err)
+	}
+
+	filename := fmt.Sprintf("%s_%06d.png", l.baseName, l.index)
+	meta := map[string]any{
+		"path":        l.path,
+		"timestamp":   time.Now().UnixNano(),
+		"source":      "video",
+		"frame_index": l.index,
+		"index":       l.index,
+		"filename":    filename,
+		"name":        []string{filename},
+	}
+	if l.usPerFrame > 0 {
+		meta["pts"] = int64(l.index) * int64(l.usPerFrame) * int64(time.Microsecond)
+	}
+	l.index++
+	return frameItem{tensors: []types.Tensor{imageToTensor(img)}, metadata: meta}, nil
+}
+
+func (l *aviLoader) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,173 @@
+package goimage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+type frameItem struct {
+	tensors  []types.Tensor
+	metadata map[string]any
+}
+
+type sourceStream interface {
+	Next(ctx context.Context) (frameItem, bool, error)
+	Close() error
+}
+
+func isImagePath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".bmp":
+		return true
+	}
+	return false
+}
+
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// listImages expands a directory or glob to its image files, ordered by sorter.
+func listImages(path string, sorter func([]string) []string) ([]string, error) {
+	pattern := path
+	if !isGlobPattern(path) {
+		pattern = filepath.Join(path, "*")
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("goimage: glob %s: %w", path, err)
+	}
+	files := matches[:0]
+	for _, m := range matches {
+		if isImagePath(m) {
+			files = append(files, m)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("goimage: no images in %s", path)
+	}
+	return sorter(files), nil
+}
+
+type fileListLoader struct {
+	label string
+	files []string
+	keys  []string // sync keys when the set is paired by name
+	index int
+}
+
+func newFileListLoader(label string, files []string) *fileListLoader {
+	return &fileListLoader{label: label, files: files}
+}
+
+func (l *fileListLoader) Next(ctx context.Context) (frameItem, bool, error) {
+	if l.index >= len(l.files) {
+		return frameItem{}, false, nil
+	}
+	select {
+	case <-ctx.Done():
+		return frameItem{}, false, ctx.Err()
+	default:
+	}
+
+	path := l.files[l.index]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return frameItem{}, false, fmt.Errorf("goimage: %w", err)
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return frameItem{}, false, fmt.Errorf("goimage: %s: %w", path, err)
+	}
+
+	base := filepath.Base(path)
+	meta := map[string]any{
+		"path":      path,
+		"timestamp": time.Now().UnixNano(),
+		"source":    "filelist",
+		"set":       l.label,
+		"relative":  base,
+		"set_index": l.index,
+		"filename":  base,
+		"index":     l.index,
+		"name":      []string{base},
+	}
+	if l.keys != nil {
+		meta["sync_key"] = l.keys[l.index]
+	}
+	l.index++
+	return frameItem{tensors: []types.Tensor{imageToTensor(img)}, metadata: meta}, true, nil
+}
+
+func (l *fileListLoader) Close() error {
+	return nil
+}
+
+// frameKey returns the name a file is paired on: the last run of digits in
+// its base name (left/000042.png, right_000042.png), or the base name without
+// extension when it has no digits.
+func frameKey(path string) string {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	end := strings.LastIndexAny(base, "0123456789")
+	if end < 0 {
+		return strings.ToLower(base)
+	}
+	start := end
+	for start > 0 && base[start-1] >= '0' && base[start-1] <= '9' {
+		start--
+	}
+	if key := strings.TrimLeft(base[start:end+1], "0"); key != "" {
+		return key
+	}
+	return "0"
+}
+
+// syncByName restricts file sets to the frames present in all of them, in
+// the order of the first set. Frames missing from any set are dropped.
+func syncByName(loaders []*fileListLoader) {
+	present := make([]map[string]string, len(loaders))
+	for i, l := range loaders {
+		present[i] = make(map[string]string, len(l.files))
+		for _, f := range l.files {
+			key := frameKey(f)
+			if _, dup := present[i][key]; !dup {
+				present[i][key] = f
+			}
+		}
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, f := range loaders[0].files {
+		key := frameKey(f)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		common := true
+		for _, p := range present[1:] {
+			if _, ok := p[key]; !ok {
+				common = false
+				break
+			}
+		}
+		if common {
+			keys = append(keys, key)
+		}
+	}
+
+	for i, l := range loaders {
+		l.files = make([]string, len(keys))
+		for j, key := range keys {
+			l.files[j] = present[i][key]
+		}
+		l.keys = keys
+	}
+}
@@ -0,0 +1,153 @@
+package goimage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// mjpegLoader streams a multipart/x-mixed-replace MJPEG HTTP stream, as
+// served by IP cameras and mjpg-streamer. A plain image response (a
+// snapshot URL) yields a single frame.
+type mjpegLoader struct {
+	url    string
+	client *http.Client
+
+	mu     sync.Mutex // guards body, which Close may release from another goroutine
+	body   io.ReadCloser
+	closed bool
+
+	parts  *multipart.Reader
+	single []byte
+	index  int
+	done   bool
+}
+
+func newMJPEGLoader(url string, client *http.Client) *mjpegLoader {
+	return &mjpegLoader{url: url, client: client}
+}
+
+func (l *mjpegLoader) open(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		resp.Body.Close()
+		return context.Canceled
+	}
+	l.body = resp.Body
+	l.mu.Unlock()
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		l.single = data
+		return nil
+	}
+
+	r := bufio.NewReader(resp.Body)
+	l.parts = multipart.NewReader(r, streamBoundary(r, params["boundary"]))
+	return nil
+}
+
+// streamBoundary returns the boundary used by the first delimiter line of the
+// stream. Many cameras declare "boundary=--foo" but delimit parts with
+// "--foo", which the declared value would not match.
+func streamBoundary(r *bufio.Reader, declared string) string {
+	head, _ := r.Peek(512)
+	for _, line := range bytes.Split(head, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if bytes.HasPrefix(line, []byte("--")) {
+			return string(line[2:])
+		}
+		break
+	}
+	return declared
+}
+
+func (l *mjpegLoader) Next(ctx context.Context) (frameItem, bool, error) {
+	if l.done {
+		return frameItem{}, false, nil
+	}
+	if l.parts == nil && l.single == nil {
+		if err := l.open(ctx); err != nil {
+			return frameItem{}, false, fmt.Errorf("goimage: %s: %w", l.url, err)
+		}
+	}
+
+	data := l.single
+	if l.parts == nil {
+		l.done = true
+	} else {
+		part, err := l.parts.NextPart()
+		if err == io.EOF {
+			l.done = true
+			return frameItem{}, false, nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return frameItem{}, false, ctx.Err()
+			}
+			return frameItem{}, false, fmt.Errorf("goimage: %s: %w", l.url, err)
+		}
+		data, err = io.ReadAll(part)
+		if err != nil {
+			return frameItem{}, false, fmt.Errorf("goimage: %s: %w", l.url, err)
+		}
+	}
+
+	img, err := decodeImage(data)
+	if err != nil {
+		return frameItem{}, false, fmt.Errorf("goimage: %s frame %d: %w", l.url, l.index, err)
+	}
+	filename := fmt.Sprintf("frame_%06d.jpg", l.index)
+	meta := map[string]any{
+		"path":      l.url,
+		"timestamp": time.Now().UnixNano(),
+		"source":    "mjpeg",
+		"index":     l.index,
+		"filename":  filename,
+		"name":      []string{filename},
+	}
+	l.index++
+	return frameItem{tensors: []types.Tensor{imageToTensor(img)}, metadata: meta}, true, nil
+}
+
+func (l *mjpegLoader) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.body == nil {
+		return nil
+	}
+	err := l.body.Close()
+	l.body = nil
+	return err
+}
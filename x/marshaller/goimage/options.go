@@ -0,0 +1,99 @@
+package goimage
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+type config struct {
+	sources    []string
+	sequential bool
+	sorter     func([]string) []string
+	client     *http.Client
+}
+
+func defaultConfig() config {
+	return config{
+		sorter: defaultSorter,
+		client: http.DefaultClient,
+	}
+}
+
+func defaultSorter(list []string) []string {
+	sorted := append([]string(nil), list...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i]) < strings.ToLower(sorted[j])
+	})
+	return sorted
+}
+
+type configOption interface {
+	types.Option
+	applyConfig(*config)
+}
+
+type optionFunc func(*config)
+
+func (optionFunc) Apply(*types.Options) {}
+
+func (o optionFunc) applyConfig(cfg *config) {
+	o(cfg)
+}
+
+func applyOptions(baseOpts types.Options, baseCfg config, opts []types.Option) (types.Options, config) {
+	localOpts := baseOpts
+	localCfg := baseCfg
+	localCfg.sources = append([]string(nil), baseCfg.sources...)
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt.Apply(&localOpts)
+		if co, ok := opt.(configOption); ok {
+			co.applyConfig(&localCfg)
+		}
+	}
+	return localOpts, localCfg
+}
+
+// WithPath adds a source: an image file (PNG, JPEG, BMP), a directory or glob
+// of images, an MJPEG AVI file, or an http(s) URL of a multipart MJPEG stream.
+// Several sources are combined into one frame per step; file sets are paired
+// by the frame number in their file names (see Unmarshaller).
+func WithPath(path string) types.Option {
+	path = strings.TrimSpace(path)
+	return optionFunc(func(cfg *config) {
+		if path != "" {
+			cfg.sources = append(cfg.sources, path)
+		}
+	})
+}
+
+// WithSequential streams the sources one after another instead of combining them.
+func WithSequential(enable bool) types.Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sequential = enable
+	})
+}
+
+// WithSorter overrides the ordering of files in directories and globs. The
+// default sorts names case-insensitively.
+func WithSorter(sorter func([]string) []string) types.Option {
+	return optionFunc(func(cfg *config) {
+		if sorter != nil {
+			cfg.sorter = sorter
+		}
+	})
+}
+
+// WithHTTPClient sets the client used for MJPEG streams.
+func WithHTTPClient(client *http.Client) types.Option {
+	return optionFunc(func(cfg *config) {
+		if client != nil {
+			cfg.client = client
+		}
+	})
+}
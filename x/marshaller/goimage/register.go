@@ -0,0 +1,29 @@
+package goimage
+
+import (
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// imageMagic are the signatures of the image and video files both image
+// backends read.
+var imageMagic = []registry.Magic{
+	{Bytes: "\x89PNG\r\n\x1a\n"},
+	{Bytes: "\xFF\xD8\xFF"},
+	{Bytes: "BM"},
+	{Offset: 8, Bytes: "AVI "},
+}
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "goimage",
+		Extensions: []string{".png", ".jpg", ".jpeg", ".bmp", ".avi"},
+		Magic:      imageMagic,
+		LoadOptions: func(path string) []types.Option {
+			return []types.Option{WithPath(path)}
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
@@ -0,0 +1,185 @@
+package goimage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// buildStreams opens the configured sources. When several sources are all
+// file sets and are combined (not sequential), they are paired by name.
+func buildStreams(cfg config) ([]sourceStream, error) {
+	streams := make([]sourceStream, 0, len(cfg.sources))
+	var fileSets []*fileListLoader
+	closeAll := func() {
+		for _, s := range streams {
+			s.Close()
+		}
+	}
+
+	for _, src := range cfg.sources {
+		lower := strings.ToLower(src)
+		switch {
+		case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+			streams = append(streams, newMJPEGLoader(src, cfg.client))
+		case strings.HasSuffix(lower, ".avi"):
+			stream, err := newAVILoader(src)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			streams = append(streams, stream)
+		default:
+			var files []string
+			if info, err := os.Stat(src); err == nil && !info.IsDir() {
+				if !isImagePath(src) {
+					closeAll()
+					return nil, fmt.Errorf("goimage: unsupported file %s", src)
+				}
+				files = []string{src}
+			} else if err != nil && !isGlobPattern(src) {
+				closeAll()
+				return nil, fmt.Errorf("goimage: %w", err)
+			} else if files, err = listImages(src, cfg.sorter); err != nil {
+				closeAll()
+				return nil, err
+			}
+			loader := newFileListLoader(src, files)
+			fileSets = append(fileSets, loader)
+			streams = append(streams, loader)
+		}
+	}
+
+	if !cfg.sequential && len(fileSets) > 1 && len(fileSets) == len(streams) {
+		syncByName(fileSets)
+	}
+	return streams, nil
+}
+
+func newFrameStream(baseCtx context.Context, streams []sourceStream, sequential bool) types.FrameStream {
+	ctx, cancel := context.WithCancel(baseCtx)
+	output := make(chan types.Frame)
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			cancel()
+			for _, stream := range streams {
+				_ = stream.Close()
+			}
+		})
+	}
+
+	send := func(frame types.Frame) bool {
+		select {
+		case output <- frame:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(output)
+		defer done()
+
+		index := 0
+		if sequential {
+			for streamIdx, stream := range streams {
+				for {
+					item, cont, err := stream.Next(ctx)
+					if err != nil {
+						send(errorFrame(err, index))
+						return
+					}
+					if !cont {
+						break
+					}
+					frame := combineFrameItems([]frameItem{item}, index)
+					frame.Metadata["stream_index"] = streamIdx
+					if !send(frame) {
+						return
+					}
+					index++
+				}
+			}
+			return
+		}
+
+		for len(streams) > 0 {
+			items := make([]frameItem, len(streams))
+			for i, stream := range streams {
+				item, cont, err := stream.Next(ctx)
+				if err != nil {
+					send(errorFrame(err, index))
+					return
+				}
+				if !cont {
+					return
+				}
+				items[i] = item
+			}
+			if !send(combineFrameItems(items, index)) {
+				return
+			}
+			index++
+		}
+	}()
+
+	return types.NewFrameStream(output, done)
+}
+
+// combineFrameItems merges the items of one step into a frame the way the
+// gocv unmarshaller does: a single source's metadata is used directly,
+// several sources are listed under "sources" and their names under "name".
+func combineFrameItems(items []frameItem, index int) types.Frame {
+	meta := map[string]any{}
+	var tensors []types.Tensor
+	sources := make([]map[string]any, 0, len(items))
+	var names []string
+
+	for _, item := range items {
+		tensors = append(tensors, item.tensors...)
+		copied := make(map[string]any, len(item.metadata))
+		for k, v := range item.metadata {
+			copied[k] = v
+		}
+		if n, ok := copied["name"].([]string); ok {
+			names = append(names, n...)
+		}
+		sources = append(sources, copied)
+	}
+
+	if len(sources) == 1 {
+		meta = sources[0]
+	} else {
+		meta["sources"] = sources
+		if key, ok := sources[0]["sync_key"]; ok {
+			meta["sync_key"] = key
+		}
+	}
+	if len(names) > 0 {
+		meta["name"] = names
+	}
+
+	return types.Frame{
+		Index:     index,
+		Timestamp: time.Now().UnixNano(),
+		Metadata:  meta,
+		Tensors:   tensors,
+	}
+}
+
+func errorFrame(err error, index int) types.Frame {
+	return types.Frame{
+		Index:     index,
+		Timestamp: time.Now().UnixNano(),
+		Metadata: map[string]any{
+			"error": err,
+		},
+	}
+}
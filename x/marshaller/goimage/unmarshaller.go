@@ -0,0 +1,117 @@
+// Package goimage provides a pure-Go (no cgo, no OpenCV) unmarshaller for
+// image sources: PNG, JPEG and BMP files, directories and globs of them,
+// Motion JPEG AVI files and multipart MJPEG HTTP streams.
+//
+// Frames carry the same tensors and metadata as the gocv unmarshaller:
+// UINT8 tensors of shape [rows, cols, 3] in BGR order, one per source, and
+// "path", "filename", "index", "name" ... metadata. Pipelines written against
+// gocv streams therefore run unchanged on builds without OpenCV.
+package goimage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Unmarshaller decodes images and image streams.
+type Unmarshaller struct {
+	opts types.Options
+	cfg  config
+}
+
+// NewUnmarshaller creates a new pure-Go image unmarshaller.
+func NewUnmarshaller(opts ...types.Option) *Unmarshaller {
+	u := &Unmarshaller{}
+	u.opts, u.cfg = applyOptions(types.Options{}, defaultConfig(), opts)
+	return u
+}
+
+// Format returns the format name.
+func (u *Unmarshaller) Format() string {
+	return "goimage"
+}
+
+// Unmarshal decodes a PNG/JPEG/BMP image from r into *image.Image or
+// *types.Tensor, or opens the WithPath sources as a *types.FrameStream. For
+// streams without configured sources, r lists one path or URL per line.
+//
+// Several sources are combined into one frame per step, with one tensor per
+// source. When all of them are file sets (directories or globs), frames are
+// paired by the frame number in the file name, so left/000042.png goes with
+// right/000042.png even if either set has gaps; unmatched files are skipped
+// and the pair's number is reported as "sync_key" metadata. Other sources are
+// combined in step. WithSequential streams the sources one after another.
+func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) error {
+	if dst == nil {
+		return types.NewError("unmarshal", "goimage", "nil destination", nil)
+	}
+	localOpts, localCfg := applyOptions(u.opts, u.cfg, opts)
+
+	switch out := dst.(type) {
+	case *image.Image:
+		img, err := readImage(r)
+		if err != nil {
+			return types.NewError("unmarshal", "goimage", "failed to decode image", err)
+		}
+		*out = img
+		return nil
+	case *types.Tensor:
+		img, err := readImage(r)
+		if err != nil {
+			return types.NewError("unmarshal", "goimage", "failed to decode image", err)
+		}
+		*out = imageToTensor(img)
+		return nil
+	case *types.FrameStream:
+		if len(localCfg.sources) == 0 && r != nil {
+			paths, err := readPaths(r)
+			if err != nil {
+				return types.NewError("unmarshal", "goimage", "failed to read source list", err)
+			}
+			localCfg.sources = paths
+		}
+		if len(localCfg.sources) == 0 {
+			return types.NewError("unmarshal", "goimage", "no sources", nil)
+		}
+		streams, err := buildStreams(localCfg)
+		if err != nil {
+			return types.NewError("unmarshal", "goimage", "failed to open sources", err)
+		}
+		ctx := localOpts.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		*out = newFrameStream(ctx, streams, localCfg.sequential)
+		return nil
+	default:
+		return types.NewError("unmarshal", "goimage", fmt.Sprintf("unsupported destination type %T", dst), nil)
+	}
+}
+
+func readImage(r io.Reader) (image.Image, error) {
+	if r == nil {
+		return nil, fmt.Errorf("nil reader")
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeImage(data)
+}
+
+func readPaths(r io.Reader) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			paths = append(paths, line)
+		}
+	}
+	return paths, scanner.Err()
+}
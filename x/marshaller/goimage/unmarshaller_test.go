@@ -0,0 +1,345 @@
+package goimage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// testImage is 3x2 blocks of scale x scale pixels: red, green, blue on the
+// first row, gray levels below. JPEG tests use 16 pixel blocks so that
+// chroma subsampling does not bleed between them.
+func testImage(scale int) *image.NRGBA {
+	colors := []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}, {0, 0, 0, 255}, {100, 100, 100, 255}, {200, 200, 200, 255}}
+	img := image.NewNRGBA(image.Rect(0, 0, 3*scale, 2*scale))
+	for y := 0; y < 2*scale; y++ {
+		for x := 0; x < 3*scale; x++ {
+			img.Set(x, y, colors[y/scale*3+x/scale])
+		}
+	}
+	return img
+}
+
+// encodeBMP writes a bottom-up 24 bit bitmap.
+func encodeBMP(img image.Image) []byte {
+	b := img.Bounds()
+	stride := (3*b.Dx() + 3) &^ 3
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.WriteString("BM")
+	binary.Write(&buf, le, uint32(54+stride*b.Dy()))
+	binary.Write(&buf, le, uint32(0))
+	binary.Write(&buf, le, uint32(54))
+	binary.Write(&buf, le, []uint32{40, uint32(b.Dx()), uint32(b.Dy())})
+	binary.Write(&buf, le, []uint16{1, 24})
+	binary.Write(&buf, le, []uint32{0, uint32(stride * b.Dy()), 2835, 2835, 0, 0})
+	for y := b.Dy() - 1; y >= 0; y-- {
+		row := make([]byte, stride)
+		for x := 0; x < b.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			row[3*x], row[3*x+1], row[3*x+2] = c.B, c.G, c.R
+		}
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
+// encodeBitfieldsBMP writes a bottom-up 32 bit BI_BITFIELDS bitmap of BGRX
+// pixels declaring the given red, green and blue masks.
+func encodeBitfieldsBMP(img image.Image, masks ...uint32) []byte {
+	b := img.Bounds()
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.WriteString("BM")
+	binary.Write(&buf, le, uint32(66+4*b.Dx()*b.Dy()))
+	binary.Write(&buf, le, uint32(0))
+	binary.Write(&buf, le, uint32(66))
+	binary.Write(&buf, le, []uint32{40, uint32(b.Dx()), uint32(b.Dy())})
+	binary.Write(&buf, le, []uint16{1, 32})
+	binary.Write(&buf, le, []uint32{3, uint32(4 * b.Dx() * b.Dy()), 2835, 2835, 0, 0})
+	binary.Write(&buf, le, masks)
+	for y := b.Dy() - 1; y >= 0; y-- {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			buf.Write([]byte{c.B, c.G, c.R, 0})
+		}
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// stripHuffmanTables removes the DHT segments like Motion JPEG encoders do.
+func stripHuffmanTables(data []byte) []byte {
+	out := append([]byte(nil), data[:2]...)
+	for i := 2; i < len(data); {
+		marker := data[i+1]
+		if marker == 0xda {
+			return append(out, data[i:]...)
+		}
+		n := 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker != 0xc4 {
+			out = append(out, data[i:i+n]...)
+		}
+		i += n
+	}
+	return out
+}
+
+// checkBGR compares the block centers of a decoded testImage(scale).
+func checkBGR(t *testing.T, tensor types.Tensor, scale, tolerance int) {
+	t.Helper()
+	if shape := tensor.Shape(); tensor.DataType() != types.UINT8 || len(shape) != 3 || shape[0] != 2*scale || shape[1] != 3*scale || shape[2] != 3 {
+		t.Fatalf("tensor = %v %v, want UINT8 [%d %d 3]", tensor.DataType(), tensor.Shape(), 2*scale, 3*scale)
+	}
+	data := tensor.Data().([]uint8)
+	want := []uint8{0, 0, 255, 0, 255, 0, 255, 0, 0, 0, 0, 0, 100, 100, 100, 200, 200, 200}
+	for block := 0; block < 6; block++ {
+		y, x := block/3*scale+scale/2, block%3*scale+scale/2
+		for c := 0; c < 3; c++ {
+			got := data[(y*3*scale+x)*3+c]
+			if d := int(got) - int(want[3*block+c]); d > tolerance || d < -tolerance {
+				t.Fatalf("block %d channel %d = %d, want %d", block, c, got, want[3*block+c])
+			}
+		}
+	}
+}
+
+func TestImageFormats(t *testing.T) {
+	img := testImage(1)
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		data             []byte
+		scale, tolerance int
+	}{
+		"png":           {pngData.Bytes(), 1, 0},
+		"bmp":           {encodeBMP(img), 1, 0},
+		"bmp bitfields": {encodeBitfieldsBMP(img, 0xff0000, 0xff00, 0xff), 1, 0},
+		"jpeg":          {encodeJPEG(t, testImage(16)), 16, 8},
+	} {
+		var tensor types.Tensor
+		if err := NewUnmarshaller().Unmarshal(bytes.NewReader(tc.data), &tensor); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		checkBGR(t, tensor, tc.scale, tc.tolerance)
+	}
+
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader([]byte("GIF89a")), new(types.Tensor)); err == nil {
+		t.Error("unknown format should fail")
+	}
+	for _, masks := range [][]uint32{{0xf800, 0x07e0, 0x001f}, {0xff, 0xff00, 0xff0000}} {
+		if err := NewUnmarshaller().Unmarshal(bytes.NewReader(encodeBitfieldsBMP(img, masks...)), new(types.Tensor)); err == nil {
+			t.Errorf("BMP masks %x should fail", masks)
+		}
+	}
+}
+
+func writeImages(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var data bytes.Buffer
+	png.Encode(&data, testImage(1))
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), data.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func collect(t *testing.T, stream types.FrameStream) []types.Frame {
+	t.Helper()
+	var frames []types.Frame
+	for frame := range stream.C {
+		if err, ok := frame.Metadata["error"].(error); ok {
+			t.Fatal(err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func TestStereoSync(t *testing.T) {
+	dir := t.TempDir()
+	writeImages(t, filepath.Join(dir, "left"), "left_0001.png", "left_0002.png", "left_0003.png", "left_0004.png")
+	writeImages(t, filepath.Join(dir, "right"), "right_0001.png", "right_0002.png", "right_0004.png", "right_0005.png", "notes.txt")
+
+	var stream types.FrameStream
+	err := NewUnmarshaller().Unmarshal(nil, &stream,
+		WithPath(filepath.Join(dir, "left")),
+		WithPath(filepath.Join(dir, "right", "*.png")))
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	frames := collect(t, stream)
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+	for i, key := range []string{"1", "2", "4"} {
+		f := frames[i]
+		names := f.Metadata["name"].([]string)
+		if len(f.Tensors) != 2 || f.Metadata["sync_key"] != key || names[0] != "left_000"+key+".png" || names[1] != "right_000"+key+".png" {
+			t.Errorf("frame %d: %d tensors, metadata %v", i, len(f.Tensors), f.Metadata)
+		}
+	}
+	checkBGR(t, frames[2].Tensors[1], 1, 0)
+
+	// sequential sources are not paired
+	err = NewUnmarshaller(WithSequential(true)).Unmarshal(nil, &stream,
+		WithPath(filepath.Join(dir, "left")),
+		WithPath(filepath.Join(dir, "right")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames := collect(t, stream); len(frames) != 8 || frames[7].Metadata["stream_index"] != 1 {
+		t.Errorf("sequential: got %d frames", len(frames))
+	}
+}
+
+// writeAVI writes a minimal Motion JPEG AVI with a 25 fps main header.
+func writeAVI(t *testing.T, path string, frames [][]byte) {
+	t.Helper()
+	chunk := func(id string, data []byte) []byte {
+		out := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		out = append(out, data...)
+		if len(data)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	list := func(id, kind string, children ...[]byte) []byte {
+		return chunk(id, append([]byte(kind), bytes.Join(children, nil)...))
+	}
+
+	avih := make([]byte, 56)
+	binary.LittleEndian.PutUint32(avih, 40000)
+	var movi [][]byte
+	for _, f := range frames {
+		movi = append(movi, chunk("00dc", f))
+	}
+	avi := list("RIFF", "AVI ",
+		list("LIST", "hdrl", chunk("avih", avih), list("LIST", "strl", chunk("strh", make([]byte, 56)))),
+		chunk("JUNK", []byte{1, 2, 3}),
+		list("LIST", "movi", movi...),
+		chunk("idx1", make([]byte, 16*len(frames))))
+	if err := os.WriteFile(path, avi, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMJPEGAVI(t *testing.T) {
+	frame := encodeJPEG(t, testImage(16))
+	path := filepath.Join(t.TempDir(), "clip.avi")
+	writeAVI(t, path, [][]byte{frame, stripHuffmanTables(frame), append(frame, 0)})
+
+	var stream types.FrameStream
+	if err := NewUnmarshaller().Unmarshal(nil, &stream, WithPath(path)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	frames := collect(t, stream)
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+	for _, f := range frames {
+		checkBGR(t, f.Tensors[0], 16, 8)
+	}
+	if pts := frames[2].Metadata["pts"]; pts != int64(80_000_000) || frames[1].Metadata["filename"] != "clip_000001.png" {
+		t.Errorf("metadata = %v", frames[2].Metadata)
+	}
+}
+
+func TestCorruptAVI(t *testing.T) {
+	frame := encodeJPEG(t, testImage(16))
+	path := filepath.Join(t.TempDir(), "clip.avi")
+	writeAVI(t, path, [][]byte{frame, frame})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := bytes.LastIndex(data, []byte("00dc"))
+	huge := bytes.Clone(data)
+	binary.LittleEndian.PutUint32(huge[second+4:], 0xfffffff0)
+
+	for name, src := range map[string][]byte{
+		"truncated": data[:second+8+len(frame)/2],
+		"huge":      huge,
+	} {
+		if err := os.WriteFile(path, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		l, err := newAVILoader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok, err := l.Next(context.Background()); !ok || err != nil {
+			t.Fatalf("%s: first frame: %v", name, err)
+		}
+		if _, ok, err := l.Next(context.Background()); ok || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: got %v, %v, want io.ErrUnexpectedEOF", name, ok, err)
+		}
+		l.Close()
+	}
+}
+
+func TestMJPEGStream(t *testing.T) {
+	frame := encodeJPEG(t, testImage(16))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// declared with the leading dashes, as many cameras do
+		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=--frame")
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "--frame\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", len(frame))
+			w.Write(frame)
+			w.Write([]byte("\r\n"))
+		}
+		w.Write([]byte("--frame--\r\n"))
+	}))
+	defer server.Close()
+
+	var stream types.FrameStream
+	if err := NewUnmarshaller().Unmarshal(nil, &stream, WithPath(server.URL+"/stream")); err != nil {
+		t.Fatal(err)
+	}
+	frames := collect(t, stream)
+	if len(frames) != 4 {
+		t.Fatalf("got %d frames, want 4", len(frames))
+	}
+	checkBGR(t, frames[3].Tensors[0], 16, 8)
+	if frames[3].Metadata["source"] != "mjpeg" || frames[3].Index != 3 {
+		t.Errorf("frame = %d %v", frames[3].Index, frames[3].Metadata)
+	}
+
+	// closing the stream early stops the download
+	if err := NewUnmarshaller().Unmarshal(nil, &stream, WithPath(server.URL)); err != nil {
+		t.Fatal(err)
+	}
+	<-stream.C
+	stream.Close()
+	for range stream.C {
+	}
+}
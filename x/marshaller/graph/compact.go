@@ -0,0 +1,267 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+const compactSuffix = ".compact"
+
+// CompactTo writes a copy of the graph to the storages named by opts
+// (WithPath, WithEdgesPath, WithLabelsPath), leaving out deleted nodes and
+// edges, data entries no record refers to and commit journals.
+// Node IDs are preserved; data offsets are renumbered.
+func (s *GraphStorage) CompactTo(storageFactory types.MappedStorageFactory, opts ...types.Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.graph == nil {
+		return types.NewError("compact", "graph", "storage is closed", nil)
+	}
+	if err := s.compactTo(storageFactory, opts, s.dataHeader.Generation+1); err != nil {
+		return types.NewError("compact", "graph", err.Error(), err)
+	}
+	return nil
+}
+
+func (s *GraphStorage) compactTo(storageFactory types.MappedStorageFactory, opts []types.Option, generation uint64) error {
+	_, cfg := applyOptions(types.Options{}, config{mirror: true}, opts)
+
+	var storages []types.MappedStorage
+	defer func() { closeStorages(storages) }()
+	for _, path := range []string{cfg.nodePath, cfg.edgePath, cfg.dataPath} {
+		storage, err := storageFactory(path, false)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		storages = append(storages, storage)
+	}
+
+	g := s.graph
+	var entries []byte
+	var entryCount uint64
+	copied := make(map[uint64]uint64)
+	copyEntry := func(offset uint64) (uint64, error) {
+		if offset == 0 {
+			return 0, nil
+		}
+		if newOffset, ok := copied[offset]; ok {
+			return newOffset, nil
+		}
+		dataType, typeName, payload, err := readDataEntry(s.dataStorage, offset)
+		if err != nil {
+			return 0, err
+		}
+		entry, err := newSerializedDataEntry(dataType, typeName, payload)
+		if err != nil {
+			return 0, err
+		}
+		newOffset := DataHeaderSize + uint64(len(entries))
+		entries = appendDataEntry(entries, entry)
+		entryCount++
+		copied[offset] = newOffset
+		return newOffset, nil
+	}
+
+	nodes := make([]NodeRecord, 0, g.numNodes)
+	for _, record := range g.nodeRecords {
+		if record.Flags&FlagDeleted != 0 {
+			continue
+		}
+		offset, err := copyEntry(record.DataOffset)
+		if err != nil {
+			return fmt.Errorf("node %d: %w", record.ID, err)
+		}
+		record.DataOffset = offset
+		nodes = append(nodes, record)
+	}
+	edges := make([]EdgeRecord, 0, g.numEdges)
+	for _, record := range g.edgeRecords {
+		if record.Flags&FlagDeleted != 0 {
+			continue
+		}
+		_, fromOK := g.nodeIndex[record.FromID]
+		_, toOK := g.nodeIndex[record.ToID]
+		if !fromOK || !toOK {
+			continue
+		}
+		offset, err := copyEntry(uint64(record.DataOffset))
+		if err != nil {
+			return fmt.Errorf("edge %d->%d: %w", record.FromID, record.ToID, err)
+		}
+		if offset > math.MaxUint32 {
+			return fmt.Errorf("edge data offset %d exceeds uint32 capacity", offset)
+		}
+		record.DataOffset = uint32(offset)
+		edges = append(edges, record)
+	}
+
+	nodeHeader, edgeHeader := s.nodeHeader, s.edgeHeader
+	var err error
+	if nodeHeader.DataFileOffset, err = copyEntry(nodeHeader.DataFileOffset); err != nil {
+		return fmt.Errorf("graph metadata: %w", err)
+	}
+	if edgeHeader.DataFileOffset, err = copyEntry(edgeHeader.DataFileOffset); err != nil {
+		return fmt.Errorf("graph metadata: %w", err)
+	}
+
+	dataHeader := DataFileHeader{
+		Magic:      s.dataHeader.Magic,
+		Version:    FormatVersion,
+		EntryCount: entryCount,
+		DataEnd:    DataHeaderSize + uint64(len(entries)),
+		Checksum:   CalculateChecksum(entries),
+		Generation: generation,
+	}
+	var dataHeaderBytes [DataHeaderSize]byte
+	if err := WriteDataHeader(dataHeaderBytes[:], &dataHeader); err != nil {
+		return err
+	}
+
+	nodeBytes := encodeNodeRecords(nodes)
+	edgeBytes := encodeEdgeRecords(edges)
+	nodeHeader.NodeCount = uint64(len(nodes))
+	edgeHeader.EdgeCount = uint64(len(edges))
+	setRecordFileGeneration(&nodeHeader.Reserved, generation)
+	setRecordFileGeneration(&edgeHeader.Reserved, generation)
+	nodeHeaderBytes := encodeNodeHeader(&nodeHeader, nodeBytes)
+	edgeHeaderBytes := encodeEdgeHeader(&edgeHeader, edgeBytes)
+
+	if err := writeAt(storages[2], 0, append(dataHeaderBytes[:], entries...)); err != nil {
+		return fmt.Errorf("failed to write data file: %w", err)
+	}
+	if err := writeAt(storages[0], 0, append(nodeHeaderBytes[:], nodeBytes...)); err != nil {
+		return fmt.Errorf("failed to write node file: %w", err)
+	}
+	if err := writeAt(storages[1], 0, append(edgeHeaderBytes[:], edgeBytes...)); err != nil {
+		return fmt.Errorf("failed to write edge file: %w", err)
+	}
+	return nil
+}
+
+// Compact compacts the graph files named by opts in place (see CompactTo).
+// The graph must not be open elsewhere.
+//
+// The compacted files are written next to the originals with a ".compact"
+// suffix and renamed over them once all three are complete. The files carry a
+// compaction generation, so a run interrupted while renaming is detected when
+// the graph is opened and completed by calling Compact again.
+func Compact(opts ...types.Option) error {
+	_, cfg := applyOptions(types.Options{}, config{mirror: true}, opts)
+	paths := []string{cfg.nodePath, cfg.edgePath, cfg.dataPath}
+
+	if err := resumeCompaction(paths); err != nil {
+		return types.NewError("compact", "graph", err.Error(), err)
+	}
+
+	factory := NewFileMap()
+	s, err := OpenStorage(factory, opts...)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path + compactSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.Close()
+			return types.NewError("compact", "graph", err.Error(), err)
+		}
+	}
+	err = s.compactTo(factory, []types.Option{
+		WithPath(paths[0] + compactSuffix),
+		WithEdgesPath(paths[1] + compactSuffix),
+		WithLabelsPath(paths[2] + compactSuffix),
+	}, s.dataHeader.Generation+1)
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return types.NewError("compact", "graph", err.Error(), err)
+	}
+
+	if err := renameCompacted(paths); err != nil {
+		return types.NewError("compact", "graph", err.Error(), err)
+	}
+	return nil
+}
+
+// resumeCompaction completes the renames of an interrupted Compact, or removes
+// its compacted files when they were not all written.
+func resumeCompaction(paths []string) error {
+	pending := false
+	for _, path := range paths {
+		if _, err := os.Stat(path + compactSuffix); err == nil {
+			pending = true
+		}
+	}
+	if !pending {
+		return nil
+	}
+
+	// Renaming started only if every file is either a complete compacted file
+	// or an original that was already replaced, all of the same generation.
+	complete := true
+	var generation uint64
+	for i, path := range paths {
+		candidate := path + compactSuffix
+		if _, err := os.Stat(candidate); err != nil {
+			candidate = path
+		}
+		gen, err := fileGeneration(candidate, i)
+		if err != nil || (i > 0 && gen != generation) {
+			complete = false
+			break
+		}
+		generation = gen
+	}
+	if complete {
+		return renameCompacted(paths)
+	}
+	for _, path := range paths {
+		if err := os.Remove(path + compactSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func renameCompacted(paths []string) error {
+	for _, path := range paths {
+		if err := os.Rename(path+compactSuffix, path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileGeneration validates one graph file and returns its compaction generation.
+// kind is the position of the file in (nodes, edges, data).
+func fileGeneration(path string, kind int) (uint64, error) {
+	storage, err := NewFileMap()(path, true)
+	if err != nil {
+		return 0, err
+	}
+	defer storage.Close()
+
+	switch kind {
+	case 0:
+		header, err := verifyNodeFile(storage)
+		if err != nil {
+			return 0, err
+		}
+		return recordFileGeneration(header.Reserved), nil
+	case 1:
+		header, err := verifyEdgeFile(storage)
+		if err != nil {
+			return 0, err
+		}
+		return recordFileGeneration(header.Reserved), nil
+	default:
+		header, err := verifyDataFile(storage)
+		if err != nil {
+			return 0, err
+		}
+		return header.Generation, nil
+	}
+}
@@ -3,6 +3,7 @@ package graph
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/crc64"
 )
 
 const (
@@ -46,12 +47,18 @@ type EdgeFileHeader struct {
 	Reserved       [20]byte
 }
 
-// DataFileHeader represents the header of the data file
+// DataFileHeader represents the header of the data file.
+// DataEnd, JournalOffset, Checksum and Generation are zero in files written
+// before transactions were supported.
 type DataFileHeader struct {
-	Magic      [8]byte
-	Version    uint32
-	EntryCount uint64
-	Reserved   [44]byte
+	Magic         [8]byte
+	Version       uint32
+	EntryCount    uint64
+	DataEnd       uint64 // End of the last committed entry
+	JournalOffset uint64 // Offset of a commit journal not yet applied (0 = none)
+	Checksum      uint64 // CRC-64 of the entries in [DataHeaderSize, DataEnd)
+	Generation    uint64 // Compaction generation, shared by all three files
+	Reserved      [12]byte
 }
 
 // NodeRecord represents a fixed-size node record (32 bytes)
@@ -227,14 +234,34 @@ func ReadEdgeRecord(data []byte) (*EdgeRecord, error) {
 	return record, nil
 }
 
-// CalculateChecksum calculates a simple checksum for header validation
-// This is a placeholder - in production, use a proper checksum algorithm
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// CalculateChecksum calculates the CRC-64 (ECMA) checksum of data.
 func CalculateChecksum(data []byte) uint64 {
-	var sum uint64
-	for _, b := range data {
-		sum += uint64(b)
-	}
-	return sum
+	return crc64.Checksum(data, crcTable)
+}
+
+// updateChecksum extends a checksum returned by CalculateChecksum with data.
+func updateChecksum(sum uint64, data []byte) uint64 {
+	return crc64.Update(sum, crcTable, data)
+}
+
+// recordFileChecksum calculates the checksum of a node or edge file: every
+// header field except the checksum itself, followed by the records.
+func recordFileChecksum(header, records []byte) uint64 {
+	sum := CalculateChecksum(header[0:36])
+	sum = updateChecksum(sum, header[44:64])
+	return updateChecksum(sum, records)
+}
+
+// recordFileGeneration returns the compaction generation stored in the
+// reserved bytes of a node or edge file header.
+func recordFileGeneration(reserved [20]byte) uint64 {
+	return binary.LittleEndian.Uint64(reserved[1:9])
+}
+
+func setRecordFileGeneration(reserved *[20]byte, generation uint64) {
+	binary.LittleEndian.PutUint64(reserved[1:9], generation)
 }
 
 // WriteDataHeader writes a data file header to a byte slice
@@ -246,7 +273,11 @@ func WriteDataHeader(data []byte, header *DataFileHeader) error {
 	copy(data[0:8], header.Magic[:])
 	binary.LittleEndian.PutUint32(data[8:12], header.Version)
 	binary.LittleEndian.PutUint64(data[12:20], header.EntryCount)
-	copy(data[20:64], header.Reserved[:])
+	binary.LittleEndian.PutUint64(data[20:28], header.DataEnd)
+	binary.LittleEndian.PutUint64(data[28:36], header.JournalOffset)
+	binary.LittleEndian.PutUint64(data[36:44], header.Checksum)
+	binary.LittleEndian.PutUint64(data[44:52], header.Generation)
+	copy(data[52:64], header.Reserved[:])
 
 	return nil
 }
@@ -261,7 +292,11 @@ func ReadDataHeader(data []byte) (*DataFileHeader, error) {
 	copy(header.Magic[:], data[0:8])
 	header.Version = binary.LittleEndian.Uint32(data[8:12])
 	header.EntryCount = binary.LittleEndian.Uint64(data[12:20])
-	copy(header.Reserved[:], data[20:64])
+	header.DataEnd = binary.LittleEndian.Uint64(data[20:28])
+	header.JournalOffset = binary.LittleEndian.Uint64(data[28:36])
+	header.Checksum = binary.LittleEndian.Uint64(data[36:44])
+	header.Generation = binary.LittleEndian.Uint64(data[44:52])
+	copy(header.Reserved[:], data[52:64])
 
 	if string(header.Magic[:]) != DataMagic {
 		return nil, fmt.Errorf("invalid data file magic: expected %s, got %s", DataMagic, string(header.Magic[:]))
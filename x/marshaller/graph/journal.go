@@ -0,0 +1,247 @@
+package graph
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+const journalTypeName = "__graph_journal__"
+
+// journal holds the node and edge file changes of a commit.
+// It is appended to data.graph and referenced from the data header before the
+// record files are touched, so a commit interrupted after that point is
+// replayed when the graph is opened again. Replaying is idempotent.
+type journal struct {
+	nodeHeader  [NodeHeaderSize]byte
+	edgeHeader  [EdgeHeaderSize]byte
+	nodePatches []recordPatch
+	edgePatches []recordPatch
+}
+
+// recordPatch is the new content of a node or edge record.
+type recordPatch struct {
+	index  uint64
+	record [NodeRecordSize]byte
+}
+
+const recordPatchSize = 8 + NodeRecordSize
+
+func newRecordPatches(records []byte, dirty map[int]struct{}) []recordPatch {
+	indices := make([]int, 0, len(dirty))
+	for idx := range dirty {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+
+	patches := make([]recordPatch, len(indices))
+	for i, idx := range indices {
+		patches[i].index = uint64(idx)
+		copy(patches[i].record[:], records[idx*NodeRecordSize:])
+	}
+	return patches
+}
+
+func (j *journal) encode() []byte {
+	size := NodeHeaderSize + EdgeHeaderSize + 8 + (len(j.nodePatches)+len(j.edgePatches))*recordPatchSize
+	buf := make([]byte, 0, size)
+	buf = append(buf, j.nodeHeader[:]...)
+	buf = append(buf, j.edgeHeader[:]...)
+	for _, patches := range [][]recordPatch{j.nodePatches, j.edgePatches} {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(patches)))
+		for _, p := range patches {
+			buf = binary.LittleEndian.AppendUint64(buf, p.index)
+			buf = append(buf, p.record[:]...)
+		}
+	}
+	return buf
+}
+
+func decodeJournal(data []byte) (*journal, error) {
+	j := &journal{}
+	if len(data) < NodeHeaderSize+EdgeHeaderSize {
+		return nil, fmt.Errorf("journal truncated")
+	}
+	copy(j.nodeHeader[:], data)
+	copy(j.edgeHeader[:], data[NodeHeaderSize:])
+	data = data[NodeHeaderSize+EdgeHeaderSize:]
+
+	readPatches := func() ([]recordPatch, error) {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("journal truncated")
+		}
+		count := int(binary.LittleEndian.Uint32(data))
+		data = data[4:]
+		if len(data) < count*recordPatchSize {
+			return nil, fmt.Errorf("journal truncated: %d record patches", count)
+		}
+		patches := make([]recordPatch, count)
+		for i := range patches {
+			patches[i].index = binary.LittleEndian.Uint64(data)
+			copy(patches[i].record[:], data[8:recordPatchSize])
+			data = data[recordPatchSize:]
+		}
+		return patches, nil
+	}
+
+	var err error
+	if j.nodePatches, err = readPatches(); err != nil {
+		return nil, err
+	}
+	if j.edgePatches, err = readPatches(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// apply writes the journal to the node and edge files.
+func (j *journal) apply(nodeStorage, edgeStorage types.MappedStorage) error {
+	nodeCount := binary.LittleEndian.Uint64(j.nodeHeader[20:28])
+	if err := patchRecordFile(nodeStorage, j.nodeHeader[:], NodeHeaderSize+int64(nodeCount)*NodeRecordSize, j.nodePatches); err != nil {
+		return fmt.Errorf("failed to patch node file: %w", err)
+	}
+	edgeCount := binary.LittleEndian.Uint64(j.edgeHeader[20:28])
+	if err := patchRecordFile(edgeStorage, j.edgeHeader[:], EdgeHeaderSize+int64(edgeCount)*EdgeRecordSize, j.edgePatches); err != nil {
+		return fmt.Errorf("failed to patch edge file: %w", err)
+	}
+	return nil
+}
+
+func patchRecordFile(storage types.MappedStorage, header []byte, size int64, patches []recordPatch) error {
+	currentSize, err := storage.Size()
+	if err != nil {
+		return err
+	}
+	if size > currentSize {
+		if err := storage.Grow(size); err != nil {
+			return err
+		}
+	}
+
+	region, err := storage.Map(0, size)
+	if err != nil {
+		return err
+	}
+	defer region.Unmap()
+
+	data := region.Bytes()
+	for _, p := range patches {
+		offset := NodeHeaderSize + int64(p.index)*NodeRecordSize
+		if offset+NodeRecordSize > size {
+			return fmt.Errorf("record %d out of range", p.index)
+		}
+		copy(data[offset:offset+NodeRecordSize], p.record[:])
+	}
+	copy(data[:NodeHeaderSize], header)
+	return region.Sync()
+}
+
+// recoverJournal replays a journal left behind by an interrupted commit and
+// clears it from the data header.
+func recoverJournal(nodeStorage, edgeStorage, dataStorage types.MappedStorage, header *DataFileHeader) error {
+	if header.JournalOffset == 0 {
+		return nil
+	}
+	_, typeName, payload, err := readDataEntry(dataStorage, header.JournalOffset)
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	if typeName != journalTypeName {
+		return fmt.Errorf("data entry at %d is not a journal", header.JournalOffset)
+	}
+	j, err := decodeJournal(payload)
+	if err != nil {
+		return err
+	}
+	if err := j.apply(nodeStorage, edgeStorage); err != nil {
+		return err
+	}
+	header.JournalOffset = 0
+	return writeDataFileHeader(dataStorage, header)
+}
+
+func writeDataFileHeader(storage types.MappedStorage, header *DataFileHeader) error {
+	region, err := storage.Map(0, DataHeaderSize)
+	if err != nil {
+		return err
+	}
+	defer region.Unmap()
+
+	if err := WriteDataHeader(region.Bytes(), header); err != nil {
+		return err
+	}
+	return region.Sync()
+}
+
+// appendDataEntry appends the encoded entry to buf.
+func appendDataEntry(buf []byte, entry serializedDataEntry) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(entry.payload)))
+	buf = append(buf, byte(entry.dataType))
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(entry.typeName)))
+	buf = append(buf, entry.typeName...)
+	return append(buf, entry.payload...)
+}
+
+// writeAt writes data at offset, growing the storage as needed.
+func writeAt(storage types.MappedStorage, offset int64, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	end := offset + int64(len(data))
+	currentSize, err := storage.Size()
+	if err != nil {
+		return err
+	}
+	if end > currentSize {
+		if err := storage.Grow(end); err != nil {
+			return err
+		}
+	}
+
+	region, err := storage.Map(offset, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	defer region.Unmap()
+
+	copy(region.Bytes(), data)
+	return region.Sync()
+}
+
+func encodeNodeRecords(records []NodeRecord) []byte {
+	buf := make([]byte, len(records)*NodeRecordSize)
+	for i := range records {
+		_ = WriteNodeRecord(buf[i*NodeRecordSize:], &records[i])
+	}
+	return buf
+}
+
+func encodeEdgeRecords(records []EdgeRecord) []byte {
+	buf := make([]byte, len(records)*EdgeRecordSize)
+	for i := range records {
+		_ = WriteEdgeRecord(buf[i*EdgeRecordSize:], &records[i])
+	}
+	return buf
+}
+
+// encodeNodeHeader encodes the header with the checksum of records.
+func encodeNodeHeader(header *NodeFileHeader, records []byte) [NodeHeaderSize]byte {
+	var buf [NodeHeaderSize]byte
+	header.Checksum = 0
+	_ = WriteNodeHeader(buf[:], header)
+	header.Checksum = recordFileChecksum(buf[:], records)
+	_ = WriteNodeHeader(buf[:], header)
+	return buf
+}
+
+// encodeEdgeHeader encodes the header with the checksum of records.
+func encodeEdgeHeader(header *EdgeFileHeader, records []byte) [EdgeHeaderSize]byte {
+	var buf [EdgeHeaderSize]byte
+	header.Checksum = 0
+	_ = WriteEdgeHeader(buf[:], header)
+	header.Checksum = recordFileChecksum(buf[:], records)
+	_ = WriteEdgeHeader(buf[:], header)
+	return buf
+}
@@ -138,5 +138,12 @@ func (m *GraphMarshaller) marshalGraph(value any, nodeStorage, edgeStorage, data
 		return types.NewError("marshal", "graph", "failed to update edge header", err)
 	}
 
+	if err := sealNodeFile(nodeStorage); err != nil {
+		return types.NewError("marshal", "graph", "failed to seal node file", err)
+	}
+	if err := sealEdgeFile(edgeStorage); err != nil {
+		return types.NewError("marshal", "graph", "failed to seal edge file", err)
+	}
+
 	return nil
 }
@@ -162,3 +162,165 @@ func validateProtobufTypes(storage types.MappedStorage, registry map[string]prot
 		return nil
 	})
 }
+
+// verifyNodeFile reads the node file header and validates the checksum of the
+// header and records. Files written without a checksum are not validated.
+func verifyNodeFile(storage types.MappedStorage) (*NodeFileHeader, error) {
+	header, err := readNodeFileHeader(storage)
+	if err != nil {
+		return nil, err
+	}
+	if header.Checksum == 0 {
+		return header, nil
+	}
+	sum, err := recordFileSum(storage, NodeHeaderSize+int64(header.NodeCount)*NodeRecordSize)
+	if err != nil {
+		return nil, fmt.Errorf("node file: %w", err)
+	}
+	if sum != header.Checksum {
+		return nil, fmt.Errorf("node file checksum mismatch: stored %#x, computed %#x", header.Checksum, sum)
+	}
+	return header, nil
+}
+
+// verifyEdgeFile reads the edge file header and validates the checksum of the
+// header and records. Files written without a checksum are not validated.
+func verifyEdgeFile(storage types.MappedStorage) (*EdgeFileHeader, error) {
+	header, err := readEdgeFileHeader(storage)
+	if err != nil {
+		return nil, err
+	}
+	if header.Checksum == 0 {
+		return header, nil
+	}
+	sum, err := recordFileSum(storage, EdgeHeaderSize+int64(header.EdgeCount)*EdgeRecordSize)
+	if err != nil {
+		return nil, fmt.Errorf("edge file: %w", err)
+	}
+	if sum != header.Checksum {
+		return nil, fmt.Errorf("edge file checksum mismatch: stored %#x, computed %#x", header.Checksum, sum)
+	}
+	return header, nil
+}
+
+func recordFileSum(storage types.MappedStorage, size int64) (uint64, error) {
+	currentSize, err := storage.Size()
+	if err != nil {
+		return 0, err
+	}
+	if size > currentSize {
+		return 0, fmt.Errorf("truncated: need %d bytes, have %d", size, currentSize)
+	}
+	region, err := storage.Map(0, size)
+	if err != nil {
+		return 0, err
+	}
+	defer region.Unmap()
+
+	data := region.Bytes()
+	return recordFileChecksum(data[:NodeHeaderSize], data[NodeHeaderSize:]), nil
+}
+
+// verifyDataFile reads the data file header and validates the checksum of the
+// committed entries. Files written before transactions have no DataEnd and are
+// not validated.
+func verifyDataFile(storage types.MappedStorage) (*DataFileHeader, error) {
+	header, err := readDataFileHeader(storage)
+	if err != nil {
+		return nil, err
+	}
+	if header.DataEnd == 0 {
+		return header, nil
+	}
+	size, err := storage.Size()
+	if err != nil {
+		return nil, err
+	}
+	if header.DataEnd < DataHeaderSize || int64(header.DataEnd) > size {
+		return nil, fmt.Errorf("data file truncated: entries end at %d, have %d bytes", header.DataEnd, size)
+	}
+	if header.JournalOffset != 0 && (header.JournalOffset < DataHeaderSize || header.JournalOffset >= header.DataEnd) {
+		return nil, fmt.Errorf("data file journal offset %d out of range", header.JournalOffset)
+	}
+
+	var sum uint64
+	if header.DataEnd > DataHeaderSize {
+		region, err := storage.Map(DataHeaderSize, int64(header.DataEnd)-DataHeaderSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map data entries: %w", err)
+		}
+		sum = CalculateChecksum(region.Bytes())
+		region.Unmap()
+	}
+	if sum != header.Checksum {
+		return nil, fmt.Errorf("data file checksum mismatch: stored %#x, computed %#x", header.Checksum, sum)
+	}
+	return header, nil
+}
+
+// verifyGraphFiles validates all three files of a graph opened read-only.
+func verifyGraphFiles(nodeStorage, edgeStorage, dataStorage types.MappedStorage) (*NodeFileHeader, *EdgeFileHeader, error) {
+	dataHeader, err := verifyDataFile(dataStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dataHeader.JournalOffset != 0 {
+		return nil, nil, fmt.Errorf("graph has an interrupted commit; open it with OpenStorage to recover")
+	}
+	nodeHeader, err := verifyNodeFile(nodeStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	edgeHeader, err := verifyEdgeFile(edgeStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkGenerations(nodeHeader, edgeHeader, dataHeader); err != nil {
+		return nil, nil, err
+	}
+	return nodeHeader, edgeHeader, nil
+}
+
+// checkGenerations detects files left behind by an interrupted Compact.
+func checkGenerations(nodeHeader *NodeFileHeader, edgeHeader *EdgeFileHeader, dataHeader *DataFileHeader) error {
+	nodeGen := recordFileGeneration(nodeHeader.Reserved)
+	edgeGen := recordFileGeneration(edgeHeader.Reserved)
+	if nodeGen != edgeGen || nodeGen != dataHeader.Generation {
+		return fmt.Errorf("graph files are from different compactions (nodes %d, edges %d, data %d); rerun Compact", nodeGen, edgeGen, dataHeader.Generation)
+	}
+	return nil
+}
+
+// dataEntriesEnd walks count entries and returns the offset just past them.
+func dataEntriesEnd(storage types.MappedStorage, count uint64) (uint64, error) {
+	offset := uint64(DataHeaderSize)
+	err := forEachDataEntry(storage, count, func(entryOffset, size uint64) {
+		offset = entryOffset + size
+	})
+	return offset, err
+}
+
+func forEachDataEntry(storage types.MappedStorage, count uint64, fn func(offset, size uint64)) error {
+	region, err := storage.Map(0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to map data storage: %w", err)
+	}
+	defer region.Unmap()
+
+	data := region.Bytes()
+	offset := uint64(DataHeaderSize)
+	for i := uint64(0); i < count; i++ {
+		if offset+dataEntryHeaderSize > uint64(len(data)) {
+			return fmt.Errorf("data entry header truncated")
+		}
+		payloadLen := binary.LittleEndian.Uint32(data[offset : offset+4])
+		typeNameLen := binary.LittleEndian.Uint16(data[offset+5 : offset+7])
+		size := dataEntryHeaderSize + uint64(typeNameLen) + uint64(payloadLen)
+		if offset+size > uint64(len(data)) {
+			return fmt.Errorf("data entry truncated")
+		}
+		fn(offset, size)
+		offset += size
+	}
+	return nil
+}
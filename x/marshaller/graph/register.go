@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/storage"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	pathOption := func(path string) []types.Option {
+		return []types.Option{WithPath(path)}
+	}
+	registry.Register(registry.Format{
+		Name:        "graph",
+		Extensions:  []string{".graph"},
+		Magic:       []registry.Magic{{Bytes: NodeMagic}},
+		Files:       true,
+		LoadOptions: pathOption,
+		SaveOptions: pathOption,
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(storageFactory(opts), opts...)
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(storageFactory(opts), opts...)
+		},
+	})
+}
+
+// storageFactory returns the factory set with types.WithMappedStorageFactory,
+// or plain files.
+func storageFactory(opts []types.Option) types.MappedStorageFactory {
+	var o types.Options
+	for _, opt := range opts {
+		opt.Apply(&o)
+	}
+	if o.MappedStorageFactory != nil {
+		return o.MappedStorageFactory
+	}
+	return storage.NewFileMap()
+}
@@ -33,7 +33,6 @@ func newStoredGraph(nodeStorage, edgeStorage, dataStorage types.MappedStorage, r
 			return nil, fmt.Errorf("failed to read node record %d: %w", i, err)
 		}
 		g.nodeRecords = append(g.nodeRecords, *record)
-		g.nodeIndex[record.ID] = i
 	}
 
 	edgeHeader, err := readEdgeFileHeader(edgeStorage)
@@ -46,12 +45,33 @@ func newStoredGraph(nodeStorage, edgeStorage, dataStorage types.MappedStorage, r
 			return nil, fmt.Errorf("failed to read edge record %d: %w", i, err)
 		}
 		g.edgeRecords = append(g.edgeRecords, *record)
-		g.edgesByFrom[record.FromID] = append(g.edgesByFrom[record.FromID], i)
 	}
+	g.reindex()
 
 	return g, nil
 }
 
+// reindex rebuilds the lookup tables from the records, skipping deleted ones.
+func (g *StoredGraph) reindex() {
+	g.nodeIndex = make(map[int64]int, len(g.nodeRecords))
+	g.edgesByFrom = make(map[int64][]int)
+	g.numNodes, g.numEdges = 0, 0
+	for i, record := range g.nodeRecords {
+		if record.Flags&FlagDeleted != 0 {
+			continue
+		}
+		g.nodeIndex[record.ID] = i
+		g.numNodes++
+	}
+	for i, record := range g.edgeRecords {
+		if record.Flags&FlagDeleted != 0 {
+			continue
+		}
+		g.edgesByFrom[record.FromID] = append(g.edgesByFrom[record.FromID], i)
+		g.numEdges++
+	}
+}
+
 // Close releases underlying storages.
 func (g *StoredGraph) Close() error {
 	var firstErr error
@@ -74,7 +94,10 @@ func (g *StoredGraph) Close() error {
 // Nodes returns an iterator over all nodes in the stored graph.
 func (g *StoredGraph) Nodes() iter.Seq[graph.Node[any, any]] {
 	return func(yield func(graph.Node[any, any]) bool) {
-		for idx := range g.nodeRecords {
+		for idx, record := range g.nodeRecords {
+			if record.Flags&FlagDeleted != 0 {
+				continue
+			}
 			node := &storedNode{graph: g, index: idx}
 			if !yield(node) {
 				return
@@ -86,7 +109,10 @@ func (g *StoredGraph) Nodes() iter.Seq[graph.Node[any, any]] {
 // Edges returns an iterator over all edges in the stored graph.
 func (g *StoredGraph) Edges() iter.Seq[graph.Edge[any, any]] {
 	return func(yield func(graph.Edge[any, any]) bool) {
-		for idx := range g.edgeRecords {
+		for idx, record := range g.edgeRecords {
+			if record.Flags&FlagDeleted != 0 {
+				continue
+			}
 			edge := &storedEdge{graph: g, index: idx}
 			if !yield(edge) {
 				return
@@ -95,14 +121,14 @@ func (g *StoredGraph) Edges() iter.Seq[graph.Edge[any, any]] {
 	}
 }
 
-// NumNodes returns the total number of nodes.
+// NumNodes returns the number of nodes that are not deleted.
 func (g *StoredGraph) NumNodes() int {
-	return len(g.nodeRecords)
+	return g.numNodes
 }
 
-// NumEdges returns the total number of edges.
+// NumEdges returns the number of edges that are not deleted.
 func (g *StoredGraph) NumEdges() int {
-	return len(g.edgeRecords)
+	return g.numEdges
 }
 
 func (g *StoredGraph) nodeByID(id int64) (*storedNode, bool) {
@@ -0,0 +1,523 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// GraphStorage provides transactional access to a graph stored in mmap-backed storage.
+//
+// A commit appends the new data entries and a journal of the record changes to
+// data.graph, then rewrites the data header to reference them. That single
+// header write is the commit point: before it the graph is unchanged, after it
+// the journal is replayed into nodes.graph and edges.graph, again on the next
+// open if the process dies in between.
+type GraphStorage struct {
+	mu          sync.Mutex
+	nodeStorage types.MappedStorage
+	edgeStorage types.MappedStorage
+	dataStorage types.MappedStorage
+	graph       *StoredGraph
+	nodeHeader  NodeFileHeader
+	edgeHeader  EdgeFileHeader
+	dataHeader  DataFileHeader
+	nextID      int64 // next node ID handed out to any transaction
+}
+
+// OpenStorage opens the graph files named by WithPath, WithEdgesPath and
+// WithLabelsPath for transactional updates. Empty files are initialized as an
+// empty graph. An interrupted commit is completed and the checksums of all
+// three files are validated before the storage is returned.
+func OpenStorage(storageFactory types.MappedStorageFactory, opts ...types.Option) (*GraphStorage, error) {
+	_, cfg := applyOptions(types.Options{}, config{mirror: true}, opts)
+
+	var storages []types.MappedStorage
+	for _, path := range []string{cfg.nodePath, cfg.edgePath, cfg.dataPath} {
+		storage, err := storageFactory(path, false)
+		if err != nil {
+			closeStorages(storages)
+			return nil, types.NewError("open", "graph", fmt.Sprintf("failed to open %s: %v", path, err), err)
+		}
+		storages = append(storages, storage)
+	}
+
+	s, err := openStorage(storages[0], storages[1], storages[2], cfg)
+	if err != nil {
+		closeStorages(storages)
+		return nil, types.NewError("open", "graph", err.Error(), err)
+	}
+	return s, nil
+}
+
+func openStorage(nodeStorage, edgeStorage, dataStorage types.MappedStorage, cfg config) (*GraphStorage, error) {
+	if err := initEmptyGraph(nodeStorage, edgeStorage, dataStorage); err != nil {
+		return nil, err
+	}
+
+	dataHeader, err := verifyDataFile(dataStorage)
+	if err != nil {
+		return nil, err
+	}
+	if err := recoverJournal(nodeStorage, edgeStorage, dataStorage, dataHeader); err != nil {
+		return nil, fmt.Errorf("failed to recover interrupted commit: %w", err)
+	}
+	nodeHeader, err := verifyNodeFile(nodeStorage)
+	if err != nil {
+		return nil, err
+	}
+	edgeHeader, err := verifyEdgeFile(edgeStorage)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGenerations(nodeHeader, edgeHeader, dataHeader); err != nil {
+		return nil, err
+	}
+
+	if dataHeader.DataEnd == 0 {
+		// Written before transactions: locate the end of the entries once
+		end, err := dataEntriesEnd(dataStorage, dataHeader.EntryCount)
+		if err != nil {
+			return nil, err
+		}
+		dataHeader.DataEnd = end
+		if end > DataHeaderSize {
+			region, err := dataStorage.Map(DataHeaderSize, int64(end)-DataHeaderSize)
+			if err != nil {
+				return nil, err
+			}
+			dataHeader.Checksum = CalculateChecksum(region.Bytes())
+			region.Unmap()
+		}
+	}
+
+	g, err := newStoredGraph(nodeStorage, edgeStorage, dataStorage, cfg.registeredTypes, graphKindFromByte(nodeHeader.Reserved[0]))
+	if err != nil {
+		return nil, err
+	}
+	g.onEqual = cfg.onEqual
+	g.onCompare = cfg.onCompare
+	g.onCost = cfg.onCost
+
+	return &GraphStorage{
+		nodeStorage: nodeStorage,
+		edgeStorage: edgeStorage,
+		dataStorage: dataStorage,
+		graph:       g,
+		nodeHeader:  *nodeHeader,
+		edgeHeader:  *edgeHeader,
+		dataHeader:  *dataHeader,
+		nextID:      nodeHeader.MaxID + 1,
+	}, nil
+}
+
+// initEmptyGraph writes an empty graph when all three storages are empty.
+func initEmptyGraph(nodeStorage, edgeStorage, dataStorage types.MappedStorage) error {
+	empty := 0
+	for _, storage := range []types.MappedStorage{nodeStorage, edgeStorage, dataStorage} {
+		size, err := storage.Size()
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			empty++
+		}
+	}
+	switch empty {
+	case 0:
+		return nil
+	case 3:
+	default:
+		return fmt.Errorf("incomplete graph: %d of 3 files are empty", empty)
+	}
+
+	if err := writeNodeFile(nodeStorage, nil, graphKindGeneric); err != nil {
+		return err
+	}
+	if err := writeEdgeFile(edgeStorage, nil, graphKindGeneric); err != nil {
+		return err
+	}
+	if _, _, _, err := writeDataFile(dataStorage, nil, nil, nil); err != nil {
+		return err
+	}
+	if err := sealNodeFile(nodeStorage); err != nil {
+		return err
+	}
+	return sealEdgeFile(edgeStorage)
+}
+
+// Graph returns the stored graph. It reflects every committed transaction.
+func (s *GraphStorage) Graph() *StoredGraph {
+	return s.graph
+}
+
+// Close releases the underlying storages.
+func (s *GraphStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.graph == nil {
+		return nil
+	}
+	err := s.graph.Close()
+	s.graph = nil
+	return err
+}
+
+// BeginTransaction starts a new transaction and returns a transaction wrapper.
+// Similar to GORM pattern - operations on the wrapper are transactional.
+// Several transactions may be open at once: node IDs are allocated from the
+// storage, so they never collide, and IDs of rolled back nodes are not reused.
+func (s *GraphStorage) BeginTransaction() (*GraphTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.graph == nil {
+		return nil, types.NewError("begin", "graph", "storage is closed", nil)
+	}
+	return &GraphTransaction{
+		storage: s,
+		changes: make([]change, 0),
+	}, nil
+}
+
+// GraphTransaction wraps graph operations in a transaction.
+// Operations are validated against the graph when the transaction is committed;
+// if any of them fails nothing is written.
+type GraphTransaction struct {
+	storage *GraphStorage
+	changes []change
+	done    bool
+}
+
+const (
+	opAddNode    = "addNode"
+	opAddEdge    = "addEdge"
+	opDeleteNode = "deleteNode"
+	opDeleteEdge = "deleteEdge"
+	opUpdateNode = "updateNode"
+	opUpdateEdge = "updateEdge"
+)
+
+// change represents a single change in a transaction
+type change struct {
+	op     string // "addNode", "addEdge", "deleteNode", "deleteEdge", "updateNode", "updateEdge"
+	nodeID int64
+	fromID int64
+	toID   int64
+	data   *serializedDataEntry // Node or edge data, nil for none (cost is calculated, not stored)
+}
+
+var errTransactionDone = errors.New("transaction already committed or rolled back")
+
+// Commit commits all changes atomically to storage.
+// A power loss at any point leaves either the previous or the committed graph.
+func (tx *GraphTransaction) Commit() error {
+	if tx.done {
+		return types.NewError("commit", "graph", errTransactionDone.Error(), errTransactionDone)
+	}
+	tx.done = true
+	if len(tx.changes) == 0 {
+		return nil
+	}
+	if err := tx.storage.commit(tx.changes); err != nil {
+		return types.NewError("commit", "graph", err.Error(), err)
+	}
+	tx.changes = nil
+	return nil
+}
+
+// Rollback discards all changes in the transaction.
+func (tx *GraphTransaction) Rollback() error {
+	tx.done = true
+	tx.changes = nil
+	return nil
+}
+
+func (tx *GraphTransaction) record(c change) error {
+	if tx.done {
+		return errTransactionDone
+	}
+	tx.changes = append(tx.changes, c)
+	return nil
+}
+
+// AddNode adds a node to the graph storage and returns its ID.
+// Changes are recorded but not committed until Commit().
+func (tx *GraphTransaction) AddNode(data any) (int64, error) {
+	entry, err := newChangeData(data)
+	if err != nil {
+		return 0, err
+	}
+	if tx.done {
+		return 0, errTransactionDone
+	}
+	id := tx.storage.allocateID()
+	if err := tx.record(change{op: opAddNode, nodeID: id, data: entry}); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// allocateID reserves the next node ID.
+func (s *GraphStorage) allocateID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	return id
+}
+
+// AddEdge adds one or more edges to the graph storage.
+// Cost (if needed) should be included in the edge data.
+func (tx *GraphTransaction) AddEdge(fromID, toID int64, data any, edges ...EdgeSpec) error {
+	for _, spec := range append([]EdgeSpec{{FromID: fromID, ToID: toID, Data: data}}, edges...) {
+		entry, err := newChangeData(spec.Data)
+		if err != nil {
+			return err
+		}
+		if err := tx.record(change{op: opAddEdge, fromID: spec.FromID, toID: spec.ToID, data: entry}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteNode marks a node and every edge touching it as deleted.
+// Compact reclaims the space.
+func (tx *GraphTransaction) DeleteNode(nodeID int64) error {
+	return tx.record(change{op: opDeleteNode, nodeID: nodeID})
+}
+
+// DeleteEdge marks one or more edges as deleted.
+// Each pair deletes all edges between the two nodes.
+func (tx *GraphTransaction) DeleteEdge(fromID, toID int64, edges ...EdgeSpec) error {
+	if err := tx.record(change{op: opDeleteEdge, fromID: fromID, toID: toID}); err != nil {
+		return err
+	}
+	for _, spec := range edges {
+		if err := tx.record(change{op: opDeleteEdge, fromID: spec.FromID, toID: spec.ToID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateNode replaces a node's data.
+// New data is always appended to data.graph so the committed entry is never
+// overwritten; the old entry becomes a hole that Compact reclaims.
+func (tx *GraphTransaction) UpdateNode(nodeID int64, data any) error {
+	entry, err := newChangeData(data)
+	if err != nil {
+		return err
+	}
+	return tx.record(change{op: opUpdateNode, nodeID: nodeID, data: entry})
+}
+
+// UpdateEdge replaces the data of every edge from fromID to toID.
+// Cost (if needed) should be included in the edge data.
+// As with UpdateNode, new data is appended and the old entry becomes a hole.
+func (tx *GraphTransaction) UpdateEdge(fromID, toID int64, data any) error {
+	entry, err := newChangeData(data)
+	if err != nil {
+		return err
+	}
+	return tx.record(change{op: opUpdateEdge, fromID: fromID, toID: toID, data: entry})
+}
+
+// EdgeSpec specifies an edge for batch operations.
+type EdgeSpec struct {
+	FromID int64
+	ToID   int64
+	Data   any // Edge data (cost included if needed)
+}
+
+func newChangeData(data any) (*serializedDataEntry, error) {
+	if data == nil {
+		return nil, nil
+	}
+	payload, dataType, typeName, err := serializeData(data)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := newSerializedDataEntry(dataType, typeName, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// commit applies changes to copies of the records, then writes them using the
+// journal protocol described on GraphStorage.
+func (s *GraphStorage) commit(changes []change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.graph
+	if g == nil {
+		return fmt.Errorf("storage is closed")
+	}
+	if s.dataHeader.JournalOffset != 0 {
+		// A previous commit could not apply its journal
+		if err := recoverJournal(s.nodeStorage, s.edgeStorage, s.dataStorage, &s.dataHeader); err != nil {
+			return fmt.Errorf("failed to apply previous journal: %w", err)
+		}
+	}
+
+	nodes := append([]NodeRecord(nil), g.nodeRecords...)
+	edges := append([]EdgeRecord(nil), g.edgeRecords...)
+	index := make(map[int64]int, len(g.nodeIndex))
+	for id, idx := range g.nodeIndex {
+		index[id] = idx
+	}
+	dirtyNodes := make(map[int]struct{})
+	dirtyEdges := make(map[int]struct{})
+	nodeHeader, edgeHeader, dataHeader := s.nodeHeader, s.edgeHeader, s.dataHeader
+
+	var entries []byte
+	var entryCount uint64
+	appendEntry := func(entry *serializedDataEntry) uint64 {
+		if entry == nil {
+			return 0
+		}
+		offset := dataHeader.DataEnd + uint64(len(entries))
+		entries = appendDataEntry(entries, *entry)
+		entryCount++
+		return offset
+	}
+	edgeOffset := func(entry *serializedDataEntry) (uint32, error) {
+		offset := appendEntry(entry)
+		if offset > math.MaxUint32 {
+			return 0, fmt.Errorf("edge data offset %d exceeds uint32 capacity", offset)
+		}
+		return uint32(offset), nil
+	}
+	matchEdges := func(fromID, toID int64) []int {
+		var matched []int
+		for i := range edges {
+			if edges[i].Flags&FlagDeleted == 0 && edges[i].FromID == fromID && edges[i].ToID == toID {
+				matched = append(matched, i)
+			}
+		}
+		return matched
+	}
+	deleted := func(flags uint8) uint8 {
+		return flags&^FlagActive | FlagDeleted
+	}
+
+	for _, c := range changes {
+		switch c.op {
+		case opAddNode:
+			if _, ok := index[c.nodeID]; ok {
+				return fmt.Errorf("node %d already exists", c.nodeID)
+			}
+			index[c.nodeID] = len(nodes)
+			dirtyNodes[len(nodes)] = struct{}{}
+			nodes = append(nodes, NodeRecord{ID: c.nodeID, DataOffset: appendEntry(c.data), Flags: FlagActive})
+			nodeHeader.MaxID = max(nodeHeader.MaxID, c.nodeID)
+		case opAddEdge:
+			for _, id := range []int64{c.fromID, c.toID} {
+				if _, ok := index[id]; !ok {
+					return fmt.Errorf("edge %d->%d: node %d not found", c.fromID, c.toID, id)
+				}
+			}
+			offset, err := edgeOffset(c.data)
+			if err != nil {
+				return err
+			}
+			dirtyEdges[len(edges)] = struct{}{}
+			edges = append(edges, EdgeRecord{FromID: c.fromID, ToID: c.toID, DataOffset: offset, Flags: FlagActive})
+			edgeHeader.MaxID = max(edgeHeader.MaxID, c.fromID, c.toID)
+		case opDeleteNode:
+			idx, ok := index[c.nodeID]
+			if !ok {
+				return fmt.Errorf("node %d not found", c.nodeID)
+			}
+			nodes[idx].Flags = deleted(nodes[idx].Flags)
+			dirtyNodes[idx] = struct{}{}
+			delete(index, c.nodeID)
+			for i := range edges {
+				if edges[i].Flags&FlagDeleted == 0 && (edges[i].FromID == c.nodeID || edges[i].ToID == c.nodeID) {
+					edges[i].Flags = deleted(edges[i].Flags)
+					dirtyEdges[i] = struct{}{}
+				}
+			}
+		case opDeleteEdge:
+			matched := matchEdges(c.fromID, c.toID)
+			if len(matched) == 0 {
+				return fmt.Errorf("edge %d->%d not found", c.fromID, c.toID)
+			}
+			for _, i := range matched {
+				edges[i].Flags = deleted(edges[i].Flags)
+				dirtyEdges[i] = struct{}{}
+			}
+		case opUpdateNode:
+			idx, ok := index[c.nodeID]
+			if !ok {
+				return fmt.Errorf("node %d not found", c.nodeID)
+			}
+			nodes[idx].DataOffset = appendEntry(c.data)
+			dirtyNodes[idx] = struct{}{}
+		case opUpdateEdge:
+			matched := matchEdges(c.fromID, c.toID)
+			if len(matched) == 0 {
+				return fmt.Errorf("edge %d->%d not found", c.fromID, c.toID)
+			}
+			offset, err := edgeOffset(c.data)
+			if err != nil {
+				return err
+			}
+			for _, i := range matched {
+				edges[i].DataOffset = offset
+				dirtyEdges[i] = struct{}{}
+			}
+		default:
+			return fmt.Errorf("unknown operation %q", c.op)
+		}
+	}
+
+	nodeBytes := encodeNodeRecords(nodes)
+	edgeBytes := encodeEdgeRecords(edges)
+	nodeHeader.NodeCount = uint64(len(nodes))
+	edgeHeader.EdgeCount = uint64(len(edges))
+	j := &journal{
+		nodeHeader:  encodeNodeHeader(&nodeHeader, nodeBytes),
+		edgeHeader:  encodeEdgeHeader(&edgeHeader, edgeBytes),
+		nodePatches: newRecordPatches(nodeBytes, dirtyNodes),
+		edgePatches: newRecordPatches(edgeBytes, dirtyEdges),
+	}
+	journalEntry, err := newSerializedDataEntry(DataTypeBytes, journalTypeName, j.encode())
+	if err != nil {
+		return err
+	}
+	journalOffset := dataHeader.DataEnd + uint64(len(entries))
+	entries = appendDataEntry(entries, journalEntry)
+	entryCount++
+
+	// 1. Append past the committed end; a crash here leaves unreferenced bytes
+	if err := writeAt(s.dataStorage, int64(dataHeader.DataEnd), entries); err != nil {
+		return fmt.Errorf("failed to append data entries: %w", err)
+	}
+
+	// 2. Commit point
+	dataHeader.EntryCount += entryCount
+	dataHeader.Checksum = updateChecksum(dataHeader.Checksum, entries)
+	dataHeader.DataEnd += uint64(len(entries))
+	dataHeader.JournalOffset = journalOffset
+	if err := writeDataFileHeader(s.dataStorage, &dataHeader); err != nil {
+		return fmt.Errorf("failed to write data header: %w", err)
+	}
+	s.nodeHeader, s.edgeHeader, s.dataHeader = nodeHeader, edgeHeader, dataHeader
+	g.nodeRecords, g.edgeRecords = nodes, edges
+	g.reindex()
+
+	// 3. Apply the journal and clear it; OpenStorage replays it if this fails
+	if err := j.apply(s.nodeStorage, s.edgeStorage); err != nil {
+		return fmt.Errorf("committed, but applying the journal failed: %w", err)
+	}
+	s.dataHeader.JournalOffset = 0
+	if err := writeDataFileHeader(s.dataStorage, &s.dataHeader); err != nil {
+		return fmt.Errorf("committed, but clearing the journal failed: %w", err)
+	}
+	return nil
+}
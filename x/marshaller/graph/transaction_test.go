@@ -0,0 +1,403 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func graphPaths(t *testing.T) []types.Option {
+	t.Helper()
+	dir := t.TempDir()
+	return []types.Option{
+		WithPath(filepath.Join(dir, "nodes.graph")),
+		WithEdgesPath(filepath.Join(dir, "edges.graph")),
+		WithLabelsPath(filepath.Join(dir, "data.graph")),
+	}
+}
+
+func openStorageT(t *testing.T, factory types.MappedStorageFactory, opts []types.Option) *GraphStorage {
+	t.Helper()
+	s, err := OpenStorage(factory, opts...)
+	if err != nil {
+		t.Fatalf("OpenStorage() failed: %v", err)
+	}
+	return s
+}
+
+// describe renders the live nodes and edges of g in a stable order.
+func describe(g *StoredGraph) string {
+	var parts []string
+	for node := range g.Nodes() {
+		parts = append(parts, fmt.Sprintf("n%d=%v", node.ID(), node.Data()))
+	}
+	for edge := range g.Edges() {
+		parts = append(parts, fmt.Sprintf("e%d-%d=%v", edge.From().ID(), edge.To().ID(), edge.Data()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+func commit(t *testing.T, s *GraphStorage, fn func(tx *GraphTransaction) error) {
+	t.Helper()
+	tx, err := s.BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fn(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() failed: %v", err)
+	}
+}
+
+// buildGraph creates a, b, c with edges a->b, b->c, c->a.
+func buildGraph(t *testing.T, s *GraphStorage) (a, b, c int64) {
+	t.Helper()
+	commit(t, s, func(tx *GraphTransaction) error {
+		var err error
+		if a, err = tx.AddNode("a"); err != nil {
+			return err
+		}
+		if b, err = tx.AddNode("b"); err != nil {
+			return err
+		}
+		if c, err = tx.AddNode(nil); err != nil {
+			return err
+		}
+		return tx.AddEdge(a, b, float32(1.5), EdgeSpec{FromID: b, ToID: c, Data: int64(7)}, EdgeSpec{FromID: c, ToID: a})
+	})
+	return a, b, c
+}
+
+func TestTransactionCommit(t *testing.T) {
+	opts := graphPaths(t)
+	s := openStorageT(t, NewFileMap(), opts)
+	a, b, c := buildGraph(t, s)
+
+	want := "e1-2=1.5 e2-3=7 e3-1=<nil> n1=a n2=b n3=<nil>"
+	if got := describe(s.Graph()); got != want {
+		t.Fatalf("after first commit got %q, want %q", got, want)
+	}
+
+	commit(t, s, func(tx *GraphTransaction) error {
+		if err := tx.UpdateNode(a, "a2"); err != nil {
+			return err
+		}
+		if err := tx.UpdateEdge(b, c, "heavy"); err != nil {
+			return err
+		}
+		return tx.DeleteEdge(c, a)
+	})
+	want = "e1-2=1.5 e2-3=heavy n1=a2 n2=b n3=<nil>"
+	if got := describe(s.Graph()); got != want {
+		t.Fatalf("after update got %q, want %q", got, want)
+	}
+
+	// Deleting a node deletes its edges
+	commit(t, s, func(tx *GraphTransaction) error { return tx.DeleteNode(b) })
+	want = "n1=a2 n3=<nil>"
+	if got := describe(s.Graph()); got != want {
+		t.Fatalf("after delete got %q, want %q", got, want)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopened for writing and read-only
+	s = openStorageT(t, NewFileMap(), opts)
+	if got := describe(s.Graph()); got != want {
+		t.Errorf("reopened got %q, want %q", got, want)
+	}
+	if s.Graph().NumNodes() != 2 || s.Graph().NumEdges() != 0 {
+		t.Errorf("reopened counts %d nodes, %d edges", s.Graph().NumNodes(), s.Graph().NumEdges())
+	}
+	tx, _ := s.BeginTransaction()
+	if id, _ := tx.AddNode("d"); id != 4 {
+		t.Errorf("new node ID %d, want 4", id)
+	}
+	tx.Rollback()
+	s.Close()
+
+	unmar, err := NewUnmarshaller(NewFileMap(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored StoredGraph
+	if err := unmar.Unmarshal(nil, &stored); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	defer stored.Close()
+	if got := describe(&stored); got != want {
+		t.Errorf("unmarshalled got %q, want %q", got, want)
+	}
+}
+
+func TestOverlappingTransactions(t *testing.T) {
+	s := openStorageT(t, NewFileMap(), graphPaths(t))
+	defer s.Close()
+
+	tx1, err := s.BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx2, err := s.BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := tx1.AddNode("a")
+	b, _ := tx2.AddNode("b")
+	if a == b {
+		t.Fatalf("overlapping transactions both allocated node %d", a)
+	}
+	if err := tx2.Commit(); err != nil {
+		t.Fatalf("second transaction: %v", err)
+	}
+	if err := tx1.Commit(); err != nil {
+		t.Fatalf("first transaction: %v", err)
+	}
+	if want := "n1=a n2=b"; describe(s.Graph()) != want {
+		t.Errorf("got %q, want %q", describe(s.Graph()), want)
+	}
+
+	// IDs of rolled back nodes are not handed out again
+	tx, _ := s.BeginTransaction()
+	c, _ := tx.AddNode("c")
+	tx.Rollback()
+	tx, _ = s.BeginTransaction()
+	if d, _ := tx.AddNode("d"); d == c {
+		t.Errorf("node ID %d reused after rollback", d)
+	}
+	tx.Rollback()
+}
+
+func TestTransactionValidation(t *testing.T) {
+	s := openStorageT(t, NewFileMap(), graphPaths(t))
+	defer s.Close()
+	a, _, _ := buildGraph(t, s)
+	before := describe(s.Graph())
+
+	tx, _ := s.BeginTransaction()
+	if _, err := tx.AddNode("ok"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.AddEdge(a, 99, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("edge to a missing node should fail the commit")
+	}
+	if got := describe(s.Graph()); got != before {
+		t.Errorf("failed commit changed the graph: %q, want %q", got, before)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Error("second commit should fail")
+	}
+
+	tx, _ = s.BeginTransaction()
+	if _, err := tx.AddNode(struct{}{}); err == nil {
+		t.Error("unsupported data should be rejected")
+	}
+	tx.DeleteNode(a)
+	tx.Rollback()
+	if err := tx.DeleteNode(a); err == nil {
+		t.Error("rolled back transaction should reject changes")
+	}
+	if got := describe(s.Graph()); got != before {
+		t.Errorf("rollback changed the graph: %q, want %q", got, before)
+	}
+}
+
+// faultyStorage fails every Map while fail is set, simulating a crash
+// between two writes.
+type faultyStorage struct {
+	types.MappedStorage
+	fail *bool
+}
+
+func (s faultyStorage) Map(offset, length int64) (types.MappedRegion, error) {
+	if *s.fail {
+		return nil, errors.New("injected fault")
+	}
+	return s.MappedStorage.Map(offset, length)
+}
+
+func faultyFactory(suffix string, fail *bool) types.MappedStorageFactory {
+	files := NewFileMap()
+	return func(path string, readOnly bool) (types.MappedStorage, error) {
+		storage, err := files(path, readOnly)
+		if err != nil || !strings.HasSuffix(path, suffix) {
+			return storage, err
+		}
+		return faultyStorage{MappedStorage: storage, fail: fail}, nil
+	}
+}
+
+func TestTransactionRecovery(t *testing.T) {
+	opts := graphPaths(t)
+	var fail bool
+	s := openStorageT(t, faultyFactory("edges.graph", &fail), opts)
+	a, b, _ := buildGraph(t, s)
+	before := describe(s.Graph())
+
+	// Interrupted after the commit point: the edge file is never patched
+	tx, _ := s.BeginTransaction()
+	tx.DeleteNode(b)
+	tx.UpdateNode(a, "recovered")
+	fail = true
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected the injected fault")
+	}
+	s.Close()
+	fail = false
+
+	unmar, _ := NewUnmarshaller(NewFileMap(), opts...)
+	var stored StoredGraph
+	if err := unmar.Unmarshal(nil, &stored); err == nil || !strings.Contains(err.Error(), "interrupted commit") {
+		t.Errorf("read-only open should report the interrupted commit, got %v", err)
+	}
+
+	s = openStorageT(t, NewFileMap(), opts)
+	want := "e3-1=<nil> n1=recovered n3=<nil>"
+	if got := describe(s.Graph()); got != want {
+		t.Errorf("recovered got %q, want %q", got, want)
+	}
+	s.Close()
+	if err := unmar.Unmarshal(nil, &stored); err != nil {
+		t.Fatalf("Unmarshal() after recovery failed: %v", err)
+	}
+	stored.Close()
+
+	// Interrupted before the commit point: nothing changes
+	opts = graphPaths(t)
+	s = openStorageT(t, faultyFactory("data.graph", &fail), opts)
+	buildGraph(t, s)
+	tx, _ = s.BeginTransaction()
+	tx.AddNode("lost")
+	fail = true
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected the injected fault")
+	}
+	fail = false
+	s.Close()
+
+	s = openStorageT(t, NewFileMap(), opts)
+	defer s.Close()
+	if got := describe(s.Graph()); got != before {
+		t.Errorf("after failed append got %q, want %q", got, before)
+	}
+	commit(t, s, func(tx *GraphTransaction) error {
+		_, err := tx.AddNode("kept")
+		return err
+	})
+	if got := describe(s.Graph()); !strings.Contains(got, "n4=kept") {
+		t.Errorf("commit after failed append: %q", got)
+	}
+}
+
+func TestChecksumValidation(t *testing.T) {
+	for _, file := range []string{"nodes.graph", "edges.graph", "data.graph"} {
+		opts := graphPaths(t)
+		s := openStorageT(t, NewFileMap(), opts)
+		buildGraph(t, s)
+		s.Close()
+
+		_, cfg := applyOptions(types.Options{}, config{}, opts)
+		path := map[string]string{"nodes.graph": cfg.nodePath, "edges.graph": cfg.edgePath, "data.graph": cfg.dataPath}[file]
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw[len(raw)-3] ^= 0x40
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := OpenStorage(NewFileMap(), opts...); err == nil || !strings.Contains(err.Error(), "checksum") {
+			t.Errorf("%s: OpenStorage() should detect corruption, got %v", file, err)
+		}
+		unmar, _ := NewUnmarshaller(NewFileMap(), opts...)
+		var stored StoredGraph
+		if err := unmar.Unmarshal(nil, &stored); err == nil {
+			t.Errorf("%s: Unmarshal() should detect corruption", file)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	opts := graphPaths(t)
+	_, cfg := applyOptions(types.Options{}, config{}, opts)
+	paths := []string{cfg.nodePath, cfg.edgePath, cfg.dataPath}
+	sizes := func() (total int64) {
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += info.Size()
+		}
+		return total
+	}
+
+	s := openStorageT(t, NewFileMap(), opts)
+	_, b, c := buildGraph(t, s)
+	for i := 0; i < 10; i++ {
+		commit(t, s, func(tx *GraphTransaction) error { return tx.UpdateNode(c, strings.Repeat("x", 100+i)) })
+	}
+	commit(t, s, func(tx *GraphTransaction) error { return tx.DeleteNode(b) })
+	want := describe(s.Graph())
+	s.Close()
+
+	grown := sizes()
+	if err := Compact(opts...); err != nil {
+		t.Fatalf("Compact() failed: %v", err)
+	}
+	if compacted := sizes(); compacted >= grown {
+		t.Errorf("compacted size %d, want less than %d", compacted, grown)
+	}
+
+	s = openStorageT(t, NewFileMap(), opts)
+	if got := describe(s.Graph()); got != want {
+		t.Errorf("compacted got %q, want %q", got, want)
+	}
+	// Only a, c, c->a and the data of a and c survive
+	if len(s.graph.nodeRecords) != 2 || len(s.graph.edgeRecords) != 1 || s.dataHeader.EntryCount != 2 {
+		t.Errorf("compacted %d node records, %d edge records, %d entries",
+			len(s.graph.nodeRecords), len(s.graph.edgeRecords), s.dataHeader.EntryCount)
+	}
+
+	// Interrupt a compaction after renaming the node file
+	tmp := []types.Option{
+		WithPath(paths[0] + compactSuffix),
+		WithEdgesPath(paths[1] + compactSuffix),
+		WithLabelsPath(paths[2] + compactSuffix),
+	}
+	if err := s.CompactTo(NewFileMap(), tmp...); err != nil {
+		t.Fatalf("CompactTo() failed: %v", err)
+	}
+	s.Close()
+	if err := os.Rename(paths[0]+compactSuffix, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenStorage(NewFileMap(), opts...); err == nil || !strings.Contains(err.Error(), "Compact") {
+		t.Errorf("mixed generations should be reported, got %v", err)
+	}
+	if err := Compact(opts...); err != nil {
+		t.Fatalf("resumed Compact() failed: %v", err)
+	}
+	s = openStorageT(t, NewFileMap(), opts)
+	defer s.Close()
+	if got := describe(s.Graph()); got != want {
+		t.Errorf("after resumed compaction got %q, want %q", got, want)
+	}
+	if gen := s.dataHeader.Generation; gen != 3 {
+		t.Errorf("generation %d, want 3", gen)
+	}
+}
@@ -65,12 +65,7 @@ func (u *GraphUnmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option
 	}
 	storages = append(storages, dataStorage)
 
-	nodeHeader, err := readNodeFileHeader(nodeStorage)
-	if err != nil {
-		closeStorages(storages)
-		return types.NewError("unmarshal", "graph", err.Error(), err)
-	}
-	edgeHeader, err := readEdgeFileHeader(edgeStorage)
+	nodeHeader, edgeHeader, err := verifyGraphFiles(nodeStorage, edgeStorage, dataStorage)
 	if err != nil {
 		closeStorages(storages)
 		return types.NewError("unmarshal", "graph", err.Error(), err)
@@ -295,6 +295,12 @@ func writeDataFile(storage types.MappedStorage, nodeEntries, edgeEntries []seria
 		metadataOffset = uint64(entryOffset)
 	}
 
+	header.DataEnd = uint64(offset)
+	header.Checksum = CalculateChecksum(data[DataHeaderSize:offset])
+	if err := WriteDataHeader(data[:DataHeaderSize], header); err != nil {
+		return nil, nil, 0, err
+	}
+
 	if err := region.Sync(); err != nil {
 		return nil, nil, 0, err
 	}
@@ -391,3 +397,34 @@ func updateEdgeRecordDataOffset(storage types.MappedStorage, index int, dataOffs
 	}
 	return region.Sync()
 }
+
+// sealNodeFile stores the checksum of the node file header and records.
+func sealNodeFile(storage types.MappedStorage) error {
+	header, err := readNodeFileHeader(storage)
+	if err != nil {
+		return err
+	}
+	return sealRecordFile(storage, NodeHeaderSize+int64(header.NodeCount)*NodeRecordSize)
+}
+
+// sealEdgeFile stores the checksum of the edge file header and records.
+func sealEdgeFile(storage types.MappedStorage) error {
+	header, err := readEdgeFileHeader(storage)
+	if err != nil {
+		return err
+	}
+	return sealRecordFile(storage, EdgeHeaderSize+int64(header.EdgeCount)*EdgeRecordSize)
+}
+
+func sealRecordFile(storage types.MappedStorage, size int64) error {
+	region, err := storage.Map(0, size)
+	if err != nil {
+		return err
+	}
+	defer region.Unmap()
+
+	data := region.Bytes()
+	// Node and edge headers share the checksum position
+	binary.LittleEndian.PutUint64(data[36:44], recordFileChecksum(data[:NodeHeaderSize], data[NodeHeaderSize:]))
+	return region.Sync()
+}
@@ -0,0 +1,503 @@
+// Package document converts domain objects (tensors, matrices, vectors,
+// layers, models and graphs) to and from the self-describing documents the
+// MessagePack and CBOR backends write: maps with string keys, lists,
+// scalars and tensors, which the codecs store as typed binary arrays.
+//
+// A document of a value is
+//
+//	{"kind": "tensor",  "tensor": <tensor>}
+//	{"kind": "matrix",  "type": "mat.Matrix4x4", "matrix": <tensor>}
+//	{"kind": "vector",  "type": "vec.Vector3D",  "vector": <tensor>}
+//	{"kind": "layer",   "layer": {"name", "type", "can_learn", "input_shape", "config", "parameters"}}
+//	{"kind": "model",   "model": {"name", "type", "can_learn", "input_shape", "layers"}}
+//	{"kind": "slice",   "slice_type": "[]float32", "slice": <tensor or list>}
+//	{"kind": "graph" or "tree", "graph": {"nodes": [{"id", "data"}], "edges": [{"from", "to", "data"}], "root"}}
+//
+// wrapped in an envelope {"schema": "easyrobot", "version": N, "value": ...}.
+// Decoded integers are int64 (uint64 above math.MaxInt64) and floats keep
+// their encoded width.
+package document
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+	nntypes "github.com/itohio/EasyRobot/x/math/nn/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+// Document is a decoded map.
+type Document = versioning.Document
+
+// EnvelopeName is the schema field of every envelope.
+const EnvelopeName = "easyrobot"
+
+// Wrap puts a value document into a versioned envelope.
+func Wrap(value Document, version int) Document {
+	return Document{"schema": EnvelopeName, "version": int64(version), "value": value}
+}
+
+// Unwrap validates the envelope and migrates the value document of schema
+// to version current.
+func Unwrap(schema string, v any, current int, opts types.Options) (Document, error) {
+	env, ok := v.(Document)
+	if !ok || env["schema"] != EnvelopeName {
+		return nil, fmt.Errorf("not an %s envelope", EnvelopeName)
+	}
+	version, err := Int(env["version"])
+	if err != nil {
+		return nil, fmt.Errorf("envelope version: %w", err)
+	}
+	value, ok := env["value"].(Document)
+	if !ok {
+		return nil, fmt.Errorf("envelope without value")
+	}
+	if err := versioning.Check(schema, version, current, opts); err != nil {
+		return nil, err
+	}
+	if version >= current {
+		return value, nil
+	}
+	return versioning.Migrate(schema, value, version, current)
+}
+
+// Tensor builds a decoded tensor from little or big endian elements. When
+// neither a tensor factory nor a destination type is set and data is
+// suitably aligned, the tensor shares memory with data.
+func Tensor(e rawtensor.Elem, order binary.ByteOrder, shape types.Shape, data []byte, opts types.Options) (types.Tensor, error) {
+	n := shape.Size()
+	if len(data) != n*e.Size {
+		return nil, fmt.Errorf("%d bytes for %v elements of shape %v", len(data), e, shape)
+	}
+	if opts.TensorFactory == nil && opts.DestinationType == 0 && order == binary.LittleEndian {
+		if t, ok := rawtensor.View(data, e, shape); ok {
+			return t, nil
+		}
+	}
+	values, err := rawtensor.Decode(data, e, order, n)
+	if err != nil {
+		return nil, err
+	}
+	return rawtensor.Wrap(shape, values, opts)
+}
+
+// Encode returns the document of value.
+func Encode(value any) (Document, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("nil value")
+	case types.Model:
+		// Model first: a Model is also a Layer
+		spec, err := models.Describe(v)
+		if err != nil {
+			return nil, err
+		}
+		return Document{"kind": "model", "model": modelDoc(spec, reflect.TypeOf(v).String())}, nil
+	case types.Layer:
+		spec, err := layers.Describe(v)
+		if err != nil {
+			return nil, err
+		}
+		return Document{"kind": "layer", "layer": layerDoc(spec)}, nil
+	case types.Tensor:
+		return Document{"kind": "tensor", "tensor": tensorLeaf(v)}, nil
+	}
+	if doc, ok, err := encodeGraph(value); ok {
+		return doc, err
+	}
+	if doc, ok, err := encodeMath(value); ok {
+		return doc, err
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+	doc := Document{"kind": "slice", "slice_type": rv.Type().String()}
+	if t, ok := numericTensor(rv); ok {
+		doc["slice"] = t
+		return doc, nil
+	}
+	list, err := Generic(value)
+	if err != nil {
+		return nil, err
+	}
+	doc["slice"] = list
+	return doc, nil
+}
+
+// Decode rebuilds the value described by doc.
+func Decode(doc Document, opts types.Options) (any, error) {
+	kind, _ := doc["kind"].(string)
+	switch kind {
+	case "tensor":
+		t, err := tensorField(doc, "tensor")
+		if err != nil || t == nil {
+			return nil, fmt.Errorf("tensor: missing data")
+		}
+		return t, nil
+	case "matrix", "vector":
+		return decodeMath(doc, kind)
+	case "layer":
+		ld, ok := doc["layer"].(Document)
+		if !ok {
+			return nil, fmt.Errorf("layer: missing")
+		}
+		spec, err := layerSpec(ld)
+		if err != nil {
+			return nil, err
+		}
+		return layers.Restore(spec)
+	case "model":
+		md, ok := doc["model"].(Document)
+		if !ok {
+			return nil, fmt.Errorf("model: missing")
+		}
+		spec, err := modelSpec(md)
+		if err != nil {
+			return nil, err
+		}
+		return models.Restore(spec)
+	case "slice":
+		return decodeSlice(doc)
+	case "graph", "tree":
+		gd, ok := doc["graph"].(Document)
+		if !ok {
+			return nil, fmt.Errorf("%s: missing", kind)
+		}
+		return decodeGraph(kind, gd)
+	}
+	return nil, fmt.Errorf("unknown kind %q", kind)
+}
+
+// Assign stores value in the variable dst points to.
+func Assign(dst, value any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("dst must be a non-nil pointer, got %T", dst)
+	}
+	elem := rv.Elem()
+	val := reflect.ValueOf(value)
+	if !val.Type().AssignableTo(elem.Type()) {
+		return fmt.Errorf("cannot assign %s to %s", val.Type(), elem.Type())
+	}
+	elem.Set(val)
+	return nil
+}
+
+func tensorLeaf(t types.Tensor) any {
+	if tensor.IsNil(t) || t.Empty() {
+		return nil
+	}
+	return t
+}
+
+func tensorField(doc Document, key string) (types.Tensor, error) {
+	switch v := doc[key].(type) {
+	case nil:
+		return nil, nil
+	case types.Tensor:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s: expected a tensor, got %T", key, v)
+	}
+}
+
+func parametersDoc(params map[nntypes.ParamIndex]types.Parameter) Document {
+	doc := make(Document, len(params))
+	for idx, p := range params {
+		doc[strconv.Itoa(int(idx))] = Document{
+			"data":          tensorLeaf(p.Data),
+			"grad":          tensorLeaf(p.Grad),
+			"requires_grad": p.RequiresGrad,
+		}
+	}
+	return doc
+}
+
+func parametersSpec(v any) (map[nntypes.ParamIndex]types.Parameter, error) {
+	doc, _ := v.(Document)
+	params := make(map[nntypes.ParamIndex]types.Parameter, len(doc))
+	for key, pv := range doc {
+		idx, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("parameter index %q: %w", key, err)
+		}
+		pd, ok := pv.(Document)
+		if !ok {
+			return nil, fmt.Errorf("parameter %d: expected a map, got %T", idx, pv)
+		}
+		var p types.Parameter
+		if p.Data, err = tensorField(pd, "data"); err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", idx, err)
+		}
+		if p.Grad, err = tensorField(pd, "grad"); err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", idx, err)
+		}
+		p.RequiresGrad, _ = pd["requires_grad"].(bool)
+		params[nntypes.ParamIndex(idx)] = p
+	}
+	return params, nil
+}
+
+func layerDoc(spec layers.Spec) Document {
+	config := make(Document, len(spec.Config))
+	for k, v := range spec.Config {
+		config[k] = v
+	}
+	return Document{
+		"name":        spec.Name,
+		"type":        spec.Type,
+		"can_learn":   spec.CanLearn,
+		"input_shape": ints(spec.InputShape),
+		"config":      config,
+		"parameters":  parametersDoc(spec.Parameters),
+	}
+}
+
+func layerSpec(doc Document) (layers.Spec, error) {
+	spec := layers.Spec{Config: layers.Config{}}
+	spec.Name, _ = doc["name"].(string)
+	spec.Type, _ = doc["type"].(string)
+	spec.CanLearn, _ = doc["can_learn"].(bool)
+	shape, err := Ints(doc["input_shape"])
+	if err != nil {
+		return spec, fmt.Errorf("layer %q input shape: %w", spec.Name, err)
+	}
+	spec.InputShape = shape
+	config, _ := doc["config"].(Document)
+	for k, v := range config {
+		s, ok := v.(string)
+		if !ok {
+			return spec, fmt.Errorf("layer %q config %s: expected a string, got %T", spec.Name, k, v)
+		}
+		spec.Config[k] = s
+	}
+	if spec.Parameters, err = parametersSpec(doc["parameters"]); err != nil {
+		return spec, fmt.Errorf("layer %q: %w", spec.Name, err)
+	}
+	return spec, nil
+}
+
+func modelDoc(spec models.Spec, typeName string) Document {
+	list := make([]any, len(spec.Layers))
+	for i, ls := range spec.Layers {
+		list[i] = layerDoc(ls)
+	}
+	return Document{
+		"name":        spec.Name,
+		"type":        typeName,
+		"can_learn":   spec.CanLearn,
+		"input_shape": ints(spec.InputShape),
+		"layers":      list,
+	}
+}
+
+func modelSpec(doc Document) (models.Spec, error) {
+	var spec models.Spec
+	spec.Name, _ = doc["name"].(string)
+	spec.CanLearn, _ = doc["can_learn"].(bool)
+	shape, err := Ints(doc["input_shape"])
+	if err != nil {
+		return spec, fmt.Errorf("model input shape: %w", err)
+	}
+	spec.InputShape = shape
+	list, _ := doc["layers"].([]any)
+	for i, lv := range list {
+		ld, ok := lv.(Document)
+		if !ok {
+			return spec, fmt.Errorf("model layer %d: expected a map, got %T", i, lv)
+		}
+		ls, err := layerSpec(ld)
+		if err != nil {
+			return spec, err
+		}
+		spec.Layers = append(spec.Layers, ls)
+	}
+	return spec, nil
+}
+
+func ints(s []int) []any {
+	if s == nil {
+		return nil
+	}
+	out := make([]any, len(s))
+	for i, v := range s {
+		out[i] = int64(v)
+	}
+	return out
+}
+
+// Ints converts a decoded list of integers.
+func Ints(v any) ([]int, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %T", v)
+	}
+	out := make([]int, len(list))
+	for i, e := range list {
+		n, err := Int(e)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+// Int converts a decoded integer.
+func Int(v any) (int, error) {
+	switch n := v.(type) {
+	case int64:
+		if int64(int(n)) == n {
+			return int(n), nil
+		}
+	case uint64:
+		if n <= math.MaxInt {
+			return int(n), nil
+		}
+	default:
+		return 0, fmt.Errorf("expected an integer, got %T", v)
+	}
+	return 0, fmt.Errorf("integer %v out of range", v)
+}
+
+// Generic converts plain data (numbers, strings, bytes, tensors and slices
+// and string keyed maps of them) to its document form.
+func Generic(v any) (any, error) {
+	switch x := v.(type) {
+	case nil, bool, string, []byte, int64, uint64, float32, float64:
+		return x, nil
+	case types.Tensor:
+		return tensorLeaf(x), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), nil
+	case reflect.Float32:
+		return float32(rv.Float()), nil
+	case reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return Generic(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		list := make([]any, rv.Len())
+		for i := range list {
+			e, err := Generic(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list[i] = e
+		}
+		return list, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", rv.Type().Key())
+		}
+		doc := make(Document, rv.Len())
+		for it := rv.MapRange(); it.Next(); {
+			e, err := Generic(it.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			doc[it.Key().String()] = e
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
+
+// numericTensor wraps slices with a tensor element type into a 1-D tensor.
+func numericTensor(rv reflect.Value) (types.Tensor, bool) {
+	if rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	shape := types.Shape{rv.Len()}
+	switch data := rv.Interface().(type) {
+	case []float32:
+		return tensor.FromArray(shape, data), true
+	case []float64:
+		return tensor.FromArray(shape, data), true
+	case []int8:
+		return tensor.FromArray(shape, data), true
+	case []int16:
+		return tensor.FromArray(shape, data), true
+	case []int32:
+		return tensor.FromArray(shape, data), true
+	case []int64:
+		return tensor.FromArray(shape, data), true
+	case []int:
+		return tensor.FromArray(shape, data), true
+	case []uint8:
+		t := tensor.New(types.UINT8, shape)
+		copy(t.Data().([]uint8), data)
+		return t, true
+	}
+	return nil, false
+}
+
+func decodeSlice(doc Document) (any, error) {
+	sliceType, _ := doc["slice_type"].(string)
+	switch v := doc["slice"].(type) {
+	case nil:
+		return nil, fmt.Errorf("slice: missing data")
+	case types.Tensor:
+		data := v.Data()
+		if sliceType == "[]int" {
+			if d, ok := data.([]int64); ok {
+				out := make([]int, len(d))
+				for i, x := range d {
+					out[i] = int(x)
+				}
+				return out, nil
+			}
+		}
+		return data, nil
+	case []any:
+		switch sliceType {
+		case "[]string":
+			return typedList[string](v)
+		case "[]bool":
+			return typedList[bool](v)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("slice: unexpected %T", v)
+	}
+}
+
+func typedList[T any](list []any) ([]T, error) {
+	out := make([]T, len(list))
+	for i, e := range list {
+		x, ok := e.(T)
+		if !ok {
+			return nil, fmt.Errorf("slice element %d: expected %T, got %T", i, x, e)
+		}
+		out[i] = x
+	}
+	return out, nil
+}
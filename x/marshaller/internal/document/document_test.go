@@ -0,0 +1,135 @@
+package document
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/graph"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+func roundTrip(t *testing.T, value any) any {
+	t.Helper()
+	doc, err := Encode(value)
+	if err != nil {
+		t.Fatalf("Encode(%T) failed: %v", value, err)
+	}
+	got, err := Decode(doc, types.Options{})
+	if err != nil {
+		t.Fatalf("Decode(%T) failed: %v", value, err)
+	}
+	return got
+}
+
+func TestMathRoundTrip(t *testing.T) {
+	values := []any{
+		mat.Matrix{{1, 2, 3}, {4, 5, 6}},
+		mat.Matrix3x3{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}},
+		mat.Matrix4x4{{1, 0, 0, 1}, {0, 1, 0, 2}, {0, 0, 1, 3}, {0, 0, 0, 1}},
+		vec.Vector{1, 2, 3, 4},
+		vec.Vector3D{1, 2, 3},
+		vec.Quaternion{0, 0, 0, 1},
+	}
+	for _, v := range values {
+		if got := roundTrip(t, v); !reflect.DeepEqual(got, v) {
+			t.Errorf("round trip of %T = %v, want %v", v, got, v)
+		}
+	}
+
+	if _, err := Encode(mat.Matrix{{1, 2}, {3}}); err == nil {
+		t.Error("expected an error for a ragged matrix")
+	}
+}
+
+func TestSliceRoundTrip(t *testing.T) {
+	values := []any{
+		[]float32{1.5, -2},
+		[]float64{3.25},
+		[]int{1, -2, 3},
+		[]int32{7, 8},
+		[]uint8{1, 2, 255},
+		[]string{"a", "b"},
+		[]bool{true, false},
+	}
+	for _, v := range values {
+		if got := roundTrip(t, v); !reflect.DeepEqual(got, v) {
+			t.Errorf("round trip of %T = %v, want %v", v, got, v)
+		}
+	}
+}
+
+func TestGraphRoundTrip(t *testing.T) {
+	g := graph.NewGenericGraph[any, any]()
+	a, b := &node{id: 1, data: "a"}, &node{id: 2, data: Document{"x": float64(1)}}
+	for _, n := range []*node{a, b} {
+		if err := g.AddNode(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AddEdge(&edge{graphEdge: graphEdge{id: 5, from: 1, to: 2, data: float32(2.5)}, fromNode: a, toNode: b}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := roundTrip(t, g).(*graph.GenericGraph[any, any])
+	if !ok {
+		t.Fatalf("decoded %T, want *graph.GenericGraph[any, any]", got)
+	}
+	if got.NumNodes() != 2 || got.NumEdges() != 1 {
+		t.Fatalf("decoded %d nodes and %d edges, want 2 and 1", got.NumNodes(), got.NumEdges())
+	}
+	for e := range got.Edges() {
+		if e.ID() != 5 || e.From().ID() != 1 || e.To().ID() != 2 || e.Cost() != 2.5 {
+			t.Errorf("edge %d %d->%d cost %v, want 5 1->2 cost 2.5", e.ID(), e.From().ID(), e.To().ID(), e.Cost())
+		}
+		if d, _ := e.To().Data().(Document); d["x"] != float64(1) {
+			t.Errorf("node data = %v", e.To().Data())
+		}
+	}
+}
+
+func TestTreeRoundTrip(t *testing.T) {
+	tree := graph.NewGenericTree[any, any]("root")
+	left := tree.AddChild(tree.RootIdx(), "left")
+	tree.AddChild(tree.RootIdx(), "right")
+	leaf := tree.AddChild(left, int64(42))
+	tree.SetCost(left, leaf, float32(0.5))
+
+	got, ok := roundTrip(t, tree).(*graph.GenericTree[any, any])
+	if !ok {
+		t.Fatalf("decoded %T, want *graph.GenericTree[any, any]", got)
+	}
+	if got.NodeCount() != tree.NodeCount() {
+		t.Fatalf("NodeCount = %d, want %d", got.NodeCount(), tree.NodeCount())
+	}
+	want := map[int64]any{}
+	for n := range tree.Nodes() {
+		want[n.ID()] = n.Data()
+	}
+	for n := range got.Nodes() {
+		if n.Data() != want[n.ID()] {
+			t.Errorf("node %d data = %v, want %v", n.ID(), n.Data(), want[n.ID()])
+		}
+	}
+	if cost, ok := got.GetCost(left, leaf); !ok || cost != float32(0.5) {
+		t.Errorf("GetCost = %v, %v, want 0.5", cost, ok)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	doc := Document{"kind": "slice"}
+	if _, err := Unwrap("test", Wrap(doc, 1), 1, types.Options{}); err != nil {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	for _, v := range []any{
+		nil,
+		Document{"schema": "other", "version": int64(1), "value": doc},
+		Document{"schema": EnvelopeName, "version": "1", "value": doc},
+		Document{"schema": EnvelopeName, "version": int64(1)},
+	} {
+		if _, err := Unwrap("test", v, 1, types.Options{}); err == nil {
+			t.Errorf("Unwrap(%v) succeeded", v)
+		}
+	}
+}
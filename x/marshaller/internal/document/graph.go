@@ -0,0 +1,205 @@
+package document
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"sort"
+
+	"github.com/itohio/EasyRobot/x/math/graph"
+)
+
+// encodeGraph captures graphs and trees with any node and edge data. Node
+// and edge data must be plain data accepted by Generic.
+func encodeGraph(value any) (Document, bool, error) {
+	g, ok := value.(graph.Graph[any, any])
+	if !ok {
+		return nil, false, nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.MethodByName("Decide").IsValid() || rv.MethodByName("Compute").IsValid() {
+		return nil, true, fmt.Errorf("%T carries operations, use the graph marshaller", value)
+	}
+
+	kind := "graph"
+	gd := Document{}
+	if tree, ok := value.(*graph.GenericTree[any, any]); ok {
+		kind = "tree"
+		root := tree.Root()
+		if root == nil {
+			return nil, true, fmt.Errorf("tree without root")
+		}
+		gd["root"] = root.ID()
+	}
+
+	nodes := make([]any, 0, g.NumNodes())
+	for n := range g.Nodes() {
+		data, err := Generic(n.Data())
+		if err != nil {
+			return nil, true, fmt.Errorf("node %d: %w", n.ID(), err)
+		}
+		nodes = append(nodes, Document{"id": n.ID(), "data": data})
+	}
+	edges := make([]any, 0, g.NumEdges())
+	for e := range g.Edges() {
+		data, err := Generic(e.Data())
+		if err != nil {
+			return nil, true, fmt.Errorf("edge %d->%d: %w", e.From().ID(), e.To().ID(), err)
+		}
+		ed := Document{"from": e.From().ID(), "to": e.To().ID(), "data": data}
+		if kind == "graph" {
+			ed["id"] = e.ID()
+		}
+		edges = append(edges, ed)
+	}
+	gd["nodes"] = nodes
+	gd["edges"] = edges
+	return Document{"kind": kind, "graph": gd}, true, nil
+}
+
+type graphEdge struct {
+	id, from, to int64
+	data         any
+}
+
+func decodeGraph(kind string, gd Document) (any, error) {
+	nodeList, _ := gd["nodes"].([]any)
+	edgeList, _ := gd["edges"].([]any)
+
+	nodes := make(map[int64]*node, len(nodeList))
+	order := make([]*node, 0, len(nodeList))
+	for i, v := range nodeList {
+		nd, ok := v.(Document)
+		if !ok {
+			return nil, fmt.Errorf("%s node %d: expected a map, got %T", kind, i, v)
+		}
+		id, err := Int(nd["id"])
+		if err != nil {
+			return nil, fmt.Errorf("%s node %d id: %w", kind, i, err)
+		}
+		n := &node{id: int64(id), data: nd["data"]}
+		nodes[n.id] = n
+		order = append(order, n)
+	}
+	edges := make([]graphEdge, 0, len(edgeList))
+	for i, v := range edgeList {
+		ed, ok := v.(Document)
+		if !ok {
+			return nil, fmt.Errorf("%s edge %d: expected a map, got %T", kind, i, v)
+		}
+		from, err1 := Int(ed["from"])
+		to, err2 := Int(ed["to"])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("%s edge %d: invalid node ids", kind, i)
+		}
+		if nodes[int64(from)] == nil || nodes[int64(to)] == nil {
+			return nil, fmt.Errorf("%s edge %d: unknown node %d->%d", kind, i, from, to)
+		}
+		e := graphEdge{from: int64(from), to: int64(to), data: ed["data"]}
+		if id, ok := ed["id"]; ok {
+			n, err := Int(id)
+			if err != nil {
+				return nil, fmt.Errorf("%s edge %d id: %w", kind, i, err)
+			}
+			e.id = int64(n)
+		}
+		edges = append(edges, e)
+	}
+
+	if kind == "tree" {
+		return decodeTree(gd, nodes, edges)
+	}
+	g := graph.NewGenericGraph[any, any]()
+	for _, n := range order {
+		if err := g.AddNode(n); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range edges {
+		if err := g.AddEdge(&edge{graphEdge: e, fromNode: nodes[e.from], toNode: nodes[e.to]}); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// decodeTree adds the children in the order of their IDs, which is the order
+// they were inserted in, so that the rebuilt tree assigns the same IDs.
+func decodeTree(gd Document, nodes map[int64]*node, edges []graphEdge) (any, error) {
+	rootID, err := Int(gd["root"])
+	if err != nil {
+		return nil, fmt.Errorf("tree root: %w", err)
+	}
+	root := nodes[int64(rootID)]
+	if root == nil {
+		return nil, fmt.Errorf("tree root %d not found", rootID)
+	}
+	tree := graph.NewGenericTree[any, any](root.data)
+	index := map[int64]int{root.id: tree.RootIdx()}
+	sort.SliceStable(edges, func(i, j int) bool { return edges[i].to < edges[j].to })
+	for _, e := range edges {
+		parent, ok := index[e.from]
+		if !ok {
+			return nil, fmt.Errorf("tree edge %d->%d: parent not reachable from the root", e.from, e.to)
+		}
+		if _, dup := index[e.to]; dup {
+			return nil, fmt.Errorf("tree node %d has several parents", e.to)
+		}
+		child := tree.AddChild(parent, nodes[e.to].data)
+		index[e.to] = child
+		if e.data != nil {
+			tree.SetCost(parent, child, e.data)
+		}
+	}
+	return tree, nil
+}
+
+// node and edge carry decoded data into GenericGraph, which copies them.
+type node struct {
+	id   int64
+	data any
+}
+
+func (n *node) ID() int64 { return n.id }
+func (n *node) Data() any { return n.data }
+func (n *node) Equal(other graph.Node[any, any]) bool {
+	return other != nil && other.ID() == n.id
+}
+func (n *node) Compare(other graph.Node[any, any]) int {
+	switch {
+	case other == nil || n.id > other.ID():
+		return 1
+	case n.id < other.ID():
+		return -1
+	}
+	return 0
+}
+func (n *node) Neighbors() iter.Seq[graph.Node[any, any]] {
+	return func(func(graph.Node[any, any]) bool) {}
+}
+func (n *node) Edges() iter.Seq[graph.Edge[any, any]] {
+	return func(func(graph.Edge[any, any]) bool) {}
+}
+func (n *node) NumNeighbors() int                 { return 0 }
+func (n *node) Cost(graph.Node[any, any]) float32 { return 0 }
+
+type edge struct {
+	graphEdge
+	fromNode, toNode *node
+}
+
+func (e *edge) ID() int64                  { return e.id }
+func (e *edge) From() graph.Node[any, any] { return e.fromNode }
+func (e *edge) To() graph.Node[any, any]   { return e.toNode }
+func (e *edge) Data() any                  { return e.data }
+func (e *edge) Cost() float32 {
+	switch v := e.data.(type) {
+	case float32:
+		return v
+	case float64:
+		return float32(v)
+	case int64:
+		return float32(v)
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package document
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// mathTypes are the matrix and vector types stored as FP32 tensors, by
+// their type name.
+var mathTypes = typeMap(
+	mat.Matrix{}, mat.Matrix2x2{}, mat.Matrix3x3{}, mat.Matrix3x4{}, mat.Matrix4x3{}, mat.Matrix4x4{},
+	vec.Vector{}, vec.Vector2D{}, vec.Vector3D{}, vec.Vector4D{}, vec.Quaternion{},
+)
+
+func typeMap(values ...any) map[string]reflect.Type {
+	m := make(map[string]reflect.Type, len(values))
+	for _, v := range values {
+		t := reflect.TypeOf(v)
+		m[t.String()] = t
+	}
+	return m
+}
+
+func mathKind(t reflect.Type) string {
+	if strings.HasPrefix(t.String(), "mat.") {
+		return "matrix"
+	}
+	return "vector"
+}
+
+func encodeMath(value any) (Document, bool, error) {
+	rt := reflect.TypeOf(value)
+	if mathTypes[rt.String()] != rt {
+		return nil, false, nil
+	}
+	rv := reflect.ValueOf(value)
+	kind := mathKind(rt)
+
+	var shape types.Shape
+	var data []float32
+	if kind == "matrix" {
+		rows, cols := rv.Len(), 0
+		if rows > 0 {
+			cols = rv.Index(0).Len()
+		}
+		shape = types.Shape{rows, cols}
+		data = make([]float32, 0, rows*cols)
+		for i := 0; i < rows; i++ {
+			row := rv.Index(i)
+			if row.Len() != cols {
+				return nil, true, fmt.Errorf("%s: row %d has %d columns, expected %d", rt, i, row.Len(), cols)
+			}
+			for j := 0; j < cols; j++ {
+				data = append(data, float32(row.Index(j).Float()))
+			}
+		}
+	} else {
+		shape = types.Shape{rv.Len()}
+		data = make([]float32, rv.Len())
+		for i := range data {
+			data[i] = float32(rv.Index(i).Float())
+		}
+	}
+	return Document{"kind": kind, "type": rt.String(), kind: tensorLeaf(tensor.FromArray(shape, data))}, true, nil
+}
+
+func decodeMath(doc Document, kind string) (any, error) {
+	name, _ := doc["type"].(string)
+	rt, ok := mathTypes[name]
+	if !ok || mathKind(rt) != kind {
+		return nil, fmt.Errorf("%s: unknown type %q", kind, name)
+	}
+	t, err := tensorField(doc, kind)
+	if err != nil {
+		return nil, err
+	}
+	shape := types.Shape{0}
+	if kind == "matrix" {
+		shape = types.Shape{0, 0}
+	}
+	if t != nil {
+		shape = t.Shape()
+	}
+	if len(shape) != 1 && kind == "vector" || len(shape) != 2 && kind == "matrix" {
+		return nil, fmt.Errorf("%s: unexpected shape %v", name, shape)
+	}
+
+	out := reflect.New(rt).Elem()
+	outer := shape[0]
+	if rt.Kind() == reflect.Slice {
+		out.Set(reflect.MakeSlice(rt, outer, outer))
+	} else if rt.Len() != outer {
+		return nil, fmt.Errorf("%s: unexpected shape %v", name, shape)
+	}
+	for i := 0; i < outer; i++ {
+		if kind == "vector" {
+			out.Index(i).SetFloat(t.At(i))
+			continue
+		}
+		cols := shape[1]
+		row := out.Index(i)
+		if row.Kind() == reflect.Slice {
+			row.Set(reflect.MakeSlice(row.Type(), cols, cols))
+		} else if row.Len() != cols {
+			return nil, fmt.Errorf("%s: unexpected shape %v", name, shape)
+		}
+		for j := 0; j < cols; j++ {
+			row.Index(j).SetFloat(t.At(i, j))
+		}
+	}
+	return out.Interface(), nil
+}
@@ -0,0 +1,209 @@
+// Package modeltest holds the model round-trip checks shared by the
+// marshaller tests.
+package modeltest
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+// ModelRoundTrip marshals a small Sequential model and checks that it is
+// rebuilt without the architecture and computes the same outputs.
+func ModelRoundTrip(t *testing.T, m types.Marshaller, u types.Unmarshaller) {
+	t.Helper()
+	model, err := nn.NewSequentialModelBuilder(tensor.NewShape(2)).
+		AddLayer(mustDense(t, 2, 4)).
+		AddLayer(layers.NewTanh("tanh")).
+		AddLayer(mustDense(t, 4, 1)).
+		AddLayer(layers.NewSigmoid("sigmoid")).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build model: %v", err)
+	}
+	if err := model.Init(tensor.NewShape(2)); err != nil {
+		t.Fatalf("Failed to initialize model: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Marshal(&buf, model); err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	// No architecture is needed on the loading side
+	var restored types.Model
+	if err := u.Unmarshal(&buf, &restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if restored.LayerCount() != model.LayerCount() {
+		t.Fatalf("LayerCount = %d, want %d", restored.LayerCount(), model.LayerCount())
+	}
+	if restored.GetLayer(1).Name() != "tanh" {
+		t.Errorf("layer 1 name = %q, want tanh", restored.GetLayer(1).Name())
+	}
+
+	for _, x := range [][]float32{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		want, err := model.Forward(tensor.FromArray(tensor.NewShape(2), x))
+		if err != nil {
+			t.Fatalf("Forward failed: %v", err)
+		}
+		got, err := restored.Forward(tensor.FromArray(tensor.NewShape(2), x))
+		if err != nil {
+			t.Fatalf("Forward on restored model failed: %v", err)
+		}
+		if got.At(0) != want.At(0) {
+			t.Errorf("XOR%v = %v, want %v", x, got.At(0), want.At(0))
+		}
+	}
+}
+
+func mustDense(t *testing.T, in, out int) *layers.Dense {
+	t.Helper()
+	d, err := layers.NewDense(in, out)
+	if err != nil {
+		t.Fatalf("Failed to create dense: %v", err)
+	}
+	return d
+}
+
+// LayerRegistryRoundTrip round-trips a model of every registered layer type
+// and checks the rebuilt layer configs and forward outputs.
+func LayerRegistryRoundTrip(t *testing.T, m types.Marshaller, u types.Unmarshaller) {
+	t.Helper()
+	must := func(l types.Layer, err error) types.Layer {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Failed to create layer: %v", err)
+		}
+		return l
+	}
+
+	tests := []struct {
+		name   string
+		input  tensor.Shape
+		tokens int  // > 0 feeds token ids below this value instead of ramps
+		layout bool // Only compare the rebuilt layers; GPTEncoding and GPTAttention cannot run Forward yet
+		layers func() []types.Layer
+	}{
+		{"conv2d", tensor.NewShape(1, 2, 6, 6), 0, false, func() []types.Layer {
+			return []types.Layer{
+				must(layers.NewConv2D(2, 3, 3, 3, 1, 1, 1, 1)),
+				layers.NewBatchNorm2D(3, 1e-3, 0.2, "bn"),
+				layers.NewReLU("relu"),
+				must(layers.NewMaxPool2D(2, 2, 2, 2, 0, 0)),
+				must(layers.NewAvgPool2D(3, 3, 1, 1, 1, 1)),
+				layers.NewPad([]int{0, 0, 0, 0, 1, 1, 1, 1}, 0.5),
+				layers.NewGlobalAvgPool2D(),
+				layers.NewDropout("dropout", layers.WithDropoutRate(0.25)),
+				must(layers.NewDense(3, 4)),
+				layers.NewSoftmax("softmax", 1),
+			}
+		}},
+		{"conv1d", tensor.NewShape(1, 2, 8), 0, false, func() []types.Layer {
+			return []types.Layer{
+				must(layers.NewConv1D(2, 3, 3, 1, 1)),
+				layers.NewTanh("tanh"),
+				layers.NewFlatten(1, 3),
+				layers.NewReshape([]int{4, 6}),
+				layers.NewTranspose(),
+				layers.NewUnsqueeze(0),
+				layers.NewSqueezeDims(0),
+				layers.NewPadReflect([]int{1, 1, 1, 1}),
+				layers.NewSigmoid("sigmoid"),
+			}
+		}},
+		{"lstm", tensor.NewShape(1, 3), 0, false, func() []types.Layer {
+			return []types.Layer{
+				must(layers.NewLSTM(3, 4)),
+				must(layers.NewDense(4, 2)),
+			}
+		}},
+		{"embedding", tensor.NewShape(1, 4), 10, false, func() []types.Layer {
+			return []types.Layer{
+				must(layers.NewGPTEmbedding(10, 8)),
+			}
+		}},
+		{"gpt", tensor.NewShape(4, 8), 0, true, func() []types.Layer {
+			return []types.Layer{
+				must(layers.NewGPTEncoding(4, 8, true)),
+				must(layers.NewGPTAttention(8, 2, 4, 0)),
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := nn.NewSequentialModelBuilder(tt.input)
+			for _, layer := range tt.layers() {
+				builder = builder.AddLayer(layer)
+			}
+			model, err := builder.Build()
+			if err != nil {
+				t.Fatalf("Failed to build model: %v", err)
+			}
+			if err := model.Init(tt.input); err != nil {
+				t.Fatalf("Failed to initialize model: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := m.Marshal(&buf, model); err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			var restored types.Model
+			if err := u.Unmarshal(&buf, &restored); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if restored.LayerCount() != model.LayerCount() {
+				t.Fatalf("LayerCount = %d, want %d", restored.LayerCount(), model.LayerCount())
+			}
+
+			for i := 0; i < model.LayerCount(); i++ {
+				want, err := layers.Describe(model.GetLayer(i))
+				if err != nil {
+					t.Fatalf("Describe failed: %v", err)
+				}
+				got, err := layers.Describe(restored.GetLayer(i))
+				if err != nil {
+					t.Fatalf("Describe on restored layer %d failed: %v", i, err)
+				}
+				if got.Type != want.Type || !reflect.DeepEqual(got.Config, want.Config) {
+					t.Errorf("layer %d = %s%v, want %s%v", i, got.Type, got.Config, want.Type, want.Config)
+				}
+			}
+			if tt.layout {
+				return
+			}
+
+			input := tensor.New(tensor.DTFP32, tt.input)
+			for i := 0; i < input.Size(); i++ {
+				if tt.tokens > 0 {
+					input.SetAt(float64((i*3)%tt.tokens), i)
+				} else {
+					input.SetAt(float64(i%7)/3-1, i)
+				}
+			}
+			want, err := model.Forward(input)
+			if err != nil {
+				t.Fatalf("Forward failed: %v", err)
+			}
+
+			got, err := restored.Forward(input)
+			if err != nil {
+				t.Fatalf("Forward on restored model failed: %v", err)
+			}
+			if !got.Shape().Equal(want.Shape()) {
+				t.Fatalf("output shape = %v, want %v", got.Shape(), want.Shape())
+			}
+			for i := 0; i < want.Size(); i++ {
+				if got.At(i) != want.At(i) {
+					t.Errorf("output[%d] = %v, want %v", i, got.At(i), want.At(i))
+				}
+			}
+		})
+	}
+}
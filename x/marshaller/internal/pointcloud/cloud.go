@@ -0,0 +1,155 @@
+// Package pointcloud holds the point cloud representation and the
+// marshal/unmarshal plumbing shared by the PLY and PCD marshallers.
+//
+// A cloud is an N x C matrix of float32 with one of the column layouts
+//
+//	3: x y z
+//	4: x y z intensity
+//	6: x y z r g b
+//	7: x y z intensity r g b
+//
+// Colors are 0..255.
+package pointcloud
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	matTypes "github.com/itohio/EasyRobot/x/math/mat/types"
+)
+
+// Cloud is a row-major point cloud.
+type Cloud struct {
+	Points    int
+	Intensity bool
+	Color     bool
+	Data      []float32 // Points x Stride()
+}
+
+// New allocates a cloud of n points.
+func New(n int, intensity, color bool) Cloud {
+	c := Cloud{Points: n, Intensity: intensity, Color: color}
+	c.Data = make([]float32, n*c.Stride())
+	return c
+}
+
+// maxReserve caps the points Reserve allocates up front.
+const maxReserve = 1 << 16
+
+// Reserve returns an empty cloud for a decoder expecting n points. Point
+// counts in file headers are untrusted, so only up to maxReserve points are
+// allocated and the cloud grows as points are added.
+func Reserve(n int, intensity, color bool) Cloud {
+	c := Cloud{Intensity: intensity, Color: color}
+	c.Data = make([]float32, 0, min(n, maxReserve)*c.Stride())
+	return c
+}
+
+// Add appends a zero point and returns its row.
+func (c *Cloud) Add() []float32 {
+	s := c.Stride()
+	c.Data = append(c.Data, make([]float32, s)...)
+	c.Points++
+	return c.Data[len(c.Data)-s:]
+}
+
+// Stride returns the number of columns.
+func (c Cloud) Stride() int {
+	n := 3
+	if c.Intensity {
+		n++
+	}
+	if c.Color {
+		n += 3
+	}
+	return n
+}
+
+// ColorOffset returns the column of the red channel.
+func (c Cloud) ColorOffset() int {
+	return c.Stride() - 3
+}
+
+// Point returns the row of point i.
+func (c Cloud) Point(i int) []float32 {
+	s := c.Stride()
+	return c.Data[i*s : (i+1)*s]
+}
+
+func layout(cols int) (intensity, color bool, err error) {
+	switch cols {
+	case 3:
+		return false, false, nil
+	case 4:
+		return true, false, nil
+	case 6:
+		return false, true, nil
+	case 7:
+		return true, true, nil
+	}
+	return false, false, fmt.Errorf("pointcloud: %d columns, want 3, 4, 6 or 7", cols)
+}
+
+// FromValue converts an N x C tensor or matrix into a cloud.
+func FromValue(v any) (Cloud, error) {
+	switch t := v.(type) {
+	case types.Tensor:
+		if t == nil || t.Empty() {
+			return Cloud{}, fmt.Errorf("pointcloud: empty tensor")
+		}
+		shape := t.Shape()
+		if len(shape) != 2 {
+			return Cloud{}, fmt.Errorf("pointcloud: tensor shape %v, want [N C]", shape)
+		}
+		intensity, color, err := layout(shape[1])
+		if err != nil {
+			return Cloud{}, err
+		}
+		c := New(shape[0], intensity, color)
+		for i := 0; i < shape[0]; i++ {
+			row := c.Point(i)
+			for j := range row {
+				row[j] = float32(t.At(i, j))
+			}
+		}
+		return c, nil
+	case matTypes.Matrix:
+		if t == nil {
+			return Cloud{}, fmt.Errorf("pointcloud: nil matrix")
+		}
+		intensity, color, err := layout(t.Cols())
+		if err != nil {
+			return Cloud{}, err
+		}
+		c := New(t.Rows(), intensity, color)
+		m := t.View().(mat.Matrix)
+		for i := range m {
+			copy(c.Point(i), m[i])
+		}
+		return c, nil
+	}
+	return Cloud{}, fmt.Errorf("pointcloud: unsupported value %T", v)
+}
+
+// Tensor returns the cloud as an FP32 [N C] tensor, converted per opts.
+func (c Cloud) Tensor(opts types.Options) (types.Tensor, error) {
+	return rawtensor.Wrap(types.Shape{c.Points, c.Stride()}, c.Data, opts)
+}
+
+// Matrix returns the cloud as an N x C matrix sharing its data.
+func (c Cloud) Matrix() mat.Matrix {
+	return mat.New(c.Points, c.Stride(), c.Data...)
+}
+
+// Clamp8 rounds and clamps a color value to a byte.
+func Clamp8(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,307 @@
+package pointcloud
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	matTypes "github.com/itohio/EasyRobot/x/math/mat/types"
+)
+
+// Metadata keys of cloud frames, matching the gocv/goimage file loaders.
+const (
+	MetaPath     = "path"
+	MetaFilename = "filename"
+	MetaIndex    = "index"
+)
+
+// Codec reads and writes one file format. Files hold a single cloud, but
+// clouds can be concatenated into one stream since every header gives the
+// size of its data.
+type Codec struct {
+	Name string // format name, also the prefix of the option keys
+	Ext  string // file extension including the dot
+
+	// Decode reads the next cloud, or returns io.EOF at the end of r.
+	Decode func(r *bufio.Reader) (Cloud, error)
+	// Encode writes one cloud.
+	Encode func(w io.Writer, c Cloud, opts types.Options) error
+}
+
+// DirKey is the option key of the directory sequences are written to.
+func (c Codec) DirKey() string { return c.Name + ".dir" }
+
+// PathKey is the option key of the file, directory or glob sequences are read from.
+func (c Codec) PathKey() string { return c.Name + ".path" }
+
+// Marshal writes a tensor or matrix, a types.Frame (its first tensor), a
+// []types.Frame or a types.FrameStream. Frame sequences are concatenated into
+// w, or written as one file per frame into the DirKey directory.
+func (c Codec) Marshal(w io.Writer, value any, opts types.Options) error {
+	switch v := value.(type) {
+	case nil:
+		return types.NewError("marshal", c.Name, "nil value", nil)
+	case types.FrameStream:
+		return c.marshalStream(w, v, opts)
+	case *types.FrameStream:
+		return c.marshalStream(w, *v, opts)
+	case []types.Frame:
+		for _, frame := range v {
+			if err := c.marshalFrame(w, frame, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	case types.Frame:
+		return c.marshalFrame(w, v, opts)
+	}
+
+	cloud, err := FromValue(value)
+	if err != nil {
+		return types.NewError("marshal", c.Name, "invalid cloud", err)
+	}
+	if err := c.Encode(w, cloud, opts); err != nil {
+		return types.NewError("marshal", c.Name, "encoding", err)
+	}
+	return nil
+}
+
+func (c Codec) marshalStream(w io.Writer, stream types.FrameStream, opts types.Options) error {
+	defer stream.Close()
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return types.NewError("marshal", c.Name, "cancelled", ctx.Err())
+		case frame, ok := <-stream.C:
+			if !ok {
+				return nil
+			}
+			if err := c.marshalFrame(w, frame, opts); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c Codec) marshalFrame(w io.Writer, frame types.Frame, opts types.Options) error {
+	if err, ok := frame.Metadata["error"].(error); ok {
+		return types.NewError("marshal", c.Name, "frame error", err)
+	}
+	if len(frame.Tensors) == 0 {
+		return types.NewError("marshal", c.Name, fmt.Sprintf("frame %d has no tensors", frame.Index), nil)
+	}
+	cloud, err := FromValue(frame.Tensors[0])
+	if err != nil {
+		return types.NewError("marshal", c.Name, fmt.Sprintf("frame %d", frame.Index), err)
+	}
+
+	dir := opts.Metadata[c.DirKey()]
+	if dir == "" {
+		if err := c.Encode(w, cloud, opts); err != nil {
+			return types.NewError("marshal", c.Name, "encoding", err)
+		}
+		return nil
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("cloud_%06d%s", frame.Index, c.Ext))
+	f, err := os.Create(path)
+	if err != nil {
+		return types.NewError("marshal", c.Name, "creating file", err)
+	}
+	bw := bufio.NewWriter(f)
+	err = c.Encode(bw, cloud, opts)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return types.NewError("marshal", c.Name, path, err)
+	}
+	return nil
+}
+
+// Unmarshal decodes into *types.Tensor, *mat.Matrix or *matTypes.Matrix (the
+// first cloud), *[]types.Frame or *types.FrameStream (all clouds). Sequences
+// come from the PathKey file, directory or glob when set, otherwise from r.
+func (c Codec) Unmarshal(r io.Reader, dst any, opts types.Options) error {
+	switch d := dst.(type) {
+	case *types.FrameStream:
+		open, err := c.sources(r, opts)
+		if err != nil {
+			return err
+		}
+		*d = c.stream(open, opts)
+		return nil
+	case *[]types.Frame:
+		open, err := c.sources(r, opts)
+		if err != nil {
+			return err
+		}
+		stream := c.stream(open, opts)
+		defer stream.Close()
+		frames := []types.Frame{}
+		for frame := range stream.C {
+			if err, ok := frame.Metadata["error"].(error); ok {
+				return types.NewError("unmarshal", c.Name, "decoding", err)
+			}
+			frames = append(frames, frame)
+		}
+		*d = frames
+		return nil
+	}
+
+	if r == nil {
+		return types.NewError("unmarshal", c.Name, "nil reader", nil)
+	}
+	cloud, err := c.Decode(bufio.NewReader(r))
+	if err != nil {
+		return types.NewError("unmarshal", c.Name, "decoding", err)
+	}
+	switch d := dst.(type) {
+	case *types.Tensor:
+		t, err := cloud.Tensor(opts)
+		if err != nil {
+			return types.NewError("unmarshal", c.Name, "tensor", err)
+		}
+		*d = t
+	case *mat.Matrix:
+		*d = cloud.Matrix()
+	case *matTypes.Matrix:
+		*d = cloud.Matrix()
+	default:
+		return types.NewError("unmarshal", c.Name, fmt.Sprintf("unsupported destination %T", dst), nil)
+	}
+	return nil
+}
+
+// source is one input of a sequence.
+type source struct {
+	path string
+	open func() (io.ReadCloser, error)
+}
+
+func (c Codec) sources(r io.Reader, opts types.Options) ([]source, error) {
+	pattern := opts.Metadata[c.PathKey()]
+	if pattern == "" {
+		if r == nil {
+			return nil, types.NewError("unmarshal", c.Name, "no reader and no path", nil)
+		}
+		return []source{{open: func() (io.ReadCloser, error) { return io.NopCloser(r), nil }}}, nil
+	}
+
+	files, err := c.list(pattern)
+	if err != nil {
+		return nil, types.NewError("unmarshal", c.Name, "listing files", err)
+	}
+	out := make([]source, len(files))
+	for i, path := range files {
+		out[i] = source{path: path, open: func() (io.ReadCloser, error) { return os.Open(path) }}
+	}
+	return out, nil
+}
+
+// list resolves a file, a directory (all files with the codec's extension) or
+// a glob into a sorted file list.
+func (c Codec) list(pattern string) ([]string, error) {
+	if info, err := os.Stat(pattern); err == nil {
+		if !info.IsDir() {
+			return []string{pattern}, nil
+		}
+		pattern = filepath.Join(pattern, "*")
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	files := matches[:0]
+	for _, m := range matches {
+		if strings.EqualFold(filepath.Ext(m), c.Ext) {
+			files = append(files, m)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no %s files in %s", c.Ext, pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// stream decodes the clouds of all sources in order, one frame per cloud.
+func (c Codec) stream(sources []source, opts types.Options) types.FrameStream {
+	base := opts.Context
+	if base == nil {
+		base = context.Background()
+	}
+	ctx, cancel := context.WithCancel(base)
+	out := make(chan types.Frame)
+
+	send := func(frame types.Frame) bool {
+		select {
+		case out <- frame:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	fail := func(index int, err error) {
+		send(types.Frame{Index: index, Timestamp: time.Now().UnixNano(), Metadata: map[string]any{"error": err}})
+	}
+
+	go func() {
+		defer close(out)
+		index := 0
+		for _, src := range sources {
+			rc, err := src.open()
+			if err != nil {
+				fail(index, err)
+				return
+			}
+			br := bufio.NewReader(rc)
+			for {
+				cloud, err := c.Decode(br)
+				if err == io.EOF {
+					break
+				}
+				var t types.Tensor
+				if err == nil {
+					t, err = cloud.Tensor(opts)
+				}
+				if err != nil {
+					rc.Close()
+					if src.path != "" {
+						err = fmt.Errorf("%s: %w", src.path, err)
+					}
+					fail(index, err)
+					return
+				}
+				meta := map[string]any{MetaIndex: index}
+				if src.path != "" {
+					meta[MetaPath] = src.path
+					meta[MetaFilename] = filepath.Base(src.path)
+				}
+				if !send(types.Frame{Index: index, Timestamp: time.Now().UnixNano(), Metadata: meta, Tensors: []types.Tensor{t}}) {
+					rc.Close()
+					return
+				}
+				index++
+			}
+			rc.Close()
+		}
+	}()
+
+	return types.NewFrameStream(out, cancel)
+}
@@ -0,0 +1,333 @@
+// Package rawtensor converts between raw little/big endian element buffers,
+// as found in NumPy and safetensors files, and tensors.
+package rawtensor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	tensortypes "github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Kind is the element class of a raw buffer.
+type Kind byte
+
+const (
+	Float    Kind = 'f'
+	Int      Kind = 'i'
+	Uint     Kind = 'u'
+	Bool     Kind = 'b'
+	BFloat16 Kind = 'B' // bfloat16, safetensors only
+)
+
+// Elem describes the raw element type: its kind and size in bytes.
+type Elem struct {
+	Kind Kind
+	Size int
+}
+
+func (e Elem) String() string {
+	if e.Kind == BFloat16 {
+		return "bf16"
+	}
+	return fmt.Sprintf("%c%d", e.Kind, e.Size)
+}
+
+// DataType returns the tensor data type the raw elements load as. Types
+// without a native tensor representation are widened: f2/bf16 load as FP32,
+// u2 as INT32, u4/u8 as INT64 and bool as UINT8.
+func (e Elem) DataType() (types.DataType, error) {
+	switch e {
+	case Elem{Float, 2}, Elem{BFloat16, 2}, Elem{Float, 4}:
+		return types.FP32, nil
+	case Elem{Float, 8}:
+		return types.FP64, nil
+	case Elem{Int, 1}:
+		return types.INT8, nil
+	case Elem{Int, 2}:
+		return types.INT16, nil
+	case Elem{Int, 4}, Elem{Uint, 2}:
+		return types.INT32, nil
+	case Elem{Int, 8}, Elem{Uint, 4}, Elem{Uint, 8}:
+		return types.INT64, nil
+	case Elem{Uint, 1}, Elem{Bool, 1}:
+		return types.UINT8, nil
+	}
+	return tensortypes.DT_UNKNOWN, fmt.Errorf("rawtensor: unsupported element type %v", e)
+}
+
+// ElemOf returns the raw element type tensors of the data type are stored as.
+func ElemOf(dt types.DataType) (Elem, error) {
+	switch dt {
+	case types.FP32:
+		return Elem{Float, 4}, nil
+	case types.FP64:
+		return Elem{Float, 8}, nil
+	case types.INT8, types.INT48:
+		return Elem{Int, 1}, nil
+	case types.INT16:
+		return Elem{Int, 2}, nil
+	case types.INT32:
+		return Elem{Int, 4}, nil
+	case types.INT64, types.INT:
+		return Elem{Int, 8}, nil
+	case types.UINT8:
+		return Elem{Uint, 1}, nil
+	}
+	return Elem{}, fmt.Errorf("rawtensor: unsupported data type %v", dt)
+}
+
+// Decode converts n raw elements into a typed Go slice of the elements' DataType.
+func Decode(b []byte, e Elem, order binary.ByteOrder, n int) (any, error) {
+	if n < 0 || len(b) < n*e.Size {
+		return nil, fmt.Errorf("rawtensor: %d bytes for %d elements of %v", len(b), n, e)
+	}
+	switch e {
+	case Elem{Float, 2}:
+		return decode(n, func(i int) float32 { return HalfToFloat32(order.Uint16(b[2*i:])) }), nil
+	case Elem{BFloat16, 2}:
+		return decode(n, func(i int) float32 { return math.Float32frombits(uint32(order.Uint16(b[2*i:])) << 16) }), nil
+	case Elem{Float, 4}:
+		return decode(n, func(i int) float32 { return math.Float32frombits(order.Uint32(b[4*i:])) }), nil
+	case Elem{Float, 8}:
+		return decode(n, func(i int) float64 { return math.Float64frombits(order.Uint64(b[8*i:])) }), nil
+	case Elem{Int, 1}:
+		return decode(n, func(i int) int8 { return int8(b[i]) }), nil
+	case Elem{Int, 2}:
+		return decode(n, func(i int) int16 { return int16(order.Uint16(b[2*i:])) }), nil
+	case Elem{Int, 4}:
+		return decode(n, func(i int) int32 { return int32(order.Uint32(b[4*i:])) }), nil
+	case Elem{Uint, 2}:
+		return decode(n, func(i int) int32 { return int32(order.Uint16(b[2*i:])) }), nil
+	case Elem{Int, 8}, Elem{Uint, 8}:
+		return decode(n, func(i int) int64 { return int64(order.Uint64(b[8*i:])) }), nil
+	case Elem{Uint, 4}:
+		return decode(n, func(i int) int64 { return int64(order.Uint32(b[4*i:])) }), nil
+	case Elem{Uint, 1}:
+		return append([]uint8(nil), b[:n]...), nil
+	case Elem{Bool, 1}:
+		return decode(n, func(i int) uint8 {
+			if b[i] != 0 {
+				return 1
+			}
+			return 0
+		}), nil
+	}
+	return nil, fmt.Errorf("rawtensor: unsupported element type %v", e)
+}
+
+func decode[T any](n int, at func(i int) T) []T {
+	out := make([]T, n)
+	for i := range out {
+		out[i] = at(i)
+	}
+	return out
+}
+
+// Encode writes the elements of t in row-major order using little endian byte order.
+func Encode(t types.Tensor) (Elem, []byte, error) {
+	e, err := ElemOf(t.DataType())
+	if err != nil {
+		return e, nil, err
+	}
+	n := t.Size()
+	out := make([]byte, n*e.Size)
+	le := binary.LittleEndian
+	switch data := contiguous(t).Data().(type) {
+	case []float32:
+		for i, v := range data[:n] {
+			le.PutUint32(out[4*i:], math.Float32bits(v))
+		}
+	case []float64:
+		for i, v := range data[:n] {
+			le.PutUint64(out[8*i:], math.Float64bits(v))
+		}
+	case []int8:
+		for i, v := range data[:n] {
+			out[i] = byte(v)
+		}
+	case []uint8:
+		copy(out, data[:n])
+	case []int16:
+		for i, v := range data[:n] {
+			le.PutUint16(out[2*i:], uint16(v))
+		}
+	case []int32:
+		for i, v := range data[:n] {
+			le.PutUint32(out[4*i:], uint32(v))
+		}
+	case []int64:
+		for i, v := range data[:n] {
+			le.PutUint64(out[8*i:], uint64(v))
+		}
+	case []int:
+		for i, v := range data[:n] {
+			le.PutUint64(out[8*i:], uint64(v))
+		}
+	default:
+		return e, nil, fmt.Errorf("rawtensor: unsupported tensor data %T", data)
+	}
+	return e, out, nil
+}
+
+// Bytes returns the same little endian elements as Encode. On little endian
+// hosts the bytes of a contiguous tensor are a view of its storage rather
+// than a copy, so they must not be modified and are only valid as long as
+// the tensor is.
+func Bytes(t types.Tensor) (Elem, []byte, error) {
+	e, err := ElemOf(t.DataType())
+	if err != nil || !littleEndianHost || !t.IsContiguous() || t.Offset() != 0 {
+		return Encode(t)
+	}
+	n := t.Size()
+	var b []byte
+	switch data := t.Data().(type) {
+	case []float32:
+		b = viewBytes(data, n)
+	case []float64:
+		b = viewBytes(data, n)
+	case []int8:
+		b = viewBytes(data, n)
+	case []uint8:
+		b = data[:n]
+	case []int16:
+		b = viewBytes(data, n)
+	case []int32:
+		b = viewBytes(data, n)
+	case []int64:
+		b = viewBytes(data, n)
+	case []int:
+		if unsafe.Sizeof(int(0)) != 8 {
+			return Encode(t)
+		}
+		b = viewBytes(data, n)
+	default:
+		return Encode(t)
+	}
+	if len(b) != n*e.Size {
+		return Encode(t)
+	}
+	return e, b, nil
+}
+
+func viewBytes[T any](data []T, n int) []byte {
+	if n == 0 || len(data) < n {
+		return nil
+	}
+	var zero T
+	return unsafe.Slice((*byte)(unsafe.Pointer(&data[0])), n*int(unsafe.Sizeof(zero)))
+}
+
+// contiguous returns t or a row-major copy of it when t is a strided view.
+func contiguous(t types.Tensor) types.Tensor {
+	if t.IsContiguous() && t.Offset() == 0 {
+		return t
+	}
+	return t.Clone()
+}
+
+// Wrap builds a tensor of the given shape around a slice returned by Decode.
+// Without a tensor factory or destination type the slice becomes the tensor
+// storage as-is; otherwise the elements are copied into a tensor created by
+// the factory with the destination type.
+func Wrap(shape types.Shape, data any, opts types.Options) (types.Tensor, error) {
+	if n := reflect.ValueOf(data).Len(); n < shape.Size() {
+		return nil, fmt.Errorf("rawtensor: %d elements for shape %v", n, shape)
+	}
+
+	var t types.Tensor
+	switch d := data.(type) {
+	case []float32:
+		t = tensor.FromArray(shape, d)
+	case []float64:
+		t = tensor.FromArray(shape, d)
+	case []int8:
+		t = tensor.FromArray(shape, d)
+	case []int16:
+		t = tensor.FromArray(shape, d)
+	case []int32:
+		t = tensor.FromArray(shape, d)
+	case []int64:
+		t = tensor.FromArray(shape, d)
+	case []uint8:
+		u := tensor.New(types.UINT8, shape)
+		copy(u.Data().([]uint8), d)
+		t = u
+	default:
+		return nil, fmt.Errorf("rawtensor: unsupported data %T", data)
+	}
+
+	if opts.TensorFactory == nil && opts.DestinationType == 0 {
+		return t, nil
+	}
+	dtype := t.DataType()
+	if opts.DestinationType != 0 {
+		dtype = opts.DestinationType
+	}
+	factory := opts.TensorFactory
+	if factory == nil {
+		factory = func(dt types.DataType, sh types.Shape) types.Tensor { return tensor.New(dt, sh) }
+	}
+	return factory(dtype, shape).Copy(t), nil
+}
+
+// View returns a tensor that shares memory with b when the element type has a
+// native tensor representation, the host is little endian and b is suitably
+// aligned. ok is false when the data has to be decoded instead.
+func View(b []byte, e Elem, shape types.Shape) (t types.Tensor, ok bool) {
+	n := shape.Size()
+	if len(b) < n*e.Size || !littleEndianHost || n == 0 {
+		return nil, false
+	}
+	p := unsafe.Pointer(&b[0])
+	if uintptr(p)%uintptr(e.Size) != 0 {
+		return nil, false
+	}
+	switch e {
+	case Elem{Float, 4}:
+		return tensor.FromArray(shape, unsafe.Slice((*float32)(p), n)), true
+	case Elem{Float, 8}:
+		return tensor.FromArray(shape, unsafe.Slice((*float64)(p), n)), true
+	case Elem{Int, 1}:
+		return tensor.FromArray(shape, unsafe.Slice((*int8)(p), n)), true
+	case Elem{Int, 2}:
+		return tensor.FromArray(shape, unsafe.Slice((*int16)(p), n)), true
+	case Elem{Int, 4}:
+		return tensor.FromArray(shape, unsafe.Slice((*int32)(p), n)), true
+	case Elem{Int, 8}:
+		return tensor.FromArray(shape, unsafe.Slice((*int64)(p), n)), true
+	}
+	return nil, false
+}
+
+var littleEndianHost = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// HalfToFloat32 converts an IEEE 754 half precision value to float32.
+func HalfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch {
+	case exp == 0 && frac == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		// subnormal: normalize the fraction
+		e := uint32(127 - 15 + 1)
+		for frac&0x400 == 0 {
+			frac <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (frac&0x3ff)<<13)
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | frac<<13)
+}
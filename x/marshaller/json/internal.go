@@ -6,6 +6,8 @@ import (
 
 	"github.com/itohio/EasyRobot/x/math/graph"
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/tensor"
 )
 
 // Internal structs for JSON encoding/decoding.
@@ -32,6 +34,7 @@ type jsonLayer struct {
 	CanLearn   bool                     `json:"can_learn"`
 	InputShape []int                    `json:"input_shape,omitempty"`
 	Parameters map[string]jsonParameter `json:"parameters,omitempty"`
+	Config     map[string]string        `json:"config,omitempty"` // Constructor arguments of registered layers
 }
 
 // jsonModel represents a model for JSON encoding/decoding.
@@ -40,6 +43,7 @@ type jsonModel struct {
 	Type       string                   `json:"type"`
 	CanLearn   bool                     `json:"can_learn"`
 	LayerCount int                      `json:"layer_count"`
+	InputShape []int                    `json:"input_shape,omitempty"`
 	Layers     []jsonLayer              `json:"layers,omitempty"`
 	Parameters map[string]jsonParameter `json:"parameters,omitempty"`
 }
@@ -165,10 +169,10 @@ func parameterToJSON(p types.Parameter) jsonParameter {
 		RequiresGrad: p.RequiresGrad,
 	}
 
-	if !p.Data.Empty() {
+	if !tensor.IsNil(p.Data) {
 		result.Data = tensorToJSON(p.Data)
 	}
-	if !p.Grad.Empty() {
+	if !tensor.IsNil(p.Grad) {
 		result.Grad = tensorToJSON(p.Grad)
 	}
 
@@ -178,13 +182,20 @@ func parameterToJSON(p types.Parameter) jsonParameter {
 func layerToJSON(layer types.Layer) jsonLayer {
 	result := jsonLayer{
 		Name:       layer.Name(),
+		Type:       reflect.TypeOf(layer).String(),
 		CanLearn:   layer.CanLearn(),
 		Parameters: make(map[string]jsonParameter),
 	}
 
+	// Registered layers carry their constructor arguments
+	if name, ok := layers.TypeName(layer); ok {
+		result.Type = name
+		result.Config = layer.(layers.Configurable).Config()
+	}
+
 	// Get input shape if available
 	input := layer.Input()
-	if !input.Empty() {
+	if !tensor.IsNil(input) {
 		result.InputShape = input.Shape()
 	}
 
@@ -206,6 +217,10 @@ func modelToJSON(model types.Model) jsonModel {
 		Parameters: make(map[string]jsonParameter),
 	}
 
+	if m, ok := model.(interface{ InputShape() tensor.Shape }); ok {
+		result.InputShape = m.InputShape()
+	}
+
 	// Convert layers
 	for i := 0; i < model.LayerCount(); i++ {
 		layer := model.GetLayer(i)
@@ -6,6 +6,7 @@ import (
 	"reflect"
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
 	"github.com/itohio/EasyRobot/x/math/graph"
 )
 
@@ -42,6 +43,11 @@ func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error
 		return types.NewError("marshal", "json", "nil value", nil)
 	}
 
+	version, err := versioning.Target(schemaName, localOpts, SchemaVersion)
+	if err != nil {
+		return types.NewError("marshal", "json", "version", err)
+	}
+
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ") // Pretty print
 
@@ -50,9 +56,13 @@ func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error
 	if err != nil {
 		return types.NewError("marshal", "json", "value conversion", err)
 	}
+	payload, err := wrap(jv, version)
+	if err != nil {
+		return types.NewError("marshal", "json", "encoding", err)
+	}
 
 	// Encode jsonValue
-	if err := encoder.Encode(jv); err != nil {
+	if err := encoder.Encode(payload); err != nil {
 		return types.NewError("marshal", "json", "encoding", err)
 	}
 
@@ -77,7 +87,6 @@ func (m *Marshaller) valueToJSON(value any) (*jsonValue, error) {
 	// Then check for Layer
 	if layer, ok := value.(types.Layer); ok {
 		jl := layerToJSON(layer)
-		jl.Type = reflect.TypeOf(value).String()
 		return &jsonValue{
 			Kind:  "layer",
 			Layer: &jl,
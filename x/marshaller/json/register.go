@@ -0,0 +1,25 @@
+package json
+
+import (
+	"bytes"
+
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "json",
+		Extensions: []string{".json"},
+		Sniff: func(head []byte) bool {
+			head = bytes.TrimLeft(head, " \t\r\n")
+			return len(head) > 0 && (head[0] == '{' || head[0] == '[')
+		},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
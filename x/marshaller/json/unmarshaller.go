@@ -9,6 +9,9 @@ import (
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
 	"github.com/itohio/EasyRobot/x/math/graph"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+	nntypes "github.com/itohio/EasyRobot/x/math/nn/types"
 	"github.com/itohio/EasyRobot/x/math/tensor"
 )
 
@@ -44,13 +47,17 @@ func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) err
 	decoder := json.NewDecoder(r)
 
 	// Decode jsonValue
-	var jv jsonValue
-	if err := decoder.Decode(&jv); err != nil {
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return types.NewError("unmarshal", "json", "decoding", err)
+	}
+	jv, err := unwrap(raw, localOpts)
+	if err != nil {
 		return types.NewError("unmarshal", "json", "decoding", err)
 	}
 
 	// Convert jsonValue to actual value
-	value, err := u.jsonToValue(&jv, localOpts)
+	value, err := u.jsonToValue(jv, localOpts)
 	if err != nil {
 		return types.NewError("unmarshal", "json", "conversion", err)
 	}
@@ -87,15 +94,17 @@ func (u *Unmarshaller) jsonToValue(jv *jsonValue, opts types.Options) (any, erro
 		if jv.Layer == nil {
 			return nil, fmt.Errorf("nil layer in jsonValue")
 		}
-		// For layers, we cannot fully reconstruct them without type information
-		return *jv.Layer, nil
+		spec, err := jsonToLayerSpec(jv.Layer, tensorFactory)
+		if err != nil {
+			return nil, err
+		}
+		return layers.Restore(spec)
 
 	case "model":
 		if jv.Model == nil {
 			return nil, fmt.Errorf("nil model in jsonValue")
 		}
-		// For models, similar to layers, we cannot fully reconstruct them
-		return *jv.Model, nil
+		return jsonToModel(jv.Model, tensorFactory)
 
 	case "graph", "tree", "decision_tree", "expression_graph", "generic":
 		if jv.Graph == nil {
@@ -156,6 +165,68 @@ func jsonToTensor(jt *jsonTensor, destType types.DataType, tensorFactory func(ty
 	return t, nil
 }
 
+func jsonToParameter(jp jsonParameter, tensorFactory func(types.DataType, types.Shape) types.Tensor) (types.Parameter, error) {
+	param := types.Parameter{RequiresGrad: jp.RequiresGrad}
+	if jp.Data != nil {
+		data, err := jsonToTensor(jp.Data, 0, tensorFactory)
+		if err != nil {
+			return param, err
+		}
+		param.Data = data
+	}
+	if jp.Grad != nil {
+		grad, err := jsonToTensor(jp.Grad, 0, tensorFactory)
+		if err != nil {
+			return param, err
+		}
+		param.Grad = grad
+	}
+	return param, nil
+}
+
+// jsonToLayerSpec converts a decoded layer into a spec the layer registry can rebuild.
+func jsonToLayerSpec(jl *jsonLayer, tensorFactory func(types.DataType, types.Shape) types.Tensor) (layers.Spec, error) {
+	spec := layers.Spec{
+		Type:       jl.Type,
+		Name:       jl.Name,
+		CanLearn:   jl.CanLearn,
+		Config:     jl.Config,
+		InputShape: jl.InputShape,
+		Parameters: make(map[nntypes.ParamIndex]types.Parameter, len(jl.Parameters)),
+	}
+	for key, jp := range jl.Parameters {
+		// Parameter indices are encoded as single-rune keys
+		idx := []rune(key)
+		if len(idx) != 1 {
+			return spec, fmt.Errorf("invalid parameter key %q", key)
+		}
+		param, err := jsonToParameter(jp, tensorFactory)
+		if err != nil {
+			return spec, err
+		}
+		spec.Parameters[nntypes.ParamIndex(idx[0])] = param
+	}
+	return spec, nil
+}
+
+// jsonToModel rebuilds a Sequential model from its layers.
+func jsonToModel(jm *jsonModel, tensorFactory func(types.DataType, types.Shape) types.Tensor) (*models.Sequential, error) {
+	spec := models.Spec{
+		Name:       jm.Name,
+		CanLearn:   jm.CanLearn,
+		InputShape: jm.InputShape,
+		Layers:     make([]layers.Spec, len(jm.Layers)),
+	}
+	for i := range jm.Layers {
+		layer, err := jsonToLayerSpec(&jm.Layers[i], tensorFactory)
+		if err != nil {
+			return nil, err
+		}
+		spec.Layers[i] = layer
+	}
+	return models.Restore(spec)
+}
+
 func convertSliceData(data any, sliceType string) (any, error) {
 	// JSON decodes numeric arrays as []any, we need to convert to proper type
 	if data == nil {
@@ -0,0 +1,77 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+)
+
+// SchemaVersion is the payload version written by Marshal. Version 0 is a
+// bare jsonValue from before payloads were versioned.
+const SchemaVersion = 1
+
+const (
+	schemaName   = "json"
+	envelopeName = "easyrobot"
+)
+
+// jsonEnvelope wraps every payload with its schema version.
+type jsonEnvelope struct {
+	Schema  string          `json:"schema"`
+	Version int             `json:"version"`
+	Value   json.RawMessage `json:"value"`
+}
+
+func init() {
+	// v1 only introduced the envelope
+	versioning.Register(schemaName, 0, 1, nil)
+}
+
+// wrap returns the envelope to encode, or jv itself for version 0.
+func wrap(jv *jsonValue, version int) (any, error) {
+	if version == 0 {
+		return jv, nil
+	}
+	data, err := json.Marshal(jv)
+	if err != nil {
+		return nil, err
+	}
+	return jsonEnvelope{Schema: envelopeName, Version: version, Value: data}, nil
+}
+
+// unwrap decodes an enveloped or a bare version 0 payload and migrates it
+// to SchemaVersion.
+func unwrap(raw json.RawMessage, opts types.Options) (*jsonValue, error) {
+	version := 0
+	payload := raw
+	var env jsonEnvelope
+	if err := json.Unmarshal(raw, &env); err == nil && env.Schema == envelopeName {
+		version, payload = env.Version, env.Value
+	}
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("envelope without value")
+	}
+	if err := versioning.Check(schemaName, version, SchemaVersion, opts); err != nil {
+		return nil, err
+	}
+	if version < SchemaVersion && versioning.NeedsMigration(schemaName, version, SchemaVersion) {
+		var doc versioning.Document
+		if err := json.Unmarshal(payload, &doc); err != nil {
+			return nil, err
+		}
+		doc, err := versioning.Migrate(schemaName, doc, version, SchemaVersion)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+	var jv jsonValue
+	if err := json.Unmarshal(payload, &jv); err != nil {
+		return nil, err
+	}
+	return &jv, nil
+}
@@ -0,0 +1,110 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+func goldenTensor() types.Tensor {
+	return tensor.FromFloat32(tensor.NewShape(2, 3), []float32{0.5, 1, 1.5, -2, 1e-3, 42})
+}
+
+func loadGolden(t *testing.T, name string, opts ...types.Option) (types.Tensor, error) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/golden/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got types.Tensor
+	err = NewUnmarshaller().Unmarshal(bytes.NewReader(data), &got, opts...)
+	return got, err
+}
+
+func checkTensor(t *testing.T, got types.Tensor, shape ...int) {
+	t.Helper()
+	if !got.Shape().Equal(tensor.NewShape(shape...)) {
+		t.Fatalf("shape %v, want %v", got.Shape(), shape)
+	}
+	for i, v := range goldenTensor().Data().([]float32) {
+		if got.Data().([]float32)[i] != v {
+			t.Fatalf("element %d is %v, want %v", i, got.Data().([]float32)[i], v)
+		}
+	}
+}
+
+func TestGoldenVersions(t *testing.T) {
+	for _, name := range []string{"tensor.v0.json", "tensor.v1.json"} {
+		got, err := loadGolden(t, name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		checkTensor(t, got, 2, 3)
+	}
+}
+
+func TestGoldenCurrent(t *testing.T) {
+	for version, name := range map[string]string{"": "tensor.v1.json", "0": "tensor.v0.json"} {
+		var buf bytes.Buffer
+		if err := NewMarshaller().Marshal(&buf, goldenTensor(), types.WithFormatVersion(version)); err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile("testdata/golden/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("output of version %q differs from %s:\n%s", version, name, buf.String())
+		}
+	}
+	if err := NewMarshaller().Marshal(&bytes.Buffer{}, goldenTensor(), types.WithFormatVersion("7")); err == nil {
+		t.Error("expected error writing an unknown version")
+	}
+}
+
+func TestMigration(t *testing.T) {
+	// pretend v1 stored tensors transposed
+	versioning.Register(schemaName, 0, 1, func(doc versioning.Document) (versioning.Document, error) {
+		tensor := doc["tensor"].(versioning.Document)
+		shape := tensor["shape"].([]any)
+		tensor["shape"] = []any{shape[1], shape[0]}
+		return doc, nil
+	})
+	t.Cleanup(func() { versioning.Register(schemaName, 0, 1, nil) })
+
+	got, err := loadGolden(t, "tensor.v0.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTensor(t, got, 3, 2)
+
+	if got, err = loadGolden(t, "tensor.v1.json"); err != nil {
+		t.Fatal(err)
+	}
+	checkTensor(t, got, 2, 3)
+}
+
+func TestFutureVersion(t *testing.T) {
+	data, err := os.ReadFile("testdata/golden/tensor.v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(`"version": 1`), []byte(`"version": 2`), 1)
+
+	var got types.Tensor
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(data), &got); err != nil {
+		t.Fatalf("best effort decoding failed: %v", err)
+	}
+	checkTensor(t, got, 2, 3)
+
+	err = NewUnmarshaller(versioning.WithStrict(true)).Unmarshal(bytes.NewReader(data), &got)
+	var future *versioning.FutureVersionError
+	if !errors.As(err, &future) || future.Version != 2 {
+		t.Fatalf("expected future version error, got %v", err)
+	}
+}
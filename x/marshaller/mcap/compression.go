@@ -0,0 +1,89 @@
+package mcap
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Chunk compressions defined by the MCAP specification.
+const (
+	CompressionNone = ""
+	CompressionZstd = "zstd"
+	CompressionLZ4  = "lz4"
+)
+
+// zstdEncoder is shared by the writers; EncodeAll is safe for concurrent use.
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+})
+
+func validCompression(compression string) error {
+	switch compression {
+	case CompressionNone, CompressionZstd, CompressionLZ4:
+		return nil
+	}
+	return fmt.Errorf("unsupported compression %q", compression)
+}
+
+// compress returns the records of a chunk compressed as named.
+func compress(compression string, records []byte) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return records, nil
+	case CompressionZstd:
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll(records, nil), nil
+	case CompressionLZ4:
+		var buf bytes.Buffer
+		w := lz4.NewWriter(&buf)
+		if _, err := w.Write(records); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, validCompression(compression)
+}
+
+// decompress returns the records of a chunk, which must decompress to the
+// declared size. The size is untrusted, so the output grows as it is
+// decoded rather than being allocated up front.
+func decompress(compression string, data []byte, size uint64) ([]byte, error) {
+	var r io.Reader
+	switch compression {
+	case CompressionNone:
+		if uint64(len(data)) != size {
+			return nil, fmt.Errorf("%d bytes of records, want %d", len(data), size)
+		}
+		return data, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case CompressionLZ4:
+		r = lz4.NewReader(bytes.NewReader(data))
+	default:
+		return nil, validCompression(compression)
+	}
+	out, err := io.ReadAll(io.LimitReader(r, int64(min(size, 1<<62))+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", compression, err)
+	}
+	if uint64(len(out)) != size {
+		return nil, fmt.Errorf("%s records decompress to %d bytes, want %d", compression, len(out), size)
+	}
+	return out, nil
+}
@@ -0,0 +1,413 @@
+package mcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Magic starts and ends every MCAP file.
+var Magic = []byte{0x89, 'M', 'C', 'A', 'P', '0', '\r', '\n'}
+
+type opcode byte
+
+const (
+	opHeader          opcode = 0x01
+	opFooter          opcode = 0x02
+	opSchema          opcode = 0x03
+	opChannel         opcode = 0x04
+	opMessage         opcode = 0x05
+	opChunk           opcode = 0x06
+	opMessageIndex    opcode = 0x07
+	opChunkIndex      opcode = 0x08
+	opAttachment      opcode = 0x09
+	opAttachmentIndex opcode = 0x0A
+	opStatistics      opcode = 0x0B
+	opMetadata        opcode = 0x0C
+	opMetadataIndex   opcode = 0x0D
+	opSummaryOffset   opcode = 0x0E
+	opDataEnd         opcode = 0x0F
+)
+
+const (
+	// recordHeaderSize is the opcode and the uint64 content length.
+	recordHeaderSize = 1 + 8
+	// footerSize is the whole footer record.
+	footerSize = recordHeaderSize + 8 + 8 + 4
+)
+
+// Schema describes the encoding of the messages of one or more channels.
+type Schema struct {
+	ID       uint16
+	Name     string
+	Encoding string
+	Data     []byte
+}
+
+// Channel is a stream of messages on one topic.
+type Channel struct {
+	ID              uint16
+	SchemaID        uint16
+	Topic           string
+	MessageEncoding string
+	Metadata        map[string]string
+}
+
+// Statistics summarizes the contents of a file.
+type Statistics struct {
+	MessageCount         uint64
+	SchemaCount          uint16
+	ChannelCount         uint32
+	AttachmentCount      uint32
+	MetadataCount        uint32
+	ChunkCount           uint32
+	MessageStartTime     uint64
+	MessageEndTime       uint64
+	ChannelMessageCounts map[uint16]uint64
+}
+
+type message struct {
+	channelID   uint16
+	sequence    uint32
+	logTime     uint64
+	publishTime uint64
+	data        []byte
+}
+
+type chunk struct {
+	messageStartTime uint64
+	messageEndTime   uint64
+	uncompressedSize uint64
+	uncompressedCRC  uint32
+	compression      string
+	records          []byte
+}
+
+type chunkIndex struct {
+	messageStartTime    uint64
+	messageEndTime      uint64
+	chunkStartOffset    uint64
+	chunkLength         uint64
+	messageIndexOffsets map[uint16]uint64
+	messageIndexLength  uint64
+	compression         string
+	compressedSize      uint64
+	uncompressedSize    uint64
+}
+
+type indexEntry struct {
+	logTime uint64
+	offset  uint64
+}
+
+type footer struct {
+	summaryStart       uint64
+	summaryOffsetStart uint64
+	summaryCRC         uint32
+}
+
+// Record encoding
+
+func appendRecord(buf []byte, op opcode, content []byte) []byte {
+	buf = append(buf, byte(op))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(content)))
+	return append(buf, content...)
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+func appendBytes32(buf, data []byte) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(data)))
+	return append(buf, data...)
+}
+
+func appendBytes64(buf, data []byte) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+func appendStringMap(buf []byte, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var content []byte
+	for _, k := range keys {
+		content = appendString(content, k)
+		content = appendString(content, m[k])
+	}
+	return appendBytes32(buf, content)
+}
+
+func appendCountMap(buf []byte, m map[uint16]uint64) []byte {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, int(k))
+	}
+	sort.Ints(keys)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(keys)*10))
+	for _, k := range keys {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(k))
+		buf = binary.LittleEndian.AppendUint64(buf, m[uint16(k)])
+	}
+	return buf
+}
+
+func encodeHeader(profile, library string) []byte {
+	return appendString(appendString(nil, profile), library)
+}
+
+func encodeFooter(f footer) []byte {
+	buf := binary.LittleEndian.AppendUint64(nil, f.summaryStart)
+	buf = binary.LittleEndian.AppendUint64(buf, f.summaryOffsetStart)
+	return binary.LittleEndian.AppendUint32(buf, f.summaryCRC)
+}
+
+func encodeSchema(s *Schema) []byte {
+	buf := binary.LittleEndian.AppendUint16(nil, s.ID)
+	buf = appendString(buf, s.Name)
+	buf = appendString(buf, s.Encoding)
+	return appendBytes32(buf, s.Data)
+}
+
+func encodeChannel(c *Channel) []byte {
+	buf := binary.LittleEndian.AppendUint16(nil, c.ID)
+	buf = binary.LittleEndian.AppendUint16(buf, c.SchemaID)
+	buf = appendString(buf, c.Topic)
+	buf = appendString(buf, c.MessageEncoding)
+	return appendStringMap(buf, c.Metadata)
+}
+
+func appendMessage(buf []byte, m *message) []byte {
+	buf = append(buf, byte(opMessage))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(2+4+8+8+len(m.data)))
+	buf = binary.LittleEndian.AppendUint16(buf, m.channelID)
+	buf = binary.LittleEndian.AppendUint32(buf, m.sequence)
+	buf = binary.LittleEndian.AppendUint64(buf, m.logTime)
+	buf = binary.LittleEndian.AppendUint64(buf, m.publishTime)
+	return append(buf, m.data...)
+}
+
+func encodeChunk(c *chunk) []byte {
+	buf := binary.LittleEndian.AppendUint64(nil, c.messageStartTime)
+	buf = binary.LittleEndian.AppendUint64(buf, c.messageEndTime)
+	buf = binary.LittleEndian.AppendUint64(buf, c.uncompressedSize)
+	buf = binary.LittleEndian.AppendUint32(buf, c.uncompressedCRC)
+	buf = appendString(buf, c.compression)
+	return appendBytes64(buf, c.records)
+}
+
+func encodeMessageIndex(channelID uint16, entries []indexEntry) []byte {
+	buf := binary.LittleEndian.AppendUint16(nil, channelID)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(entries)*16))
+	for _, e := range entries {
+		buf = binary.LittleEndian.AppendUint64(buf, e.logTime)
+		buf = binary.LittleEndian.AppendUint64(buf, e.offset)
+	}
+	return buf
+}
+
+func encodeChunkIndex(c *chunkIndex) []byte {
+	buf := binary.LittleEndian.AppendUint64(nil, c.messageStartTime)
+	buf = binary.LittleEndian.AppendUint64(buf, c.messageEndTime)
+	buf = binary.LittleEndian.AppendUint64(buf, c.chunkStartOffset)
+	buf = binary.LittleEndian.AppendUint64(buf, c.chunkLength)
+	buf = appendCountMap(buf, c.messageIndexOffsets)
+	buf = binary.LittleEndian.AppendUint64(buf, c.messageIndexLength)
+	buf = appendString(buf, c.compression)
+	buf = binary.LittleEndian.AppendUint64(buf, c.compressedSize)
+	return binary.LittleEndian.AppendUint64(buf, c.uncompressedSize)
+}
+
+func encodeStatistics(s *Statistics) []byte {
+	buf := binary.LittleEndian.AppendUint64(nil, s.MessageCount)
+	buf = binary.LittleEndian.AppendUint16(buf, s.SchemaCount)
+	buf = binary.LittleEndian.AppendUint32(buf, s.ChannelCount)
+	buf = binary.LittleEndian.AppendUint32(buf, s.AttachmentCount)
+	buf = binary.LittleEndian.AppendUint32(buf, s.MetadataCount)
+	buf = binary.LittleEndian.AppendUint32(buf, s.ChunkCount)
+	buf = binary.LittleEndian.AppendUint64(buf, s.MessageStartTime)
+	buf = binary.LittleEndian.AppendUint64(buf, s.MessageEndTime)
+	return appendCountMap(buf, s.ChannelMessageCounts)
+}
+
+func encodeSummaryOffset(group opcode, start, length uint64) []byte {
+	buf := []byte{byte(group)}
+	buf = binary.LittleEndian.AppendUint64(buf, start)
+	return binary.LittleEndian.AppendUint64(buf, length)
+}
+
+// Record decoding
+
+// decoder reads the fields of one record. The first failure is kept in err
+// and turns later reads into no-ops.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) take(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.buf)) < n {
+		d.err = fmt.Errorf("record truncated")
+		return nil
+	}
+	b := d.buf[:n:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) u8() uint8 {
+	if b := d.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) u16() uint16 {
+	if b := d.take(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) u32() uint32 {
+	if b := d.take(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) u64() uint64 {
+	if b := d.take(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) str() string {
+	return string(d.take(uint64(d.u32())))
+}
+
+func (d *decoder) bytes32() []byte {
+	return d.take(uint64(d.u32()))
+}
+
+func (d *decoder) bytes64() []byte {
+	return d.take(d.u64())
+}
+
+func (d *decoder) stringMap() map[string]string {
+	sub := decoder{buf: d.bytes32()}
+	m := make(map[string]string)
+	for d.err == nil && len(sub.buf) > 0 {
+		k := sub.str()
+		m[k] = sub.str()
+		if sub.err != nil {
+			d.err = sub.err
+		}
+	}
+	return m
+}
+
+func (d *decoder) countMap() map[uint16]uint64 {
+	sub := decoder{buf: d.bytes32()}
+	m := make(map[uint16]uint64)
+	for d.err == nil && len(sub.buf) > 0 {
+		k := sub.u16()
+		m[k] = sub.u64()
+		if sub.err != nil {
+			d.err = sub.err
+		}
+	}
+	return m
+}
+
+func decodeSchema(b []byte) (*Schema, error) {
+	d := decoder{buf: b}
+	s := &Schema{ID: d.u16(), Name: d.str(), Encoding: d.str(), Data: d.bytes32()}
+	return s, d.err
+}
+
+func decodeChannel(b []byte) (*Channel, error) {
+	d := decoder{buf: b}
+	c := &Channel{ID: d.u16(), SchemaID: d.u16(), Topic: d.str(), MessageEncoding: d.str(), Metadata: d.stringMap()}
+	return c, d.err
+}
+
+func decodeMessage(b []byte) (*message, error) {
+	d := decoder{buf: b}
+	m := &message{channelID: d.u16(), sequence: d.u32(), logTime: d.u64(), publishTime: d.u64()}
+	m.data = d.buf
+	return m, d.err
+}
+
+func decodeChunk(b []byte) (*chunk, error) {
+	d := decoder{buf: b}
+	c := &chunk{
+		messageStartTime: d.u64(),
+		messageEndTime:   d.u64(),
+		uncompressedSize: d.u64(),
+		uncompressedCRC:  d.u32(),
+		compression:      d.str(),
+		records:          d.bytes64(),
+	}
+	return c, d.err
+}
+
+func decodeChunkIndex(b []byte) (*chunkIndex, error) {
+	d := decoder{buf: b}
+	c := &chunkIndex{
+		messageStartTime:    d.u64(),
+		messageEndTime:      d.u64(),
+		chunkStartOffset:    d.u64(),
+		chunkLength:         d.u64(),
+		messageIndexOffsets: d.countMap(),
+		messageIndexLength:  d.u64(),
+		compression:         d.str(),
+		compressedSize:      d.u64(),
+		uncompressedSize:    d.u64(),
+	}
+	return c, d.err
+}
+
+func decodeStatistics(b []byte) (*Statistics, error) {
+	d := decoder{buf: b}
+	s := &Statistics{
+		MessageCount:         d.u64(),
+		SchemaCount:          d.u16(),
+		ChannelCount:         d.u32(),
+		AttachmentCount:      d.u32(),
+		MetadataCount:        d.u32(),
+		ChunkCount:           d.u32(),
+		MessageStartTime:     d.u64(),
+		MessageEndTime:       d.u64(),
+		ChannelMessageCounts: d.countMap(),
+	}
+	return s, d.err
+}
+
+func decodeFooter(b []byte) (footer, error) {
+	d := decoder{buf: b}
+	f := footer{summaryStart: d.u64(), summaryOffsetStart: d.u64(), summaryCRC: d.u32()}
+	return f, d.err
+}
+
+// nextRecord splits the first record off buf.
+func nextRecord(buf []byte) (op opcode, content, rest []byte, err error) {
+	if len(buf) < recordHeaderSize {
+		return 0, nil, nil, fmt.Errorf("record truncated")
+	}
+	length := binary.LittleEndian.Uint64(buf[1:])
+	if length > uint64(len(buf)-recordHeaderSize) {
+		return 0, nil, nil, fmt.Errorf("record of %d bytes truncated", length)
+	}
+	end := recordHeaderSize + int(length)
+	return opcode(buf[0]), buf[recordHeaderSize:end:end], buf[end:], nil
+}
@@ -0,0 +1,125 @@
+// Package mcap records and replays multi-sensor sessions in the MCAP container
+// format (https://mcap.dev), readable by Foxglove and the MCAP tooling.
+//
+// Channels carry protobuf messages with their schemas embedded as
+// FileDescriptorSets. Frames are stored as types.core.Frame messages with raw
+// little endian tensors; sensor readings use the types/devices messages
+// (IMUReading, LIDARReading, EncoderReading, ...) or any other proto.Message.
+//
+// The Writer and Reader give direct access to channels and messages. The
+// marshaller records a types.FrameStream or frames; the unmarshaller replays
+// a recording as a types.FrameStream ordered by log time, optionally paced to
+// the original timing (see WithPlaybackRate) and starting at a given time
+// using the chunk index (see WithTimeRange).
+package mcap
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Marshaller records frames to MCAP.
+type Marshaller struct {
+	opts types.Options
+}
+
+// NewMarshaller creates a new MCAP marshaller.
+func NewMarshaller(opts ...types.Option) *Marshaller {
+	m := &Marshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&m.opts)
+	}
+	return m
+}
+
+// Format returns the format name.
+func (m *Marshaller) Format() string {
+	return "mcap"
+}
+
+// Marshal records a types.FrameStream (until it ends or Options.Context is
+// cancelled), a []types.Frame or a single types.Frame.
+//
+// Each frame goes to the topic in its "topic" metadata, or to the WithTopic
+// topic (default DefaultTopic). A frame whose "message" metadata holds a
+// proto.Message, as produced by playback of non-frame channels, records that
+// message instead of the frame.
+func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	localOpts := m.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+	topic := DefaultTopic
+	if t := localOpts.Metadata[topicKey]; t != "" {
+		topic = t
+	}
+
+	writer, err := NewWriter(w, types.WithMetadata(localOpts.Metadata))
+	if err != nil {
+		return types.NewError("marshal", "mcap", "failed to start recording", err)
+	}
+
+	switch v := value.(type) {
+	case types.FrameStream:
+		err = writeStream(localOpts.Context, writer, v, topic)
+	case *types.FrameStream:
+		err = writeStream(localOpts.Context, writer, *v, topic)
+	case []types.Frame:
+		for _, frame := range v {
+			if err = writeFrame(writer, frame, topic); err != nil {
+				break
+			}
+		}
+	case types.Frame:
+		err = writeFrame(writer, v, topic)
+	default:
+		return types.NewError("marshal", "mcap", fmt.Sprintf("unsupported type %T", value), nil)
+	}
+	if err != nil {
+		return types.NewError("marshal", "mcap", "failed to record", err)
+	}
+	if err := writer.Close(); err != nil {
+		return types.NewError("marshal", "mcap", "failed to finish recording", err)
+	}
+	return nil
+}
+
+func writeStream(ctx context.Context, w *Writer, stream types.FrameStream, topic string) error {
+	defer stream.Close()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case frame, ok := <-stream.C:
+			if !ok {
+				return nil
+			}
+			if err := writeFrame(w, frame, topic); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeFrame(w *Writer, frame types.Frame, topic string) error {
+	if err, ok := frame.Metadata["error"].(error); ok {
+		return err
+	}
+	if t, ok := frame.Metadata[metaTopic].(string); ok && t != "" {
+		topic = t
+	}
+	if msg, ok := frame.Metadata[metaMessage].(proto.Message); ok {
+		return w.Write(topic, frame.Timestamp, msg)
+	}
+	return w.WriteFrame(topic, frame)
+}
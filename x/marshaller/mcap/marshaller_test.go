@@ -0,0 +1,285 @@
+package mcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/itohio/EasyRobot/types/devices"
+	mathpb "github.com/itohio/EasyRobot/types/math"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const ms = int64(time.Millisecond)
+
+// record writes 10 camera frames at 10 Hz, an IMU reading every 20 ms and a
+// lidar scan every 50 ms, in small chunks.
+func record(t *testing.T, opts ...types.Option) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, append([]types.Option{WithChunkSize(256)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ts := int64(0); ts < 1000*ms; ts += 10 * ms {
+		if ts%(100*ms) == 0 {
+			img := tensor.New(types.UINT8, tensor.NewShape(2, 3))
+			pixels := img.Data().([]uint8)
+			for i := range pixels {
+				pixels[i] = uint8(ts/(10*ms) + int64(i))
+			}
+			frame := types.Frame{Index: int(ts / (100 * ms)), Timestamp: ts, Metadata: map[string]any{"camera": "left"}, Tensors: []types.Tensor{img}}
+			if err := w.WriteFrame("/camera/left", frame); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if ts%(20*ms) == 0 {
+			imu := &devices.IMUReading{Acceleration: &mathpb.Vector3D{Z: 9.81}, Timestamp: ts}
+			if err := w.Write("/imu", ts, imu); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if ts%(50*ms) == 0 {
+			scan := &devices.LIDARReading{DistancesMm: []float32{100, 200}, AnglesDeg: []float32{0, 180}, Timestamp: ts, Valid: true}
+			if err := w.Write("/scan", ts, scan); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := record(t)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+
+	stats := r.Statistics()
+	if stats.MessageCount != 10+50+20 || stats.ChannelCount != 3 || stats.SchemaCount != 3 || stats.ChunkCount < 2 {
+		t.Errorf("statistics = %+v", stats)
+	}
+	if stats.MessageEndTime != uint64(980*ms) {
+		t.Errorf("end time = %d", stats.MessageEndTime)
+	}
+	if channels := r.Channels(); channels[0].Topic != "/camera/left" || r.Schemas()[0].Name != "types.core.Frame" {
+		t.Errorf("channels = %v, schemas = %v", channels, r.Schemas())
+	}
+
+	last := int64(-1)
+	counts := make(map[string]int)
+	for m, err := range r.Messages(0, 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.LogTime < last {
+			t.Fatalf("message at %d after %d", m.LogTime, last)
+		}
+		last = m.LogTime
+		counts[m.Channel.Topic]++
+		msg, err := m.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scan, ok := msg.(*devices.LIDARReading); ok && (scan.Timestamp != m.LogTime || scan.DistancesMm[1] != 200) {
+			t.Errorf("scan = %v", scan)
+		}
+	}
+	if counts["/camera/left"] != 10 || counts["/imu"] != 50 || counts["/scan"] != 20 {
+		t.Errorf("message counts = %v", counts)
+	}
+
+	var frames []types.Frame
+	err = NewUnmarshaller().Unmarshal(bytes.NewReader(data), &frames, WithTopics("/camera/left"))
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(frames) != 10 {
+		t.Fatalf("got %d frames", len(frames))
+	}
+	f := frames[3]
+	if f.Timestamp != 300*ms || f.Metadata["camera"] != "left" || f.Metadata["topic"] != "/camera/left" {
+		t.Errorf("frame = %+v", f)
+	}
+	img := f.Tensors[0]
+	if img.DataType() != types.UINT8 || !img.Shape().Equal(tensor.NewShape(2, 3)) || img.Data().([]uint8)[5] != 35 {
+		t.Errorf("image = %v %v", img.Shape(), img.Data())
+	}
+}
+
+func TestCompression(t *testing.T) {
+	for _, compression := range []string{CompressionZstd, CompressionLZ4} {
+		data := record(t, WithCompression(compression))
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s: NewReader failed: %v", compression, err)
+		}
+		n := 0
+		for m, err := range r.Messages(0, 0) {
+			if err != nil {
+				t.Fatalf("%s: %v", compression, err)
+			}
+			if _, err := m.Decode(); err != nil {
+				t.Fatalf("%s: %v", compression, err)
+			}
+			n++
+		}
+		if n != 80 {
+			t.Errorf("%s: read %d messages, want 80", compression, n)
+		}
+		if len(data) >= len(record(t)) {
+			t.Errorf("%s: %d bytes, uncompressed %d", compression, len(data), len(record(t)))
+		}
+	}
+
+	if _, err := NewWriter(&bytes.Buffer{}, WithCompression("brotli")); err == nil {
+		t.Error("unknown compression should fail")
+	}
+	if _, err := decompress(CompressionZstd, []byte{0x28, 0xb5, 0x2f, 0xfd, 0}, 1<<40); err == nil {
+		t.Error("corrupt zstd records should fail")
+	}
+	if _, err := decompress("brotli", nil, 0); err == nil {
+		t.Error("unknown compression should fail")
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	*bytes.Reader
+	n int
+}
+
+func (r *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	r.n += len(p)
+	return r.Reader.ReadAt(p, off)
+}
+
+func TestSeek(t *testing.T) {
+	data := record(t)
+	src := &countingReader{Reader: bytes.NewReader(data)}
+	r, err := NewReader(src, int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened := src.n
+
+	var times []int64
+	for m, err := range r.Messages(900*ms, 950*ms, "/imu", "/scan") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		times = append(times, m.LogTime)
+	}
+	want := []int64{900 * ms, 900 * ms, 920 * ms, 940 * ms}
+	if len(times) != len(want) {
+		t.Fatalf("times = %v, want %v", times, want)
+	}
+	for i := range want {
+		if times[i] != want[i] {
+			t.Errorf("times = %v, want %v", times, want)
+		}
+	}
+	if read := src.n - opened; read > len(data)/4 {
+		t.Errorf("seeking read %d of %d bytes", read, len(data))
+	}
+
+	// without a summary the data section is scanned
+	unindexed := bytes.Clone(data)
+	footer := len(unindexed) - len(Magic) - footerSize + recordHeaderSize
+	binary.LittleEndian.PutUint64(unindexed[footer:], 0)
+	binary.LittleEndian.PutUint32(unindexed[footer+16:], 0)
+	r, err = NewReader(bytes.NewReader(unindexed), int64(len(unindexed)))
+	if err != nil {
+		t.Fatalf("NewReader without summary failed: %v", err)
+	}
+	if stats := r.Statistics(); stats.MessageCount != 80 || stats.ChannelCount != 3 {
+		t.Errorf("scanned statistics = %+v", stats)
+	}
+	n := 0
+	for _, err := range r.Messages(900*ms, 0, "/camera/left") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("got %d frames after 900ms", n)
+	}
+}
+
+func TestPlayback(t *testing.T) {
+	ch := make(chan types.Frame)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 5; i++ {
+			frame := types.Frame{Index: i, Timestamp: int64(i) * 20 * ms, Tensors: []types.Tensor{
+				tensor.FromFloat32(tensor.NewShape(2), []float32{float32(i), -1}),
+			}}
+			if i == 2 {
+				frame.Metadata = map[string]any{"topic": "/encoder", "message": &devices.EncoderReading{Position: 42}}
+			}
+			ch <- frame
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := NewMarshaller().Marshal(&buf, types.NewFrameStream(ch, nil)); err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		rate    float64
+		minTime time.Duration
+	}{{0, 0}, {2, 35 * time.Millisecond}} {
+		var stream types.FrameStream
+		err := NewUnmarshaller().Unmarshal(bytes.NewReader(buf.Bytes()), &stream, WithPlaybackRate(tc.rate))
+		if err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		start := time.Now()
+		var frames []types.Frame
+		for frame := range stream.C {
+			if err, ok := frame.Metadata["error"].(error); ok {
+				t.Fatal(err)
+			}
+			frames = append(frames, frame)
+		}
+		elapsed := time.Since(start)
+		if len(frames) != 5 {
+			t.Fatalf("rate %g: got %d frames", tc.rate, len(frames))
+		}
+		if elapsed < tc.minTime {
+			t.Errorf("rate %g: playback took %v, want at least %v", tc.rate, elapsed, tc.minTime)
+		}
+		if got := frames[4].Tensors[0].Data().([]float32); got[0] != 4 || frames[4].Metadata["topic"] != DefaultTopic {
+			t.Errorf("frame 4 = %v %v", got, frames[4].Metadata)
+		}
+		if enc, ok := frames[2].Metadata["message"].(*devices.EncoderReading); !ok || !proto.Equal(enc, &devices.EncoderReading{Position: 42}) {
+			t.Errorf("frame 2 message = %v", frames[2].Metadata["message"])
+		}
+	}
+
+	// closing the stream stops playback
+	var stream types.FrameStream
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(buf.Bytes()), &stream, WithPlaybackRate(0.01)); err != nil {
+		t.Fatal(err)
+	}
+	<-stream.C
+	stream.Close()
+	select {
+	case _, ok := <-stream.C:
+		if ok {
+			t.Error("stream yielded a frame after Close")
+		}
+	case <-time.After(time.Second):
+		t.Error("stream was not closed")
+	}
+}
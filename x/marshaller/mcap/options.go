@@ -0,0 +1,132 @@
+package mcap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+const (
+	chunkSizeKey   = "mcap.chunk_size"
+	compressionKey = "mcap.compression"
+	topicKey       = "mcap.topic"
+	topicsKey      = "mcap.topics"
+	rateKey        = "mcap.rate"
+	startKey       = "mcap.start"
+	endKey         = "mcap.end"
+)
+
+// Frame metadata keys used by the marshaller and the playback stream.
+const (
+	metaTopic    = "topic"
+	metaMessage  = "message"
+	metaSequence = "sequence"
+)
+
+// DefaultTopic is the topic frames are recorded on unless WithTopic or the
+// frame's "topic" metadata names another one.
+const DefaultTopic = "/frames"
+
+type metadataOption struct {
+	key, value string
+}
+
+func (o metadataOption) Apply(opts *types.Options) {
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]string)
+	}
+	opts.Metadata[o.key] = o.value
+}
+
+// WithChunkSize sets the uncompressed size in bytes after which the writer
+// starts a new chunk.
+func WithChunkSize(size int) types.Option {
+	return metadataOption{chunkSizeKey, strconv.Itoa(size)}
+}
+
+// WithCompression sets the compression of the chunks written:
+// CompressionNone (the default), CompressionZstd or CompressionLZ4.
+func WithCompression(compression string) types.Option {
+	return metadataOption{compressionKey, compression}
+}
+
+// WithTopic sets the topic frames without "topic" metadata are recorded on.
+func WithTopic(topic string) types.Option {
+	return metadataOption{topicKey, topic}
+}
+
+// WithTopics restricts reading to messages on the given topics.
+func WithTopics(topics ...string) types.Option {
+	return metadataOption{topicsKey, strings.Join(topics, ",")}
+}
+
+// WithPlaybackRate paces playback relative to the recorded log times: 1 replays
+// in real time, 2 twice as fast. 0 (the default) yields frames as fast as the
+// consumer reads them.
+func WithPlaybackRate(rate float64) types.Option {
+	return metadataOption{rateKey, strconv.FormatFloat(rate, 'g', -1, 64)}
+}
+
+// WithTimeRange restricts reading to messages logged in [start, end). The
+// chunk index is used to seek to start. A zero end reads to the end of the
+// recording.
+func WithTimeRange(start, end time.Time) types.Option {
+	var o withTimeRange
+	if !start.IsZero() {
+		o.start = start.UnixNano()
+	}
+	if !end.IsZero() {
+		o.end = end.UnixNano()
+	}
+	return o
+}
+
+type withTimeRange struct {
+	start, end int64
+}
+
+func (o withTimeRange) Apply(opts *types.Options) {
+	metadataOption{startKey, strconv.FormatInt(o.start, 10)}.Apply(opts)
+	if o.end > 0 {
+		metadataOption{endKey, strconv.FormatInt(o.end, 10)}.Apply(opts)
+	}
+}
+
+// readConfig holds the reading options decoded from Options.Metadata.
+type readConfig struct {
+	topics []string
+	rate   float64
+	start  int64
+	end    int64
+}
+
+func readOptions(opts types.Options) (readConfig, error) {
+	var cfg readConfig
+	meta := opts.Metadata
+	if v := meta[topicsKey]; v != "" {
+		cfg.topics = strings.Split(v, ",")
+	}
+	var err error
+	if v, ok := meta[rateKey]; ok {
+		if cfg.rate, err = strconv.ParseFloat(v, 64); err == nil && cfg.rate < 0 {
+			err = fmt.Errorf("negative rate")
+		}
+		if err != nil {
+			return cfg, types.NewError("unmarshal", "mcap", "invalid playback rate "+v, err)
+		}
+	}
+	if v, ok := meta[startKey]; ok {
+		if cfg.start, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return cfg, types.NewError("unmarshal", "mcap", "invalid start time "+v, err)
+		}
+	}
+	if v, ok := meta[endKey]; ok {
+		if cfg.end, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return cfg, types.NewError("unmarshal", "mcap", "invalid end time "+v, err)
+		}
+	}
+	return cfg, nil
+}
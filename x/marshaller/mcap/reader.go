@@ -0,0 +1,490 @@
+package mcap
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"slices"
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Message is a message read from an MCAP file.
+type Message struct {
+	Channel     *Channel
+	Schema      *Schema // nil for channels without a schema
+	Sequence    uint32
+	LogTime     int64
+	PublishTime int64
+	Data        []byte
+
+	reader *Reader
+}
+
+// Decode unmarshals a protobuf message. Types linked into the binary (such as
+// those in types/core and types/devices) decode to their Go structs; other
+// schemas decode to dynamic messages.
+func (m *Message) Decode() (proto.Message, error) {
+	if m.Schema == nil {
+		return nil, fmt.Errorf("mcap: channel %s has no schema", m.Channel.Topic)
+	}
+	mt, err := m.reader.messageType(m.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("mcap: %w", err)
+	}
+	msg := mt.New().Interface()
+	if err := proto.Unmarshal(m.Data, msg); err != nil {
+		return nil, fmt.Errorf("mcap: %s: %w", m.Channel.Topic, err)
+	}
+	return msg, nil
+}
+
+// block is a chunk, or a message outside of chunks, in the data section.
+type block struct {
+	start, end     uint64
+	offset, length uint64
+}
+
+// Reader reads indexed MCAP files. The summary section is used when present;
+// files without one are scanned once when opened.
+type Reader struct {
+	r    io.ReaderAt
+	size int64
+
+	mu       sync.Mutex
+	schemas  map[uint16]*Schema
+	channels map[uint16]*Channel
+	types    map[uint16]protoreflect.MessageType
+
+	blocks []block // ordered by start time
+	stats  Statistics
+}
+
+// NewReader opens the MCAP file of the given size read through r.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	rd := &Reader{
+		r:        r,
+		size:     size,
+		schemas:  make(map[uint16]*Schema),
+		channels: make(map[uint16]*Channel),
+		types:    make(map[uint16]protoreflect.MessageType),
+	}
+	if err := rd.open(); err != nil {
+		return nil, fmt.Errorf("mcap: %w", err)
+	}
+	return rd, nil
+}
+
+func (r *Reader) readAt(offset, length uint64) ([]byte, error) {
+	if offset+length > uint64(r.size) || offset+length < offset {
+		return nil, fmt.Errorf("range [%d, +%d) beyond end of file", offset, length)
+	}
+	buf := make([]byte, length)
+	if _, err := r.r.ReadAt(buf, int64(offset)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (r *Reader) open() error {
+	magicSize := uint64(len(Magic))
+	if uint64(r.size) < 2*magicSize+footerSize {
+		return fmt.Errorf("file too short")
+	}
+	head, err := r.readAt(0, magicSize)
+	if err != nil {
+		return err
+	}
+	footerStart := uint64(r.size) - magicSize - footerSize
+	tail, err := r.readAt(footerStart, footerSize+magicSize)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(head, Magic) || !bytes.Equal(tail[footerSize:], Magic) {
+		return fmt.Errorf("not an MCAP file")
+	}
+	op, content, _, err := nextRecord(tail[:footerSize])
+	if err != nil || op != opFooter {
+		return fmt.Errorf("missing footer")
+	}
+	f, err := decodeFooter(content)
+	if err != nil {
+		return err
+	}
+
+	if f.summaryStart != 0 && f.summaryStart <= footerStart {
+		indexed, err := r.readSummary(f, footerStart)
+		if err != nil {
+			return err
+		}
+		if indexed {
+			return nil
+		}
+	}
+	return r.scan(footerStart)
+}
+
+// readSummary loads schemas, channels, chunk indexes and statistics. It
+// reports false when the summary does not index the messages.
+func (r *Reader) readSummary(f footer, footerStart uint64) (bool, error) {
+	summary, err := r.readAt(f.summaryStart, footerStart-f.summaryStart)
+	if err != nil {
+		return false, err
+	}
+	if f.summaryCRC != 0 {
+		crc := crc32.NewIEEE()
+		crc.Write(summary)
+		footerRecord := appendRecord(nil, opFooter, encodeFooter(f))
+		crc.Write(footerRecord[:len(footerRecord)-4])
+		if crc.Sum32() != f.summaryCRC {
+			return false, fmt.Errorf("summary checksum mismatch")
+		}
+	}
+
+	hasStats := false
+	for len(summary) > 0 {
+		op, content, rest, err := nextRecord(summary)
+		if err != nil {
+			return false, fmt.Errorf("summary: %w", err)
+		}
+		summary = rest
+		switch op {
+		case opSchema:
+			s, err := decodeSchema(content)
+			if err != nil {
+				return false, fmt.Errorf("schema: %w", err)
+			}
+			r.schemas[s.ID] = s
+		case opChannel:
+			c, err := decodeChannel(content)
+			if err != nil {
+				return false, fmt.Errorf("channel: %w", err)
+			}
+			r.channels[c.ID] = c
+		case opChunkIndex:
+			c, err := decodeChunkIndex(content)
+			if err != nil {
+				return false, fmt.Errorf("chunk index: %w", err)
+			}
+			r.blocks = append(r.blocks, block{
+				start:  c.messageStartTime,
+				end:    c.messageEndTime,
+				offset: c.chunkStartOffset,
+				length: c.chunkLength,
+			})
+		case opStatistics:
+			s, err := decodeStatistics(content)
+			if err != nil {
+				return false, fmt.Errorf("statistics: %w", err)
+			}
+			r.stats = *s
+			hasStats = true
+		}
+	}
+	if !hasStats || (len(r.blocks) == 0 && r.stats.MessageCount > 0) {
+		r.blocks = nil
+		return false, nil
+	}
+	r.sortBlocks()
+	return true, nil
+}
+
+// scan indexes the data section of a file without a usable summary.
+func (r *Reader) scan(footerStart uint64) error {
+	r.stats = Statistics{ChannelMessageCounts: make(map[uint16]uint64)}
+	count := func(m *message) {
+		if r.stats.MessageCount == 0 || m.logTime < r.stats.MessageStartTime {
+			r.stats.MessageStartTime = m.logTime
+		}
+		r.stats.MessageEndTime = max(r.stats.MessageEndTime, m.logTime)
+		r.stats.MessageCount++
+		r.stats.ChannelMessageCounts[m.channelID]++
+	}
+
+	offset := uint64(len(Magic))
+	for offset < footerStart {
+		header, err := r.readAt(offset, recordHeaderSize)
+		if err != nil {
+			return err
+		}
+		length := recordHeaderSize + binary.LittleEndian.Uint64(header[1:])
+		op := opcode(header[0])
+		if op == opDataEnd || op == opFooter {
+			break
+		}
+
+		switch op {
+		case opSchema, opChannel:
+			record, err := r.readAt(offset, length)
+			if err != nil {
+				return err
+			}
+			if err := r.addDefinition(op, record[recordHeaderSize:]); err != nil {
+				return err
+			}
+		case opMessage:
+			record, err := r.readAt(offset, length)
+			if err != nil {
+				return err
+			}
+			m, err := decodeMessage(record[recordHeaderSize:])
+			if err != nil {
+				return fmt.Errorf("message at %d: %w", offset, err)
+			}
+			count(m)
+			r.blocks = append(r.blocks, block{start: m.logTime, end: m.logTime, offset: offset, length: length})
+		case opChunk:
+			b := block{offset: offset, length: length}
+			messages, err := r.loadBlock(b)
+			if err != nil {
+				return err
+			}
+			if len(messages) > 0 {
+				b.start, b.end = messages[0].logTime, messages[0].logTime
+			}
+			for _, m := range messages {
+				count(m)
+				b.start = min(b.start, m.logTime)
+				b.end = max(b.end, m.logTime)
+			}
+			r.blocks = append(r.blocks, b)
+		}
+		offset += length
+	}
+
+	r.stats.SchemaCount = uint16(len(r.schemas))
+	r.stats.ChannelCount = uint32(len(r.channels))
+	r.sortBlocks()
+	return nil
+}
+
+func (r *Reader) sortBlocks() {
+	sort.SliceStable(r.blocks, func(i, j int) bool { return r.blocks[i].start < r.blocks[j].start })
+}
+
+func (r *Reader) addDefinition(op opcode, content []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch op {
+	case opSchema:
+		s, err := decodeSchema(content)
+		if err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+		if _, ok := r.schemas[s.ID]; !ok {
+			r.schemas[s.ID] = s
+		}
+	case opChannel:
+		c, err := decodeChannel(content)
+		if err != nil {
+			return fmt.Errorf("channel: %w", err)
+		}
+		if _, ok := r.channels[c.ID]; !ok {
+			r.channels[c.ID] = c
+		}
+	}
+	return nil
+}
+
+// loadBlock reads the messages of a block in the order they were written.
+// Schema and channel records found in chunks are registered.
+func (r *Reader) loadBlock(b block) ([]*message, error) {
+	record, err := r.readAt(b.offset, b.length)
+	if err != nil {
+		return nil, err
+	}
+	op, content, _, err := nextRecord(record)
+	if err != nil {
+		return nil, fmt.Errorf("record at %d: %w", b.offset, err)
+	}
+	switch op {
+	case opMessage:
+		m, err := decodeMessage(content)
+		if err != nil {
+			return nil, fmt.Errorf("message at %d: %w", b.offset, err)
+		}
+		return []*message{m}, nil
+	case opChunk:
+	default:
+		return nil, fmt.Errorf("unexpected record 0x%02x at %d", op, b.offset)
+	}
+
+	c, err := decodeChunk(content)
+	if err != nil {
+		return nil, fmt.Errorf("chunk at %d: %w", b.offset, err)
+	}
+	records, err := decompress(c.compression, c.records, c.uncompressedSize)
+	if err != nil {
+		return nil, fmt.Errorf("chunk at %d: %w", b.offset, err)
+	}
+	if c.uncompressedCRC != 0 && crc32.ChecksumIEEE(records) != c.uncompressedCRC {
+		return nil, fmt.Errorf("chunk at %d: checksum mismatch", b.offset)
+	}
+
+	var messages []*message
+	for len(records) > 0 {
+		op, content, rest, err := nextRecord(records)
+		if err != nil {
+			return nil, fmt.Errorf("chunk at %d: %w", b.offset, err)
+		}
+		records = rest
+		switch op {
+		case opSchema, opChannel:
+			if err := r.addDefinition(op, content); err != nil {
+				return nil, err
+			}
+		case opMessage:
+			m, err := decodeMessage(content)
+			if err != nil {
+				return nil, fmt.Errorf("chunk at %d: %w", b.offset, err)
+			}
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+// Schemas returns the schemas of the file ordered by ID.
+func (r *Reader) Schemas() []*Schema {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*Schema, 0, len(r.schemas))
+	for _, s := range r.schemas {
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b *Schema) int { return int(a.ID) - int(b.ID) })
+	return out
+}
+
+// Channels returns the channels of the file ordered by ID.
+func (r *Reader) Channels() []*Channel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*Channel, 0, len(r.channels))
+	for _, c := range r.channels {
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(a, b *Channel) int { return int(a.ID) - int(b.ID) })
+	return out
+}
+
+// Statistics returns the message counts and time span of the file.
+func (r *Reader) Statistics() Statistics {
+	return r.stats
+}
+
+// Messages iterates over the messages logged in [start, end) on the given
+// topics (all topics when none are given) in log time order. Chunks ending
+// before start are skipped using the chunk index without being read. An end
+// of 0 reads to the end of the file.
+func (r *Reader) Messages(start, end int64, topics ...string) iter.Seq2[*Message, error] {
+	from := uint64(max(start, 0))
+	return func(yield func(*Message, error) bool) {
+		var pending messageHeap
+		order := 0
+		next := 0
+		for {
+			for next < len(r.blocks) && (pending.Len() == 0 || r.blocks[next].start <= uint64(pending[0].LogTime)) {
+				b := r.blocks[next]
+				next++
+				if b.end < from || (end > 0 && b.start >= uint64(end)) {
+					continue
+				}
+				messages, err := r.loadBlock(b)
+				if err != nil {
+					yield(nil, fmt.Errorf("mcap: %w", err))
+					return
+				}
+				for _, m := range messages {
+					if m.logTime < from || (end > 0 && m.logTime >= uint64(end)) {
+						continue
+					}
+					msg, err := r.message(m)
+					if err != nil {
+						yield(nil, err)
+						return
+					}
+					if len(topics) > 0 && !slices.Contains(topics, msg.Channel.Topic) {
+						continue
+					}
+					heap.Push(&pending, pendingMessage{msg, order})
+					order++
+				}
+			}
+			if pending.Len() == 0 {
+				return
+			}
+			if !yield(heap.Pop(&pending).(pendingMessage).Message, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *Reader) message(m *message) (*Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.channels[m.channelID]
+	if !ok {
+		return nil, fmt.Errorf("mcap: message on unknown channel %d", m.channelID)
+	}
+	msg := &Message{
+		Channel:     c,
+		Sequence:    m.sequence,
+		LogTime:     int64(m.logTime),
+		PublishTime: int64(m.publishTime),
+		Data:        m.data,
+		reader:      r,
+	}
+	if c.SchemaID != 0 {
+		if msg.Schema, ok = r.schemas[c.SchemaID]; !ok {
+			return nil, fmt.Errorf("mcap: channel %s refers to unknown schema %d", c.Topic, c.SchemaID)
+		}
+	}
+	return msg, nil
+}
+
+func (r *Reader) messageType(s *Schema) (protoreflect.MessageType, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if mt, ok := r.types[s.ID]; ok {
+		return mt, nil
+	}
+	mt, err := messageType(s)
+	if err != nil {
+		return nil, err
+	}
+	r.types[s.ID] = mt
+	return mt, nil
+}
+
+// pendingMessage orders messages by log time, then by read order.
+type pendingMessage struct {
+	*Message
+	order int
+}
+
+type messageHeap []pendingMessage
+
+func (h messageHeap) Len() int { return len(h) }
+func (h messageHeap) Less(i, j int) bool {
+	if h[i].LogTime != h[j].LogTime {
+		return h[i].LogTime < h[j].LogTime
+	}
+	return h[i].order < h[j].order
+}
+func (h messageHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *messageHeap) Push(x any)   { *h = append(*h, x.(pendingMessage)) }
+func (h *messageHeap) Pop() any {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
@@ -0,0 +1,20 @@
+package mcap
+
+import (
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "mcap",
+		Extensions: []string{".mcap"},
+		Magic:      []registry.Magic{{Bytes: string(Magic)}},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
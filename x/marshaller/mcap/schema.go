@@ -0,0 +1,151 @@
+package mcap
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	core "github.com/itohio/EasyRobot/types/core"
+	mathpb "github.com/itohio/EasyRobot/types/math"
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// EncodingProtobuf is the schema and message encoding of protobuf channels.
+const EncodingProtobuf = "protobuf"
+
+// protoSchema builds a protobuf schema: the message name and a serialized
+// FileDescriptorSet holding the message's file and everything it imports.
+func protoSchema(md protoreflect.MessageDescriptor) (*Schema, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(md.ParentFile())
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Name: string(md.FullName()), Encoding: EncodingProtobuf, Data: data}, nil
+}
+
+// messageType resolves a protobuf schema. Types linked into the binary are
+// used directly; others are built from the schema's descriptors.
+func messageType(s *Schema) (protoreflect.MessageType, error) {
+	if s.Encoding != EncodingProtobuf {
+		return nil, fmt.Errorf("unsupported schema encoding %q", s.Encoding)
+	}
+	name := protoreflect.FullName(s.Name)
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(name); err == nil {
+		return mt, nil
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(s.Data, set); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", s.Name, err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", s.Name, err)
+	}
+	desc, err := files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", s.Name, err)
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("schema %s is not a message", s.Name)
+	}
+	return dynamicpb.NewMessageType(md), nil
+}
+
+// frameToProto converts a frame to the types.core.Frame message. Tensors are
+// stored as little endian raw bytes; metadata values are formatted as strings.
+func frameToProto(frame types.Frame) (*core.Frame, error) {
+	pf := &core.Frame{
+		Index:     int64(frame.Index),
+		Timestamp: frame.Timestamp,
+	}
+	for key, value := range frame.Metadata {
+		if key == metaTopic || key == metaMessage {
+			continue
+		}
+		if pf.Metadata == nil {
+			pf.Metadata = make(map[string]string)
+		}
+		if s, ok := value.(string); ok {
+			pf.Metadata[key] = s
+		} else {
+			pf.Metadata[key] = fmt.Sprint(value)
+		}
+	}
+	for i, t := range frame.Tensors {
+		if t == nil || t.Empty() {
+			continue
+		}
+		_, data, err := rawtensor.Encode(t)
+		if err != nil {
+			return nil, fmt.Errorf("tensor %d: %w", i, err)
+		}
+		shape := t.Shape()
+		pt := &mathpb.Tensor{
+			Dtype:     mathpb.DataType(t.DataType()),
+			Shape:     make([]int32, len(shape)),
+			DataBytes: data,
+		}
+		for j, d := range shape {
+			pt.Shape[j] = int32(d)
+		}
+		pf.Tensors = append(pf.Tensors, pt)
+	}
+	return pf, nil
+}
+
+// protoToFrame converts a types.core.Frame message back to a frame.
+func protoToFrame(pf *core.Frame, opts types.Options) (types.Frame, error) {
+	frame := types.Frame{
+		Index:     int(pf.Index),
+		Timestamp: pf.Timestamp,
+		Metadata:  make(map[string]any, len(pf.Metadata)+2),
+	}
+	for key, value := range pf.Metadata {
+		frame.Metadata[key] = value
+	}
+	for i, pt := range pf.Tensors {
+		shape := make(types.Shape, len(pt.Shape))
+		for j, d := range pt.Shape {
+			shape[j] = int(d)
+		}
+		e, err := rawtensor.ElemOf(types.DataType(pt.Dtype))
+		if err != nil {
+			return frame, fmt.Errorf("tensor %d: %w", i, err)
+		}
+		values, err := rawtensor.Decode(pt.DataBytes, e, binary.LittleEndian, shape.Size())
+		if err != nil {
+			return frame, fmt.Errorf("tensor %d: %w", i, err)
+		}
+		t, err := rawtensor.Wrap(shape, values, opts)
+		if err != nil {
+			return frame, fmt.Errorf("tensor %d: %w", i, err)
+		}
+		frame.Tensors = append(frame.Tensors, t)
+	}
+	return frame, nil
+}
@@ -0,0 +1,196 @@
+package mcap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	core "github.com/itohio/EasyRobot/types/core"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Unmarshaller replays MCAP recordings.
+type Unmarshaller struct {
+	opts types.Options
+}
+
+// NewUnmarshaller creates a new MCAP unmarshaller.
+func NewUnmarshaller(opts ...types.Option) *Unmarshaller {
+	u := &Unmarshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&u.opts)
+	}
+	return u
+}
+
+// Format returns the format name.
+func (u *Unmarshaller) Format() string {
+	return "mcap"
+}
+
+// Unmarshal opens a recording into *types.FrameStream (playback),
+// *[]types.Frame or **Reader.
+//
+// Sources implementing io.ReaderAt and io.Seeker (such as *os.File) are read
+// in place and must stay open while the stream or reader is in use; other
+// sources are read into memory.
+//
+// Frames are yielded in log time order across all channels. types.core.Frame
+// messages become frames with their tensors; other messages become frames
+// without tensors holding the decoded proto.Message in the "message"
+// metadata. Every frame carries "topic" and "sequence" metadata, its log time
+// as Timestamp and its position in the playback as Index.
+func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) error {
+	localOpts := u.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+	cfg, err := readOptions(localOpts)
+	if err != nil {
+		return err
+	}
+
+	reader, err := openReader(r)
+	if err != nil {
+		return types.NewError("unmarshal", "mcap", "failed to open recording", err)
+	}
+
+	switch d := dst.(type) {
+	case *types.FrameStream:
+		ctx := localOpts.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		*d = playback(ctx, reader, cfg, localOpts)
+	case *[]types.Frame:
+		var frames []types.Frame
+		for m, err := range reader.Messages(cfg.start, cfg.end, cfg.topics...) {
+			if err != nil {
+				return types.NewError("unmarshal", "mcap", "failed to read recording", err)
+			}
+			frame, err := messageToFrame(m, len(frames), localOpts)
+			if err != nil {
+				return types.NewError("unmarshal", "mcap", "failed to decode message", err)
+			}
+			frames = append(frames, frame)
+		}
+		*d = frames
+	case **Reader:
+		*d = reader
+	default:
+		return types.NewError("unmarshal", "mcap", fmt.Sprintf("unsupported destination type %T", dst), nil)
+	}
+	return nil
+}
+
+func openReader(r io.Reader) (*Reader, error) {
+	if ra, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		size, err := ra.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		return NewReader(ra, size)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// playback streams the recording, sleeping between frames when a playback
+// rate is set so that frames are yielded at rate times the recorded pace.
+func playback(baseCtx context.Context, reader *Reader, cfg readConfig, opts types.Options) types.FrameStream {
+	ctx, cancel := context.WithCancel(baseCtx)
+	output := make(chan types.Frame)
+
+	go func() {
+		defer close(output)
+		defer cancel()
+
+		send := func(frame types.Frame) bool {
+			select {
+			case output <- frame:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var firstLog int64
+		var firstWall time.Time
+		index := 0
+		for m, err := range reader.Messages(cfg.start, cfg.end, cfg.topics...) {
+			if err != nil {
+				send(errorFrame(err, index))
+				return
+			}
+			frame, err := messageToFrame(m, index, opts)
+			if err != nil {
+				send(errorFrame(err, index))
+				return
+			}
+
+			if cfg.rate > 0 {
+				if index == 0 {
+					firstLog, firstWall = m.LogTime, time.Now()
+				}
+				due := firstWall.Add(time.Duration(float64(m.LogTime-firstLog) / cfg.rate))
+				if wait := time.Until(due); wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-timer.C:
+					case <-ctx.Done():
+						timer.Stop()
+						return
+					}
+				}
+			}
+
+			if !send(frame) {
+				return
+			}
+			index++
+		}
+	}()
+
+	return types.NewFrameStream(output, cancel)
+}
+
+func messageToFrame(m *Message, index int, opts types.Options) (types.Frame, error) {
+	msg, err := m.Decode()
+	if err != nil {
+		return types.Frame{}, err
+	}
+
+	var frame types.Frame
+	if pf, ok := msg.(*core.Frame); ok {
+		if frame, err = protoToFrame(pf, opts); err != nil {
+			return frame, fmt.Errorf("mcap: %s: %w", m.Channel.Topic, err)
+		}
+	} else {
+		frame.Metadata = map[string]any{metaMessage: msg}
+	}
+	frame.Index = index
+	frame.Timestamp = m.LogTime
+	frame.Metadata[metaTopic] = m.Channel.Topic
+	frame.Metadata[metaSequence] = m.Sequence
+	return frame, nil
+}
+
+func errorFrame(err error, index int) types.Frame {
+	return types.Frame{
+		Index:     index,
+		Timestamp: time.Now().UnixNano(),
+		Metadata: map[string]any{
+			"error": err,
+		},
+	}
+}
@@ -0,0 +1,324 @@
+package mcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strconv"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	library          = "EasyRobot"
+	defaultChunkSize = 1 << 20
+)
+
+// Writer records protobuf messages and frames to an MCAP file.
+//
+// Messages are grouped into chunks of roughly the configured chunk size,
+// each followed by per-channel message indexes. Close writes the summary
+// section (schemas, channels, chunk indexes and statistics) that Reader
+// uses for seeking. Chunks are stored uncompressed unless WithCompression
+// selects zstd or lz4.
+type Writer struct {
+	out         *bufio.Writer
+	offset      uint64
+	crc         hash.Hash32
+	chunkSize   int
+	compression string
+
+	schemas  []*Schema
+	schemaID map[protoreflect.FullName]uint16
+	channels []*Channel
+	topics   map[string]*Channel
+	sequence map[uint16]uint32
+
+	chunk      []byte
+	chunkStart uint64
+	chunkEnd   uint64
+	chunkIndex map[uint16][]indexEntry
+
+	chunkIndexes []*chunkIndex
+	stats        Statistics
+	err          error
+	closed       bool
+}
+
+// NewWriter writes the MCAP preamble to w and returns a writer for it.
+// Use WithChunkSize to change the default chunk size of 1 MiB.
+func NewWriter(w io.Writer, opts ...types.Option) (*Writer, error) {
+	var cfg types.Options
+	for _, opt := range opts {
+		opt.Apply(&cfg)
+	}
+	chunkSize := defaultChunkSize
+	if v, ok := cfg.Metadata[chunkSizeKey]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("mcap: invalid chunk size %q", v)
+		}
+		chunkSize = n
+	}
+	compression := cfg.Metadata[compressionKey]
+	if err := validCompression(compression); err != nil {
+		return nil, fmt.Errorf("mcap: %w", err)
+	}
+
+	wr := &Writer{
+		out:         bufio.NewWriter(w),
+		crc:         crc32.NewIEEE(),
+		chunkSize:   chunkSize,
+		compression: compression,
+		schemaID:    make(map[protoreflect.FullName]uint16),
+		topics:      make(map[string]*Channel),
+		sequence:    make(map[uint16]uint32),
+		chunkIndex:  make(map[uint16][]indexEntry),
+		stats:       Statistics{ChannelMessageCounts: make(map[uint16]uint64)},
+	}
+	wr.write(Magic)
+	wr.write(appendRecord(nil, opHeader, encodeHeader("", library)))
+	if wr.err != nil {
+		return nil, wr.err
+	}
+	return wr, nil
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	if _, err := w.out.Write(b); err != nil {
+		w.err = err
+		return
+	}
+	w.crc.Write(b)
+	w.offset += uint64(len(b))
+}
+
+// AddChannel registers a protobuf channel for topic with the schema of msg.
+// Adding a topic again returns the existing channel if the message types
+// match. Channels are added implicitly by Write.
+func (w *Writer) AddChannel(topic string, msg proto.Message, metadata map[string]string) (*Channel, error) {
+	if w.closed {
+		return nil, fmt.Errorf("mcap: writer is closed")
+	}
+	md := msg.ProtoReflect().Descriptor()
+	if c, ok := w.topics[topic]; ok {
+		if s := w.schemas[c.SchemaID-1]; s.Name != string(md.FullName()) {
+			return nil, fmt.Errorf("mcap: topic %s carries %s, not %s", topic, s.Name, md.FullName())
+		}
+		return c, nil
+	}
+
+	id, ok := w.schemaID[md.FullName()]
+	if !ok {
+		s, err := protoSchema(md)
+		if err != nil {
+			return nil, fmt.Errorf("mcap: schema %s: %w", md.FullName(), err)
+		}
+		s.ID = uint16(len(w.schemas) + 1)
+		w.schemas = append(w.schemas, s)
+		w.schemaID[md.FullName()] = s.ID
+		w.addToChunk(appendRecord(nil, opSchema, encodeSchema(s)))
+		id = s.ID
+	}
+
+	c := &Channel{
+		ID:              uint16(len(w.channels)),
+		SchemaID:        id,
+		Topic:           topic,
+		MessageEncoding: EncodingProtobuf,
+		Metadata:        metadata,
+	}
+	w.channels = append(w.channels, c)
+	w.topics[topic] = c
+	w.addToChunk(appendRecord(nil, opChannel, encodeChannel(c)))
+	return c, w.err
+}
+
+// Write records msg on topic at logTime (nanoseconds).
+func (w *Writer) Write(topic string, logTime int64, msg proto.Message) error {
+	if logTime < 0 {
+		return fmt.Errorf("mcap: negative log time %d", logTime)
+	}
+	c, err := w.AddChannel(topic, msg, nil)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("mcap: %s: %w", topic, err)
+	}
+
+	m := &message{
+		channelID:   c.ID,
+		sequence:    w.sequence[c.ID],
+		logTime:     uint64(logTime),
+		publishTime: uint64(logTime),
+		data:        data,
+	}
+	w.sequence[c.ID]++
+
+	if len(w.chunkIndex) == 0 || m.logTime < w.chunkStart {
+		w.chunkStart = m.logTime
+	}
+	if m.logTime > w.chunkEnd {
+		w.chunkEnd = m.logTime
+	}
+	w.chunkIndex[c.ID] = append(w.chunkIndex[c.ID], indexEntry{logTime: m.logTime, offset: uint64(len(w.chunk))})
+	w.addToChunk(appendMessage(nil, m))
+
+	if w.stats.MessageCount == 0 || m.logTime < w.stats.MessageStartTime {
+		w.stats.MessageStartTime = m.logTime
+	}
+	if m.logTime > w.stats.MessageEndTime {
+		w.stats.MessageEndTime = m.logTime
+	}
+	w.stats.MessageCount++
+	w.stats.ChannelMessageCounts[c.ID]++
+	return w.err
+}
+
+// WriteFrame records a frame on topic as a types.core.Frame message
+// timestamped with frame.Timestamp.
+func (w *Writer) WriteFrame(topic string, frame types.Frame) error {
+	pf, err := frameToProto(frame)
+	if err != nil {
+		return fmt.Errorf("mcap: %s: %w", topic, err)
+	}
+	return w.Write(topic, frame.Timestamp, pf)
+}
+
+func (w *Writer) addToChunk(record []byte) {
+	w.chunk = append(w.chunk, record...)
+	if len(w.chunk) >= w.chunkSize {
+		w.flushChunk()
+	}
+}
+
+// flushChunk writes the pending chunk and its message indexes.
+func (w *Writer) flushChunk() {
+	if len(w.chunk) == 0 {
+		return
+	}
+	records, err := compress(w.compression, w.chunk)
+	if err != nil {
+		w.err = err
+		return
+	}
+	c := &chunk{
+		messageStartTime: w.chunkStart,
+		messageEndTime:   w.chunkEnd,
+		uncompressedSize: uint64(len(w.chunk)),
+		uncompressedCRC:  crc32.ChecksumIEEE(w.chunk),
+		compression:      w.compression,
+		records:          records,
+	}
+	if len(w.chunkIndex) == 0 {
+		// schema and channel records only
+		c.messageStartTime, c.messageEndTime = 0, 0
+	}
+	index := &chunkIndex{
+		messageStartTime:    c.messageStartTime,
+		messageEndTime:      c.messageEndTime,
+		chunkStartOffset:    w.offset,
+		messageIndexOffsets: make(map[uint16]uint64),
+		compression:         c.compression,
+		compressedSize:      uint64(len(records)),
+		uncompressedSize:    c.uncompressedSize,
+	}
+	w.write(appendRecord(nil, opChunk, encodeChunk(c)))
+	index.chunkLength = w.offset - index.chunkStartOffset
+
+	indexStart := w.offset
+	for _, ch := range w.channels {
+		entries, ok := w.chunkIndex[ch.ID]
+		if !ok {
+			continue
+		}
+		index.messageIndexOffsets[ch.ID] = w.offset
+		w.write(appendRecord(nil, opMessageIndex, encodeMessageIndex(ch.ID, entries)))
+	}
+	index.messageIndexLength = w.offset - indexStart
+
+	w.chunkIndexes = append(w.chunkIndexes, index)
+	w.chunk = w.chunk[:0]
+	w.chunkStart, w.chunkEnd = 0, 0
+	clear(w.chunkIndex)
+}
+
+// Close flushes the last chunk and writes the summary and footer. It does
+// not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.flushChunk()
+	w.closed = true
+
+	w.write(appendRecord(nil, opDataEnd, binary.LittleEndian.AppendUint32(nil, w.crc.Sum32())))
+
+	summaryStart := w.offset
+	w.crc.Reset()
+	type group struct {
+		op            opcode
+		start, length uint64
+	}
+	var groups []group
+	writeGroup := func(op opcode, records [][]byte) {
+		if len(records) == 0 {
+			return
+		}
+		start := w.offset
+		for _, r := range records {
+			w.write(appendRecord(nil, op, r))
+		}
+		groups = append(groups, group{op, start, w.offset - start})
+	}
+
+	var records [][]byte
+	for _, s := range w.schemas {
+		records = append(records, encodeSchema(s))
+	}
+	writeGroup(opSchema, records)
+	records = records[:0]
+	for _, c := range w.channels {
+		records = append(records, encodeChannel(c))
+	}
+	writeGroup(opChannel, records)
+	records = records[:0]
+	for _, c := range w.chunkIndexes {
+		records = append(records, encodeChunkIndex(c))
+	}
+	writeGroup(opChunkIndex, records)
+
+	w.stats.SchemaCount = uint16(len(w.schemas))
+	w.stats.ChannelCount = uint32(len(w.channels))
+	w.stats.ChunkCount = uint32(len(w.chunkIndexes))
+	writeGroup(opStatistics, [][]byte{encodeStatistics(&w.stats)})
+
+	summaryOffsetStart := w.offset
+	for _, g := range groups {
+		w.write(appendRecord(nil, opSummaryOffset, encodeSummaryOffset(g.op, g.start, g.length)))
+	}
+
+	// The summary CRC covers the footer up to and including summary_offset_start.
+	f := footer{summaryStart: summaryStart, summaryOffsetStart: summaryOffsetStart}
+	record := appendRecord(nil, opFooter, encodeFooter(f))
+	w.crc.Write(record[:len(record)-4])
+	f.summaryCRC = w.crc.Sum32()
+	w.write(appendRecord(nil, opFooter, encodeFooter(f)))
+	w.write(Magic)
+
+	if w.err == nil {
+		w.err = w.out.Flush()
+	}
+	return w.err
+}
@@ -0,0 +1,244 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/document"
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// maxDepth bounds the nesting of arrays and maps.
+const maxDepth = 512
+
+// decoder reads documents from an in-memory message. Tensors may share
+// memory with buf.
+type decoder struct {
+	buf   []byte
+	pos   int
+	depth int
+	opts  types.Options
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.buf)-d.pos {
+		return nil, fmt.Errorf("msgpack: unexpected end of data at offset %d", d.pos)
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads an n byte big endian argument.
+func (d *decoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// count reads an n byte length and checks that at least min bytes per item remain.
+func (d *decoder) count(n, min int) (int, error) {
+	v, err := d.uint(n)
+	if err != nil {
+		return 0, err
+	}
+	if v > uint64(len(d.buf)-d.pos)/uint64(min) {
+		return 0, fmt.Errorf("msgpack: length %d exceeds the data at offset %d", v, d.pos)
+	}
+	return int(v), nil
+}
+
+func (d *decoder) decode() (any, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c <= 0x8f:
+		return d.decodeMap(int(c & 0x0f))
+	case c <= 0x9f:
+		return d.decodeArray(int(c & 0x0f))
+	case c <= 0xbf:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.count(1<<(c-0xc4), 1)
+		if err != nil {
+			return nil, err
+		}
+		p, err := d.next(n)
+		return append([]byte(nil), p...), err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.count(1<<(c-0xc7), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(n)
+	case 0xca:
+		v, err := d.uint(4)
+		return math.Float32frombits(uint32(v)), err
+	case 0xcb:
+		v, err := d.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (c - 0xd0)
+		v, err := d.uint(n)
+		if err != nil {
+			return nil, err
+		}
+		// sign extend
+		shift := 64 - 8*n
+		return int64(v<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.count(1<<(c-0xd9), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xdc, 0xdd:
+		n, err := d.count(2<<(c-0xdc), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.count(2<<(c-0xde), 2)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("msgpack: invalid type byte 0x%02x at offset %d", c, d.pos-1)
+}
+
+func (d *decoder) decodeString(n int) (any, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *decoder) enter() error {
+	if d.depth++; d.depth > maxDepth {
+		return fmt.Errorf("msgpack: nesting deeper than %d", maxDepth)
+	}
+	return nil
+}
+
+func (d *decoder) decodeArray(n int) (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+	list := make([]any, n)
+	for i := range list {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func (d *decoder) decodeMap(n int) (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+	doc := make(document.Document, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		switch k := k.(type) {
+		case string:
+			key = k
+		case int64:
+			key = strconv.FormatInt(k, 10)
+		case uint64:
+			key = strconv.FormatUint(k, 10)
+		default:
+			return nil, fmt.Errorf("msgpack: unsupported map key %T", k)
+		}
+		if doc[key], err = d.decode(); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func (d *decoder) decodeExt(n int) (any, error) {
+	typ, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	p, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != ExtTensor {
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d", int8(typ[0]))
+	}
+	return decodeTensor(p, d.opts)
+}
+
+// decodeTensor parses the payload of a tensor extension.
+func decodeTensor(p []byte, opts types.Options) (types.Tensor, error) {
+	if len(p) < 4 {
+		return nil, fmt.Errorf("msgpack: tensor header truncated")
+	}
+	header, ndim := int(p[0]), int(p[3])
+	if header < 4+4*ndim || header > len(p) {
+		return nil, fmt.Errorf("msgpack: invalid tensor header of %d bytes for %d dimensions", header, ndim)
+	}
+	elem := rawtensor.Elem{Kind: rawtensor.Kind(p[1]), Size: int(p[2])}
+	if elem.Size == 0 {
+		return nil, fmt.Errorf("msgpack: invalid tensor element %v", elem)
+	}
+	data := p[header:]
+	shape := make(types.Shape, ndim)
+	n := 1
+	for i := range shape {
+		dim := binary.LittleEndian.Uint32(p[4+4*i:])
+		if dim != 0 && uint64(n)*uint64(dim) > uint64(len(data)) {
+			return nil, fmt.Errorf("msgpack: tensor shape exceeds its %d bytes", len(data))
+		}
+		shape[i] = int(dim)
+		n *= int(dim)
+	}
+	return document.Tensor(elem, binary.LittleEndian, shape, data, opts)
+}
@@ -0,0 +1,26 @@
+// Package msgpack implements MessagePack marshalling/unmarshalling for
+// EasyRobot domain objects: tensors, mat and vec matrices and vectors,
+// layers, models, graphs and trees (see internal/document for the layout).
+//
+// Every message is a map {"schema": "easyrobot", "version": 1, "value": ...}
+// with keys in sorted order. Tensors are stored as extension type ExtTensor
+// whose payload is
+//
+//	offset 0     uint8      header size H, padding included
+//	offset 1     uint8      element kind as in NumPy type strings: 'f', 'i' or 'u'
+//	offset 2     uint8      element size in bytes
+//	offset 3     uint8      number of dimensions N
+//	offset 4     uint32[N]  dimensions, little endian
+//	offset H     elements in row-major order, little endian
+//
+// so that, for example, Python reads them with
+//
+//	h, kind, size, ndim = payload[:4]
+//	shape = struct.unpack_from(f"<{ndim}I", payload, 4)
+//	array = numpy.frombuffer(payload, f"<{chr(kind)}{size}", offset=h).reshape(shape)
+//
+// The header is padded so that the elements are aligned to their size
+// relative to the start of the message. Marshal writes the elements straight
+// from the tensor storage, and Unmarshal returns tensors sharing memory with
+// the message it read unless a tensor factory or destination type is set.
+package msgpack
@@ -0,0 +1,223 @@
+package msgpack
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// encoder writes documents, keeping track of the stream position so that
+// tensor elements can be aligned.
+type encoder struct {
+	w   *bufio.Writer
+	pos int
+	buf [9]byte
+}
+
+func (e *encoder) write(p []byte) error {
+	n, err := e.w.Write(p)
+	e.pos += n
+	return err
+}
+
+// head writes a type byte followed by an n byte big endian argument.
+func (e *encoder) head(b byte, v uint64, n int) error {
+	e.buf[0] = b
+	for i := 0; i < n; i++ {
+		e.buf[n-i] = byte(v >> (8 * i))
+	}
+	return e.write(e.buf[:1+n])
+}
+
+func (e *encoder) encode(v any) error {
+	switch x := v.(type) {
+	case nil:
+		return e.head(0xc0, 0, 0)
+	case bool:
+		if x {
+			return e.head(0xc3, 0, 0)
+		}
+		return e.head(0xc2, 0, 0)
+	case int:
+		return e.encodeInt(int64(x))
+	case int8:
+		return e.encodeInt(int64(x))
+	case int16:
+		return e.encodeInt(int64(x))
+	case int32:
+		return e.encodeInt(int64(x))
+	case int64:
+		return e.encodeInt(x)
+	case uint:
+		return e.encodeUint(uint64(x))
+	case uint8:
+		return e.encodeUint(uint64(x))
+	case uint16:
+		return e.encodeUint(uint64(x))
+	case uint32:
+		return e.encodeUint(uint64(x))
+	case uint64:
+		return e.encodeUint(x)
+	case float32:
+		return e.head(0xca, uint64(math.Float32bits(x)), 4)
+	case float64:
+		return e.head(0xcb, math.Float64bits(x), 8)
+	case string:
+		if err := e.length(len(x), 0xa0, 31, 0xd9, 0xda, 0xdb); err != nil {
+			return err
+		}
+		_, err := e.w.WriteString(x)
+		e.pos += len(x)
+		return err
+	case []byte:
+		if err := e.length(len(x), 0, -1, 0xc4, 0xc5, 0xc6); err != nil {
+			return err
+		}
+		return e.write(x)
+	case []any:
+		if err := e.length(len(x), 0x90, 15, 0, 0xdc, 0xdd); err != nil {
+			return err
+		}
+		for _, item := range x {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		if err := e.length(len(x), 0x80, 15, 0, 0xde, 0xdf); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := e.encode(k); err != nil {
+				return err
+			}
+			if err := e.encode(x[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case types.Tensor:
+		return e.encodeTensor(x)
+	}
+	return fmt.Errorf("msgpack: unsupported type %T", v)
+}
+
+func (e *encoder) encodeInt(n int64) error {
+	switch {
+	case n >= 0:
+		return e.encodeUint(uint64(n))
+	case n >= -32:
+		return e.head(byte(n), 0, 0)
+	case n >= math.MinInt8:
+		return e.head(0xd0, uint64(n), 1)
+	case n >= math.MinInt16:
+		return e.head(0xd1, uint64(n), 2)
+	case n >= math.MinInt32:
+		return e.head(0xd2, uint64(n), 4)
+	}
+	return e.head(0xd3, uint64(n), 8)
+}
+
+func (e *encoder) encodeUint(n uint64) error {
+	switch {
+	case n <= 0x7f:
+		return e.head(byte(n), 0, 0)
+	case n <= math.MaxUint8:
+		return e.head(0xcc, n, 1)
+	case n <= math.MaxUint16:
+		return e.head(0xcd, n, 2)
+	case n <= math.MaxUint32:
+		return e.head(0xce, n, 4)
+	}
+	return e.head(0xcf, n, 8)
+}
+
+// length writes the header of a string, binary, array or map of n items:
+// fix|n up to fixMax, otherwise the 8 (if any), 16 or 32 bit length form.
+func (e *encoder) length(n int, fix byte, fixMax int, b8, b16, b32 byte) error {
+	switch {
+	case n <= fixMax:
+		return e.head(fix|byte(n), 0, 0)
+	case n <= math.MaxUint8 && b8 != 0:
+		return e.head(b8, uint64(n), 1)
+	case n <= math.MaxUint16:
+		return e.head(b16, uint64(n), 2)
+	case uint64(n) <= math.MaxUint32:
+		return e.head(b32, uint64(n), 4)
+	}
+	return fmt.Errorf("msgpack: length %d too large", n)
+}
+
+// encodeTensor writes a tensor extension (see ExtTensor). The header is
+// padded so that the elements start at a multiple of their size from the
+// start of the stream.
+func (e *encoder) encodeTensor(t types.Tensor) error {
+	elem, data, err := rawtensor.Bytes(t)
+	if err != nil {
+		return err
+	}
+	shape := t.Shape()
+	if len(shape) > MaxDims {
+		return fmt.Errorf("msgpack: %d dimensions, at most %d supported", len(shape), MaxDims)
+	}
+	header := 4 + 4*len(shape)
+	pad, head := 0, 0
+	for range 3 {
+		head = extHeadSize(header + pad + len(data))
+		pad = (elem.Size - (e.pos+head+header)%elem.Size) % elem.Size
+	}
+	size := header + pad + len(data)
+	if uint64(size) > math.MaxUint32 {
+		return fmt.Errorf("msgpack: tensor of %d bytes too large", len(data))
+	}
+
+	switch extHeadSize(size) {
+	case 3:
+		err = e.head(0xc7, uint64(size), 1)
+	case 4:
+		err = e.head(0xc8, uint64(size), 2)
+	default:
+		err = e.head(0xc9, uint64(size), 4)
+	}
+	if err != nil {
+		return err
+	}
+	hdr := make([]byte, header+pad+1)
+	hdr[0] = ExtTensor
+	hdr[1] = byte(header + pad)
+	hdr[2] = byte(elem.Kind)
+	hdr[3] = byte(elem.Size)
+	hdr[4] = byte(len(shape))
+	for i, dim := range shape {
+		if dim < 0 || uint64(dim) > math.MaxUint32 {
+			return fmt.Errorf("msgpack: dimension %d out of range", dim)
+		}
+		binary.LittleEndian.PutUint32(hdr[5+4*i:], uint32(dim))
+	}
+	if err := e.write(hdr); err != nil {
+		return err
+	}
+	return e.write(data)
+}
+
+// extHeadSize is the size of the ext8/16/32 head, type byte included.
+func extHeadSize(n int) int {
+	switch {
+	case n <= math.MaxUint8:
+		return 3
+	case n <= math.MaxUint16:
+		return 4
+	}
+	return 6
+}
@@ -0,0 +1,61 @@
+package msgpack
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/document"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+)
+
+// Marshaller implements MessagePack marshalling.
+type Marshaller struct {
+	opts types.Options
+}
+
+// NewMarshaller creates a new MessagePack marshaller.
+func NewMarshaller(opts ...types.Option) *Marshaller {
+	m := &Marshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&m.opts)
+	}
+	return m
+}
+
+// Format returns the format name.
+func (m *Marshaller) Format() string {
+	return "msgpack"
+}
+
+// Marshal encodes value to MessagePack.
+func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	localOpts := m.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+
+	if value == nil {
+		return types.NewError("marshal", "msgpack", "nil value", nil)
+	}
+	version, err := versioning.Target(schemaName, localOpts, SchemaVersion)
+	if err != nil {
+		return types.NewError("marshal", "msgpack", "version", err)
+	}
+
+	doc, err := document.Encode(value)
+	if err != nil {
+		return types.NewError("marshal", "msgpack", "value conversion", err)
+	}
+
+	enc := &encoder{w: bufio.NewWriter(w)}
+	if err := enc.encode(document.Wrap(doc, version)); err != nil {
+		return types.NewError("marshal", "msgpack", "encoding", err)
+	}
+	if err := enc.w.Flush(); err != nil {
+		return types.NewError("marshal", "msgpack", "encoding", err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package msgpack
+
+import (
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "msgpack",
+		Extensions: []string{".msgpack", ".mpk"},
+		// the envelope map starts with its "schema" key
+		Magic: []registry.Magic{{Bytes: "\x83\xa6schema"}},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
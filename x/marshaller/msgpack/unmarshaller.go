@@ -0,0 +1,66 @@
+package msgpack
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/document"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Unmarshaller implements MessagePack unmarshalling.
+type Unmarshaller struct {
+	opts types.Options
+}
+
+// NewUnmarshaller creates a new MessagePack unmarshaller.
+func NewUnmarshaller(opts ...types.Option) *Unmarshaller {
+	u := &Unmarshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&u.opts)
+	}
+	return u
+}
+
+// Format returns the format name.
+func (u *Unmarshaller) Format() string {
+	return "msgpack"
+}
+
+// Unmarshal decodes a MessagePack message into dst. Matrices and vectors
+// decode to their mat/vec types, graphs to *graph.GenericGraph[any, any]
+// and trees to *graph.GenericTree[any, any].
+func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) error {
+	localOpts := u.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return types.NewError("unmarshal", "msgpack", "reading", err)
+	}
+	d := &decoder{buf: data, opts: localOpts}
+	v, err := d.decode()
+	if err == nil && d.pos != len(data) {
+		err = fmt.Errorf("%d trailing bytes", len(data)-d.pos)
+	}
+	if err != nil {
+		return types.NewError("unmarshal", "msgpack", "decoding", err)
+	}
+
+	doc, err := document.Unwrap(schemaName, v, SchemaVersion, localOpts)
+	if err != nil {
+		return types.NewError("unmarshal", "msgpack", "decoding", err)
+	}
+	value, err := document.Decode(doc, localOpts)
+	if err != nil {
+		return types.NewError("unmarshal", "msgpack", "conversion", err)
+	}
+	if err := document.Assign(dst, value); err != nil {
+		return types.NewError("unmarshal", "msgpack", "assignment", err)
+	}
+	return nil
+}
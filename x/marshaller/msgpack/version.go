@@ -0,0 +1,13 @@
+package msgpack
+
+// SchemaVersion is the payload version written by Marshal.
+const SchemaVersion = 1
+
+const schemaName = "msgpack"
+
+const (
+	// ExtTensor is the MessagePack extension type of tensors.
+	ExtTensor = 1
+	// MaxDims is the largest number of tensor dimensions.
+	MaxDims = 32
+)
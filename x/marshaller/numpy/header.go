@@ -0,0 +1,226 @@
+package numpy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+)
+
+// npyMagic starts every .npy file.
+const npyMagic = "\x93NUMPY"
+
+// npyAlign is the alignment of the array data within the file.
+const npyAlign = 64
+
+// maxHeaderSize rejects longer header dictionaries, as numpy.load does.
+const maxHeaderSize = 10000
+
+// header is the decoded .npy array description.
+type header struct {
+	elem    rawtensor.Elem
+	order   binary.ByteOrder
+	fortran bool
+	shape   []int
+}
+
+// readHeader reads the magic, version and header dictionary of a .npy stream.
+func readHeader(r io.Reader) (header, error) {
+	var h header
+	pre := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r, pre); err != nil {
+		return h, fmt.Errorf("reading magic: %w", err)
+	}
+	if string(pre[:len(npyMagic)]) != npyMagic {
+		return h, fmt.Errorf("not a .npy file")
+	}
+
+	var size int
+	switch major := pre[len(npyMagic)]; major {
+	case 1:
+		var n uint16
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return h, fmt.Errorf("reading header length: %w", err)
+		}
+		size = int(n)
+	case 2, 3:
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return h, fmt.Errorf("reading header length: %w", err)
+		}
+		size = int(n)
+	default:
+		return h, fmt.Errorf("unsupported .npy version %d", major)
+	}
+	if size > maxHeaderSize {
+		return h, fmt.Errorf("header of %d bytes exceeds %d", size, maxHeaderSize)
+	}
+
+	dict := make([]byte, size)
+	if _, err := io.ReadFull(r, dict); err != nil {
+		return h, fmt.Errorf("reading header: %w", err)
+	}
+	return parseHeader(string(dict))
+}
+
+// parseHeader parses the Python dict literal of a .npy header, e.g.
+// {'descr': '<f4', 'fortran_order': False, 'shape': (2, 3), }
+func parseHeader(dict string) (header, error) {
+	var h header
+	descr, err := dictValue(dict, "descr")
+	if err != nil {
+		return h, err
+	}
+	if !strings.HasPrefix(descr, "'") {
+		return h, fmt.Errorf("structured dtype %s is not supported", descr)
+	}
+	if h.elem, h.order, err = parseDescr(strings.Trim(descr, "'")); err != nil {
+		return h, err
+	}
+
+	fortran, err := dictValue(dict, "fortran_order")
+	if err != nil {
+		return h, err
+	}
+	h.fortran = fortran == "True"
+
+	shape, err := dictValue(dict, "shape")
+	if err != nil {
+		return h, err
+	}
+	shape = strings.Trim(shape, "()")
+	for _, dim := range strings.Split(shape, ",") {
+		if dim = strings.TrimSpace(dim); dim == "" {
+			continue
+		}
+		n, err := strconv.Atoi(dim)
+		if err != nil || n < 0 {
+			return h, fmt.Errorf("invalid shape dimension %q", dim)
+		}
+		h.shape = append(h.shape, n)
+	}
+	if _, _, err := h.dataSize(); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+// dictValue extracts the literal value of key from a flat Python dict.
+func dictValue(dict, key string) (string, error) {
+	i := strings.Index(dict, "'"+key+"'")
+	if i < 0 {
+		return "", fmt.Errorf("header has no %q", key)
+	}
+	rest := strings.TrimSpace(dict[i+len(key)+2:])
+	if !strings.HasPrefix(rest, ":") {
+		return "", fmt.Errorf("malformed header near %q", key)
+	}
+	rest = strings.TrimSpace(rest[1:])
+	if rest == "" {
+		return "", fmt.Errorf("malformed header near %q", key)
+	}
+
+	end := -1
+	switch rest[0] {
+	case '\'':
+		end = strings.IndexByte(rest[1:], '\'') + 2
+	case '(':
+		end = strings.IndexByte(rest, ')') + 1
+	case '[':
+		end = strings.IndexByte(rest, ']') + 1
+	default:
+		end = strings.IndexAny(rest, ",}")
+	}
+	if end <= 0 {
+		return "", fmt.Errorf("malformed header near %q", key)
+	}
+	return strings.TrimSpace(rest[:end]), nil
+}
+
+// parseDescr parses a NumPy type string such as "<f4", "|u1" or ">i8".
+func parseDescr(descr string) (rawtensor.Elem, binary.ByteOrder, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if len(descr) < 3 {
+		return rawtensor.Elem{}, nil, fmt.Errorf("invalid dtype %q", descr)
+	}
+	switch descr[0] {
+	case '<', '|', '=':
+	case '>':
+		order = binary.BigEndian
+	default:
+		return rawtensor.Elem{}, nil, fmt.Errorf("invalid dtype %q", descr)
+	}
+	size, err := strconv.Atoi(descr[2:])
+	if err != nil {
+		return rawtensor.Elem{}, nil, fmt.Errorf("invalid dtype %q", descr)
+	}
+	e := rawtensor.Elem{Kind: rawtensor.Kind(descr[1]), Size: size}
+	if _, err := e.DataType(); err != nil {
+		return e, nil, fmt.Errorf("unsupported dtype %q", descr)
+	}
+	return e, order, nil
+}
+
+// formatDescr returns the NumPy type string of a little endian element type.
+func formatDescr(e rawtensor.Elem) string {
+	if e.Size == 1 {
+		return fmt.Sprintf("|%c1", e.Kind)
+	}
+	return fmt.Sprintf("<%c%d", e.Kind, e.Size)
+}
+
+// writeHeader writes the magic, version and padded header dictionary.
+func writeHeader(w io.Writer, e rawtensor.Elem, shape []int) error {
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		dims[i] = strconv.Itoa(d)
+	}
+	s := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		s += "," // Python one-element tuple
+	}
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", formatDescr(e), s)
+
+	// Version 1.0 stores the header length in 16 bits, 2.0 in 32 bits
+	major, lenSize := byte(1), 2
+	if len(dict)+len(npyMagic)+2+lenSize+1 > 0xffff {
+		major, lenSize = 2, 4
+	}
+	total := len(npyMagic) + 2 + lenSize + len(dict) + 1
+	pad := (npyAlign - total%npyAlign) % npyAlign
+	dict += strings.Repeat(" ", pad) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.WriteByte(major)
+	buf.WriteByte(0)
+	if major == 1 {
+		binary.Write(&buf, binary.LittleEndian, uint16(len(dict)))
+	} else {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(dict)))
+	}
+	buf.WriteString(dict)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// dataSize returns the number of elements and the byte size of the array
+// data, failing when the shape overflows.
+func (h header) dataSize() (n, size int, err error) {
+	n = 1
+	for _, d := range h.shape {
+		if d != 0 && n > math.MaxInt/d {
+			return 0, 0, fmt.Errorf("shape %v overflows", h.shape)
+		}
+		n *= d
+	}
+	if n > math.MaxInt/h.elem.Size {
+		return 0, 0, fmt.Errorf("shape %v overflows", h.shape)
+	}
+	return n, n * h.elem.Size, nil
+}
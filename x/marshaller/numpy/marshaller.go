@@ -0,0 +1,79 @@
+// Package numpy implements marshallers for the NumPy .npy and .npz formats.
+//
+// A tensor is written as a single .npy array; a map of named tensors or the
+// state dict of a model (see models.StateDict) is written as a .npz archive.
+// The unmarshaller detects the format from the stream contents.
+package numpy
+
+import (
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+)
+
+const compressKey = "numpy.compress"
+
+type withCompression struct{}
+
+func (withCompression) Apply(opts *types.Options) {
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]string)
+	}
+	opts.Metadata[compressKey] = "true"
+}
+
+// WithCompression deflates the entries of .npz archives like numpy.savez_compressed.
+func WithCompression() types.Option {
+	return withCompression{}
+}
+
+// Marshaller implements .npy/.npz marshalling.
+type Marshaller struct {
+	opts types.Options
+}
+
+// NewMarshaller creates a new NumPy marshaller.
+func NewMarshaller(opts ...types.Option) *Marshaller {
+	m := &Marshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&m.opts)
+	}
+	return m
+}
+
+// Format returns the format name.
+func (m *Marshaller) Format() string {
+	return "numpy"
+}
+
+// Marshal writes a tensor as .npy, and a map[string]types.Tensor or a model as .npz.
+func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	localOpts := m.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+	compress := localOpts.Metadata[compressKey] == "true"
+
+	switch v := value.(type) {
+	case nil:
+		return types.NewError("marshal", "numpy", "nil value", nil)
+	case types.Model:
+		if err := writeNPZ(w, models.StateDict(v), compress); err != nil {
+			return types.NewError("marshal", "numpy", "npz", err)
+		}
+	case types.Tensor:
+		if err := writeNPY(w, v); err != nil {
+			return types.NewError("marshal", "numpy", "npy", err)
+		}
+	case map[string]types.Tensor:
+		if err := writeNPZ(w, v, compress); err != nil {
+			return types.NewError("marshal", "numpy", "npz", err)
+		}
+	default:
+		return types.NewError("marshal", "numpy", "unsupported value type", nil)
+	}
+	return nil
+}
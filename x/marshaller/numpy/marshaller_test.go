@@ -0,0 +1,231 @@
+package numpy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+func TestNPYRoundTrip(t *testing.T) {
+	for _, dt := range []types.DataType{types.FP32, types.FP64, types.INT8, types.INT16, types.INT32, types.INT64, types.UINT8} {
+		src := tensor.New(dt, tensor.NewShape(2, 3))
+		setValues(src, []float64{0, 7, 3, 10, 6, 2})
+
+		var buf bytes.Buffer
+		if err := NewMarshaller().Marshal(&buf, src); err != nil {
+			t.Fatalf("%v: Marshal failed: %v", dt, err)
+		}
+		if headerLen := bytes.IndexByte(buf.Bytes(), '\n') + 1; headerLen%npyAlign != 0 {
+			t.Errorf("%v: data starts at %d, not %d byte aligned", dt, headerLen, npyAlign)
+		}
+
+		var dst types.Tensor
+		if err := NewUnmarshaller().Unmarshal(&buf, &dst); err != nil {
+			t.Fatalf("%v: Unmarshal failed: %v", dt, err)
+		}
+		if dst.DataType() != dt || !dst.Shape().Equal(src.Shape()) {
+			t.Fatalf("%v: got %v %v", dt, dst.DataType(), dst.Shape())
+		}
+		if got, want := values(dst), values(src); !equal(got, want) {
+			t.Errorf("%v: got %v, want %v", dt, got, want)
+		}
+	}
+}
+
+func TestNPYHeader(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMarshaller().Marshal(&buf, tensor.FromFloat32(tensor.NewShape(3), []float32{1, 2, 3})); err != nil {
+		t.Fatal(err)
+	}
+	header := buf.String()[:bytes.IndexByte(buf.Bytes(), '\n')]
+	if !strings.Contains(header, "{'descr': '<f4', 'fortran_order': False, 'shape': (3,), }") {
+		t.Errorf("unexpected header %q", header)
+	}
+}
+
+// npy builds a version 1.0 .npy file from a header dict and raw data.
+func npy(dict string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic + "\x01\x00")
+	binary.Write(&buf, binary.LittleEndian, uint16(len(dict)+1))
+	buf.WriteString(dict + "\n")
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestNPYFortranBigEndian(t *testing.T) {
+	// [[1 2 3] [4 5 6]] in column-major order
+	var data bytes.Buffer
+	for _, v := range []int32{1, 4, 2, 5, 3, 6} {
+		binary.Write(&data, binary.BigEndian, v)
+	}
+	file := npy("{'descr': '>i4', 'fortran_order': True, 'shape': (2, 3), }", data.Bytes())
+
+	var dst types.Tensor
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(file), &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.DataType() != types.INT32 || !dst.Shape().Equal(tensor.NewShape(2, 3)) {
+		t.Fatalf("got %v %v", dst.DataType(), dst.Shape())
+	}
+	if got := values(dst); !equal(got, []float64{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("got %v, want row-major 1..6", got)
+	}
+}
+
+func TestNPYHostileHeader(t *testing.T) {
+	for name, src := range map[string][]byte{
+		"huge":      npy("{'descr': '<f4', 'fortran_order': False, 'shape': (100000000000, ), }", nil),
+		"overflow":  npy("{'descr': '<f8', 'fortran_order': False, 'shape': (4294967296, 4294967296, ), }", nil),
+		"short":     npy("{'descr': '<f4', 'fortran_order': False, 'shape': (3, ), }", make([]byte, 8)),
+		"long dict": append([]byte(npyMagic+"\x02\x00\xff\xff\xff\xff"), "{}"...),
+	} {
+		var got types.Tensor
+		if err := NewUnmarshaller().Unmarshal(bytes.NewReader(src), &got); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestNPYWidenedTypes(t *testing.T) {
+	tests := []struct {
+		dict string
+		data []byte
+		dt   types.DataType
+		want []float64
+	}{
+		{"{'descr': '<f2', 'fortran_order': False, 'shape': (3,), }", []byte{0x00, 0x3c, 0x00, 0xc0, 0x00, 0x38}, types.FP32, []float64{1, -2, .5}},
+		{"{'descr': '|b1', 'fortran_order': False, 'shape': (3,), }", []byte{1, 0, 1}, types.UINT8, []float64{1, 0, 1}},
+		{"{'descr': '<u2', 'fortran_order': False, 'shape': (2,), }", []byte{0xff, 0xff, 0x01, 0x00}, types.INT32, []float64{65535, 1}},
+		{"{'descr': '<f8', 'fortran_order': False, 'shape': (), }", []byte{0, 0, 0, 0, 0, 0, 0x10, 0x40}, types.FP64, []float64{4}},
+	}
+	for _, tt := range tests {
+		var dst types.Tensor
+		if err := NewUnmarshaller().Unmarshal(bytes.NewReader(npy(tt.dict, tt.data)), &dst); err != nil {
+			t.Fatalf("%s: %v", tt.dict, err)
+		}
+		if dst.DataType() != tt.dt {
+			t.Errorf("%s: dtype %v, want %v", tt.dict, dst.DataType(), tt.dt)
+		}
+		if got := values(dst); !equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.dict, got, tt.want)
+		}
+	}
+
+	var dst types.Tensor
+	err := NewUnmarshaller().Unmarshal(bytes.NewReader(npy("{'descr': '<c8', 'fortran_order': False, 'shape': (1,), }", make([]byte, 8))), &dst)
+	if err == nil {
+		t.Error("complex dtype should be rejected")
+	}
+}
+
+func TestNPZ(t *testing.T) {
+	src := map[string]types.Tensor{
+		"a": tensor.FromFloat32(tensor.NewShape(2), []float32{1, 2}),
+		"b": tensor.FromArray(tensor.NewShape(1, 3), []int16{-1, 0, 1}),
+	}
+	for _, opts := range [][]types.Option{nil, {WithCompression()}} {
+		var buf bytes.Buffer
+		if err := NewMarshaller(opts...).Marshal(&buf, src); err != nil {
+			t.Fatal(err)
+		}
+		var dst map[string]types.Tensor
+		if err := NewUnmarshaller().Unmarshal(&buf, &dst); err != nil {
+			t.Fatal(err)
+		}
+		if len(dst) != 2 || dst["b"].DataType() != types.INT16 || !equal(values(dst["b"]), []float64{-1, 0, 1}) || !equal(values(dst["a"]), []float64{1, 2}) {
+			t.Errorf("unexpected archive contents %v", dst)
+		}
+	}
+}
+
+func TestNPZStateDict(t *testing.T) {
+	newModel := func() types.Model {
+		dense1, _ := layers.NewDense(2, 4)
+		dense2, _ := layers.NewDense(4, 1)
+		model, err := nn.NewSequentialModelBuilder(tensor.NewShape(2)).
+			AddLayer(dense1).
+			AddLayer(layers.NewTanh("tanh")).
+			AddLayer(dense2).
+			Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := model.Init(tensor.NewShape(2)); err != nil {
+			t.Fatal(err)
+		}
+		return model
+	}
+	src, dst := newModel(), newModel()
+
+	var buf bytes.Buffer
+	if err := NewMarshaller().Marshal(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewUnmarshaller().Unmarshal(&buf, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	input := tensor.FromFloat32(tensor.NewShape(2), []float32{.3, -.7})
+	want, _ := src.Forward(input)
+	want = want.Clone()
+	got, err := dst.Forward(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.At(0) != want.At(0) {
+		t.Errorf("output %v, want %v", got.At(0), want.At(0))
+	}
+}
+
+// values returns the elements of a tensor of any data type.
+func values(t types.Tensor) []float64 {
+	v := reflect.ValueOf(t.Data())
+	out := make([]float64, t.Size())
+	for i := range out {
+		e := v.Index(i)
+		switch e.Kind() {
+		case reflect.Float32, reflect.Float64:
+			out[i] = e.Float()
+		case reflect.Uint8:
+			out[i] = float64(e.Uint())
+		default:
+			out[i] = float64(e.Int())
+		}
+	}
+	return out
+}
+
+func setValues(t types.Tensor, src []float64) {
+	v := reflect.ValueOf(t.Data())
+	for i, x := range src {
+		e := v.Index(i)
+		switch e.Kind() {
+		case reflect.Float32, reflect.Float64:
+			e.SetFloat(x)
+		case reflect.Uint8:
+			e.SetUint(uint64(x))
+		default:
+			e.SetInt(int64(x))
+		}
+	}
+}
+
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
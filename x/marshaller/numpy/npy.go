@@ -0,0 +1,160 @@
+package numpy
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// readNPY decodes a single .npy array.
+func readNPY(r io.Reader, opts types.Options) (types.Tensor, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	shape := types.Shape(h.shape)
+	if len(shape) == 0 {
+		// 0-d arrays load as a single element vector
+		shape = types.Shape{1}
+	}
+	n, size, err := h.dataSize()
+	if err != nil {
+		return nil, err
+	}
+	// the header is untrusted, so the data is read as it arrives rather than
+	// into a buffer of the declared size
+	raw, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return nil, fmt.Errorf("reading data: %w", err)
+	}
+	if len(raw) != size {
+		return nil, fmt.Errorf("reading data: %w", io.ErrUnexpectedEOF)
+	}
+
+	data, err := rawtensor.Decode(raw, h.elem, h.order, n)
+	if err != nil {
+		return nil, err
+	}
+	if h.fortran && len(shape) > 1 {
+		data = fortranToC(data, shape)
+	}
+	return rawtensor.Wrap(shape, data, opts)
+}
+
+// writeNPY encodes a tensor as a C-ordered little endian .npy array.
+func writeNPY(w io.Writer, t types.Tensor) error {
+	e, raw, err := rawtensor.Encode(t)
+	if err != nil {
+		return err
+	}
+	if err := writeHeader(w, e, t.Shape()); err != nil {
+		return err
+	}
+	_, err = w.Write(raw)
+	return err
+}
+
+// readNPZ decodes every array of a .npz archive keyed by its name.
+func readNPZ(data []byte, opts types.Options) (map[string]types.Tensor, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]types.Tensor, len(zr.File))
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".npy") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		t, err := readNPY(rc, opts)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		result[strings.TrimSuffix(f.Name, ".npy")] = t
+	}
+	return result, nil
+}
+
+// writeNPZ encodes the tensors as a .npz archive, one "<name>.npy" entry per tensor.
+func writeNPZ(w io.Writer, tensors map[string]types.Tensor, compress bool) error {
+	names := make([]string, 0, len(tensors))
+	for name := range tensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	method := zip.Store
+	if compress {
+		method = zip.Deflate
+	}
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: method})
+		if err != nil {
+			return err
+		}
+		if err := writeNPY(fw, tensors[name]); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// fortranToC reorders column-major elements into row-major order.
+func fortranToC(data any, shape types.Shape) any {
+	n := shape.Size()
+	perm := make([]int, n)
+	index := make([]int, len(shape))
+	for c := 0; c < n; c++ {
+		// c walks the row-major order; f is the column-major offset of the same element
+		f, stride := 0, 1
+		for d := range shape {
+			f += index[d] * stride
+			stride *= shape[d]
+		}
+		perm[c] = f
+		for d := len(shape) - 1; d >= 0; d-- {
+			if index[d]++; index[d] < shape[d] {
+				break
+			}
+			index[d] = 0
+		}
+	}
+
+	switch d := data.(type) {
+	case []float32:
+		return permute(d, perm)
+	case []float64:
+		return permute(d, perm)
+	case []int8:
+		return permute(d, perm)
+	case []int16:
+		return permute(d, perm)
+	case []int32:
+		return permute(d, perm)
+	case []int64:
+		return permute(d, perm)
+	case []uint8:
+		return permute(d, perm)
+	}
+	return data
+}
+
+func permute[T any](data []T, perm []int) []T {
+	out := make([]T, len(perm))
+	for i, p := range perm {
+		out[i] = data[p]
+	}
+	return out
+}
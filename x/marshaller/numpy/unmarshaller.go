@@ -0,0 +1,97 @@
+package numpy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+)
+
+// zipMagic starts every .npz archive.
+const zipMagic = "PK\x03\x04"
+
+// Unmarshaller implements .npy/.npz unmarshalling.
+type Unmarshaller struct {
+	opts types.Options
+}
+
+// NewUnmarshaller creates a new NumPy unmarshaller.
+func NewUnmarshaller(opts ...types.Option) *Unmarshaller {
+	u := &Unmarshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&u.opts)
+	}
+	return u
+}
+
+// Format returns the format name.
+func (u *Unmarshaller) Format() string {
+	return "numpy"
+}
+
+// Unmarshal decodes a .npy or .npz stream into dst, which may be:
+//   - *types.Tensor: a .npy array or a .npz archive holding exactly one array
+//   - *map[string]types.Tensor: a .npz archive, or a .npy array stored as "arr_0"
+//   - types.Model: a .npz state dict loaded into the initialized model's parameters
+func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) error {
+	localOpts := u.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+
+	tensors, err := decode(r, localOpts)
+	if err != nil {
+		return types.NewError("unmarshal", "numpy", "decoding", err)
+	}
+
+	switch d := dst.(type) {
+	case *types.Tensor:
+		if len(tensors) != 1 {
+			return types.NewError("unmarshal", "numpy", fmt.Sprintf("expected 1 array, got %d", len(tensors)), nil)
+		}
+		for _, t := range tensors {
+			*d = t
+		}
+	case *map[string]types.Tensor:
+		*d = tensors
+	case types.Model:
+		if err := models.LoadStateDict(d, tensors, false); err != nil {
+			return types.NewError("unmarshal", "numpy", "state dict", err)
+		}
+	case *types.Model:
+		if *d == nil {
+			return types.NewError("unmarshal", "numpy", "state dict needs an initialized model", nil)
+		}
+		if err := models.LoadStateDict(*d, tensors, false); err != nil {
+			return types.NewError("unmarshal", "numpy", "state dict", err)
+		}
+	default:
+		return types.NewError("unmarshal", "numpy", fmt.Sprintf("unsupported destination %T", dst), nil)
+	}
+	return nil
+}
+
+// decode reads a .npy array or .npz archive as named tensors.
+func decode(r io.Reader, opts types.Options) (map[string]types.Tensor, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zipMagic))
+	if err != nil {
+		return nil, err
+	}
+	if string(magic) == zipMagic {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		return readNPZ(data, opts)
+	}
+	t, err := readNPY(br, opts)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]types.Tensor{"arr_0": t}, nil
+}
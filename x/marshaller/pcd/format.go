@@ -0,0 +1,459 @@
+package pcd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/pointcloud"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// field is one FIELDS entry of a PCD header.
+type field struct {
+	name  string
+	size  int
+	typ   byte // 'F', 'I' or 'U'
+	count int
+}
+
+func (f field) bytes() int { return f.size * f.count }
+
+type header struct {
+	fields []field
+	points int
+	data   string
+}
+
+// readHeader parses the header up to and including the DATA line. It
+// returns io.EOF when r holds nothing but whitespace.
+func readHeader(r *bufio.Reader) (header, error) {
+	var h header
+	if err := skipSpace(r); err != nil {
+		return h, err
+	}
+	var sizes, typs, counts []string
+	width, height := -1, 1
+	first := true
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			if first {
+				return h, err
+			}
+			return h, fmt.Errorf("header: %w", unexpected(err))
+		}
+		first = false
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		args := fields[1:]
+		switch fields[0] {
+		case "VERSION", "VIEWPOINT":
+		case "FIELDS", "COLUMNS":
+			h.fields = make([]field, len(args))
+			for i, name := range args {
+				h.fields[i] = field{name: name, size: 4, typ: 'F', count: 1}
+			}
+		case "SIZE":
+			sizes = args
+		case "TYPE":
+			typs = args
+		case "COUNT":
+			counts = args
+		case "WIDTH":
+			if width, err = strconv.Atoi(firstArg(args)); err != nil || width < 0 {
+				return h, fmt.Errorf("invalid WIDTH %q", line)
+			}
+		case "HEIGHT":
+			if height, err = strconv.Atoi(firstArg(args)); err != nil || height < 0 {
+				return h, fmt.Errorf("invalid HEIGHT %q", line)
+			}
+		case "POINTS":
+			if h.points, err = strconv.Atoi(firstArg(args)); err != nil || h.points < 0 {
+				return h, fmt.Errorf("invalid POINTS %q", line)
+			}
+		case "DATA":
+			h.data = firstArg(args)
+			if width >= 0 && h.points == 0 {
+				if height > 0 && width > math.MaxInt/height {
+					return h, fmt.Errorf("WIDTH %d x HEIGHT %d overflows", width, height)
+				}
+				h.points = width * height
+			}
+			return h, h.apply(sizes, typs, counts)
+		default:
+			return h, fmt.Errorf("unknown header line %q", line)
+		}
+	}
+}
+
+func (h *header) apply(sizes, typs, counts []string) error {
+	if len(h.fields) == 0 {
+		return fmt.Errorf("no FIELDS")
+	}
+	switch h.data {
+	case EncodingASCII, EncodingBinary, EncodingBinaryCompressed:
+	default:
+		return fmt.Errorf("unsupported DATA %q", h.data)
+	}
+	for _, list := range [][]string{sizes, typs, counts} {
+		if list != nil && len(list) != len(h.fields) {
+			return fmt.Errorf("SIZE/TYPE/COUNT do not match %d FIELDS", len(h.fields))
+		}
+	}
+	for i := range h.fields {
+		f := &h.fields[i]
+		var err error
+		if sizes != nil {
+			if f.size, err = strconv.Atoi(sizes[i]); err != nil {
+				return fmt.Errorf("invalid SIZE %q", sizes[i])
+			}
+		}
+		if typs != nil {
+			f.typ = typs[i][0]
+		}
+		if counts != nil {
+			if f.count, err = strconv.Atoi(counts[i]); err != nil || f.count < 0 || f.count > math.MaxInt32 {
+				return fmt.Errorf("invalid COUNT %q", counts[i])
+			}
+		}
+		switch {
+		case f.typ == 'F' && (f.size == 4 || f.size == 8):
+		case (f.typ == 'I' || f.typ == 'U') && (f.size == 1 || f.size == 2 || f.size == 4 || f.size == 8):
+		default:
+			return fmt.Errorf("unsupported field %s: TYPE %c SIZE %d", f.name, f.typ, f.size)
+		}
+	}
+	return nil
+}
+
+// Cloud columns of the recognized fields; color is unpacked from rgb/rgba.
+const (
+	colX = iota
+	colY
+	colZ
+	colIntensity
+	colColor
+	colNone = -1
+)
+
+func column(f field) int {
+	if f.count != 1 {
+		return colNone
+	}
+	switch f.name {
+	case "x":
+		return colX
+	case "y":
+		return colY
+	case "z":
+		return colZ
+	case "intensity", "reflectance":
+		return colIntensity
+	case "rgb", "rgba":
+		if f.size == 4 {
+			return colColor
+		}
+	}
+	return colNone
+}
+
+func decode(r *bufio.Reader) (pointcloud.Cloud, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return pointcloud.Cloud{}, err
+	}
+
+	cols := make([]int, len(h.fields))
+	var have [5]bool
+	for i, f := range h.fields {
+		cols[i] = column(f)
+		if cols[i] != colNone {
+			have[cols[i]] = true
+		}
+	}
+	if !have[colX] || !have[colY] || !have[colZ] {
+		return pointcloud.Cloud{}, fmt.Errorf("fields %v without x, y and z", fieldNames(h.fields))
+	}
+	stride := 0
+	for _, f := range h.fields {
+		stride += f.bytes()
+	}
+	cloud := pointcloud.Reserve(h.points, have[colIntensity], have[colColor])
+	set := func(row []float32, col int, v float64, bits uint32) {
+		switch col {
+		case colNone:
+		case colColor:
+			c := cloud.ColorOffset()
+			row[c], row[c+1], row[c+2] = float32(bits>>16&0xff), float32(bits>>8&0xff), float32(bits&0xff)
+		default:
+			row[col] = float32(v)
+		}
+	}
+
+	switch h.data {
+	case EncodingASCII:
+		for i := 0; i < h.points; i++ {
+			line, err := readLine(r)
+			if err != nil {
+				return cloud, fmt.Errorf("point %d: %w", i, unexpected(err))
+			}
+			values := strings.Fields(line)
+			row := cloud.Add()
+			k := 0
+			for j, f := range h.fields {
+				if k+f.count > len(values) {
+					return cloud, fmt.Errorf("point %d: short line %q", i, line)
+				}
+				if cols[j] != colNone {
+					v, bits, err := parseASCII(values[k], f)
+					if err != nil {
+						return cloud, fmt.Errorf("point %d: %w", i, err)
+					}
+					set(row, cols[j], v, bits)
+				}
+				k += f.count
+			}
+		}
+	case EncodingBinary:
+		buf := make([]byte, stride)
+		for i := 0; i < h.points; i++ {
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return cloud, fmt.Errorf("point %d: %w", i, unexpected(err))
+			}
+			row := cloud.Add()
+			off := 0
+			for j, f := range h.fields {
+				if cols[j] != colNone {
+					v, bits := binaryValue(buf[off:], f)
+					set(row, cols[j], v, bits)
+				}
+				off += f.bytes()
+			}
+		}
+	case EncodingBinaryCompressed:
+		var sizes [8]byte
+		if _, err := io.ReadFull(r, sizes[:]); err != nil {
+			return cloud, fmt.Errorf("compressed sizes: %w", unexpected(err))
+		}
+		compressed := int64(binary.LittleEndian.Uint32(sizes[:]))
+		size := int(binary.LittleEndian.Uint32(sizes[4:]))
+		if size%stride != 0 || size/stride != h.points {
+			return cloud, fmt.Errorf("uncompressed size %d does not hold %d points of %d bytes", size, h.points, stride)
+		}
+		// the declared size is untrusted, so the data is read as it arrives
+		in, err := io.ReadAll(io.LimitReader(r, compressed))
+		if err != nil {
+			return cloud, fmt.Errorf("compressed data: %w", err)
+		}
+		if int64(len(in)) != compressed {
+			return cloud, fmt.Errorf("compressed data: %w", io.ErrUnexpectedEOF)
+		}
+		data, err := lzfDecompress(in, size)
+		if err != nil {
+			return cloud, err
+		}
+		for range h.points {
+			cloud.Add()
+		}
+		// fields are stored one after another, each for all points
+		off := 0
+		for j, f := range h.fields {
+			if cols[j] != colNone {
+				for i := 0; i < h.points; i++ {
+					v, bits := binaryValue(data[off+i*f.size:], f)
+					set(cloud.Point(i), cols[j], v, bits)
+				}
+			}
+			off += f.bytes() * h.points
+		}
+	}
+	return cloud, nil
+}
+
+func parseASCII(s string, f field) (float64, uint32, error) {
+	if f.typ == 'F' {
+		v, err := strconv.ParseFloat(s, 64)
+		return v, math.Float32bits(float32(v)), err
+	}
+	if f.typ == 'U' {
+		v, err := strconv.ParseUint(s, 10, 64)
+		return float64(v), uint32(v), err
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	return float64(v), uint32(v), err
+}
+
+// binaryValue returns a little endian element as a number and as raw bits,
+// which carry packed rgb values.
+func binaryValue(b []byte, f field) (float64, uint32) {
+	le := binary.LittleEndian
+	switch {
+	case f.typ == 'F' && f.size == 4:
+		bits := le.Uint32(b)
+		return float64(math.Float32frombits(bits)), bits
+	case f.typ == 'F':
+		return math.Float64frombits(le.Uint64(b)), 0
+	case f.size == 1 && f.typ == 'I':
+		return float64(int8(b[0])), uint32(b[0])
+	case f.size == 1:
+		return float64(b[0]), uint32(b[0])
+	case f.size == 2 && f.typ == 'I':
+		return float64(int16(le.Uint16(b))), uint32(le.Uint16(b))
+	case f.size == 2:
+		return float64(le.Uint16(b)), uint32(le.Uint16(b))
+	case f.size == 4 && f.typ == 'I':
+		return float64(int32(le.Uint32(b))), le.Uint32(b)
+	case f.size == 4:
+		return float64(le.Uint32(b)), le.Uint32(b)
+	case f.typ == 'I':
+		return float64(int64(le.Uint64(b))), uint32(le.Uint64(b))
+	default:
+		return float64(le.Uint64(b)), uint32(le.Uint64(b))
+	}
+}
+
+func encode(w io.Writer, c pointcloud.Cloud, opts types.Options) error {
+	data := opts.Metadata[encodingKey]
+	if data == "" {
+		data = EncodingBinary
+	}
+	switch data {
+	case EncodingASCII, EncodingBinary, EncodingBinaryCompressed:
+	default:
+		return fmt.Errorf("unsupported encoding %q", data)
+	}
+
+	names, sizes, typs, counts := "x y z", "4 4 4", "F F F", "1 1 1"
+	if c.Intensity {
+		names, sizes, typs, counts = names+" intensity", sizes+" 4", typs+" F", counts+" 1"
+	}
+	if c.Color {
+		// PCL stores packed 0x00RRGGBB colors in a float field
+		names, sizes, typs, counts = names+" rgb", sizes+" 4", typs+" F", counts+" 1"
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# .PCD v0.7 - Point Cloud Data file format\nVERSION 0.7\nFIELDS %s\nSIZE %s\nTYPE %s\nCOUNT %s\n", names, sizes, typs, counts)
+	fmt.Fprintf(bw, "WIDTH %d\nHEIGHT 1\nVIEWPOINT 0 0 0 1 0 0 0\nPOINTS %d\nDATA %s\n", c.Points, c.Points, data)
+
+	// fields of point i as float32 bits
+	nfields := 3
+	if c.Intensity {
+		nfields++
+	}
+	if c.Color {
+		nfields++
+	}
+	values := func(i int, dst []uint32) {
+		row := c.Point(i)
+		for j := 0; j < 3; j++ {
+			dst[j] = math.Float32bits(row[j])
+		}
+		if c.Intensity {
+			dst[3] = math.Float32bits(row[3])
+		}
+		if c.Color {
+			k := c.ColorOffset()
+			dst[nfields-1] = uint32(pointcloud.Clamp8(row[k]))<<16 | uint32(pointcloud.Clamp8(row[k+1]))<<8 | uint32(pointcloud.Clamp8(row[k+2]))
+		}
+	}
+
+	point := make([]uint32, nfields)
+	switch data {
+	case EncodingASCII:
+		var buf []byte
+		for i := 0; i < c.Points; i++ {
+			values(i, point)
+			buf = buf[:0]
+			for j, bits := range point {
+				if j > 0 {
+					buf = append(buf, ' ')
+				}
+				buf = strconv.AppendFloat(buf, float64(math.Float32frombits(bits)), 'g', -1, 32)
+			}
+			buf = append(buf, '\n')
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+		}
+	case EncodingBinary:
+		buf := make([]byte, 4*nfields)
+		for i := 0; i < c.Points; i++ {
+			values(i, point)
+			for j, bits := range point {
+				binary.LittleEndian.PutUint32(buf[4*j:], bits)
+			}
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+		}
+	case EncodingBinaryCompressed:
+		raw := make([]byte, 4*nfields*c.Points)
+		for i := 0; i < c.Points; i++ {
+			values(i, point)
+			for j, bits := range point {
+				binary.LittleEndian.PutUint32(raw[4*(j*c.Points+i):], bits)
+			}
+		}
+		compressed := lzfCompress(raw)
+		var sizes [8]byte
+		binary.LittleEndian.PutUint32(sizes[:], uint32(len(compressed)))
+		binary.LittleEndian.PutUint32(sizes[4:], uint32(len(raw)))
+		bw.Write(sizes[:])
+		if _, err := bw.Write(compressed); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func fieldNames(fields []field) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func skipSpace(r *bufio.Reader) error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return r.UnreadByte()
+		}
+	}
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package pcd
+
+import "errors"
+
+// LZF as used by PCL for binary_compressed data: a control byte below 32
+// starts a run of ctrl+1 literals, otherwise its top three bits are the
+// back-reference length-2 (7 means an extra length byte follows) and the low
+// five bits plus the next byte the offset-1.
+
+const (
+	lzfHashLog   = 14
+	lzfMaxOffset = 1 << 13
+	lzfMaxLit    = 1 << 5
+	lzfMaxRef    = 7 + 255 + 2
+	// lzfMaxRatio bounds the expansion: the longest back-reference takes
+	// three bytes.
+	lzfMaxRatio = lzfMaxRef/3 + 1
+)
+
+var errLZF = errors.New("corrupt LZF data")
+
+func lzfCompress(in []byte) []byte {
+	var table [1 << lzfHashLog]int32 // position+1 of the last occurrence
+	out := make([]byte, 0, len(in)+len(in)/32+1)
+
+	ctrl := len(out) // index of the open literal run's control byte
+	out = append(out, 0)
+	lit := 0
+	literal := func(b byte) {
+		out = append(out, b)
+		lit++
+		if lit == lzfMaxLit {
+			out[ctrl] = lzfMaxLit - 1
+			ctrl, lit = len(out), 0
+			out = append(out, 0)
+		}
+	}
+
+	ip := 0
+	for ip+2 < len(in) {
+		h := (uint32(in[ip])<<16 | uint32(in[ip+1])<<8 | uint32(in[ip+2])) * 2654435761 >> (32 - lzfHashLog)
+		ref := int(table[h]) - 1
+		table[h] = int32(ip + 1)
+		off := ip - ref - 1
+		if ref < 0 || off >= lzfMaxOffset || in[ref] != in[ip] || in[ref+1] != in[ip+1] || in[ref+2] != in[ip+2] {
+			literal(in[ip])
+			ip++
+			continue
+		}
+
+		n, limit := 3, min(len(in)-ip, lzfMaxRef)
+		for n < limit && in[ref+n] == in[ip+n] {
+			n++
+		}
+		// close the literal run, dropping its control byte when empty
+		if lit == 0 {
+			out = out[:ctrl]
+		} else {
+			out[ctrl] = byte(lit - 1)
+		}
+		if l := n - 2; l < 7 {
+			out = append(out, byte(l<<5|off>>8), byte(off))
+		} else {
+			out = append(out, byte(7<<5|off>>8), byte(l-7), byte(off))
+		}
+		ip += n
+		ctrl, lit = len(out), 0
+		out = append(out, 0)
+	}
+	for ; ip < len(in); ip++ {
+		literal(in[ip])
+	}
+	if lit == 0 {
+		out = out[:ctrl]
+	} else {
+		out[ctrl] = byte(lit - 1)
+	}
+	return out
+}
+
+func lzfDecompress(in []byte, size int) ([]byte, error) {
+	if size > len(in)*lzfMaxRatio {
+		return nil, errLZF
+	}
+	out := make([]byte, 0, size)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < lzfMaxLit {
+			n := ctrl + 1
+			if i+n > len(in) || len(out)+n > size {
+				return nil, errLZF
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, errLZF
+			}
+			n += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, errLZF
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		n += 2
+		if ref < 0 || len(out)+n > size {
+			return nil, errLZF
+		}
+		for k := 0; k < n; k++ { // references may overlap the output
+			out = append(out, out[ref+k])
+		}
+	}
+	if len(out) != size {
+		return nil, errLZF
+	}
+	return out, nil
+}
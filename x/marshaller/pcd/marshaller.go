@@ -0,0 +1,56 @@
+// Package pcd implements marshallers for PCL PCD point clouds in ascii, binary
+// and binary_compressed (LZF) encodings.
+//
+// Clouds are N x C tensors or matrices with the columns x y z, optionally
+// followed by intensity and/or red green blue (0..255), i.e. C is 3, 4, 6 or
+// 7. Colors are stored as packed rgb fields; other fields are ignored on
+// load. Frame sequences are written as concatenated PCD
+// files or, with WithDir, as one file per frame; the unmarshaller reads them
+// back into a types.FrameStream, also from a directory or glob (WithPath).
+package pcd
+
+import (
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/pointcloud"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+var codec = pointcloud.Codec{
+	Name:   "pcd",
+	Ext:    ".pcd",
+	Decode: decode,
+	Encode: encode,
+}
+
+// Marshaller implements PCD marshalling.
+type Marshaller struct {
+	opts types.Options
+}
+
+// NewMarshaller creates a new PCD marshaller.
+func NewMarshaller(opts ...types.Option) *Marshaller {
+	m := &Marshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&m.opts)
+	}
+	return m
+}
+
+// Format returns the format name.
+func (m *Marshaller) Format() string {
+	return "pcd"
+}
+
+// Marshal writes a cloud tensor or matrix, a types.Frame (its first tensor),
+// a []types.Frame or a types.FrameStream. The encoding defaults to
+// binary.
+func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	localOpts := m.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+	return codec.Marshal(w, value, localOpts)
+}
@@ -0,0 +1,163 @@
+package pcd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+func TestRoundTrip(t *testing.T) {
+	// x y z intensity r g b
+	want := []float32{
+		0, 0, 0, 0.5, 255, 0, 0,
+		1.5, -2.25, 3, 1, 0, 128, 255,
+		-0.001, 1e6, 7, 0, 10, 20, 30,
+	}
+	for _, enc := range []string{EncodingASCII, EncodingBinary, EncodingBinaryCompressed} {
+		var buf bytes.Buffer
+		if err := NewMarshaller(WithEncoding(enc)).Marshal(&buf, tensor.FromFloat32(tensor.NewShape(3, 7), want)); err != nil {
+			t.Fatalf("%s: Marshal failed: %v", enc, err)
+		}
+		if !strings.Contains(buf.String(), "FIELDS x y z intensity rgb\n") || !strings.Contains(buf.String(), "DATA "+enc+"\n") {
+			t.Errorf("%s: unexpected header %q", enc, buf.String())
+		}
+
+		var dst types.Tensor
+		if err := NewUnmarshaller().Unmarshal(&buf, &dst); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", enc, err)
+		}
+		if got := dst.Data().([]float32); !equal(got, want) {
+			t.Errorf("%s: got %v, want %v", enc, got, want)
+		}
+	}
+}
+
+func TestPCLFile(t *testing.T) {
+	// as written by pcl::io::savePCDFileASCII for PointXYZRGB, plus an unused
+	// multi-count field
+	rgb := math.Float32frombits(0x00ff8000)
+	src := `# .PCD v.7 - Point Cloud Data file format
+VERSION .7
+FIELDS x y z rgb normal
+SIZE 4 4 4 4 4
+TYPE F F F F F
+COUNT 1 1 1 1 3
+WIDTH 2
+HEIGHT 1
+VIEWPOINT 0 0 0 1 0 0 0
+POINTS 2
+DATA ascii
+1 2 3 ` + strconv.FormatFloat(float64(rgb), 'g', -1, 32) + ` 0 0 1
+4 5 6 0 0 0 1
+`
+	var m mat.Matrix
+	if err := NewUnmarshaller().Unmarshal(strings.NewReader(src), &m); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(m) != 2 || !equal(m[0], []float32{1, 2, 3, 255, 128, 0}) || !equal(m[1], []float32{4, 5, 6, 0, 0, 0}) {
+		t.Errorf("got %v", m)
+	}
+
+	// binary with mixed field types: x y z as doubles, intensity as uint16
+	var buf bytes.Buffer
+	buf.WriteString("VERSION 0.7\nFIELDS x y z intensity\nSIZE 8 8 8 2\nTYPE F F F U\nCOUNT 1 1 1 1\nWIDTH 1\nHEIGHT 1\nDATA binary\n")
+	binary.Write(&buf, binary.LittleEndian, []float64{1, 2, 3})
+	binary.Write(&buf, binary.LittleEndian, uint16(700))
+	if err := NewUnmarshaller().Unmarshal(&buf, &m); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !equal(m[0], []float32{1, 2, 3, 700}) {
+		t.Errorf("got %v", m)
+	}
+}
+
+func TestLZF(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 5000)
+	rng.Read(random)
+	repetitive := bytes.Repeat([]byte("abcabcabd"), 1000)
+	for name, data := range map[string][]byte{"empty": nil, "short": {1, 2}, "random": random, "repetitive": repetitive, "zeros": make([]byte, 10000)} {
+		compressed := lzfCompress(data)
+		got, err := lzfDecompress(compressed, len(data))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: round trip failed: %v", name, err)
+		}
+		if name == "zeros" && len(compressed) > 200 {
+			t.Errorf("zeros compressed to %d bytes", len(compressed))
+		}
+	}
+	if _, err := lzfDecompress([]byte{0xe0, 0x05, 0x00}, 10); err == nil {
+		t.Error("reference before the start should fail")
+	}
+}
+
+// Headers declaring far more data than the input holds must fail without
+// allocating for the declared size.
+func TestHostileHeader(t *testing.T) {
+	const head = "VERSION 0.7\nFIELDS x y z\nSIZE 4 4 4\nTYPE F F F\nCOUNT 1 1 1\nWIDTH 4000000000\nHEIGHT 1\nPOINTS 4000000000\n"
+	sizes := func(compressed, size uint32) string {
+		var b [8]byte
+		binary.LittleEndian.PutUint32(b[:], compressed)
+		binary.LittleEndian.PutUint32(b[4:], size)
+		return string(b[:])
+	}
+	for name, src := range map[string]string{
+		"ascii":      head + "DATA ascii\n1 2 3\n",
+		"binary":     head + "DATA binary\n" + strings.Repeat("\x00", 24),
+		"compressed": "VERSION 0.7\nFIELDS x y z\nWIDTH 1000\nPOINTS 1000\nDATA binary_compressed\n" + sizes(0xffffffff, 12000) + "\x00\x00",
+		"expansion":  "VERSION 0.7\nFIELDS x y z\nWIDTH 100000\nPOINTS 100000\nDATA binary_compressed\n" + sizes(2, 1200000) + "\x00\x00",
+		"overflow":   "VERSION 0.7\nFIELDS x y z\nWIDTH 4611686018427387904\nHEIGHT 4\nDATA binary\n",
+	} {
+		var m mat.Matrix
+		if err := NewUnmarshaller().Unmarshal(strings.NewReader(src), &m); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSequence(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMarshaller(WithEncoding(EncodingBinaryCompressed))
+	for i := 0; i < 3; i++ {
+		if err := m.Marshal(&buf, mat.New(1, 3, float32(i), 0, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stream types.FrameStream
+	if err := NewUnmarshaller().Unmarshal(&buf, &stream); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for f := range stream.C {
+		if err, ok := f.Metadata["error"].(error); ok {
+			t.Fatal(err)
+		}
+		if f.Index != n || f.Tensors[0].At(0, 0) != float64(n) {
+			t.Errorf("frame %d: %v", n, f.Tensors[0].Data())
+		}
+		n++
+	}
+	if n != 3 {
+		t.Errorf("got %d frames, want 3", n)
+	}
+}
+
+func equal(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package pcd
+
+import "github.com/itohio/EasyRobot/x/marshaller/types"
+
+// Encodings of the DATA header line.
+const (
+	EncodingASCII            = "ascii"
+	EncodingBinary           = "binary"
+	EncodingBinaryCompressed = "binary_compressed"
+)
+
+const encodingKey = "pcd.encoding"
+
+type metadataOption struct {
+	key, value string
+}
+
+func (o metadataOption) Apply(opts *types.Options) {
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]string)
+	}
+	opts.Metadata[o.key] = o.value
+}
+
+// WithEncoding selects EncodingASCII, EncodingBinary (default) or EncodingBinaryCompressed.
+func WithEncoding(encoding string) types.Option {
+	return metadataOption{encodingKey, encoding}
+}
+
+// WithDir writes frame sequences as cloud_<index>.pcd files into dir instead
+// of concatenating them into the writer.
+func WithDir(dir string) types.Option {
+	return metadataOption{codec.DirKey(), dir}
+}
+
+// WithPath reads frame sequences from a file, a directory of .pcd files or a
+// glob instead of the reader.
+func WithPath(path string) types.Option {
+	return metadataOption{codec.PathKey(), path}
+}
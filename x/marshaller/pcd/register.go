@@ -0,0 +1,24 @@
+package pcd
+
+import (
+	"bytes"
+
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "pcd",
+		Extensions: []string{".pcd"},
+		Sniff: func(head []byte) bool {
+			return bytes.HasPrefix(head, []byte("# .PCD")) || bytes.HasPrefix(head, []byte("VERSION"))
+		},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package pcd
+
+import (
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Unmarshaller implements PCD unmarshalling.
+type Unmarshaller struct {
+	opts types.Options
+}
+
+// NewUnmarshaller creates a new PCD unmarshaller.
+func NewUnmarshaller(opts ...types.Option) *Unmarshaller {
+	u := &Unmarshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&u.opts)
+	}
+	return u
+}
+
+// Format returns the format name.
+func (u *Unmarshaller) Format() string {
+	return "pcd"
+}
+
+// Unmarshal decodes a PCD cloud into dst, which may be:
+//   - *types.Tensor: an FP32 [N C] tensor (or Options.DestinationType)
+//   - *mat.Matrix or *matTypes.Matrix: an N x C matrix
+//   - *[]types.Frame or *types.FrameStream: every cloud of r, or of the
+//     WithPath files, as one frame each
+func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) error {
+	localOpts := u.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+	return codec.Unmarshal(r, dst, localOpts)
+}
@@ -0,0 +1,412 @@
+package ply
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/pointcloud"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// scalar is a PLY property type.
+type scalar struct {
+	size  int
+	float bool
+	sign  bool
+}
+
+var scalars = map[string]scalar{
+	"char": {1, false, true}, "int8": {1, false, true},
+	"uchar": {1, false, false}, "uint8": {1, false, false},
+	"short": {2, false, true}, "int16": {2, false, true},
+	"ushort": {2, false, false}, "uint16": {2, false, false},
+	"int": {4, false, true}, "int32": {4, false, true},
+	"uint": {4, false, false}, "uint32": {4, false, false},
+	"float": {4, true, true}, "float32": {4, true, true},
+	"double": {8, true, true}, "float64": {8, true, true},
+}
+
+type property struct {
+	name  string
+	typ   scalar
+	list  bool
+	count scalar // list length type
+}
+
+type element struct {
+	name       string
+	count      int
+	properties []property
+}
+
+type header struct {
+	format   string
+	order    binary.ByteOrder
+	elements []element
+}
+
+// readHeader parses the header up to and including end_header. It returns
+// io.EOF when r holds nothing but whitespace.
+func readHeader(r *bufio.Reader) (header, error) {
+	var h header
+	if err := skipSpace(r); err != nil {
+		return h, err
+	}
+	line, err := readLine(r)
+	if err != nil {
+		return h, err
+	}
+	if line != "ply" {
+		return h, fmt.Errorf("not a PLY file")
+	}
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return h, fmt.Errorf("header: %w", unexpected(err))
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "comment", "obj_info":
+		case "format":
+			if len(fields) < 2 {
+				return h, fmt.Errorf("invalid format line %q", line)
+			}
+			h.format = fields[1]
+			switch h.format {
+			case EncodingASCII:
+			case EncodingBinaryLE:
+				h.order = binary.LittleEndian
+			case EncodingBinaryBE:
+				h.order = binary.BigEndian
+			default:
+				return h, fmt.Errorf("unsupported format %q", h.format)
+			}
+		case "element":
+			if len(fields) != 3 {
+				return h, fmt.Errorf("invalid element line %q", line)
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil || n < 0 {
+				return h, fmt.Errorf("invalid element count %q", fields[2])
+			}
+			h.elements = append(h.elements, element{name: fields[1], count: n})
+		case "property":
+			if len(h.elements) == 0 {
+				return h, fmt.Errorf("property before element")
+			}
+			p, err := parseProperty(fields)
+			if err != nil {
+				return h, err
+			}
+			e := &h.elements[len(h.elements)-1]
+			e.properties = append(e.properties, p)
+		case "end_header":
+			if h.format == "" {
+				return h, fmt.Errorf("missing format")
+			}
+			return h, nil
+		default:
+			return h, fmt.Errorf("unknown header line %q", line)
+		}
+	}
+}
+
+func parseProperty(fields []string) (property, error) {
+	if len(fields) == 5 && fields[1] == "list" {
+		count, ok1 := scalars[fields[2]]
+		typ, ok2 := scalars[fields[3]]
+		if !ok1 || !ok2 || count.float {
+			return property{}, fmt.Errorf("invalid list property %v", fields)
+		}
+		return property{name: fields[4], typ: typ, list: true, count: count}, nil
+	}
+	if len(fields) != 3 {
+		return property{}, fmt.Errorf("invalid property %v", fields)
+	}
+	typ, ok := scalars[fields[1]]
+	if !ok {
+		return property{}, fmt.Errorf("unknown property type %q", fields[1])
+	}
+	return property{name: fields[2], typ: typ}, nil
+}
+
+// column maps vertex property names to cloud columns: 0-2 x y z, 3 intensity
+// and 4-6 red green blue (relative; placed after the layout is known).
+func column(name string) int {
+	switch name {
+	case "x":
+		return 0
+	case "y":
+		return 1
+	case "z":
+		return 2
+	case "intensity", "scalar_intensity", "reflectance":
+		return 3
+	case "red", "r", "diffuse_red":
+		return 4
+	case "green", "g", "diffuse_green":
+		return 5
+	case "blue", "b", "diffuse_blue":
+		return 6
+	}
+	return -1
+}
+
+func decode(r *bufio.Reader) (pointcloud.Cloud, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return pointcloud.Cloud{}, err
+	}
+
+	var cloud pointcloud.Cloud
+	found := false
+	for _, e := range h.elements {
+		if e.name != "vertex" {
+			if err := skipElement(r, h, e); err != nil {
+				return cloud, err
+			}
+			continue
+		}
+		if found {
+			return cloud, fmt.Errorf("more than one vertex element")
+		}
+		found = true
+
+		cols := make([]int, len(e.properties))
+		var have [7]bool
+		for i, p := range e.properties {
+			cols[i] = -1
+			if c := column(p.name); c >= 0 && !p.list {
+				cols[i] = c
+				have[c] = true
+			}
+		}
+		if !have[0] || !have[1] || !have[2] {
+			return cloud, fmt.Errorf("vertex element without x, y and z")
+		}
+		cloud = pointcloud.Reserve(e.count, have[3], have[4] && have[5] && have[6])
+		for i, c := range cols {
+			switch {
+			case c >= 4 && !cloud.Color, c == 3 && !cloud.Intensity:
+				cols[i] = -1
+			case c >= 4:
+				cols[i] = cloud.ColorOffset() + c - 4
+			}
+		}
+
+		values := make([]float64, len(e.properties))
+		for i := 0; i < e.count; i++ {
+			if err := readValues(r, h, e, values); err != nil {
+				return cloud, fmt.Errorf("vertex %d: %w", i, err)
+			}
+			row := cloud.Add()
+			for j, c := range cols {
+				if c < 0 {
+					continue
+				}
+				v := values[j]
+				if c >= cloud.ColorOffset() && cloud.Color && e.properties[j].typ.float {
+					v *= 255 // float colors are 0..1
+				}
+				row[c] = float32(v)
+			}
+		}
+	}
+	if !found {
+		return cloud, fmt.Errorf("no vertex element")
+	}
+	return cloud, nil
+}
+
+// readValues reads one element instance. List properties are consumed and
+// reported as their length.
+func readValues(r *bufio.Reader, h header, e element, values []float64) error {
+	if h.format == EncodingASCII {
+		line, err := readLine(r)
+		if err != nil {
+			return unexpected(err)
+		}
+		fields := strings.Fields(line)
+		k := 0
+		for i, p := range e.properties {
+			n := 1
+			if p.list {
+				if k >= len(fields) {
+					return fmt.Errorf("short line %q", line)
+				}
+				l, err := strconv.Atoi(fields[k])
+				if err != nil || l < 0 {
+					return fmt.Errorf("invalid list length %q", fields[k])
+				}
+				values[i] = float64(l)
+				k++
+				n = l
+			}
+			if k+n > len(fields) {
+				return fmt.Errorf("short line %q", line)
+			}
+			if !p.list {
+				v, err := strconv.ParseFloat(fields[k], 64)
+				if err != nil {
+					return err
+				}
+				values[i] = v
+			}
+			k += n
+		}
+		return nil
+	}
+
+	var buf [8]byte
+	read := func(s scalar) (float64, error) {
+		if _, err := io.ReadFull(r, buf[:s.size]); err != nil {
+			return 0, unexpected(err)
+		}
+		return scalarValue(buf[:s.size], s, h.order), nil
+	}
+	for i, p := range e.properties {
+		if !p.list {
+			v, err := read(p.typ)
+			if err != nil {
+				return err
+			}
+			values[i] = v
+			continue
+		}
+		l, err := read(p.count)
+		if err != nil {
+			return err
+		}
+		values[i] = l
+		if _, err := r.Discard(int(l) * p.typ.size); err != nil {
+			return unexpected(err)
+		}
+	}
+	return nil
+}
+
+func skipElement(r *bufio.Reader, h header, e element) error {
+	values := make([]float64, len(e.properties))
+	for i := 0; i < e.count; i++ {
+		if err := readValues(r, h, e, values); err != nil {
+			return fmt.Errorf("%s %d: %w", e.name, i, err)
+		}
+	}
+	return nil
+}
+
+func scalarValue(b []byte, s scalar, order binary.ByteOrder) float64 {
+	switch {
+	case s.size == 1 && s.sign:
+		return float64(int8(b[0]))
+	case s.size == 1:
+		return float64(b[0])
+	case s.size == 2 && s.sign:
+		return float64(int16(order.Uint16(b)))
+	case s.size == 2:
+		return float64(order.Uint16(b))
+	case s.size == 4 && s.float:
+		return float64(math.Float32frombits(order.Uint32(b)))
+	case s.size == 4 && s.sign:
+		return float64(int32(order.Uint32(b)))
+	case s.size == 4:
+		return float64(order.Uint32(b))
+	default:
+		return math.Float64frombits(order.Uint64(b))
+	}
+}
+
+func encode(w io.Writer, c pointcloud.Cloud, opts types.Options) error {
+	format := opts.Metadata[encodingKey]
+	if format == "" {
+		format = EncodingBinaryLE
+	}
+	var order binary.AppendByteOrder
+	switch format {
+	case EncodingASCII:
+	case EncodingBinaryLE:
+		order = binary.LittleEndian
+	case EncodingBinaryBE:
+		order = binary.BigEndian
+	default:
+		return fmt.Errorf("unsupported encoding %q", format)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ply\nformat %s 1.0\ncomment EasyRobot\nelement vertex %d\n", format, c.Points)
+	bw.WriteString("property float x\nproperty float y\nproperty float z\n")
+	if c.Intensity {
+		bw.WriteString("property float intensity\n")
+	}
+	if c.Color {
+		bw.WriteString("property uchar red\nproperty uchar green\nproperty uchar blue\n")
+	}
+	bw.WriteString("end_header\n")
+
+	colors := c.ColorOffset()
+	var buf []byte
+	for i := 0; i < c.Points; i++ {
+		row := c.Point(i)
+		buf = buf[:0]
+		for j, v := range row {
+			isColor := c.Color && j >= colors
+			switch {
+			case order == nil && isColor:
+				buf = strconv.AppendUint(buf, uint64(pointcloud.Clamp8(v)), 10)
+			case order == nil:
+				buf = strconv.AppendFloat(buf, float64(v), 'g', -1, 32)
+			case isColor:
+				buf = append(buf, pointcloud.Clamp8(v))
+			default:
+				buf = order.AppendUint32(buf, math.Float32bits(v))
+			}
+			if order == nil {
+				if j == len(row)-1 {
+					buf = append(buf, '\n')
+				} else {
+					buf = append(buf, ' ')
+				}
+			}
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func skipSpace(r *bufio.Reader) error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return r.UnreadByte()
+		}
+	}
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
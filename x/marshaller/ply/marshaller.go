@@ -0,0 +1,56 @@
+// Package ply implements marshallers for PLY (Stanford polygon) point clouds
+// in ascii and binary encodings.
+//
+// Clouds are N x C tensors or matrices with the columns x y z, optionally
+// followed by intensity and/or red green blue (0..255), i.e. C is 3, 4, 6 or
+// 7. Vertex properties other than those are ignored on load, and so are other
+// elements such as faces. Frame sequences are written as concatenated PLY
+// files or, with WithDir, as one file per frame; the unmarshaller reads them
+// back into a types.FrameStream, also from a directory or glob (WithPath).
+package ply
+
+import (
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/pointcloud"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+var codec = pointcloud.Codec{
+	Name:   "ply",
+	Ext:    ".ply",
+	Decode: decode,
+	Encode: encode,
+}
+
+// Marshaller implements PLY marshalling.
+type Marshaller struct {
+	opts types.Options
+}
+
+// NewMarshaller creates a new PLY marshaller.
+func NewMarshaller(opts ...types.Option) *Marshaller {
+	m := &Marshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&m.opts)
+	}
+	return m
+}
+
+// Format returns the format name.
+func (m *Marshaller) Format() string {
+	return "ply"
+}
+
+// Marshal writes a cloud tensor or matrix, a types.Frame (its first tensor),
+// a []types.Frame or a types.FrameStream. The encoding defaults to
+// binary little endian.
+func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	localOpts := m.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+	return codec.Marshal(w, value, localOpts)
+}
@@ -0,0 +1,174 @@
+package ply
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+// cloud returns an N x cols cloud with colors in the last three columns.
+func cloud(cols int) []float32 {
+	rows := [][]float32{
+		{0, 0, 0, 0.5, 255, 0, 0},
+		{1.5, -2.25, 3, 1, 0, 128, 255},
+		{-0.001, 1e6, 7, 0, 10, 20, 30},
+	}
+	var data []float32
+	for _, r := range rows {
+		switch cols {
+		case 3:
+			data = append(data, r[:3]...)
+		case 4:
+			data = append(data, r[:4]...)
+		case 6:
+			data = append(append(data, r[:3]...), r[4:]...)
+		case 7:
+			data = append(data, r...)
+		}
+	}
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, enc := range []string{EncodingASCII, EncodingBinaryLE, EncodingBinaryBE} {
+		for _, cols := range []int{3, 4, 6, 7} {
+			want := cloud(cols)
+			var buf bytes.Buffer
+			if err := NewMarshaller(WithEncoding(enc)).Marshal(&buf, tensor.FromFloat32(tensor.NewShape(3, cols), want)); err != nil {
+				t.Fatalf("%s/%d: Marshal failed: %v", enc, cols, err)
+			}
+			if !strings.HasPrefix(buf.String(), "ply\nformat "+enc+" 1.0\n") {
+				t.Errorf("%s/%d: header %q", enc, cols, buf.String()[:30])
+			}
+
+			var dst types.Tensor
+			if err := NewUnmarshaller().Unmarshal(&buf, &dst); err != nil {
+				t.Fatalf("%s/%d: Unmarshal failed: %v", enc, cols, err)
+			}
+			if shape := dst.Shape(); shape[0] != 3 || shape[1] != cols {
+				t.Fatalf("%s/%d: shape %v", enc, cols, shape)
+			}
+			if got := dst.Data().([]float32); !equal(got, want) {
+				t.Errorf("%s/%d: got %v, want %v", enc, cols, got, want)
+			}
+		}
+	}
+}
+
+func TestForeignFile(t *testing.T) {
+	// a mesh with float colors, an unused normal and a face list
+	src := `ply
+format ascii 1.0
+comment made elsewhere
+element vertex 3
+property double x
+property double y
+property double z
+property float nx
+property float red
+property float green
+property float blue
+element face 1
+property list uchar int vertex_indices
+end_header
+0 0 0 1 1 0 0
+1 0 0 1 0 1 0
+0 1 0 1 0 0 0.5
+3 0 1 2
+`
+	var m mat.Matrix
+	if err := NewUnmarshaller().Unmarshal(strings.NewReader(src), &m); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(m) != 3 || len(m[0]) != 6 || m[0][3] != 255 || m[2][1] != 1 || m[2][5] != 127.5 {
+		t.Errorf("got %v", m)
+	}
+
+	if err := NewUnmarshaller().Unmarshal(strings.NewReader("ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nend_header\n1\n"), &m); err == nil {
+		t.Error("cloud without y and z should fail")
+	}
+	if err := NewMarshaller().Marshal(&bytes.Buffer{}, mat.New(2, 5)); err == nil {
+		t.Error("5 columns should fail")
+	}
+}
+
+// A vertex count far beyond the input must fail without allocating for it.
+func TestHostileHeader(t *testing.T) {
+	for _, format := range []string{"ascii", "binary_little_endian"} {
+		src := "ply\nformat " + format + " 1.0\nelement vertex 4000000000\nproperty float x\nproperty float y\nproperty float z\nend_header\n"
+		var m mat.Matrix
+		if err := NewUnmarshaller().Unmarshal(strings.NewReader(src), &m); err == nil {
+			t.Errorf("%s: expected an error", format)
+		}
+	}
+}
+
+func TestSequence(t *testing.T) {
+	frames := make([]types.Frame, 3)
+	for i := range frames {
+		m := mat.New(2, 3, float32(i), 0, 0, 0, float32(i), 1)
+		frames[i] = types.Frame{Index: i, Tensors: []types.Tensor{tensor.FromFloat32(tensor.NewShape(2, 3), m.Flat())}}
+	}
+
+	// concatenated into one stream
+	var buf bytes.Buffer
+	if err := NewMarshaller().Marshal(&buf, frames); err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got []types.Frame
+	if err := NewUnmarshaller().Unmarshal(&buf, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(got) != 3 || got[2].Tensors[0].At(0, 0) != 2 || got[2].Index != 2 {
+		t.Fatalf("got %d frames", len(got))
+	}
+
+	// one file per frame, streamed back from the directory
+	dir := t.TempDir()
+	ch := make(chan types.Frame, len(frames))
+	for _, f := range frames {
+		ch <- f
+	}
+	close(ch)
+	if err := NewMarshaller(WithEncoding(EncodingASCII)).Marshal(nil, types.NewFrameStream(ch, nil), WithDir(dir)); err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644)
+
+	var stream types.FrameStream
+	if err := NewUnmarshaller().Unmarshal(nil, &stream, WithPath(dir)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	n := 0
+	for f := range stream.C {
+		if err, ok := f.Metadata["error"].(error); ok {
+			t.Fatal(err)
+		}
+		if f.Metadata["filename"] != fmt.Sprintf("cloud_%06d.ply", n) || f.Tensors[0].At(1, 1) != float64(n) {
+			t.Errorf("frame %d: %v", n, f.Metadata)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Errorf("streamed %d frames, want 3", n)
+	}
+}
+
+func equal(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package ply
+
+import "github.com/itohio/EasyRobot/x/marshaller/types"
+
+// Encodings of the PLY format line.
+const (
+	EncodingASCII    = "ascii"
+	EncodingBinaryLE = "binary_little_endian"
+	EncodingBinaryBE = "binary_big_endian"
+)
+
+const encodingKey = "ply.encoding"
+
+type metadataOption struct {
+	key, value string
+}
+
+func (o metadataOption) Apply(opts *types.Options) {
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]string)
+	}
+	opts.Metadata[o.key] = o.value
+}
+
+// WithEncoding selects EncodingASCII, EncodingBinaryLE (default) or EncodingBinaryBE.
+func WithEncoding(encoding string) types.Option {
+	return metadataOption{encodingKey, encoding}
+}
+
+// WithDir writes frame sequences as cloud_<index>.ply files into dir instead
+// of concatenating them into the writer.
+func WithDir(dir string) types.Option {
+	return metadataOption{codec.DirKey(), dir}
+}
+
+// WithPath reads frame sequences from a file, a directory of .ply files or a
+// glob instead of the reader.
+func WithPath(path string) types.Option {
+	return metadataOption{codec.PathKey(), path}
+}
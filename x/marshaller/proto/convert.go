@@ -2,8 +2,13 @@ package marshallerv1
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+	nntypes "github.com/itohio/EasyRobot/x/math/nn/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
 	pb "github.com/itohio/EasyRobot/types/core"
 )
 
@@ -206,14 +211,14 @@ func parameterToProto(p types.Parameter) (*pb.Parameter, error) {
 		RequiresGrad: p.RequiresGrad,
 	}
 
-	if !p.Data.Empty() {
+	if !tensor.IsNil(p.Data) {
 		data, err := tensorToProto(p.Data)
 		if err != nil {
 			return nil, err
 		}
 		result.Data = data
 	}
-	if !p.Grad.Empty() {
+	if !tensor.IsNil(p.Grad) {
 		grad, err := tensorToProto(p.Grad)
 		if err != nil {
 			return nil, err
@@ -227,18 +232,21 @@ func parameterToProto(p types.Parameter) (*pb.Parameter, error) {
 func layerToProto(layer types.Layer) (*pb.Layer, error) {
 	result := &pb.Layer{
 		Name:       layer.Name(),
+		Type:       reflect.TypeOf(layer).String(),
 		CanLearn:   layer.CanLearn(),
 		Parameters: make(map[int32]*pb.Parameter),
 	}
 
+	// Registered layers carry their constructor arguments
+	if name, ok := layers.TypeName(layer); ok {
+		result.Type = name
+		result.Config = layer.(layers.Configurable).Config()
+	}
+
 	// Get input shape if available
 	input := layer.Input()
-	if !input.Empty() {
-		shape := input.Shape()
-		result.InputShape = make([]int32, len(shape))
-		for i, s := range shape {
-			result.InputShape[i] = int32(s)
-		}
+	if !tensor.IsNil(input) {
+		result.InputShape = shapeToProto(input.Shape())
 	}
 
 	// Convert parameters
@@ -257,11 +265,16 @@ func layerToProto(layer types.Layer) (*pb.Layer, error) {
 func modelToProto(model types.Model) (*pb.Model, error) {
 	result := &pb.Model{
 		Name:       model.Name(),
+		Type:       reflect.TypeOf(model).String(),
 		CanLearn:   model.CanLearn(),
 		Layers:     make([]*pb.Layer, 0),
 		Parameters: make(map[int32]*pb.Parameter),
 	}
 
+	if m, ok := model.(interface{ InputShape() tensor.Shape }); ok {
+		result.InputShape = shapeToProto(m.InputShape())
+	}
+
 	// Convert layers
 	for i := 0; i < model.LayerCount(); i++ {
 		layer := model.GetLayer(i)
@@ -286,3 +299,82 @@ func modelToProto(model types.Model) (*pb.Model, error) {
 
 	return result, nil
 }
+
+func shapeToProto(shape tensor.Shape) []int32 {
+	if len(shape) == 0 {
+		return nil
+	}
+	result := make([]int32, len(shape))
+	for i, s := range shape {
+		result[i] = int32(s)
+	}
+	return result
+}
+
+func protoToShape(shape []int32) tensor.Shape {
+	if len(shape) == 0 {
+		return nil
+	}
+	result := make(tensor.Shape, len(shape))
+	for i, s := range shape {
+		result[i] = int(s)
+	}
+	return result
+}
+
+func protoToParameter(pp *pb.Parameter, tensorFactory func(types.DataType, types.Shape) types.Tensor) (types.Parameter, error) {
+	param := types.Parameter{RequiresGrad: pp.GetRequiresGrad()}
+	if pp.GetData() != nil {
+		data, err := protoToTensor(pp.GetData(), 0, tensorFactory)
+		if err != nil {
+			return param, err
+		}
+		param.Data = data
+	}
+	if pp.GetGrad() != nil {
+		grad, err := protoToTensor(pp.GetGrad(), 0, tensorFactory)
+		if err != nil {
+			return param, err
+		}
+		param.Grad = grad
+	}
+	return param, nil
+}
+
+// protoToLayerSpec converts a layer message into a spec the layer registry can rebuild.
+func protoToLayerSpec(pl *pb.Layer, tensorFactory func(types.DataType, types.Shape) types.Tensor) (layers.Spec, error) {
+	spec := layers.Spec{
+		Type:       pl.GetType(),
+		Name:       pl.GetName(),
+		CanLearn:   pl.GetCanLearn(),
+		Config:     pl.GetConfig(),
+		InputShape: protoToShape(pl.GetInputShape()),
+		Parameters: make(map[nntypes.ParamIndex]types.Parameter, len(pl.GetParameters())),
+	}
+	for idx, pp := range pl.GetParameters() {
+		param, err := protoToParameter(pp, tensorFactory)
+		if err != nil {
+			return spec, err
+		}
+		spec.Parameters[nntypes.ParamIndex(idx)] = param
+	}
+	return spec, nil
+}
+
+// protoToModel rebuilds a Sequential model from its layers.
+func protoToModel(pm *pb.Model, tensorFactory func(types.DataType, types.Shape) types.Tensor) (*models.Sequential, error) {
+	spec := models.Spec{
+		Name:       pm.GetName(),
+		CanLearn:   pm.GetCanLearn(),
+		InputShape: protoToShape(pm.GetInputShape()),
+		Layers:     make([]layers.Spec, 0, len(pm.GetLayers())),
+	}
+	for _, pl := range pm.GetLayers() {
+		layer, err := protoToLayerSpec(pl, tensorFactory)
+		if err != nil {
+			return nil, err
+		}
+		spec.Layers = append(spec.Layers, layer)
+	}
+	return models.Restore(spec)
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
 	pb "github.com/itohio/EasyRobot/types/core"
 	"google.golang.org/protobuf/proto"
 )
@@ -37,9 +38,14 @@ func (m *Marshaller) Marshal(dst io.Writer, value any, opts ...types.Option) err
 	for _, opt := range opts {
 		opt.Apply(&m.opts)
 	}
+	version, err := versioning.Target(schemaName, m.opts, SchemaVersion)
+	if err != nil {
+		return fmt.Errorf("protobuf version: %w", err)
+	}
+
 	// If value is already a proto.Message, marshal it directly
 	if msg, ok := value.(proto.Message); ok {
-		data, err := proto.Marshal(msg)
+		data, err := wrap(msg, version)
 		if err != nil {
 			return fmt.Errorf("protobuf marshal: %w", err)
 		}
@@ -53,7 +59,7 @@ func (m *Marshaller) Marshal(dst io.Writer, value any, opts ...types.Option) err
 		return fmt.Errorf("protobuf convert: %w", err)
 	}
 
-	data, err := proto.Marshal(protoValue)
+	data, err := wrap(protoValue, version)
 	if err != nil {
 		return fmt.Errorf("protobuf marshal: %w", err)
 	}
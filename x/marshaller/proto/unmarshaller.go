@@ -6,6 +6,7 @@ import (
 	"reflect"
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
 	"github.com/itohio/EasyRobot/x/math/tensor"
 	tensortypes "github.com/itohio/EasyRobot/x/math/tensor/types"
 	pb "github.com/itohio/EasyRobot/types/core"
@@ -47,12 +48,12 @@ func (u *Unmarshaller) Unmarshal(src io.Reader, dst any, opts ...types.Option) e
 
 	// If dst is a proto.Message, unmarshal directly
 	if msg, ok := dst.(proto.Message); ok {
-		return proto.Unmarshal(data, msg)
+		return unwrap(data, msg, u.opts)
 	}
 
 	// Otherwise, unmarshal to our Value wrapper and convert
 	var protoValue pb.Value
-	if err := proto.Unmarshal(data, &protoValue); err != nil {
+	if err := unwrap(data, &protoValue, u.opts); err != nil {
 		return fmt.Errorf("protobuf unmarshal: %w", err)
 	}
 
@@ -92,14 +93,22 @@ func (u *Unmarshaller) protoToValue(pv *pb.Value) (any, error) {
 		return pv.GetVector(), nil
 
 	case "layer":
-		// Note: We can't reconstruct nn.Layer without type information
-		// Return the protobuf message for now
-		return pv.GetLayer(), nil
+		layerProto := pv.GetLayer()
+		if layerProto == nil {
+			return nil, fmt.Errorf("layer value is nil")
+		}
+		spec, err := protoToLayerSpec(layerProto, tensorFactory)
+		if err != nil {
+			return nil, err
+		}
+		return layers.Restore(spec)
 
 	case "model":
-		// Note: We can't reconstruct nn.Model without type information
-		// Return the protobuf message for now
-		return pv.GetModel(), nil
+		modelProto := pv.GetModel()
+		if modelProto == nil {
+			return nil, fmt.Errorf("model value is nil")
+		}
+		return protoToModel(modelProto, tensorFactory)
 
 	case "slice":
 		sliceProto := pv.GetSlice()
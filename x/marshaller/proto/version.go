@@ -0,0 +1,138 @@
+package marshallerv1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+)
+
+// SchemaVersion is the payload version written by Marshal. Version 0 is a
+// bare message from before payloads were versioned.
+const SchemaVersion = 1
+
+const (
+	schemaName   = "protobuf"
+	envelopeName = "easyrobot"
+	// typeKey names the message type in migration documents, as in the JSON
+	// form of google.protobuf.Any.
+	typeKey = "@type"
+)
+
+// Every payload is wrapped in an envelope message:
+//
+//	message Envelope {
+//	  string schema = 1;   // "easyrobot"
+//	  uint32 version = 2;
+//	  string type = 3;     // full name of the payload message
+//	  bytes payload = 4;
+//	}
+const (
+	envelopeSchema protowire.Number = iota + 1
+	envelopeVersion
+	envelopeType
+	envelopePayload
+)
+
+func init() {
+	// v1 only introduced the envelope
+	versioning.Register(schemaName, 0, 1, nil)
+}
+
+// wrap encodes msg, in an envelope unless version is 0.
+func wrap(msg proto.Message, version int) ([]byte, error) {
+	payload, err := proto.Marshal(msg)
+	if err != nil || version == 0 {
+		return payload, err
+	}
+	var b []byte
+	b = protowire.AppendTag(b, envelopeSchema, protowire.BytesType)
+	b = protowire.AppendString(b, envelopeName)
+	b = protowire.AppendTag(b, envelopeVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(version))
+	b = protowire.AppendTag(b, envelopeType, protowire.BytesType)
+	b = protowire.AppendString(b, string(msg.ProtoReflect().Descriptor().FullName()))
+	b = protowire.AppendTag(b, envelopePayload, protowire.BytesType)
+	return protowire.AppendBytes(b, payload), nil
+}
+
+// parseEnvelope returns the version and payload of an envelope, or ok=false
+// for bare messages.
+func parseEnvelope(data []byte) (version int, payload []byte, ok bool) {
+	var schema string
+	for b := data; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, nil, false
+		}
+		b = b[n:]
+		switch {
+		case num == envelopeVersion && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, nil, false
+			}
+			version, b = int(v), b[n:]
+		case (num == envelopeSchema || num == envelopeType || num == envelopePayload) && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return 0, nil, false
+			}
+			switch num {
+			case envelopeSchema:
+				schema = string(v)
+			case envelopePayload:
+				payload = v
+			}
+			b = b[n:]
+		default:
+			return 0, nil, false
+		}
+	}
+	return version, payload, schema == envelopeName
+}
+
+// unwrap decodes an enveloped or a bare version 0 payload into msg and
+// migrates it to SchemaVersion.
+func unwrap(data []byte, msg proto.Message, opts types.Options) error {
+	version, payload, ok := parseEnvelope(data)
+	if !ok {
+		version, payload = 0, data
+	}
+	if err := versioning.Check(schemaName, version, SchemaVersion, opts); err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return err
+	}
+	if version >= SchemaVersion || !versioning.NeedsMigration(schemaName, version, SchemaVersion) {
+		return nil
+	}
+
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var doc versioning.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	doc[typeKey] = string(msg.ProtoReflect().Descriptor().FullName())
+	if doc, err = versioning.Migrate(schemaName, doc, version, SchemaVersion); err != nil {
+		return err
+	}
+	delete(doc, typeKey)
+	if data, err = json.Marshal(doc); err != nil {
+		return err
+	}
+	proto.Reset(msg)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("migrated document: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package marshallerv1
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/itohio/EasyRobot/types/control"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"google.golang.org/protobuf/proto"
+)
+
+func goldenTensor() types.Tensor {
+	return tensor.FromFloat32(tensor.NewShape(2, 3), []float32{0.5, 1, 1.5, -2, 1e-3, 42})
+}
+
+func goldenGains() *control.PIDGains {
+	return &control.PIDGains{P: 1.5, I: 0.25, D: 0.125}
+}
+
+func readGolden(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/golden/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func loadTensor(t *testing.T, data []byte, opts ...types.Option) (types.Tensor, error) {
+	t.Helper()
+	var got types.Tensor
+	err := NewUnmarshaller(opts...).Unmarshal(bytes.NewReader(data), &got)
+	return got, err
+}
+
+func checkTensor(t *testing.T, got types.Tensor, shape ...int) {
+	t.Helper()
+	if !got.Shape().Equal(tensor.NewShape(shape...)) {
+		t.Fatalf("shape %v, want %v", got.Shape(), shape)
+	}
+	for i, v := range goldenTensor().Data().([]float32) {
+		if got.Data().([]float32)[i] != v {
+			t.Fatalf("element %d is %v, want %v", i, got.Data().([]float32)[i], v)
+		}
+	}
+}
+
+func TestGoldenVersions(t *testing.T) {
+	for _, name := range []string{"tensor.v0.pb", "tensor.v1.pb"} {
+		got, err := loadTensor(t, readGolden(t, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		checkTensor(t, got, 2, 3)
+	}
+	for _, name := range []string{"pid.v0.pb", "pid.v1.pb"} {
+		var got control.PIDGains
+		if err := NewUnmarshaller().Unmarshal(bytes.NewReader(readGolden(t, name)), &got); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !proto.Equal(&got, goldenGains()) {
+			t.Errorf("%s: got %v", name, &got)
+		}
+	}
+}
+
+func TestGoldenCurrent(t *testing.T) {
+	for _, tc := range []struct {
+		value   any
+		version string
+		golden  string
+	}{
+		{goldenTensor(), "", "tensor.v1.pb"},
+		{goldenTensor(), "0", "tensor.v0.pb"},
+		{goldenGains(), "", "pid.v1.pb"},
+		{goldenGains(), "0", "pid.v0.pb"},
+	} {
+		var buf bytes.Buffer
+		if err := NewMarshaller(types.WithFormatVersion(tc.version)).Marshal(&buf, tc.value); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), readGolden(t, tc.golden)) {
+			t.Errorf("output of version %q differs from %s", tc.version, tc.golden)
+		}
+	}
+	if err := NewMarshaller(types.WithFormatVersion("7")).Marshal(&bytes.Buffer{}, goldenTensor()); err == nil {
+		t.Error("expected error writing an unknown version")
+	}
+}
+
+func TestMigration(t *testing.T) {
+	// pretend v1 stored tensors transposed and renamed the integral gain
+	versioning.Register(schemaName, 0, 1, func(doc versioning.Document) (versioning.Document, error) {
+		switch doc[typeKey] {
+		case "easyrobot.core.types.Value":
+			tensor := doc["tensor"].(versioning.Document)
+			shape := tensor["shape"].([]any)
+			tensor["shape"] = []any{shape[1], shape[0]}
+		case "types.control.PIDGains":
+			doc["i"] = 2 * doc["i"].(float64)
+		}
+		return doc, nil
+	})
+	t.Cleanup(func() { versioning.Register(schemaName, 0, 1, nil) })
+
+	got, err := loadTensor(t, readGolden(t, "tensor.v0.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTensor(t, got, 3, 2)
+	if got, err = loadTensor(t, readGolden(t, "tensor.v1.pb")); err != nil {
+		t.Fatal(err)
+	}
+	checkTensor(t, got, 2, 3)
+
+	var gains control.PIDGains
+	if err := NewUnmarshaller().Unmarshal(bytes.NewReader(readGolden(t, "pid.v0.pb")), &gains); err != nil {
+		t.Fatal(err)
+	}
+	if gains.I != 0.5 || gains.P != 1.5 {
+		t.Errorf("migrated gains %v", &gains)
+	}
+}
+
+func TestFutureVersion(t *testing.T) {
+	pv, err := NewMarshaller().valueToProto(goldenTensor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := wrap(pv, SchemaVersion+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadTensor(t, data)
+	if err != nil {
+		t.Fatalf("best effort decoding failed: %v", err)
+	}
+	checkTensor(t, got, 2, 3)
+
+	_, err = loadTensor(t, data, versioning.WithStrict(true))
+	var future *versioning.FutureVersionError
+	if !errors.As(err, &future) || future.Version != SchemaVersion+1 {
+		t.Fatalf("expected future version error, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+// Package all registers every pure-Go marshaller backend with the registry.
+// Backends needing cgo (gocv, tflite) register when imported directly.
+package all
+
+import (
+	_ "github.com/itohio/EasyRobot/x/marshaller/cbor"
+	_ "github.com/itohio/EasyRobot/x/marshaller/gob"
+	_ "github.com/itohio/EasyRobot/x/marshaller/goimage"
+	_ "github.com/itohio/EasyRobot/x/marshaller/graph"
+	_ "github.com/itohio/EasyRobot/x/marshaller/json"
+	_ "github.com/itohio/EasyRobot/x/marshaller/mcap"
+	_ "github.com/itohio/EasyRobot/x/marshaller/msgpack"
+	_ "github.com/itohio/EasyRobot/x/marshaller/numpy"
+	_ "github.com/itohio/EasyRobot/x/marshaller/pcd"
+	_ "github.com/itohio/EasyRobot/x/marshaller/ply"
+	_ "github.com/itohio/EasyRobot/x/marshaller/proto"
+	_ "github.com/itohio/EasyRobot/x/marshaller/safetensors"
+	_ "github.com/itohio/EasyRobot/x/marshaller/text"
+	_ "github.com/itohio/EasyRobot/x/marshaller/yaml"
+)
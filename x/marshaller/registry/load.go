@@ -0,0 +1,254 @@
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	formatKey = "registry.format"
+	headSize  = 512
+)
+
+var (
+	gzipMagic = []byte{0x1F, 0x8B}
+	zstdMagic = []byte{0x28, 0xB5, 0x2F, 0xFD}
+)
+
+type withFormat struct {
+	name string
+}
+
+func (opt withFormat) Apply(opts *types.Options) {
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]string)
+	}
+	opts.Metadata[formatKey] = opt.name
+}
+
+// WithFormat skips detection and uses the named format.
+func WithFormat(name string) types.Option {
+	return withFormat{name: name}
+}
+
+func chosenFormat(opts []types.Option) (string, error) {
+	var o types.Options
+	for _, opt := range opts {
+		opt.Apply(&o)
+	}
+	name := o.Metadata[formatKey]
+	if name == "" {
+		return "", nil
+	}
+	if _, ok := Lookup(name); !ok {
+		return "", fmt.Errorf("registry: format %q is not registered", name)
+	}
+	return name, nil
+}
+
+// compression returns the compression suffix of path and the path without it.
+func compression(path string) (string, string) {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".gz", ".zst"} {
+		if strings.HasSuffix(lower, ext) {
+			return ext, path[:len(path)-len(ext)]
+		}
+	}
+	return "", path
+}
+
+// Load decodes the file at path into dst with the detected backend.
+// Gzip and Zstandard compressed files are decompressed transparently,
+// recognised by their content. opts are passed on to the backend.
+//
+// For *types.FrameStream destinations the file stays open until the stream
+// is closed.
+func Load(path string, dst any, opts ...types.Option) error {
+	name, err := chosenFormat(opts)
+	if err != nil {
+		return err
+	}
+	canLoad := func(f Format) bool { return f.NewUnmarshaller != nil }
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	var zr *zstd.Decoder
+	closeFile := func() {
+		if zr != nil {
+			zr.Close()
+		}
+		file.Close()
+	}
+	keepOpen := false
+	defer func() {
+		if !keepOpen {
+			closeFile()
+		}
+	}()
+
+	var r io.Reader = file
+	br := bufio.NewReaderSize(file, 64<<10)
+	head, _ := br.Peek(headSize)
+	_, inner := compression(path)
+	compressed := true
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("registry: %s: %w", path, err)
+		}
+		br = bufio.NewReaderSize(gz, 64<<10)
+	case bytes.HasPrefix(head, zstdMagic):
+		// decode synchronously in the goroutine reading the stream
+		if zr, err = zstd.NewReader(br, zstd.WithDecoderConcurrency(1)); err != nil {
+			return fmt.Errorf("registry: %s: %w", path, err)
+		}
+		br = bufio.NewReaderSize(zr, 64<<10)
+	default:
+		compressed = false
+		inner = path
+	}
+	if compressed {
+		if head, err = br.Peek(headSize); err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+			return fmt.Errorf("registry: %s: %w", path, err)
+		}
+		r = br
+	} else if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var format Format
+	if name != "" {
+		format, _ = Lookup(name)
+		if !canLoad(format) {
+			return fmt.Errorf("registry: format %s cannot load", name)
+		}
+	} else if format, err = Detect(inner, head, canLoad); err != nil {
+		return err
+	}
+	if format.Files && compressed {
+		return fmt.Errorf("registry: %s reads files itself and cannot load compressed %s", format.Name, path)
+	}
+
+	u, err := format.NewUnmarshaller()
+	if err != nil {
+		return err
+	}
+	if format.LoadOptions != nil && !compressed {
+		opts = append(format.LoadOptions(path), opts...)
+	}
+
+	stream, isStream := dst.(*types.FrameStream)
+	if !isStream && !compressed {
+		// hide io.ReaderAt: backends reading in place must not outlive Load
+		r = struct{ io.Reader }{file}
+	}
+	if format.Files {
+		r = nil
+	}
+	if err := u.Unmarshal(r, dst, opts...); err != nil {
+		return err
+	}
+	if isStream && !format.Files {
+		s := *stream
+		*stream = types.NewFrameStream(s.C, func() {
+			s.Close()
+			closeFile()
+		})
+		keepOpen = true
+	}
+	return nil
+}
+
+// Save encodes value into the file at path with the backend chosen by the
+// file extension (or WithFormat). A trailing .gz or .zst compresses the file.
+// The file is removed if encoding fails.
+func Save(path string, value any, opts ...types.Option) (err error) {
+	name, err := chosenFormat(opts)
+	if err != nil {
+		return err
+	}
+	canSave := func(f Format) bool { return f.NewMarshaller != nil }
+	ext, inner := compression(path)
+
+	var format Format
+	if name != "" {
+		format, _ = Lookup(name)
+		if !canSave(format) {
+			return fmt.Errorf("registry: format %s cannot save", name)
+		}
+	} else if format, err = Detect(inner, nil, canSave); err != nil {
+		return err
+	}
+
+	m, err := format.NewMarshaller()
+	if err != nil {
+		return err
+	}
+	if format.SaveOptions != nil {
+		opts = append(format.SaveOptions(inner), opts...)
+	}
+	if format.Files {
+		if ext != "" {
+			return fmt.Errorf("registry: %s writes files itself and cannot save compressed %s", format.Name, path)
+		}
+		return m.Marshal(nil, value, opts...)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	bw := bufio.NewWriterSize(file, 64<<10)
+	var w io.Writer = bw
+	var closer io.Closer
+	switch ext {
+	case ".gz":
+		gz := gzip.NewWriter(bw)
+		w, closer = gz, gz
+	case ".zst":
+		z, err := zstd.NewWriter(bw)
+		if err != nil {
+			return err
+		}
+		w, closer = z, z
+	}
+	defer func() {
+		// the compressor is closed on errors too, releasing its goroutines
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err == nil {
+			err = bw.Flush()
+		}
+	}()
+	return m.Marshal(w, value, opts...)
+}
@@ -0,0 +1,207 @@
+// Package registry is an optional lookup of marshaller backends by file
+// name and content. Backends register themselves from init, so importing a
+// backend package (or registry/all for every pure-Go backend) is enough to
+// make Load and Save understand its files:
+//
+//	import (
+//		"github.com/itohio/EasyRobot/x/marshaller/registry"
+//		_ "github.com/itohio/EasyRobot/x/marshaller/registry/all"
+//	)
+//
+//	var t types.Tensor
+//	err := registry.Load("weights.npy.zst", &t)
+//
+// Constructing backends directly remains the primary API; the registry only
+// saves command line tools from mapping extensions to constructors.
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Magic is a byte signature at a fixed offset of a file.
+type Magic struct {
+	Offset int
+	Bytes  string
+}
+
+func (m Magic) match(head []byte) bool {
+	return len(head) >= m.Offset+len(m.Bytes) && bytes.Equal(head[m.Offset:m.Offset+len(m.Bytes)], []byte(m.Bytes))
+}
+
+// Format describes how to recognise and construct a backend.
+type Format struct {
+	Name       string
+	Extensions []string // lower case with the dot, e.g. ".yaml"
+	Magic      []Magic  // a matching signature identifies the format
+	// Sniff is a weaker content check for formats without a signature, used
+	// when the extension is missing or ambiguous.
+	Sniff func(head []byte) bool
+	// Files marks backends that open the path themselves: Load and Save pass
+	// neither a reader nor a writer, and compression is not available.
+	Files bool
+	// LoadOptions and SaveOptions derive backend options from the path.
+	LoadOptions func(path string) []types.Option
+	SaveOptions func(path string) []types.Option
+
+	NewMarshaller   func(opts ...types.Option) (types.Marshaller, error)   // nil if read only
+	NewUnmarshaller func(opts ...types.Option) (types.Unmarshaller, error) // nil if write only
+}
+
+var (
+	registryMu sync.RWMutex
+	formats    = make(map[string]Format)
+)
+
+// Register adds a format. Registering the same name twice replaces the
+// previous registration.
+func Register(f Format) {
+	if f.Name == "" || (f.NewMarshaller == nil && f.NewUnmarshaller == nil) {
+		panic("registry.Register: name and a constructor are required")
+	}
+	for i, ext := range f.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			panic(fmt.Sprintf("registry.Register: extension %q of %s must start with a dot", ext, f.Name))
+		}
+		f.Extensions[i] = strings.ToLower(ext)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	formats[f.Name] = f
+}
+
+// Lookup returns the format registered under name.
+func Lookup(name string) (Format, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := formats[name]
+	return f, ok
+}
+
+// Formats returns all registered formats sorted by name.
+func Formats() []Format {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	list := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// DetectError reports a file whose format could not be determined.
+type DetectError struct {
+	Path       string
+	Candidates []string // formats that remained possible, or all that were considered
+	Ambiguous  bool     // several candidates matched equally well
+}
+
+func (e *DetectError) Error() string {
+	if e.Ambiguous {
+		return fmt.Sprintf("registry: ambiguous format of %s, candidates: %s (use WithFormat)", e.Path, strings.Join(e.Candidates, ", "))
+	}
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("registry: unknown format of %s, no formats registered (import the backends or registry/all)", e.Path)
+	}
+	return fmt.Sprintf("registry: unknown format of %s, known formats: %s", e.Path, strings.Join(e.Candidates, ", "))
+}
+
+func (f Format) hasExtension(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, ext := range f.Extensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Format) matchMagic(head []byte) bool {
+	for _, m := range f.Magic {
+		if m.match(head) {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect picks the format of a file from its name and its first bytes (nil
+// when only the name is known). A signature match wins; otherwise the
+// extension decides, with Sniff breaking ties, and files without a known
+// extension are sniffed. usable filters the formats considered.
+func Detect(path string, head []byte, usable func(Format) bool) (Format, error) {
+	var all []Format
+	for _, f := range Formats() {
+		if usable == nil || usable(f) {
+			all = append(all, f)
+		}
+	}
+
+	var byMagic, byExt []Format
+	for _, f := range all {
+		if f.matchMagic(head) {
+			byMagic = append(byMagic, f)
+		}
+		if f.hasExtension(path) {
+			byExt = append(byExt, f)
+		}
+	}
+
+	candidates := byExt
+	switch {
+	case len(byMagic) == 1:
+		return byMagic[0], nil
+	case len(byMagic) > 1:
+		if both := filter(byMagic, func(f Format) bool { return f.hasExtension(path) }); len(both) > 0 {
+			candidates = both
+		} else {
+			candidates = byMagic
+		}
+	case head != nil && len(byExt) > 0:
+		// a format with a signature that does not match is ruled out
+		candidates = filter(byExt, func(f Format) bool { return len(f.Magic) == 0 })
+	}
+
+	if len(candidates) == 0 && head != nil {
+		candidates = filter(all, func(f Format) bool { return f.Sniff != nil && f.Sniff(head) })
+	} else if len(candidates) > 1 && head != nil {
+		if sniffed := filter(candidates, func(f Format) bool { return f.Sniff != nil && f.Sniff(head) }); len(sniffed) > 0 {
+			candidates = sniffed
+		}
+	}
+
+	switch len(candidates) {
+	case 1:
+		return candidates[0], nil
+	case 0:
+		return Format{}, &DetectError{Path: path, Candidates: names(all)}
+	default:
+		return Format{}, &DetectError{Path: path, Candidates: names(candidates), Ambiguous: true}
+	}
+}
+
+func filter(list []Format, keep func(Format) bool) []Format {
+	var out []Format
+	for _, f := range list {
+		if keep(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func names(list []Format) []string {
+	out := make([]string, len(list))
+	for i, f := range list {
+		out[i] = f.Name
+	}
+	return out
+}
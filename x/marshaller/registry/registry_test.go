@@ -0,0 +1,174 @@
+package registry_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	_ "github.com/itohio/EasyRobot/x/marshaller/registry/all"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+var want = []float32{0, 1, 2, 3, 4, 5}
+
+func sample() types.Tensor {
+	return tensor.FromFloat32(tensor.NewShape(2, 3), want)
+}
+
+func check(t *testing.T, name string, got types.Tensor) {
+	t.Helper()
+	if got == nil || got.Shape().Rank() != 2 || got.Shape()[0] != 2 || got.Shape()[1] != 3 {
+		t.Fatalf("%s: got %v", name, got)
+	}
+	for i, v := range want {
+		if got.At(i/3, i%3) != float64(v) {
+			t.Fatalf("%s: element %d is %v, want %v", name, i, got.At(i/3, i%3), v)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"t.gob", "t.json", "t.yml", "t.gob.gz", "t.yaml.zst", "w.npy", "w.npy.zst", "c.pcd.gz", "c.ply", "t.msgpack", "t.cbor.zst"} {
+		path := filepath.Join(dir, name)
+		if err := registry.Save(path, sample()); err != nil {
+			t.Fatalf("%s: Save failed: %v", name, err)
+		}
+		var got types.Tensor
+		if err := registry.Load(path, &got); err != nil {
+			t.Fatalf("%s: Load failed: %v", name, err)
+		}
+		check(t, name, got)
+	}
+}
+
+func TestCompressedBySuffix(t *testing.T) {
+	dir := t.TempDir()
+	for ext, magic := range map[string]string{".gz": "\x1f\x8b", ".zst": "\x28\xb5\x2f\xfd"} {
+		path := filepath.Join(dir, "c.json"+ext)
+		if err := registry.Save(path, sample()); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), magic) {
+			t.Errorf("%s: file starts with %q", ext, data[:4])
+		}
+	}
+}
+
+// failingMarshaller writes some data and then fails.
+type failingMarshaller struct{}
+
+func (failingMarshaller) Format() string { return "failing" }
+
+func (failingMarshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	if _, err := w.Write(make([]byte, 4<<20)); err != nil {
+		return err
+	}
+	return errFailing
+}
+
+var errFailing = errors.New("failing")
+
+func TestSaveFailure(t *testing.T) {
+	registry.Register(registry.Format{
+		Name:          "failing",
+		Extensions:    []string{".failing"},
+		NewMarshaller: func(...types.Option) (types.Marshaller, error) { return failingMarshaller{}, nil },
+	})
+	dir := t.TempDir()
+	for _, name := range []string{"c.failing", "c.failing.gz", "c.failing.zst"} {
+		path := filepath.Join(dir, name)
+		if err := registry.Save(path, sample()); !errors.Is(err, errFailing) {
+			t.Errorf("%s: got %v, want the marshaller error", name, err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: failed Save left the file behind: %v", name, err)
+		}
+	}
+}
+
+func TestDetectByContent(t *testing.T) {
+	dir := t.TempDir()
+	points := sample()
+
+	// signatures win over misleading names, compressed or not
+	for _, name := range []string{"cloud.ply", "cloud.ply.zst"} {
+		if err := registry.Save(filepath.Join(dir, name), points); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Rename(filepath.Join(dir, "cloud.ply"), filepath.Join(dir, "cloud.dat")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "cloud.ply.zst"), filepath.Join(dir, "cloud.bin")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"cloud.dat", "cloud.bin"} {
+		var got types.Tensor
+		if err := registry.Load(filepath.Join(dir, name), &got); err != nil {
+			t.Fatalf("%s: Load failed: %v", name, err)
+		}
+		check(t, name, got)
+	}
+
+	// formats without a signature or known extension are sniffed
+	if err := registry.Save(filepath.Join(dir, "cloud.pcd"), points); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "cloud.pcd"), filepath.Join(dir, "cloud.txt")); err != nil {
+		t.Fatal(err)
+	}
+	var got types.Tensor
+	if err := registry.Load(filepath.Join(dir, "cloud.txt"), &got); err != nil {
+		t.Fatal(err)
+	}
+	check(t, "cloud.txt", got)
+}
+
+func TestDetectErrors(t *testing.T) {
+	_, err := registry.Detect("data.xyz", []byte{1, 2, 3, 4}, nil)
+	var de *registry.DetectError
+	if !errors.As(err, &de) || de.Ambiguous {
+		t.Fatalf("expected unknown format error, got %v", err)
+	}
+	for _, name := range []string{"json", "ply", "numpy"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not list %s", err, name)
+		}
+	}
+
+	registry.Register(registry.Format{Name: "test-a", Extensions: []string{".tst"}, NewMarshaller: newText})
+	registry.Register(registry.Format{Name: "test-b", Extensions: []string{".tst"}, NewMarshaller: newText})
+	err = registry.Save(filepath.Join(t.TempDir(), "x.tst"), sample())
+	if !errors.As(err, &de) || !de.Ambiguous || strings.Join(de.Candidates, ",") != "test-a,test-b" {
+		t.Fatalf("expected ambiguity between test formats, got %v", err)
+	}
+	if err := registry.Save(filepath.Join(t.TempDir(), "x.tst"), sample(), registry.WithFormat("json")); err != nil {
+		t.Fatalf("WithFormat: %v", err)
+	}
+
+	if _, err := registry.Detect("model.tflite", nil, func(f registry.Format) bool { return f.NewMarshaller != nil }); err == nil {
+		t.Error("saving to a read-only format should fail")
+	}
+}
+
+func newText(opts ...types.Option) (types.Marshaller, error) {
+	f, _ := registry.Lookup("text")
+	return f.NewMarshaller(opts...)
+}
+
+func TestNumpyMagic(t *testing.T) {
+	f, err := registry.Detect("weights", []byte("\x93NUMPY\x01\x00"), nil)
+	if err != nil || f.Name != "numpy" {
+		t.Fatalf("got %q, %v", f.Name, err)
+	}
+}
@@ -0,0 +1,155 @@
+package safetensors
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/storage"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+)
+
+// File is an opened safetensors file. Tensors returned by a mapped File share
+// memory with the mapping and must not be used after Close.
+type File struct {
+	opts    types.Options
+	storage types.MappedStorage
+	region  types.MappedRegion
+	data    []byte // Data section
+	header  header
+}
+
+// Open maps the safetensors file at path. The storage is created by
+// Options.MappedStorageFactory, or storage.NewFileMap when none is given.
+// Options.TensorFactory and Options.DestinationType make Tensor return copies.
+func Open(path string, opts ...types.Option) (*File, error) {
+	f := &File{}
+	for _, opt := range opts {
+		opt.Apply(&f.opts)
+	}
+	factory := f.opts.MappedStorageFactory
+	if factory == nil {
+		factory = storage.NewFileMap()
+	}
+
+	st, err := factory(path, true)
+	if err != nil {
+		return nil, types.NewError("open", "safetensors", path, err)
+	}
+	region, err := st.Map(0, 0)
+	if err != nil {
+		st.Close()
+		return nil, types.NewError("open", "safetensors", path, err)
+	}
+	f.storage, f.region = st, region
+	if err := f.parse(region.Bytes()); err != nil {
+		f.Close()
+		return nil, types.NewError("open", "safetensors", path, err)
+	}
+	return f, nil
+}
+
+// Parse reads a safetensors file held in memory. Tensors share memory with b.
+func Parse(b []byte, opts ...types.Option) (*File, error) {
+	f := &File{}
+	for _, opt := range opts {
+		opt.Apply(&f.opts)
+	}
+	if err := f.parse(b); err != nil {
+		return nil, types.NewError("parse", "safetensors", "header", err)
+	}
+	return f, nil
+}
+
+func (f *File) parse(b []byte) error {
+	h, err := parseHeader(b)
+	if err != nil {
+		return err
+	}
+	f.header = h
+	f.data = b[h.size:]
+	return nil
+}
+
+// Names returns the sorted tensor names.
+func (f *File) Names() []string {
+	names := make([]string, 0, len(f.header.tensors))
+	for name := range f.header.tensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Metadata returns the free-form metadata of the file.
+func (f *File) Metadata() map[string]string {
+	return f.header.metadata
+}
+
+// Info returns the stored description of the named tensor.
+func (f *File) Info(name string) (Info, bool) {
+	info, ok := f.header.tensors[name]
+	return info, ok
+}
+
+// Tensor returns the named tensor. F32, F64 and signed integer tensors are
+// views into the file when the host is little endian; other element types
+// are decoded into a new tensor (see rawtensor.Elem.DataType).
+func (f *File) Tensor(name string) (types.Tensor, error) {
+	if f.header.tensors == nil {
+		return nil, fmt.Errorf("safetensors: file is closed")
+	}
+	info, ok := f.header.tensors[name]
+	if !ok {
+		return nil, fmt.Errorf("safetensors: no tensor %q", name)
+	}
+	if f.opts.TensorFactory == nil && f.opts.DestinationType == 0 {
+		b := f.data[info.DataOffsets[0]:info.DataOffsets[1]]
+		if t, ok := rawtensor.View(b, dtypes[info.DType], shapeOf(info)); ok {
+			return t, nil
+		}
+	}
+	return decodeTensor(f.data, info, f.opts)
+}
+
+// Tensors returns every tensor of the file keyed by name.
+func (f *File) Tensors() (map[string]types.Tensor, error) {
+	result := make(map[string]types.Tensor, len(f.header.tensors))
+	for name := range f.header.tensors {
+		t, err := f.Tensor(name)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = t
+	}
+	return result, nil
+}
+
+// LoadInto copies the file, a state dict, into the parameters of an initialized
+// model. Every model parameter must be present unless partial is set.
+func (f *File) LoadInto(model types.Model, partial bool) error {
+	tensors, err := f.Tensors()
+	if err != nil {
+		return err
+	}
+	return models.LoadStateDict(model, tensors, partial)
+}
+
+// Close unmaps the file. Tensors viewing the file become invalid.
+func (f *File) Close() error {
+	var err error
+	if f.region != nil {
+		err = f.region.Unmap()
+		f.region = nil
+	}
+	if f.storage != nil {
+		if cerr := f.storage.Close(); err == nil {
+			err = cerr
+		}
+		f.storage = nil
+	}
+	f.data = nil
+	f.header.tensors = nil
+	return err
+}
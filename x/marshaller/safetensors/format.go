@@ -0,0 +1,207 @@
+// Package safetensors implements the safetensors format: an 8 byte little
+// endian header length, a JSON header describing every tensor and the raw
+// little endian tensor data.
+//
+// Open maps a file through a types.MappedStorageFactory and returns tensors
+// that share memory with the mapping whenever the element type allows it.
+// The Marshaller/Unmarshaller pair handles streams of named tensors and
+// model state dicts (see models.StateDict).
+package safetensors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/itohio/EasyRobot/x/marshaller/internal/rawtensor"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// metadataKey holds the free-form string metadata in the header.
+const metadataKey = "__metadata__"
+
+// maxHeaderSize guards against allocating absurd headers from corrupt files.
+const maxHeaderSize = 100 << 20
+
+// dtypes maps safetensors dtype names to raw element types.
+var dtypes = map[string]rawtensor.Elem{
+	"F64":  {Kind: rawtensor.Float, Size: 8},
+	"F32":  {Kind: rawtensor.Float, Size: 4},
+	"F16":  {Kind: rawtensor.Float, Size: 2},
+	"BF16": {Kind: rawtensor.BFloat16, Size: 2},
+	"I64":  {Kind: rawtensor.Int, Size: 8},
+	"I32":  {Kind: rawtensor.Int, Size: 4},
+	"I16":  {Kind: rawtensor.Int, Size: 2},
+	"I8":   {Kind: rawtensor.Int, Size: 1},
+	"U64":  {Kind: rawtensor.Uint, Size: 8},
+	"U32":  {Kind: rawtensor.Uint, Size: 4},
+	"U16":  {Kind: rawtensor.Uint, Size: 2},
+	"U8":   {Kind: rawtensor.Uint, Size: 1},
+	"BOOL": {Kind: rawtensor.Bool, Size: 1},
+}
+
+func dtypeName(e rawtensor.Elem) string {
+	for name, elem := range dtypes {
+		if elem == e {
+			return name
+		}
+	}
+	return ""
+}
+
+// Info describes a tensor stored in a safetensors file.
+type Info struct {
+	DType       string `json:"dtype"`
+	Shape       []int  `json:"shape"`
+	DataOffsets [2]int `json:"data_offsets"` // Relative to the start of the data section
+}
+
+// header is the decoded JSON header.
+type header struct {
+	size     int // Bytes before the data section
+	tensors  map[string]Info
+	metadata map[string]string
+}
+
+// parseHeader decodes the header at the start of b.
+func parseHeader(b []byte) (header, error) {
+	var h header
+	if len(b) < 8 {
+		return h, fmt.Errorf("file too short")
+	}
+	n := binary.LittleEndian.Uint64(b)
+	if n > maxHeaderSize || uint64(len(b)-8) < n {
+		return h, fmt.Errorf("invalid header length %d", n)
+	}
+	h.size = 8 + int(n)
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b[8:h.size], &raw); err != nil {
+		return h, fmt.Errorf("header: %w", err)
+	}
+	h.tensors = make(map[string]Info, len(raw))
+	for name, msg := range raw {
+		if name == metadataKey {
+			if err := json.Unmarshal(msg, &h.metadata); err != nil {
+				return h, fmt.Errorf("metadata: %w", err)
+			}
+			continue
+		}
+		var info Info
+		if err := json.Unmarshal(msg, &info); err != nil {
+			return h, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := validate(info, len(b)-h.size); err != nil {
+			return h, fmt.Errorf("%s: %w", name, err)
+		}
+		h.tensors[name] = info
+	}
+	return h, nil
+}
+
+// validate checks that the tensor lies within the data section and that its
+// size matches the dtype and shape.
+func validate(info Info, dataSize int) error {
+	e, ok := dtypes[info.DType]
+	if !ok {
+		return fmt.Errorf("unsupported dtype %s", info.DType)
+	}
+	n := 1
+	for _, d := range info.Shape {
+		if d < 0 {
+			return fmt.Errorf("invalid shape %v", info.Shape)
+		}
+		n *= d
+	}
+	begin, end := info.DataOffsets[0], info.DataOffsets[1]
+	if begin < 0 || end < begin || end > dataSize {
+		return fmt.Errorf("data offsets %v outside of %d data bytes", info.DataOffsets, dataSize)
+	}
+	if end-begin != n*e.Size {
+		return fmt.Errorf("%d bytes for shape %v of %s", end-begin, info.Shape, info.DType)
+	}
+	return nil
+}
+
+// shapeOf returns the tensor shape of info; scalars load as single element vectors.
+func shapeOf(info Info) types.Shape {
+	if len(info.Shape) == 0 {
+		return types.Shape{1}
+	}
+	return types.Shape(append([]int(nil), info.Shape...))
+}
+
+// decodeTensor copies the tensor described by info out of the data section.
+func decodeTensor(data []byte, info Info, opts types.Options) (types.Tensor, error) {
+	e := dtypes[info.DType]
+	shape := shapeOf(info)
+	values, err := rawtensor.Decode(data[info.DataOffsets[0]:info.DataOffsets[1]], e, binary.LittleEndian, shape.Size())
+	if err != nil {
+		return nil, err
+	}
+	return rawtensor.Wrap(shape, values, opts)
+}
+
+// write encodes the tensors and metadata. The header is padded so that the
+// data section, and with it every tensor, is 8 byte aligned.
+func write(w io.Writer, tensors map[string]types.Tensor, metadata map[string]string) error {
+	names := make([]string, 0, len(tensors))
+	for name := range tensors {
+		if name == metadataKey {
+			return fmt.Errorf("reserved tensor name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hdr := make(map[string]any, len(names)+1)
+	if len(metadata) > 0 {
+		hdr[metadataKey] = metadata
+	}
+	blobs := make([][]byte, len(names))
+	offset := 0
+	for i, name := range names {
+		t := tensors[name]
+		e, raw, err := rawtensor.Encode(t)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		dtype := dtypeName(e)
+		if dtype == "" {
+			return fmt.Errorf("%s: unsupported element type %v", name, e)
+		}
+		hdr[name] = Info{
+			DType:       dtype,
+			Shape:       append([]int{}, t.Shape()...),
+			DataOffsets: [2]int{offset, offset + len(raw)},
+		}
+		blobs[i] = raw
+		offset += len(raw)
+	}
+
+	js, err := json.Marshal(hdr)
+	if err != nil {
+		return err
+	}
+	if pad := (8 - len(js)%8) % 8; pad > 0 {
+		js = append(js, bytes.Repeat([]byte{' '}, pad)...)
+	}
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(js)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(js); err != nil {
+		return err
+	}
+	for _, raw := range blobs {
+		if _, err := w.Write(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
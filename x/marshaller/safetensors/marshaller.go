@@ -0,0 +1,56 @@
+package safetensors
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+)
+
+// Marshaller implements safetensors marshalling.
+type Marshaller struct {
+	opts types.Options
+}
+
+// NewMarshaller creates a new safetensors marshaller.
+func NewMarshaller(opts ...types.Option) *Marshaller {
+	m := &Marshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&m.opts)
+	}
+	return m
+}
+
+// Format returns the format name.
+func (m *Marshaller) Format() string {
+	return "safetensors"
+}
+
+// Marshal writes a map[string]types.Tensor or the state dict of a model.
+// Options.Metadata is stored as the file metadata.
+func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	localOpts := m.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+
+	var tensors map[string]types.Tensor
+	switch v := value.(type) {
+	case nil:
+		return types.NewError("marshal", "safetensors", "nil value", nil)
+	case types.Model:
+		tensors = models.StateDict(v)
+	case map[string]types.Tensor:
+		tensors = v
+	default:
+		return types.NewError("marshal", "safetensors", fmt.Sprintf("unsupported value type %T", value), nil)
+	}
+
+	if err := write(w, tensors, localOpts.Metadata); err != nil {
+		return types.NewError("marshal", "safetensors", "encoding", err)
+	}
+	return nil
+}
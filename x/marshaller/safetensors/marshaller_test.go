@@ -0,0 +1,179 @@
+package safetensors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/itohio/EasyRobot/x/marshaller/storage"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+func newModel(t *testing.T) types.Model {
+	t.Helper()
+	dense1, err := layers.NewDense(3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dense2, err := layers.NewDense(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := nn.NewSequentialModelBuilder(tensor.NewShape(3)).
+		AddLayer(dense1).
+		AddLayer(layers.NewReLU("relu")).
+		AddLayer(dense2).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Init(tensor.NewShape(3)); err != nil {
+		t.Fatal(err)
+	}
+	return model
+}
+
+func forward(t *testing.T, model types.Model) []float64 {
+	t.Helper()
+	out, err := model.Forward(tensor.FromFloat32(tensor.NewShape(3), []float32{.5, -1, 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []float64{out.At(0), out.At(1)}
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := map[string]types.Tensor{
+		"weights": tensor.FromFloat32(tensor.NewShape(2, 2), []float32{1, -2, 3.5, 4}),
+		"ids":     tensor.FromArray(tensor.NewShape(3), []int64{-7, 0, 1 << 40}),
+		"small":   tensor.FromArray(tensor.NewShape(1), []int8{-3}),
+	}
+
+	var buf bytes.Buffer
+	err := NewMarshaller().Marshal(&buf, src, types.WithMetadata(map[string]string{"format": "pt"}))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if n := binary.LittleEndian.Uint64(buf.Bytes()); (8+n)%8 != 0 {
+		t.Errorf("data section at %d is not 8 byte aligned", 8+n)
+	}
+
+	f, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if f.Metadata()["format"] != "pt" {
+		t.Errorf("metadata = %v", f.Metadata())
+	}
+	if info, _ := f.Info("ids"); info.DType != "I64" {
+		t.Errorf("ids dtype = %s, want I64", info.DType)
+	}
+
+	var dst map[string]types.Tensor
+	if err := NewUnmarshaller().Unmarshal(&buf, &dst); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if w := dst["weights"].Data().([]float32); w[2] != 3.5 || !dst["weights"].Shape().Equal(tensor.NewShape(2, 2)) {
+		t.Errorf("weights = %v %v", dst["weights"].Shape(), w)
+	}
+	if ids := dst["ids"].Data().([]int64); ids[2] != 1<<40 {
+		t.Errorf("ids = %v", ids)
+	}
+	if s := dst["small"].Data().([]int8); s[0] != -3 {
+		t.Errorf("small = %v", s)
+	}
+}
+
+func TestHalfPrecision(t *testing.T) {
+	header := `{"h":{"dtype":"F16","shape":[2],"data_offsets":[0,4]},"b":{"dtype":"BF16","shape":[2],"data_offsets":[4,8]}}`
+	var file bytes.Buffer
+	binary.Write(&file, binary.LittleEndian, uint64(len(header)))
+	file.WriteString(header)
+	file.Write([]byte{0x00, 0x3c, 0x00, 0xc2}) // 1, -3
+	file.Write([]byte{0x80, 0x3f, 0x20, 0x41}) // 1, 10
+
+	f, err := Parse(file.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]float32{"h": {1, -3}, "b": {1, 10}} {
+		tt, err := f.Tensor(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tt.Data().([]float32); got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+
+	bad := bytes.Replace(file.Bytes(), []byte(`[4,8]`), []byte(`[4,9]`), 1)
+	if _, err := Parse(bad); err == nil {
+		t.Error("inconsistent data offsets should be rejected")
+	}
+}
+
+func TestMappedStateDict(t *testing.T) {
+	src := newModel(t)
+	path := filepath.Join(t.TempDir(), "model.safetensors")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewMarshaller().Marshal(out, src); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	f, err := Open(path, types.WithMappedStorageFactory(storage.NewFileMap()))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if names := f.Names(); len(names) != 4 || names[0] != "0.bias" || names[3] != "2.weight" {
+		t.Errorf("names = %v", names)
+	}
+
+	// F32 tensors view the mapping instead of copying it
+	weight, err := f.Tensor("0.weight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := weight.Data().([]float32)
+	region := f.region.Bytes()
+	start := uintptr(unsafe.Pointer(&region[0]))
+	if p := uintptr(unsafe.Pointer(&data[0])); p < start || p >= start+uintptr(len(region)) {
+		t.Error("tensor was copied out of the mapping")
+	}
+
+	dst := newModel(t)
+	if err := f.LoadInto(dst, false); err != nil {
+		t.Fatalf("LoadInto failed: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want, got := forward(t, src), forward(t, dst)
+	if want[0] != got[0] || want[1] != got[1] {
+		t.Errorf("restored output %v, want %v", got, want)
+	}
+
+	// Unmarshal maps named files when a storage factory is given
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	other := newModel(t)
+	err = NewUnmarshaller(types.WithMappedStorageFactory(storage.NewFileMap())).Unmarshal(in, other)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got := forward(t, other); want[0] != got[0] || want[1] != got[1] {
+		t.Errorf("unmarshalled output %v, want %v", got, want)
+	}
+}
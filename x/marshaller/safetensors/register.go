@@ -0,0 +1,23 @@
+package safetensors
+
+import (
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "safetensors",
+		Extensions: []string{".safetensors"},
+		// an 8 byte header length followed by the JSON header
+		Sniff: func(head []byte) bool {
+			return len(head) > 8 && head[8] == '{'
+		},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
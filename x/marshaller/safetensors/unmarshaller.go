@@ -0,0 +1,88 @@
+package safetensors
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Unmarshaller implements safetensors unmarshalling.
+type Unmarshaller struct {
+	opts types.Options
+}
+
+// NewUnmarshaller creates a new safetensors unmarshaller.
+func NewUnmarshaller(opts ...types.Option) *Unmarshaller {
+	u := &Unmarshaller{
+		opts: types.Options{},
+	}
+	for _, opt := range opts {
+		opt.Apply(&u.opts)
+	}
+	return u
+}
+
+// Format returns the format name.
+func (u *Unmarshaller) Format() string {
+	return "safetensors"
+}
+
+// Unmarshal decodes a safetensors stream into dst, which may be:
+//   - *map[string]types.Tensor: every tensor, copied out of the stream
+//   - types.Model or *types.Model: a state dict loaded into the initialized model's parameters
+//
+// When Options.MappedStorageFactory is set and r is a named file (such as
+// *os.File), the file is mapped instead of read into memory.
+// Use Open for zero-copy access to the tensors.
+func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) error {
+	localOpts := u.opts
+	for _, opt := range opts {
+		opt.Apply(&localOpts)
+	}
+
+	f, err := u.open(r, localOpts)
+	if err != nil {
+		return types.NewError("unmarshal", "safetensors", "decoding", err)
+	}
+	defer f.Close()
+
+	switch d := dst.(type) {
+	case *map[string]types.Tensor:
+		tensors := make(map[string]types.Tensor, len(f.header.tensors))
+		for name, info := range f.header.tensors {
+			// Always copy: the mapping is released on return
+			t, err := decodeTensor(f.data, info, localOpts)
+			if err != nil {
+				return types.NewError("unmarshal", "safetensors", name, err)
+			}
+			tensors[name] = t
+		}
+		*d = tensors
+	case types.Model:
+		if err := f.LoadInto(d, false); err != nil {
+			return types.NewError("unmarshal", "safetensors", "state dict", err)
+		}
+	case *types.Model:
+		if *d == nil {
+			return types.NewError("unmarshal", "safetensors", "state dict needs an initialized model", nil)
+		}
+		if err := f.LoadInto(*d, false); err != nil {
+			return types.NewError("unmarshal", "safetensors", "state dict", err)
+		}
+	default:
+		return types.NewError("unmarshal", "safetensors", fmt.Sprintf("unsupported destination %T", dst), nil)
+	}
+	return nil
+}
+
+func (u *Unmarshaller) open(r io.Reader, opts types.Options) (*File, error) {
+	if named, ok := r.(interface{ Name() string }); ok && opts.MappedStorageFactory != nil {
+		return Open(named.Name(), types.WithMappedStorageFactory(opts.MappedStorageFactory))
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
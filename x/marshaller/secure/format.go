@@ -0,0 +1,203 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Sealed payload layout, integers little endian:
+//
+//	magic      "ERSEAL"
+//	version    u8, currently 1
+//	cipher     u8, see Cipher
+//	signer     u8 length + signing key ID
+//	key        u8 length + encryption key ID, empty when not encrypted
+//	nonce      u8 length + nonce, empty when not encrypted
+//	payload    u64 length + payload, encrypted with everything before the
+//	           payload as additional data
+//	signature  Ed25519 signature of everything before it
+const (
+	// Magic starts every sealed payload.
+	Magic = "ERSEAL"
+
+	formatVersion = 1
+)
+
+// ErrVerification is wrapped by every error about a sealed payload that is
+// malformed, tampered with, signed by an untrusted key or not decryptable.
+var ErrVerification = errors.New("secure: verification failed")
+
+func verificationError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrVerification, fmt.Sprintf(format, args...))
+}
+
+func newAEAD(c Cipher, key []byte) (cipher.AEAD, error) {
+	switch c {
+	case AESGCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case ChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	}
+	return nil, fmt.Errorf("secure: unsupported cipher %v", c)
+}
+
+// seal signs and, when a cipher is configured, encrypts payload.
+func seal(payload []byte, cfg config) ([]byte, error) {
+	if len(cfg.signKey) != crypto.PrivateKeySize {
+		return nil, errors.New("secure: no signing key")
+	}
+	if len(cfg.signID) > 255 || len(cfg.encID) > 255 {
+		return nil, errors.New("secure: key IDs are limited to 255 bytes")
+	}
+
+	var aead cipher.AEAD
+	var nonce []byte
+	if cfg.cipher != None {
+		var err error
+		if aead, err = newAEAD(cfg.cipher, cfg.encKey); err != nil {
+			return nil, err
+		}
+		nonce = make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+	}
+
+	// room for the header, the payload, an AEAD tag and the signature
+	out := make([]byte, 0, len(Magic)+5+len(cfg.signID)+len(cfg.encID)+len(nonce)+8+len(payload)+16+crypto.SignatureSize)
+	out = append(out, Magic...)
+	out = append(out, formatVersion, byte(cfg.cipher))
+	out = append(out, byte(len(cfg.signID)))
+	out = append(out, cfg.signID...)
+	encID := ""
+	if aead != nil {
+		encID = cfg.encID
+	}
+	out = append(out, byte(len(encID)))
+	out = append(out, encID...)
+	out = append(out, byte(len(nonce)))
+	out = append(out, nonce...)
+
+	size := len(payload)
+	if aead != nil {
+		size += aead.Overhead()
+	}
+	out = binary.LittleEndian.AppendUint64(out, uint64(size))
+	if aead != nil {
+		out = aead.Seal(out, nonce, payload, out)
+	} else {
+		out = append(out, payload...)
+	}
+	return append(out, cfg.signKey.Sign(out)...), nil
+}
+
+// open verifies data and returns its payload, decrypted if needed. When
+// padded is set, zero bytes after the signature are ignored; storage can
+// grow but not shrink.
+func open(data []byte, cfg config, padded bool) ([]byte, error) {
+	if len(cfg.trusted) == 0 {
+		return nil, errors.New("secure: no trusted keys")
+	}
+	r := reader{buf: data}
+	if magic := r.next(len(Magic)); string(magic) != Magic {
+		return nil, verificationError("not a sealed payload")
+	}
+	head := r.next(2)
+	signID := string(r.short())
+	encID := string(r.short())
+	nonce := r.short()
+	sizeBytes := r.next(8)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if head[0] != formatVersion {
+		return nil, verificationError("unsupported version %d", head[0])
+	}
+	header := data[:r.pos]
+	size := binary.LittleEndian.Uint64(sizeBytes)
+	if size > uint64(len(data)-r.pos) {
+		return nil, verificationError("truncated payload")
+	}
+	payload := r.next(int(size))
+	signed := data[:r.pos]
+	sig := r.next(crypto.SignatureSize)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if rest := data[r.pos:]; len(rest) > 0 && (!padded || len(bytes.Trim(rest, "\x00")) > 0) {
+		return nil, verificationError("%d trailing bytes", len(rest))
+	}
+
+	key, ok := cfg.trusted[signID]
+	if !ok {
+		return nil, verificationError("untrusted signing key %q", signID)
+	}
+	if !key.Verify(signed, sig) {
+		return nil, verificationError("bad signature by %q", signID)
+	}
+
+	c := Cipher(head[1])
+	if c == None {
+		if len(cfg.decKeys) > 0 {
+			return nil, verificationError("payload is not encrypted")
+		}
+		return payload, nil
+	}
+	encKey, ok := cfg.decKeys[encID]
+	if !ok {
+		return nil, verificationError("no decryption key %q", encID)
+	}
+	aead, err := newAEAD(c, encKey)
+	if err != nil {
+		return nil, verificationError("%v", err)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, verificationError("invalid nonce")
+	}
+	plain, err := aead.Open(nil, nonce, payload, header)
+	if err != nil {
+		return nil, verificationError("decrypting with %q: %v", encID, err)
+	}
+	return plain, nil
+}
+
+// reader consumes the header, remembering the first error.
+type reader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.buf)-r.pos {
+		r.err = verificationError("truncated header")
+		return nil
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// short reads a u8 length prefixed field.
+func (r *reader) short() []byte {
+	n := r.next(1)
+	if n == nil {
+		return nil
+	}
+	return r.next(int(n[0]))
+}
@@ -0,0 +1,47 @@
+package secure
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Marshaller wraps another marshaller and seals its output: the payload is
+// signed with WithSigningKey and, with WithEncryptionKey, encrypted.
+type Marshaller struct {
+	inner types.Marshaller
+	opts  types.Options
+	cfg   config
+}
+
+// NewMarshaller creates a marshaller sealing the output of inner.
+func NewMarshaller(inner types.Marshaller, opts ...types.Option) *Marshaller {
+	m := &Marshaller{inner: inner}
+	m.opts, m.cfg = applyOptions(types.Options{}, config{}, opts)
+	return m
+}
+
+// Format returns the format name, "secure+" and the wrapped format.
+func (m *Marshaller) Format() string {
+	return "secure+" + m.inner.Format()
+}
+
+// Marshal encodes value with the wrapped marshaller, which receives opts
+// as well, and writes the sealed result.
+func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error {
+	_, cfg := applyOptions(m.opts, m.cfg, opts)
+
+	var buf bytes.Buffer
+	if err := m.inner.Marshal(&buf, value, opts...); err != nil {
+		return err
+	}
+	sealed, err := seal(buf.Bytes(), cfg)
+	if err != nil {
+		return types.NewError("marshal", m.Format(), "sealing", err)
+	}
+	if _, err := w.Write(sealed); err != nil {
+		return types.NewError("marshal", m.Format(), "write", err)
+	}
+	return nil
+}
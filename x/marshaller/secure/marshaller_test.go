@@ -0,0 +1,224 @@
+package secure
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/crypto"
+	"github.com/itohio/EasyRobot/x/marshaller/gob"
+	"github.com/itohio/EasyRobot/x/marshaller/graph"
+	"github.com/itohio/EasyRobot/x/marshaller/json"
+	"github.com/itohio/EasyRobot/x/marshaller/msgpack"
+	"github.com/itohio/EasyRobot/x/marshaller/storage"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	graphlib "github.com/itohio/EasyRobot/x/math/graph"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+func testKeys(t *testing.T, seed int64) (crypto.PubKey, crypto.PrivKey) {
+	t.Helper()
+	pub, priv, err := crypto.FromSeed(rand.New(rand.NewSource(seed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func sample() types.Tensor {
+	return tensor.FromArray(tensor.NewShape(2, 3), []float32{1, 2, 3, 4, 5, 6})
+}
+
+func checkSample(t *testing.T, got types.Tensor) {
+	t.Helper()
+	want := sample()
+	for i := range want.Size() {
+		if got.At(i) != want.At(i) {
+			t.Fatalf("element %d = %v, want %v", i, got.At(i), want.At(i))
+		}
+	}
+}
+
+func sealSample(t *testing.T, opts ...types.Option) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NewMarshaller(gob.NewMarshaller(), opts...).Marshal(&buf, sample()); err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func openSample(data []byte, opts ...types.Option) (types.Tensor, error) {
+	var got types.Tensor
+	err := NewUnmarshaller(gob.NewUnmarshaller(), opts...).Unmarshal(bytes.NewReader(data), &got)
+	return got, err
+}
+
+func TestRoundTrip(t *testing.T) {
+	pub, priv := testKeys(t, 1)
+	key := bytes.Repeat([]byte{7}, 32)
+	backends := []struct {
+		m types.Marshaller
+		u types.Unmarshaller
+	}{
+		{gob.NewMarshaller(), gob.NewUnmarshaller()},
+		{json.NewMarshaller(), json.NewUnmarshaller()},
+		{msgpack.NewMarshaller(), msgpack.NewUnmarshaller()},
+	}
+
+	for _, b := range backends {
+		for _, c := range []Cipher{None, AESGCM, ChaCha20Poly1305} {
+			t.Run(b.m.Format()+"/"+c.String(), func(t *testing.T) {
+				opts := []types.Option{WithSigningKey("", priv), WithTrustedKey("", pub)}
+				if c != None {
+					opts = append(opts, WithEncryptionKey(c, "k1", key))
+				}
+				m := NewMarshaller(b.m, opts...)
+				if m.Format() != "secure+"+b.m.Format() {
+					t.Errorf("Format() = %q", m.Format())
+				}
+				var buf bytes.Buffer
+				if err := m.Marshal(&buf, sample()); err != nil {
+					t.Fatalf("Marshal failed: %v", err)
+				}
+				if !bytes.HasPrefix(buf.Bytes(), []byte(Magic)) {
+					t.Fatal("missing magic")
+				}
+
+				var got types.Tensor
+				if err := NewUnmarshaller(b.u, opts...).Unmarshal(&buf, &got); err != nil {
+					t.Fatalf("Unmarshal failed: %v", err)
+				}
+				checkSample(t, got)
+			})
+		}
+	}
+}
+
+func TestFailClosed(t *testing.T) {
+	pub, priv := testKeys(t, 1)
+	otherPub, _ := testKeys(t, 2)
+	key := bytes.Repeat([]byte{7}, 32)
+	sign := WithSigningKey("robot", priv)
+	trust := WithTrustedKey("robot", pub)
+	encrypt := WithEncryptionKey(ChaCha20Poly1305, "k1", key)
+
+	signed := sealSample(t, sign)
+	encrypted := sealSample(t, sign, encrypt)
+
+	tests := []struct {
+		name string
+		data []byte
+		opts []types.Option
+	}{
+		{"no trusted keys", signed, nil},
+		{"untrusted signer", signed, []types.Option{WithTrustedKey("other", otherPub)}},
+		{"wrong key under the same ID", signed, []types.Option{WithTrustedKey("robot", otherPub)}},
+		{"not encrypted", signed, []types.Option{trust, WithDecryptionKey("k1", key)}},
+		{"unknown decryption key", encrypted, []types.Option{trust, WithDecryptionKey("k2", key)}},
+		{"no decryption key", encrypted, []types.Option{trust}},
+		{"truncated", signed[:len(signed)-1], []types.Option{trust}},
+		{"trailing bytes", append(append([]byte(nil), signed...), 0), []types.Option{trust}},
+		{"plain payload", signed[len(Magic):], []types.Option{trust}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := openSample(tt.data, tt.opts...); err == nil {
+				t.Fatal("Unmarshal succeeded")
+			}
+		})
+	}
+
+	// Any modified byte is detected
+	for _, data := range [][]byte{signed, encrypted} {
+		for i := range data {
+			tampered := append([]byte(nil), data...)
+			tampered[i] ^= 0x10
+			_, err := openSample(tampered, trust, encrypt)
+			if !errors.Is(err, ErrVerification) {
+				t.Fatalf("byte %d modified: got %v, want ErrVerification", i, err)
+			}
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	pub, priv := testKeys(t, 1)
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16)
+	old := sealSample(t, WithSigningKey("", priv), WithEncryptionKey(ChaCha20Poly1305, "2024", oldKey))
+
+	got, err := openSample(old, WithTrustedKey("", pub), WithEncryptionKey(AESGCM, "2025", newKey), WithDecryptionKey("2024", oldKey))
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	checkSample(t, got)
+}
+
+func TestMarshalWithoutSigningKey(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMarshaller(gob.NewMarshaller()).Marshal(&buf, sample()); err == nil {
+		t.Fatal("Marshal without a signing key succeeded")
+	}
+}
+
+// TestTarStorage keeps a graph in a tar archive through the storage
+// wrapper.
+func TestTarStorage(t *testing.T) {
+	pub, priv := testKeys(t, 1)
+	opts := []types.Option{
+		WithSigningKey("", priv),
+		WithTrustedKey("", pub),
+		WithEncryptionKey(AESGCM, "k1", bytes.Repeat([]byte{3}, 32)),
+	}
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "graph.tar")
+	paths := []types.Option{
+		graph.WithPath(filepath.Join(dir, "nodes.graph")),
+		graph.WithEdgesPath(filepath.Join(dir, "edges.graph")),
+		graph.WithLabelsPath(filepath.Join(dir, "data.graph")),
+	}
+	src := &graphlib.MatrixGraph{Matrix: mat.Matrix{{1, 2}, {3, 4}}, Obstacle: -1}
+
+	tar := storage.NewTarMap(archive)
+	mar, err := graph.NewMarshaller(NewStorageFactory(tar.Factory(), opts...), paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mar.Marshal(nil, src); err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := tar.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	unmar, err := graph.NewUnmarshaller(NewStorageFactory(storage.NewTarMap(archive).Factory(), opts...), paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored graph.StoredGraph
+	if err := unmar.Unmarshal(nil, &stored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	defer stored.Close()
+	restored := mat.New(2, 2)
+	if err := graphlib.ToMatrix[any, any](&stored, restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored[1][0] != 3 {
+		t.Errorf("restored %v, want %v", restored, src.Matrix)
+	}
+
+	// The archive is unreadable without the keys
+	unmar, err = graph.NewUnmarshaller(NewStorageFactory(storage.NewTarMap(archive).Factory(), WithTrustedKey("", pub)), paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locked graph.StoredGraph
+	if err := unmar.Unmarshal(nil, &locked); !errors.Is(err, ErrVerification) {
+		t.Fatalf("Unmarshal without the decryption key: got %v, want ErrVerification", err)
+	}
+}
@@ -0,0 +1,146 @@
+package secure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/itohio/EasyRobot/x/crypto"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Cipher selects the payload encryption.
+type Cipher uint8
+
+const (
+	// None leaves the payload in the clear; it is still signed.
+	None Cipher = iota
+	// AESGCM encrypts with AES-GCM using a 16, 24 or 32 byte key.
+	AESGCM
+	// ChaCha20Poly1305 encrypts with ChaCha20-Poly1305 using a 32 byte key,
+	// the faster choice on targets without AES instructions.
+	ChaCha20Poly1305
+)
+
+func (c Cipher) String() string {
+	switch c {
+	case None:
+		return "none"
+	case AESGCM:
+		return "aes-gcm"
+	case ChaCha20Poly1305:
+		return "chacha20-poly1305"
+	}
+	return "unknown"
+}
+
+// KeyID returns the default key ID of a public key: the first 8 bytes of
+// its SHA-256 in hex.
+func KeyID(pub crypto.PubKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// config holds the keys of a marshaller, unmarshaller or storage factory.
+type config struct {
+	signID  string
+	signKey crypto.PrivKey
+	trusted map[string]crypto.PubKey
+
+	cipher  Cipher
+	encID   string
+	encKey  []byte
+	decKeys map[string][]byte
+}
+
+func (c config) clone() config {
+	out := c
+	out.trusted = make(map[string]crypto.PubKey, len(c.trusted))
+	for id, k := range c.trusted {
+		out.trusted[id] = k
+	}
+	out.decKeys = make(map[string][]byte, len(c.decKeys))
+	for id, k := range c.decKeys {
+		out.decKeys[id] = k
+	}
+	return out
+}
+
+type configOption interface {
+	types.Option
+	applyConfig(*config)
+}
+
+type option func(*config)
+
+// Apply implements types.Option; keys never enter types.Options.
+func (o option) Apply(*types.Options) {}
+
+func (o option) applyConfig(cfg *config) { o(cfg) }
+
+// applyOptions applies opts to copies of the base options and keys.
+func applyOptions(base types.Options, cfg config, opts []types.Option) (types.Options, config) {
+	local := base
+	localCfg := cfg.clone()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt.Apply(&local)
+		if cfgOpt, ok := opt.(configOption); ok {
+			cfgOpt.applyConfig(&localCfg)
+		}
+	}
+	return local, localCfg
+}
+
+// WithSigningKey signs marshalled payloads with an Ed25519 key. An empty
+// keyID defaults to KeyID of the public key.
+func WithSigningKey(keyID string, key crypto.PrivKey) types.Option {
+	return option(func(cfg *config) {
+		if keyID == "" && len(key) == crypto.PrivateKeySize {
+			keyID = KeyID(key.Public())
+		}
+		cfg.signID, cfg.signKey = keyID, key
+	})
+}
+
+// WithTrustedKey accepts payloads signed by key under keyID when
+// unmarshalling. An empty keyID defaults to KeyID(key). At least one
+// trusted key is required to unmarshal.
+func WithTrustedKey(keyID string, key crypto.PubKey) types.Option {
+	return option(func(cfg *config) {
+		if keyID == "" {
+			keyID = KeyID(key)
+		}
+		if cfg.trusted == nil {
+			cfg.trusted = make(map[string]crypto.PubKey)
+		}
+		cfg.trusted[keyID] = key
+	})
+}
+
+// WithEncryptionKey encrypts marshalled payloads with cipher and key,
+// recorded under keyID. The key is also used to decrypt payloads naming
+// keyID.
+func WithEncryptionKey(cipher Cipher, keyID string, key []byte) types.Option {
+	return option(func(cfg *config) {
+		cfg.cipher, cfg.encID, cfg.encKey = cipher, keyID, key
+		addDecryptionKey(cfg, keyID, key)
+	})
+}
+
+// WithDecryptionKey adds a key for decrypting payloads naming keyID, e.g.
+// a retired key after rotation. Once any decryption key is set, payloads
+// that are not encrypted are refused.
+func WithDecryptionKey(keyID string, key []byte) types.Option {
+	return option(func(cfg *config) {
+		addDecryptionKey(cfg, keyID, key)
+	})
+}
+
+func addDecryptionKey(cfg *config, keyID string, key []byte) {
+	if cfg.decKeys == nil {
+		cfg.decKeys = make(map[string][]byte)
+	}
+	cfg.decKeys[keyID] = key
+}
@@ -0,0 +1,166 @@
+package secure
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/itohio/EasyRobot/x/marshaller/storage"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// NewStorageFactory wraps factory so that everything it stores is sealed,
+// e.g. graph files in a storage.TarFactory archive. Opening a storage
+// verifies and decrypts its content into memory; closing a writable one
+// seals the content and writes it back, so it must be closed before a
+// TarFactory commit. Writable storage needs both a signing key and the
+// trusted key to check the existing content.
+func NewStorageFactory(factory types.MappedStorageFactory, opts ...types.Option) types.MappedStorageFactory {
+	_, cfg := applyOptions(types.Options{}, config{}, opts)
+	return func(path string, readOnly bool) (types.MappedStorage, error) {
+		inner, err := factory(path, readOnly)
+		if err != nil {
+			return nil, err
+		}
+		plain, err := readSealed(inner, cfg)
+		if err != nil {
+			inner.Close()
+			return nil, fmt.Errorf("secure storage %s: %w", path, err)
+		}
+		mem, err := storage.NewMemoryMap()(path, false)
+		if err == nil {
+			err = writeAt(mem, plain, false)
+		}
+		if err != nil {
+			inner.Close()
+			return nil, err
+		}
+		s := &sealedStorage{MappedStorage: mem, cfg: cfg}
+		if readOnly {
+			inner.Close()
+		} else {
+			s.inner = inner
+		}
+		return s, nil
+	}
+}
+
+// readSealed returns the opened content of inner, empty for new storage.
+func readSealed(inner types.MappedStorage, cfg config) ([]byte, error) {
+	size, err := inner.Size()
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	region, err := inner.Map(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer region.Unmap()
+	plain, err := open(region.Bytes(), cfg, true)
+	if err != nil {
+		return nil, err
+	}
+	// the region is released on return
+	return append([]byte(nil), plain...), nil
+}
+
+// writeAt grows s to hold data and copies it to the start, zeroing the rest
+// when zeroRest is set.
+func writeAt(s types.MappedStorage, data []byte, zeroRest bool) error {
+	if err := s.Grow(int64(len(data))); err != nil {
+		return err
+	}
+	size, err := s.Size()
+	if err != nil || size == 0 {
+		return err
+	}
+	region, err := s.Map(0, 0)
+	if err != nil {
+		return err
+	}
+	b := region.Bytes()
+	n := copy(b, data)
+	if zeroRest {
+		clear(b[n:])
+	}
+	if err := region.Sync(); err != nil {
+		region.Unmap()
+		return err
+	}
+	return region.Unmap()
+}
+
+// sealedStorage serves the plain content from memory; inner is nil for
+// read-only storage.
+type sealedStorage struct {
+	types.MappedStorage
+	cfg config
+
+	mu     sync.Mutex
+	inner  types.MappedStorage
+	closed bool
+}
+
+func (s *sealedStorage) Grow(size int64) error {
+	if s.inner == nil {
+		return errors.New("secure storage: read-only")
+	}
+	return s.MappedStorage.Grow(size)
+}
+
+// ReaderWriterSeeker exposes the plain content as a stream.
+func (s *sealedStorage) ReaderWriterSeeker() (io.ReadWriteSeeker, error) {
+	if s.inner == nil {
+		region, err := s.MappedStorage.Map(0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return readOnlyView{bytes.NewReader(region.Bytes())}, nil
+	}
+	rws, ok := s.MappedStorage.(types.ReaderWriterSeekerStorage)
+	if !ok {
+		return nil, errors.New("secure storage: streams not supported")
+	}
+	return rws.ReaderWriterSeeker()
+}
+
+type readOnlyView struct {
+	*bytes.Reader
+}
+
+func (readOnlyView) Write([]byte) (int, error) {
+	return 0, errors.New("secure storage: read-only")
+}
+
+// Close seals writable content into the wrapped storage.
+func (s *sealedStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	defer s.MappedStorage.Close()
+	if s.inner == nil {
+		return nil
+	}
+	defer s.inner.Close()
+
+	var plain []byte
+	if size, err := s.MappedStorage.Size(); err != nil {
+		return err
+	} else if size > 0 {
+		region, err := s.MappedStorage.Map(0, 0)
+		if err != nil {
+			return err
+		}
+		plain = region.Bytes()
+	}
+	sealed, err := seal(plain, s.cfg)
+	if err != nil {
+		return fmt.Errorf("secure storage: %w", err)
+	}
+	return writeAt(s.inner, sealed, true)
+}
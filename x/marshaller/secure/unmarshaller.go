@@ -0,0 +1,47 @@
+package secure
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Unmarshaller verifies and decrypts sealed payloads before handing them to
+// the wrapped unmarshaller. It fails closed: payloads that are not signed
+// by a key given with WithTrustedKey, or not encrypted while decryption
+// keys are set, never reach the wrapped unmarshaller.
+type Unmarshaller struct {
+	inner types.Unmarshaller
+	opts  types.Options
+	cfg   config
+}
+
+// NewUnmarshaller creates an unmarshaller opening payloads for inner.
+func NewUnmarshaller(inner types.Unmarshaller, opts ...types.Option) *Unmarshaller {
+	u := &Unmarshaller{inner: inner}
+	u.opts, u.cfg = applyOptions(types.Options{}, config{}, opts)
+	return u
+}
+
+// Format returns the format name, "secure+" and the wrapped format.
+func (u *Unmarshaller) Format() string {
+	return "secure+" + u.inner.Format()
+}
+
+// Unmarshal verifies the sealed payload in r and decodes it into dst with
+// the wrapped unmarshaller, which receives opts as well. Verification
+// errors wrap ErrVerification.
+func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) error {
+	_, cfg := applyOptions(u.opts, u.cfg, opts)
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return types.NewError("unmarshal", u.Format(), "reading", err)
+	}
+	payload, err := open(data, cfg, false)
+	if err != nil {
+		return types.NewError("unmarshal", u.Format(), "verification", err)
+	}
+	return u.inner.Unmarshal(bytes.NewReader(payload), dst, opts...)
+}
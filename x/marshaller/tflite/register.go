@@ -0,0 +1,17 @@
+package tflite
+
+import (
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "tflite",
+		Extensions: []string{".tflite"},
+		Magic:      []registry.Magic{{Offset: 4, Bytes: "TFL3"}},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
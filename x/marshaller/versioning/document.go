@@ -0,0 +1,176 @@
+package versioning
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ToDocument converts a wire struct into a Document: structs become maps
+// keyed by field name, maps get string keys and slices of structs become
+// []any. Other values, including numeric slices, are kept as they are so
+// that typed data survives a migration unchanged.
+func ToDocument(v any) (Document, error) {
+	doc, ok := toDocument(reflect.ValueOf(v)).(Document)
+	if !ok {
+		return nil, fmt.Errorf("versioning: %T is not a struct", v)
+	}
+	return doc, nil
+}
+
+func toDocument(rv reflect.Value) any {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return toDocument(rv.Elem())
+	case reflect.Struct:
+		doc := make(Document, rv.NumField())
+		for i := 0; i < rv.NumField(); i++ {
+			if f := rv.Type().Field(i); f.IsExported() {
+				doc[f.Name] = toDocument(rv.Field(i))
+			}
+		}
+		return doc
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		doc := make(Document, rv.Len())
+		for it := rv.MapRange(); it.Next(); {
+			doc[fmt.Sprint(it.Key().Interface())] = toDocument(it.Value())
+		}
+		return doc
+	case reflect.Slice, reflect.Array:
+		switch rv.Type().Elem().Kind() {
+		case reflect.Struct, reflect.Pointer, reflect.Map, reflect.Interface:
+			if rv.Kind() == reflect.Slice && rv.IsNil() {
+				return nil
+			}
+			out := make([]any, rv.Len())
+			for i := range out {
+				out[i] = toDocument(rv.Index(i))
+			}
+			return out
+		}
+	}
+	return rv.Interface()
+}
+
+// FromDocument fills the struct pointed to by dst from doc, the reverse of
+// ToDocument. Numbers are converted between kinds as needed, so migrations
+// may use plain ints and float64s.
+func FromDocument(doc Document, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("versioning: FromDocument needs a non-nil pointer, got %T", dst)
+	}
+	return fromDocument(doc, rv.Elem(), "")
+}
+
+func fromDocument(v any, rv reflect.Value, path string) error {
+	if v == nil {
+		rv.SetZero()
+		return nil
+	}
+	src := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Interface:
+		rv.Set(src)
+		return nil
+	case reflect.Pointer:
+		elem := reflect.New(rv.Type().Elem())
+		if err := fromDocument(v, elem.Elem(), path); err != nil {
+			return err
+		}
+		rv.Set(elem)
+		return nil
+	case reflect.Struct:
+		doc, ok := v.(Document)
+		if !ok {
+			break
+		}
+		for i := 0; i < rv.NumField(); i++ {
+			f := rv.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if fv, ok := doc[f.Name]; ok {
+				if err := fromDocument(fv, rv.Field(i), path+"."+f.Name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case reflect.Map:
+		doc, ok := v.(Document)
+		if !ok {
+			break
+		}
+		m := reflect.MakeMapWithSize(rv.Type(), len(doc))
+		for k, ev := range doc {
+			key := reflect.New(rv.Type().Key()).Elem()
+			if err := parseKey(k, key); err != nil {
+				return fmt.Errorf("versioning: %s: %w", path, err)
+			}
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := fromDocument(ev, elem, path+"."+k); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		rv.Set(m)
+		return nil
+	case reflect.Slice:
+		list, ok := v.([]any)
+		if !ok {
+			break
+		}
+		s := reflect.MakeSlice(rv.Type(), len(list), len(list))
+		for i, ev := range list {
+			if err := fromDocument(ev, s.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(s)
+		return nil
+	}
+	switch {
+	case src.Type().AssignableTo(rv.Type()):
+		rv.Set(src)
+	case isNumber(src.Kind()) && isNumber(rv.Kind()):
+		rv.Set(src.Convert(rv.Type()))
+	default:
+		return fmt.Errorf("versioning: %s: cannot use %T as %s", path, v, rv.Type())
+	}
+	return nil
+}
+
+func parseKey(s string, key reflect.Value) error {
+	switch key.Kind() {
+	case reflect.String:
+		key.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		key.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		key.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported map key %s", key.Type())
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
@@ -0,0 +1,176 @@
+// Package versioning keeps persisted marshaller payloads readable across
+// schema changes.
+//
+// The gob, json, yaml and protobuf backends wrap every payload in an
+// envelope carrying the schema version it was written with. Payloads without
+// an envelope, written before versioning existed, are version 0. On
+// Unmarshal, older payloads are brought to the current version by the
+// migrations registered for their schema, one step at a time:
+//
+//	func init() {
+//		// v2 renamed the "bias" parameter of Dense layers to "b"
+//		versioning.Register("json", 1, 2, func(doc versioning.Document) (versioning.Document, error) {
+//			...
+//			return doc, nil
+//		})
+//	}
+//
+// Migrations work on the generic form of the payload (see Document) so they
+// do not depend on the unexported wire structs of the backends. Payloads
+// from a newer version than the reader knows are decoded on a best effort
+// basis unless WithStrict is set.
+package versioning
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+// Document is the generic form of a payload: maps of field names as they
+// appear on the wire, slices and scalars.
+type Document = map[string]any
+
+// Migration transforms a document of one version into the next. It may
+// modify doc in place and return it.
+type Migration func(doc Document) (Document, error)
+
+type step struct {
+	to int
+	fn Migration
+}
+
+var (
+	registryMu sync.RWMutex
+	migrations = make(map[string]map[int]step)
+)
+
+// Register adds the migration of schema from version from to version to.
+// A nil migration marks versions that only differ in the envelope.
+// Registering the same step twice replaces the previous registration.
+func Register(schema string, from, to int, m Migration) {
+	if schema == "" || from < 0 || to <= from {
+		panic(fmt.Sprintf("versioning.Register: invalid migration %q %d -> %d", schema, from, to))
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if migrations[schema] == nil {
+		migrations[schema] = make(map[int]step)
+	}
+	migrations[schema][from] = step{to: to, fn: m}
+}
+
+// Registered returns the sorted versions schema has migrations from.
+func Registered(schema string) []int {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var from []int
+	for v := range migrations[schema] {
+		from = append(from, v)
+	}
+	sort.Ints(from)
+	return from
+}
+
+// Migrate applies the registered migrations of schema to bring doc from
+// version from to version to.
+func Migrate(schema string, doc Document, from, to int) (Document, error) {
+	if from > to {
+		return nil, fmt.Errorf("versioning: cannot migrate %s back from v%d to v%d", schema, from, to)
+	}
+	for v := from; v < to; {
+		registryMu.RLock()
+		s, ok := migrations[schema][v]
+		registryMu.RUnlock()
+		if !ok || s.to > to {
+			return nil, fmt.Errorf("versioning: no migration of %s from v%d towards v%d", schema, v, to)
+		}
+		if s.fn != nil {
+			var err error
+			if doc, err = s.fn(doc); err != nil {
+				return nil, fmt.Errorf("versioning: migrating %s v%d -> v%d: %w", schema, v, s.to, err)
+			}
+		}
+		v = s.to
+	}
+	return doc, nil
+}
+
+// NeedsMigration reports whether a payload of version from needs document
+// level changes to reach version to, as opposed to envelope-only steps.
+func NeedsMigration(schema string, from, to int) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for v := from; v < to; {
+		s, ok := migrations[schema][v]
+		if !ok {
+			return true // let Migrate report the gap
+		}
+		if s.fn != nil {
+			return true
+		}
+		v = s.to
+	}
+	return false
+}
+
+// FutureVersionError reports a payload written by a newer schema in strict mode.
+type FutureVersionError struct {
+	Schema           string
+	Version, Current int
+}
+
+func (e *FutureVersionError) Error() string {
+	return fmt.Sprintf("versioning: %s payload is v%d, newer than supported v%d", e.Schema, e.Version, e.Current)
+}
+
+const strictKey = "versioning.strict"
+
+type withStrict bool
+
+func (s withStrict) Apply(opts *types.Options) {
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]string)
+	}
+	opts.Metadata[strictKey] = strconv.FormatBool(bool(s))
+}
+
+// WithStrict refuses payloads from versions newer than the reader supports
+// instead of decoding them on a best effort basis.
+func WithStrict(strict bool) types.Option {
+	return withStrict(strict)
+}
+
+// Strict reports whether WithStrict(true) is set.
+func Strict(opts types.Options) bool {
+	return opts.Metadata[strictKey] == "true"
+}
+
+// Check validates the version of a payload against the current one.
+func Check(schema string, version, current int, opts types.Options) error {
+	if version < 0 {
+		return fmt.Errorf("versioning: invalid %s version %d", schema, version)
+	}
+	if version > current && Strict(opts) {
+		return &FutureVersionError{Schema: schema, Version: version, Current: current}
+	}
+	return nil
+}
+
+// Target returns the version Marshal writes: Options.FormatVersion, which
+// may be empty for the current version or "0" for payloads without an
+// envelope that pre-versioning readers understand, as long as the payload
+// layout has not changed since.
+func Target(schema string, opts types.Options, current int) (int, error) {
+	if opts.FormatVersion == "" {
+		return current, nil
+	}
+	v, err := strconv.Atoi(opts.FormatVersion)
+	if err != nil || (v != 0 && v != current) || (v == 0 && NeedsMigration(schema, 0, current)) {
+		return 0, fmt.Errorf("versioning: cannot write %s version %q (current is %d)", schema, opts.FormatVersion, current)
+	}
+	return v, nil
+}
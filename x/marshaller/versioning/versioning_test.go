@@ -0,0 +1,146 @@
+package versioning
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func TestMigrateChain(t *testing.T) {
+	const schema = "test.chain"
+	Register(schema, 0, 1, nil)
+	Register(schema, 1, 3, func(doc Document) (Document, error) {
+		doc["gain"] = doc["k"]
+		delete(doc, "k")
+		return doc, nil
+	})
+	Register(schema, 3, 4, func(doc Document) (Document, error) {
+		return Document{"params": doc}, nil
+	})
+
+	doc, err := Migrate(schema, Document{"k": 2.5}, 0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Document{"params": Document{"gain": 2.5}}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("got %v, want %v", doc, want)
+	}
+	if got := Registered(schema); !reflect.DeepEqual(got, []int{0, 1, 3}) {
+		t.Errorf("registered %v", got)
+	}
+
+	if NeedsMigration(schema, 0, 1) {
+		t.Error("envelope-only step needs no migration")
+	}
+	if !NeedsMigration(schema, 0, 3) {
+		t.Error("0 -> 3 changes the document")
+	}
+	if _, err := Migrate(schema, Document{}, 0, 2); err == nil {
+		t.Error("expected error when a step overshoots the target")
+	}
+	if _, err := Migrate(schema, Document{}, 4, 5); err == nil {
+		t.Error("expected error for a missing step")
+	}
+	if _, err := Migrate(schema, Document{}, 3, 1); err == nil {
+		t.Error("expected error migrating backwards")
+	}
+
+	fail := errors.New("boom")
+	Register(schema, 4, 5, func(Document) (Document, error) { return nil, fail })
+	if _, err := Migrate(schema, Document{}, 0, 5); !errors.Is(err, fail) {
+		t.Errorf("expected wrapped migration error, got %v", err)
+	}
+}
+
+func TestCheckAndTarget(t *testing.T) {
+	const schema = "test.target"
+	Register(schema, 0, 1, nil)
+
+	if err := Check(schema, 3, 2, types.Options{}); err != nil {
+		t.Errorf("lenient check failed: %v", err)
+	}
+	var strict types.Options
+	WithStrict(true).Apply(&strict)
+	var future *FutureVersionError
+	if err := Check(schema, 3, 2, strict); !errors.As(err, &future) {
+		t.Errorf("expected future version error, got %v", err)
+	}
+	if err := Check(schema, 2, 2, strict); err != nil {
+		t.Errorf("current version refused: %v", err)
+	}
+
+	for version, want := range map[string]int{"": 1, "1": 1, "0": 0} {
+		got, err := Target(schema, types.Options{FormatVersion: version}, 1)
+		if err != nil || got != want {
+			t.Errorf("Target(%q) = %d, %v", version, got, err)
+		}
+	}
+	for _, version := range []string{"2", "x", "-1"} {
+		if _, err := Target(schema, types.Options{FormatVersion: version}, 1); err == nil {
+			t.Errorf("Target(%q) should fail", version)
+		}
+	}
+	Register(schema, 0, 1, func(doc Document) (Document, error) { return doc, nil })
+	if _, err := Target(schema, types.Options{FormatVersion: "0"}, 1); err == nil {
+		t.Error("version 0 must not be written once the layout changed")
+	}
+}
+
+type wireParam struct {
+	Data         any
+	RequiresGrad bool
+}
+
+type wireLayer struct {
+	Name       string
+	Shape      []int
+	Parameters map[int]wireParam
+	Children   []*wireLayer
+	Extra      *wireParam
+	hidden     int
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	in := wireLayer{
+		Name:  "dense",
+		Shape: []int{2, 3},
+		Parameters: map[int]wireParam{
+			0: {Data: []float32{1, 2, 3}, RequiresGrad: true},
+			1: {Data: []float64{4}},
+		},
+		Children: []*wireLayer{{Name: "child"}},
+		hidden:   7,
+	}
+	doc, err := ToDocument(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["hidden"]; ok {
+		t.Error("unexported field in document")
+	}
+	params := doc["Parameters"].(Document)
+	if _, ok := params["0"].(Document)["Data"].([]float32); !ok {
+		t.Errorf("typed data not preserved: %T", params["0"].(Document)["Data"])
+	}
+
+	// a migration using plain numbers
+	doc["Shape"] = []any{3, 2.0}
+	var out wireLayer
+	if err := FromDocument(doc, &out); err != nil {
+		t.Fatal(err)
+	}
+	in.Shape, in.hidden = []int{3, 2}, 0
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	if err := FromDocument(Document{"Name": 5}, &out); err == nil {
+		t.Error("expected type error")
+	}
+	if _, err := ToDocument(42); err == nil {
+		t.Error("expected error for a non-struct")
+	}
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/itohio/EasyRobot/x/math/graph"
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/tensor"
 )
 
 // Internal structs for YAML encoding/decoding.
@@ -31,6 +33,7 @@ type yamlLayer struct {
 	CanLearn   bool                     `yaml:"can_learn"`
 	InputShape []int                    `yaml:"input_shape,omitempty"`
 	Parameters map[string]yamlParameter `yaml:"parameters,omitempty"`
+	Config     map[string]string        `yaml:"config,omitempty"` // Constructor arguments of registered layers
 }
 
 // yamlModel represents a model for YAML encoding/decoding.
@@ -39,6 +42,7 @@ type yamlModel struct {
 	Type       string                   `yaml:"type"`
 	CanLearn   bool                     `yaml:"can_learn"`
 	LayerCount int                      `yaml:"layer_count"`
+	InputShape []int                    `yaml:"input_shape,omitempty"`
 	Layers     []yamlLayer              `yaml:"layers,omitempty"`
 	Parameters map[string]yamlParameter `yaml:"parameters,omitempty"`
 }
@@ -164,10 +168,10 @@ func parameterToYAML(p types.Parameter) yamlParameter {
 		RequiresGrad: p.RequiresGrad,
 	}
 
-	if !p.Data.Empty() {
+	if !tensor.IsNil(p.Data) {
 		result.Data = tensorToYAML(p.Data)
 	}
-	if !p.Grad.Empty() {
+	if !tensor.IsNil(p.Grad) {
 		result.Grad = tensorToYAML(p.Grad)
 	}
 
@@ -177,13 +181,20 @@ func parameterToYAML(p types.Parameter) yamlParameter {
 func layerToYAML(layer types.Layer) yamlLayer {
 	result := yamlLayer{
 		Name:       layer.Name(),
+		Type:       reflect.TypeOf(layer).String(),
 		CanLearn:   layer.CanLearn(),
 		Parameters: make(map[string]yamlParameter),
 	}
 
+	// Registered layers carry their constructor arguments
+	if name, ok := layers.TypeName(layer); ok {
+		result.Type = name
+		result.Config = layer.(layers.Configurable).Config()
+	}
+
 	// Get input shape if available
 	input := layer.Input()
-	if !input.Empty() {
+	if !tensor.IsNil(input) {
 		result.InputShape = input.Shape()
 	}
 
@@ -205,6 +216,10 @@ func modelToYAML(model types.Model) yamlModel {
 		Parameters: make(map[string]yamlParameter),
 	}
 
+	if m, ok := model.(interface{ InputShape() tensor.Shape }); ok {
+		result.InputShape = m.InputShape()
+	}
+
 	// Convert layers
 	for i := 0; i < model.LayerCount(); i++ {
 		layer := model.GetLayer(i)
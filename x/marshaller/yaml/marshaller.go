@@ -7,6 +7,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
 	"github.com/itohio/EasyRobot/x/math/graph"
 )
 
@@ -43,6 +44,11 @@ func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error
 		return types.NewError("marshal", "yaml", "nil value", nil)
 	}
 
+	version, err := versioning.Target(schemaName, localOpts, SchemaVersion)
+	if err != nil {
+		return types.NewError("marshal", "yaml", "version", err)
+	}
+
 	encoder := yaml.NewEncoder(w)
 	encoder.SetIndent(2)
 	defer encoder.Close()
@@ -52,9 +58,13 @@ func (m *Marshaller) Marshal(w io.Writer, value any, opts ...types.Option) error
 	if err != nil {
 		return types.NewError("marshal", "yaml", "value conversion", err)
 	}
+	payload, err := wrap(yv, version)
+	if err != nil {
+		return types.NewError("marshal", "yaml", "encoding", err)
+	}
 
 	// Encode yamlValue
-	if err := encoder.Encode(yv); err != nil {
+	if err := encoder.Encode(payload); err != nil {
 		return types.NewError("marshal", "yaml", "encoding", err)
 	}
 
@@ -79,7 +89,6 @@ func (m *Marshaller) valueToYAML(value any) (*yamlValue, error) {
 	// Then check for Layer
 	if layer, ok := value.(types.Layer); ok {
 		yl := layerToYAML(layer)
-		yl.Type = reflect.TypeOf(value).String()
 		return &yamlValue{
 			Kind:  "layer",
 			Layer: &yl,
@@ -0,0 +1,24 @@
+package yaml
+
+import (
+	"bytes"
+
+	"github.com/itohio/EasyRobot/x/marshaller/registry"
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+)
+
+func init() {
+	registry.Register(registry.Format{
+		Name:       "yaml",
+		Extensions: []string{".yaml", ".yml"},
+		Sniff: func(head []byte) bool {
+			return bytes.HasPrefix(head, []byte("---")) || bytes.HasPrefix(head, []byte("%YAML"))
+		},
+		NewMarshaller: func(opts ...types.Option) (types.Marshaller, error) {
+			return NewMarshaller(opts...), nil
+		},
+		NewUnmarshaller: func(opts ...types.Option) (types.Unmarshaller, error) {
+			return NewUnmarshaller(opts...), nil
+		},
+	})
+}
@@ -10,6 +10,9 @@ import (
 
 	"github.com/itohio/EasyRobot/x/marshaller/types"
 	"github.com/itohio/EasyRobot/x/math/graph"
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/nn/models"
+	nntypes "github.com/itohio/EasyRobot/x/math/nn/types"
 	"github.com/itohio/EasyRobot/x/math/tensor"
 )
 
@@ -45,13 +48,17 @@ func (u *Unmarshaller) Unmarshal(r io.Reader, dst any, opts ...types.Option) err
 	decoder := yaml.NewDecoder(r)
 
 	// Decode yamlValue
-	var yv yamlValue
-	if err := decoder.Decode(&yv); err != nil {
+	var root yaml.Node
+	if err := decoder.Decode(&root); err != nil {
+		return types.NewError("unmarshal", "yaml", "decoding", err)
+	}
+	yv, err := unwrap(&root, localOpts)
+	if err != nil {
 		return types.NewError("unmarshal", "yaml", "decoding", err)
 	}
 
 	// Convert yamlValue to actual value
-	value, err := u.yamlToValue(&yv, localOpts)
+	value, err := u.yamlToValue(yv, localOpts)
 	if err != nil {
 		return types.NewError("unmarshal", "yaml", "conversion", err)
 	}
@@ -88,13 +95,17 @@ func (u *Unmarshaller) yamlToValue(yv *yamlValue, opts types.Options) (any, erro
 		if yv.Layer == nil {
 			return nil, fmt.Errorf("nil layer in yamlValue")
 		}
-		return *yv.Layer, nil
+		spec, err := yamlToLayerSpec(yv.Layer, tensorFactory)
+		if err != nil {
+			return nil, err
+		}
+		return layers.Restore(spec)
 
 	case "model":
 		if yv.Model == nil {
 			return nil, fmt.Errorf("nil model in yamlValue")
 		}
-		return *yv.Model, nil
+		return yamlToModel(yv.Model, tensorFactory)
 
 	case "graph", "tree", "decision_tree", "expression_graph", "generic":
 		if yv.Graph == nil {
@@ -153,6 +164,68 @@ func yamlToTensor(yt *yamlTensor, destType types.DataType, tensorFactory func(ty
 	return t, nil
 }
 
+func yamlToParameter(jp yamlParameter, tensorFactory func(types.DataType, types.Shape) types.Tensor) (types.Parameter, error) {
+	param := types.Parameter{RequiresGrad: jp.RequiresGrad}
+	if jp.Data != nil {
+		data, err := yamlToTensor(jp.Data, 0, tensorFactory)
+		if err != nil {
+			return param, err
+		}
+		param.Data = data
+	}
+	if jp.Grad != nil {
+		grad, err := yamlToTensor(jp.Grad, 0, tensorFactory)
+		if err != nil {
+			return param, err
+		}
+		param.Grad = grad
+	}
+	return param, nil
+}
+
+// yamlToLayerSpec converts a decoded layer into a spec the layer registry can rebuild.
+func yamlToLayerSpec(jl *yamlLayer, tensorFactory func(types.DataType, types.Shape) types.Tensor) (layers.Spec, error) {
+	spec := layers.Spec{
+		Type:       jl.Type,
+		Name:       jl.Name,
+		CanLearn:   jl.CanLearn,
+		Config:     jl.Config,
+		InputShape: jl.InputShape,
+		Parameters: make(map[nntypes.ParamIndex]types.Parameter, len(jl.Parameters)),
+	}
+	for key, jp := range jl.Parameters {
+		// Parameter indices are encoded as single-rune keys
+		idx := []rune(key)
+		if len(idx) != 1 {
+			return spec, fmt.Errorf("invalid parameter key %q", key)
+		}
+		param, err := yamlToParameter(jp, tensorFactory)
+		if err != nil {
+			return spec, err
+		}
+		spec.Parameters[nntypes.ParamIndex(idx[0])] = param
+	}
+	return spec, nil
+}
+
+// yamlToModel rebuilds a Sequential model from its layers.
+func yamlToModel(jm *yamlModel, tensorFactory func(types.DataType, types.Shape) types.Tensor) (*models.Sequential, error) {
+	spec := models.Spec{
+		Name:       jm.Name,
+		CanLearn:   jm.CanLearn,
+		InputShape: jm.InputShape,
+		Layers:     make([]layers.Spec, len(jm.Layers)),
+	}
+	for i := range jm.Layers {
+		layer, err := yamlToLayerSpec(&jm.Layers[i], tensorFactory)
+		if err != nil {
+			return nil, err
+		}
+		spec.Layers[i] = layer
+	}
+	return models.Restore(spec)
+}
+
 func convertSliceData(data any, sliceType string) (any, error) {
 	// YAML decodes numeric arrays as []any, we need to convert to proper type
 	if data == nil {
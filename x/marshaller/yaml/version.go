@@ -0,0 +1,79 @@
+package yaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/itohio/EasyRobot/x/marshaller/types"
+	"github.com/itohio/EasyRobot/x/marshaller/versioning"
+)
+
+// SchemaVersion is the payload version written by Marshal. Version 0 is a
+// bare yamlValue from before payloads were versioned.
+const SchemaVersion = 1
+
+const (
+	schemaName   = "yaml"
+	envelopeName = "easyrobot"
+)
+
+// yamlEnvelope wraps every payload with its schema version.
+type yamlEnvelope struct {
+	Schema  string    `yaml:"schema"`
+	Version int       `yaml:"version"`
+	Value   yaml.Node `yaml:"value"`
+}
+
+func init() {
+	// v1 only introduced the envelope
+	versioning.Register(schemaName, 0, 1, nil)
+}
+
+// wrap returns the envelope to encode, or yv itself for version 0.
+func wrap(yv *yamlValue, version int) (any, error) {
+	if version == 0 {
+		return yv, nil
+	}
+	env := &yamlEnvelope{Schema: envelopeName, Version: version}
+	if err := env.Value.Encode(yv); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// unwrap decodes an enveloped or a bare version 0 payload and migrates it
+// to SchemaVersion.
+func unwrap(root *yaml.Node, opts types.Options) (*yamlValue, error) {
+	version := 0
+	payload := root
+	var env yamlEnvelope
+	if err := root.Decode(&env); err == nil && env.Schema == envelopeName {
+		version, payload = env.Version, &env.Value
+	}
+	if payload.Kind == 0 {
+		return nil, fmt.Errorf("envelope without value")
+	}
+	if err := versioning.Check(schemaName, version, SchemaVersion, opts); err != nil {
+		return nil, err
+	}
+	if version < SchemaVersion && versioning.NeedsMigration(schemaName, version, SchemaVersion) {
+		var doc versioning.Document
+		if err := payload.Decode(&doc); err != nil {
+			return nil, err
+		}
+		doc, err := versioning.Migrate(schemaName, doc, version, SchemaVersion)
+		if err != nil {
+			return nil, err
+		}
+		payload = &yaml.Node{}
+		if err := payload.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	var yv yamlValue
+	if err := payload.Decode(&yv); err != nil {
+		return nil, err
+	}
+	return &yv, nil
+}
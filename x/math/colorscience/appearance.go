@@ -0,0 +1,285 @@
+package colorscience
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// Surround describes the relative luminance of the surround of a stimulus
+// in a color appearance model.
+type Surround struct {
+	// F is the factor of the degree of adaptation.
+	F float64
+	// C is the impact of the surround.
+	C float64
+	// Nc is the chromatic induction factor.
+	Nc float64
+}
+
+// Standard surrounds of CIECAM02 and CAM16.
+var (
+	// SurroundAverage is for surface colors viewed in a lit room.
+	SurroundAverage = Surround{F: 1.0, C: 0.69, Nc: 1.0}
+	// SurroundDim is for television viewed in a dim room.
+	SurroundDim = Surround{F: 0.9, C: 0.59, Nc: 0.9}
+	// SurroundDark is for projection in a dark room.
+	SurroundDark = Surround{F: 0.8, C: 0.525, Nc: 0.8}
+)
+
+// ViewingConditions are the parameters of a color appearance model.
+type ViewingConditions struct {
+	// White is the XYZ of the adopted white, usually with Y = 100.
+	White XYZ
+	// AdaptingLuminance is the luminance of the adapting field in cd/m²,
+	// usually a fifth of the luminance of the white.
+	AdaptingLuminance float64
+	// Background is the relative luminance Y of the background, 0 to 100;
+	// 20 is a gray world.
+	Background float64
+	// Surround is the surround of the stimulus.
+	Surround Surround
+	// Discount assumes full adaptation to the white (D = 1), as when the
+	// illuminant is discounted for surface colors.
+	Discount bool
+}
+
+// Appearance is the color appearance of a stimulus in CIECAM02 or CAM16.
+type Appearance struct {
+	// J is the lightness and Q the brightness.
+	J, Q float32
+	// C is the chroma, M the colorfulness and S the saturation.
+	C, M, S float32
+	// Hue is the hue angle in degrees and HueQuadrature the hue
+	// composition, 0 for red, 100 for yellow, 200 for green and 300 for blue.
+	Hue, HueQuadrature float32
+}
+
+// UCS represents CAM16-UCS (or CAM02-UCS) uniform color space values J', a'
+// and b'.
+type UCS vec.Vector3D
+
+var (
+	matCAT02 = mat3{
+		{0.7328, 0.4296, -0.1624},
+		{-0.7036, 1.6975, 0.0061},
+		{0.0030, 0.0136, 0.9834},
+	}
+	matHPE = mat3{
+		{0.38971, 0.68898, -0.07868},
+		{-0.22981, 1.18340, 0.04641},
+		{0, 0, 1},
+	}
+	matM16 = mat3{
+		{0.401288, 0.650173, -0.051461},
+		{-0.250268, 1.204414, 0.045854},
+		{-0.002079, 0.048952, 0.953127},
+	}
+)
+
+// cam holds the terms of a color appearance model that depend only on the
+// viewing conditions.
+type cam struct {
+	// adapt maps XYZ to the space of the chromatic adaptation, post maps
+	// adapted responses to the space of the cone compression
+	adapt, adaptInv mat3
+	post, postInv   mat3
+	gain            [3]float64
+	fl, n, nbb, z   float64
+	c, nc, aw       float64
+}
+
+func newCAM(vc ViewingConditions, adapt, cone mat3) cam {
+	if vc.AdaptingLuminance <= 0 || vc.Background <= 0 || vc.White[1] <= 0 {
+		panic("colorscience: viewing conditions must have positive luminances")
+	}
+	m := cam{adapt: adapt, adaptInv: adapt.inverse(), c: vc.Surround.C, nc: vc.Surround.Nc}
+	m.post = cone.mul(m.adaptInv)
+	m.postInv = m.post.inverse()
+
+	la := vc.AdaptingLuminance
+	white := vec64(vec.Vector3D(vc.White))
+	yw := white[1]
+	d := 1.0
+	if !vc.Discount {
+		d = vc.Surround.F * (1 - math.Exp((-la-42)/92)/3.6)
+		d = min(max(d, 0), 1)
+	}
+	rgbw := adapt.apply(white)
+	for i := range 3 {
+		m.gain[i] = d*yw/rgbw[i] + 1 - d
+	}
+	k := 1 / (5*la + 1)
+	k4 := k * k * k * k
+	m.fl = 0.2*k4*5*la + 0.1*(1-k4)*(1-k4)*math.Cbrt(5*la)
+	m.n = vc.Background / yw
+	m.nbb = 0.725 * math.Pow(1/m.n, 0.2)
+	m.z = 1.48 + math.Sqrt(m.n)
+	m.aw = m.achromatic(m.compress(white))
+	return m
+}
+
+// compress returns the post adaptation cone responses of XYZ.
+func (m cam) compress(xyz [3]float64) [3]float64 {
+	rgb := m.adapt.apply(xyz)
+	for i := range rgb {
+		rgb[i] *= m.gain[i]
+	}
+	rgb = m.post.apply(rgb)
+	for i, v := range rgb {
+		p := math.Pow(m.fl*math.Abs(v)/100, 0.42)
+		rgb[i] = math.Copysign(400*p/(p+27.13), v) + 0.1
+	}
+	return rgb
+}
+
+// expand inverts compress.
+func (m cam) expand(rgb [3]float64) [3]float64 {
+	for i, v := range rgb {
+		v -= 0.1
+		a := math.Abs(v)
+		rgb[i] = math.Copysign(100/m.fl*math.Pow(27.13*a/(400-a), 1/0.42), v)
+	}
+	rgb = m.postInv.apply(rgb)
+	for i := range rgb {
+		rgb[i] /= m.gain[i]
+	}
+	return m.adaptInv.apply(rgb)
+}
+
+func (m cam) achromatic(rgb [3]float64) float64 {
+	return (2*rgb[0] + rgb[1] + rgb[2]/20 - 0.305) * m.nbb
+}
+
+// eccentricity is the eccentricity factor of a hue angle in degrees.
+func eccentricity(h float64) float64 {
+	return 0.25 * (math.Cos(rad(h)+2) + 3.8)
+}
+
+// Unique hues of the hue quadrature: angles, eccentricities and
+// quadratures of red, yellow, green, blue and red again.
+var (
+	uniqueHues = [5]float64{20.14, 90, 164.25, 237.53, 380.14}
+	uniqueEcc  = [5]float64{0.8, 0.7, 1.0, 1.2, 0.8}
+)
+
+func hueQuadrature(h float64) float64 {
+	if h < uniqueHues[0] {
+		h += 360
+	}
+	i := 0
+	for i < 3 && h >= uniqueHues[i+1] {
+		i++
+	}
+	t1 := (h - uniqueHues[i]) / uniqueEcc[i]
+	t2 := (uniqueHues[i+1] - h) / uniqueEcc[i+1]
+	return 100*float64(i) + 100*t1/(t1+t2)
+}
+
+func (m cam) forward(xyz XYZ) Appearance {
+	rgb := m.compress(vec64(vec.Vector3D(xyz)))
+	a := rgb[0] - 12*rgb[1]/11 + rgb[2]/11
+	b := (rgb[0] + rgb[1] - 2*rgb[2]) / 9
+	h := hueDeg(a, b)
+	J := 100 * math.Pow(max(m.achromatic(rgb), 0)/m.aw, m.c*m.z)
+	Q := 4 / m.c * math.Sqrt(J/100) * (m.aw + 4) * math.Pow(m.fl, 0.25)
+	t := 50000.0 / 13 * m.nc * m.nbb * eccentricity(h) * math.Hypot(a, b) /
+		(rgb[0] + rgb[1] + 21*rgb[2]/20)
+	C := math.Pow(t, 0.9) * math.Sqrt(J/100) * math.Pow(1.64-math.Pow(0.29, m.n), 0.73)
+	M := C * math.Pow(m.fl, 0.25)
+	var s float64
+	if Q > 0 {
+		s = 100 * math.Sqrt(M/Q)
+	}
+	return Appearance{
+		J: float32(J), Q: float32(Q),
+		C: float32(C), M: float32(M), S: float32(s),
+		Hue: float32(h), HueQuadrature: float32(hueQuadrature(h)),
+	}
+}
+
+func (m cam) inverse(app Appearance) XYZ {
+	J, C, h := float64(app.J), float64(app.C), float64(app.Hue)
+	if J <= 0 {
+		return XYZ{}
+	}
+	t := math.Pow(C/(math.Sqrt(J/100)*math.Pow(1.64-math.Pow(0.29, m.n), 0.73)), 1/0.9)
+	A := m.aw * math.Pow(J/100, 1/(m.c*m.z))
+	p2 := A/m.nbb + 0.305
+	const p3 = 21.0 / 20
+	var a, b float64
+	if t > 0 {
+		p1 := 50000.0 / 13 * m.nc * m.nbb * eccentricity(h) / t
+		sin, cos := math.Sincos(rad(h))
+		if math.Abs(sin) >= math.Abs(cos) {
+			p4 := p1 / sin
+			b = p2 * (2 + p3) * (460.0 / 1403) /
+				(p4 + (2+p3)*(220.0/1403)*(cos/sin) - 27.0/1403 + p3*(6300.0/1403))
+			a = b * cos / sin
+		} else {
+			p5 := p1 / cos
+			a = p2 * (2 + p3) * (460.0 / 1403) /
+				(p5 + (2+p3)*(220.0/1403) - (27.0/1403-p3*(6300.0/1403))*(sin/cos))
+			b = a * sin / cos
+		}
+	}
+	rgb := [3]float64{
+		(460*p2 + 451*a + 288*b) / 1403,
+		(460*p2 - 891*a - 261*b) / 1403,
+		(460*p2 - 220*a - 6300*b) / 1403,
+	}
+	return NewXYZ(vec32(m.expand(rgb)))
+}
+
+// CIECAM02 returns the CIE 159:2004 color appearance of XYZ, scaled as the
+// white of the viewing conditions.
+func CIECAM02(xyz XYZ, vc ViewingConditions) Appearance {
+	return newCAM(vc, matCAT02, matHPE).forward(xyz)
+}
+
+// CIECAM02Inverse returns the XYZ of the lightness J, chroma C and hue
+// angle Hue of a CIECAM02 appearance.
+func CIECAM02Inverse(app Appearance, vc ViewingConditions) XYZ {
+	return newCAM(vc, matCAT02, matHPE).inverse(app)
+}
+
+// CAM16 returns the CAM16 color appearance of XYZ (Li et al. 2017), which
+// replaces the two matrices of CIECAM02 by one and behaves for all colors.
+func CAM16(xyz XYZ, vc ViewingConditions) Appearance {
+	return newCAM(vc, matM16, matM16).forward(xyz)
+}
+
+// CAM16Inverse returns the XYZ of the lightness J, chroma C and hue angle
+// Hue of a CAM16 appearance.
+func CAM16Inverse(app Appearance, vc ViewingConditions) XYZ {
+	return newCAM(vc, matM16, matM16).inverse(app)
+}
+
+// CAM16-UCS coefficients (Luo, Cui and Li 2006).
+const (
+	ucsC1 = 0.007
+	ucsC2 = 0.0228
+)
+
+// UCS returns the CAM16-UCS coordinates of a CAM16 appearance, or the
+// CAM02-UCS coordinates of a CIECAM02 one, from its J, M and Hue.
+func (app Appearance) UCS() UCS {
+	J, M := float64(app.J), float64(app.M)
+	jp := 1.7 * J / (1 + ucsC1*J)
+	mp := math.Log(1+ucsC2*M) / ucsC2
+	s, c := math.Sincos(rad(float64(app.Hue)))
+	return UCS{float32(jp), float32(mp * c), float32(mp * s)}
+}
+
+// JMh returns the lightness, colorfulness and hue angle of UCS coordinates.
+func (u UCS) JMh() (float32, float32, float32) {
+	jp, a, b := float64(u[0]), float64(u[1]), float64(u[2])
+	J := jp / (1.7 - ucsC1*jp)
+	M := (math.Exp(ucsC2*math.Hypot(a, b)) - 1) / ucsC2
+	return float32(J), float32(M), float32(hueDeg(a, b))
+}
+
+// DeltaEUCS calculates the Euclidean color difference in CAM16-UCS.
+func DeltaEUCS(u1, u2 UCS) float32 {
+	return vec.Vector3D(u1).Distance(vec.Vector3D(u2))
+}
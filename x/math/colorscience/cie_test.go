@@ -0,0 +1,271 @@
+package colorscience
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Sharma, Wu and Dalal (2005) CIEDE2000 test data.
+var ciede2000Data = []struct {
+	lab1, lab2 LAB
+	dE         float32
+}{
+	{NewLAB(50, 2.6772, -79.7751), NewLAB(50, 0, -82.7485), 2.0425},
+	{NewLAB(50, 3.1571, -77.2803), NewLAB(50, 0, -82.7485), 2.8615},
+	{NewLAB(50, 2.8361, -74.0200), NewLAB(50, 0, -82.7485), 3.4412},
+	{NewLAB(50, -1.3802, -84.2814), NewLAB(50, 0, -82.7485), 1.0000},
+	{NewLAB(50, -1.1848, -84.8006), NewLAB(50, 0, -82.7485), 1.0000},
+	{NewLAB(50, -0.9009, -85.5211), NewLAB(50, 0, -82.7485), 1.0000},
+	{NewLAB(50, 0, 0), NewLAB(50, -1, 2), 2.3669},
+	{NewLAB(50, -1, 2), NewLAB(50, 0, 0), 2.3669},
+	{NewLAB(50, 2.4900, -0.0010), NewLAB(50, -2.4900, 0.0009), 7.1792},
+	{NewLAB(50, 2.4900, -0.0010), NewLAB(50, -2.4900, 0.0010), 7.1792},
+	{NewLAB(50, 2.4900, -0.0010), NewLAB(50, -2.4900, 0.0011), 7.2195},
+	{NewLAB(50, 2.4900, -0.0010), NewLAB(50, -2.4900, 0.0012), 7.2195},
+	{NewLAB(50, -0.0010, 2.4900), NewLAB(50, 0.0009, -2.4900), 4.8045},
+	{NewLAB(50, -0.0010, 2.4900), NewLAB(50, 0.0010, -2.4900), 4.8045},
+	{NewLAB(50, -0.0010, 2.4900), NewLAB(50, 0.0011, -2.4900), 4.7461},
+	{NewLAB(50, 2.5, 0), NewLAB(50, 0, -2.5), 4.3065},
+	{NewLAB(50, 2.5, 0), NewLAB(73, 25, -18), 27.1492},
+	{NewLAB(50, 2.5, 0), NewLAB(61, -5, 29), 22.8977},
+	{NewLAB(50, 2.5, 0), NewLAB(56, -27, -3), 31.9030},
+	{NewLAB(50, 2.5, 0), NewLAB(58, 24, 15), 19.4535},
+	{NewLAB(50, 2.5, 0), NewLAB(50, 3.1736, 0.5854), 1.0000},
+	{NewLAB(50, 2.5, 0), NewLAB(50, 3.2972, 0), 1.0000},
+	{NewLAB(50, 2.5, 0), NewLAB(50, 1.8634, 0.5757), 1.0000},
+	{NewLAB(50, 2.5, 0), NewLAB(50, 3.2592, 0.3350), 1.0000},
+	{NewLAB(60.2574, -34.0099, 36.2677), NewLAB(60.4626, -34.1751, 39.4387), 1.2644},
+	{NewLAB(63.0109, -31.0961, -5.8663), NewLAB(62.8187, -29.7946, -4.0864), 1.2630},
+	{NewLAB(61.2901, 3.7196, -5.3901), NewLAB(61.4292, 2.2480, -4.9620), 1.8731},
+	{NewLAB(35.0831, -44.1164, 3.7933), NewLAB(35.0232, -40.0716, 1.5901), 1.8645},
+	{NewLAB(22.7233, 20.0904, -46.6940), NewLAB(23.0331, 14.9730, -42.5619), 2.0373},
+	{NewLAB(36.4612, 47.8580, 18.3852), NewLAB(36.2715, 50.5065, 21.2231), 1.4146},
+	{NewLAB(90.8027, -2.0831, 1.4410), NewLAB(91.1528, -1.6435, 0.0447), 1.4441},
+	{NewLAB(90.9257, -0.5406, -0.9208), NewLAB(88.6381, -0.8985, -0.7239), 1.5381},
+	{NewLAB(6.7747, -0.2908, -2.4247), NewLAB(5.8714, -0.0985, -2.2286), 0.6377},
+	{NewLAB(2.0776, 0.0795, -1.1350), NewLAB(0.9033, -0.0636, -0.5514), 0.9082},
+}
+
+func TestDeltaE2000(t *testing.T) {
+	for i, d := range ciede2000Data {
+		assert.InDelta(t, d.dE, DeltaE2000(d.lab1, d.lab2), 1e-4, "pair %d", i+1)
+		assert.InDelta(t, d.dE, DeltaE2000(d.lab2, d.lab1), 1e-4, "pair %d swapped", i+1)
+	}
+}
+
+func TestDeltaE94AndCMC(t *testing.T) {
+	lab1 := NewLAB(100, 21.57210357, 272.22819350)
+	lab2 := NewLAB(100, 426.67945353, 72.39590835)
+	assert.InDelta(t, 83.779225, DeltaE94(lab1, lab2, CIE94GraphicArts), 1e-3)
+	assert.InDelta(t, 88.335553, DeltaE94(lab1, lab2, CIE94Textiles), 1e-3)
+	assert.InDelta(t, 172.704771, DeltaECMC(lab1, lab2, 2, 1), 1e-3)
+	assert.InDelta(t, 94.035649, DeltaE2000(lab1, lab2), 1e-3)
+
+	// both reduce to DeltaE76 for lightness differences of unit weight
+	lab3 := NewLAB(60, 0, 0)
+	lab4 := NewLAB(50, 0, 0)
+	assert.InDelta(t, 10, DeltaE94(lab3, lab4, CIE94GraphicArts), 1e-5)
+	assert.InDelta(t, 5, DeltaE94(lab3, lab4, CIE94Textiles), 1e-5)
+	assert.Zero(t, DeltaECMC(lab1, lab1, 1, 1))
+}
+
+func TestLUV(t *testing.T) {
+	wp := WhitePointD65_2
+	L, u, v := XYZToLUV(20.654008, 12.197225, 5.136952, wp)
+	assert.InDelta(t, 41.527875, L, 0.05)
+	assert.InDelta(t, 96.836261, u, 0.05)
+	assert.InDelta(t, 17.752113, v, 0.05)
+
+	xyz := NewXYZ(20.654008, 12.197225, 5.136952)
+	back := xyz.ToLUV(wp).ToXYZ(wp)
+	for i := range 3 {
+		assert.InDelta(t, xyz[i], back[i], 1e-3)
+	}
+	assert.InDeltaSlice(t, []float32{100, 0, 0}, sliceOf(NewXYZ(wp[0], wp[1], wp[2]).ToLUV(wp)), 1e-3)
+}
+
+func TestLCh(t *testing.T) {
+	lch := NewLAB(50, 0, -20).ToLCh()
+	assert.InDeltaSlice(t, []float32{50, 20, 270}, sliceOf(lch), 1e-4)
+	lab := lch.ToLAB()
+	assert.InDeltaSlice(t, []float32{50, 0, -20}, sliceOf(lab), 1e-4)
+}
+
+func TestOklab(t *testing.T) {
+	// Ottosson's table of XYZ (D65, Y = 1) and Oklab pairs
+	tests := []struct{ xyz, lab [3]float32 }{
+		{[3]float32{0.950, 1.000, 1.089}, [3]float32{1.000, 0.000, 0.000}},
+		{[3]float32{1.000, 0.000, 0.000}, [3]float32{0.450, 1.236, -0.019}},
+		{[3]float32{0.000, 1.000, 0.000}, [3]float32{0.922, -0.671, 0.263}},
+		{[3]float32{0.000, 0.000, 1.000}, [3]float32{0.153, -1.415, -0.449}},
+	}
+	for _, tt := range tests {
+		xyz := NewXYZ(tt.xyz[0], tt.xyz[1], tt.xyz[2])
+		lab := xyz.ToOklab()
+		assert.InDeltaSlice(t, tt.lab[:], sliceOf(lab), 1e-3, "XYZ %v", tt.xyz)
+		assert.InDeltaSlice(t, tt.xyz[:], sliceOf(lab.ToXYZ()), 1e-4, "XYZ %v", tt.xyz)
+	}
+	assert.InDelta(t, 0.5, DeltaEOk(Oklab{0.5, 0, 0}, Oklab{1, 0, 0}), 1e-6)
+}
+
+func TestICtCp(t *testing.T) {
+	assert.InDelta(t, 1, PQEncode(10000), 1e-6)
+	assert.InDelta(t, 100, PQDecode(PQEncode(100)), 1e-3)
+	assert.InDelta(t, 0.508078, PQEncode(100), 1e-5)
+
+	// white has no chroma and the intensity of its luminance
+	I, Ct, Cp := XYZToICtCp(95.047, 100, 108.883)
+	assert.InDelta(t, 0.508078, I, 1e-4)
+	assert.InDelta(t, 0, Ct, 1e-4)
+	assert.InDelta(t, 0, Cp, 1e-4)
+
+	xyz := NewXYZ(20.654008, 12.197225, 5.136952)
+	back := xyz.ToICtCp().ToXYZ()
+	assert.InDeltaSlice(t, sliceOf(xyz), sliceOf(back), 1e-3)
+
+	// DeltaEITP of grays is the difference of their intensities
+	c1 := NewXYZ(19.0094, 20, 21.7766).ToICtCp()
+	c2 := NewXYZ(19.1995, 20.2, 21.9944).ToICtCp()
+	assert.InDelta(t, 720*(c2[0]-c1[0]), DeltaEITP(c1, c2), 1e-3)
+}
+
+func TestRGBSpaces(t *testing.T) {
+	tests := []struct {
+		space *RGBSpace
+		m     [3][3]float32
+	}{
+		{SRGB, [3][3]float32{
+			{0.4123908, 0.3575843, 0.1804808},
+			{0.2126390, 0.7151687, 0.0721923},
+			{0.0193308, 0.1191948, 0.9505322},
+		}},
+		{AdobeRGB, [3][3]float32{
+			{0.5766690, 0.1855582, 0.1882286},
+			{0.2973450, 0.6273636, 0.0752915},
+			{0.0270314, 0.0706889, 0.9913375},
+		}},
+		{DisplayP3, [3][3]float32{
+			{0.4865709, 0.2656677, 0.1982173},
+			{0.2289746, 0.6917385, 0.0792869},
+			{0.0000000, 0.0451134, 1.0439444},
+		}},
+		{Rec2020, [3][3]float32{
+			{0.6369580, 0.1446169, 0.1688810},
+			{0.2627002, 0.6779981, 0.0593017},
+			{0.0000000, 0.0280727, 1.0609851},
+		}},
+	}
+	for _, tt := range tests {
+		m := tt.space.ToXYZMatrix()
+		for i := range 3 {
+			assert.InDeltaSlice(t, tt.m[i][:], m[i][:], 1e-6, "%s row %d", tt.space.Name, i)
+		}
+
+		white := tt.space.ToXYZ(NewRGB(1, 1, 1))
+		assert.InDelta(t, 1, white[1], 1e-6, tt.space.Name)
+		for _, v := range []float32{-0.5, 0, 0.001, 0.02, 0.18, 0.5, 1} {
+			assert.InDelta(t, v, tt.space.Decode(tt.space.Encode(v)), 1e-6, "%s %v", tt.space.Name, v)
+		}
+
+		rgb := NewRGB(0.2, 0.6, 0.9)
+		assert.InDeltaSlice(t, sliceOf(rgb), sliceOf(tt.space.FromXYZ(tt.space.ToXYZ(rgb))), 1e-5, tt.space.Name)
+	}
+
+	// sRGB agrees with the fixed conversion
+	r, g, b := float32(0.2), float32(0.6), float32(0.9)
+	X, Y, Z := RGBToXYZ(r, g, b)
+	assert.InDeltaSlice(t, []float32{X, Y, Z}, sliceOf(SRGB.ToXYZ(NewRGB(r, g, b))), 1e-3)
+	// the red of Rec.2020 is outside sRGB
+	red := SRGB.FromXYZ(Rec2020.ToXYZ(NewRGB(1, 0, 0)))
+	assert.Greater(t, red[0], float32(1))
+	assert.Less(t, red[1], float32(0))
+}
+
+func TestCIECAM02(t *testing.T) {
+	vc := ViewingConditions{
+		White:             NewXYZ(95.05, 100, 108.88),
+		AdaptingLuminance: 318.31,
+		Background:        20,
+		Surround:          SurroundAverage,
+	}
+	xyz := NewXYZ(19.01, 20.00, 21.78)
+	app := CIECAM02(xyz, vc)
+	assert.InDelta(t, 41.7311, app.J, 1e-3)
+	assert.InDelta(t, 0.1047, app.C, 1e-3)
+	assert.InDelta(t, 219.0484, app.Hue, 1e-2)
+	assert.InDelta(t, 2.3603, app.S, 1e-3)
+	assert.InDelta(t, 195.3713, app.Q, 1e-3)
+	assert.InDelta(t, 0.1088, app.M, 1e-3)
+	assert.InDelta(t, 278.0607, app.HueQuadrature, 1e-2)
+	assert.InDeltaSlice(t, sliceOf(xyz), sliceOf(CIECAM02Inverse(app, vc)), 1e-3)
+
+	// CIE 159:2004 worked example 1
+	vc = ViewingConditions{
+		White:             NewXYZ(98.88, 90, 32.03),
+		AdaptingLuminance: 200,
+		Background:        18,
+		Surround:          SurroundAverage,
+	}
+	xyz = NewXYZ(19.31, 23.93, 10.14)
+	app = CIECAM02(xyz, vc)
+	assert.InDelta(t, 48.0314, app.J, 1e-3)
+	assert.InDelta(t, 38.7789, app.C, 1e-3)
+	assert.InDelta(t, 191.0452, app.Hue, 1e-3)
+	assert.InDelta(t, 240.8885, app.HueQuadrature, 1e-3)
+	assert.InDelta(t, 183.1240, app.Q, 1e-3)
+	assert.InDelta(t, 38.7789, app.M, 1e-3)
+	assert.InDelta(t, 46.0177, app.S, 1e-3)
+	assert.InDeltaSlice(t, sliceOf(xyz), sliceOf(CIECAM02Inverse(app, vc)), 1e-3)
+
+	// CAM02-UCS
+	ucs := Appearance{J: 41.73109, M: 0.10884, Hue: 219.04843}.UCS()
+	assert.InDeltaSlice(t, []float32{54.90433, -0.08450, -0.06854}, sliceOf(ucs), 1e-4)
+}
+
+func TestCAM16(t *testing.T) {
+	vc := ViewingConditions{
+		White:             NewXYZ(95.05, 100, 108.88),
+		AdaptingLuminance: 318.31,
+		Background:        20,
+		Surround:          SurroundAverage,
+	}
+	xyz := NewXYZ(19.01, 20.00, 21.78)
+	app := CAM16(xyz, vc)
+	assert.InDelta(t, 41.7312, app.J, 1e-3)
+	assert.InDelta(t, 0.1034, app.C, 1e-3)
+	assert.InDelta(t, 217.0680, app.Hue, 1e-2)
+	assert.InDelta(t, 2.3450, app.S, 1e-3)
+	assert.InDelta(t, 195.3717, app.Q, 1e-3)
+	assert.InDelta(t, 0.1074, app.M, 1e-3)
+	assert.InDelta(t, 275.5950, app.HueQuadrature, 1e-2)
+
+	// the inverse round trips for saturated colors and other surrounds
+	for _, s := range []Surround{SurroundAverage, SurroundDim, SurroundDark} {
+		vc.Surround = s
+		for _, xyz := range []XYZ{
+			NewXYZ(19.01, 20.00, 21.78),
+			NewXYZ(41.24, 21.26, 1.93),
+			NewXYZ(35.76, 71.52, 11.92),
+			NewXYZ(18.05, 7.22, 95.05),
+		} {
+			app := CAM16(xyz, vc)
+			assert.InDeltaSlice(t, sliceOf(xyz), sliceOf(CAM16Inverse(app, vc)), 1e-2, "%v", xyz)
+
+			ucs := app.UCS()
+			J, M, h := ucs.JMh()
+			assert.InDelta(t, app.J, J, 1e-3)
+			assert.InDelta(t, app.M, M, 1e-3)
+			assert.InDelta(t, app.Hue, h, 1e-3)
+		}
+	}
+
+	u1 := CAM16(NewXYZ(19.01, 20.00, 21.78), vc).UCS()
+	u2 := CAM16(NewXYZ(19.01, 21.00, 21.78), vc).UCS()
+	assert.Greater(t, DeltaEUCS(u1, u2), float32(0))
+	assert.Zero(t, DeltaEUCS(u1, u1))
+}
+
+func sliceOf[T ~[3]float32](v T) []float32 {
+	return []float32{v[0], v[1], v[2]}
+}
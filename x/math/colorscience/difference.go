@@ -0,0 +1,162 @@
+package colorscience
+
+import (
+	"math"
+)
+
+// CIE94Weights are the application dependent parametric factors of CIE94.
+type CIE94Weights struct {
+	KL, K1, K2 float64
+}
+
+var (
+	// CIE94GraphicArts are the CIE94 weights for graphic arts.
+	CIE94GraphicArts = CIE94Weights{KL: 1, K1: 0.045, K2: 0.015}
+	// CIE94Textiles are the CIE94 weights for textiles.
+	CIE94Textiles = CIE94Weights{KL: 2, K1: 0.048, K2: 0.014}
+)
+
+// lab64 returns the components of a LAB value in double precision.
+func lab64(lab LAB) (float64, float64, float64) {
+	return float64(lab[0]), float64(lab[1]), float64(lab[2])
+}
+
+// hueDeg returns the hue angle of (a, b) in degrees in [0, 360), 0 for the
+// achromatic axis.
+func hueDeg(a, b float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// DeltaE94 calculates the CIE94 color difference of a sample from a reference.
+// CIE94 is not symmetric: the chroma of the reference weights the chroma and
+// hue differences.
+func DeltaE94(reference, sample LAB, w CIE94Weights) float32 {
+	L1, a1, b1 := lab64(reference)
+	L2, a2, b2 := lab64(sample)
+	C1 := math.Hypot(a1, b1)
+	C2 := math.Hypot(a2, b2)
+	dL := L1 - L2
+	dC := C1 - C2
+	da, db := a1-a2, b1-b2
+	dH2 := max(0, da*da+db*db-dC*dC)
+
+	SC := 1 + w.K1*C1
+	SH := 1 + w.K2*C1
+	l := dL / w.KL
+	c := dC / SC
+	return float32(math.Sqrt(l*l + c*c + dH2/(SH*SH)))
+}
+
+// DeltaE2000 calculates the CIEDE2000 color difference between two LAB color
+// values with unit parametric factors.
+func DeltaE2000(lab1, lab2 LAB) float32 {
+	return DeltaE2000Weighted(lab1, lab2, 1, 1, 1)
+}
+
+// DeltaE2000Weighted calculates the CIEDE2000 color difference with the
+// parametric factors kL, kC and kH for lightness, chroma and hue (Sharma,
+// Wu and Dalal 2005). Textiles commonly use kL = 2.
+func DeltaE2000Weighted(lab1, lab2 LAB, kL, kC, kH float64) float32 {
+	L1, a1, b1 := lab64(lab1)
+	L2, a2, b2 := lab64(lab2)
+
+	// a* is stretched near the neutral axis to fix the blue region
+	Cbar := (math.Hypot(a1, b1) + math.Hypot(a2, b2)) / 2
+	c7 := math.Pow(Cbar, 7)
+	G := 0.5 * (1 - math.Sqrt(c7/(c7+math.Pow(25, 7))))
+	a1p, a2p := (1+G)*a1, (1+G)*a2
+	C1p, C2p := math.Hypot(a1p, b1), math.Hypot(a2p, b2)
+	h1p, h2p := hueDeg(a1p, b1), hueDeg(a2p, b2)
+
+	dLp := L2 - L1
+	dCp := C2p - C1p
+	// opposite hues are on the boundary of the branches below; rounding of
+	// the hue angles must not move them across it
+	const eps = 1e-9
+	var dhp float64
+	if C1p*C2p != 0 {
+		dhp = h2p - h1p
+		switch {
+		case dhp > 180+eps:
+			dhp -= 360
+		case dhp < -180-eps:
+			dhp += 360
+		}
+	}
+	dHp := 2 * math.Sqrt(C1p*C2p) * math.Sin(rad(dhp)/2)
+
+	Lbarp := (L1 + L2) / 2
+	Cbarp := (C1p + C2p) / 2
+	hbarp := h1p + h2p
+	if C1p*C2p != 0 {
+		switch {
+		case math.Abs(h1p-h2p) <= 180+eps:
+			hbarp /= 2
+		case hbarp < 360:
+			hbarp = (hbarp + 360) / 2
+		default:
+			hbarp = (hbarp - 360) / 2
+		}
+	}
+
+	T := 1 - 0.17*math.Cos(rad(hbarp-30)) + 0.24*math.Cos(rad(2*hbarp)) +
+		0.32*math.Cos(rad(3*hbarp+6)) - 0.20*math.Cos(rad(4*hbarp-63))
+	dTheta := 30 * math.Exp(-math.Pow((hbarp-275)/25, 2))
+	c7 = math.Pow(Cbarp, 7)
+	RC := 2 * math.Sqrt(c7/(c7+math.Pow(25, 7)))
+	l50 := (Lbarp - 50) * (Lbarp - 50)
+	SL := 1 + 0.015*l50/math.Sqrt(20+l50)
+	SC := 1 + 0.045*Cbarp
+	SH := 1 + 0.015*Cbarp*T
+	RT := -math.Sin(rad(2*dTheta)) * RC
+
+	l := dLp / (kL * SL)
+	c := dCp / (kC * SC)
+	h := dHp / (kH * SH)
+	return float32(math.Sqrt(l*l + c*c + h*h + RT*c*h))
+}
+
+// DeltaECMC calculates the CMC(l:c) color difference of a sample from a
+// reference. Common ratios are 2:1 for acceptability and 1:1 for
+// perceptibility. Like CIE94 it is not symmetric.
+func DeltaECMC(reference, sample LAB, l, c float64) float32 {
+	L1, a1, b1 := lab64(reference)
+	L2, a2, b2 := lab64(sample)
+	C1 := math.Hypot(a1, b1)
+	C2 := math.Hypot(a2, b2)
+	h1 := hueDeg(a1, b1)
+	dL := L1 - L2
+	dC := C1 - C2
+	da, db := a1-a2, b1-b2
+	dH2 := max(0, da*da+db*db-dC*dC)
+
+	c4 := C1 * C1 * C1 * C1
+	F := math.Sqrt(c4 / (c4 + 1900))
+	var T float64
+	if h1 >= 164 && h1 <= 345 {
+		T = 0.56 + math.Abs(0.2*math.Cos(rad(h1+168)))
+	} else {
+		T = 0.36 + math.Abs(0.4*math.Cos(rad(h1+35)))
+	}
+	SL := 0.511
+	if L1 >= 16 {
+		SL = 0.040975 * L1 / (1 + 0.01765*L1)
+	}
+	SC := 0.0638*C1/(1+0.0131*C1) + 0.638
+	SH := SC * (F*T + 1 - F)
+
+	x := dL / (l * SL)
+	y := dC / (c * SC)
+	return float32(math.Sqrt(x*x + y*y + dH2/(SH*SH)))
+}
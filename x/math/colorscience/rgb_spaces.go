@@ -0,0 +1,178 @@
+package colorscience
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// RGBSpace is an RGB working space: the chromaticities of its primaries and
+// white point, from which its XYZ matrices are derived, and its transfer
+// function. XYZ values are relative to the white of the space with Y = 1
+// for white, as RGBToXYZ; no chromatic adaptation is applied.
+type RGBSpace struct {
+	Name string
+	// Primaries are the xy chromaticities of red, green and blue.
+	Primaries [3][2]float64
+	// White is the xy chromaticity of the white point.
+	White [2]float64
+
+	encode, decode func(float64) float64
+	toXYZ, fromXYZ mat3
+}
+
+// NewRGBSpace creates an RGB working space. encode maps linear values to
+// the encoded signal and decode inverts it; both are applied to the
+// magnitude of negative values, which keeps colors outside the gamut.
+func NewRGBSpace(name string, primaries [3][2]float64, white [2]float64, encode, decode func(float64) float64) *RGBSpace {
+	var p mat3
+	for j, c := range primaries {
+		x, y := c[0], c[1]
+		p[0][j], p[1][j], p[2][j] = x/y, 1, (1-x-y)/y
+	}
+	w := [3]float64{white[0] / white[1], 1, (1 - white[0] - white[1]) / white[1]}
+	// the primaries are scaled so that their sum is the white
+	s := p.inverse().apply(w)
+	for i := range 3 {
+		for j := range 3 {
+			p[i][j] *= s[j]
+		}
+	}
+	return &RGBSpace{
+		Name:      name,
+		Primaries: primaries,
+		White:     white,
+		encode:    encode,
+		decode:    decode,
+		toXYZ:     p,
+		fromXYZ:   p.inverse(),
+	}
+}
+
+// whiteD65 is the chromaticity of D65 for the 2 degree observer.
+var whiteD65 = [2]float64{0.3127, 0.3290}
+
+// Standard RGB working spaces, all with a D65 white.
+var (
+	// SRGB is IEC 61966-2-1 sRGB.
+	SRGB = NewRGBSpace("sRGB",
+		[3][2]float64{{0.64, 0.33}, {0.30, 0.60}, {0.15, 0.06}},
+		whiteD65, srgbEncode, srgbDecode)
+	// AdobeRGB is Adobe RGB (1998) with a pure gamma of 563/256.
+	AdobeRGB = NewRGBSpace("Adobe RGB",
+		[3][2]float64{{0.64, 0.33}, {0.21, 0.71}, {0.15, 0.06}},
+		whiteD65, gammaEncode(563.0/256), gammaDecode(563.0/256))
+	// DisplayP3 is Display P3: DCI-P3 primaries with the sRGB transfer
+	// function.
+	DisplayP3 = NewRGBSpace("Display P3",
+		[3][2]float64{{0.680, 0.320}, {0.265, 0.690}, {0.150, 0.060}},
+		whiteD65, srgbEncode, srgbDecode)
+	// Rec2020 is ITU-R BT.2020 with its camera transfer function (OETF).
+	Rec2020 = NewRGBSpace("Rec.2020",
+		[3][2]float64{{0.708, 0.292}, {0.170, 0.797}, {0.131, 0.046}},
+		whiteD65, rec2020Encode, rec2020Decode)
+)
+
+// signed applies a transfer function to the magnitude of a value.
+func signed(f func(float64) float64, v float64) float64 {
+	if v < 0 {
+		return -f(-v)
+	}
+	return f(v)
+}
+
+func srgbEncode(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+func srgbDecode(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func gammaEncode(gamma float64) func(float64) float64 {
+	return func(v float64) float64 {
+		return math.Pow(v, 1/gamma)
+	}
+}
+
+func gammaDecode(gamma float64) func(float64) float64 {
+	return func(v float64) float64 {
+		return math.Pow(v, gamma)
+	}
+}
+
+// BT.2020 OETF constants at full precision.
+const (
+	rec2020Alpha = 1.09929682680944
+	rec2020Beta  = 0.018053968510807
+)
+
+func rec2020Encode(v float64) float64 {
+	if v < rec2020Beta {
+		return 4.5 * v
+	}
+	return rec2020Alpha*math.Pow(v, 0.45) - (rec2020Alpha - 1)
+}
+
+func rec2020Decode(v float64) float64 {
+	if v < 4.5*rec2020Beta {
+		return v / 4.5
+	}
+	return math.Pow((v+rec2020Alpha-1)/rec2020Alpha, 1/0.45)
+}
+
+// Encode applies the transfer function of the space to a linear value.
+func (s *RGBSpace) Encode(linear float32) float32 {
+	return float32(signed(s.encode, float64(linear)))
+}
+
+// Decode inverts the transfer function of the space.
+func (s *RGBSpace) Decode(encoded float32) float32 {
+	return float32(signed(s.decode, float64(encoded)))
+}
+
+// ToXYZMatrix returns the matrix from linear RGB to XYZ.
+func (s *RGBSpace) ToXYZMatrix() mat.Matrix3x3 {
+	return matrix3x3(s.toXYZ)
+}
+
+// FromXYZMatrix returns the matrix from XYZ to linear RGB.
+func (s *RGBSpace) FromXYZMatrix() mat.Matrix3x3 {
+	return matrix3x3(s.fromXYZ)
+}
+
+func matrix3x3(m mat3) mat.Matrix3x3 {
+	var out mat.Matrix3x3
+	for i := range 3 {
+		for j := range 3 {
+			out[i][j] = float32(m[i][j])
+		}
+	}
+	return out
+}
+
+// ToXYZ converts encoded RGB values in [0, 1] to XYZ with Y = 1 for white.
+func (s *RGBSpace) ToXYZ(rgb RGB) XYZ {
+	v := vec64(vec.Vector3D(rgb))
+	for i := range v {
+		v[i] = signed(s.decode, v[i])
+	}
+	return NewXYZ(vec32(s.toXYZ.apply(v)))
+}
+
+// FromXYZ converts XYZ with Y = 1 for white to encoded RGB values. Colors
+// outside the gamut of the space fall outside [0, 1]; they are not clipped.
+func (s *RGBSpace) FromXYZ(xyz XYZ) RGB {
+	v := s.fromXYZ.apply(vec64(vec.Vector3D(xyz)))
+	for i := range v {
+		v[i] = signed(s.encode, v[i])
+	}
+	return NewRGB(vec32(v))
+}
@@ -0,0 +1,322 @@
+package colorscience
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// LUV represents CIE 1976 L*u*v* color values.
+type LUV vec.Vector3D
+
+// LCh represents the cylindrical form of LAB or LUV: lightness, chroma and
+// hue angle in degrees.
+type LCh vec.Vector3D
+
+// Oklab represents Oklab color values (Ottosson 2020): perceptual lightness
+// and the green-red and blue-yellow axes.
+type Oklab vec.Vector3D
+
+// ICtCp represents ITU-R BT.2100 ICtCp color values: PQ encoded intensity
+// and the blue-yellow and red-green chroma axes.
+type ICtCp vec.Vector3D
+
+// mat3 is a 3x3 matrix in double precision for color space transforms.
+type mat3 [3][3]float64
+
+func (m mat3) apply(v [3]float64) [3]float64 {
+	var out [3]float64
+	for i := range 3 {
+		out[i] = m[i][0]*v[0] + m[i][1]*v[1] + m[i][2]*v[2]
+	}
+	return out
+}
+
+func (m mat3) mul(o mat3) mat3 {
+	var out mat3
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += m[i][k] * o[k][j]
+			}
+		}
+	}
+	return out
+}
+
+// inverse returns the inverse by the adjugate; the transforms are never
+// singular.
+func (m mat3) inverse() mat3 {
+	var adj mat3
+	for i := range 3 {
+		for j := range 3 {
+			r0, r1 := (j+1)%3, (j+2)%3
+			c0, c1 := (i+1)%3, (i+2)%3
+			adj[i][j] = m[r0][c0]*m[r1][c1] - m[r0][c1]*m[r1][c0]
+		}
+	}
+	det := m[0][0]*adj[0][0] + m[0][1]*adj[1][0] + m[0][2]*adj[2][0]
+	for i := range 3 {
+		for j := range 3 {
+			adj[i][j] /= det
+		}
+	}
+	return adj
+}
+
+func vec64(v vec.Vector3D) [3]float64 {
+	return [3]float64{float64(v[0]), float64(v[1]), float64(v[2])}
+}
+
+func vec32(v [3]float64) (float32, float32, float32) {
+	return float32(v[0]), float32(v[1]), float32(v[2])
+}
+
+// lightness returns CIE L* of a relative luminance Y/Yn.
+func lightness(y float64) float64 {
+	const delta = 6.0 / 29.0
+	if y > delta*delta*delta {
+		return 116*math.Cbrt(y) - 16
+	}
+	return y * (29.0 * 29.0 * 29.0 / 27.0)
+}
+
+// lightnessInverse returns the relative luminance Y/Yn of CIE L*.
+func lightnessInverse(L float64) float64 {
+	if L > 8 {
+		f := (L + 16) / 116
+		return f * f * f
+	}
+	return L * (27.0 / (29.0 * 29.0 * 29.0))
+}
+
+// uvPrime returns the CIE 1976 UCS chromaticity of XYZ, 0 for black.
+func uvPrime(X, Y, Z float64) (float64, float64) {
+	d := X + 15*Y + 3*Z
+	if d == 0 {
+		return 0, 0
+	}
+	return 4 * X / d, 9 * Y / d
+}
+
+// XYZToLUV converts CIE XYZ to CIE L*u*v* relative to a white point.
+func XYZToLUV(X, Y, Z float32, illuminant WhitePoint) (float32, float32, float32) {
+	Xn, Yn, Zn := float64(illuminant[0]), float64(illuminant[1]), float64(illuminant[2])
+	un, vn := uvPrime(Xn, Yn, Zn)
+	u, v := uvPrime(float64(X), float64(Y), float64(Z))
+	L := lightness(float64(Y) / Yn)
+	if L == 0 {
+		return 0, 0, 0
+	}
+	return float32(L), float32(13 * L * (u - un)), float32(13 * L * (v - vn))
+}
+
+// LUVToXYZ converts CIE L*u*v* to CIE XYZ relative to a white point.
+func LUVToXYZ(L, u, v float32, illuminant WhitePoint) (float32, float32, float32) {
+	if L <= 0 {
+		return 0, 0, 0
+	}
+	Xn, Yn, Zn := float64(illuminant[0]), float64(illuminant[1]), float64(illuminant[2])
+	un, vn := uvPrime(Xn, Yn, Zn)
+	l := float64(L)
+	up := float64(u)/(13*l) + un
+	vp := float64(v)/(13*l) + vn
+	Y := Yn * lightnessInverse(l)
+	X := Y * 9 * up / (4 * vp)
+	Z := Y * (12 - 3*up - 20*vp) / (4 * vp)
+	return float32(X), float32(Y), float32(Z)
+}
+
+// LABToLCh converts L*a*b* (or L*u*v*) to lightness, chroma and hue angle in
+// degrees [0, 360).
+func LABToLCh(L, a, b float32) (float32, float32, float32) {
+	A, B := float64(a), float64(b)
+	return L, float32(math.Hypot(A, B)), float32(hueDeg(A, B))
+}
+
+// LChToLAB converts lightness, chroma and hue angle in degrees back to
+// L*a*b* (or L*u*v*).
+func LChToLAB(L, C, h float32) (float32, float32, float32) {
+	s, c := math.Sincos(rad(float64(h)))
+	return L, float32(float64(C) * c), float32(float64(C) * s)
+}
+
+var (
+	// oklabM1 maps XYZ (D65, Y of white 1) to approximate cone responses
+	oklabM1 = mat3{
+		{0.8189330101, 0.3618667424, -0.1288597137},
+		{0.0329845436, 0.9293118715, 0.0361456387},
+		{0.0482003018, 0.2643662691, 0.6338517070},
+	}
+	// oklabM2 maps the compressed cone responses to Lab
+	oklabM2 = mat3{
+		{0.2104542553, 0.7936177850, -0.0040720468},
+		{1.9779984951, -2.4285922050, 0.4505937099},
+		{0.0259040371, 0.7827717662, -0.8086757660},
+	}
+	oklabM1Inv = oklabM1.inverse()
+	oklabM2Inv = oklabM2.inverse()
+)
+
+// XYZToOklab converts CIE XYZ under D65, scaled so the white has Y = 1, to
+// Oklab.
+func XYZToOklab(X, Y, Z float32) (float32, float32, float32) {
+	lms := oklabM1.apply([3]float64{float64(X), float64(Y), float64(Z)})
+	for i := range lms {
+		lms[i] = math.Cbrt(lms[i])
+	}
+	return vec32(oklabM2.apply(lms))
+}
+
+// OklabToXYZ converts Oklab to CIE XYZ under D65 with Y = 1 for the white.
+func OklabToXYZ(L, a, b float32) (float32, float32, float32) {
+	lms := oklabM2Inv.apply([3]float64{float64(L), float64(a), float64(b)})
+	for i := range lms {
+		lms[i] = lms[i] * lms[i] * lms[i]
+	}
+	return vec32(oklabM1Inv.apply(lms))
+}
+
+// PQ constants of SMPTE ST 2084.
+const (
+	pqM1 = 2610.0 / 16384
+	pqM2 = 2523.0 / 4096 * 128
+	pqC1 = 3424.0 / 4096
+	pqC2 = 2413.0 / 4096 * 32
+	pqC3 = 2392.0 / 4096 * 32
+	// pqPeak is the luminance encoded as 1, in cd/m²
+	pqPeak = 10000
+)
+
+// PQEncode applies the SMPTE ST 2084 perceptual quantizer to an absolute
+// luminance in cd/m², returning a signal in [0, 1] for 0 to 10000 cd/m².
+func PQEncode(luminance float32) float32 {
+	return float32(pq(float64(luminance) / pqPeak))
+}
+
+// PQDecode inverts PQEncode.
+func PQDecode(signal float32) float32 {
+	return float32(pqInverse(float64(signal)) * pqPeak)
+}
+
+func pq(y float64) float64 {
+	p := math.Pow(math.Abs(y), pqM1)
+	return math.Copysign(math.Pow((pqC1+pqC2*p)/(1+pqC3*p), pqM2), y)
+}
+
+func pqInverse(e float64) float64 {
+	p := math.Pow(math.Abs(e), 1/pqM2)
+	return math.Copysign(math.Pow(max(p-pqC1, 0)/(pqC2-pqC3*p), 1/pqM1), e)
+}
+
+var (
+	// ictcpLMS maps linear Rec.2020 RGB to LMS
+	ictcpLMS = mat3{
+		{1688.0 / 4096, 2146.0 / 4096, 262.0 / 4096},
+		{683.0 / 4096, 2951.0 / 4096, 462.0 / 4096},
+		{99.0 / 4096, 309.0 / 4096, 3688.0 / 4096},
+	}
+	// ictcpMatrix maps PQ encoded LMS to ICtCp
+	ictcpMatrix = mat3{
+		{2048.0 / 4096, 2048.0 / 4096, 0},
+		{6610.0 / 4096, -13613.0 / 4096, 7003.0 / 4096},
+		{17933.0 / 4096, -17390.0 / 4096, -543.0 / 4096},
+	}
+	ictcpLMSInv    = ictcpLMS.inverse()
+	ictcpMatrixInv = ictcpMatrix.inverse()
+)
+
+// XYZToICtCp converts absolute CIE XYZ under D65, in cd/m², to ICtCp with
+// the PQ transfer function of ITU-R BT.2100.
+func XYZToICtCp(X, Y, Z float32) (float32, float32, float32) {
+	rgb := Rec2020.fromXYZ.apply([3]float64{float64(X), float64(Y), float64(Z)})
+	lms := ictcpLMS.apply(rgb)
+	for i := range lms {
+		lms[i] = pq(lms[i] / pqPeak)
+	}
+	return vec32(ictcpMatrix.apply(lms))
+}
+
+// ICtCpToXYZ converts ICtCp with the PQ transfer function to absolute CIE
+// XYZ under D65 in cd/m².
+func ICtCpToXYZ(I, Ct, Cp float32) (float32, float32, float32) {
+	lms := ictcpMatrixInv.apply([3]float64{float64(I), float64(Ct), float64(Cp)})
+	for i := range lms {
+		lms[i] = pqInverse(lms[i]) * pqPeak
+	}
+	return vec32(Rec2020.toXYZ.apply(ictcpLMSInv.apply(lms)))
+}
+
+// ToLUV converts XYZ to LUV using the provided white point.
+func (xyz XYZ) ToLUV(illuminant WhitePoint) LUV {
+	X, Y, Z := vec.Vector3D(xyz).XYZ()
+	L, u, v := XYZToLUV(X, Y, Z, illuminant)
+	return LUV{L, u, v}
+}
+
+// ToOklab converts XYZ under D65 with Y = 1 for the white to Oklab.
+func (xyz XYZ) ToOklab() Oklab {
+	X, Y, Z := vec.Vector3D(xyz).XYZ()
+	L, a, b := XYZToOklab(X, Y, Z)
+	return Oklab{L, a, b}
+}
+
+// ToICtCp converts absolute XYZ under D65 in cd/m² to ICtCp.
+func (xyz XYZ) ToICtCp() ICtCp {
+	X, Y, Z := vec.Vector3D(xyz).XYZ()
+	I, Ct, Cp := XYZToICtCp(X, Y, Z)
+	return ICtCp{I, Ct, Cp}
+}
+
+// ToXYZ converts LUV to XYZ using the provided white point.
+func (luv LUV) ToXYZ(illuminant WhitePoint) XYZ {
+	X, Y, Z := LUVToXYZ(luv[0], luv[1], luv[2], illuminant)
+	return NewXYZ(X, Y, Z)
+}
+
+// ToLCh converts LUV to its cylindrical form LCh(uv).
+func (luv LUV) ToLCh() LCh {
+	L, C, h := LABToLCh(luv[0], luv[1], luv[2])
+	return LCh{L, C, h}
+}
+
+// ToLCh converts LAB to its cylindrical form LCh(ab).
+func (lab LAB) ToLCh() LCh {
+	L, C, h := LABToLCh(lab.LAB())
+	return LCh{L, C, h}
+}
+
+// ToLAB converts LCh(ab) back to LAB.
+func (lch LCh) ToLAB() LAB {
+	return NewLAB(LChToLAB(lch[0], lch[1], lch[2]))
+}
+
+// ToLUV converts LCh(uv) back to LUV.
+func (lch LCh) ToLUV() LUV {
+	L, u, v := LChToLAB(lch[0], lch[1], lch[2])
+	return LUV{L, u, v}
+}
+
+// ToXYZ converts Oklab to XYZ under D65 with Y = 1 for the white.
+func (lab Oklab) ToXYZ() XYZ {
+	return NewXYZ(OklabToXYZ(lab[0], lab[1], lab[2]))
+}
+
+// ToXYZ converts ICtCp to absolute XYZ under D65 in cd/m².
+func (c ICtCp) ToXYZ() XYZ {
+	return NewXYZ(ICtCpToXYZ(c[0], c[1], c[2]))
+}
+
+// DeltaEOk calculates the Euclidean color difference in Oklab.
+func DeltaEOk(lab1, lab2 Oklab) float32 {
+	return vec.Vector3D(lab1).Distance(vec.Vector3D(lab2))
+}
+
+// DeltaEITP calculates the ΔE ITP color difference of ITU-R BT.2124 between
+// two ICtCp values, scaled so 1 is about a just noticeable difference.
+func DeltaEITP(c1, c2 ICtCp) float32 {
+	dI := float64(c1[0] - c2[0])
+	dT := 0.5 * float64(c1[1]-c2[1])
+	dP := float64(c1[2] - c2[2])
+	return float32(720 * math.Sqrt(dI*dI+dT*dT+dP*dP))
+}
@@ -0,0 +1,63 @@
+package rigidbody
+
+import (
+	"testing"
+
+	"github.com/chewxy/math32"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// TestForwardTrajectory pins the closed loop trajectory along a straight
+// path. The lookahead is longer than a meter so that the speed profile, and
+// not the short range snapping of advanceProfile, shapes the motion.
+//
+// The body cruises at the speed from which the profile can still stop
+// within the lookahead, and rests at the end of the path.
+func TestForwardTrajectory(t *testing.T) {
+	params := defaultParams()
+	params.LookaheadDistance = 2
+	m := customPlanner(t, Constraints{
+		MaxSpeed:               2,
+		MaxAcceleration:        1,
+		MaxDeceleration:        1,
+		MaxJerk:                5,
+		MaxTurnRate:            math32.Pi / 2,
+		MaxTurnAcceleration:    math32.Pi,
+		MaxLateralAcceleration: 3,
+	}, params)
+	if err := m.SetPath([]vec.Vector3D{{0, 0, 0}, {10, 0, 0}}); err != nil {
+		t.Fatalf("SetPath error: %v", err)
+	}
+
+	want := []struct {
+		step     int
+		x, speed float32
+	}{
+		{25, 0.0817, 0.4000},
+		{50, 0.4067, 0.9000},
+		{100, 1.7966, 1.8002},
+		{150, 3.6631, 1.8831},
+		{200, 5.5466, 1.8836},
+		{250, 7.4302, 1.8836},
+		{300, 10.0000, 0.0000},
+		{400, 10.0000, 0.0000},
+	}
+
+	state := mat.New(testStateRows, 1)
+	destination := mat.New(testStateRows, 1)
+	step := 0
+	for _, w := range want {
+		for ; step < w.step; step++ {
+			if err := m.Forward(state, destination, nil); err != nil {
+				t.Fatalf("Forward error: %v", err)
+			}
+			for i := range destination {
+				state[i][0] = destination[i][0]
+			}
+		}
+		if math32.Abs(state[0][0]-w.x) > 1e-3 || math32.Abs(state[4][0]-w.speed) > 1e-3 {
+			t.Errorf("step %d: x=%.4f speed=%.4f, want x=%.4f speed=%.4f", w.step, state[0][0], state[4][0], w.x, w.speed)
+		}
+	}
+}
@@ -7,36 +7,72 @@ import (
 	"github.com/itohio/EasyRobot/x/math"
 )
 
+// snapDistance is the remaining distance below which a slow profile snaps to the target.
+const snapDistance = .001
+
 // VAJ1D implements a jerk-limited (velocity–acceleration–jerk) trajectory
 // generator for one-dimensional motion. The profile respects configured
 // limits while tracking a target position that may change over time.
+//
+// Velocity and Acceleration are signed. A very large jerk produces a
+// trapezoidal velocity profile; a finite jerk produces an S-curve.
 type VAJ1D struct {
-	maxV, maxA, maxJ           float32
-	v1max, v2max, vamax        float32
-	Velocity, Acceleration, j0 float32
-	Input, Output, Target      float32
+	maxV, maxA, maxJ       float32
+	Velocity, Acceleration float32
+	Input, Output, Target  float32
 }
 
 // New1D constructs a VAJ1D profile with the supplied kinematic limits.
 // The returned profile is ready to track targets via successive Update calls.
 func New1D(maxVelocity, maxAcceleration, jerk float32) VAJ1D {
-	vamax := (maxAcceleration * maxAcceleration) / (jerk * 2)
-	v1max := maxVelocity / 2
-	v2max := maxVelocity / 2
-	if v1max > vamax {
-		v1max = vamax
+	return VAJ1D{
+		maxV: maxVelocity,
+		maxA: maxAcceleration,
+		maxJ: jerk,
+	}
+}
+
+// Limits returns the velocity, acceleration and jerk limits.
+func (l *VAJ1D) Limits() (maxVelocity, maxAcceleration, jerk float32) {
+	return l.maxV, l.maxA, l.maxJ
+}
+
+// SetLimits changes the kinematic limits without altering the current state.
+func (l *VAJ1D) SetLimits(maxVelocity, maxAcceleration, jerk float32) *VAJ1D {
+	l.maxV, l.maxA, l.maxJ = maxVelocity, maxAcceleration, jerk
+	return l
+}
+
+// Duration estimates the time of a rest-to-rest move over distance under the
+// current limits. Scaling velocity, acceleration and jerk by r, r² and r³
+// stretches the duration by 1/r, which is how multi-axis moves are synchronized.
+func (l *VAJ1D) Duration(distance float32) float32 {
+	d := math32.Abs(distance)
+	if d == 0 || l.maxV <= 0 || l.maxA <= 0 || l.maxJ <= 0 {
+		return 0
 	}
-	if v2max < maxVelocity-vamax {
-		v2max = maxVelocity - vamax
+	if d >= l.maxV*l.rampTime(l.maxV) {
+		return d/l.maxV + l.rampTime(l.maxV)
 	}
-	return VAJ1D{
-		maxV:  maxVelocity,
-		maxA:  maxAcceleration,
-		maxJ:  jerk,
-		v1max: v1max,
-		v2max: v2max,
-		vamax: vamax,
+	// peak velocity is not reached: find the one whose ramps cover the distance
+	lo, hi := float32(0), l.maxV
+	for i := 0; i < 32; i++ {
+		v := (lo + hi) * .5
+		if v*l.rampTime(v) < d {
+			lo = v
+		} else {
+			hi = v
+		}
 	}
+	return 2 * l.rampTime(lo)
+}
+
+// rampTime is the time to accelerate from rest to velocity v.
+func (l *VAJ1D) rampTime(v float32) float32 {
+	if v >= l.maxA*l.maxA/l.maxJ {
+		return v/l.maxA + l.maxA/l.maxJ
+	}
+	return 2 * math32.Sqrt(v/l.maxJ)
 }
 
 // Reset clears the velocity and acceleration state without altering limits.
@@ -46,6 +82,11 @@ func (l *VAJ1D) Reset() *VAJ1D {
 	return l
 }
 
+// Done reports whether the profile rests at the target.
+func (l *VAJ1D) Done() bool {
+	return l.Output == l.Target && l.Velocity == 0 && l.Acceleration == 0
+}
+
 // Update advances the internal profile by the supplied sample period and moves
 // the Output toward Target while respecting jerk, acceleration, and velocity
 // constraints.
@@ -54,143 +95,133 @@ func (l *VAJ1D) Update(samplePeriod float32) *VAJ1D {
 		l.Input = l.Output
 	}()
 
+	dt := samplePeriod
 	x1 := l.Target - l.Input
 	var c float32 = 1
-	if x1 < 1 {
+	if x1 < 0 {
 		x1 = -x1
 		c = -1
 	}
 
-	if x1 < .001 {
-		l.Output = l.Input
-		l.j0 = 0
+	// work in a frame where the target lies ahead
+	v0 := l.Velocity * c
+	a0 := l.Acceleration * c
+
+	// largest acceleration change within one step
+	aStep := math32.Min(l.maxA, l.maxJ*dt)
+
+	// settle within snapDistance when slow, or when slightly overshot
+	if x1 < snapDistance && (math32.Abs(v0) <= aStep*dt || (v0 < 0 && v0 >= -l.maxA*dt)) {
+		l.Output = l.Target
 		l.Velocity = 0
 		l.Acceleration = 0
 		return l
 	}
 
-	x0 := l.calculateKinematics(samplePeriod, x1)
-	l.Output = l.Input + x0*c
+	a1 := l.nextAcceleration(dt, x1, v0, a0)
+	v1 := math.Clamp(v0+(a0+a1)*.5*dt, -l.maxV, l.maxV)
+	if v0 >= 0 && v1 < 0 {
+		// braking never reverses the motion
+		v1, a1 = 0, 0
+	}
+	x0 := (v0 + v1) * .5 * dt
+
+	// arriving within this step at low speed, or too close to start moving
+	// within the limits: finish exactly at the target
+	if (x0 >= x1 && v1 <= 2*aStep*dt) || (v0 == 0 && v1 == 0) {
+		l.Output = l.Target
+		l.Velocity = 0
+		l.Acceleration = 0
+		return l
+	}
 
+	l.Output = l.Input + x0*c
+	l.Velocity = v1 * c
+	l.Acceleration = a1 * c
 	return l
 }
 
-// calculateKinematics integrates the next position increment given the
-// remaining distance x1 while enforcing kinematic constraints.
-func (l *VAJ1D) calculateKinematics(samplePeriod, x1 float32) float32 {
-	dt := samplePeriod
-	v0 := l.Velocity
-	a0 := l.Acceleration
-
-	// check the stage of the acceleration/deceleration part
-	x0, v0, a0, jC := l.calculateJerk(samplePeriod, x1, v0, a0, l.j0)
+// nextAcceleration selects the acceleration for the next step: the largest
+// one reachable under the jerk limit that still allows stopping at the target.
+func (l *VAJ1D) nextAcceleration(dt, x1, v0, a0 float32) float32 {
+	dj := l.maxJ * dt
+	// acceleration changes take at least one step, which bounds the effective jerk
+	j := math32.Min(l.maxJ, l.maxA/dt)
 
-	// check if we are able to stop in time
-	stopAt := l.calculateStoppingDistance(v0, a0)
-
-	if stopAt <= x1 {
-		if math32.Abs(v0) >= l.maxV-l.maxA*dt-0.5*l.maxJ*dt*dt-0.001 {
-			a0 = 0
-			jC = 0
-			v0 = l.maxV
-		}
-	} else if jC == 0 || (a0 < l.maxA && a0 >= 0) {
-		jC = -1
+	// moving away from the target: turn around
+	if v0 < 0 {
+		return towards(a0, l.maxA, dj)
 	}
 
-	// integrate the step
-	x0 += (v0 + (0.5*a0*dt+(1.0/6.0)*l.j0*dt)*dt) * dt
-	l.Velocity += (a0 + .5*l.j0*dt) * dt
-	a0 += l.j0 * dt
-	l.Acceleration = math.Clamp(a0, -l.maxA, l.maxA)
-	l.j0 = jC * l.maxJ
-
-	return x0
-}
+	lo := math32.Max(a0-dj, -l.maxA)
+	hi := math32.Min(a0+dj, l.maxA)
+	// cruise: release acceleration early enough not to exceed maxV
+	if v0 >= l.maxV || (a0 > 0 && v0+a0*a0/(2*j) >= l.maxV) {
+		hi = math32.Max(lo, math32.Min(hi, towards(a0, 0, dj)))
+	}
 
-// calculateJerk determines the jerk control to keep the trajectory within
-// the v1, v2, and jerk bounds, returning the position delta and updated
-// state variables.
-func (l *VAJ1D) calculateJerk(dt, x1, v0, a0, j0 float32) (float32, float32, float32, float32) {
-	var x0, jC float32
-	v0x := v0 + a0*dt + .5*j0*dt*dt
-	if v0x >= 0 && v0x <= l.v1max {
-		if j0 == -l.maxJ {
-			_, t := math.Quad(.5*j0, a0, v0-l.v1max, 1e-6)
-			if t <= 2*dt {
-				x0 = (v0 + (0.5*a0+(1.0/6.0)*j0*t)*t) * t
-				v0 += (a0 + .5*j0*t) * t
-				a0 += j0 * t
-			}
-		}
-		jC = 1
-	} else if v0x < l.maxV && v0x > l.v2max {
-		if j0 == l.maxJ {
-			t, _ := math.Quad(.5*j0, a0, v0-l.v2max, 1e-6)
-			x0 = (v0 + (0.5*a0+(1.0/6.0)*j0*t)*t) * t
-			v0 += (a0 + .5*j0*t) * t
-			a0 += j0 * t
+	// stopping distance after one step with acceleration a1
+	fits := func(a1 float32) bool {
+		v1 := v0 + (a0+a1)*.5*dt
+		return stoppingDistance(v1, a1, l.maxA, j)+(v0+v1)*.5*dt <= x1
+	}
+	if fits(hi) {
+		return hi
+	}
+	if !fits(lo) {
+		return lo
+	}
+	for i := 0; i < 16; i++ {
+		a := (lo + hi) * .5
+		if fits(a) {
+			lo = a
+		} else {
+			hi = a
 		}
-		jC = -1
-	} else {
-		jC = 0
 	}
-
-	return x0, v0, a0, jC
+	return lo
 }
 
-// calculateStoppingDistance estimates the distance required to stop from the
-// current velocity and acceleration under the configured jerk and acceleration
-// limits.
-func (l *VAJ1D) calculateStoppingDistance(v0, a0 float32) float32 {
-	var (
-		s, s1, s2, s3 float32
-		v1m, v2m      float32
-		v1, v2, a1    float32
-	)
-
-	// Remove acceleration
-	if a0 > 0 {
-		t := a0 / l.maxJ
-		jt := .5 * l.maxJ * t
-		s = (v0 + (.5*a0-(1.0/3.0)*jt)*t) * t
-		v0 += (a0 - jt) * t
-		a0 = 0
+// stoppingDistance estimates the distance required to stop from velocity v0
+// (towards the target) and acceleration a0 under jerk j and acceleration limit maxA.
+func stoppingDistance(v0, a0, maxA, j float32) float32 {
+	if v0 <= 0 {
+		return 0
 	}
-
-	if a0 == 0 {
-		v1m = math32.Min(v0/2, l.vamax)
-		v2m = math32.Max(v0/2, v0-l.vamax)
-	} else {
-		v1m = l.v1max
-		v2m = l.v2max
+	var s float32
+	if a0 > 0 {
+		// remove acceleration first
+		t := a0 / j
+		s = (v0 + (.5*a0-(1.0/6.0)*j*t)*t) * t
+		v0 += .5 * a0 * t
+	} else if a0 < 0 {
+		t := -a0 / j
+		if v0 <= .5*a0*a0/j {
+			// releasing the deceleration stops the motion
+			return math32.Max(0, (v0+(.5*a0+(1.0/6.0)*j*t)*t)*t)
+		}
+		// already decelerating: start from the virtual point where deceleration began
+		v0 += .5 * a0 * a0 / j
+		s = -(v0*t - (1.0/6.0)*j*t*t*t)
 	}
+	return s + brakingDistance(v0, maxA, j)
+}
 
-	// decelerate
-	if v0 > v2m {
-		_, t := math.Quad(-.5*l.maxJ, a0, v0-v2m, 1e-6)
-		v2 = v2m
-		v1 = v2m
-		s1 = (v0 + (0.5*a0-(1.0/6.0)*l.maxJ*t)*t) * t
-		a1 = a0 - l.maxJ*t
-	} else {
-		v1 = v0
-		v2 = v0
-		a1 = a0
+// brakingDistance is the length of a symmetric S-curve stop from velocity v at zero acceleration.
+func brakingDistance(v, maxA, j float32) float32 {
+	if v <= 0 {
+		return 0
 	}
-
-	// coast
-	if v1 <= v2m && v1 > v1m {
-		t := (v1 - v1m) / (-a1)
-		v2 = v1m
-		s2 = (v1 + .5*a1*t) * t
+	if v >= maxA*maxA/j {
+		return .5 * v * (v/maxA + maxA/j)
 	}
+	return v * math32.Sqrt(v/j)
+}
 
-	// remove deceleration
-	if v2 > 0 {
-		t, _ := math.Quad(.5*l.maxJ, a1, v2, 1e-6)
-		s3 = (v2 + (.5*a1+(1.0/6.0)*l.maxJ*t)*t) * t
+// towards moves a toward target by at most step.
+func towards(a, target, step float32) float32 {
+	if a < target {
+		return math32.Min(a+step, target)
 	}
-
-	return s + s1 + s2 + s3
+	return math32.Max(a-step, target)
 }
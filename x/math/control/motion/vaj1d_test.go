@@ -0,0 +1,54 @@
+package vaj
+
+import (
+	"testing"
+
+	"github.com/chewxy/math32"
+)
+
+func TestVAJ1DReachesTarget(t *testing.T) {
+	const dt = .01
+	for _, jerk := range []float32{40, 400, 1e6} {
+		for _, target := range []float32{10, .5, -3, .002} {
+			l := New1D(2, 4, jerk)
+			l.Target = target
+
+			var steps int
+			var overshoot float32
+			for ; !l.Done() && steps < 10000; steps++ {
+				l.Update(dt)
+				if math32.Abs(l.Velocity) > 2 || math32.Abs(l.Acceleration) > 4 {
+					t.Fatalf("jerk=%v target=%v: limits exceeded v=%v a=%v", jerk, target, l.Velocity, l.Acceleration)
+				}
+				overshoot = math32.Max(overshoot, (l.Output-target)*math32.Copysign(1, target))
+			}
+			if !l.Done() || l.Output != target {
+				t.Fatalf("jerk=%v target=%v: stopped at %v", jerk, target, l.Output)
+			}
+			if overshoot > .001 {
+				t.Fatalf("jerk=%v target=%v: overshoot %v", jerk, target, overshoot)
+			}
+			if d, took := l.Duration(target), float32(steps)*dt; math32.Abs(took-d) > .05 {
+				t.Fatalf("jerk=%v target=%v: took %vs, Duration %vs", jerk, target, took, d)
+			}
+		}
+	}
+}
+
+func TestVAJ1DTargetChange(t *testing.T) {
+	l := New1D(2, 4, 40)
+	l.Target = 10
+	for i := 0; i < 150; i++ {
+		l.Update(.01)
+	}
+	l.Target = -1
+	for i := 0; i < 1000 && !l.Done(); i++ {
+		l.Update(.01)
+		if l.Output < -1 {
+			t.Fatalf("overshot the new target: %v", l.Output)
+		}
+	}
+	if l.Output != -1 {
+		t.Fatalf("stopped at %v", l.Output)
+	}
+}
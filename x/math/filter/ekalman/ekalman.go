@@ -456,7 +456,9 @@ func (e *EKF) UpdateMeasurement(z vec.Vector) *EKF {
 	// P = (I - K * H) * P_pred
 	zeroMatrix(e.tempM)
 	e.tempM.Mul(e.tempN2, e.P)
-	copy(e.P, e.tempM)
+	for i := range e.P {
+		copy(e.P[i], e.tempM[i])
+	}
 
 	// Copy state to Output for Filter interface
 	copy(e.outputVec, e.x)
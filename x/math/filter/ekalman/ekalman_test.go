@@ -148,6 +148,52 @@ func TestEKF_NumericalJacobian(t *testing.T) {
 	}
 }
 
+// TestEKF_CovarianceUpdate tests that the covariance converges over
+// repeated cycles instead of collapsing after the first update.
+func TestEKF_CovarianceUpdate(t *testing.T) {
+	// State: [position, velocity], measurement: [position]
+	fFunc := func(x, u vec.Vector, dt float32) vec.Vector {
+		return vec.NewFrom(x[0]+x[1]*dt, x[1])
+	}
+	hFunc := func(x vec.Vector) vec.Vector {
+		return vec.NewFrom(x[0])
+	}
+	fJacobian := func(x, u vec.Vector, dt float32) mat.Matrix {
+		return mat.New(2, 2,
+			1, dt,
+			0, 1,
+		)
+	}
+	hJacobian := func(x vec.Vector) mat.Matrix {
+		return mat.New(1, 2, 1, 0)
+	}
+	Q := mat.New(2, 2,
+		0.01, 0,
+		0, 0.01,
+	)
+	R := mat.New(1, 1, 1)
+
+	ekf := New(2, 1, fFunc, hFunc, fJacobian, hJacobian, Q, R)
+	ekf.SetCovariance(mat.New(2, 2,
+		10, 0,
+		0, 10,
+	))
+
+	// The target moves at 2 per step; the velocity is only observable
+	// through repeated updates
+	for i := 1; i <= 30; i++ {
+		ekf.Predict(1)
+		ekf.UpdateMeasurement(vec.NewFrom(2 * float32(i)))
+	}
+	state := ekf.Output()
+	if state[0] < 59.5 || state[0] > 60.5 || state[1] < 1.9 || state[1] > 2.1 {
+		t.Errorf("Expected position ~60 and velocity ~2, got %v", state)
+	}
+	if ekf.P[0][0] <= 0 || ekf.P[0][0] >= 1 || ekf.P[1][1] <= 0 {
+		t.Errorf("Expected converged positive covariance, got %v", ekf.P)
+	}
+}
+
 // TestEKF_WithControl tests EKF with control input.
 func TestEKF_WithControl(t *testing.T) {
 	n, m, k := 2, 1, 1
@@ -112,6 +112,12 @@ func Gaussian(signal vecTypes.Vector, sigma float32, windowSize int) vec.Vector
 	return filter.ProcessBuffer(signal)
 }
 
+// Kernel returns the normalized windowSize tap Gaussian kernel of the
+// filter, e.g. for separable smoothing of images.
+func Kernel(sigma float32, windowSize int) vec.Vector {
+	return computeCoefficients(sigma, windowSize)
+}
+
 // computeCoefficients calculates Gaussian filter coefficients.
 // Uses 1D Gaussian kernel: G(x) = exp(-x^2 / (2 * sigma^2))
 func computeCoefficients(sigma float32, windowSize int) vec.Vector {
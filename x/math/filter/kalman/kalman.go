@@ -335,7 +335,9 @@ func (k *Kalman) update(z vec.Vector) *Kalman {
 	// P = (I - K * H) * P_pred
 	zeroMatrix(k.tempM)
 	k.tempM.Mul(k.tempN2, k.P)
-	copy(k.P, k.tempM)
+	for i := range k.P {
+		copy(k.P[i], k.tempM[i])
+	}
 
 	// Copy state to Output for Filter interface
 	copy(k.outputVec, k.x)
@@ -102,6 +102,42 @@ func TestKalmanFilter_PredictUpdate(t *testing.T) {
 	}
 }
 
+// TestKalmanFilter_CovarianceUpdate tests that the covariance converges
+// over repeated cycles instead of collapsing after the first update.
+func TestKalmanFilter_CovarianceUpdate(t *testing.T) {
+	// State: [position, velocity], measurement: [position]
+	F := mat.New(2, 2,
+		1, 1,
+		0, 1,
+	)
+	H := mat.New(1, 2, 1, 0)
+	Q := mat.New(2, 2,
+		0.01, 0,
+		0, 0.01,
+	)
+	R := mat.New(1, 1, 1)
+
+	filter := New(2, 1, F, H, Q, R)
+	filter.SetCovariance(mat.New(2, 2,
+		10, 0,
+		0, 10,
+	))
+
+	// The target moves at 2 per step; the velocity is only observable
+	// through repeated updates
+	for i := 1; i <= 30; i++ {
+		filter.Predict()
+		filter.UpdateMeasurement(vec.NewFrom(2 * float32(i)))
+	}
+	state := filter.Output()
+	if state[0] < 59.5 || state[0] > 60.5 || state[1] < 1.9 || state[1] > 2.1 {
+		t.Errorf("Expected position ~60 and velocity ~2, got %v", state)
+	}
+	if filter.P[0][0] <= 0 || filter.P[0][0] >= 1 || filter.P[1][1] <= 0 {
+		t.Errorf("Expected converged positive covariance, got %v", filter.P)
+	}
+}
+
 // TestKalmanFilter_Reset tests filter reset functionality.
 func TestKalmanFilter_Reset(t *testing.T) {
 	n, m := 2, 1
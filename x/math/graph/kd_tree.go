@@ -241,7 +241,7 @@ func (kt *GenericKDTree[V, E]) nearestNeighborRecursive(nodeIdx int, query V, be
 	bestIdx, bestDist = kt.nearestNeighborRecursive(nearSubtree, query, bestIdx, bestDist)
 
 	// Check if we need to search far subtree
-	if diff*diff < bestDist {
+	if diff*diff < bestDist*bestDist {
 		bestIdx, bestDist = kt.nearestNeighborRecursive(farSubtree, query, bestIdx, bestDist)
 	}
 
@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/itohio/EasyRobot/x/math/vec"
@@ -58,6 +60,26 @@ func TestKDTree_NearestNeighbor(t *testing.T) {
 	assert.Less(t, dist, float32(0.1), "Should find closest point")
 }
 
+func TestKDTree_NearestNeighborExact(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	vectors := make([]vec.Vector, 200)
+	for i := range vectors {
+		vectors[i] = vec.NewFrom(r.Float32()*100, r.Float32()*100)
+	}
+	kt := NewGenericKDTree[vec.Vector, float32](vectors)
+
+	// the far subtree must be searched whenever the splitting plane is
+	// closer than the best distance so far
+	for range 100 {
+		query := vec.NewFrom(r.Float32()*100, r.Float32()*100)
+		best := float32(math.MaxFloat32)
+		for _, v := range vectors {
+			best = min(best, query.Distance(v))
+		}
+		assert.Equal(t, best, query.Distance(kt.NearestNeighbor(query)))
+	}
+}
+
 func TestKDTree_GetHeight(t *testing.T) {
 	points := [][]float32{
 		{1.0, 1.0},
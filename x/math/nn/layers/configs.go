@@ -0,0 +1,362 @@
+package layers
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/nn/types"
+)
+
+// Constructor configurations of the built-in layers. Every layer in this
+// package is registered so that marshallers can rebuild it by type name.
+// Concatenate takes several inputs and is not a types.Layer, so it is not
+// registered.
+
+func init() {
+	Register("ReLU", (*ReLU)(nil), func(cfg Config) (types.Layer, error) {
+		return NewReLU(""), nil
+	})
+	Register("Sigmoid", (*Sigmoid)(nil), func(cfg Config) (types.Layer, error) {
+		return NewSigmoid(""), nil
+	})
+	Register("Tanh", (*Tanh)(nil), func(cfg Config) (types.Layer, error) {
+		return NewTanh(""), nil
+	})
+	Register("Softmax", (*Softmax)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		dim := r.int("dim")
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewSoftmax("", dim), nil
+	})
+	Register("Dropout", (*Dropout)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		rate := r.float("rate")
+		training := r.bool("training")
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewDropout("", WithDropoutRate(float32(rate)), WithTrainingMode(training)), nil
+	})
+	Register("BatchNorm2D", (*BatchNorm2D)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		bn := NewBatchNorm2D(r.int("features"), r.float("eps"), r.float("momentum"), "")
+		bn.isTraining = r.bool("training")
+		if r.err != nil {
+			return nil, r.err
+		}
+		return bn, nil
+	})
+	Register("Dense", (*Dense)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		in, out, opts := r.int("in"), r.int("out"), r.options()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewDense(in, out, opts...)
+	})
+	Register("Conv1D", (*Conv1D)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		in, out, kernel := r.int("in"), r.int("out"), r.int("kernel")
+		stride, pad, opts := r.int("stride"), r.int("pad"), r.options()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewConv1D(in, out, kernel, stride, pad, opts...)
+	})
+	Register("Conv2D", (*Conv2D)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		in, out := r.int("in"), r.int("out")
+		kernelH, kernelW := r.int("kernel_h"), r.int("kernel_w")
+		strideH, strideW := r.int("stride_h"), r.int("stride_w")
+		padH, padW := r.int("pad_h"), r.int("pad_w")
+		opts := r.options()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewConv2D(in, out, kernelH, kernelW, strideH, strideW, padH, padW, opts...)
+	})
+	Register("LSTM", (*LSTM)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		in, hidden, opts := r.int("input_size"), r.int("hidden_size"), r.options()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewLSTM(in, hidden, opts...)
+	})
+	Register("GPTAttention", (*GPTAttention)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		embed, heads, seq := r.int("embed_dim"), r.int("heads"), r.int("max_seq_len")
+		dropout, opts := r.float("dropout"), r.options()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewGPTAttention(embed, heads, seq, dropout, opts...)
+	})
+	Register("GPTEmbedding", (*GPTEmbedding)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		vocab, embed, opts := r.int("vocab_size"), r.int("embed_dim"), r.options()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewGPTEmbedding(vocab, embed, opts...)
+	})
+	Register("GPTEncoding", (*GPTEncoding)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		seq, embed, learned := r.int("max_seq_len"), r.int("embed_dim"), r.bool("learned")
+		opts := r.options()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewGPTEncoding(seq, embed, learned, opts...)
+	})
+	Register("MaxPool2D", (*MaxPool2D)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		kh, kw, sh, sw, ph, pw := r.pool()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewMaxPool2D(kh, kw, sh, sw, ph, pw)
+	})
+	Register("AvgPool2D", (*AvgPool2D)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		kh, kw, sh, sw, ph, pw := r.pool()
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewAvgPool2D(kh, kw, sh, sw, ph, pw)
+	})
+	Register("GlobalAvgPool2D", (*GlobalAvgPool2D)(nil), func(cfg Config) (types.Layer, error) {
+		return NewGlobalAvgPool2D(), nil
+	})
+	Register("Flatten", (*Flatten)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		start, end := r.int("start_dim"), r.int("end_dim")
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewFlatten(start, end), nil
+	})
+	Register("Reshape", (*Reshape)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		shape := r.ints("shape")
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewReshape(shape), nil
+	})
+	Register("Unsqueeze", (*Unsqueeze)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		dim := r.int("dim")
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewUnsqueeze(dim), nil
+	})
+	Register("Squeeze", (*Squeeze)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		if !r.has("dims") {
+			return NewSqueeze(), nil
+		}
+		dims := r.ints("dims")
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewSqueezeDims(dims...), nil
+	})
+	Register("Transpose", (*Transpose)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		if !r.has("dims") {
+			return NewTranspose(), nil
+		}
+		dims := r.ints("dims")
+		if r.err != nil {
+			return nil, r.err
+		}
+		return NewTransposeDims(dims...), nil
+	})
+	Register("Pad", (*Pad)(nil), func(cfg Config) (types.Layer, error) {
+		r := configReader{cfg: cfg}
+		padding, value, mode := r.ints("padding"), r.float("value"), r.int("mode")
+		if r.err != nil {
+			return nil, r.err
+		}
+		p := NewPad(padding, float32(value))
+		if p == nil {
+			return nil, fmt.Errorf("padding %v must have an even length", padding)
+		}
+		p.mode = PaddingMode(mode)
+		return p, nil
+	})
+}
+
+// pool reads the kernel, stride and padding of a 2D pooling layer.
+func (r *configReader) pool() (kernelH, kernelW, strideH, strideW, padH, padW int) {
+	return r.int("kernel_h"), r.int("kernel_w"), r.int("stride_h"), r.int("stride_w"), r.int("pad_h"), r.int("pad_w")
+}
+
+func poolConfig(kernelH, kernelW, strideH, strideW, padH, padW int) Config {
+	return Config{
+		"kernel_h": formatInt(kernelH),
+		"kernel_w": formatInt(kernelW),
+		"stride_h": formatInt(strideH),
+		"stride_w": formatInt(strideW),
+		"pad_h":    formatInt(padH),
+		"pad_w":    formatInt(padW),
+	}
+}
+
+// Config returns the constructor configuration of the layer.
+func (r *ReLU) Config() Config { return Config{} }
+
+// Config returns the constructor configuration of the layer.
+func (s *Sigmoid) Config() Config { return Config{} }
+
+// Config returns the constructor configuration of the layer.
+func (t *Tanh) Config() Config { return Config{} }
+
+// Config returns the constructor configuration of the layer.
+func (s *Softmax) Config() Config {
+	return Config{"dim": formatInt(s.dim)}
+}
+
+// Config returns the constructor configuration of the layer.
+func (d *Dropout) Config() Config {
+	return Config{
+		"rate":     formatFloat(float64(d.p)),
+		"training": formatBool(d.isTraining),
+	}
+}
+
+// Config returns the constructor configuration of the layer.
+func (bn *BatchNorm2D) Config() Config {
+	return Config{
+		"features": formatInt(bn.numFeatures),
+		"eps":      formatFloat(bn.eps),
+		"momentum": formatFloat(bn.momentum),
+		"training": formatBool(bn.isTraining),
+	}
+}
+
+// Config returns the constructor configuration of the layer.
+func (d *Dense) Config() Config {
+	cfg := d.Base.baseConfig()
+	cfg["in"] = formatInt(d.inFeatures)
+	cfg["out"] = formatInt(d.outFeatures)
+	return cfg
+}
+
+// Config returns the constructor configuration of the layer.
+func (c *Conv1D) Config() Config {
+	cfg := c.Base.baseConfig()
+	cfg["in"] = formatInt(c.inChannels)
+	cfg["out"] = formatInt(c.outChannels)
+	cfg["kernel"] = formatInt(c.kernelLen)
+	cfg["stride"] = formatInt(c.stride)
+	cfg["pad"] = formatInt(c.pad)
+	cfg["bias"] = formatBool(c.hasBias)
+	return cfg
+}
+
+// Config returns the constructor configuration of the layer.
+func (c *Conv2D) Config() Config {
+	cfg := c.Base.baseConfig()
+	for k, v := range poolConfig(c.kernelH, c.kernelW, c.strideH, c.strideW, c.padH, c.padW) {
+		cfg[k] = v
+	}
+	cfg["in"] = formatInt(c.inChannels)
+	cfg["out"] = formatInt(c.outChannels)
+	cfg["bias"] = formatBool(c.hasBias)
+	return cfg
+}
+
+// Config returns the constructor configuration of the layer.
+func (l *LSTM) Config() Config {
+	cfg := l.Base.baseConfig()
+	cfg["input_size"] = formatInt(l.inputSize)
+	cfg["hidden_size"] = formatInt(l.hiddenSize)
+	return cfg
+}
+
+// Config returns the constructor configuration of the layer.
+func (a *GPTAttention) Config() Config {
+	cfg := a.Base.baseConfig()
+	cfg["embed_dim"] = formatInt(a.embedDim)
+	cfg["heads"] = formatInt(a.numHeads)
+	cfg["max_seq_len"] = formatInt(a.maxSeqLen)
+	cfg["dropout"] = formatFloat(a.dropout)
+	return cfg
+}
+
+// Config returns the constructor configuration of the layer.
+func (e *GPTEmbedding) Config() Config {
+	cfg := e.Base.baseConfig()
+	cfg["vocab_size"] = formatInt(e.vocabSize)
+	cfg["embed_dim"] = formatInt(e.embedDim)
+	return cfg
+}
+
+// Config returns the constructor configuration of the layer.
+func (e *GPTEncoding) Config() Config {
+	cfg := e.Base.baseConfig()
+	cfg["max_seq_len"] = formatInt(e.maxSeqLen)
+	cfg["embed_dim"] = formatInt(e.embedDim)
+	cfg["learned"] = formatBool(e.learned)
+	return cfg
+}
+
+// Config returns the constructor configuration of the layer.
+func (m *MaxPool2D) Config() Config {
+	return poolConfig(m.kernelH, m.kernelW, m.strideH, m.strideW, m.padH, m.padW)
+}
+
+// Config returns the constructor configuration of the layer.
+func (a *AvgPool2D) Config() Config {
+	return poolConfig(a.kernelH, a.kernelW, a.strideH, a.strideW, a.padH, a.padW)
+}
+
+// Config returns the constructor configuration of the layer.
+func (g *GlobalAvgPool2D) Config() Config { return Config{} }
+
+// Config returns the constructor configuration of the layer.
+func (f *Flatten) Config() Config {
+	return Config{
+		"start_dim": formatInt(f.startDim),
+		"end_dim":   formatInt(f.endDim),
+	}
+}
+
+// Config returns the constructor configuration of the layer.
+func (r *Reshape) Config() Config {
+	return Config{"shape": formatInts(r.targetShape)}
+}
+
+// Config returns the constructor configuration of the layer.
+func (u *Unsqueeze) Config() Config {
+	return Config{"dim": formatInt(u.dim)}
+}
+
+// Config returns the constructor configuration of the layer.
+func (s *Squeeze) Config() Config {
+	if s.dims == nil {
+		return Config{}
+	}
+	return Config{"dims": formatInts(s.dims)}
+}
+
+// Config returns the constructor configuration of the layer.
+func (t *Transpose) Config() Config {
+	if t.dims == nil {
+		return Config{}
+	}
+	return Config{"dims": formatInts(t.dims)}
+}
+
+// Config returns the constructor configuration of the layer.
+func (p *Pad) Config() Config {
+	return Config{
+		"padding": formatInts(p.padding),
+		"value":   formatFloat(float64(p.value)),
+		"mode":    formatInt(int(p.mode)),
+	}
+}
@@ -0,0 +1,279 @@
+package layers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/itohio/EasyRobot/x/math/nn/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+// Config holds the constructor arguments of a layer keyed by argument name.
+// Values are stored as strings so that every marshaller can carry them as-is.
+type Config map[string]string
+
+// Configurable is implemented by layers that can report the arguments they were constructed with.
+type Configurable interface {
+	Config() Config
+}
+
+// Factory constructs a layer of a registered type from its configuration.
+type Factory func(cfg Config) (types.Layer, error)
+
+// Spec describes a layer independently of any serialization format.
+type Spec struct {
+	Type       string // Registered type name, e.g. "Dense"
+	Name       string
+	CanLearn   bool
+	Config     Config
+	InputShape tensor.Shape // Shape the layer was initialized with (optional)
+	Parameters map[types.ParamIndex]types.Parameter
+}
+
+var (
+	registryMu sync.RWMutex
+	factories  = make(map[string]Factory)
+	typeNames  = make(map[reflect.Type]string)
+)
+
+// Register associates a type name with a layer implementation and its factory.
+// The prototype is only used to identify the Go type of the layer.
+// Registering the same name twice replaces the previous registration.
+func Register(name string, prototype Configurable, factory Factory) {
+	if name == "" || prototype == nil || factory == nil {
+		panic("layers.Register: name, prototype and factory are required")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	factories[name] = factory
+	typeNames[reflect.TypeOf(prototype)] = name
+}
+
+// Registered returns the sorted names of all registered layer types.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TypeName returns the registered type name of the layer.
+func TypeName(layer types.Layer) (string, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	name, ok := typeNames[reflect.TypeOf(layer)]
+	return name, ok
+}
+
+// Describe captures the type, configuration and parameters of a registered layer.
+func Describe(layer types.Layer) (Spec, error) {
+	if layer == nil {
+		return Spec{}, fmt.Errorf("layers.Describe: nil layer")
+	}
+	name, ok := TypeName(layer)
+	if !ok {
+		return Spec{}, fmt.Errorf("layers.Describe: unregistered layer type %T", layer)
+	}
+	spec := Spec{
+		Type:       name,
+		Name:       layer.Name(),
+		CanLearn:   layer.CanLearn(),
+		Config:     layer.(Configurable).Config(),
+		Parameters: layer.Parameters(),
+	}
+	if input := layer.Input(); !tensor.IsNil(input) {
+		spec.InputShape = input.Shape().Clone()
+	}
+	return spec, nil
+}
+
+// Build constructs a layer from the spec without loading its parameters.
+func Build(spec Spec) (types.Layer, error) {
+	registryMu.RLock()
+	factory, ok := factories[spec.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("layers.Build: unknown layer type %q", spec.Type)
+	}
+
+	cfg := spec.Config
+	if cfg == nil {
+		cfg = Config{}
+	}
+	layer, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("layers.Build: %s: %w", spec.Type, err)
+	}
+
+	if n, ok := layer.(interface{ SetName(string) }); ok && spec.Name != "" {
+		n.SetName(spec.Name)
+	}
+	layer.SetCanLearn(spec.CanLearn)
+	return layer, nil
+}
+
+// Restore constructs a layer from the spec, initializes it with the recorded
+// input shape if there is one and loads its parameters.
+func Restore(spec Spec) (types.Layer, error) {
+	layer, err := Build(spec)
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.InputShape) > 0 {
+		if err := layer.Init(spec.InputShape); err != nil {
+			return nil, fmt.Errorf("layers.Restore: %s: %w", spec.Type, err)
+		}
+	}
+	if err := LoadParameters(layer, spec.Parameters); err != nil {
+		return nil, err
+	}
+	return layer, nil
+}
+
+// LoadParameters copies the parameters into the layer. Parameters that match
+// an existing one in shape are copied in place so that views created by Init
+// stay valid; others replace the layer parameter.
+func LoadParameters(layer types.Layer, params map[types.ParamIndex]types.Parameter) error {
+	if len(params) == 0 {
+		return nil
+	}
+	setter, ok := layer.(interface {
+		SetParameters(map[types.ParamIndex]types.Parameter) error
+	})
+	if !ok {
+		return fmt.Errorf("layers.LoadParameters: %T does not accept parameters", layer)
+	}
+
+	replace := make(map[types.ParamIndex]types.Parameter)
+	for idx, param := range params {
+		current, ok := layer.Parameter(idx)
+		if ok && !tensor.IsNil(current.Data) && !tensor.IsNil(param.Data) &&
+			current.Data.Shape().Equal(param.Data.Shape()) && current.Data.DataType() == param.Data.DataType() {
+			current.Data.Copy(param.Data)
+			current.RequiresGrad = param.RequiresGrad
+			replace[idx] = current
+			continue
+		}
+		replace[idx] = param
+	}
+	return setter.SetParameters(replace)
+}
+
+// configReader parses config values, remembering the first error.
+type configReader struct {
+	cfg Config
+	err error
+}
+
+func (r *configReader) fail(key string, err error) {
+	if r.err == nil {
+		r.err = fmt.Errorf("config %q: %w", key, err)
+	}
+}
+
+func (r *configReader) has(key string) bool {
+	_, ok := r.cfg[key]
+	return ok
+}
+
+func (r *configReader) value(key string) (string, bool) {
+	v, ok := r.cfg[key]
+	if !ok {
+		r.fail(key, fmt.Errorf("missing"))
+	}
+	return v, ok
+}
+
+func (r *configReader) int(key string) int {
+	s, ok := r.value(key)
+	if !ok {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		r.fail(key, err)
+	}
+	return v
+}
+
+func (r *configReader) float(key string) float64 {
+	s, ok := r.value(key)
+	if !ok {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		r.fail(key, err)
+	}
+	return v
+}
+
+func (r *configReader) bool(key string) bool {
+	s, ok := r.value(key)
+	if !ok {
+		return false
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		r.fail(key, err)
+	}
+	return v
+}
+
+func (r *configReader) ints(key string) []int {
+	s, ok := r.value(key)
+	if !ok || s == "" {
+		return []int{}
+	}
+	parts := strings.Split(s, ",")
+	v := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			r.fail(key, err)
+			return nil
+		}
+		v[i] = n
+	}
+	return v
+}
+
+// options converts the common base configuration into layer options.
+func (r *configReader) options() []Option {
+	var opts []Option
+	if r.has("bias") {
+		opts = append(opts, UseBias(r.bool("bias")))
+	}
+	if r.has("dtype") {
+		opts = append(opts, WithDataType(tensor.DataType(r.int("dtype"))))
+	}
+	return opts
+}
+
+func formatInt(v int) string       { return strconv.Itoa(v) }
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+func formatBool(v bool) string     { return strconv.FormatBool(v) }
+func formatInts(v []int) string {
+	s := make([]string, len(v))
+	for i, n := range v {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+// baseConfig records the data type when it differs from the default.
+func (b *Base) baseConfig() Config {
+	cfg := Config{}
+	if b.dataType != tensor.DTFP32 {
+		cfg["dtype"] = formatInt(int(b.dataType))
+	}
+	return cfg
+}
@@ -0,0 +1,114 @@
+package layers
+
+import (
+	"testing"
+
+	nntypes "github.com/itohio/EasyRobot/x/math/nn/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryRoundTrip(t *testing.T) {
+	must := func(l nntypes.Layer, err error) nntypes.Layer {
+		require.NoError(t, err)
+		return l
+	}
+
+	tests := []struct {
+		name  string
+		layer nntypes.Layer
+		input tensor.Shape
+	}{
+		{"relu", NewReLU("relu"), tensor.NewShape(2, 3)},
+		{"sigmoid", NewSigmoid("sigmoid"), tensor.NewShape(2, 3)},
+		{"tanh", NewTanh("tanh"), tensor.NewShape(2, 3)},
+		{"softmax", NewSoftmax("softmax", 1), tensor.NewShape(2, 3)},
+		{"dropout", NewDropout("dropout", WithDropoutRate(0.25)), tensor.NewShape(2, 3)},
+		{"batchnorm", NewBatchNorm2D(2, 1e-3, 0.2, "bn"), tensor.NewShape(1, 2, 3, 3)},
+		{"dense", must(NewDense(3, 2, WithCanLearn(true))), tensor.NewShape(2, 3)},
+		{"dense_nobias", must(NewDense(3, 2, UseBias(false))), tensor.NewShape(3)},
+		{"conv1d", must(NewConv1D(2, 3, 3, 1, 1)), tensor.NewShape(1, 2, 8)},
+		{"conv2d", must(NewConv2D(2, 3, 3, 2, 1, 2, 1, 0)), tensor.NewShape(1, 2, 6, 6)},
+		{"lstm", must(NewLSTM(3, 4)), tensor.NewShape(1, 3)},
+		{"maxpool", must(NewMaxPool2D(2, 2, 2, 2, 0, 0)), tensor.NewShape(1, 1, 4, 4)},
+		{"avgpool", must(NewAvgPool2D(3, 3, 1, 1, 1, 1)), tensor.NewShape(1, 1, 4, 4)},
+		{"globalavgpool", NewGlobalAvgPool2D(), tensor.NewShape(1, 2, 3, 3)},
+		{"flatten", NewFlatten(1, 3), tensor.NewShape(2, 2, 3, 1)},
+		{"reshape", NewReshape([]int{3, 2}), tensor.NewShape(2, 3)},
+		{"unsqueeze", NewUnsqueeze(0), tensor.NewShape(2, 3)},
+		{"squeeze", NewSqueezeDims(1), tensor.NewShape(2, 1, 3)},
+		{"transpose", NewTranspose(), tensor.NewShape(2, 3)},
+		{"pad", NewPad([]int{1, 1, 0, 2}, 0.5), tensor.NewShape(2, 3)},
+		{"pad_reflect", NewPadReflect([]int{1, 1, 1, 1}), tensor.NewShape(3, 3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.layer.Init(tt.input))
+			input := tensor.New(tensor.DTFP32, tt.input)
+			for i := 0; i < input.Size(); i++ {
+				input.SetAt(float64(i%7)-3, i)
+			}
+			want, err := tt.layer.Forward(input)
+			require.NoError(t, err)
+			want = want.Clone()
+
+			// The recorded input shape lets Restore initialize the layer
+			spec, err := Describe(tt.layer)
+			require.NoError(t, err)
+			assert.Equal(t, tt.input, spec.InputShape)
+			restored, err := Restore(spec)
+			require.NoError(t, err)
+
+			assert.IsType(t, tt.layer, restored)
+			assert.Equal(t, tt.layer.Name(), restored.Name())
+			assert.Equal(t, tt.layer.CanLearn(), restored.CanLearn())
+			assert.Equal(t, tt.layer.(Configurable).Config(), restored.(Configurable).Config())
+
+			got, err := restored.Forward(input)
+			require.NoError(t, err)
+			require.True(t, want.Shape().Equal(got.Shape()), "shape %v, want %v", got.Shape(), want.Shape())
+			for i := 0; i < want.Size(); i++ {
+				assert.Equal(t, want.At(i), got.At(i), "element %d", i)
+			}
+		})
+	}
+}
+
+func TestRegistryGPT(t *testing.T) {
+	attention, err := NewGPTAttention(8, 2, 4, 0)
+	require.NoError(t, err)
+	embedding, err := NewGPTEmbedding(10, 8)
+	require.NoError(t, err)
+	encoding, err := NewGPTEncoding(4, 8, true)
+	require.NoError(t, err)
+
+	for _, layer := range []nntypes.Layer{attention, embedding, encoding} {
+		spec, err := Describe(layer)
+		require.NoError(t, err)
+		restored, err := Build(spec)
+		require.NoError(t, err)
+		assert.IsType(t, layer, restored)
+		assert.Equal(t, layer.(Configurable).Config(), restored.(Configurable).Config())
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	_, err := Build(Spec{Type: "NoSuchLayer"})
+	assert.Error(t, err)
+
+	_, err = Build(Spec{Type: "Dense", Config: Config{"in": "3"}})
+	assert.Error(t, err, "missing out")
+
+	_, err = Build(Spec{Type: "Dense", Config: Config{"in": "x", "out": "2"}})
+	assert.Error(t, err)
+
+	_, err = Describe(nil)
+	assert.Error(t, err)
+
+	assert.Contains(t, Registered(), "Dense")
+	name, ok := TypeName(NewReLU(""))
+	assert.True(t, ok)
+	assert.Equal(t, "ReLU", name)
+}
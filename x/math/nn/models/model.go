@@ -55,6 +55,14 @@ func (m *Sequential) LayerCount() int {
 	return len(m.layers)
 }
 
+// InputShape returns the input shape the model was built or initialized with.
+func (m *Sequential) InputShape() tensor.Shape {
+	if m == nil {
+		return nil
+	}
+	return m.inputShape
+}
+
 // Init initializes all layers in the model.
 // This should be called after building the model and before first Forward pass.
 // Init initializes the model with the given input shape.
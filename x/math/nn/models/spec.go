@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/nn/layers"
+	"github.com/itohio/EasyRobot/x/math/nn/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+// Spec describes a Sequential model independently of any serialization format.
+type Spec struct {
+	Name       string
+	CanLearn   bool
+	InputShape tensor.Shape
+	Layers     []layers.Spec
+}
+
+// Describe captures the architecture and parameters of a model built from registered layers.
+func Describe(model types.Model) (Spec, error) {
+	if model == nil {
+		return Spec{}, fmt.Errorf("models.Describe: nil model")
+	}
+	spec := Spec{
+		Name:     model.Name(),
+		CanLearn: model.CanLearn(),
+		Layers:   make([]layers.Spec, 0, model.LayerCount()),
+	}
+	if m, ok := model.(interface{ InputShape() tensor.Shape }); ok {
+		spec.InputShape = m.InputShape().Clone()
+	}
+	for i := 0; i < model.LayerCount(); i++ {
+		ls, err := layers.Describe(model.GetLayer(i))
+		if err != nil {
+			return Spec{}, fmt.Errorf("models.Describe: layer %d: %w", i, err)
+		}
+		spec.Layers = append(spec.Layers, ls)
+	}
+	return spec, nil
+}
+
+// Restore rebuilds a Sequential model from its spec: layers are constructed
+// from the registry, the model is initialized with the recorded input shape
+// and the layer parameters are loaded.
+func Restore(spec Spec) (*Sequential, error) {
+	if len(spec.Layers) == 0 {
+		return nil, fmt.Errorf("models.Restore: no layers")
+	}
+	if len(spec.InputShape) == 0 {
+		return nil, fmt.Errorf("models.Restore: input shape is empty")
+	}
+
+	built := make([]types.Layer, len(spec.Layers))
+	layerNames := make(map[string]int)
+	for i, ls := range spec.Layers {
+		layer, err := layers.Build(ls)
+		if err != nil {
+			return nil, fmt.Errorf("models.Restore: layer %d: %w", i, err)
+		}
+		built[i] = layer
+		if name := layer.Name(); name != "" {
+			layerNames[name] = i
+		}
+	}
+
+	base := layers.NewBase("model")
+	base.ParseOptions(layers.WithCanLearn(spec.CanLearn))
+	if spec.Name != "" {
+		base.SetName(spec.Name)
+	}
+	model := NewSequential(base, built, layerNames, spec.InputShape)
+	if err := model.Init(spec.InputShape); err != nil {
+		return nil, fmt.Errorf("models.Restore: %w", err)
+	}
+
+	// Parameters are loaded after Init since some layers create them there
+	for i, ls := range spec.Layers {
+		if err := layers.LoadParameters(built[i], ls.Parameters); err != nil {
+			return nil, fmt.Errorf("models.Restore: layer %d: %w", i, err)
+		}
+	}
+	return model, nil
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/math/nn/types"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+)
+
+// paramNames maps parameter indices to state dict names. The names follow
+// PyTorch where an equivalent exists so that dicts exported by Python code
+// ("0.weight", "0.bias", ...) load directly.
+var paramNames = map[types.ParamIndex]string{
+	types.ParamWeights:      "weight",
+	types.ParamBiases:       "bias",
+	types.ParamKernels:      "kernel",
+	types.ParamLSTMWeightIH: "weight_ih",
+	types.ParamLSTMWeightHH: "weight_hh",
+	types.ParamLSTMBias:     "bias",
+}
+
+// ParamName returns the state dict name of a parameter index.
+func ParamName(idx types.ParamIndex) string {
+	if name, ok := paramNames[idx]; ok {
+		return name
+	}
+	return "param" + strconv.Itoa(int(idx))
+}
+
+// StateDict returns the parameters of the model keyed by "<layer index>.<param name>".
+// The tensors are the parameter tensors themselves, not copies.
+func StateDict(model types.Model) map[string]tensor.Tensor {
+	dict := make(map[string]tensor.Tensor)
+	if model == nil {
+		return dict
+	}
+	for i := 0; i < model.LayerCount(); i++ {
+		layer := model.GetLayer(i)
+		if layer == nil {
+			continue
+		}
+		for idx, param := range layer.Parameters() {
+			if tensor.IsNil(param.Data) {
+				continue
+			}
+			dict[strconv.Itoa(i)+"."+ParamName(idx)] = param.Data
+		}
+	}
+	return dict
+}
+
+// LoadStateDict copies the tensors of a state dict into the parameters of an
+// initialized model. Keys are "<layer>.<param name>" where layer is either the
+// layer index or the layer name. Values are converted to the parameter data type.
+// Every parameter of the model must be present unless partial is set; keys that
+// do not match any parameter are always an error.
+func LoadStateDict(model types.Model, dict map[string]tensor.Tensor, partial bool) error {
+	if model == nil {
+		return fmt.Errorf("models.LoadStateDict: nil model")
+	}
+
+	loaded := make(map[string]bool, len(dict))
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		dot := strings.LastIndexByte(key, '.')
+		if dot <= 0 {
+			return fmt.Errorf("models.LoadStateDict: invalid key %q", key)
+		}
+		index := findLayer(model, key[:dot])
+		if index < 0 {
+			return fmt.Errorf("models.LoadStateDict: %q: no such layer", key)
+		}
+		layer := model.GetLayer(index)
+		idx, ok := findParam(layer, key[dot+1:])
+		if !ok {
+			return fmt.Errorf("models.LoadStateDict: %q: no such parameter", key)
+		}
+		param, _ := layer.Parameter(idx)
+		src := dict[key]
+		if tensor.IsNil(param.Data) || tensor.IsNil(src) {
+			return fmt.Errorf("models.LoadStateDict: %q: layer not initialized", key)
+		}
+		if param.Data.Size() != src.Size() {
+			return fmt.Errorf("models.LoadStateDict: %q: shape %v, want %v", key, src.Shape(), param.Data.Shape())
+		}
+		if !param.Data.Shape().Equal(src.Shape()) {
+			// Same number of elements in a different layout, e.g. a flattened bias
+			src = src.Reshape(nil, param.Data.Shape())
+		}
+		param.Data.Copy(src)
+		loaded[strconv.Itoa(index)+"."+ParamName(idx)] = true
+	}
+
+	if partial {
+		return nil
+	}
+	for key := range StateDict(model) {
+		if !loaded[key] {
+			return fmt.Errorf("models.LoadStateDict: missing %q", key)
+		}
+	}
+	return nil
+}
+
+// findLayer resolves a layer index or name to an index.
+func findLayer(model types.Model, prefix string) int {
+	if i, err := strconv.Atoi(prefix); err == nil {
+		if i >= 0 && i < model.LayerCount() {
+			return i
+		}
+		return -1
+	}
+	for i := 0; i < model.LayerCount(); i++ {
+		if layer := model.GetLayer(i); layer != nil && layer.Name() == prefix {
+			return i
+		}
+	}
+	if m, ok := model.(*Sequential); ok && m.layerNames != nil {
+		if i, ok := m.layerNames[prefix]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// findParam resolves a parameter name within a layer.
+func findParam(layer types.Layer, name string) (types.ParamIndex, bool) {
+	for idx := range layer.Parameters() {
+		if ParamName(idx) == name {
+			return idx, true
+		}
+	}
+	return 0, false
+}
@@ -40,6 +40,7 @@ const (
 	DNN_BACKEND       FQDNType = ALGORITHMS + 5
 	FEATURES_TYPE     FQDNType = ALGORITHMS + 6
 	MATCHER           FQDNType = ALGORITHMS + 7
+	FIDUCIALS         FQDNType = ALGORITHMS + 8
 	DRAWER            FQDNType = ALGORITHMS + 0xFF
 	CRYPTO            FQDNType = 0x8000
 	SIGNATURE         FQDNType = CRYPTO + 1
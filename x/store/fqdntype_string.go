@@ -45,6 +45,7 @@ func _() {
 	_ = x[DNN_BACKEND-517]
 	_ = x[FEATURES_TYPE-518]
 	_ = x[MATCHER-519]
+	_ = x[FIDUCIALS-520]
 	_ = x[DRAWER-767]
 	_ = x[CRYPTO-32768]
 	_ = x[SIGNATURE-32769]
@@ -53,7 +54,7 @@ func _() {
 const (
 	_FQDNType_name_0 = "NONEINDEXTIMESTAMPFPSDROPPED_FRAMESPATHROBOT_IDROBOT_STATUSEVENTS"
 	_FQDNType_name_1 = "IMAGESIMAGEIMAGE_GRAYSCALEIMAGE_TRANSFORMEDSYNC_DATASTEREO_LEFTSTEREO_RIGHTDEPTH_IMAGEMAP_IMAGE"
-	_FQDNType_name_2 = "AUDIOKEY_POINTSDESCRIPTORSFEATURESDNN_MODELDNN_BACKENDFEATURES_TYPEMATCHER"
+	_FQDNType_name_2 = "AUDIOKEY_POINTSDESCRIPTORSFEATURESDNN_MODELDNN_BACKENDFEATURES_TYPEMATCHERFIDUCIALS"
 	_FQDNType_name_3 = "DRAWERSENSORS"
 	_FQDNType_name_4 = "ENVIRONMENTUSER_KEY_CODEUSER_MOUSE_DOWNUSER_MOUSE_UPUSER_MOUSE_MOVEENV_OBSERVATIONENV_ACTION"
 	_FQDNType_name_5 = "CRYPTOSIGNATURE"
@@ -63,7 +64,7 @@ const (
 var (
 	_FQDNType_index_0 = [...]uint8{0, 4, 9, 18, 21, 35, 39, 47, 59, 65}
 	_FQDNType_index_1 = [...]uint8{0, 6, 11, 26, 43, 52, 63, 75, 86, 95}
-	_FQDNType_index_2 = [...]uint8{0, 5, 15, 26, 34, 43, 54, 67, 74}
+	_FQDNType_index_2 = [...]uint8{0, 5, 15, 26, 34, 43, 54, 67, 74, 83}
 	_FQDNType_index_3 = [...]uint8{0, 6, 13}
 	_FQDNType_index_4 = [...]uint8{0, 11, 24, 39, 52, 67, 82, 92}
 	_FQDNType_index_5 = [...]uint8{0, 6, 15}
@@ -76,7 +77,7 @@ func (i FQDNType) String() string {
 	case 256 <= i && i <= 264:
 		i -= 256
 		return _FQDNType_name_1[_FQDNType_index_1[i]:_FQDNType_index_1[i+1]]
-	case 512 <= i && i <= 519:
+	case 512 <= i && i <= 520:
 		i -= 512
 		return _FQDNType_name_2[_FQDNType_index_2[i]:_FQDNType_index_2[i+1]]
 	case 767 <= i && i <= 768:
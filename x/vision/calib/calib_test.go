@@ -0,0 +1,492 @@
+package calib
+
+import (
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+const stereoData = "../../marshaller/testdata/stereo_calib"
+
+var stereoViews = []string{"01", "02", "03", "04", "05", "06", "07", "08", "09", "11", "12", "13", "14"}
+
+func loadGray(t *testing.T, path string) types.Tensor {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := image.NewGray(img.Bounds())
+	draw.Draw(g, g.Rect, img, img.Bounds().Min, draw.Src)
+	out := tensor.New(types.UINT8, tensor.NewShape(g.Rect.Dy(), g.Rect.Dx()))
+	copy(out.Data().([]uint8), g.Pix)
+	return out
+}
+
+// render draws a board seen through the homography h (board to image) with
+// 4x4 supersampling; color returns the brightness at board coordinates.
+func render(w, h int, hom [3][3]float64, color func(x, y float64) float64) types.Tensor {
+	inv, _ := rot(hom).inverse()
+	out := tensor.New(types.UINT8, tensor.NewShape(h, w))
+	data := out.Data().([]uint8)
+	for y := range h {
+		for x := range w {
+			var sum float64
+			for sy := range 4 {
+				for sx := range 4 {
+					p := inv.apply([3]float64{float64(x) + (float64(sx)+0.5)/4 - 0.5, float64(y) + (float64(sy)+0.5)/4 - 0.5, 1})
+					sum += color(p[0]/p[2], p[1]/p[2])
+				}
+			}
+			data[y*w+x] = uint8(math.Round(sum / 16))
+		}
+	}
+	return out
+}
+
+func project(hom [3][3]float64, x, y float64) vec.Vector2D {
+	p := rot(hom).apply([3]float64{x, y, 1})
+	return vec.Vector2D{float32(p[0] / p[2]), float32(p[1] / p[2])}
+}
+
+// chessboard returns the brightness of a board of unit squares whose top
+// left square at (-1, -1) is dark, on a white page.
+func chessboard(squaresX, squaresY int) func(x, y float64) float64 {
+	return func(x, y float64) float64 {
+		sx, sy := math.Floor(x+1), math.Floor(y+1)
+		if x < -1.5 || y < -1.5 || sx > float64(squaresX) || sy > float64(squaresY) {
+			return 120
+		}
+		if sx < 0 || sy < 0 || sx >= float64(squaresX) || sy >= float64(squaresY) {
+			return 230
+		}
+		if int(sx+sy)%2 == 0 {
+			return 25
+		}
+		return 230
+	}
+}
+
+func TestDistortion(t *testing.T) {
+	cams := []Camera{
+		{Fx: 500, Fy: 510, Cx: 320, Cy: 240, Distortion: []float64{-0.28, 0.08, 0.001, -0.002, 0.01}},
+		{Fx: 300, Fy: 300, Cx: 320, Cy: 240, Model: Fisheye, Distortion: []float64{0.05, -0.01, 0.002, -0.0005}},
+	}
+	for _, c := range cams {
+		for _, p := range []vec.Vector2D{{20, 30}, {600, 400}, {320, 240}, {100, 450}} {
+			n := c.Normalize(p)
+			back := c.Project(vec.Vector3D{n[0] * 2, n[1] * 2, 2})
+			if back.Distance(p) > 1e-3 {
+				t.Errorf("%v: %v -> %v -> %v", c.Model, p, n, back)
+			}
+		}
+	}
+}
+
+func TestRodrigues(t *testing.T) {
+	for _, r := range []vec.Vector3D{{0, 0, 0}, {0.1, -0.2, 0.3}, {0, 0, math.Pi - 1e-7}, {2, 1, -1}} {
+		m := Rodrigues(r)
+		back := RotationVector(m)
+		again := Rodrigues(back)
+		for i := range 3 {
+			for j := range 3 {
+				if math.Abs(float64(again[i][j]-m[i][j])) > 1e-5 {
+					t.Fatalf("%v -> %v", r, back)
+				}
+			}
+		}
+	}
+}
+
+func TestFindChessboardCorners(t *testing.T) {
+	// a 9x6 corner board seen at an angle
+	hom := [3][3]float64{{38, 9, 120}, {-6, 36, 90}, {0.0004, 0.0006, 1}}
+	img := render(640, 480, hom, chessboard(10, 7))
+	corners, ok, err := FindChessboardCorners(img, 9, 6)
+	if err != nil || !ok {
+		t.Fatalf("board not found: %v", err)
+	}
+	for i, c := range corners {
+		want := project(hom, float64(i%9), float64(i/9))
+		if c.Distance(want) > 0.1 {
+			t.Errorf("corner %d at %v, want %v", i, c, want)
+		}
+	}
+
+	// upside down the corners start next to the dark outer square again
+	flipped := [3][3]float64{{-38, -9, 120 + 38*8 + 9*5}, {6, -36, 90 - 6*8 + 36*5}, {-0.0004, -0.0006, 1 + 0.0004*8 + 0.0006*5}}
+	corners, ok, _ = FindChessboardCorners(render(640, 480, flipped, chessboard(10, 7)), 9, 6)
+	if !ok {
+		t.Fatal("flipped board not found")
+	}
+	for i, c := range corners {
+		if want := project(flipped, float64(i%9), float64(i/9)); c.Distance(want) > 0.1 {
+			t.Fatalf("corner %d at %v, want %v", i, c, want)
+		}
+	}
+
+	if _, ok, _ := FindChessboardCorners(img, 8, 6); ok {
+		t.Error("found a board of the wrong size")
+	}
+}
+
+func TestCalibrateSynthetic(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, truth := range []Camera{
+		{Width: 640, Height: 480, Fx: 520, Fy: 515, Cx: 330, Cy: 236, Distortion: []float64{-0.25, 0.07, 0.0012, -0.0008, 0}},
+		{Width: 640, Height: 480, Fx: 280, Fy: 281, Cx: 318, Cy: 244, Model: Fisheye, Distortion: []float64{0.04, -0.02, 0.003, -0.001}},
+	} {
+		// keep the boards filling a similar part of the image
+		depth := truth.Fx / 1200
+		board := ObjectPoints(9, 6, 0.03)
+		var obj [][]vec.Vector3D
+		var img [][]vec.Vector2D
+		for range 12 {
+			rv := [3]float64{r.Float64()*0.8 - 0.4, r.Float64()*0.8 - 0.4, r.Float64()*0.6 - 0.3}
+			z := depth * (0.8 + r.Float64()*0.5)
+			t := [3]float64{(r.Float64()*0.6-0.3)*z - 0.12, (r.Float64()*0.6-0.3)*z - 0.075, z}
+			rm := rodrigues(rv)
+			pts := make([]vec.Vector2D, len(board))
+			for i, p := range board {
+				c := rm.apply([3]float64{float64(p[0]), float64(p[1]), 0})
+				u, v := truth.project([3]float64{c[0] + t[0], c[1] + t[1], c[2] + t[2]})
+				pts[i] = vec.Vector2D{float32(u + r.NormFloat64()*0.1), float32(v + r.NormFloat64()*0.1)}
+			}
+			obj = append(obj, board)
+			img = append(img, pts)
+		}
+		res, err := Calibrate(obj, img, truth.Width, truth.Height, WithModel(truth.Model))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := res.Camera
+		if math.Abs(c.Fx-truth.Fx) > 2 || math.Abs(c.Fy-truth.Fy) > 2 || math.Abs(c.Cx-truth.Cx) > 2 || math.Abs(c.Cy-truth.Cy) > 2 {
+			t.Errorf("%v: camera %+v, want %+v", truth.Model, c, truth)
+		}
+		if res.Errors.RMS > 0.2 || len(res.Errors.PerView) != 12 || res.Errors.Max < res.Errors.Mean {
+			t.Errorf("%v: errors %+v", truth.Model, res.Errors)
+		}
+		// the distortion is recovered over the area the boards cover
+		for _, p := range []vec.Vector2D{{150, 130}, {500, 400}, {320, 240}} {
+			n := truth.Normalize(p)
+			got := c.Project(vec.Vector3D{n[0], n[1], 1})
+			if got.Distance(p) > 1 {
+				t.Errorf("%v: %v projects to %v", truth.Model, p, got)
+			}
+		}
+	}
+
+	if _, err := Calibrate([][]vec.Vector3D{{{0, 0, 1}}}, [][]vec.Vector2D{{{0, 0}}}, 640, 480); err == nil {
+		t.Error("a single view was accepted")
+	}
+}
+
+func TestSolvePnP(t *testing.T) {
+	cam := Camera{Width: 640, Height: 480, Fx: 520, Fy: 515, Cx: 330, Cy: 236, Distortion: []float64{-0.25, 0.07, 0.0012, -0.0008, 0}}
+	board := ObjectPoints(9, 6, 0.03)
+	rv, tv := vec.Vector3D{0.3, -0.25, 0.1}, vec.Vector3D{-0.1, -0.05, 0.45}
+	rm := Rodrigues(rv)
+	r := rand.New(rand.NewSource(5))
+	img := make([]vec.Vector2D, len(board))
+	for i, p := range board {
+		var c vec.Vector3D
+		for k := range 3 {
+			c[k] = rm[k][0]*p[0] + rm[k][1]*p[1] + tv[k]
+		}
+		img[i] = cam.Project(c)
+		img[i][0] += float32(r.NormFloat64() * 0.1)
+		img[i][1] += float32(r.NormFloat64() * 0.1)
+	}
+	gotR, gotT, err := cam.SolvePnP(board, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotR.Distance(rv) > 2e-3 || gotT.Distance(tv) > 1e-3 {
+		t.Errorf("pose %v %v, want %v %v", gotR, gotT, rv, tv)
+	}
+	if _, _, err := cam.SolvePnP(board[:3], img[:3]); err == nil {
+		t.Error("three points accepted")
+	}
+}
+
+// TestStereoCalib calibrates the OpenCV stereo sample pair and compares the
+// results with those of cv::calibrateCamera and cv::stereoCalibrate.
+func TestStereoCalib(t *testing.T) {
+	var obj [][]vec.Vector3D
+	var left, right [][]vec.Vector2D
+	for _, n := range stereoViews {
+		l, ok := findBoard(t, filepath.Join(stereoData, "left"+n+".jpg"))
+		if !ok {
+			t.Errorf("no board in left%s", n)
+			continue
+		}
+		r, ok := findBoard(t, filepath.Join(stereoData, "right"+n+".jpg"))
+		if !ok {
+			t.Errorf("no board in right%s", n)
+			continue
+		}
+		obj = append(obj, ObjectPoints(9, 6, 1))
+		left = append(left, l)
+		right = append(right, r)
+	}
+
+	l, err := Calibrate(obj, left, 640, 480)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Calibrate(obj, right, 640, 480)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		got, want float64
+	}{
+		{l.Camera.Fx, 532.8}, {l.Camera.Fy, 532.9}, {l.Camera.Cx, 342.5}, {l.Camera.Cy, 233.9},
+		{r.Camera.Fx, 537.4}, {r.Camera.Fy, 537.0}, {r.Camera.Cx, 327.6}, {r.Camera.Cy, 248.9},
+	} {
+		if math.Abs(c.got-c.want) > 2 {
+			t.Errorf("intrinsic %v, want %v", c.got, c.want)
+		}
+	}
+	if l.Errors.RMS > 0.4 || r.Errors.RMS > 0.4 {
+		t.Errorf("RMS %v and %v", l.Errors.RMS, r.Errors.RMS)
+	}
+
+	s, err := StereoCalibrate(obj, left, right, &l.Camera, &r.Camera)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Errors.RMS > 0.4 {
+		t.Errorf("stereo RMS %v", s.Errors.RMS)
+	}
+	if math.Abs(float64(s.T[0])+3.33) > 0.05 || math.Abs(float64(s.T[1])) > 0.1 || math.Abs(float64(s.T[2])) > 0.1 {
+		t.Errorf("T = %v", s.T)
+	}
+	if angle := RotationVector(s.R).Magnitude(); angle > 0.02 {
+		t.Errorf("rotation of %v rad", angle)
+	}
+	// corresponding undistorted corners satisfy the epipolar constraint
+	for i, p := range s.Left.UndistortPoints(left[0]) {
+		q := s.Right.UndistortPoints(right[0][i : i+1])[0]
+		var line [3]float64
+		for a := range 3 {
+			line[a] = float64(s.F[a][0]*p[0] + s.F[a][1]*p[1] + s.F[a][2])
+		}
+		d := line[0]*float64(q[0]) + line[1]*float64(q[1]) + line[2]
+		if dist := math.Abs(d) / math.Hypot(line[0], line[1]); dist > 1 {
+			t.Errorf("corner %d is %v px off its epipolar line", i, dist)
+		}
+	}
+
+	// after rectification the epipolar lines are the image rows
+	rect := s.Rectify()
+	if rect.P2[0][3] >= 0 || rect.P1[0][0] != rect.P2[0][0] || rect.P1[0][2] != rect.P2[0][2] {
+		t.Errorf("projections %v and %v", rect.P1, rect.P2)
+	}
+	lmap, rmap := rect.Maps(s)
+	var worst float64
+	for v := range obj {
+		for i := range obj[v] {
+			pl := rectifyPoint(&s.Left, rect.R1, rect.P1, left[v][i])
+			pr := rectifyPoint(&s.Right, rect.R2, rect.P2, right[v][i])
+			worst = math.Max(worst, math.Abs(float64(pl[1]-pr[1])))
+			if pl[0] <= pr[0] {
+				t.Fatalf("view %d corner %d has disparity %v", v, i, pl[0]-pr[0])
+			}
+			// the remap table samples the corner where it was seen
+			j := int(pl[1]+0.5)*lmap.Width + int(pl[0]+0.5)
+			if d := math.Hypot(float64(lmap.X[j]-left[v][i][0]), float64(lmap.Y[j]-left[v][i][1])); d > 1 {
+				t.Fatalf("left map of view %d corner %d is %v px off", v, i, d)
+			}
+		}
+	}
+	if worst > 1 || rmap.Width != 640 || rmap.Height != 480 {
+		t.Errorf("rows of corresponding corners differ by up to %v px", worst)
+	}
+}
+
+// rectifyPoint maps a pixel into the rectified image of a camera.
+func rectifyPoint(c *Camera, r mat.Matrix3x3, p mat.Matrix3x4, q vec.Vector2D) vec.Vector2D {
+	n := c.Normalize(q)
+	var x [3]float32
+	for i := range 3 {
+		x[i] = r[i][0]*n[0] + r[i][1]*n[1] + r[i][2]
+	}
+	return vec.Vector2D{p[0][0]*x[0]/x[2] + p[0][2], p[1][1]*x[1]/x[2] + p[1][2]}
+}
+
+func findBoard(t *testing.T, path string) ([]vec.Vector2D, bool) {
+	corners, ok, err := FindChessboardCorners(loadGray(t, path), 9, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return corners, ok
+}
+
+func TestCharuco(t *testing.T) {
+	board := CharucoBoard{SquaresX: 5, SquaresY: 7, SquareLength: 1, MarkerLength: 0.6}
+	if board.Markers() != 17 || len(board.Corners()) != 24 {
+		t.Fatalf("%d markers, %d corners", board.Markers(), len(board.Corners()))
+	}
+	squares := map[[2]int]bool{}
+	for id := range board.Markers() {
+		x, y, ok := board.markerSquare(id)
+		if !ok || (x+y)%2 != 1 || squares[[2]int{x, y}] {
+			t.Fatalf("marker %d in square %d,%d", id, x, y)
+		}
+		squares[[2]int{x, y}] = true
+	}
+
+	hom := [3][3]float64{{52, 8, 150}, {-5, 50, 60}, {0.0003, 0.0004, 1}}
+	img := render(640, 480, hom, func(x, y float64) float64 {
+		sx, sy := math.Floor(x), math.Floor(y)
+		if x < 0 || y < 0 || sx >= 5 || sy >= 7 {
+			return 230
+		}
+		if int(sx+sy)%2 == 0 {
+			return 25
+		}
+		// a dark marker with a bright bit inside the white squares
+		fx, fy := x-sx, y-sy
+		if fx > 0.2 && fx < 0.8 && fy > 0.2 && fy < 0.8 {
+			if fx > 0.4 && fx < 0.6 && fy > 0.4 && fy < 0.6 {
+				return 230
+			}
+			return 25
+		}
+		return 230
+	})
+
+	// noisy marker detections of a few markers
+	r := rand.New(rand.NewSource(1))
+	ids := []int{0, 3, 6, 8, 12}
+	var markers [][4]vec.Vector2D
+	for _, id := range ids {
+		mc, _ := board.MarkerCorners(id)
+		var c [4]vec.Vector2D
+		for k := range 4 {
+			c[k] = project(hom, float64(mc[k][0]), float64(mc[k][1]))
+			c[k][0] += float32(r.NormFloat64() * 0.5)
+			c[k][1] += float32(r.NormFloat64() * 0.5)
+		}
+		markers = append(markers, c)
+	}
+	cornerIDs, corners, err := board.InterpolateCorners(img, ids, markers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cornerIDs) < 10 {
+		t.Fatalf("%d corners interpolated", len(cornerIDs))
+	}
+	objects := board.Corners()
+	for i, id := range cornerIDs {
+		want := project(hom, float64(objects[id][0]), float64(objects[id][1]))
+		if corners[i].Distance(want) > 0.15 {
+			t.Errorf("corner %d at %v, want %v", id, corners[i], want)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	res := &Result{
+		Camera:    Camera{Width: 640, Height: 480, Fx: 530, Fy: 531, Cx: 320, Cy: 240, Model: Fisheye, Distortion: []float64{0.1, 0.01, 0, 0}},
+		Rotations: make([]vec.Vector3D, 7),
+		Errors:    Errors{RMS: 0.3},
+	}
+	for _, name := range []string{"camera.yaml", "camera.json"} {
+		path := filepath.Join(dir, name)
+		if err := Save(path, res.File(9, 6)); err != nil {
+			t.Fatal(err)
+		}
+		var f MonoFile
+		if err := Load(path, &f); err != nil {
+			t.Fatal(err)
+		}
+		cam, err := f.Camera()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cam.Fx != 530 || cam.Cy != 240 || cam.Model != Fisheye || len(cam.Distortion) != 4 || f.NumSamples != 7 || f.GridShape != [2]int{9, 6} {
+			t.Errorf("%s: %+v", name, f)
+		}
+	}
+
+	// the JSON cmd/calib_stereo writes
+	path := filepath.Join(dir, "stereo.json")
+	data := `{
+  "left_camera_matrix": [[530, 0, 320], [0, 530, 240], [0, 0, 1]],
+  "left_distortion_coefficients": [-0.2, 0.05, 0, 0, 0],
+  "right_camera_matrix": [[531, 0, 321], [0, 531, 241], [0, 0, 1]],
+  "right_distortion_coefficients": [-0.21, 0.04, 0, 0, 0],
+  "rotation": [[1, 0, 0], [0, 1, 0], [0, 0, 1]],
+  "translation": [-0.06, 0, 0],
+  "essential": [[0, 0, 0], [0, 0, 0.06], [0, -0.06, 0]],
+  "fundamental": [[0, 0, 0], [0, 0, 1], [0, -1, 0]],
+  "left_rectification": null,
+  "right_rectification": null,
+  "left_projection": null,
+  "right_projection": null,
+  "disparity_to_depth_map": null,
+  "image_size": [640, 480],
+  "num_samples": 20,
+  "grid_shape": [9, 7],
+  "reprojection_error": 0.31
+}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var f StereoFile
+	if err := Load(path, &f); err != nil {
+		t.Fatal(err)
+	}
+	s, err := f.Stereo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Right.Cx != 321 || s.T[0] != -0.06 || s.Left.Model != RadialTangential || s.Left.Height != 480 {
+		t.Errorf("%+v", s)
+	}
+	// without rectification in the file it is computed
+	want, err := f.Rectification()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.P2[0][3] >= 0 || want.Q[3][2] <= 0 {
+		t.Errorf("rectification %+v", want)
+	}
+	path = filepath.Join(dir, "stereo.yaml")
+	if err := Save(path, s.File(9, 7)); err != nil {
+		t.Fatal(err)
+	}
+	f = StereoFile{}
+	if err := Load(path, &f); err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Rectification()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *want {
+		t.Errorf("rectification %+v, want %+v", got, want)
+	}
+	f.DisparityToDepthMap = f.DisparityToDepthMap[:3]
+	if _, err := f.Rectification(); err == nil {
+		t.Error("3x4 disparity to depth map accepted")
+	}
+}
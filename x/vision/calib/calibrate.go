@@ -0,0 +1,331 @@
+package calib
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/geometry"
+)
+
+// Option configures calibration.
+type Option func(*config)
+
+type config struct {
+	model             Model
+	maxIterations     int
+	fixPrincipalPoint bool
+	zeroTangent       bool
+}
+
+// WithModel selects the distortion model (default: RadialTangential).
+func WithModel(m Model) Option {
+	return func(c *config) {
+		c.model = m
+	}
+}
+
+// WithMaxIterations caps the Levenberg-Marquardt iterations (default: 30).
+func WithMaxIterations(n int) Option {
+	return func(c *config) {
+		if n <= 0 {
+			panic("calib: iterations must be positive")
+		}
+		c.maxIterations = n
+	}
+}
+
+// WithFixedPrincipalPoint keeps the principal point at the image centre.
+func WithFixedPrincipalPoint() Option {
+	return func(c *config) {
+		c.fixPrincipalPoint = true
+	}
+}
+
+// WithZeroTangent fixes the tangential coefficients p1 and p2 of the
+// radial-tangential model at 0.
+func WithZeroTangent() Option {
+	return func(c *config) {
+		c.zeroTangent = true
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{model: RadialTangential, maxIterations: 30}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// Errors summarises reprojection errors in pixels.
+type Errors struct {
+	// RMS is the root mean square error over all points, the value OpenCV
+	// calibration functions return.
+	RMS float64
+	// Mean and Max are the mean and largest point errors.
+	Mean, Max float64
+	// PerView is the RMS error of every view.
+	PerView []float64
+}
+
+// Result is a calibrated camera and the board poses it was estimated with.
+type Result struct {
+	Camera Camera
+	// Rotations and Translations map board points of every view to camera
+	// coordinates; rotations are rotation vectors.
+	Rotations, Translations []vec.Vector3D
+	// Errors are the reprojection errors of the calibration views.
+	Errors Errors
+}
+
+// Calibrate estimates the intrinsics and distortion of a camera from views
+// of a planar target with Zhang's method: the focal lengths are
+// initialised from the view homographies with the principal point at the
+// image centre, the board poses from the homographies of the normalized
+// points, and everything is refined jointly by Levenberg-Marquardt.
+// objectPoints must lie in the Z = 0 plane.
+func Calibrate(objectPoints [][]vec.Vector3D, imagePoints [][]vec.Vector2D, width, height int, opts ...Option) (*Result, error) {
+	cfg := newConfig(opts)
+	if err := checkViews(objectPoints, imagePoints); err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("calib: invalid image size %dx%d", width, height)
+	}
+
+	cam := Camera{
+		Width: width, Height: height,
+		Cx: float64(width-1) / 2, Cy: float64(height-1) / 2,
+		Model:      cfg.model,
+		Distortion: make([]float64, cfg.model.coefficients()),
+	}
+	if err := initFocal(&cam, objectPoints, imagePoints); err != nil {
+		return nil, err
+	}
+	poses := make([][]float64, len(objectPoints))
+	for i := range objectPoints {
+		r, t, err := cam.boardPose(objectPoints[i], imagePoints[i])
+		if err != nil {
+			return nil, fmt.Errorf("calib: view %d: %w", i, err)
+		}
+		poses[i] = append(r[:], t[:]...)
+	}
+
+	// shared parameters: fx, fy, cx, cy and the distortion coefficients
+	shared := append([]float64{cam.Fx, cam.Fy, cam.Cx, cam.Cy}, cam.Distortion...)
+	fixed := make([]bool, len(shared))
+	if cfg.fixPrincipalPoint {
+		fixed[2], fixed[3] = true, true
+	}
+	if cfg.zeroTangent && cfg.model == RadialTangential {
+		fixed[6], fixed[7] = true, true
+	}
+	p := &problem{
+		shared: shared,
+		local:  poses,
+		fixed:  fixed,
+		counts: make([]int, len(objectPoints)),
+		residuals: func(shared, local []float64, i int, out []float64) {
+			c := cam
+			c.setParams(shared)
+			c.residuals(local, objectPoints[i], imagePoints[i], out)
+		},
+	}
+	for i := range objectPoints {
+		p.counts[i] = 2 * len(objectPoints[i])
+	}
+	if _, err := p.solve(cfg.maxIterations); err != nil {
+		return nil, err
+	}
+	cam.setParams(p.shared)
+	cam.Distortion = slices.Clone(cam.Distortion)
+
+	res := &Result{Camera: cam}
+	for _, pose := range p.local {
+		res.Rotations = append(res.Rotations, vec.Vector3D{float32(pose[0]), float32(pose[1]), float32(pose[2])})
+		res.Translations = append(res.Translations, vec.Vector3D{float32(pose[3]), float32(pose[4]), float32(pose[5])})
+	}
+	res.Errors = ReprojectionErrors(&res.Camera, objectPoints, imagePoints, res.Rotations, res.Translations)
+	if math.IsNaN(res.Errors.RMS) {
+		return nil, ErrNotConverged
+	}
+	return res, nil
+}
+
+// ReprojectionErrors projects the object points of every view with its
+// pose and compares them with the image points.
+func ReprojectionErrors(cam *Camera, objectPoints [][]vec.Vector3D, imagePoints [][]vec.Vector2D, rotations, translations []vec.Vector3D) Errors {
+	var e Errors
+	var sum float64
+	n := 0
+	for i := range objectPoints {
+		pose := []float64{
+			float64(rotations[i][0]), float64(rotations[i][1]), float64(rotations[i][2]),
+			float64(translations[i][0]), float64(translations[i][1]), float64(translations[i][2]),
+		}
+		r := make([]float64, 2*len(objectPoints[i]))
+		cam.residuals(pose, objectPoints[i], imagePoints[i], r)
+		var view float64
+		for j := 0; j < len(r); j += 2 {
+			d2 := r[j]*r[j] + r[j+1]*r[j+1]
+			view += d2
+			d := math.Sqrt(d2)
+			e.Mean += d
+			e.Max = math.Max(e.Max, d)
+		}
+		sum += view
+		n += len(objectPoints[i])
+		e.PerView = append(e.PerView, math.Sqrt(view/float64(len(objectPoints[i]))))
+	}
+	if n > 0 {
+		e.RMS = math.Sqrt(sum / float64(n))
+		e.Mean /= float64(n)
+	}
+	return e
+}
+
+func checkViews(objectPoints [][]vec.Vector3D, imagePoints [][]vec.Vector2D) error {
+	if len(objectPoints) != len(imagePoints) {
+		return fmt.Errorf("calib: %d object point views for %d image point views", len(objectPoints), len(imagePoints))
+	}
+	if len(objectPoints) < 2 {
+		return ErrTooFewViews
+	}
+	for i := range objectPoints {
+		if len(objectPoints[i]) != len(imagePoints[i]) {
+			return fmt.Errorf("calib: view %d has %d object points for %d image points", i, len(objectPoints[i]), len(imagePoints[i]))
+		}
+		if len(objectPoints[i]) < 4 {
+			return fmt.Errorf("calib: view %d: %w", i, geometry.ErrTooFewPoints)
+		}
+		for _, p := range objectPoints[i] {
+			if p[2] != 0 {
+				return ErrNotPlanar
+			}
+		}
+	}
+	return nil
+}
+
+// setParams loads fx, fy, cx, cy and the distortion coefficients.
+func (c *Camera) setParams(p []float64) {
+	c.Fx, c.Fy, c.Cx, c.Cy = p[0], p[1], p[2], p[3]
+	c.Distortion = p[4:]
+}
+
+// residuals writes the projection errors of a view with the pose
+// (rotation vector, translation) to out, x and y interleaved.
+func (c *Camera) residuals(pose []float64, objectPoints []vec.Vector3D, imagePoints []vec.Vector2D, out []float64) {
+	r := rodrigues([3]float64{pose[0], pose[1], pose[2]})
+	for j, op := range objectPoints {
+		p := r.apply([3]float64{float64(op[0]), float64(op[1]), float64(op[2])})
+		p[0] += pose[3]
+		p[1] += pose[4]
+		p[2] += pose[5]
+		u, v := c.project(p)
+		out[2*j] = u - float64(imagePoints[j][0])
+		out[2*j+1] = v - float64(imagePoints[j][1])
+	}
+}
+
+// initFocal estimates fx and fy from the orthogonality of the board axes
+// in every view homography, with the principal point fixed (as OpenCV's
+// initIntrinsicParams).
+func initFocal(cam *Camera, objectPoints [][]vec.Vector3D, imagePoints [][]vec.Vector2D) error {
+	// rows of A f = b for f = (1/fx^2, 1/fy^2)
+	var ata [2][2]float64
+	var atb [2]float64
+	add := func(a0, a1, b float64) {
+		ata[0][0] += a0 * a0
+		ata[0][1] += a0 * a1
+		ata[1][1] += a1 * a1
+		atb[0] += a0 * b
+		atb[1] += a1 * b
+	}
+	for i := range objectPoints {
+		h, err := geometry.Homography(planar(objectPoints[i]), imagePoints[i])
+		if err != nil {
+			return fmt.Errorf("calib: view %d: %w", i, err)
+		}
+		// move the principal point to the origin
+		var m [3][3]float64
+		for r := range 3 {
+			for c := range 3 {
+				m[r][c] = float64(h[r][c])
+			}
+		}
+		for c := range 3 {
+			m[0][c] -= cam.Cx * m[2][c]
+			m[1][c] -= cam.Cy * m[2][c]
+		}
+		col := func(j int) [3]float64 { return [3]float64{m[0][j], m[1][j], m[2][j]} }
+		h1, h2 := col(0), col(1)
+		d1 := [3]float64{h1[0] + h2[0], h1[1] + h2[1], h1[2] + h2[2]}
+		d2 := [3]float64{h1[0] - h2[0], h1[1] - h2[1], h1[2] - h2[2]}
+		for _, pair := range [2][2][3]float64{{h1, h2}, {d1, d2}} {
+			a, b := pair[0], pair[1]
+			na, nb := norm(a), norm(b)
+			if na == 0 || nb == 0 {
+				continue
+			}
+			a = [3]float64{a[0] / na, a[1] / na, a[2] / na}
+			b = [3]float64{b[0] / nb, b[1] / nb, b[2] / nb}
+			add(a[0]*b[0], a[1]*b[1], -a[2]*b[2])
+		}
+	}
+	ata[1][0] = ata[0][1]
+	det := ata[0][0]*ata[1][1] - ata[0][1]*ata[1][0]
+	if math.Abs(det) < 1e-30 {
+		return geometry.ErrDegenerate
+	}
+	f0 := (ata[1][1]*atb[0] - ata[0][1]*atb[1]) / det
+	f1 := (ata[0][0]*atb[1] - ata[1][0]*atb[0]) / det
+	if f0 == 0 || f1 == 0 {
+		return geometry.ErrDegenerate
+	}
+	cam.Fx = math.Sqrt(math.Abs(1 / f0))
+	cam.Fy = math.Sqrt(math.Abs(1 / f1))
+	return nil
+}
+
+// planar drops the Z coordinate of board points.
+func planar(points []vec.Vector3D) []vec.Vector2D {
+	out := make([]vec.Vector2D, len(points))
+	for i, p := range points {
+		out[i] = vec.Vector2D{p[0], p[1]}
+	}
+	return out
+}
+
+// boardPose estimates the pose of a planar board from the homography
+// between its points and the normalized image points.
+func (c *Camera) boardPose(objectPoints []vec.Vector3D, imagePoints []vec.Vector2D) ([3]float64, [3]float64, error) {
+	normalized := make([]vec.Vector2D, len(imagePoints))
+	for i, p := range imagePoints {
+		normalized[i] = c.Normalize(p)
+	}
+	h, err := geometry.Homography(planar(objectPoints), normalized)
+	if err != nil {
+		return [3]float64{}, [3]float64{}, err
+	}
+	col := func(j int) [3]float64 {
+		return [3]float64{float64(h[0][j]), float64(h[1][j]), float64(h[2][j])}
+	}
+	h1, h2, h3 := col(0), col(1), col(2)
+	scale := 2 / (norm(h1) + norm(h2))
+	// the board is in front of the camera
+	if h3[2] < 0 {
+		scale = -scale
+	}
+	r1 := [3]float64{h1[0] * scale, h1[1] * scale, h1[2] * scale}
+	r2 := [3]float64{h2[0] * scale, h2[1] * scale, h2[2] * scale}
+	r3 := cross(r1, r2)
+	var r rot
+	for i := range 3 {
+		r[i] = [3]float64{r1[i], r2[i], r3[i]}
+	}
+	r = r.orthonormalize()
+	return r.vector(), [3]float64{h3[0] * scale, h3[1] * scale, h3[2] * scale}, nil
+}
@@ -0,0 +1,173 @@
+// Package calib calibrates cameras without OpenCV: chessboard corner
+// detection with sub-pixel refinement, ChArUco corner interpolation, and
+// Zhang's method (homography initialisation refined by Levenberg-Marquardt)
+// for the pinhole camera with radial-tangential or fisheye distortion.
+//
+// Calibrations are stored in the format written by cmd/calib_mono and
+// cmd/calib_stereo.
+package calib
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+var (
+	// ErrTooFewViews is returned when there are not enough views to
+	// calibrate.
+	ErrTooFewViews = errors.New("calib: too few views")
+	// ErrNotPlanar is returned for calibration targets with non-zero Z.
+	ErrNotPlanar = errors.New("calib: object points must lie in the Z = 0 plane")
+	// ErrNotConverged is returned when the optimisation fails.
+	ErrNotConverged = errors.New("calib: optimisation did not converge")
+)
+
+// Model is a lens distortion model.
+type Model int
+
+const (
+	// RadialTangential is the Brown-Conrady model of OpenCV with
+	// coefficients k1, k2, p1, p2, k3.
+	RadialTangential Model = iota
+	// Fisheye is the equidistant model of OpenCV's fisheye module with
+	// coefficients k1, k2, k3, k4.
+	Fisheye
+)
+
+func (m Model) String() string {
+	switch m {
+	case RadialTangential:
+		return "radtan"
+	case Fisheye:
+		return "fisheye"
+	}
+	return fmt.Sprintf("Model(%d)", int(m))
+}
+
+// coefficients returns the number of distortion coefficients of the model.
+func (m Model) coefficients() int {
+	if m == Fisheye {
+		return 4
+	}
+	return 5
+}
+
+// Camera is a pinhole camera with lens distortion.
+type Camera struct {
+	// Width and Height is the image size in pixels.
+	Width, Height int
+	// Fx, Fy are the focal lengths and Cx, Cy the principal point in pixels.
+	Fx, Fy, Cx, Cy float64
+	// Model selects the distortion model.
+	Model Model
+	// Distortion holds the coefficients of the model; missing ones are 0.
+	Distortion []float64
+}
+
+// Matrix returns the camera matrix K.
+func (c *Camera) Matrix() mat.Matrix3x3 {
+	return mat.Matrix3x3{
+		{float32(c.Fx), 0, float32(c.Cx)},
+		{0, float32(c.Fy), float32(c.Cy)},
+		{0, 0, 1},
+	}
+}
+
+// coeff returns distortion coefficient i, or 0 when not set.
+func (c *Camera) coeff(i int) float64 {
+	if i < len(c.Distortion) {
+		return c.Distortion[i]
+	}
+	return 0
+}
+
+// distort applies the lens distortion to normalized coordinates.
+func (c *Camera) distort(x, y float64) (float64, float64) {
+	if c.Model == Fisheye {
+		r := math.Hypot(x, y)
+		if r < 1e-12 {
+			return x, y
+		}
+		theta := math.Atan(r)
+		t2 := theta * theta
+		thetaD := theta * (1 + t2*(c.coeff(0)+t2*(c.coeff(1)+t2*(c.coeff(2)+t2*c.coeff(3)))))
+		s := thetaD / r
+		return x * s, y * s
+	}
+	k1, k2, p1, p2, k3 := c.coeff(0), c.coeff(1), c.coeff(2), c.coeff(3), c.coeff(4)
+	r2 := x*x + y*y
+	radial := 1 + r2*(k1+r2*(k2+r2*k3))
+	return x*radial + 2*p1*x*y + p2*(r2+2*x*x),
+		y*radial + p1*(r2+2*y*y) + 2*p2*x*y
+}
+
+// undistort inverts distort iteratively.
+func (c *Camera) undistort(xd, yd float64) (float64, float64) {
+	if c.Model == Fisheye {
+		thetaD := math.Hypot(xd, yd)
+		if thetaD < 1e-12 {
+			return xd, yd
+		}
+		// Newton on theta (1 + k1 theta^2 + ...) = thetaD
+		theta := thetaD
+		for range 20 {
+			t2 := theta * theta
+			f := theta*(1+t2*(c.coeff(0)+t2*(c.coeff(1)+t2*(c.coeff(2)+t2*c.coeff(3))))) - thetaD
+			df := 1 + t2*(3*c.coeff(0)+t2*(5*c.coeff(1)+t2*(7*c.coeff(2)+t2*9*c.coeff(3))))
+			step := f / df
+			theta -= step
+			if math.Abs(step) < 1e-12 {
+				break
+			}
+		}
+		s := math.Tan(theta) / thetaD
+		return xd * s, yd * s
+	}
+	x, y := xd, yd
+	for range 20 {
+		dx, dy := c.distort(x, y)
+		ex, ey := dx-xd, dy-yd
+		x, y = x-ex, y-ey
+		if ex*ex+ey*ey < 1e-24 {
+			break
+		}
+	}
+	return x, y
+}
+
+// project maps a point in camera coordinates to pixels.
+func (c *Camera) project(p [3]float64) (float64, float64) {
+	x, y := c.distort(p[0]/p[2], p[1]/p[2])
+	return c.Fx*x + c.Cx, c.Fy*y + c.Cy
+}
+
+// Project maps a point in camera coordinates to pixels.
+func (c *Camera) Project(p vec.Vector3D) vec.Vector2D {
+	u, v := c.project([3]float64{float64(p[0]), float64(p[1]), float64(p[2])})
+	return vec.Vector2D{float32(u), float32(v)}
+}
+
+// Normalize removes the camera matrix and the lens distortion from a pixel,
+// returning the point on the Z = 1 plane.
+func (c *Camera) Normalize(p vec.Vector2D) vec.Vector2D {
+	x, y := c.undistort((float64(p[0])-c.Cx)/c.Fx, (float64(p[1])-c.Cy)/c.Fy)
+	return vec.Vector2D{float32(x), float32(y)}
+}
+
+// UndistortPoints maps distorted pixels to the pixels of an ideal pinhole
+// camera with the same camera matrix.
+func (c *Camera) UndistortPoints(points []vec.Vector2D) []vec.Vector2D {
+	out := make([]vec.Vector2D, len(points))
+	for i, p := range points {
+		n := c.Normalize(p)
+		out[i] = vec.Vector2D{
+			float32(c.Fx*float64(n[0]) + c.Cx),
+			float32(c.Fy*float64(n[1]) + c.Cy),
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package calib
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/geometry"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// CharucoBoard is a chessboard with ArUco markers in its white squares, laid
+// out as OpenCV's legacy ChArUco boards: the top left square is black and
+// markers are numbered from 0 in raster order of the white squares. The
+// chessboard corners are numbered in raster order of the inner corners.
+type CharucoBoard struct {
+	// SquaresX and SquaresY is the number of squares.
+	SquaresX, SquaresY int
+	// SquareLength and MarkerLength are the side lengths in object units.
+	SquareLength, MarkerLength float32
+}
+
+// Markers returns the number of markers on the board.
+func (b *CharucoBoard) Markers() int {
+	return b.SquaresX * b.SquaresY / 2
+}
+
+// Corners returns the object points of the chessboard corners by id.
+func (b *CharucoBoard) Corners() []vec.Vector3D {
+	out := make([]vec.Vector3D, 0, (b.SquaresX-1)*(b.SquaresY-1))
+	for y := 1; y < b.SquaresY; y++ {
+		for x := 1; x < b.SquaresX; x++ {
+			out = append(out, vec.Vector3D{float32(x) * b.SquareLength, float32(y) * b.SquareLength, 0})
+		}
+	}
+	return out
+}
+
+// markerSquare returns the square holding a marker.
+func (b *CharucoBoard) markerSquare(id int) (x, y int, ok bool) {
+	if id < 0 || id >= b.Markers() {
+		return 0, 0, false
+	}
+	// white squares have odd x + y
+	n := 2*id + 1
+	y = n / b.SquaresX
+	x = n % b.SquaresX
+	if b.SquaresX%2 == 0 && y%2 == 1 {
+		x--
+	}
+	return x, y, y < b.SquaresY
+}
+
+// MarkerCorners returns the object points of a marker's corners clockwise
+// from its top left, as ArUco detectors report them.
+func (b *CharucoBoard) MarkerCorners(id int) ([4]vec.Vector3D, bool) {
+	x, y, ok := b.markerSquare(id)
+	if !ok {
+		return [4]vec.Vector3D{}, false
+	}
+	margin := (b.SquareLength - b.MarkerLength) / 2
+	x0 := float32(x)*b.SquareLength + margin
+	y0 := float32(y)*b.SquareLength + margin
+	m := b.MarkerLength
+	return [4]vec.Vector3D{{x0, y0, 0}, {x0 + m, y0, 0}, {x0 + m, y0 + m, 0}, {x0, y0 + m, 0}}, true
+}
+
+// InterpolateCorners locates the chessboard corners next to detected
+// markers. Every corner is predicted by the homography of the corners of
+// its adjacent markers and refined to sub-pixel accuracy in the image,
+// within the white margin around the markers. It returns the corner ids
+// and their image positions.
+func (b *CharucoBoard) InterpolateCorners(img types.Tensor, markerIDs []int, markerCorners [][4]vec.Vector2D) ([]int, []vec.Vector2D, error) {
+	if len(markerIDs) != len(markerCorners) {
+		return nil, nil, fmt.Errorf("calib: %d marker ids for %d marker corners", len(markerIDs), len(markerCorners))
+	}
+	g, err := gray.FromTensor(img)
+	if err != nil {
+		return nil, nil, err
+	}
+	detected := make(map[[2]int]int, len(markerIDs))
+	for i, id := range markerIDs {
+		if x, y, ok := b.markerSquare(id); ok {
+			detected[[2]int{x, y}] = i
+		}
+	}
+
+	objects := b.Corners()
+	var ids []int
+	var corners []vec.Vector2D
+	for id, obj := range objects {
+		cx, cy := id%(b.SquaresX-1)+1, id/(b.SquaresX-1)+1
+		// the squares around corner (cx, cy) have their top left at
+		// (cx-1..cx, cy-1..cy)
+		var src, dst []vec.Vector2D
+		for _, sq := range [4][2]int{{cx - 1, cy - 1}, {cx, cy - 1}, {cx - 1, cy}, {cx, cy}} {
+			i, ok := detected[sq]
+			if !ok {
+				continue
+			}
+			mc, _ := b.MarkerCorners(markerIDs[i])
+			for k := range 4 {
+				src = append(src, vec.Vector2D{mc[k][0], mc[k][1]})
+				dst = append(dst, markerCorners[i][k])
+			}
+		}
+		if len(src) == 0 {
+			continue
+		}
+		h, err := geometry.Homography(src, dst)
+		if err != nil {
+			continue
+		}
+		p := geometry.PerspectiveTransform(h, vec.Vector2D{obj[0], obj[1]})
+		// keep the refinement window inside the white margin
+		margin := (b.SquareLength - b.MarkerLength) / 2
+		q := geometry.PerspectiveTransform(h, vec.Vector2D{obj[0] + margin, obj[1] + margin})
+		win := min(subPixWindow, int(float64(p.Distance(q))/math.Sqrt2)-1)
+		if win < 1 || p[0] < 0 || p[1] < 0 || p[0] > float32(g.W-1) || p[1] > float32(g.H-1) {
+			continue
+		}
+		x, y := cornerSubPix(g, float64(p[0]), float64(p[1]), win)
+		ids = append(ids, id)
+		corners = append(corners, vec.Vector2D{float32(x), float32(y)})
+	}
+	return ids, corners, nil
+}
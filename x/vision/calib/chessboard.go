@@ -0,0 +1,458 @@
+package calib
+
+import (
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+const (
+	// saddleSigma is the scale of the Hessian saddle response.
+	saddleSigma = 2
+	// saddleRadius is the non-maximum suppression radius of candidates.
+	saddleRadius = 4
+	// ringRadius is the radius of the ring sampled around a candidate to
+	// check that it joins four alternating squares.
+	ringRadius  = 4
+	ringSamples = 32
+	// minContrast is the smallest intensity difference between the dark and
+	// bright squares of a corner.
+	minContrast = 20
+	// subPixWindow is the half window of corner refinement, as the 11x11
+	// window of cmd/calib_mono.
+	subPixWindow = 5
+)
+
+// FindChessboardCorners finds the cols x rows inner corners of a chessboard
+// in a single channel image. The corners are returned row by row, refined
+// to sub-pixel accuracy, with rows running along cols corners. The board x
+// axis (along a row) turned to the y axis (down the rows) is clockwise on
+// screen, so object points from ObjectPoints keep their handedness. When
+// cols and rows differ in parity the first corner touches a dark outer
+// square; otherwise it is the corner closest to the top left of the image.
+// It reports false when the board is not found.
+func FindChessboardCorners(img types.Tensor, cols, rows int) ([]vec.Vector2D, bool, error) {
+	if cols < 2 || rows < 2 {
+		return nil, false, ErrTooFewViews
+	}
+	g, err := gray.FromTensor(img)
+	if err != nil {
+		return nil, false, err
+	}
+	smooth, err := blur(g, 1)
+	if err != nil {
+		return nil, false, err
+	}
+	candidates, err := saddles(g, smooth)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(candidates) < cols*rows {
+		return nil, false, nil
+	}
+
+	points := make([][2]float64, len(candidates))
+	for i, c := range candidates {
+		points[i][0], points[i][1] = cornerSubPix(g, c.x, c.y, min(subPixWindow, int(c.spacing/3)+1))
+	}
+
+	grid, ok := growGrid(points, candidates, cols, rows)
+	if !ok {
+		return nil, false, nil
+	}
+	grid = orientGrid(smooth, grid, cols, rows)
+	out := make([]vec.Vector2D, 0, cols*rows)
+	for _, row := range grid {
+		for _, p := range row {
+			out = append(out, vec.Vector2D{float32(p[0]), float32(p[1])})
+		}
+	}
+	return out, true, nil
+}
+
+// ObjectPoints returns the cols x rows corners of a board with the given
+// square size in the Z = 0 plane, row by row as FindChessboardCorners.
+func ObjectPoints(cols, rows int, square float32) []vec.Vector3D {
+	out := make([]vec.Vector3D, 0, cols*rows)
+	for y := range rows {
+		for x := range cols {
+			out = append(out, vec.Vector3D{float32(x) * square, float32(y) * square, 0})
+		}
+	}
+	return out
+}
+
+// candidate is a possible chessboard corner.
+type candidate struct {
+	x, y     float64
+	response float64
+	// spacing is the distance to the nearest other candidate.
+	spacing float64
+}
+
+// saddles returns the X junctions of g: local maxima of the negative
+// Hessian determinant that are surrounded by four alternating dark and
+// bright sectors.
+func saddles(g gray.Image, smooth gray.Image) ([]candidate, error) {
+	s, err := blur(g, saddleSigma)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]float32, g.W*g.H)
+	var peak float32
+	for y := 1; y < g.H-1; y++ {
+		for x := 1; x < g.W-1; x++ {
+			c := s.At(x, y)
+			dxx := s.At(x+1, y) - 2*c + s.At(x-1, y)
+			dyy := s.At(x, y+1) - 2*c + s.At(x, y-1)
+			dxy := (s.At(x+1, y+1) - s.At(x-1, y+1) - s.At(x+1, y-1) + s.At(x-1, y-1)) / 4
+			if r := dxy*dxy - dxx*dyy; r > 0 {
+				response[y*g.W+x] = r
+				peak = max(peak, r)
+			}
+		}
+	}
+
+	var out []candidate
+	border := ringRadius + 2
+	for y := border; y < g.H-border; y++ {
+		for x := border; x < g.W-border; x++ {
+			r := response[y*g.W+x]
+			if r <= peak*1e-3 || !localMax(response, g.W, g.H, x, y, saddleRadius) {
+				continue
+			}
+			if !isXJunction(smooth, float64(x), float64(y)) {
+				continue
+			}
+			out = append(out, candidate{x: float64(x), y: float64(y), response: float64(r)})
+		}
+	}
+	for i := range out {
+		out[i].spacing = math.Inf(1)
+		for j := range out {
+			if i != j {
+				out[i].spacing = math.Min(out[i].spacing, math.Hypot(out[i].x-out[j].x, out[i].y-out[j].y))
+			}
+		}
+	}
+	return out, nil
+}
+
+// localMax reports whether the response at (x, y) is the largest within
+// radius; ties keep the first pixel in raster order.
+func localMax(response []float32, w, h, x, y, radius int) bool {
+	r := response[y*w+x]
+	for v := max(y-radius, 0); v <= min(y+radius, h-1); v++ {
+		for u := max(x-radius, 0); u <= min(x+radius, w-1); u++ {
+			o := response[v*w+u]
+			if o > r || (o == r && v*w+u < y*w+x) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isXJunction samples a ring around (x, y) and reports whether it crosses
+// exactly two dark and two bright sectors of sufficient contrast.
+func isXJunction(g gray.Image, x, y float64) bool {
+	var ring [ringSamples]float64
+	var mean float64
+	for i := range ring {
+		a := 2 * math.Pi * float64(i) / ringSamples
+		ring[i] = g.Sample(x+ringRadius*math.Cos(a), y+ringRadius*math.Sin(a))
+		mean += ring[i]
+	}
+	mean /= ringSamples
+	var bright, dark, nb, nd float64
+	transitions := 0
+	for i, v := range ring {
+		if v > mean {
+			bright += v
+			nb++
+		} else {
+			dark += v
+			nd++
+		}
+		if (v > mean) != (ring[(i+1)%ringSamples] > mean) {
+			transitions++
+		}
+	}
+	if transitions != 4 || nb < ringSamples/4 || nd < ringSamples/4 {
+		return false
+	}
+	// both edges are lines through the corner, so opposite sectors match
+	// even under perspective; T junctions and blobs do not
+	mismatched := 0
+	for i := range ringSamples / 2 {
+		if (ring[i] > mean) != (ring[i+ringSamples/2] > mean) {
+			mismatched++
+		}
+	}
+	if mismatched > 4 {
+		return false
+	}
+	return bright/nb-dark/nd >= minContrast
+}
+
+// growGrid assembles the cols x rows board from corner candidates, growing
+// lattices from the strongest ones first.
+func growGrid(points [][2]float64, candidates []candidate, cols, rows int) ([][][2]float64, bool) {
+	order := make([]int, len(points))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case candidates[a].response > candidates[b].response:
+			return -1
+		case candidates[a].response < candidates[b].response:
+			return 1
+		}
+		return 0
+	})
+	// members of a lattice that is not the board are not grown again
+	visited := make([]bool, len(points))
+	for _, seed := range order {
+		if visited[seed] {
+			continue
+		}
+		cells, ok := grow(points, seed)
+		if !ok {
+			continue
+		}
+		for _, i := range cells {
+			visited[i] = true
+		}
+		if grid, ok := extract(points, cells, cols, rows); ok {
+			return grid, true
+		}
+	}
+	return nil, false
+}
+
+type cell struct{ i, j int }
+
+// grow walks the lattice from seed along the directions to its nearest
+// neighbours, predicting every next corner from the last step.
+func grow(points [][2]float64, seed int) (map[cell]int, bool) {
+	p := points[seed]
+	// nearest neighbour gives the first axis, the nearest roughly
+	// perpendicular one the second
+	nearest := func(skip func(int) bool) int {
+		best, bestDist := -1, math.Inf(1)
+		for i, q := range points {
+			if i == seed || skip(i) {
+				continue
+			}
+			if d := math.Hypot(q[0]-p[0], q[1]-p[1]); d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		return best
+	}
+	a := nearest(func(int) bool { return false })
+	if a < 0 {
+		return nil, false
+	}
+	u := [2]float64{points[a][0] - p[0], points[a][1] - p[1]}
+	b := nearest(func(i int) bool {
+		d := [2]float64{points[i][0] - p[0], points[i][1] - p[1]}
+		c := (d[0]*u[0] + d[1]*u[1]) / (math.Hypot(d[0], d[1]) * math.Hypot(u[0], u[1]))
+		return math.Abs(c) > 0.5
+	})
+	if b < 0 {
+		return nil, false
+	}
+	v := [2]float64{points[b][0] - p[0], points[b][1] - p[1]}
+	if math.Hypot(v[0], v[1]) > 2*math.Hypot(u[0], u[1]) {
+		return nil, false
+	}
+
+	cells := map[cell]int{{0, 0}: seed, {1, 0}: a, {0, 1}: b}
+	used := map[int]bool{seed: true, a: true, b: true}
+	queue := []cell{{0, 0}, {1, 0}, {0, 1}}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, d := range [4]cell{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			next := cell{c.i + d.i, c.j + d.j}
+			if _, ok := cells[next]; ok {
+				continue
+			}
+			// step from the cell behind c, or from the seed axes
+			step := u
+			if d.j != 0 {
+				step = v
+			}
+			step = [2]float64{step[0] * float64(d.i+d.j), step[1] * float64(d.i+d.j)}
+			if back, ok := cells[cell{c.i - d.i, c.j - d.j}]; ok {
+				step = [2]float64{points[cells[c]][0] - points[back][0], points[cells[c]][1] - points[back][1]}
+			} else if side, ok := neighbourStep(points, cells, c, d); ok {
+				step = side
+			}
+			pc := points[cells[c]]
+			want := [2]float64{pc[0] + step[0], pc[1] + step[1]}
+			tolerance := 0.3 * math.Hypot(step[0], step[1])
+			best, bestDist := -1, tolerance
+			for i, q := range points {
+				if used[i] {
+					continue
+				}
+				if dist := math.Hypot(q[0]-want[0], q[1]-want[1]); dist < bestDist {
+					best, bestDist = i, dist
+				}
+			}
+			if best >= 0 {
+				cells[next] = best
+				used[best] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return cells, true
+}
+
+// neighbourStep borrows the step in direction d from a cell next to c.
+func neighbourStep(points [][2]float64, cells map[cell]int, c, d cell) ([2]float64, bool) {
+	for _, s := range [2]cell{{d.j, d.i}, {-d.j, -d.i}} {
+		from, ok1 := cells[cell{c.i + s.i, c.j + s.j}]
+		to, ok2 := cells[cell{c.i + s.i + d.i, c.j + s.j + d.j}]
+		if ok1 && ok2 {
+			return [2]float64{points[to][0] - points[from][0], points[to][1] - points[from][1]}, true
+		}
+	}
+	return [2]float64{}, false
+}
+
+// extract returns the cols x rows window of the grown lattice, transposed
+// when needed, as rows of corners. Stray junctions next to the board may
+// extend the lattice, so the window must be the only fully populated one.
+func extract(points [][2]float64, cells map[cell]int, cols, rows int) ([][][2]float64, bool) {
+	if len(cells) < cols*rows {
+		return nil, false
+	}
+	minI, maxI, minJ, maxJ := math.MaxInt, math.MinInt, math.MaxInt, math.MinInt
+	for c := range cells {
+		minI, maxI = min(minI, c.i), max(maxI, c.i)
+		minJ, maxJ = min(minJ, c.j), max(maxJ, c.j)
+	}
+	var grid [][][2]float64
+	found := 0
+	for _, transpose := range [2]bool{false, true} {
+		if transpose && cols == rows {
+			break
+		}
+		w, h := cols, rows
+		if transpose {
+			w, h = rows, cols
+		}
+		for i0 := minI; i0+w-1 <= maxI; i0++ {
+			for j0 := minJ; j0+h-1 <= maxJ; j0++ {
+				if g, ok := window(points, cells, i0, j0, cols, rows, transpose); ok {
+					grid = g
+					found++
+				}
+			}
+		}
+	}
+	return grid, found == 1
+}
+
+// window collects the corners of a lattice window starting at (i0, j0).
+func window(points [][2]float64, cells map[cell]int, i0, j0, cols, rows int, transpose bool) ([][][2]float64, bool) {
+	grid := make([][][2]float64, rows)
+	for r := range grid {
+		grid[r] = make([][2]float64, cols)
+		for c := range grid[r] {
+			key := cell{i0 + c, j0 + r}
+			if transpose {
+				key = cell{i0 + r, j0 + c}
+			}
+			idx, ok := cells[key]
+			if !ok {
+				return nil, false
+			}
+			grid[r][c] = points[idx]
+		}
+	}
+	return grid, true
+}
+
+// orientGrid fixes the handedness and the starting corner of the board as
+// documented by FindChessboardCorners.
+func orientGrid(smooth gray.Image, grid [][][2]float64, cols, rows int) [][][2]float64 {
+	// row direction turned to the column direction must be clockwise
+	p0, px, py := grid[0][0], grid[0][1], grid[1][0]
+	if (px[0]-p0[0])*(py[1]-p0[1])-(px[1]-p0[1])*(py[0]-p0[0]) < 0 {
+		slices.Reverse(grid)
+	}
+	rotate := func(g [][][2]float64) [][][2]float64 {
+		slices.Reverse(g)
+		for _, row := range g {
+			slices.Reverse(row)
+		}
+		return g
+	}
+	if (cols+rows)%2 == 1 {
+		if evenSquaresBrighter(smooth, grid) {
+			grid = rotate(grid)
+		}
+		return grid
+	}
+	first, last := grid[0][0], grid[rows-1][cols-1]
+	if first[0]+first[1] > last[0]+last[1] {
+		grid = rotate(grid)
+	}
+	if cols == rows {
+		// a square board may start at any corner; quarter turns keep the
+		// handedness
+		best := grid
+		bestScore := grid[0][0][0] + grid[0][0][1]
+		turned := quarterTurn(grid)
+		for range 3 {
+			if s := turned[0][0][0] + turned[0][0][1]; s < bestScore {
+				best, bestScore = turned, s
+			}
+			turned = quarterTurn(turned)
+		}
+		grid = best
+	}
+	return grid
+}
+
+// quarterTurn rotates the corner indices of a square grid by 90 degrees,
+// keeping the handedness.
+func quarterTurn(g [][][2]float64) [][][2]float64 {
+	n := len(g)
+	out := make([][][2]float64, n)
+	for r := range out {
+		out[r] = make([][2]float64, n)
+		for c := range out[r] {
+			out[r][c] = g[n-1-c][r]
+		}
+	}
+	return out
+}
+
+// evenSquaresBrighter reports whether the squares between the corners
+// grid[r][c] and grid[r+1][c+1] with even r + c are brighter than the odd
+// ones. The square outside the first corner has the colour of the even
+// squares.
+func evenSquaresBrighter(smooth gray.Image, grid [][][2]float64) bool {
+	var score float64
+	for r := range len(grid) - 1 {
+		for c := range len(grid[r]) - 1 {
+			a, b := grid[r][c], grid[r+1][c+1]
+			v := smooth.Sample((a[0]+b[0])/2, (a[1]+b[1])/2)
+			if (r+c)%2 == 1 {
+				v = -v
+			}
+			score += v
+		}
+	}
+	return score > 0
+}
@@ -0,0 +1,248 @@
+package calib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"gopkg.in/yaml.v3"
+)
+
+// MonoFile is the calibration file of cmd/calib_mono.
+type MonoFile struct {
+	CameraMatrix      [][]float64 `json:"camera_matrix" yaml:"camera_matrix"`
+	DistortionCoeffs  []float64   `json:"distortion_coefficients" yaml:"distortion_coefficients"`
+	ImageSize         [2]int      `json:"image_size" yaml:"image_size"`
+	NumSamples        int         `json:"num_samples" yaml:"num_samples"`
+	GridShape         [2]int      `json:"grid_shape" yaml:"grid_shape"`
+	ReprojectionError float64     `json:"reprojection_error" yaml:"reprojection_error"`
+	// DistortionModel is "fisheye" for the fisheye model; files without it
+	// use the radial-tangential model.
+	DistortionModel string `json:"distortion_model,omitempty" yaml:"distortion_model,omitempty"`
+}
+
+// StereoFile is the calibration file of cmd/calib_stereo.
+type StereoFile struct {
+	LeftCameraMatrix      [][]float64 `json:"left_camera_matrix" yaml:"left_camera_matrix"`
+	LeftDistortionCoeffs  []float64   `json:"left_distortion_coefficients" yaml:"left_distortion_coefficients"`
+	RightCameraMatrix     [][]float64 `json:"right_camera_matrix" yaml:"right_camera_matrix"`
+	RightDistortionCoeffs []float64   `json:"right_distortion_coefficients" yaml:"right_distortion_coefficients"`
+	Rotation              [][]float64 `json:"rotation" yaml:"rotation"`
+	Translation           []float64   `json:"translation" yaml:"translation"`
+	Essential             [][]float64 `json:"essential" yaml:"essential"`
+	Fundamental           [][]float64 `json:"fundamental" yaml:"fundamental"`
+	LeftRectification     [][]float64 `json:"left_rectification" yaml:"left_rectification"`
+	RightRectification    [][]float64 `json:"right_rectification" yaml:"right_rectification"`
+	LeftProjection        [][]float64 `json:"left_projection" yaml:"left_projection"`
+	RightProjection       [][]float64 `json:"right_projection" yaml:"right_projection"`
+	DisparityToDepthMap   [][]float64 `json:"disparity_to_depth_map" yaml:"disparity_to_depth_map"`
+	ImageSize             [2]int      `json:"image_size" yaml:"image_size"`
+	NumSamples            int         `json:"num_samples" yaml:"num_samples"`
+	GridShape             [2]int      `json:"grid_shape" yaml:"grid_shape"`
+	ReprojectionError     float64     `json:"reprojection_error" yaml:"reprojection_error"`
+	// DistortionModel applies to both cameras, as in MonoFile.
+	DistortionModel string `json:"distortion_model,omitempty" yaml:"distortion_model,omitempty"`
+}
+
+// File returns the calibration file of a result for a board with the given
+// grid of corners.
+func (r *Result) File(cols, rows int) *MonoFile {
+	c := &r.Camera
+	return &MonoFile{
+		CameraMatrix:      cameraMatrix(c),
+		DistortionCoeffs:  append([]float64(nil), c.Distortion...),
+		ImageSize:         [2]int{c.Width, c.Height},
+		NumSamples:        len(r.Rotations),
+		GridShape:         [2]int{cols, rows},
+		ReprojectionError: r.Errors.RMS,
+		DistortionModel:   modelName(c.Model),
+	}
+}
+
+// Camera returns the calibrated camera of the file.
+func (f *MonoFile) Camera() (*Camera, error) {
+	return fileCamera(f.CameraMatrix, f.DistortionCoeffs, f.ImageSize, f.DistortionModel)
+}
+
+// File returns the calibration file of a stereo pair for a board with the
+// given grid of corners, including its rectification.
+func (s *Stereo) File(cols, rows int) *StereoFile {
+	r := s.Rectify()
+	return &StereoFile{
+		LeftCameraMatrix:      cameraMatrix(&s.Left),
+		LeftDistortionCoeffs:  append([]float64(nil), s.Left.Distortion...),
+		RightCameraMatrix:     cameraMatrix(&s.Right),
+		RightDistortionCoeffs: append([]float64(nil), s.Right.Distortion...),
+		Rotation:              rows3(s.R),
+		Translation:           []float64{float64(s.T[0]), float64(s.T[1]), float64(s.T[2])},
+		Essential:             rows3(s.E),
+		Fundamental:           rows3(s.F),
+		LeftRectification:     rows3(r.R1),
+		RightRectification:    rows3(r.R2),
+		LeftProjection:        rowsOf(r.P1[0][:], r.P1[1][:], r.P1[2][:]),
+		RightProjection:       rowsOf(r.P2[0][:], r.P2[1][:], r.P2[2][:]),
+		DisparityToDepthMap:   rowsOf(r.Q[0][:], r.Q[1][:], r.Q[2][:], r.Q[3][:]),
+		ImageSize:             [2]int{s.Left.Width, s.Left.Height},
+		NumSamples:            len(s.Errors.PerView),
+		GridShape:             [2]int{cols, rows},
+		ReprojectionError:     s.Errors.RMS,
+		DistortionModel:       modelName(s.Left.Model),
+	}
+}
+
+// Stereo returns the calibrated stereo pair of the file.
+func (f *StereoFile) Stereo() (*Stereo, error) {
+	left, err := fileCamera(f.LeftCameraMatrix, f.LeftDistortionCoeffs, f.ImageSize, f.DistortionModel)
+	if err != nil {
+		return nil, fmt.Errorf("left camera: %w", err)
+	}
+	right, err := fileCamera(f.RightCameraMatrix, f.RightDistortionCoeffs, f.ImageSize, f.DistortionModel)
+	if err != nil {
+		return nil, fmt.Errorf("right camera: %w", err)
+	}
+	s := &Stereo{Left: *left, Right: *right, Errors: Errors{RMS: f.ReprojectionError}}
+	if s.R, err = matrix3(f.Rotation); err != nil {
+		return nil, fmt.Errorf("rotation: %w", err)
+	}
+	if len(f.Translation) != 3 {
+		return nil, fmt.Errorf("calib: translation has %d elements, want 3", len(f.Translation))
+	}
+	s.T = vec.Vector3D{float32(f.Translation[0]), float32(f.Translation[1]), float32(f.Translation[2])}
+	if s.E, err = matrix3(f.Essential); err != nil {
+		return nil, fmt.Errorf("essential: %w", err)
+	}
+	if s.F, err = matrix3(f.Fundamental); err != nil {
+		return nil, fmt.Errorf("fundamental: %w", err)
+	}
+	return s, nil
+}
+
+// Rectification returns the rectification stored in the file, or computes
+// it from the stereo pair when the file has none.
+func (f *StereoFile) Rectification() (*Rectification, error) {
+	if len(f.LeftRectification) == 0 {
+		s, err := f.Stereo()
+		if err != nil {
+			return nil, err
+		}
+		return s.Rectify(), nil
+	}
+	r := &Rectification{}
+	for _, m := range []struct {
+		name string
+		rows [][]float64
+		dst  [][]float32
+	}{
+		{"left rectification", f.LeftRectification, [][]float32{r.R1[0][:], r.R1[1][:], r.R1[2][:]}},
+		{"right rectification", f.RightRectification, [][]float32{r.R2[0][:], r.R2[1][:], r.R2[2][:]}},
+		{"left projection", f.LeftProjection, [][]float32{r.P1[0][:], r.P1[1][:], r.P1[2][:]}},
+		{"right projection", f.RightProjection, [][]float32{r.P2[0][:], r.P2[1][:], r.P2[2][:]}},
+		{"disparity to depth map", f.DisparityToDepthMap, [][]float32{r.Q[0][:], r.Q[1][:], r.Q[2][:], r.Q[3][:]}},
+	} {
+		if err := parseRows(m.rows, m.dst); err != nil {
+			return nil, fmt.Errorf("%s: %w", m.name, err)
+		}
+	}
+	return r, nil
+}
+
+// Save writes a calibration file as YAML for .yaml and .yml paths and as
+// indented JSON, like the calibration tools, otherwise.
+func Save(path string, v any) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(v)
+	default:
+		data, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("calib: failed to marshal calibration: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Load reads a calibration file in YAML or JSON into v.
+func Load(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("calib: failed to read calibration file: %w", err)
+	}
+	// YAML is a superset of the JSON the tools write
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("calib: failed to unmarshal calibration: %w", err)
+	}
+	return nil
+}
+
+func modelName(m Model) string {
+	if m == Fisheye {
+		return m.String()
+	}
+	return ""
+}
+
+func cameraMatrix(c *Camera) [][]float64 {
+	return [][]float64{{c.Fx, 0, c.Cx}, {0, c.Fy, c.Cy}, {0, 0, 1}}
+}
+
+func fileCamera(k [][]float64, dist []float64, size [2]int, model string) (*Camera, error) {
+	if len(k) != 3 || len(k[0]) != 3 || len(k[1]) != 3 {
+		return nil, fmt.Errorf("calib: camera matrix must be 3x3")
+	}
+	c := &Camera{
+		Width: size[0], Height: size[1],
+		Fx: k[0][0], Fy: k[1][1], Cx: k[0][2], Cy: k[1][2],
+		Distortion: append([]float64(nil), dist...),
+	}
+	switch model {
+	case "", RadialTangential.String():
+	case Fisheye.String():
+		c.Model = Fisheye
+	default:
+		return nil, fmt.Errorf("calib: unknown distortion model %q", model)
+	}
+	return c, nil
+}
+
+func rows3(m mat.Matrix3x3) [][]float64 {
+	return rowsOf(m[0][:], m[1][:], m[2][:])
+}
+
+func rowsOf(rows ...[]float32) [][]float64 {
+	out := make([][]float64, len(rows))
+	for i, r := range rows {
+		out[i] = make([]float64, len(r))
+		for j, v := range r {
+			out[i][j] = float64(v)
+		}
+	}
+	return out
+}
+
+func matrix3(rows [][]float64) (mat.Matrix3x3, error) {
+	var m mat.Matrix3x3
+	err := parseRows(rows, [][]float32{m[0][:], m[1][:], m[2][:]})
+	return m, err
+}
+
+// parseRows copies a matrix of the file into the rows of dst.
+func parseRows(rows [][]float64, dst [][]float32) error {
+	if len(rows) != len(dst) {
+		return fmt.Errorf("calib: matrix must be %dx%d", len(dst), len(dst[0]))
+	}
+	for i, r := range rows {
+		if len(r) != len(dst[i]) {
+			return fmt.Errorf("calib: matrix must be %dx%d", len(dst), len(dst[0]))
+		}
+		for j, v := range r {
+			dst[i][j] = float32(v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package calib
+
+import (
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// blur returns g smoothed by a Gaussian.
+func blur(g gray.Image, sigma float32) (gray.Image, error) {
+	out, err := imgproc.GaussianBlur(tensor.FromArray(tensor.NewShape(g.H, g.W), g.Pix), imgproc.HWC, 0, sigma)
+	if err != nil {
+		return gray.Image{}, err
+	}
+	return gray.FromTensor(out)
+}
@@ -0,0 +1,214 @@
+package calib
+
+import (
+	"math"
+)
+
+// problem is a nonlinear least squares problem over parameters shared by
+// all views and parameters local to one view. The residuals of a view
+// depend only on the shared parameters and its own local ones, which keeps
+// the numeric Jacobian cheap.
+type problem struct {
+	shared []float64
+	local  [][]float64
+	// fixed marks shared parameters that are not optimised.
+	fixed []bool
+	// counts is the number of residuals of every view.
+	counts []int
+	// residuals writes the residuals of view i to out.
+	residuals func(shared, local []float64, i int, out []float64)
+}
+
+// cost returns the sum of squared residuals.
+func (p *problem) cost(shared []float64, local [][]float64) float64 {
+	var sum float64
+	for i := range p.local {
+		r := make([]float64, p.counts[i])
+		p.residuals(shared, local[i], i, r)
+		for _, v := range r {
+			sum += v * v
+		}
+	}
+	return sum
+}
+
+// derivStep is the relative step of the central difference Jacobian.
+const derivStep = 1e-6
+
+// jacobian returns the central difference derivatives of the residuals of
+// view i with respect to the shared and its local parameters, column by
+// column.
+func (p *problem) jacobian(i int) (shared, local [][]float64) {
+	n := p.counts[i]
+	plus, minus := make([]float64, n), make([]float64, n)
+	column := func(x []float64, j int) []float64 {
+		v := x[j]
+		h := derivStep * math.Max(math.Abs(v), 1)
+		x[j] = v + h
+		p.residuals(p.shared, p.local[i], i, plus)
+		x[j] = v - h
+		p.residuals(p.shared, p.local[i], i, minus)
+		x[j] = v
+		col := make([]float64, n)
+		for k := range col {
+			col[k] = (plus[k] - minus[k]) / (2 * h)
+		}
+		return col
+	}
+	shared = make([][]float64, len(p.shared))
+	for j := range p.shared {
+		if p.fixed == nil || !p.fixed[j] {
+			shared[j] = column(p.shared, j)
+		}
+	}
+	local = make([][]float64, len(p.local[i]))
+	for j := range p.local[i] {
+		local[j] = column(p.local[i], j)
+	}
+	return shared, local
+}
+
+// solve minimises the cost with Levenberg-Marquardt and returns the final
+// sum of squared residuals.
+func (p *problem) solve(maxIterations int) (float64, error) {
+	ns := len(p.shared)
+	offsets := make([]int, len(p.local)+1)
+	offsets[0] = ns
+	for i, l := range p.local {
+		offsets[i+1] = offsets[i] + len(l)
+	}
+	n := offsets[len(p.local)]
+
+	cost := p.cost(p.shared, p.local)
+	if math.IsNaN(cost) || math.IsInf(cost, 0) {
+		return cost, ErrNotConverged
+	}
+	lambda := -1.0
+	for range maxIterations {
+		// normal equations JtJ dx = -Jt r, accumulated view by view
+		jtj := make([][]float64, n)
+		for i := range jtj {
+			jtj[i] = make([]float64, n)
+		}
+		jtr := make([]float64, n)
+		for i := range p.local {
+			js, jl := p.jacobian(i)
+			r := make([]float64, p.counts[i])
+			p.residuals(p.shared, p.local[i], i, r)
+			cols := make([][]float64, n)
+			idx := make([]int, 0, ns+len(jl))
+			for j, c := range js {
+				if c != nil {
+					cols[j] = c
+					idx = append(idx, j)
+				}
+			}
+			for j, c := range jl {
+				cols[offsets[i]+j] = c
+				idx = append(idx, offsets[i]+j)
+			}
+			for a, ja := range idx {
+				for _, jb := range idx[a:] {
+					jtj[ja][jb] += dot(cols[ja], cols[jb])
+				}
+				jtr[ja] += dot(cols[ja], r)
+			}
+		}
+		for i := range n {
+			for j := range i {
+				jtj[i][j] = jtj[j][i]
+			}
+		}
+		if lambda < 0 {
+			var maxDiag float64
+			for i := range n {
+				maxDiag = math.Max(maxDiag, jtj[i][i])
+			}
+			lambda = 1e-3 * maxDiag
+		}
+
+		improved := false
+		for range 10 {
+			a := make([][]float64, n)
+			b := make([]float64, n)
+			for i := range n {
+				a[i] = append([]float64(nil), jtj[i]...)
+				a[i][i] += lambda * math.Max(jtj[i][i], 1e-12)
+				b[i] = -jtr[i]
+			}
+			dx, ok := choleskySolve(a, b)
+			if !ok {
+				lambda *= 10
+				continue
+			}
+			shared := append([]float64(nil), p.shared...)
+			for j := range ns {
+				shared[j] += dx[j]
+			}
+			local := make([][]float64, len(p.local))
+			for i, l := range p.local {
+				local[i] = append([]float64(nil), l...)
+				for j := range l {
+					local[i][j] += dx[offsets[i]+j]
+				}
+			}
+			next := p.cost(shared, local)
+			if next < cost {
+				p.shared, p.local = shared, local
+				done := cost-next < 1e-12*cost
+				cost = next
+				lambda = math.Max(lambda/10, 1e-12)
+				improved = !done
+				break
+			}
+			lambda *= 10
+		}
+		if !improved {
+			break
+		}
+	}
+	return cost, nil
+}
+
+func dot(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+// choleskySolve solves a x = b for a symmetric positive definite a in place.
+func choleskySolve(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	for i := range n {
+		for j := 0; j <= i; j++ {
+			s := a[i][j]
+			for k := range j {
+				s -= a[i][k] * a[j][k]
+			}
+			if i == j {
+				if s <= 0 {
+					return nil, false
+				}
+				a[i][i] = math.Sqrt(s)
+			} else {
+				a[i][j] = s / a[j][j]
+			}
+		}
+	}
+	x := append([]float64(nil), b...)
+	for i := range n {
+		for k := range i {
+			x[i] -= a[i][k] * x[k]
+		}
+		x[i] /= a[i][i]
+	}
+	for i := n - 1; i >= 0; i-- {
+		for k := i + 1; k < n; k++ {
+			x[i] -= a[k][i] * x[k]
+		}
+		x[i] /= a[i][i]
+	}
+	return x, true
+}
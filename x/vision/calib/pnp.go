@@ -0,0 +1,45 @@
+package calib
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/geometry"
+)
+
+// SolvePnP estimates the pose of an object whose points lie in its Z = 0
+// plane, such as a board or a fiducial tag, from their images: the rotation
+// vector and translation map object coordinates to camera coordinates. The
+// pose from the homography of the normalized image points is refined by
+// Levenberg-Marquardt on the reprojection errors.
+func (c *Camera) SolvePnP(objectPoints []vec.Vector3D, imagePoints []vec.Vector2D) (r, t vec.Vector3D, err error) {
+	if len(objectPoints) != len(imagePoints) {
+		return r, t, fmt.Errorf("calib: %d object points for %d image points", len(objectPoints), len(imagePoints))
+	}
+	if len(objectPoints) < 4 {
+		return r, t, geometry.ErrTooFewPoints
+	}
+	for _, p := range objectPoints {
+		if p[2] != 0 {
+			return r, t, ErrNotPlanar
+		}
+	}
+	r0, t0, err := c.boardPose(objectPoints, imagePoints)
+	if err != nil {
+		return r, t, err
+	}
+	p := &problem{
+		local:  [][]float64{append(r0[:], t0[:]...)},
+		counts: []int{2 * len(objectPoints)},
+		residuals: func(_, local []float64, _ int, out []float64) {
+			c.residuals(local, objectPoints, imagePoints, out)
+		},
+	}
+	if _, err := p.solve(50); err != nil {
+		return r, t, err
+	}
+	pose := p.local[0]
+	r = vec.Vector3D{float32(pose[0]), float32(pose[1]), float32(pose[2])}
+	t = vec.Vector3D{float32(pose[3]), float32(pose[4]), float32(pose[5])}
+	return r, t, nil
+}
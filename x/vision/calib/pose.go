@@ -0,0 +1,184 @@
+package calib
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// rot is a rotation matrix in float64.
+type rot [3][3]float64
+
+// Rodrigues converts a rotation vector (axis times angle in radians) to a
+// rotation matrix.
+func Rodrigues(r vec.Vector3D) mat.Matrix3x3 {
+	m := rodrigues([3]float64{float64(r[0]), float64(r[1]), float64(r[2])})
+	var out mat.Matrix3x3
+	for i := range 3 {
+		for j := range 3 {
+			out[i][j] = float32(m[i][j])
+		}
+	}
+	return out
+}
+
+// RotationVector converts a rotation matrix to a rotation vector, the
+// inverse of Rodrigues.
+func RotationVector(m mat.Matrix3x3) vec.Vector3D {
+	var r rot
+	for i := range 3 {
+		for j := range 3 {
+			r[i][j] = float64(m[i][j])
+		}
+	}
+	v := r.vector()
+	return vec.Vector3D{float32(v[0]), float32(v[1]), float32(v[2])}
+}
+
+func rodrigues(r [3]float64) rot {
+	theta := math.Sqrt(r[0]*r[0] + r[1]*r[1] + r[2]*r[2])
+	if theta < 1e-12 {
+		// first order: I + [r]x
+		return rot{{1, -r[2], r[1]}, {r[2], 1, -r[0]}, {-r[1], r[0], 1}}
+	}
+	x, y, z := r[0]/theta, r[1]/theta, r[2]/theta
+	c, s := math.Cos(theta), math.Sin(theta)
+	t := 1 - c
+	return rot{
+		{c + x*x*t, x*y*t - z*s, x*z*t + y*s},
+		{y*x*t + z*s, c + y*y*t, y*z*t - x*s},
+		{z*x*t - y*s, z*y*t + x*s, c + z*z*t},
+	}
+}
+
+// vector returns the rotation vector of r.
+func (r rot) vector() [3]float64 {
+	c := (r[0][0] + r[1][1] + r[2][2] - 1) / 2
+	c = math.Max(-1, math.Min(1, c))
+	theta := math.Acos(c)
+	axis := [3]float64{r[2][1] - r[1][2], r[0][2] - r[2][0], r[1][0] - r[0][1]}
+	s := math.Sin(theta)
+	if s > 1e-6 {
+		k := theta / (2 * s)
+		return [3]float64{axis[0] * k, axis[1] * k, axis[2] * k}
+	}
+	if theta < 1e-6 {
+		return [3]float64{axis[0] / 2, axis[1] / 2, axis[2] / 2}
+	}
+	// theta near pi: the axis is the column of R + I with the largest norm
+	best, norm := 0, -1.0
+	for j := range 3 {
+		var n float64
+		for i := range 3 {
+			v := r[i][j]
+			if i == j {
+				v++
+			}
+			n += v * v
+		}
+		if n > norm {
+			best, norm = j, n
+		}
+	}
+	var out [3]float64
+	norm = math.Sqrt(norm)
+	for i := range 3 {
+		v := r[i][best]
+		if i == best {
+			v++
+		}
+		out[i] = v / norm * theta
+	}
+	return out
+}
+
+func (r rot) mul(b rot) rot {
+	var out rot
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += r[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func (r rot) t() rot {
+	var out rot
+	for i := range 3 {
+		for j := range 3 {
+			out[i][j] = r[j][i]
+		}
+	}
+	return out
+}
+
+func (r rot) apply(p [3]float64) [3]float64 {
+	return [3]float64{
+		r[0][0]*p[0] + r[0][1]*p[1] + r[0][2]*p[2],
+		r[1][0]*p[0] + r[1][1]*p[1] + r[1][2]*p[2],
+		r[2][0]*p[0] + r[2][1]*p[1] + r[2][2]*p[2],
+	}
+}
+
+// orthonormalize returns the rotation closest to a nearly orthogonal matrix
+// by the polar decomposition iteration R = (R + R^-T) / 2.
+func (r rot) orthonormalize() rot {
+	for range 20 {
+		inv, ok := r.inverse()
+		if !ok {
+			return r
+		}
+		var next rot
+		var change float64
+		for i := range 3 {
+			for j := range 3 {
+				next[i][j] = (r[i][j] + inv[j][i]) / 2
+				change += math.Abs(next[i][j] - r[i][j])
+			}
+		}
+		r = next
+		if change < 1e-14 {
+			break
+		}
+	}
+	return r
+}
+
+func (r rot) inverse() (rot, bool) {
+	det := r[0][0]*(r[1][1]*r[2][2]-r[1][2]*r[2][1]) -
+		r[0][1]*(r[1][0]*r[2][2]-r[1][2]*r[2][0]) +
+		r[0][2]*(r[1][0]*r[2][1]-r[1][1]*r[2][0])
+	if det == 0 {
+		return rot{}, false
+	}
+	var out rot
+	for i := range 3 {
+		for j := range 3 {
+			a, b := (j+1)%3, (j+2)%3
+			c, d := (i+1)%3, (i+2)%3
+			out[i][j] = (r[a][c]*r[b][d] - r[a][d]*r[b][c]) / det
+		}
+	}
+	return out, true
+}
+
+func (r rot) toMat() mat.Matrix3x3 {
+	var out mat.Matrix3x3
+	for i := range 3 {
+		for j := range 3 {
+			out[i][j] = float32(r[i][j])
+		}
+	}
+	return out
+}
+
+func cross(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func norm(a [3]float64) float64 {
+	return math.Sqrt(a[0]*a[0] + a[1]*a[1] + a[2]*a[2])
+}
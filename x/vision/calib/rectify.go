@@ -0,0 +1,161 @@
+package calib
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+)
+
+// Rectification holds the transforms that make the epipolar lines of a
+// stereo pair horizontal (or vertical for a vertical baseline), as
+// cv::stereoRectify returns them. R1 and R2 rotate the left and right camera
+// frames into the common rectified frame, P1 and P2 project rectified
+// points, and Q maps (x, y, disparity, 1) to homogeneous 3-D points in the
+// rectified left camera frame.
+type Rectification struct {
+	R1, R2 mat.Matrix3x3
+	P1, P2 mat.Matrix3x4
+	Q      mat.Matrix4x4
+}
+
+// Rectify computes the rectification of the pair with Bouguet's method: each
+// camera is rotated half way towards the other and then both are aligned
+// with the baseline. Both rectified cameras share the focal length and the
+// principal point (zero disparity at infinity), chosen so that the image
+// corners stay in view.
+func (s *Stereo) Rectify() *Rectification {
+	om := RotationVector(s.R)
+	rr := rodrigues([3]float64{-0.5 * float64(om[0]), -0.5 * float64(om[1]), -0.5 * float64(om[2])})
+	t := rr.apply([3]float64{float64(s.T[0]), float64(s.T[1]), float64(s.T[2])})
+
+	// rotate the baseline onto the x (or y) axis
+	idx := 0
+	if math.Abs(t[1]) > math.Abs(t[0]) {
+		idx = 1
+	}
+	var axis [3]float64
+	axis[idx] = 1
+	if t[idx] < 0 {
+		axis[idx] = -1
+	}
+	w := cross(t, axis)
+	if nw := norm(w); nw > 0 {
+		angle := math.Acos(math.Abs(t[idx]) / norm(t))
+		for k := range w {
+			w[k] *= angle / nw
+		}
+	}
+	wr := rodrigues(w)
+	r1, r2 := wr.mul(rr.t()), wr.mul(rr)
+	t = r2.apply([3]float64{float64(s.T[0]), float64(s.T[1]), float64(s.T[2])})
+
+	// the smaller focal length across the baseline, shrunk by barrel
+	// distortion at the corners, keeps the detail of both images
+	f := math.Inf(1)
+	for _, c := range []*Camera{&s.Left, &s.Right} {
+		fc := c.Fy
+		if idx == 1 {
+			fc = c.Fx
+		}
+		if k1 := c.coeff(0); c.Model == RadialTangential && k1 < 0 {
+			w, h := float64(c.Width), float64(c.Height)
+			fc *= 1 + k1*(w*w+h*h)/(4*fc*fc)
+		}
+		f = math.Min(f, fc)
+	}
+
+	// centre the rectified image corners, then share the principal point
+	var cx, cy float64
+	for i, c := range []*Camera{&s.Left, &s.Right} {
+		r := r1
+		if i == 1 {
+			r = r2
+		}
+		w, h := float64(c.Width-1), float64(c.Height-1)
+		var sx, sy float64
+		for _, p := range [4][2]float64{{0, 0}, {w, 0}, {0, h}, {w, h}} {
+			x, y := c.undistort((p[0]-c.Cx)/c.Fx, (p[1]-c.Cy)/c.Fy)
+			q := r.apply([3]float64{x, y, 1})
+			sx += f * q[0] / q[2]
+			sy += f * q[1] / q[2]
+		}
+		cx += w/2 - sx/4
+		cy += h/2 - sy/4
+	}
+	cx, cy = cx/2, cy/2
+
+	rect := &Rectification{R1: r1.toMat(), R2: r2.toMat()}
+	p := mat.Matrix3x4{{float32(f), 0, float32(cx), 0}, {0, float32(f), float32(cy), 0}, {0, 0, 1, 0}}
+	rect.P1, rect.P2 = p, p
+	rect.P2[idx][3] = float32(t[idx] * f)
+	rect.Q = mat.Matrix4x4{
+		{1, 0, 0, float32(-cx)},
+		{0, 1, 0, float32(-cy)},
+		{0, 0, 0, float32(f)},
+		{0, 0, float32(-1 / t[idx]), 0},
+	}
+	return rect
+}
+
+// Maps returns the remap tables of the left and right images of the pair.
+func (r *Rectification) Maps(s *Stereo) (left, right *imgproc.Map) {
+	return s.Left.UndistortRectifyMap(r.R1, camera(r.P1)), s.Right.UndistortRectifyMap(r.R2, camera(r.P2))
+}
+
+// UndistortMap returns the remap table that removes the lens distortion of
+// the camera while keeping its camera matrix, as cv::undistort does.
+func (c *Camera) UndistortMap() *imgproc.Map {
+	return c.UndistortRectifyMap(mat.Matrix3x3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}, c.Matrix())
+}
+
+// UndistortRectifyMap returns the remap table of an image of the camera
+// rotated by r and projected with the camera matrix k, as
+// cv::initUndistortRectifyMap computes it. The table has the size of the
+// camera image.
+func (c *Camera) UndistortRectifyMap(r mat.Matrix3x3, k mat.Matrix3x3) *imgproc.Map {
+	m := &imgproc.Map{Width: c.Width, Height: c.Height, X: make([]float32, c.Width*c.Height), Y: make([]float32, c.Width*c.Height)}
+	// undo the rectifying rotation: camera ray = R^T K^-1 pixel
+	var kr rot
+	for i := range 3 {
+		for j := range 3 {
+			kr[i][j] = float64(k[i][j])
+		}
+	}
+	kInv, ok := kr.inverse()
+	if !ok {
+		for i := range m.X {
+			m.X[i], m.Y[i] = float32(math.NaN()), float32(math.NaN())
+		}
+		return m
+	}
+	var rr rot
+	for i := range 3 {
+		for j := range 3 {
+			rr[i][j] = float64(r[i][j])
+		}
+	}
+	back := rr.t().mul(kInv)
+	for v := range c.Height {
+		for u := range c.Width {
+			i := v*c.Width + u
+			q := back.apply([3]float64{float64(u), float64(v), 1})
+			if q[2] <= 0 {
+				m.X[i], m.Y[i] = float32(math.NaN()), float32(math.NaN())
+				continue
+			}
+			x, y := c.project(q)
+			m.X[i], m.Y[i] = float32(x), float32(y)
+		}
+	}
+	return m
+}
+
+// camera returns the camera matrix of a projection matrix.
+func camera(p mat.Matrix3x4) mat.Matrix3x3 {
+	var k mat.Matrix3x3
+	for i := range 3 {
+		copy(k[i][:], p[i][:3])
+	}
+	return k
+}
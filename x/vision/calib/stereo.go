@@ -0,0 +1,151 @@
+package calib
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// Stereo is a calibrated camera pair. R and T map left camera coordinates
+// to right camera coordinates: Xr = R Xl + T.
+type Stereo struct {
+	Left, Right Camera
+	R           mat.Matrix3x3
+	T           vec.Vector3D
+	// E and F are the essential and fundamental matrices with
+	// xr^T F xl = 0 for corresponding pixels.
+	E, F mat.Matrix3x3
+	// Errors are the reprojection errors of both cameras; PerView holds
+	// one value per image pair.
+	Errors Errors
+}
+
+// StereoCalibrate estimates the pose of the right camera relative to the
+// left one from simultaneous views of a planar target. The intrinsics of
+// both cameras are kept fixed, so calibrate them with Calibrate first. The
+// relative pose is initialised with the median of the per view estimates
+// and refined with the left board poses by Levenberg-Marquardt.
+func StereoCalibrate(objectPoints [][]vec.Vector3D, left, right [][]vec.Vector2D, leftCam, rightCam *Camera, opts ...Option) (*Stereo, error) {
+	cfg := newConfig(opts)
+	if err := checkViews(objectPoints, left); err != nil {
+		return nil, err
+	}
+	if err := checkViews(objectPoints, right); err != nil {
+		return nil, err
+	}
+
+	n := len(objectPoints)
+	poses := make([][]float64, n)
+	var om, t [3][]float64
+	for i := range n {
+		rl, tl, err := leftCam.boardPose(objectPoints[i], left[i])
+		if err != nil {
+			return nil, fmt.Errorf("calib: left view %d: %w", i, err)
+		}
+		rr, tr, err := rightCam.boardPose(objectPoints[i], right[i])
+		if err != nil {
+			return nil, fmt.Errorf("calib: right view %d: %w", i, err)
+		}
+		poses[i] = append(rl[:], tl[:]...)
+		r := rodrigues(rr).mul(rodrigues(rl).t())
+		v, rtl := r.vector(), r.apply(tl)
+		for k := range 3 {
+			om[k] = append(om[k], v[k])
+			t[k] = append(t[k], tr[k]-rtl[k])
+		}
+	}
+	shared := make([]float64, 6)
+	for k := range 3 {
+		shared[k], shared[3+k] = median(om[k]), median(t[k])
+	}
+
+	p := &problem{
+		shared: shared,
+		local:  poses,
+		counts: make([]int, n),
+		residuals: func(shared, local []float64, i int, out []float64) {
+			m := len(objectPoints[i])
+			leftCam.residuals(local, objectPoints[i], left[i], out[:2*m])
+			rightCam.residuals(composePose(shared, local), objectPoints[i], right[i], out[2*m:])
+		},
+	}
+	for i := range n {
+		p.counts[i] = 4 * len(objectPoints[i])
+	}
+	if _, err := p.solve(cfg.maxIterations); err != nil {
+		return nil, err
+	}
+
+	s := &Stereo{Left: *leftCam, Right: *rightCam}
+	r := rodrigues([3]float64{p.shared[0], p.shared[1], p.shared[2]})
+	s.R = r.toMat()
+	s.T = vec.Vector3D{float32(p.shared[3]), float32(p.shared[4]), float32(p.shared[5])}
+	s.E, s.F = epipolarMatrices(r, [3]float64{p.shared[3], p.shared[4], p.shared[5]}, leftCam, rightCam)
+
+	var sum float64
+	count := 0
+	for i := range n {
+		res := make([]float64, p.counts[i])
+		p.residuals(p.shared, p.local[i], i, res)
+		var view float64
+		for j := 0; j < len(res); j += 2 {
+			d2 := res[j]*res[j] + res[j+1]*res[j+1]
+			view += d2
+			d := math.Sqrt(d2)
+			s.Errors.Mean += d
+			s.Errors.Max = math.Max(s.Errors.Max, d)
+		}
+		sum += view
+		count += len(res) / 2
+		s.Errors.PerView = append(s.Errors.PerView, math.Sqrt(view/float64(len(res)/2)))
+	}
+	s.Errors.RMS = math.Sqrt(sum / float64(count))
+	s.Errors.Mean /= float64(count)
+	if math.IsNaN(s.Errors.RMS) {
+		return nil, ErrNotConverged
+	}
+	return s, nil
+}
+
+// composePose returns the pose of the board in the right camera from the
+// relative pose (rotation vector, translation) and the left board pose.
+func composePose(relative, left []float64) []float64 {
+	r := rodrigues([3]float64{relative[0], relative[1], relative[2]})
+	rl := rodrigues([3]float64{left[0], left[1], left[2]})
+	v := r.mul(rl).vector()
+	t := r.apply([3]float64{left[3], left[4], left[5]})
+	return []float64{v[0], v[1], v[2], t[0] + relative[3], t[1] + relative[4], t[2] + relative[5]}
+}
+
+// epipolarMatrices returns E = [T]x R and F = Kr^-T E Kl^-1, F scaled to a
+// unit last element.
+func epipolarMatrices(r rot, t [3]float64, leftCam, rightCam *Camera) (mat.Matrix3x3, mat.Matrix3x3) {
+	tx := rot{{0, -t[2], t[1]}, {t[2], 0, -t[0]}, {-t[1], t[0], 0}}
+	e := tx.mul(r)
+	kl := rot{{leftCam.Fx, 0, leftCam.Cx}, {0, leftCam.Fy, leftCam.Cy}, {0, 0, 1}}
+	kr := rot{{rightCam.Fx, 0, rightCam.Cx}, {0, rightCam.Fy, rightCam.Cy}, {0, 0, 1}}
+	klInv, _ := kl.inverse()
+	krInv, _ := kr.inverse()
+	f := krInv.t().mul(e).mul(klInv)
+	if f[2][2] != 0 {
+		s := f[2][2]
+		for i := range 3 {
+			for j := range 3 {
+				f[i][j] /= s
+			}
+		}
+	}
+	return e.toMat(), f.toMat()
+}
+
+func median(v []float64) float64 {
+	s := slices.Clone(v)
+	slices.Sort(s)
+	if len(s)%2 == 1 {
+		return s[len(s)/2]
+	}
+	return (s[len(s)/2-1] + s[len(s)/2]) / 2
+}
@@ -0,0 +1,75 @@
+package calib
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+const (
+	subPixIterations = 30
+	subPixEpsilon    = 0.001
+)
+
+// CornerSubPix refines corners to sub-pixel accuracy like cv::cornerSubPix:
+// the corner is the point that every image gradient in the
+// (2*win+1) x (2*win+1) window around it is orthogonal to the vector
+// towards it. Corners that drift out of their window keep their original
+// position.
+func CornerSubPix(img types.Tensor, corners []vec.Vector2D, win int) ([]vec.Vector2D, error) {
+	g, err := gray.FromTensor(img)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]vec.Vector2D, len(corners))
+	for i, c := range corners {
+		x, y := cornerSubPix(g, float64(c[0]), float64(c[1]), win)
+		out[i] = vec.Vector2D{float32(x), float32(y)}
+	}
+	return out, nil
+}
+
+func cornerSubPix(g gray.Image, x0, y0 float64, win int) (float64, float64) {
+	// Gaussian weights falling to exp(-1) at the window border
+	weight := make([]float64, 2*win+1)
+	for i := range weight {
+		d := float64(i-win) / float64(win)
+		weight[i] = math.Exp(-d * d)
+	}
+
+	x, y := x0, y0
+	for range subPixIterations {
+		var a, b, c, bx, by float64
+		for i := -win; i <= win; i++ {
+			for j := -win; j <= win; j++ {
+				px, py := x+float64(j), y+float64(i)
+				gx := (g.Sample(px+1, py) - g.Sample(px-1, py)) / 2
+				gy := (g.Sample(px, py+1) - g.Sample(px, py-1)) / 2
+				m := weight[i+win] * weight[j+win]
+				gxx, gxy, gyy := gx*gx*m, gx*gy*m, gy*gy*m
+				a += gxx
+				b += gxy
+				c += gyy
+				bx += gxx*px + gxy*py
+				by += gxy*px + gyy*py
+			}
+		}
+		det := a*c - b*b
+		if math.Abs(det) < 1e-12 {
+			break
+		}
+		nx := (c*bx - b*by) / det
+		ny := (a*by - b*bx) / det
+		step := math.Hypot(nx-x, ny-y)
+		x, y = nx, ny
+		if step < subPixEpsilon {
+			break
+		}
+	}
+	if math.Abs(x-x0) > float64(win) || math.Abs(y-y0) > float64(win) {
+		return x0, y0
+	}
+	return x, y
+}
@@ -0,0 +1,194 @@
+package detect
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// values returns the data of an output tensor and its shape without the
+// leading batch dimensions of size 1, keeping at least two dimensions.
+func values(t types.Tensor, name string) ([]float32, []int, error) {
+	if t == nil || tensor.IsNil(t) {
+		return nil, nil, fmt.Errorf("detect: nil %s", name)
+	}
+	if !t.IsContiguous() {
+		return nil, nil, fmt.Errorf("detect: %s views are not supported, copy the tensor first", name)
+	}
+	shape := []int(t.Shape())
+	for len(shape) > 2 && shape[0] == 1 {
+		shape = shape[1:]
+	}
+	if len(shape) != 2 {
+		return nil, nil, fmt.Errorf("detect: %s must be two dimensional after the batch, got %v", name, t.Shape())
+	}
+	switch data := t.Data().(type) {
+	case []float32:
+		return data, shape, nil
+	case []float64:
+		out := make([]float32, len(data))
+		for i, v := range data {
+			out[i] = float32(v)
+		}
+		return out, shape, nil
+	default:
+		return nil, nil, fmt.Errorf("detect: unsupported %s data type %v, want FP32 or FP64", name, t.DataType())
+	}
+}
+
+// DecodeSSD decodes the output of the DetectionOutput layer of Caffe and
+// OpenCV DNN SSD models, [1, 1, N, 7] rows of the image index, the class,
+// the score and the normalized left, top, right and bottom of a box. The
+// layer has already suppressed overlapping boxes and dropped the
+// background.
+func DecodeSSD(out types.Tensor, opts ...Option) ([]Detection, error) {
+	cfg := newConfig(opts)
+	data, shape, err := values(out, "output")
+	if err != nil {
+		return nil, err
+	}
+	if shape[1] != 7 {
+		return nil, fmt.Errorf("detect: SSD output rows must have 7 values, got %v", out.Shape())
+	}
+	var dets []Detection
+	for i := range shape[0] {
+		row := data[i*7 : i*7+7]
+		if row[0] < 0 || row[2] < cfg.threshold {
+			continue
+		}
+		b := Box{Min: vec.Vector2D{row[3], row[4]}, Max: vec.Vector2D{row[5], row[6]}}
+		dets = append(dets, Detection{Box: b.Scale(cfg.sx, cfg.sy), Class: int(row[1]), Score: row[2]})
+	}
+	return dets, nil
+}
+
+// DecodeSSDHead decodes the raw outputs of an SSD head: boxes [N, 4] are
+// regressions (dx, dy, dw, dh) against the anchors, scores [N, C] the class
+// scores of every anchor. A box is centered at the anchor center plus d
+// times the center variance times the anchor size, its size is the anchor
+// size times exp(d times the size variance). Every anchor yields at most
+// one detection, of its best class. The boxes are in the coordinates of
+// the anchors, usually normalized; suppress duplicates with NMS.
+func DecodeSSDHead(boxes, scores types.Tensor, anchors []Box, opts ...Option) ([]Detection, error) {
+	cfg := newConfig(opts)
+	loc, ls, err := values(boxes, "boxes")
+	if err != nil {
+		return nil, err
+	}
+	conf, cs, err := values(scores, "scores")
+	if err != nil {
+		return nil, err
+	}
+	if ls[1] != 4 || ls[0] != len(anchors) || cs[0] != len(anchors) {
+		return nil, fmt.Errorf("detect: %d anchors with boxes %v and scores %v", len(anchors), boxes.Shape(), scores.Shape())
+	}
+	classes := cs[1]
+	var dets []Detection
+	for i, a := range anchors {
+		best, class := float32(math.Inf(-1)), -1
+		for c, s := range conf[i*classes : (i+1)*classes] {
+			if c != cfg.background && s > best {
+				best, class = s, c
+			}
+		}
+		if cfg.sigmoid {
+			best = sigmoid(best)
+		}
+		if class < 0 || best < cfg.threshold {
+			continue
+		}
+		d := loc[i*4 : i*4+4]
+		dx, dy, dw, dh := d[0], d[1], d[2], d[3]
+		if cfg.yx {
+			dx, dy, dw, dh = d[1], d[0], d[3], d[2]
+		}
+		c := a.Center()
+		w, h := a.Width(), a.Height()
+		b := CenterBox(
+			c[0]+dx*cfg.center*w,
+			c[1]+dy*cfg.center*h,
+			w*float32(math.Exp(float64(dw*cfg.size))),
+			h*float32(math.Exp(float64(dh*cfg.size))),
+		)
+		dets = append(dets, Detection{Box: b.Scale(cfg.sx, cfg.sy), Class: class, Score: best})
+	}
+	return dets, nil
+}
+
+func sigmoid(x float32) float32 {
+	return float32(1 / (1 + math.Exp(-float64(x))))
+}
+
+// DecodeYOLOv5 decodes the output of a YOLOv5 model, [1, N, 5+C] rows of
+// the box center, width and height, the objectness and the C class scores,
+// all after the sigmoid as exported. The score of a detection is the
+// objectness times the best class score. Boxes are in pixels of the network
+// input, or normalized for TFLite exports; suppress duplicates with NMS.
+func DecodeYOLOv5(out types.Tensor, opts ...Option) ([]Detection, error) {
+	cfg := newConfig(opts)
+	data, shape, err := values(out, "output")
+	if err != nil {
+		return nil, err
+	}
+	n := shape[1]
+	if n < 6 {
+		return nil, fmt.Errorf("detect: YOLOv5 output rows must have at least 6 values, got %v", out.Shape())
+	}
+	var dets []Detection
+	for i := range shape[0] {
+		row := data[i*n : (i+1)*n]
+		if row[4] < cfg.threshold {
+			continue
+		}
+		class, score := argmax(row[5:], 1)
+		score *= row[4]
+		if score < cfg.threshold {
+			continue
+		}
+		b := CenterBox(row[0], row[1], row[2], row[3])
+		dets = append(dets, Detection{Box: b.Scale(cfg.sx, cfg.sy), Class: class, Score: score})
+	}
+	return dets, nil
+}
+
+// DecodeYOLOv8 decodes the output of an anchor free YOLOv8 or later
+// Ultralytics model, [1, 4+C, N]: the box centers, widths and heights and
+// the C class scores of N candidates, channels first. The score of a
+// detection is its best class score. Boxes are in pixels of the network
+// input, or normalized for TFLite exports; suppress duplicates with NMS.
+func DecodeYOLOv8(out types.Tensor, opts ...Option) ([]Detection, error) {
+	cfg := newConfig(opts)
+	data, shape, err := values(out, "output")
+	if err != nil {
+		return nil, err
+	}
+	channels, n := shape[0], shape[1]
+	if channels < 5 {
+		return nil, fmt.Errorf("detect: YOLOv8 output must have at least 5 channels, got %v", out.Shape())
+	}
+	var dets []Detection
+	for i := range n {
+		class, score := argmax(data[4*n+i:], n)
+		if score < cfg.threshold {
+			continue
+		}
+		b := CenterBox(data[i], data[n+i], data[2*n+i], data[3*n+i])
+		dets = append(dets, Detection{Box: b.Scale(cfg.sx, cfg.sy), Class: class, Score: score})
+	}
+	return dets, nil
+}
+
+// argmax returns the index and value of the largest of the values at the
+// given stride.
+func argmax(v []float32, stride int) (int, float32) {
+	best, at := float32(math.Inf(-1)), -1
+	for i, k := 0, 0; i < len(v); i, k = i+stride, k+1 {
+		if v[i] > best {
+			best, at = v[i], k
+		}
+	}
+	return at, best
+}
@@ -0,0 +1,131 @@
+// Package detect decodes the outputs of object detection networks into
+// boxes and suppresses duplicates. Decoders cover the common heads: the
+// DetectionOutput layer of Caffe and OpenCV DNN SSD models, raw SSD box
+// regressions against anchors, and YOLOv5 and YOLOv8 outputs as exported
+// by Ultralytics. NMS and SoftNMS remove overlapping boxes of a class.
+//
+// Outputs are FP32 or FP64 tensors, such as those of the dnn step or the
+// tflite marshaller. Boxes are in the coordinates of the network output,
+// normalized or in pixels of the network input; WithSize and Letterbox map
+// them back to the image. The track subpackage follows detections from
+// frame to frame.
+package detect
+
+import (
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// Box is an axis aligned rectangle from its top left corner Min to its
+// bottom right corner Max.
+type Box struct {
+	Min, Max vec.Vector2D
+}
+
+// CenterBox returns the box of the given center and size.
+func CenterBox(cx, cy, w, h float32) Box {
+	return Box{
+		Min: vec.Vector2D{cx - w/2, cy - h/2},
+		Max: vec.Vector2D{cx + w/2, cy + h/2},
+	}
+}
+
+// Width returns the width of the box, 0 if it is empty.
+func (b Box) Width() float32 {
+	return max(0, b.Max[0]-b.Min[0])
+}
+
+// Height returns the height of the box, 0 if it is empty.
+func (b Box) Height() float32 {
+	return max(0, b.Max[1]-b.Min[1])
+}
+
+// Area returns the area of the box.
+func (b Box) Area() float32 {
+	return b.Width() * b.Height()
+}
+
+// Center returns the center of the box.
+func (b Box) Center() vec.Vector2D {
+	return vec.Vector2D{(b.Min[0] + b.Max[0]) / 2, (b.Min[1] + b.Max[1]) / 2}
+}
+
+// Intersect returns the overlap of two boxes, empty if they do not overlap.
+func (b Box) Intersect(o Box) Box {
+	return Box{
+		Min: vec.Vector2D{max(b.Min[0], o.Min[0]), max(b.Min[1], o.Min[1])},
+		Max: vec.Vector2D{min(b.Max[0], o.Max[0]), min(b.Max[1], o.Max[1])},
+	}
+}
+
+// IoU returns the intersection over union of two boxes, 0 for empty boxes.
+func (b Box) IoU(o Box) float32 {
+	in := b.Intersect(o).Area()
+	union := b.Area() + o.Area() - in
+	if union <= 0 {
+		return 0
+	}
+	return in / union
+}
+
+// Scale multiplies the coordinates of the box by sx and sy.
+func (b Box) Scale(sx, sy float32) Box {
+	return Box{
+		Min: vec.Vector2D{b.Min[0] * sx, b.Min[1] * sy},
+		Max: vec.Vector2D{b.Max[0] * sx, b.Max[1] * sy},
+	}
+}
+
+// Detection is a detected object.
+type Detection struct {
+	Box Box
+	// Class is the index of the class in the labels of the network.
+	Class int
+	// Score is the confidence of the detection, usually in [0, 1].
+	Score float32
+}
+
+// Letterbox maps boxes of a network input back to the image it was made
+// from by scaling with a fixed aspect ratio and padding evenly, as the
+// Ultralytics preprocessing does.
+type Letterbox struct {
+	// Scale is the ratio of network input pixels to image pixels.
+	Scale float32
+	// Pad is the padding of the scaled image on the left and top.
+	Pad vec.Vector2D
+	// Width and Height are the size of the image.
+	Width, Height float32
+}
+
+// NewLetterbox returns the letterbox that fits a width x height image into
+// an inputWidth x inputHeight network input.
+func NewLetterbox(width, height, inputWidth, inputHeight int) Letterbox {
+	if width <= 0 || height <= 0 || inputWidth <= 0 || inputHeight <= 0 {
+		panic("detect: letterbox sizes must be positive")
+	}
+	s := min(float32(inputWidth)/float32(width), float32(inputHeight)/float32(height))
+	return Letterbox{
+		Scale:  s,
+		Pad:    vec.Vector2D{(float32(inputWidth) - float32(width)*s) / 2, (float32(inputHeight) - float32(height)*s) / 2},
+		Width:  float32(width),
+		Height: float32(height),
+	}
+}
+
+// Box maps a box in network input pixels to image pixels, clipped to the
+// image.
+func (l Letterbox) Box(b Box) Box {
+	unmap := func(p vec.Vector2D) vec.Vector2D {
+		return vec.Vector2D{
+			min(max((p[0]-l.Pad[0])/l.Scale, 0), l.Width),
+			min(max((p[1]-l.Pad[1])/l.Scale, 0), l.Height),
+		}
+	}
+	return Box{Min: unmap(b.Min), Max: unmap(b.Max)}
+}
+
+// Restore maps the boxes of detections to image pixels in place.
+func (l Letterbox) Restore(dets []Detection) {
+	for i := range dets {
+		dets[i].Box = l.Box(dets[i].Box)
+	}
+}
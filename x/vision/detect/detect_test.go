@@ -0,0 +1,191 @@
+package detect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+func near(a, b Box, tol float32) bool {
+	for i := range 2 {
+		if abs(a.Min[i]-b.Min[i]) > tol || abs(a.Max[i]-b.Max[i]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x float32) float32 {
+	return float32(math.Abs(float64(x)))
+}
+
+func TestIoU(t *testing.T) {
+	a := CenterBox(0, 0, 2, 2)
+	for _, c := range []struct {
+		b    Box
+		want float32
+	}{
+		{a, 1},
+		{CenterBox(1, 0, 2, 2), 2.0 / 6},
+		{CenterBox(5, 5, 2, 2), 0},
+		{CenterBox(0, 0, 1, 1), 0.25},
+		{Box{}, 0},
+	} {
+		if got := a.IoU(c.b); abs(got-c.want) > 1e-6 {
+			t.Errorf("IoU with %v: %v, want %v", c.b, got, c.want)
+		}
+	}
+}
+
+func TestNMS(t *testing.T) {
+	dets := []Detection{
+		{Box: CenterBox(10, 10, 10, 10), Class: 0, Score: 0.8},
+		{Box: CenterBox(11, 10, 10, 10), Class: 0, Score: 0.9},
+		{Box: CenterBox(11, 10, 10, 10), Class: 1, Score: 0.7},
+		{Box: CenterBox(40, 10, 10, 10), Class: 0, Score: 0.6},
+	}
+	got := NMS(dets, 0.5)
+	if len(got) != 3 || got[0].Score != 0.9 || got[1].Class != 1 || got[2].Score != 0.6 {
+		t.Errorf("NMS kept %+v", got)
+	}
+	if dets[0].Score != 0.8 {
+		t.Error("NMS changed its input")
+	}
+
+	soft := SoftNMS(dets, 0.5, 0.1)
+	if len(soft) != 4 || soft[0].Score != 0.9 {
+		t.Fatalf("SoftNMS kept %+v", soft)
+	}
+	// the overlapped detection of class 0 decays below the others
+	last := soft[3]
+	iou := dets[0].Box.IoU(dets[1].Box)
+	if want := 0.8 * float32(math.Exp(float64(-iou*iou/0.5))); last.Class != 0 || abs(last.Score-want) > 1e-6 {
+		t.Errorf("decayed to %+v, want score %v", last, want)
+	}
+	if got := SoftNMS(dets, 0.5, 0.5); len(got) != 3 {
+		t.Errorf("SoftNMS with min score kept %+v", got)
+	}
+}
+
+func TestDecodeSSD(t *testing.T) {
+	out := tensor.FromArray(tensor.NewShape(1, 1, 3, 7), []float32{
+		0, 15, 0.9, 0.1, 0.2, 0.3, 0.6,
+		0, 7, 0.1, 0.1, 0.2, 0.3, 0.6,
+		-1, 0, 0, 0, 0, 0, 0,
+	})
+	dets, err := DecodeSSD(out, WithSize(200, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Box{Min: [2]float32{20, 20}, Max: [2]float32{60, 60}}
+	if len(dets) != 1 || dets[0].Class != 15 || dets[0].Score != 0.9 || !near(dets[0].Box, want, 1e-4) {
+		t.Errorf("decoded %+v", dets)
+	}
+	if _, err := DecodeSSD(tensor.FromArray(tensor.NewShape(2, 6), make([]float32, 12))); err == nil {
+		t.Error("rows of 6 accepted")
+	}
+	if _, err := DecodeSSD(tensor.New(types.UINT8, tensor.NewShape(1, 7))); err == nil {
+		t.Error("UINT8 output accepted")
+	}
+}
+
+func TestDecodeSSDHead(t *testing.T) {
+	anchors := []Box{CenterBox(0.25, 0.25, 0.2, 0.2), CenterBox(0.75, 0.75, 0.4, 0.2)}
+	// the first anchor moves right by half its width and doubles its height
+	dh := float32(math.Log(2) / 0.2)
+	boxes := tensor.FromArray(tensor.NewShape(1, 2, 4), []float32{
+		5, 0, 0, dh,
+		0, 0, 0, 0,
+	})
+	// background, then two classes
+	scores := tensor.FromArray(tensor.NewShape(1, 2, 3), []float32{
+		0.1, 0.2, 0.7,
+		0.9, 0.05, 0.05,
+	})
+	dets, err := DecodeSSDHead(boxes, scores, anchors, WithBackground(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 1 || dets[0].Class != 2 || !near(dets[0].Box, CenterBox(0.35, 0.25, 0.2, 0.4), 1e-5) {
+		t.Errorf("decoded %+v", dets)
+	}
+
+	// the same regression in TensorFlow order with logits
+	boxes = tensor.FromArray(tensor.NewShape(2, 4), []float32{
+		0, 5, dh, 0,
+		0, 0, 0, 0,
+	})
+	scores = tensor.FromArray(tensor.NewShape(2, 3), []float32{
+		-3, -2, 2,
+		3, -3, -3,
+	})
+	dets, err = DecodeSSDHead(boxes, scores, anchors, WithBackground(0), WithYX(), WithSigmoid(), WithThreshold(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 1 || !near(dets[0].Box, CenterBox(0.35, 0.25, 0.2, 0.4), 1e-5) || abs(dets[0].Score-0.8808) > 1e-3 {
+		t.Errorf("decoded %+v", dets)
+	}
+	if _, err := DecodeSSDHead(boxes, scores, anchors[:1]); err == nil {
+		t.Error("anchor count mismatch accepted")
+	}
+}
+
+func TestDecodeYOLO(t *testing.T) {
+	// three candidates in pixels of a 640 input, two classes
+	v5 := tensor.FromArray(tensor.NewShape(1, 3, 7), []float32{
+		100, 100, 50, 80, 0.9, 0.2, 0.8,
+		300, 200, 40, 40, 0.1, 0.9, 0.1,
+		500, 400, 60, 20, 0.5, 0.9, 0.1,
+	})
+	dets, err := DecodeYOLOv5(v5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 2 || dets[0].Class != 1 || abs(dets[0].Score-0.72) > 1e-6 ||
+		!near(dets[0].Box, CenterBox(100, 100, 50, 80), 1e-4) || dets[1].Class != 0 || abs(dets[1].Score-0.45) > 1e-6 {
+		t.Errorf("YOLOv5 decoded %+v", dets)
+	}
+
+	// the same candidates channels first without objectness
+	v8 := tensor.FromArray(tensor.NewShape(1, 6, 3), []float32{
+		100, 300, 500,
+		100, 200, 400,
+		50, 40, 60,
+		80, 40, 20,
+		0.2, 0.1, 0.9,
+		0.8, 0.2, 0.1,
+	})
+	dets, err = DecodeYOLOv8(v8, WithThreshold(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 2 || dets[0].Class != 1 || dets[0].Score != 0.8 ||
+		!near(dets[1].Box, CenterBox(500, 400, 60, 20), 1e-4) || dets[1].Class != 0 {
+		t.Errorf("YOLOv8 decoded %+v", dets)
+	}
+	if _, err := DecodeYOLOv8(tensor.FromArray(tensor.NewShape(1, 4, 3), make([]float32, 12))); err == nil {
+		t.Error("output without classes accepted")
+	}
+}
+
+func TestLetterbox(t *testing.T) {
+	// a 1280x720 image in a 640x640 input is halved and padded by 140 rows
+	l := NewLetterbox(1280, 720, 640, 640)
+	if l.Scale != 0.5 || l.Pad[0] != 0 || l.Pad[1] != 140 {
+		t.Fatalf("letterbox %+v", l)
+	}
+	dets := []Detection{
+		{Box: Box{Min: [2]float32{10, 150}, Max: [2]float32{110, 250}}},
+		{Box: Box{Min: [2]float32{600, 100}, Max: [2]float32{700, 200}}},
+	}
+	l.Restore(dets)
+	if !near(dets[0].Box, Box{Min: [2]float32{20, 20}, Max: [2]float32{220, 220}}, 1e-4) {
+		t.Errorf("restored %v", dets[0].Box)
+	}
+	if !near(dets[1].Box, Box{Min: [2]float32{1200, 0}, Max: [2]float32{1280, 120}}, 1e-4) {
+		t.Errorf("clipped %v", dets[1].Box)
+	}
+}
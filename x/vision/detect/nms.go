@@ -0,0 +1,74 @@
+package detect
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// byScore returns a copy of the detections sorted by descending score.
+func byScore(dets []Detection) []Detection {
+	out := slices.Clone(dets)
+	slices.SortStableFunc(out, func(a, b Detection) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return out
+}
+
+// NMS is greedy non-maximum suppression: from the best score down, every
+// detection that overlaps a kept one of the same class by more than iou is
+// removed. Detections of different classes never suppress each other; give
+// them all the same class for class agnostic suppression. The result is
+// sorted by descending score.
+func NMS(dets []Detection, iou float32) []Detection {
+	sorted := byScore(dets)
+	out := sorted[:0]
+	for _, d := range sorted {
+		keep := true
+		for _, k := range out {
+			if k.Class == d.Class && k.Box.IoU(d.Box) > iou {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// SoftNMS is Gaussian soft non-maximum suppression (Bodla et al. 2017):
+// instead of removing the detections that overlap a better one of the same
+// class, their scores decay by exp(-IoU²/sigma), and those that fall below
+// minScore are dropped. It keeps overlapping objects that NMS would lose
+// in crowds. The result is sorted by descending score.
+func SoftNMS(dets []Detection, sigma, minScore float32) []Detection {
+	if sigma <= 0 {
+		panic("detect: sigma must be positive")
+	}
+	rest := byScore(dets)
+	var out []Detection
+	for len(rest) > 0 {
+		best := 0
+		for i, d := range rest {
+			if d.Score > rest[best].Score {
+				best = i
+			}
+		}
+		top := rest[best]
+		rest = slices.Delete(rest, best, best+1)
+		if top.Score < minScore {
+			break
+		}
+		out = append(out, top)
+		for i := range rest {
+			if rest[i].Class != top.Class {
+				continue
+			}
+			o := top.Box.IoU(rest[i].Box)
+			rest[i].Score *= float32(math.Exp(float64(-o * o / sigma)))
+		}
+	}
+	return out
+}
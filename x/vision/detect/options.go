@@ -0,0 +1,88 @@
+package detect
+
+// config holds the parameters of the decoders; each reads the ones it
+// needs.
+type config struct {
+	threshold  float32
+	sx, sy     float32
+	center     float32
+	size       float32
+	yx         bool
+	sigmoid    bool
+	background int
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		threshold:  0.25,
+		sx:         1,
+		sy:         1,
+		center:     0.1,
+		size:       0.2,
+		background: -1,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// Option configures a decoder.
+type Option func(*config)
+
+// WithThreshold sets the least score of a detection (default: 0.25).
+func WithThreshold(score float32) Option {
+	return func(c *config) {
+		c.threshold = score
+	}
+}
+
+// WithSize multiplies the decoded coordinates by a width and height, to
+// turn normalized boxes into pixels (default: 1, boxes as the network
+// outputs them).
+func WithSize(width, height float32) Option {
+	return func(c *config) {
+		if width <= 0 || height <= 0 {
+			panic("detect: size must be positive")
+		}
+		c.sx, c.sy = width, height
+	}
+}
+
+// WithVariance sets the variances the box regressions of an SSD head are
+// divided by, for the center offsets and the log sizes (default: 0.1 and
+// 0.2, the scales 10 and 5 of the TensorFlow box coder).
+func WithVariance(center, size float32) Option {
+	return func(c *config) {
+		if center <= 0 || size <= 0 {
+			panic("detect: variances must be positive")
+		}
+		c.center, c.size = center, size
+	}
+}
+
+// WithYX reads the box regressions of an SSD head in the order of the
+// TensorFlow Object Detection API, (dy, dx, dh, dw), instead of (dx, dy,
+// dw, dh).
+func WithYX() Option {
+	return func(c *config) {
+		c.yx = true
+	}
+}
+
+// WithSigmoid applies the logistic function to the class scores of an SSD
+// head, for heads that output logits.
+func WithSigmoid() Option {
+	return func(c *config) {
+		c.sigmoid = true
+	}
+}
+
+// WithBackground sets the class of the scores of an SSD head that is the
+// background and never detected (default: none). Class indices of the
+// detections stay those of the scores.
+func WithBackground(class int) Option {
+	return func(c *config) {
+		c.background = class
+	}
+}
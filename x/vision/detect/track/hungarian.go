@@ -0,0 +1,102 @@
+package track
+
+import "math"
+
+// match assigns rows to columns of a score matrix for the largest total
+// score and returns the pairs scoring at least minScore.
+func match(score [][]float64, minScore float64) [][2]int {
+	n := len(score)
+	if n == 0 || len(score[0]) == 0 {
+		return nil
+	}
+	m := len(score[0])
+	// the Hungarian method wants no more rows than columns
+	transposed := n > m
+	rows, cols := n, m
+	if transposed {
+		rows, cols = m, n
+	}
+	cost := make([][]float64, rows)
+	for i := range cost {
+		cost[i] = make([]float64, cols)
+		for j := range cost[i] {
+			if transposed {
+				cost[i][j] = -score[j][i]
+			} else {
+				cost[i][j] = -score[i][j]
+			}
+		}
+	}
+	var out [][2]int
+	for i, j := range assign(cost) {
+		if transposed {
+			i, j = j, i
+		}
+		if score[i][j] >= minScore {
+			out = append(out, [2]int{i, j})
+		}
+	}
+	return out
+}
+
+// assign solves the assignment problem of a cost matrix with no more rows
+// than columns by the Hungarian method with potentials, O(n²m), and
+// returns the column of every row.
+func assign(cost [][]float64) []int {
+	n, m := len(cost), len(cost[0])
+	// potentials u of rows and v of columns; p is the row of a column and
+	// column 0 the row being added, all 1 based
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1)
+	way := make([]int, m+1)
+	minv := make([]float64, m+1)
+	used := make([]bool, m+1)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		for j := range minv {
+			minv[j], used[j] = math.Inf(1), false
+		}
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], math.Inf(1), 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if c := cost[i0-1][j-1] - u[i0] - v[j]; c < minv[j] {
+					minv[j], way[j] = c, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		// augment along the alternating path
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+	out := make([]int, n)
+	for j := 1; j <= m; j++ {
+		if p[j] != 0 {
+			out[p[j]-1] = j - 1
+		}
+	}
+	return out
+}
@@ -0,0 +1,244 @@
+// Package track follows detected objects from frame to frame and gives
+// them persistent IDs, as SORT does (Bewley et al. 2016): every object has
+// a constant velocity Kalman filter of its box, detections are assigned to
+// the predicted boxes by the Hungarian method on their overlap, and
+// objects are confirmed after a few consecutive detections and dropped
+// after a few missed ones.
+//
+// Detections may come from any source, the decoders of x/vision/detect or
+// another detector. Boxes are expected in pixels; the noise of the filters
+// is tuned for that scale.
+package track
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/filter/kalman"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/detect"
+)
+
+// Track is a tracked object in the current frame.
+type Track struct {
+	// ID identifies the object over frames, from 1.
+	ID int
+	// Box is the filtered box of the object.
+	Box detect.Box
+	// Class and Score are those of the last detection of the object.
+	Class int
+	Score float32
+	// Velocity is the motion of the box center in pixels per frame.
+	Velocity vec.Vector2D
+	// Hits is the number of frames the object was detected in, Age the
+	// number of frames since it was first detected.
+	Hits, Age int
+}
+
+// object is an object being tracked, confirmed or not.
+type object struct {
+	kf     *kalman.Kalman
+	id     int
+	class  int
+	score  float32
+	hits   int
+	streak int
+	missed int
+	age    int
+}
+
+// Tracker assigns detections to tracked objects.
+type Tracker struct {
+	maxAge  int
+	minHits int
+	minIoU  float32
+
+	f, h, q, r mat.Matrix
+	objects    []*object
+	frame      int
+	next       int
+}
+
+// Option configures a Tracker.
+type Option func(*Tracker)
+
+// WithMaxAge sets the frames an object is kept without detections
+// (default: 1). Longer bridges occlusions at the cost of stale tracks.
+func WithMaxAge(frames int) Option {
+	return func(t *Tracker) {
+		if frames < 0 {
+			panic("track: max age must not be negative")
+		}
+		t.maxAge = frames
+	}
+}
+
+// WithMinHits sets the consecutive detections that confirm an object
+// before it is reported (default: 3). The first frames of the tracker
+// report objects at once.
+func WithMinHits(n int) Option {
+	return func(t *Tracker) {
+		if n < 1 {
+			panic("track: min hits must be positive")
+		}
+		t.minHits = n
+	}
+}
+
+// WithMinIoU sets the least overlap of a detection with the predicted box
+// of an object it is assigned to (default: 0.3).
+func WithMinIoU(iou float32) Option {
+	return func(t *Tracker) {
+		t.minIoU = iou
+	}
+}
+
+// New creates a tracker.
+func New(opts ...Option) *Tracker {
+	t := &Tracker{maxAge: 1, minHits: 3, minIoU: 0.3}
+	for _, opt := range opts {
+		opt(t)
+	}
+	// the state is the box center, area and aspect ratio and the velocities
+	// of the center and area; the noise is that of SORT
+	t.f = mat.New(7, 7)
+	t.f.Eye()
+	for i := range 3 {
+		t.f[i][i+4] = 1
+	}
+	t.h = mat.New(4, 7)
+	for i := range 4 {
+		t.h[i][i] = 1
+	}
+	t.q = diag(1, 1, 1, 1, 0.01, 0.01, 1e-4)
+	t.r = diag(1, 1, 10, 10)
+	return t
+}
+
+func diag(v ...float32) mat.Matrix {
+	m := mat.New(len(v), len(v))
+	for i, x := range v {
+		m[i][i] = x
+	}
+	return m
+}
+
+// Reset drops all objects and restarts the IDs.
+func (t *Tracker) Reset() {
+	t.objects, t.frame, t.next = nil, 0, 0
+}
+
+// Update advances the tracker by a frame with its detections and returns
+// the confirmed objects detected in it, sorted by ID. A detection is only
+// assigned to an object of the same class; give all detections the same
+// class to track regardless of class.
+func (t *Tracker) Update(dets []detect.Detection) []Track {
+	t.frame++
+	live := t.objects[:0]
+	for _, o := range t.objects {
+		x := o.kf.Output()
+		if x[2]+x[6] <= 0 {
+			// the area must not shrink below zero
+			s := slices.Clone(x)
+			s[6] = 0
+			o.kf.SetState(s)
+		}
+		o.kf.Predict()
+		o.age++
+		if o.missed > 0 {
+			o.streak = 0
+		}
+		o.missed++
+		if valid(o.kf.Output()) {
+			live = append(live, o)
+		}
+	}
+	t.objects = live
+
+	iou := make([][]float64, len(dets))
+	for i, d := range dets {
+		iou[i] = make([]float64, len(t.objects))
+		for j, o := range t.objects {
+			if o.class == d.Class {
+				iou[i][j] = float64(d.Box.IoU(box(o.kf.Output())))
+			}
+		}
+	}
+	matched := make([]bool, len(dets))
+	for _, p := range match(iou, float64(t.minIoU)) {
+		d, o := dets[p[0]], t.objects[p[1]]
+		o.kf.UpdateMeasurement(measurement(d.Box))
+		o.hits++
+		o.streak++
+		o.missed = 0
+		o.score = d.Score
+		matched[p[0]] = true
+	}
+	for i, d := range dets {
+		if matched[i] || d.Box.Area() <= 0 {
+			continue
+		}
+		o := &object{
+			kf:     kalman.New(7, 4, t.f, t.h, t.q, t.r),
+			class:  d.Class,
+			score:  d.Score,
+			hits:   1,
+			streak: 1,
+		}
+		z := measurement(d.Box)
+		o.kf.SetState(vec.NewFrom(z[0], z[1], z[2], z[3], 0, 0, 0))
+		// the velocities are unknown
+		o.kf.SetCovariance(diag(10, 10, 10, 10, 1e4, 1e4, 1e4))
+		t.objects = append(t.objects, o)
+	}
+
+	var out []Track
+	live = t.objects[:0]
+	for _, o := range t.objects {
+		if o.missed == 0 && (o.streak >= t.minHits || t.frame <= t.minHits) {
+			if o.id == 0 {
+				t.next++
+				o.id = t.next
+			}
+			x := o.kf.Output()
+			out = append(out, Track{
+				ID:       o.id,
+				Box:      box(x),
+				Class:    o.class,
+				Score:    o.score,
+				Velocity: vec.Vector2D{x[4], x[5]},
+				Hits:     o.hits,
+				Age:      o.age,
+			})
+		}
+		if o.missed <= t.maxAge {
+			live = append(live, o)
+		}
+	}
+	t.objects = live
+	slices.SortFunc(out, func(a, b Track) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+// measurement returns the center, area and aspect ratio of a box.
+func measurement(b detect.Box) vec.Vector {
+	c := b.Center()
+	return vec.NewFrom(c[0], c[1], b.Area(), b.Width()/b.Height())
+}
+
+// box returns the box of a state.
+func box(x vec.Vector) detect.Box {
+	w := float32(math.Sqrt(float64(x[2] * x[3])))
+	return detect.CenterBox(x[0], x[1], w, x[2]/w)
+}
+
+func valid(x vec.Vector) bool {
+	for _, v := range x[:4] {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return false
+		}
+	}
+	return x[2] > 0 && x[3] > 0
+}
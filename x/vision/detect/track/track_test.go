@@ -0,0 +1,129 @@
+package track
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/vision/detect"
+)
+
+func TestAssign(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := range 50 {
+		n, m := 1+r.Intn(5), 1+r.Intn(5)
+		score := make([][]float64, n)
+		for i := range score {
+			score[i] = make([]float64, m)
+			for j := range score[i] {
+				score[i][j] = r.Float64()
+			}
+		}
+		got := 0.0
+		for _, p := range match(score, 0) {
+			got += score[p[0]][p[1]]
+		}
+		if want := best(score, 0, make([]bool, m)); math.Abs(got-want) > 1e-9 {
+			t.Errorf("trial %d: %dx%d total %g, want %g", trial, n, m, got, want)
+		}
+	}
+}
+
+// best is the largest total score of an assignment by exhaustive search.
+func best(score [][]float64, row int, used []bool) float64 {
+	if row == len(score) {
+		return 0
+	}
+	// the row may stay unassigned when there are more rows than columns
+	b := best(score, row+1, used)
+	for j := range used {
+		if !used[j] {
+			used[j] = true
+			b = max(b, score[row][j]+best(score, row+1, used))
+			used[j] = false
+		}
+	}
+	return b
+}
+
+func TestTracker(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	tr := New(WithMaxAge(2))
+	// two objects crossing each other horizontally and a third appearing
+	// later
+	truth := func(frame int) []detect.Detection {
+		f := float32(frame)
+		objs := []detect.Detection{
+			{Box: detect.CenterBox(100+8*f, 100, 40, 60), Score: 0.9},
+			{Box: detect.CenterBox(300-8*f, 110, 40, 60), Score: 0.8},
+		}
+		if frame >= 10 {
+			objs = append(objs, detect.Detection{Box: detect.CenterBox(500, 300, 30, 30), Class: 1, Score: 0.7})
+		}
+		return objs
+	}
+	// noisy detections, the first object missed in one frame
+	detections := func(frame int) []detect.Detection {
+		dets := truth(frame)
+		for i := range dets {
+			dets[i].Box.Min[0] += float32(r.NormFloat64())
+			dets[i].Box.Max[1] += float32(r.NormFloat64())
+		}
+		if frame == 6 {
+			dets = dets[1:]
+		}
+		return dets
+	}
+	ids := map[int]int{}
+	for frame := range 20 {
+		tracks := tr.Update(detections(frame))
+		for _, tk := range tracks {
+			// the object of a track is the nearest one
+			c := tk.Box.Center()
+			obj, dist := -1, math.Inf(1)
+			for k, o := range truth(frame) {
+				oc := o.Box.Center()
+				if e := math.Hypot(float64(c[0]-oc[0]), float64(c[1]-oc[1])); e < dist {
+					obj, dist = k, e
+				}
+			}
+			if dist > 3 {
+				t.Errorf("frame %d: track %d at %v is %.1f px off", frame, tk.ID, c, dist)
+			}
+			if id, ok := ids[obj]; ok && id != tk.ID {
+				t.Errorf("frame %d: object %d switched from ID %d to %d", frame, obj, id, tk.ID)
+			}
+			ids[obj] = tk.ID
+		}
+		// the first object is confirmed again three frames after the miss,
+		// the third three frames after it appears
+		want := 2
+		switch {
+		case frame >= 6 && frame < 9:
+			want = 1
+		case frame >= 12:
+			want = 3
+		}
+		if len(tracks) != want {
+			t.Errorf("frame %d: %d tracks, want %d", frame, len(tracks), want)
+		}
+		if frame == 19 && math.Abs(float64(tracks[0].Velocity[0])-8) > 0.5 {
+			t.Errorf("velocity %v, want 8 px/frame", tracks[0].Velocity)
+		}
+	}
+	if len(ids) != 3 || ids[0] == ids[1] || ids[2] != 3 {
+		t.Errorf("IDs %v", ids)
+	}
+
+	// objects not detected for longer than the max age are dropped
+	for range 3 {
+		tr.Update(nil)
+	}
+	if tracks := tr.Update(detections(20)[:1]); len(tracks) != 0 {
+		t.Errorf("stale objects reported: %+v", tracks)
+	}
+	tr.Reset()
+	if tracks := tr.Update(detections(0)); len(tracks) != 2 || tracks[0].ID != 1 {
+		t.Errorf("after reset: %+v", tracks)
+	}
+}
@@ -0,0 +1,96 @@
+package keypoints
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"math/rand"
+
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// DescriptorBits is the length of a Descriptor in bits.
+const DescriptorBits = 256
+
+// Descriptor is a 256 bit binary descriptor, compared by Hamming distance.
+type Descriptor [DescriptorBits / 8]byte
+
+// Hamming returns the number of differing bits of a and b.
+func Hamming(a, b *Descriptor) int {
+	n := 0
+	for i := 0; i < len(a); i += 8 {
+		n += bits.OnesCount64(binary.LittleEndian.Uint64(a[i:]) ^ binary.LittleEndian.Uint64(b[i:]))
+	}
+	return n
+}
+
+// Bit returns bit i of d.
+func (d *Descriptor) Bit(i int) bool {
+	return d[i>>3]&(1<<(i&7)) != 0
+}
+
+// Vector unpacks d into a 0/1 vector, whose squared Euclidean distance to
+// another unpacked descriptor is their Hamming distance; e.g. for
+// KDTreeIndex.
+func (d *Descriptor) Vector() vec.Vector {
+	v := make(vec.Vector, DescriptorBits)
+	for i := range v {
+		if d.Bit(i) {
+			v[i] = 1
+		}
+	}
+	return v
+}
+
+// patchRadius is the radius of the patch described by ORB.
+const patchRadius = 15
+
+// briefPattern holds the 256 point pairs of the steered BRIEF test, x1, y1,
+// x2, y2 each, drawn once from an isotropic Gaussian (BRIEF G II) and kept
+// inside the patch circle so that any rotation stays within the patch. The
+// pattern is not OpenCV's learned one; descriptors are only comparable with
+// descriptors of this package.
+var briefPattern = func() [DescriptorBits][4]float32 {
+	var pattern [DescriptorBits][4]float32
+	r := rand.New(rand.NewSource(0x0b1ef))
+	sigma := float64(2*patchRadius+1) / 5
+	point := func() (float32, float32) {
+		for {
+			x, y := math.Round(r.NormFloat64()*sigma), math.Round(r.NormFloat64()*sigma)
+			if x*x+y*y <= float64(patchRadius-1)*float64(patchRadius-1) {
+				return float32(x), float32(y)
+			}
+		}
+	}
+	for i := range pattern {
+		for {
+			x1, y1 := point()
+			x2, y2 := point()
+			if x1 != x2 || y1 != y2 {
+				pattern[i] = [4]float32{x1, y1, x2, y2}
+				break
+			}
+		}
+	}
+	return pattern
+}()
+
+// describe computes the steered BRIEF descriptor of the smoothed image g at
+// (x, y) rotated by angle. The point must be at least patchRadius pixels
+// away from the border.
+func describe(g gray.Image, x, y int, angle float32) Descriptor {
+	sin, cos := math.Sincos(float64(angle))
+	s, c := float32(sin), float32(cos)
+	var d Descriptor
+	for i, p := range briefPattern {
+		x1 := x + int(math.Round(float64(c*p[0]-s*p[1])))
+		y1 := y + int(math.Round(float64(s*p[0]+c*p[1])))
+		x2 := x + int(math.Round(float64(c*p[2]-s*p[3])))
+		y2 := y + int(math.Round(float64(s*p[2]+c*p[3])))
+		if g.At(x1, y1) < g.At(x2, y2) {
+			d[i>>3] |= 1 << (i & 7)
+		}
+	}
+	return d
+}
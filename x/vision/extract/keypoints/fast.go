@@ -0,0 +1,118 @@
+package keypoints
+
+import (
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// circle is the Bresenham circle of radius 3 around a FAST candidate,
+// clockwise from the top.
+var circle = [16][2]int{
+	{0, -3}, {1, -3}, {2, -2}, {3, -1}, {3, 0}, {3, 1}, {2, 2}, {1, 3},
+	{0, 3}, {-1, 3}, {-2, 2}, {-3, 1}, {-3, 0}, {-3, -1}, {-2, -2}, {-1, -3},
+}
+
+// fastArc is the number of contiguous circle pixels of FAST-9.
+const fastArc = 9
+
+// FAST detects FAST-9 corners: pixels with 9 contiguous pixels on the
+// surrounding circle all brighter or all darker by more than threshold. The
+// response is the largest threshold for which the pixel stays a corner.
+// With nonmax set, only the local maxima of the response in a 3x3
+// neighbourhood are kept.
+func FAST(img types.Tensor, threshold float32, nonmax bool) ([]KeyPoint, error) {
+	g, err := gray.FromTensor(img)
+	if err != nil {
+		return nil, err
+	}
+	return fast(g, threshold, nonmax, 3), nil
+}
+
+// fast detects corners at least border pixels (and never less than 3) away
+// from the image edges.
+func fast(g gray.Image, threshold float32, nonmax bool, border int) []KeyPoint {
+	border = max(border, 3)
+	if g.W <= 2*border || g.H <= 2*border {
+		return nil
+	}
+	var offsets [16]int
+	for i, c := range circle {
+		offsets[i] = c[1]*g.W + c[0]
+	}
+
+	score := make([]float32, g.W*g.H)
+	var corners []int
+	for y := border; y < g.H-border; y++ {
+		for x := border; x < g.W-border; x++ {
+			i := y*g.W + x
+			if s := fastScore(g.Pix, i, &offsets, threshold); s > threshold {
+				score[i] = s
+				corners = append(corners, i)
+			}
+		}
+	}
+
+	out := make([]KeyPoint, 0, len(corners))
+	for _, i := range corners {
+		s := score[i]
+		if nonmax && !isLocalMax(score, i, g.W, s) {
+			continue
+		}
+		out = append(out, KeyPoint{X: float32(i % g.W), Y: float32(i / g.W), Size: 7, Response: s})
+	}
+	return out
+}
+
+// isLocalMax reports whether s at i beats its 8 neighbours; ties keep the
+// first pixel in raster order.
+func isLocalMax(score []float32, i, w int, s float32) bool {
+	for _, d := range [...]int{-w - 1, -w, -w + 1, -1} {
+		if score[i+d] >= s {
+			return false
+		}
+	}
+	for _, d := range [...]int{1, w - 1, w, w + 1} {
+		if score[i+d] > s {
+			return false
+		}
+	}
+	return true
+}
+
+// fastScore returns the largest difference d such that fastArc contiguous
+// circle pixels all differ from the center by at least d in the same
+// direction, or 0 when the pixel is clearly no corner.
+func fastScore(pix []float32, i int, offsets *[16]int, threshold float32) float32 {
+	p := pix[i]
+	hi, lo := p+threshold, p-threshold
+
+	// any 9-arc contains at least two of the four compass pixels
+	bright, dark := 0, 0
+	for k := 0; k < 16; k += 4 {
+		v := pix[i+offsets[k]]
+		if v > hi {
+			bright++
+		} else if v < lo {
+			dark++
+		}
+	}
+	if bright < 2 && dark < 2 {
+		return 0
+	}
+
+	var d [16]float32
+	for k, o := range offsets {
+		d[k] = pix[i+o] - p
+	}
+	var best float32
+	for start := range 16 {
+		minBright, minDark := d[start], -d[start]
+		for k := 1; k < fastArc; k++ {
+			v := d[(start+k)&15]
+			minBright = min(minBright, v)
+			minDark = min(minDark, -v)
+		}
+		best = max(best, minBright, minDark)
+	}
+	return best
+}
@@ -0,0 +1,23 @@
+// Package keypoints detects, describes and matches image keypoints in plain
+// Go: FAST corners, ORB (oriented FAST and rotated BRIEF) on an image
+// pyramid, and brute force, LSH and k-d tree matchers. It is the OpenCV-free
+// counterpart of the GoCV features step.
+//
+// Images are single channel UINT8 or FP32 tensors of shape [H, W] or
+// [H, W, 1].
+package keypoints
+
+// KeyPoint is a detected feature in level 0 image coordinates.
+type KeyPoint struct {
+	// X and Y is the position in pixels.
+	X, Y float32
+	// Size is the diameter of the described neighbourhood.
+	Size float32
+	// Angle is the orientation in radians from the x axis towards the y
+	// axis of the image, i.e. clockwise on screen; 0 when not computed.
+	Angle float32
+	// Response ranks keypoints; larger is stronger.
+	Response float32
+	// Octave is the pyramid level the keypoint was found on.
+	Octave int
+}
@@ -0,0 +1,37 @@
+package keypoints
+
+import (
+	"github.com/itohio/EasyRobot/x/math/graph"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// KDTree finds nearest neighbours of float descriptors with the k-d tree of
+// x/math/graph. It suits low dimensional descriptors; binary descriptors can
+// be indexed through Descriptor.Vector, where LSH is usually faster.
+type KDTree struct {
+	tree  *graph.GenericKDTree[vec.Vector, float32]
+	index map[*float32]int
+}
+
+// NewKDTree indexes train; every vector must have the same, non-zero
+// length.
+func NewKDTree(train []vec.Vector) *KDTree {
+	index := make(map[*float32]int, len(train))
+	for i, v := range train {
+		index[&v[0]] = i
+	}
+	return &KDTree{tree: graph.NewGenericKDTree[vec.Vector, float32](train), index: index}
+}
+
+// Match returns the nearest train vector of every query vector.
+func (m *KDTree) Match(query []vec.Vector) []Match {
+	out := make([]Match, 0, len(query))
+	for q, v := range query {
+		nn := m.tree.NearestNeighbor(v)
+		if len(nn) == 0 {
+			continue
+		}
+		out = append(out, Match{Query: q, Train: m.index[&nn[0]], Distance: v.Distance(nn)})
+	}
+	return out
+}
@@ -0,0 +1,245 @@
+package keypoints
+
+import (
+	"image"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/geometry"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+)
+
+func readScene(t *testing.T) types.Tensor {
+	t.Helper()
+	f, err := os.Open("testdata/scene.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := img.(*image.Gray)
+	out := tensor.New(types.UINT8, tensor.NewShape(g.Rect.Dy(), g.Rect.Dx()))
+	copy(out.Data().([]uint8), g.Pix)
+	return out
+}
+
+func square(w, h, x0, y0, size int) types.Tensor {
+	out := tensor.New(types.UINT8, tensor.NewShape(h, w))
+	data := out.Data().([]uint8)
+	for i := range data {
+		data[i] = 30
+	}
+	for y := y0; y < y0+size; y++ {
+		for x := x0; x < x0+size; x++ {
+			data[y*w+x] = 200
+		}
+	}
+	return out
+}
+
+func TestFAST(t *testing.T) {
+	img := square(40, 40, 10, 12, 15)
+	all, err := FAST(img, 20, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kps, err := FAST(img, 20, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kps) != 4 || len(all) <= len(kps) {
+		t.Fatalf("%d corners, %d before suppression: %v", len(kps), len(all), kps)
+	}
+	// the responses of a hard edged square saturate around its corners, so
+	// the survivors may sit a pixel or two along the edges
+	for _, kp := range kps {
+		nearCorner := false
+		for _, c := range [][2]float32{{10, 12}, {24, 12}, {10, 26}, {24, 26}} {
+			if math.Abs(float64(kp.X-c[0])) <= 2 && math.Abs(float64(kp.Y-c[1])) <= 2 {
+				nearCorner = true
+			}
+		}
+		if !nearCorner {
+			t.Errorf("corner %v is not at the square corners", kp)
+		}
+		if kp.Response <= 20 {
+			t.Errorf("response %v", kp.Response)
+		}
+	}
+
+	if kps, _ := FAST(img, 200, true); len(kps) != 0 {
+		t.Errorf("%d corners above the contrast", len(kps))
+	}
+	if _, err := FAST(tensor.New(types.UINT8, tensor.NewShape(4, 4, 3)), 20, true); err == nil {
+		t.Error("color image accepted")
+	}
+}
+
+func TestHamming(t *testing.T) {
+	var a, b Descriptor
+	b[0], b[31] = 0x0f, 0x80
+	if d := Hamming(&a, &b); d != 5 {
+		t.Errorf("Hamming = %d, want 5", d)
+	}
+	va, vb := a.Vector(), b.Vector()
+	if d := va.DistanceSqr(vb); d != 5 {
+		t.Errorf("unpacked squared distance = %v, want 5", d)
+	}
+	if !b.Bit(255) || b.Bit(254) {
+		t.Error("Bit")
+	}
+}
+
+// TestORBRotation matches the scene against a rotated and shrunk copy and
+// recovers the transform with RANSAC.
+func TestORBRotation(t *testing.T) {
+	scene := readScene(t)
+	h, w := scene.Shape()[0], scene.Shape()[1]
+
+	// rotate by 90 degrees clockwise on screen: (x, y) -> (h-1-y, x)
+	rotated := tensor.New(types.UINT8, tensor.NewShape(w, h))
+	src, dst := scene.Data().([]uint8), rotated.Data().([]uint8)
+	for y := range h {
+		for x := range w {
+			dst[x*h+(h-1-y)] = src[y*w+x]
+		}
+	}
+	const scale = 0.75
+	moved, err := imgproc.Resize(rotated, imgproc.HWC, int(float64(h)*scale), int(float64(w)*scale), imgproc.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform := func(p vec.Vector2D) vec.Vector2D {
+		x, y := float64(h-1)-float64(p[1]), float64(p[0])
+		return vec.Vector2D{float32((x+0.5)*scale - 0.5), float32((y+0.5)*scale - 0.5)}
+	}
+
+	orb := NewORB(WithFeatures(400))
+	kp1, d1, err := orb.DetectAndCompute(scene)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kp2, d2, err := orb.DetectAndCompute(moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kp1) < 200 || len(kp1) > 400 || len(d1) != len(kp1) || len(d2) != len(kp2) {
+		t.Fatalf("%d keypoints, %d descriptors", len(kp1), len(d1))
+	}
+	octaves := map[int]bool{}
+	for _, kp := range kp1 {
+		octaves[kp.Octave] = true
+	}
+	if len(octaves) < 3 {
+		t.Errorf("keypoints on %d pyramid levels", len(octaves))
+	}
+
+	matches := NewBruteForce(d2).Match(d1, true)
+	var p1, p2 []vec.Vector2D
+	correct, turned := 0, 0
+	for _, m := range matches {
+		a, b := kp1[m.Query], kp2[m.Train]
+		p1 = append(p1, vec.Vector2D{a.X, a.Y})
+		p2 = append(p2, vec.Vector2D{b.X, b.Y})
+		if transform(vec.Vector2D{a.X, a.Y}).Distance(vec.Vector2D{b.X, b.Y}) < 3 {
+			correct++
+			// the orientation turns with the image
+			turn := math.Remainder(float64(b.Angle-a.Angle)-math.Pi/2, 2*math.Pi)
+			if math.Abs(turn) > 0.5 {
+				turned++
+			}
+		}
+	}
+	if correct < 40 || correct < len(matches)/2 {
+		t.Fatalf("%d of %d cross-checked matches are correct", correct, len(matches))
+	}
+	if turned > correct/10 {
+		t.Errorf("%d of %d keypoint orientations did not turn with the image", turned, correct)
+	}
+
+	homography, mask, err := geometry.FindHomography(p1, p2, geometry.WithThreshold(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inliers := 0
+	for _, in := range mask {
+		if in {
+			inliers++
+		}
+	}
+	// check inside the textured part of the scene the matches cover
+	for _, p := range []vec.Vector2D{{60, 60}, {160, 100}, {220, 140}} {
+		got, want := geometry.PerspectiveTransform(homography, p), transform(p)
+		if got.Distance(want) > 2 {
+			t.Errorf("%v maps to %v, want %v (%d inliers)", p, got, want, inliers)
+		}
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	train := make([]Descriptor, 300)
+	for i := range train {
+		r.Read(train[i][:])
+	}
+	// noisy copies of the first 100 train descriptors
+	query := make([]Descriptor, 100)
+	for i := range query {
+		query[i] = train[i]
+		for range 20 {
+			b := r.Intn(DescriptorBits)
+			query[i][b>>3] ^= 1 << (b & 7)
+		}
+	}
+
+	bf := NewBruteForce(train)
+	knn := bf.KnnMatch(query, 2)
+	for i, m := range knn {
+		if len(m) != 2 || m[0].Train != i || m[0].Distance > m[1].Distance {
+			t.Fatalf("query %d: %v", i, m)
+		}
+	}
+	if n := len(RatioTest(knn, 0.8)); n != 100 {
+		t.Errorf("ratio test kept %d of 100", n)
+	}
+	if n := len(bf.Match(query, true)); n != 100 {
+		t.Errorf("cross check kept %d of 100", n)
+	}
+
+	found := 0
+	for i, m := range NewLSH(train).KnnMatch(query, 1) {
+		if len(m) == 1 && m[0].Train == i {
+			found++
+		}
+	}
+	if found < 90 {
+		t.Errorf("LSH found %d of 100", found)
+	}
+
+	// float descriptors through the k-d tree
+	points := make([]vec.Vector, 500)
+	for i := range points {
+		points[i] = vec.NewFrom(r.Float32(), r.Float32(), r.Float32())
+	}
+	queries := []vec.Vector{vec.NewFrom(0.5, 0.5, 0.5), vec.NewFrom(0.1, 0.9, 0.2)}
+	for _, m := range NewKDTree(points).Match(queries) {
+		best := 0
+		for i, p := range points {
+			if queries[m.Query].Distance(p) < queries[m.Query].Distance(points[best]) {
+				best = i
+			}
+		}
+		if m.Train != best {
+			t.Errorf("query %d matched %d, want %d", m.Query, m.Train, best)
+		}
+	}
+}
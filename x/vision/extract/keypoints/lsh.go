@@ -0,0 +1,134 @@
+package keypoints
+
+import (
+	"math/rand"
+)
+
+// LSH indexes binary descriptors in hash tables keyed by random subsets of
+// their bits (locality sensitive hashing, as FLANN does for ORB). Only the
+// train descriptors sharing a bucket with the query, or a bucket up to the
+// probe level bits away, are compared, so matching is approximate.
+type LSH struct {
+	train  []Descriptor
+	tables []lshTable
+
+	tableCount int
+	keyBits    int
+	probeLevel int
+	seed       int64
+}
+
+type lshTable struct {
+	bits    []int
+	buckets map[uint32][]int
+}
+
+var _ Matcher = (*LSH)(nil)
+
+// LSHOption configures an LSH index.
+type LSHOption func(*LSH)
+
+// WithTables sets the number of hash tables (default: 6).
+func WithTables(n int) LSHOption {
+	return func(l *LSH) {
+		if n <= 0 {
+			panic("keypoints: number of LSH tables must be positive")
+		}
+		l.tableCount = n
+	}
+}
+
+// WithKeyBits sets the number of descriptor bits hashed per table, 1-32
+// (default: 12).
+func WithKeyBits(n int) LSHOption {
+	return func(l *LSH) {
+		if n <= 0 || n > 32 {
+			panic("keypoints: LSH key bits must be 1-32")
+		}
+		l.keyBits = n
+	}
+}
+
+// WithProbeLevel also probes the buckets whose keys differ in up to n bits
+// from the query key, trading speed for recall (default: 1).
+func WithProbeLevel(n int) LSHOption {
+	return func(l *LSH) {
+		l.probeLevel = min(max(n, 0), 2)
+	}
+}
+
+// WithSeed seeds the choice of hashed bits (default: 1).
+func WithSeed(seed int64) LSHOption {
+	return func(l *LSH) {
+		l.seed = seed
+	}
+}
+
+// NewLSH indexes train.
+func NewLSH(train []Descriptor, opts ...LSHOption) *LSH {
+	l := &LSH{train: train, tableCount: 6, keyBits: 12, probeLevel: 1, seed: 1}
+	for _, opt := range opts {
+		opt(l)
+	}
+	r := rand.New(rand.NewSource(l.seed))
+	l.tables = make([]lshTable, l.tableCount)
+	for i := range l.tables {
+		t := &l.tables[i]
+		t.bits = r.Perm(DescriptorBits)[:l.keyBits]
+		t.buckets = make(map[uint32][]int)
+		for j := range train {
+			key := t.key(&train[j])
+			t.buckets[key] = append(t.buckets[key], j)
+		}
+	}
+	return l
+}
+
+func (t *lshTable) key(d *Descriptor) uint32 {
+	var key uint32
+	for i, b := range t.bits {
+		if d.Bit(b) {
+			key |= 1 << i
+		}
+	}
+	return key
+}
+
+// KnnMatch implements Matcher. Queries without candidates get no matches.
+func (l *LSH) KnnMatch(query []Descriptor, k int) [][]Match {
+	out := make([][]Match, len(query))
+	if k <= 0 {
+		return out
+	}
+	seen := make(map[int]bool)
+	for q := range query {
+		clear(seen)
+		best := make([]Match, 0, k+1)
+		visit := func(bucket []int) {
+			for _, t := range bucket {
+				if seen[t] {
+					continue
+				}
+				seen[t] = true
+				best = insertMatch(best, Match{Query: q, Train: t, Distance: float32(Hamming(&query[q], &l.train[t]))}, k)
+			}
+		}
+		for i := range l.tables {
+			t := &l.tables[i]
+			key := t.key(&query[q])
+			visit(t.buckets[key])
+			if l.probeLevel >= 1 {
+				for a := range l.keyBits {
+					visit(t.buckets[key^1<<a])
+					if l.probeLevel >= 2 {
+						for b := a + 1; b < l.keyBits; b++ {
+							visit(t.buckets[key^1<<a^1<<b])
+						}
+					}
+				}
+			}
+		}
+		out[q] = best
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package keypoints
+
+import (
+	"slices"
+)
+
+// Match pairs a query descriptor with a train descriptor.
+type Match struct {
+	// Query and Train index the matched descriptors.
+	Query, Train int
+	// Distance is the Hamming distance for binary descriptors and the
+	// Euclidean distance for vectors.
+	Distance float32
+}
+
+// Matcher finds the k nearest train descriptors of every query descriptor,
+// closest first.
+type Matcher interface {
+	KnnMatch(query []Descriptor, k int) [][]Match
+}
+
+// BruteForce compares every query descriptor with every train descriptor
+// by Hamming distance.
+type BruteForce struct {
+	train []Descriptor
+}
+
+var _ Matcher = (*BruteForce)(nil)
+
+// NewBruteForce creates an exhaustive matcher over train.
+func NewBruteForce(train []Descriptor) *BruteForce {
+	return &BruteForce{train: train}
+}
+
+// KnnMatch implements Matcher.
+func (m *BruteForce) KnnMatch(query []Descriptor, k int) [][]Match {
+	out := make([][]Match, len(query))
+	if k <= 0 {
+		return out
+	}
+	for q := range query {
+		best := make([]Match, 0, k+1)
+		for t := range m.train {
+			best = insertMatch(best, Match{Query: q, Train: t, Distance: float32(Hamming(&query[q], &m.train[t]))}, k)
+		}
+		out[q] = best
+	}
+	return out
+}
+
+// Match returns the nearest train descriptor of every query descriptor.
+// With crossCheck set, a pair is kept only when the query descriptor is
+// also the nearest one of its train descriptor.
+func (m *BruteForce) Match(query []Descriptor, crossCheck bool) []Match {
+	matches := firstMatches(m.KnnMatch(query, 1))
+	if !crossCheck {
+		return matches
+	}
+	back := firstMatches(NewBruteForce(query).KnnMatch(m.train, 1))
+	nearest := make(map[int]int, len(back))
+	for _, b := range back {
+		nearest[b.Query] = b.Train
+	}
+	return slices.DeleteFunc(matches, func(mt Match) bool {
+		q, ok := nearest[mt.Train]
+		return !ok || q != mt.Query
+	})
+}
+
+// RatioTest keeps the nearest match of every query whose distance is below
+// ratio times the distance of the second nearest (Lowe's test; 0.7-0.8 is
+// typical). Queries with a single candidate pass.
+func RatioTest(knn [][]Match, ratio float32) []Match {
+	var out []Match
+	for _, m := range knn {
+		switch {
+		case len(m) == 0:
+		case len(m) == 1 || m[0].Distance < ratio*m[1].Distance:
+			out = append(out, m[0])
+		}
+	}
+	return out
+}
+
+func firstMatches(knn [][]Match) []Match {
+	out := make([]Match, 0, len(knn))
+	for _, m := range knn {
+		if len(m) > 0 {
+			out = append(out, m[0])
+		}
+	}
+	return out
+}
+
+// insertMatch adds m to best, sorted by distance and capped at k.
+func insertMatch(best []Match, m Match, k int) []Match {
+	if len(best) == k && m.Distance >= best[k-1].Distance {
+		return best
+	}
+	i, _ := slices.BinarySearchFunc(best, m.Distance, func(b Match, d float32) int {
+		if b.Distance <= d {
+			return -1
+		}
+		return 1
+	})
+	best = slices.Insert(best, i, m)
+	if len(best) > k {
+		best = best[:k]
+	}
+	return best
+}
@@ -0,0 +1,254 @@
+package keypoints
+
+import (
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// ORB detects oriented FAST corners on an image pyramid and describes them
+// with rotated BRIEF (Rublee et al. 2011).
+type ORB struct {
+	features      int
+	scaleFactor   float32
+	levels        int
+	edgeThreshold int
+	fastThreshold float32
+}
+
+// Option configures an ORB detector.
+type Option func(*ORB)
+
+// WithFeatures sets the maximum number of keypoints (default: 500).
+func WithFeatures(n int) Option {
+	return func(o *ORB) {
+		if n <= 0 {
+			panic("keypoints: number of features must be positive")
+		}
+		o.features = n
+	}
+}
+
+// WithScaleFactor sets the scale between pyramid levels (default: 1.2).
+func WithScaleFactor(f float32) Option {
+	return func(o *ORB) {
+		if f <= 1 {
+			panic("keypoints: scale factor must be above 1")
+		}
+		o.scaleFactor = f
+	}
+}
+
+// WithLevels sets the number of pyramid levels (default: 8).
+func WithLevels(n int) Option {
+	return func(o *ORB) {
+		if n <= 0 {
+			panic("keypoints: number of levels must be positive")
+		}
+		o.levels = n
+	}
+}
+
+// WithEdgeThreshold sets the border in pixels where no keypoints are
+// detected (default: 31). It is raised to the patch radius plus one if
+// smaller.
+func WithEdgeThreshold(n int) Option {
+	return func(o *ORB) {
+		o.edgeThreshold = max(n, patchRadius+1)
+	}
+}
+
+// WithFastThreshold sets the FAST intensity threshold (default: 20).
+func WithFastThreshold(t float32) Option {
+	return func(o *ORB) {
+		o.fastThreshold = t
+	}
+}
+
+// NewORB creates an ORB detector.
+func NewORB(opts ...Option) *ORB {
+	o := &ORB{
+		features:      500,
+		scaleFactor:   1.2,
+		levels:        8,
+		edgeThreshold: 31,
+		fastThreshold: 20,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Detect finds up to the configured number of keypoints.
+func (o *ORB) Detect(img types.Tensor) ([]KeyPoint, error) {
+	kps, _, err := o.detect(img, false)
+	return kps, err
+}
+
+// DetectAndCompute finds keypoints and their descriptors.
+func (o *ORB) DetectAndCompute(img types.Tensor) ([]KeyPoint, []Descriptor, error) {
+	return o.detect(img, true)
+}
+
+// level is one pyramid image and its scale relative to level 0.
+type level struct {
+	img   gray.Image
+	scale float32
+}
+
+// pyramid downsamples img by scaleFactor per level, stopping early when a
+// level gets too small to hold a keypoint.
+func (o *ORB) pyramid(img types.Tensor) ([]level, error) {
+	g, err := gray.FromTensor(img)
+	if err != nil {
+		return nil, err
+	}
+	levels := []level{{img: g, scale: 1}}
+	for i := 1; i < o.levels; i++ {
+		scale := float32(math.Pow(float64(o.scaleFactor), float64(i)))
+		w := int(math.Round(float64(float32(g.W) / scale)))
+		h := int(math.Round(float64(float32(g.H) / scale)))
+		if w <= 2*o.edgeThreshold || h <= 2*o.edgeThreshold {
+			break
+		}
+		prev := levels[len(levels)-1].img
+		resized, err := imgproc.Resize(prev.Tensor(), imgproc.HWC, w, h, imgproc.Bilinear)
+		if err != nil {
+			return nil, err
+		}
+		next, err := gray.FromTensor(resized)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, level{img: next, scale: scale})
+	}
+	return levels, nil
+}
+
+func (o *ORB) detect(img types.Tensor, compute bool) ([]KeyPoint, []Descriptor, error) {
+	levels, err := o.pyramid(img)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// distribute the features over the levels proportionally to their area
+	factor := 1 / float64(o.scaleFactor)
+	perLevel := make([]int, len(levels))
+	desired := float64(o.features) * (1 - factor) / (1 - math.Pow(factor, float64(len(levels))))
+	total := 0
+	for i := range len(levels) - 1 {
+		perLevel[i] = int(math.Round(desired))
+		total += perLevel[i]
+		desired *= factor
+	}
+	perLevel[len(levels)-1] = max(o.features-total, 0)
+
+	var keypoints []KeyPoint
+	var descriptors []Descriptor
+	base := levels[0].img
+	for i, l := range levels {
+		// map pixel centres back to level 0 by the actual resize ratio
+		sx := float32(base.W) / float32(l.img.W)
+		sy := float32(base.H) / float32(l.img.H)
+		kps := fast(l.img, o.fastThreshold, true, o.edgeThreshold)
+		kps = retainBest(kps, 2*perLevel[i])
+		for j := range kps {
+			kps[j].Response = harris(l.img, int(kps[j].X), int(kps[j].Y))
+		}
+		kps = retainBest(kps, perLevel[i])
+
+		var smooth gray.Image
+		if compute && len(kps) > 0 {
+			blurred, err := imgproc.GaussianBlur(l.img.Tensor(), imgproc.HWC, 7, 2)
+			if err != nil {
+				return nil, nil, err
+			}
+			if smooth, err = gray.FromTensor(blurred); err != nil {
+				return nil, nil, err
+			}
+		}
+		for _, kp := range kps {
+			x, y := int(kp.X), int(kp.Y)
+			kp.Angle = orientation(l.img, x, y)
+			if compute {
+				descriptors = append(descriptors, describe(smooth, x, y, kp.Angle))
+			}
+			kp.X, kp.Y = (kp.X+0.5)*sx-0.5, (kp.Y+0.5)*sy-0.5
+			kp.Size = float32(2*patchRadius+1) * l.scale
+			kp.Octave = i
+			keypoints = append(keypoints, kp)
+		}
+	}
+	return keypoints, descriptors, nil
+}
+
+// retainBest keeps the n strongest keypoints, in their original order.
+func retainBest(kps []KeyPoint, n int) []KeyPoint {
+	if len(kps) <= n {
+		return kps
+	}
+	order := make([]int, len(kps))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case kps[a].Response > kps[b].Response:
+			return -1
+		case kps[a].Response < kps[b].Response:
+			return 1
+		}
+		return 0
+	})
+	order = order[:n]
+	slices.Sort(order)
+	out := make([]KeyPoint, n)
+	for i, j := range order {
+		out[i] = kps[j]
+	}
+	return out
+}
+
+// harrisBlock is the window of the Harris corner response.
+const harrisBlock = 7
+
+// harris returns the Harris corner response det(M) - 0.04 trace(M)^2 of the
+// structure tensor M over a harrisBlock window, from Sobel gradients.
+func harris(g gray.Image, x, y int) float32 {
+	r := harrisBlock / 2
+	var a, b, c float64
+	for v := y - r; v <= y+r; v++ {
+		for u := x - r; u <= x+r; u++ {
+			dx := float64(g.At(u+1, v-1) + 2*g.At(u+1, v) + g.At(u+1, v+1) -
+				g.At(u-1, v-1) - 2*g.At(u-1, v) - g.At(u-1, v+1))
+			dy := float64(g.At(u-1, v+1) + 2*g.At(u, v+1) + g.At(u+1, v+1) -
+				g.At(u-1, v-1) - 2*g.At(u, v-1) - g.At(u+1, v-1))
+			a += dx * dx
+			b += dy * dy
+			c += dx * dy
+		}
+	}
+	// normalise as OpenCV: 1 / (4 * block * 255)
+	scale := 1 / (4.0 * harrisBlock * 255)
+	a, b, c = a*scale*scale, b*scale*scale, c*scale*scale
+	return float32(a*b - c*c - 0.04*(a+b)*(a+b))
+}
+
+// orientation returns the direction from (x, y) to the intensity centroid of
+// the circular patch around it.
+func orientation(g gray.Image, x, y int) float32 {
+	var m01, m10 float32
+	for v := -patchRadius; v <= patchRadius; v++ {
+		span := int(math.Sqrt(float64(patchRadius*patchRadius - v*v)))
+		for u := -span; u <= span; u++ {
+			p := g.At(x+u, y+v)
+			m10 += float32(u) * p
+			m01 += float32(v) * p
+		}
+	}
+	return float32(math.Atan2(float64(m01), float64(m10)))
+}
@@ -0,0 +1,77 @@
+package tags
+
+import (
+	"github.com/itohio/EasyRobot/x/options"
+	"github.com/itohio/EasyRobot/x/store"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+	"github.com/itohio/EasyRobot/x/vision/fiducial"
+)
+
+type Options struct {
+	src           store.FQDNType
+	dst           store.FQDNType
+	dictionaries  []*fiducial.Dictionary
+	maxCorrection int
+	path          string
+	camera        *calib.Camera
+	tagSize       float32
+}
+
+// WithKey sets the key of the image and the key the detections are
+// stored at.
+func WithKey(src, dst store.FQDNType) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.src = src
+			opt.dst = dst
+		}
+	}
+}
+
+// WithDictionary sets the tag dictionaries, tried in order (default:
+// fiducial.Tag36h11).
+func WithDictionary(d ...*fiducial.Dictionary) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.dictionaries = d
+		}
+	}
+}
+
+// WithMaxCorrection sets the most bit errors corrected per tag.
+func WithMaxCorrection(n int) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.maxCorrection = n
+		}
+	}
+}
+
+// WithCalibration loads the camera from a file written by cmd/calib_mono
+// to estimate tag poses.
+func WithCalibration(path string) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.path = path
+		}
+	}
+}
+
+// WithCamera estimates tag poses with a calibrated camera instead of a file.
+func WithCamera(c *calib.Camera) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.camera = c
+		}
+	}
+}
+
+// WithTagSize sets the side of the tag borders in the units of the pose
+// translations. Poses are estimated when it is set along with a camera.
+func WithTagSize(size float32) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.tagSize = size
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package tags provides a pipeline step that detects AprilTag and ArUco
+// style fiducial tags in tensor images with package fiducial and stores
+// them as []fiducial.Detection. With a camera calibration and the tag size
+// the detections carry the pose of every tag in the camera frame.
+package tags
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/options"
+	"github.com/itohio/EasyRobot/x/pipeline"
+	"github.com/itohio/EasyRobot/x/pipeline/steps"
+	"github.com/itohio/EasyRobot/x/store"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+	"github.com/itohio/EasyRobot/x/vision/fiducial"
+)
+
+const NAME = "tags"
+
+func init() {
+	pipeline.Register(NAME, NewTags)
+}
+
+// NewTags returns a step that detects tags in the image.
+func NewTags(opts ...options.Option) (pipeline.Step, error) {
+	d, err := NewDetector(opts...)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	newOpts := opts
+	newOpts = append(newOpts, steps.WithNamedProcessorFunc(NAME, detect(d, o.src, o.dst)))
+	return steps.NewProcessor(newOpts...)
+}
+
+// NewDetector creates the tag detector configured by the options, loading
+// the camera calibration if one is given.
+func NewDetector(opts ...options.Option) (*fiducial.Detector, error) {
+	o := newOptions(opts)
+	var fopts []fiducial.Option
+	if len(o.dictionaries) > 0 {
+		fopts = append(fopts, fiducial.WithDictionary(o.dictionaries...))
+	}
+	if o.maxCorrection >= 0 {
+		fopts = append(fopts, fiducial.WithMaxCorrection(o.maxCorrection))
+	}
+
+	cam := o.camera
+	if cam == nil && o.path != "" {
+		var f calib.MonoFile
+		if err := calib.Load(o.path, &f); err != nil {
+			return nil, err
+		}
+		var err error
+		if cam, err = f.Camera(); err != nil {
+			return nil, err
+		}
+	}
+	if cam != nil {
+		if o.tagSize <= 0 {
+			return nil, fmt.Errorf("tags: poses need the tag size")
+		}
+		fopts = append(fopts, fiducial.WithCamera(cam, o.tagSize))
+	}
+	return fiducial.NewDetector(fopts...), nil
+}
+
+func detect(d *fiducial.Detector, srcKey, dstKey store.FQDNType) func(src, dst store.Store) error {
+	return func(src, dst store.Store) error {
+		val, ok := src.Get(srcKey)
+		if !ok {
+			return nil
+		}
+
+		img, ok := val.(types.Tensor)
+		if !ok {
+			return nil
+		}
+
+		dets, err := d.Detect(img)
+		if err != nil {
+			return err
+		}
+
+		dst.Set(dstKey, dets)
+
+		return nil
+	}
+}
+
+func newOptions(opts []options.Option) Options {
+	o := Options{
+		src:           store.IMAGE,
+		dst:           store.FIDUCIALS,
+		maxCorrection: -1,
+	}
+	options.ApplyOptions(&o, opts...)
+	return o
+}
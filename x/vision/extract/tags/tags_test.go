@@ -0,0 +1,52 @@
+package tags
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/store"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+	"github.com/itohio/EasyRobot/x/vision/fiducial"
+)
+
+func TestTags(t *testing.T) {
+	img, err := fiducial.Tag25h9.Render(4, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 0.1 m tag is 84 px wide, 0.092 m away from a camera of 77 px
+	// focal length
+	cam := calib.Camera{Width: 108, Height: 108, Fx: 77, Fy: 77, Cx: 53.5, Cy: 53.5, Distortion: make([]float64, 5)}
+	path := filepath.Join(t.TempDir(), "mono.yaml")
+	if err := calib.Save(path, (&calib.Result{Camera: cam}).File(9, 6)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTags(WithCalibration(path), WithDictionary(fiducial.Tag25h9), WithTagSize(0.1)); err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDetector(WithCalibration(path), WithDictionary(fiducial.Tag25h9), WithTagSize(0.1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in, out := store.New(), store.New()
+	in.Set(store.IMAGE, img)
+	if err := detect(d, store.IMAGE, store.FIDUCIALS)(in, out); err != nil {
+		t.Fatal(err)
+	}
+	v, ok := out.Get(store.FIDUCIALS)
+	if !ok {
+		t.Fatal("no detections")
+	}
+	dets := v.([]fiducial.Detection)
+	if len(dets) != 1 || dets[0].ID != 4 || !dets[0].HasPose {
+		t.Fatalf("detected %+v", dets)
+	}
+	if z := dets[0].Transform[2][3]; z < 0.09 || z > 0.1 {
+		t.Errorf("tag at %v m", z)
+	}
+
+	if _, err := NewTags(WithCamera(&cam)); err == nil {
+		t.Error("poses without a tag size accepted")
+	}
+}
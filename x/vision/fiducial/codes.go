@@ -0,0 +1,29 @@
+package fiducial
+
+// The AprilTag code tables, converted from the bit order of the AprilTag 3
+// library (tagXXhXX.c) to the raster order of Dictionary.
+
+// tag36h11Codes are ids 0 to 65 of the 587 tag36h11 codes.
+var tag36h11Codes = []uint64{
+	0xd5d628584, 0xd97f18b49, 0xdd280910e, 0xe479e9c98, 0xebcbca822, 0xf31dab3ac,
+	0x056a5d085, 0x10652e1d4, 0x22b1dfead, 0x265ad0472, 0x34fe91b86, 0x3ff962cd5,
+	0x43a25329a, 0x474b4385f, 0x4e9d243e9, 0x5246149ae, 0x5997f5538, 0x683bb6c4c,
+	0x6be4a7211, 0x7e3158eea, 0x81da494af, 0x858339a74, 0x8cd51a5fe, 0x9f21cc2d7,
+	0xa2cabc89c, 0xadc58d9eb, 0xb16e7dfb0, 0xb8c05eb3a, 0xd25ef139d, 0xd607e1962,
+	0xe4aba3076, 0x2dde6a3da, 0x43d40c678, 0x5620be351, 0x64c47fa65, 0x686d7002a,
+	0x6c16605ef, 0x6fbf50bb4, 0x8d06d39dc, 0x9f53856b5, 0xadf746dc9, 0xbc9b084dd,
+	0xd290aa77b, 0xd9e28b305, 0xe4dd5c454, 0xfad2fe6f2, 0x181a8151a, 0x26be42c2e,
+	0x2e10237b8, 0x405cd5491, 0x7742eab1c, 0x85e6ac230, 0x8d388cdba, 0x9f853ea93,
+	0xc41ea2445, 0xcf1973594, 0x14a34a333, 0x31eacd15b, 0x6c79d2dab, 0x73cbb3935,
+	0x89c155bd3, 0x8d6a46198, 0x91133675d, 0xa708d89fb, 0xae5ab9585, 0xb9558a6d4,
+}
+
+// tag25h9Codes are the 35 tag25h9 codes.
+var tag25h9Codes = []uint64{
+	0x155cbf1, 0x1e4d1b6, 0x17b0b68, 0x1eac9cd, 0x12e14ce, 0x03548bb,
+	0x07757e6, 0x1065dab, 0x1baa2e7, 0x0dea688, 0x081d927, 0x051b241,
+	0x0dbc8ae, 0x1e50e19, 0x15819d2, 0x16d8282, 0x163e035, 0x09d9b81,
+	0x173eec4, 0x0ae3a09, 0x05f7c51, 0x1a137fc, 0x0dc9562, 0x1802e45,
+	0x1c3542c, 0x0870fa4, 0x0914709, 0x16684f0, 0x0c8f2a5, 0x0833ebb,
+	0x059717f, 0x13cd050, 0x0fa0ad1, 0x1b763b0, 0x0b991ce,
+}
@@ -0,0 +1,300 @@
+package fiducial
+
+import (
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+	"github.com/itohio/EasyRobot/x/vision/geometry"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// Detection is a decoded tag.
+type Detection struct {
+	// Dictionary is the name of the dictionary of the tag.
+	Dictionary string
+	// ID is the index of the tag code in its dictionary.
+	ID int
+	// Corners are the outer corners of the tag border in the image,
+	// clockwise from the top left of the tag as printed.
+	Corners [4]vec.Vector2D
+	// Errors is the number of corrected bits.
+	Errors int
+	// Transform maps tag coordinates to camera coordinates, the pose of the
+	// tag in the camera frame. The tag frame has its origin in the centre of
+	// the tag, x to the right, y up and z out of the tag, as OpenCV ArUco
+	// poses. It is set when HasPose is.
+	Transform mat.Matrix4x4
+	// HasPose reports whether the detector has a camera to estimate the pose.
+	HasPose bool
+}
+
+// Detector finds tags of one or more dictionaries in images.
+type Detector struct {
+	dictionaries  []*Dictionary
+	windows       []int
+	minPerimeter  float64
+	maxCorrection int
+	camera        *calib.Camera
+	tagSize       float32
+}
+
+// Option configures a Detector.
+type Option func(*Detector)
+
+// WithDictionary sets the dictionaries to decode, tried in order (default:
+// Tag36h11).
+func WithDictionary(d ...*Dictionary) Option {
+	return func(det *Detector) {
+		if len(d) == 0 {
+			panic("fiducial: no dictionary")
+		}
+		det.dictionaries = d
+	}
+}
+
+// WithThresholdWindows sets the odd window sizes of the adaptive threshold
+// the quads are searched in (default: 7, 15 and 23 pixels). Large windows
+// find big tags and tags in uneven light, small ones keep close tags apart.
+func WithThresholdWindows(sizes ...int) Option {
+	return func(det *Detector) {
+		for _, s := range sizes {
+			if s < 3 || s%2 == 0 {
+				panic("fiducial: threshold windows must be odd and at least 3")
+			}
+		}
+		det.windows = sizes
+	}
+}
+
+// WithMinPerimeter sets the smallest perimeter of a tag in pixels
+// (default: 40).
+func WithMinPerimeter(px float64) Option {
+	return func(det *Detector) {
+		det.minPerimeter = px
+	}
+}
+
+// WithMaxCorrection sets the most bit errors corrected (default: 2, at most
+// the Correction of the dictionary). Fewer corrections give fewer false
+// detections.
+func WithMaxCorrection(n int) Option {
+	return func(det *Detector) {
+		det.maxCorrection = n
+	}
+}
+
+// WithCamera enables pose estimation for tags of the given outer border
+// side length, in the units of the resulting translations.
+func WithCamera(c *calib.Camera, tagSize float32) Option {
+	return func(det *Detector) {
+		if tagSize <= 0 {
+			panic("fiducial: tag size must be positive")
+		}
+		det.camera, det.tagSize = c, tagSize
+	}
+}
+
+// NewDetector creates a tag detector.
+func NewDetector(opts ...Option) *Detector {
+	d := &Detector{
+		dictionaries:  []*Dictionary{Tag36h11},
+		windows:       []int{7, 15, 23},
+		minPerimeter:  40,
+		maxCorrection: 2,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Detect returns the tags found in an image, sorted by dictionary and id.
+func (d *Detector) Detect(img types.Tensor) ([]Detection, error) {
+	g, err := gray.FromTensor(img)
+	if err != nil {
+		return nil, err
+	}
+	var out []Detection
+	for _, w := range d.windows {
+		quads, err := quads(g, w, d.minPerimeter)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range quads {
+			det, ok := d.decode(g, q)
+			if ok && !duplicate(out, det) {
+				out = append(out, det)
+			}
+		}
+	}
+	order := make(map[string]int, len(d.dictionaries))
+	for i, dict := range d.dictionaries {
+		order[dict.Name] = i
+	}
+	slices.SortStableFunc(out, func(a, b Detection) int {
+		if order[a.Dictionary] != order[b.Dictionary] {
+			return order[a.Dictionary] - order[b.Dictionary]
+		}
+		return a.ID - b.ID
+	})
+
+	if d.camera == nil {
+		return out, nil
+	}
+	for i := range out {
+		t, err := EstimatePose(d.camera, out[i].Corners, d.tagSize)
+		if err != nil {
+			continue
+		}
+		out[i].Transform, out[i].HasPose = t, true
+	}
+	return out, nil
+}
+
+// decode refines a quad and reads it with every dictionary.
+func (d *Detector) decode(g gray.Image, q quad) (Detection, bool) {
+	side := 0.0
+	for i := range 4 {
+		side += dist(q[i], q[(i+1)%4]) / 4
+	}
+	for _, dict := range d.dictionaries {
+		n := dict.Size + 2
+		refined, ok := refine(g, q, side/float64(n))
+		if !ok {
+			continue
+		}
+		code, ok := read(g, refined, dict)
+		if !ok {
+			continue
+		}
+		id, turns, errs, ok := dict.Match(code, min(d.maxCorrection, dict.Correction()))
+		if !ok {
+			continue
+		}
+		// the top left of the tag is seen at the corner it was turned to
+		det := Detection{Dictionary: dict.Name, ID: id, Errors: errs}
+		for i := range 4 {
+			p := refined[(i+turns)%4]
+			det.Corners[i] = vec.Vector2D{float32(p[0]), float32(p[1])}
+		}
+		return det, true
+	}
+	return Detection{}, false
+}
+
+// minContrast is the least difference between the mean intensities of the
+// dark and bright bits of a tag.
+const minContrast = 20
+
+// read samples the bits of a quad through its homography. The bits are
+// split into dark and bright by Otsu's threshold; at most one in eight
+// border bits may be bright.
+func read(g gray.Image, q quad, dict *Dictionary) (uint64, bool) {
+	n := dict.Size + 2
+	dst := make([]vec.Vector2D, 4)
+	for i, p := range q {
+		dst[i] = vec.Vector2D{float32(p[0]), float32(p[1])}
+	}
+	h, err := geometry.Homography([]vec.Vector2D{{0, 0}, {float32(n), 0}, {float32(n), float32(n)}, {0, float32(n)}}, dst)
+	if err != nil {
+		return 0, false
+	}
+	values := make([]float64, n*n)
+	for y := range n {
+		for x := range n {
+			var sum float64
+			for _, dy := range [3]float32{0.3, 0.5, 0.7} {
+				for _, dx := range [3]float32{0.3, 0.5, 0.7} {
+					p := geometry.PerspectiveTransform(h, vec.Vector2D{float32(x) + dx, float32(y) + dy})
+					sum += g.Sample(float64(p[0]), float64(p[1]))
+				}
+			}
+			values[y*n+x] = sum / 9
+		}
+	}
+	threshold, contrast := otsu(values)
+	if contrast < minContrast {
+		return 0, false
+	}
+
+	var code uint64
+	bright := 0
+	for y := range n {
+		for x := range n {
+			white := values[y*n+x] > threshold
+			if x == 0 || y == 0 || x == n-1 || y == n-1 {
+				if white {
+					bright++
+				}
+				continue
+			}
+			code <<= 1
+			if white {
+				code |= 1
+			}
+		}
+	}
+	return code, bright <= 4*(n-1)/8
+}
+
+// otsu splits values into two classes of the largest between-class
+// variance and returns the threshold and the difference of the class
+// means.
+func otsu(values []float64) (threshold, contrast float64) {
+	v := slices.Clone(values)
+	slices.Sort(v)
+	var total float64
+	for _, x := range v {
+		total += x
+	}
+	var best, low float64
+	for i := 1; i < len(v); i++ {
+		low += v[i-1]
+		m0 := low / float64(i)
+		m1 := (total - low) / float64(len(v)-i)
+		w0 := float64(i) / float64(len(v))
+		if between := w0 * (1 - w0) * (m1 - m0) * (m1 - m0); between > best {
+			best, threshold, contrast = between, (v[i-1]+v[i])/2, m1-m0
+		}
+	}
+	return threshold, contrast
+}
+
+// duplicate reports whether a tag was already found, at another threshold
+// window.
+func duplicate(found []Detection, det Detection) bool {
+	c := center(det.Corners)
+	for _, f := range found {
+		if f.Dictionary == det.Dictionary && f.ID == det.ID && c.Distance(center(f.Corners)) < f.Corners[0].Distance(f.Corners[2])/4 {
+			return true
+		}
+	}
+	return false
+}
+
+func center(c [4]vec.Vector2D) vec.Vector2D {
+	return vec.Vector2D{(c[0][0] + c[1][0] + c[2][0] + c[3][0]) / 4, (c[0][1] + c[1][1] + c[2][1] + c[3][1]) / 4}
+}
+
+// EstimatePose returns the transform from tag coordinates to camera
+// coordinates of a tag with the given side length from its corners, as in
+// Detection.
+func EstimatePose(cam *calib.Camera, corners [4]vec.Vector2D, size float32) (mat.Matrix4x4, error) {
+	s := size / 2
+	object := []vec.Vector3D{{-s, s, 0}, {s, s, 0}, {s, -s, 0}, {-s, -s, 0}}
+	r, t, err := cam.SolvePnP(object, corners[:])
+	if err != nil {
+		return mat.Matrix4x4{}, err
+	}
+	rot := calib.Rodrigues(r)
+	var m mat.Matrix4x4
+	m.Homogenous(&rot, t)
+	if math.IsNaN(float64(m[0][3])) {
+		return mat.Matrix4x4{}, calib.ErrNotConverged
+	}
+	return m, nil
+}
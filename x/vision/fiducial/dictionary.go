@@ -0,0 +1,164 @@
+package fiducial
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Dictionary is a family of square tags with Size x Size data bits inside a
+// black border one bit wide. A code holds the bits of a tag in raster order
+// from its top left, the most significant bit first, 1 for white.
+type Dictionary struct {
+	// Name identifies the dictionary in logs and files.
+	Name string
+	// Size is the number of data bits along a side.
+	Size int
+	// Codes are the tags by id.
+	Codes []uint64
+	// MinHamming is the least Hamming distance between two codes in any
+	// rotation, or between a code and its own rotations.
+	MinHamming int
+
+	// rotations holds every code turned clockwise 0 to 3 times.
+	rotations [][4]uint64
+}
+
+var (
+	// ArUcoOriginal is the dictionary of the original ArUco library
+	// (cv::aruco::DICT_ARUCO_ORIGINAL): 5x5 bits, 1024 ids. Every row holds
+	// two bits of the id, coded by one of four words.
+	ArUcoOriginal = NewDictionary("aruco_original", 5, arucoCodes())
+	// Tag36h11 is the AprilTag 36h11 family: 6x6 bits and a minimum Hamming
+	// distance of 11. It holds ids 0 to 65 of the 587 published codes; load
+	// the full AprilTag table with NewDictionary to read tags with higher ids.
+	Tag36h11 = NewDictionary("tag36h11", 6, tag36h11Codes)
+	// Tag25h9 is the AprilTag 25h9 family: 5x5 bits, a minimum Hamming
+	// distance of 9 and 35 codes.
+	Tag25h9 = NewDictionary("tag25h9", 5, tag25h9Codes)
+)
+
+// arucoCodes returns the codes of the original ArUco markers.
+func arucoCodes() []uint64 {
+	words := [4]uint64{0x10, 0x17, 0x09, 0x0e}
+	codes := make([]uint64, 1024)
+	for id := range codes {
+		var c uint64
+		for y := range 5 {
+			c = c<<5 | words[(id>>(2*(4-y)))&3]
+		}
+		codes[id] = c
+	}
+	return codes
+}
+
+// NewDictionary creates a dictionary of tags with size x size data bits,
+// e.g. from the published AprilTag or OpenCV code tables converted to this
+// bit order. It panics when a code has more bits than the tag.
+func NewDictionary(name string, size int, codes []uint64) *Dictionary {
+	if size < 2 || size > 8 {
+		panic("fiducial: tags have 2 to 8 data bits along a side")
+	}
+	d := &Dictionary{
+		Name:       name,
+		Size:       size,
+		Codes:      codes,
+		MinHamming: size * size,
+		rotations:  make([][4]uint64, 0, len(codes)),
+	}
+	perm := rotation(size)
+	for _, c := range codes {
+		if c>>(size*size) != 0 {
+			panic(fmt.Sprintf("fiducial: code %#x has more than %d bits", c, size*size))
+		}
+		r := [4]uint64{c}
+		for k := 1; k < 4; k++ {
+			r[k] = rotate(r[k-1], perm)
+			d.MinHamming = min(d.MinHamming, bits.OnesCount64(c^r[k]))
+		}
+		for _, prev := range d.rotations {
+			for _, p := range prev {
+				d.MinHamming = min(d.MinHamming, bits.OnesCount64(c^p))
+			}
+		}
+		d.rotations = append(d.rotations, r)
+	}
+	return d
+}
+
+// Correction returns the number of bit errors the dictionary can correct
+// without ambiguity.
+func (d *Dictionary) Correction() int {
+	return max((d.MinHamming-1)/2, 0)
+}
+
+// Match returns the id of the code closest to the bits read from a tag, the
+// number of clockwise quarter turns of the tag in the image and the number
+// of bit errors. ok is false when every code differs by more than
+// maxErrors bits.
+func (d *Dictionary) Match(code uint64, maxErrors int) (id, turns, errors int, ok bool) {
+	errors = d.Size*d.Size + 1
+	for i, r := range d.rotations {
+		for k, c := range r {
+			if n := bits.OnesCount64(code ^ c); n < errors {
+				id, turns, errors = i, k, n
+			}
+		}
+	}
+	return id, turns, errors, errors <= maxErrors
+}
+
+// bit returns the data bit at column x and row y of a code.
+func (d *Dictionary) bit(code uint64, x, y int) bool {
+	return code>>(d.Size*d.Size-1-(y*d.Size+x))&1 == 1
+}
+
+// Render draws a tag as a UINT8 [H, W] image with cell pixels per bit,
+// surrounded by a white quiet zone one bit wide.
+func (d *Dictionary) Render(id, cell int) (types.Tensor, error) {
+	if id < 0 || id >= len(d.Codes) {
+		return nil, fmt.Errorf("fiducial: %s has no tag %d", d.Name, id)
+	}
+	if cell <= 0 {
+		return nil, fmt.Errorf("fiducial: invalid cell size %d", cell)
+	}
+	n := d.Size + 4
+	img := tensor.New(types.UINT8, tensor.NewShape(n*cell, n*cell))
+	data := img.Data().([]uint8)
+	for y := range n * cell {
+		for x := range n * cell {
+			cx, cy := x/cell-2, y/cell-2
+			white := cx < -1 || cy < -1 || cx > d.Size || cy > d.Size
+			if cx >= 0 && cy >= 0 && cx < d.Size && cy < d.Size {
+				white = d.bit(d.Codes[id], cx, cy)
+			}
+			if white {
+				data[y*n*cell+x] = 255
+			}
+		}
+	}
+	return img, nil
+}
+
+// rotation returns the bit permutation of a clockwise quarter turn of a
+// size x size code.
+func rotation(size int) []int {
+	n := size * size
+	perm := make([]int, n)
+	for y := range size {
+		for x := range size {
+			perm[n-1-(y*size+x)] = n - 1 - (x*size + size - 1 - y)
+		}
+	}
+	return perm
+}
+
+func rotate(code uint64, perm []int) uint64 {
+	var out uint64
+	for from, to := range perm {
+		out |= (code >> uint(from) & 1) << uint(to)
+	}
+	return out
+}
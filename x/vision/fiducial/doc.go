@@ -0,0 +1,10 @@
+// Package fiducial detects square fiducial tags, AprilTag and ArUco style,
+// in plain Go and estimates their 6-DOF pose: dark quads are found in
+// adaptively thresholded images, their corners refined on the edges of the
+// tag border, the bits sampled through the homography of the quad and
+// decoded against a dictionary with Hamming error correction, and the pose
+// solved from the corners with the calibrated camera.
+//
+// Images are single channel UINT8 or FP32 tensors of shape [H, W] or
+// [H, W, 1].
+package fiducial
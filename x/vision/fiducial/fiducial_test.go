@@ -0,0 +1,334 @@
+package fiducial
+
+import (
+	"math"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+)
+
+// view is a tag seen through a homography from tag cells to the image.
+type view struct {
+	dict *Dictionary
+	code uint64
+	hom  [3][3]float64
+}
+
+// brightness returns the view's intensity at an image point, or false
+// outside of the tag and its quiet zone; inv is the inverse homography.
+func (v view) brightness(inv [3][3]float64, x, y float64) (float64, bool) {
+	p := apply(inv, x, y)
+	n := float64(v.dict.Size + 2)
+	if p[0] < -1 || p[1] < -1 || p[0] >= n+1 || p[1] >= n+1 {
+		return 0, false
+	}
+	cx, cy := int(math.Floor(p[0]))-1, int(math.Floor(p[1]))-1
+	switch {
+	case p[0] < 0 || p[1] < 0 || p[0] >= n || p[1] >= n:
+		return 230, true
+	case cx < 0 || cy < 0 || cx >= v.dict.Size || cy >= v.dict.Size:
+		return 25, true
+	case v.dict.bit(v.code, cx, cy):
+		return 230, true
+	}
+	return 25, true
+}
+
+// render draws the views on a mid gray background with 4x4 supersampling.
+func render(w, h int, views ...view) types.Tensor {
+	img := tensor.New(types.UINT8, tensor.NewShape(h, w))
+	data := img.Data().([]uint8)
+	inv := make([][3][3]float64, len(views))
+	for i, v := range views {
+		inv[i] = inverse(v.hom)
+	}
+	for y := range h {
+		for x := range w {
+			var sum float64
+			for sy := range 4 {
+				for sx := range 4 {
+					px, py := float64(x)+(float64(sx)+0.5)/4-0.5, float64(y)+(float64(sy)+0.5)/4-0.5
+					v := 128.0
+					for i, vw := range views {
+						if b, ok := vw.brightness(inv[i], px, py); ok {
+							v = b
+						}
+					}
+					sum += v
+				}
+			}
+			data[y*w+x] = uint8(math.Round(sum / 16))
+		}
+	}
+	return img
+}
+
+func apply(m [3][3]float64, x, y float64) [2]float64 {
+	w := m[2][0]*x + m[2][1]*y + m[2][2]
+	return [2]float64{(m[0][0]*x + m[0][1]*y + m[0][2]) / w, (m[1][0]*x + m[1][1]*y + m[1][2]) / w}
+}
+
+func inverse(m [3][3]float64) [3][3]float64 {
+	var inv [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			a, b := m[(j+1)%3][(i+1)%3], m[(j+2)%3][(i+2)%3]
+			c, d := m[(j+1)%3][(i+2)%3], m[(j+2)%3][(i+1)%3]
+			inv[i][j] = a*b - c*d
+		}
+	}
+	return inv
+}
+
+// placed maps tag cells to the image: scaled, turned by angle around the
+// tag centre, moved to (x, y) and tilted by the perspective terms.
+func placed(d *Dictionary, scale, angle, x, y, px, py float64) [3][3]float64 {
+	half := float64(d.Size+2) / 2
+	c, s := math.Cos(angle)*scale, math.Sin(angle)*scale
+	affine := [3][3]float64{
+		{c, -s, x - c*half + s*half},
+		{s, c, y - s*half - c*half},
+		{0, 0, 1},
+	}
+	// the perspective keeps (x, y) in place
+	return mul([3][3]float64{{1, 0, 0}, {0, 1, 0}, {px, py, 1 - px*x - py*y}}, affine)
+}
+
+// corner returns where the outer corner k of the view's tag is imaged.
+func (v view) corner(k int) vec.Vector2D {
+	n := float64(v.dict.Size + 2)
+	cells := [4][2]float64{{0, 0}, {n, 0}, {n, n}, {0, n}}
+	p := apply(v.hom, cells[k][0], cells[k][1])
+	return vec.Vector2D{float32(p[0]), float32(p[1])}
+}
+
+func TestDictionary(t *testing.T) {
+	for _, tc := range []struct {
+		d          *Dictionary
+		n, minHamm int
+	}{
+		{Tag36h11, 66, 11},
+		{Tag25h9, 35, 9},
+		{ArUcoOriginal, 1024, 0},
+	} {
+		if len(tc.d.Codes) != tc.n || tc.d.MinHamming < tc.minHamm {
+			t.Errorf("%s: %d codes, minimum Hamming distance %d", tc.d.Name, len(tc.d.Codes), tc.d.MinHamming)
+		}
+	}
+	if c := ArUcoOriginal.Codes[0]; c != 0b10000_10000_10000_10000_10000 {
+		t.Errorf("ArUco 0 is %025b", c)
+	}
+	if c := ArUcoOriginal.Codes[1023]; c != 0b01110_01110_01110_01110_01110 {
+		t.Errorf("ArUco 1023 is %025b", c)
+	}
+
+	// a tag turned twice with two bits flipped
+	perm := rotation(6)
+	code := rotate(rotate(Tag36h11.Codes[42], perm), perm) ^ 1<<3 ^ 1<<20
+	id, turns, errs, ok := Tag36h11.Match(code, Tag36h11.Correction())
+	if !ok || id != 42 || turns != 2 || errs != 2 {
+		t.Errorf("matched %d, %d turns, %d errors, %v", id, turns, errs, ok)
+	}
+	if _, _, _, ok := Tag36h11.Match(code, 1); ok {
+		t.Error("matched with too many errors")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	views := []view{
+		{Tag36h11, Tag36h11.Codes[0], placed(Tag36h11, 12, 0, 120, 110, 0, 0)},
+		{Tag36h11, Tag36h11.Codes[7], placed(Tag36h11, 10, math.Pi/2+0.2, 330, 120, 0.0004, -0.0003)},
+		{Tag36h11, Tag36h11.Codes[63], placed(Tag36h11, 14, math.Pi, 520, 140, -0.0002, 0.0005)},
+		{Tag25h9, Tag25h9.Codes[5], placed(Tag25h9, 13, -math.Pi/2, 140, 340, 0.0003, 0.0003)},
+		{ArUcoOriginal, ArUcoOriginal.Codes[300], placed(ArUcoOriginal, 11, 0.5, 350, 350, 0, 0)},
+		{ArUcoOriginal, ArUcoOriginal.Codes[777], placed(ArUcoOriginal, 8, -0.3, 530, 360, 0.0005, 0)},
+	}
+	want := []struct {
+		dict *Dictionary
+		id   int
+		view int
+	}{
+		{Tag36h11, 0, 0}, {Tag36h11, 7, 1}, {Tag36h11, 63, 2},
+		{ArUcoOriginal, 300, 4}, {ArUcoOriginal, 777, 5},
+		{Tag25h9, 5, 3},
+	}
+	img := render(640, 480, views...)
+	dets, err := NewDetector(WithDictionary(Tag36h11, ArUcoOriginal, Tag25h9)).Detect(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != len(want) {
+		t.Fatalf("%d detections: %+v", len(dets), dets)
+	}
+	for i, w := range want {
+		det := dets[i]
+		if det.Dictionary != w.dict.Name || det.ID != w.id || det.Errors != 0 || det.HasPose {
+			t.Errorf("detection %d: %+v, want %s %d", i, det, w.dict.Name, w.id)
+			continue
+		}
+		for k := range 4 {
+			if c := views[w.view].corner(k); det.Corners[k].Distance(c) > 0.25 {
+				t.Errorf("%s %d: corner %d at %v, want %v", w.dict.Name, w.id, k, det.Corners[k], c)
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	img, err := Tag25h9.Render(11, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := img.Shape(); s[0] != 72 || s[1] != 72 {
+		t.Fatalf("rendered %v", s)
+	}
+	dets, err := NewDetector(WithDictionary(Tag25h9)).Detect(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 1 || dets[0].ID != 11 {
+		t.Fatalf("detected %+v", dets)
+	}
+	// the border runs along pixel edges, corner pixels are [8, 64)
+	want := [4]vec.Vector2D{{7.5, 7.5}, {63.5, 7.5}, {63.5, 63.5}, {7.5, 63.5}}
+	for k := range 4 {
+		if dets[0].Corners[k].Distance(want[k]) > 0.25 {
+			t.Errorf("corner %d at %v, want %v", k, dets[0].Corners[k], want[k])
+		}
+	}
+	if _, err := Tag25h9.Render(len(Tag25h9.Codes), 8); err == nil {
+		t.Error("rendered a tag outside the dictionary")
+	}
+}
+
+// apriltagBits are the cells, one based inside the border, of the bits of
+// the AprilTag 3 codes from the most significant.
+var apriltagBits = map[int][][2]int{
+	6: {{1, 1}, {2, 1}, {3, 1}, {4, 1}, {5, 1}, {2, 2}, {3, 2}, {4, 2}, {3, 3},
+		{6, 1}, {6, 2}, {6, 3}, {6, 4}, {6, 5}, {5, 2}, {5, 3}, {5, 4}, {4, 3},
+		{6, 6}, {5, 6}, {4, 6}, {3, 6}, {2, 6}, {5, 5}, {4, 5}, {3, 5}, {4, 4},
+		{1, 6}, {1, 5}, {1, 4}, {1, 3}, {1, 2}, {2, 5}, {2, 4}, {2, 3}, {3, 4}},
+	5: {{1, 1}, {2, 1}, {3, 1}, {4, 1}, {2, 2}, {3, 2},
+		{5, 1}, {5, 2}, {5, 3}, {5, 4}, {4, 2}, {4, 3},
+		{5, 5}, {4, 5}, {3, 5}, {2, 5}, {4, 4}, {3, 4},
+		{1, 5}, {1, 4}, {1, 3}, {1, 2}, {2, 4}, {2, 3}, {3, 3}},
+}
+
+// fromAprilTag converts a code of the AprilTag 3 tables to raster order.
+func fromAprilTag(code uint64, size int) uint64 {
+	n := size * size
+	var out uint64
+	for i, cell := range apriltagBits[size] {
+		bit := code >> uint(n-1-i) & 1
+		out |= bit << uint(n-1-((cell[1]-1)*size+cell[0]-1))
+	}
+	return out
+}
+
+func TestPublishedCodes(t *testing.T) {
+	// codes as printed in tag36h11.c and tag25h9.c
+	for _, tc := range []struct {
+		d    *Dictionary
+		id   int
+		code uint64
+	}{
+		{Tag36h11, 0, 0xd7e00984b},
+		{Tag36h11, 1, 0xdda664ca7},
+		{Tag36h11, 65, 0xbd25cb40b},
+		{Tag25h9, 0, 0x156f1f4},
+		{Tag25h9, 1, 0x1f28cd5},
+	} {
+		code := fromAprilTag(tc.code, tc.d.Size)
+		if tc.d.Codes[tc.id] != code {
+			t.Errorf("%s %d is %#x, want %#x", tc.d.Name, tc.id, tc.d.Codes[tc.id], code)
+		}
+		img := render(320, 240, view{tc.d, code, placed(tc.d, 14, 0.3, 160, 120, 0.0003, 0)})
+		dets, err := NewDetector(WithDictionary(tc.d)).Detect(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dets) != 1 || dets[0].ID != tc.id || dets[0].Errors != 0 {
+			t.Errorf("%s %d: detected %+v", tc.d.Name, tc.id, dets)
+		}
+	}
+}
+
+func TestCorrection(t *testing.T) {
+	v := view{Tag36h11, Tag36h11.Codes[59] ^ 1<<0 ^ 1<<17, placed(Tag36h11, 12, 0.1, 160, 120, 0, 0)}
+	img := render(320, 240, v)
+	dets, err := NewDetector().Detect(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 1 || dets[0].ID != 59 || dets[0].Errors != 2 {
+		t.Fatalf("detected %+v", dets)
+	}
+	dets, err = NewDetector(WithMaxCorrection(1)).Detect(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 0 {
+		t.Fatalf("detected %+v without correction", dets)
+	}
+}
+
+func TestPose(t *testing.T) {
+	cam := &calib.Camera{Width: 640, Height: 480, Fx: 600, Fy: 605, Cx: 322, Cy: 238, Distortion: make([]float64, 5)}
+	const size = 0.1
+	// a tag facing the camera, its y and z axes opposite to the camera's,
+	// tilted
+	r := calib.Rodrigues(vec.Vector3D{0.5, -0.3, 0.2})
+	for i := range 3 {
+		r[i][1], r[i][2] = -r[i][1], -r[i][2]
+	}
+	tr := vec.Vector3D{0.04, -0.02, 0.45}
+
+	// tag cells to image: K [r1 r2 t] A, where A maps cells to tag
+	// coordinates with y up
+	n := float64(Tag36h11.Size + 2)
+	k := [3][3]float64{{cam.Fx, 0, cam.Cx}, {0, cam.Fy, cam.Cy}, {0, 0, 1}}
+	var rt [3][3]float64
+	for i := range 3 {
+		rt[i] = [3]float64{float64(r[i][0]), float64(r[i][1]), float64(tr[i])}
+	}
+	a := [3][3]float64{{size / n, 0, -size / 2}, {0, -size / n, size / 2}, {0, 0, 1}}
+	hom := mul(mul(k, rt), a)
+
+	img := render(640, 480, view{Tag36h11, Tag36h11.Codes[3], hom})
+	dets, err := NewDetector(WithCamera(cam, size)).Detect(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 1 || dets[0].ID != 3 || !dets[0].HasPose {
+		t.Fatalf("detected %+v", dets)
+	}
+	m := dets[0].Transform
+	for i := range 3 {
+		if d := math.Abs(float64(m[i][3] - tr[i])); d > 0.002 {
+			t.Errorf("translation %d is %v, want %v", i, m[i][3], tr[i])
+		}
+		for j := range 3 {
+			if d := math.Abs(float64(m[i][j] - r[i][j])); d > 0.01 {
+				t.Errorf("rotation %d,%d is %v, want %v", i, j, m[i][j], r[i][j])
+			}
+		}
+	}
+	if m[3] != [4]float32{0, 0, 0, 1} {
+		t.Errorf("last row %v", m[3])
+	}
+}
+
+func mul(a, b [3][3]float64) [3][3]float64 {
+	var out [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,260 @@
+package fiducial
+
+import (
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// point is an image position in pixels.
+type point [2]float64
+
+// quad is a tag outline, corners clockwise in the image.
+type quad [4]point
+
+// adaptiveOffset is subtracted from the local mean when thresholding, so
+// that flat regions stay bright.
+const adaptiveOffset = 7
+
+// quads finds dark convex quadrilaterals: components of the thresholded
+// image whose convex hull is close to a quadrilateral.
+func quads(g gray.Image, window int, minPerimeter float64) ([]quad, error) {
+	bin, err := imgproc.AdaptiveThreshold(g.Tensor(), 255, imgproc.AdaptiveMean, imgproc.ThreshBinaryInv, window, adaptiveOffset)
+	if err != nil {
+		return nil, err
+	}
+	labels, comps, err := imgproc.ConnectedComponentsWithStats(bin, 8)
+	if err != nil {
+		return nil, err
+	}
+	lab := labels.Data().([]int32)
+
+	var out []quad
+	for l, c := range comps {
+		if l == 0 || float64(2*(c.Width+c.Height)) < minPerimeter || c.Width >= g.W || c.Height >= g.H {
+			continue
+		}
+		hull := convexHull(boundary(lab, g.W, g.H, int32(l), c))
+		if q, ok := fitQuad(hull, minPerimeter); ok {
+			out = append(out, q)
+		}
+	}
+	return out, nil
+}
+
+// boundary returns the pixels of a component with a 4-neighbour outside
+// it.
+func boundary(lab []int32, w, h int, l int32, c imgproc.Component) []point {
+	var pts []point
+	inside := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < w && y < h && lab[y*w+x] == l
+	}
+	for y := c.Top; y < c.Top+c.Height; y++ {
+		for x := c.Left; x < c.Left+c.Width; x++ {
+			if lab[y*w+x] != l {
+				continue
+			}
+			if !inside(x-1, y) || !inside(x+1, y) || !inside(x, y-1) || !inside(x, y+1) {
+				pts = append(pts, point{float64(x), float64(y)})
+			}
+		}
+	}
+	return pts
+}
+
+// convexHull returns the hull of the points by Andrew's monotone chain.
+func convexHull(pts []point) []point {
+	if len(pts) < 3 {
+		return pts
+	}
+	slices.SortFunc(pts, func(a, b point) int {
+		if a[0] != b[0] {
+			return cmpFloat(a[0], b[0])
+		}
+		return cmpFloat(a[1], b[1])
+	})
+	hull := make([]point, 0, 2*len(pts))
+	for pass := range 2 {
+		start := len(hull)
+		for i := range pts {
+			p := pts[i]
+			if pass == 1 {
+				p = pts[len(pts)-1-i]
+			}
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) >= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1]
+	}
+	return hull
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cross is the z component of (b - a) x (c - a); positive when a, b, c
+// turn clockwise in the image, whose y axis points down.
+func cross(a, b, c point) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// fitQuad picks the corners of a convex hull: the ends of its longest
+// diagonal and the points farthest from it on either side. The hull must
+// stay within a small distance of the quadrilateral.
+func fitQuad(hull []point, minPerimeter float64) (quad, bool) {
+	if len(hull) < 4 {
+		return quad{}, false
+	}
+	var c point
+	for _, p := range hull {
+		c[0] += p[0] / float64(len(hull))
+		c[1] += p[1] / float64(len(hull))
+	}
+	a := farthest(hull, func(p point) float64 { return dist(p, c) })
+	b := farthest(hull, func(p point) float64 { return dist(p, hull[a]) })
+	pa, pb := hull[a], hull[b]
+	l := farthest(hull, func(p point) float64 { return cross(pa, pb, p) })
+	r := farthest(hull, func(p point) float64 { return -cross(pa, pb, p) })
+	if cross(pa, pb, hull[l]) <= 0 || cross(pa, pb, hull[r]) >= 0 {
+		return quad{}, false
+	}
+	// clockwise in the image: a, then the corner on the right of a->b
+	q := quad{pa, hull[r], pb, hull[l]}
+
+	perimeter := 0.0
+	for i := range 4 {
+		side := dist(q[i], q[(i+1)%4])
+		if side < minPerimeter/8 {
+			return quad{}, false
+		}
+		perimeter += side
+		if cross(q[i], q[(i+1)%4], q[(i+2)%4]) <= 0 {
+			return quad{}, false
+		}
+	}
+	if perimeter < minPerimeter {
+		return quad{}, false
+	}
+	tolerance := max(2, 0.02*perimeter)
+	for _, p := range hull {
+		best := math.Inf(1)
+		for i := range 4 {
+			best = min(best, segmentDistance(p, q[i], q[(i+1)%4]))
+		}
+		if best > tolerance {
+			return quad{}, false
+		}
+	}
+	return q, true
+}
+
+func farthest(pts []point, score func(p point) float64) int {
+	best := 0
+	for i, p := range pts {
+		if score(p) > score(pts[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func dist(a, b point) float64 {
+	return math.Hypot(a[0]-b[0], a[1]-b[1])
+}
+
+func segmentDistance(p, a, b point) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / (dx*dx + dy*dy)
+	t = min(max(t, 0), 1)
+	return dist(p, point{a[0] + t*dx, a[1] + t*dy})
+}
+
+// refine moves the sides of a quad onto the dark to bright edges of the
+// image: edge points are located along the normals of every side as the
+// centroid of the intensity gradient, lines are fitted to them and the
+// corners are the intersections of adjacent lines. cell is the size of a
+// tag bit in pixels, which bounds the search.
+func refine(g gray.Image, q quad, cell float64) (quad, bool) {
+	reach := min(max(0.6*cell, 1), 3)
+	var lines [4][2]point // point and direction
+	for i := range 4 {
+		a, b := q[i], q[(i+1)%4]
+		length := dist(a, b)
+		u := point{(b[0] - a[0]) / length, (b[1] - a[1]) / length}
+		// clockwise corners put the outside on the left of a->b
+		n := point{u[1], -u[0]}
+		samples := max(4, int(length/2))
+		var pts []point
+		for k := range samples {
+			t := 0.1 + 0.8*(float64(k)+0.5)/float64(samples)
+			p := point{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}
+			var sum, weight float64
+			for s := -reach; s <= reach+1e-9; s += 0.25 {
+				lo := g.Sample(p[0]+(s-0.25)*n[0], p[1]+(s-0.25)*n[1])
+				hi := g.Sample(p[0]+(s+0.25)*n[0], p[1]+(s+0.25)*n[1])
+				if d := hi - lo; d > 0 {
+					sum += s * d
+					weight += d
+				}
+			}
+			if weight > 5 {
+				s := sum / weight
+				pts = append(pts, point{p[0] + s*n[0], p[1] + s*n[1]})
+			}
+		}
+		if len(pts) < 2 {
+			return q, false
+		}
+		lines[i] = fitLine(pts)
+	}
+	var out quad
+	for i := range 4 {
+		p, ok := intersect(lines[(i+3)%4], lines[i])
+		if !ok || dist(p, q[i]) > 2*reach+1 {
+			return q, false
+		}
+		out[i] = p
+	}
+	return out, true
+}
+
+// fitLine returns the total least squares line through the points as its
+// centroid and direction.
+func fitLine(pts []point) [2]point {
+	var c point
+	for _, p := range pts {
+		c[0] += p[0] / float64(len(pts))
+		c[1] += p[1] / float64(len(pts))
+	}
+	var sxx, sxy, syy float64
+	for _, p := range pts {
+		dx, dy := p[0]-c[0], p[1]-c[1]
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	angle := 0.5 * math.Atan2(2*sxy, sxx-syy)
+	return [2]point{c, {math.Cos(angle), math.Sin(angle)}}
+}
+
+func intersect(l1, l2 [2]point) (point, bool) {
+	p, u := l1[0], l1[1]
+	q, v := l2[0], l2[1]
+	den := u[0]*v[1] - u[1]*v[0]
+	if math.Abs(den) < 1e-9 {
+		return point{}, false
+	}
+	t := ((q[0]-p[0])*v[1] - (q[1]-p[1])*v[0]) / den
+	return point{p[0] + t*u[0], p[1] + t*u[1]}, true
+}
@@ -0,0 +1,281 @@
+package flow
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/internal/concurrency"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// Farneback computes dense flow by polynomial expansion: the neighbourhood
+// of every pixel is approximated by a quadratic, and the displacement that
+// best maps the quadratics of one frame onto the other is averaged over a
+// window, coarse to fine.
+type Farneback struct {
+	cfg config
+}
+
+// NewFarneback creates a dense flow estimator; see WithPyrScale,
+// WithLevels, WithWindow, WithIterations, WithPolyN and WithGaussianWindow.
+func NewFarneback(opts ...Option) *Farneback {
+	return &Farneback{cfg: newConfig(config{
+		window:     15,
+		levels:     3,
+		iterations: 3,
+		pyrScale:   0.5,
+		polyN:      5,
+		polySigma:  1.1,
+	}, opts)}
+}
+
+// minLevelSize is the smallest side of a pyramid level.
+const minLevelSize = 32
+
+// Compute returns the flow from prev to next as an FP32 [H, W, 2] tensor
+// of x and y displacements in pixels, prev(x, y) ≈ next(x + dx, y + dy).
+func (f *Farneback) Compute(prev, next types.Tensor) (types.Tensor, error) {
+	p, n, err := pair(prev, next)
+	if err != nil {
+		return nil, err
+	}
+	levels := 0
+	for scale := f.cfg.pyrScale; levels < f.cfg.levels; scale *= f.cfg.pyrScale {
+		if float64(p.W)*scale < minLevelSize || float64(p.H)*scale < minLevelSize {
+			break
+		}
+		levels++
+	}
+
+	var flow []float32
+	fw, fh := 0, 0
+	for k := levels; k >= 0; k-- {
+		scale := math.Pow(f.cfg.pyrScale, float64(k))
+		p1, err := f.scaled(p, scale)
+		if err != nil {
+			return nil, err
+		}
+		n1, err := f.scaled(n, scale)
+		if err != nil {
+			return nil, err
+		}
+		if flow == nil {
+			flow = make([]float32, 2*p1.W*p1.H)
+		} else {
+			up, err := imgproc.Resize(tensor.FromArray(tensor.NewShape(fh, fw, 2), flow), imgproc.HWC, p1.W, p1.H, imgproc.Bilinear)
+			if err != nil {
+				return nil, err
+			}
+			flow = up.Data().([]float32)
+			for i := range flow {
+				flow[i] /= float32(f.cfg.pyrScale)
+			}
+		}
+		fw, fh = p1.W, p1.H
+
+		r0 := expand(p1, f.cfg.polyN, f.cfg.polySigma)
+		r1 := expand(n1, f.cfg.polyN, f.cfg.polySigma)
+		for range f.cfg.iterations {
+			m := constraints(r0, r1, fw, fh, flow)
+			if err := f.solve(m, fw, fh, flow); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tensor.FromArray(tensor.NewShape(fh, fw, 2), flow), nil
+}
+
+// scaled returns a frame smoothed against aliasing and resized to the
+// pyramid scale, as OpenCV does.
+func (f *Farneback) scaled(g gray.Image, scale float64) (gray.Image, error) {
+	if scale == 1 {
+		return g, nil
+	}
+	sigma := (1/scale - 1) * 0.5
+	ksize := max(3, int(math.Round(sigma*5))|1)
+	blurred, err := imgproc.GaussianBlur(g.Tensor(), imgproc.HWC, ksize, float32(sigma))
+	if err != nil {
+		return gray.Image{}, err
+	}
+	w, h := int(math.Round(float64(g.W)*scale)), int(math.Round(float64(g.H)*scale))
+	resized, err := imgproc.Resize(blurred, imgproc.HWC, w, h, imgproc.Bilinear)
+	if err != nil {
+		return gray.Image{}, err
+	}
+	return gray.Wrap(resized), nil
+}
+
+// expand fits f(x) ≈ xᵀAx + bᵀx + c to the (2n+1)² neighbourhood of every
+// pixel, weighted by a Gaussian of sigma, and returns b1, b2, a11, a22 and
+// a12 (twice the off-diagonal of A) per pixel. The weighted moments are
+// separable and the least squares solution is a fixed linear map of them.
+func expand(g gray.Image, n int, sigma float64) []float32 {
+	weights := make([]float64, 2*n+1)
+	for i := range weights {
+		x := float64(i - n)
+		weights[i] = math.Exp(-x * x / (2 * sigma * sigma))
+	}
+	// normal equations of the basis 1, x, y, x², y², xy
+	var normal [6][6]float64
+	for dy := -n; dy <= n; dy++ {
+		for dx := -n; dx <= n; dx++ {
+			x, y := float64(dx), float64(dy)
+			basis := [6]float64{1, x, y, x * x, y * y, x * y}
+			w := weights[dx+n] * weights[dy+n]
+			for k := range 6 {
+				for l := range 6 {
+					normal[k][l] += w * basis[k] * basis[l]
+				}
+			}
+		}
+	}
+	inv := invert6(normal)
+
+	// rows: sums of w f, w x f and w x² f
+	rows := make([]float64, 3*g.W*g.H)
+	concurrency.Parallel(g.H, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := range g.W {
+				var s0, s1, s2 float64
+				for dx := -n; dx <= n; dx++ {
+					v := weights[dx+n] * float64(g.At(x+dx, y))
+					s0 += v
+					s1 += float64(dx) * v
+					s2 += float64(dx*dx) * v
+				}
+				i := 3 * (y*g.W + x)
+				rows[i], rows[i+1], rows[i+2] = s0, s1, s2
+			}
+		}
+	})
+
+	out := make([]float32, 5*g.W*g.H)
+	concurrency.Parallel(g.H, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := range g.W {
+				var m [6]float64
+				for dy := -n; dy <= n; dy++ {
+					yy := min(max(y+dy, 0), g.H-1)
+					i := 3 * (yy*g.W + x)
+					w, fy := weights[dy+n], float64(dy)
+					m[0] += w * rows[i]
+					m[1] += w * rows[i+1]
+					m[2] += w * fy * rows[i]
+					m[3] += w * rows[i+2]
+					m[4] += w * fy * fy * rows[i]
+					m[5] += w * fy * rows[i+1]
+				}
+				o := out[5*(y*g.W+x):]
+				for k := 1; k < 6; k++ {
+					var c float64
+					for l := range 6 {
+						c += inv[k][l] * m[l]
+					}
+					o[k-1] = float32(c)
+				}
+			}
+		}
+	})
+	return out
+}
+
+// invert6 inverts the normal matrix by Gauss-Jordan elimination; it is
+// symmetric positive definite, so no pivoting is needed.
+func invert6(a [6][6]float64) [6][6]float64 {
+	var inv [6][6]float64
+	for i := range 6 {
+		inv[i][i] = 1
+	}
+	for c := range 6 {
+		p := a[c][c]
+		for j := range 6 {
+			a[c][j] /= p
+			inv[c][j] /= p
+		}
+		for r := range 6 {
+			if r == c || a[r][c] == 0 {
+				continue
+			}
+			f := a[r][c]
+			for j := range 6 {
+				a[r][j] -= f * a[c][j]
+				inv[r][j] -= f * inv[c][j]
+			}
+		}
+	}
+	return inv
+}
+
+// constraints returns per pixel the normal equations G d = h of the
+// displacement, g11, g12, g22, h1 and h2. With A the mean quadratic of both
+// frames and Δb = -(b(next at x+d) - b(prev at x))/2 + A d, A d = Δb is
+// solved in the least squares sense. Pixels whose displacement leaves the
+// frame keep it.
+func constraints(r0, r1 []float32, w, h int, flow []float32) []float32 {
+	m := make([]float32, 5*w*h)
+	concurrency.Parallel(h, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := range w {
+				i := y*w + x
+				dx, dy := float64(flow[2*i]), float64(flow[2*i+1])
+				c0 := r0[5*i : 5*i+5]
+				var c1 [5]float64
+				fx, fy := float64(x)+dx, float64(y)+dy
+				if fx >= 0 && fy >= 0 && fx < float64(w-1) && fy < float64(h-1) {
+					x1, y1 := int(fx), int(fy)
+					ax, ay := fx-float64(x1), fy-float64(y1)
+					j := 5 * (y1*w + x1)
+					for k := range 5 {
+						c1[k] = (1-ay)*((1-ax)*float64(r1[j+k])+ax*float64(r1[j+5+k])) +
+							ay*((1-ax)*float64(r1[j+5*w+k])+ax*float64(r1[j+5*w+5+k]))
+					}
+				} else {
+					for k := range 5 {
+						c1[k] = float64(c0[k])
+					}
+				}
+				a11 := (float64(c0[2]) + c1[2]) / 2
+				a22 := (float64(c0[3]) + c1[3]) / 2
+				a12 := (float64(c0[4]) + c1[4]) / 4
+				b1 := -(c1[0]-float64(c0[0]))/2 + a11*dx + a12*dy
+				b2 := -(c1[1]-float64(c0[1]))/2 + a12*dx + a22*dy
+				o := m[5*i : 5*i+5]
+				o[0] = float32(a11*a11 + a12*a12)
+				o[1] = float32(a12 * (a11 + a22))
+				o[2] = float32(a22*a22 + a12*a12)
+				o[3] = float32(a11*b1 + a12*b2)
+				o[4] = float32(a12*b1 + a22*b2)
+			}
+		}
+	})
+	return m
+}
+
+// solve averages the constraints over the window and solves them for the
+// flow of every pixel.
+func (f *Farneback) solve(m []float32, w, h int, flow []float32) error {
+	var t types.Tensor = tensor.FromArray(tensor.NewShape(h, w, 5), m)
+	var err error
+	if f.cfg.gaussian {
+		t, err = imgproc.GaussianBlur(t, imgproc.HWC, f.cfg.window, float32(f.cfg.window/2)*0.3)
+	} else {
+		t, err = imgproc.BoxBlur(t, imgproc.HWC, f.cfg.window)
+	}
+	if err != nil {
+		return err
+	}
+	avg := t.Data().([]float32)
+	for i := range w * h {
+		g := avg[5*i : 5*i+5]
+		g11, g12, g22, h1, h2 := float64(g[0]), float64(g[1]), float64(g[2]), float64(g[3]), float64(g[4])
+		// a small term relative to the texture strength keeps flat and
+		// edge-only regions from blowing up
+		tr := g11 + g22
+		idet := 1 / (g11*g22 - g12*g12 + 1e-4*tr*tr + 1e-12)
+		flow[2*i] = float32((g22*h1 - g12*h2) * idet)
+		flow[2*i+1] = float32((g11*h2 - g12*h1) * idet)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package flow
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// texture is a smooth random pattern of sinusoids.
+type texture struct {
+	fx, fy, phase, amp []float64
+}
+
+func newTexture(seed int64) texture {
+	rnd := rand.New(rand.NewSource(seed))
+	var t texture
+	for range 24 {
+		angle := rnd.Float64() * 2 * math.Pi
+		freq := 0.05 + rnd.Float64()*0.25
+		t.fx = append(t.fx, freq*math.Cos(angle))
+		t.fy = append(t.fy, freq*math.Sin(angle))
+		t.phase = append(t.phase, rnd.Float64()*2*math.Pi)
+		t.amp = append(t.amp, 6+rnd.Float64()*6)
+	}
+	return t
+}
+
+func (t texture) at(x, y float64) float64 {
+	v := 128.0
+	for i := range t.fx {
+		v += t.amp[i] * math.Sin(t.fx[i]*x+t.fy[i]*y+t.phase[i])
+	}
+	return v
+}
+
+// frame renders the texture seen through warp, which maps image to texture
+// coordinates.
+func (t texture) frame(w, h int, warp func(x, y float64) (float64, float64)) types.Tensor {
+	img := tensor.New(types.FP32, tensor.NewShape(h, w))
+	data := img.Data().([]float32)
+	for y := range h {
+		for x := range w {
+			data[y*w+x] = float32(t.at(warp(float64(x), float64(y))))
+		}
+	}
+	return img
+}
+
+func shifted(dx, dy float64) func(x, y float64) (float64, float64) {
+	return func(x, y float64) (float64, float64) { return x - dx, y - dy }
+}
+
+func TestLK(t *testing.T) {
+	tex := newTexture(1)
+	prev := tex.frame(320, 240, shifted(0, 0))
+	for _, shift := range [][2]float64{{0.4, -0.3}, {3.3, -1.7}, {-12.6, 9.2}} {
+		next := tex.frame(320, 240, shifted(shift[0], shift[1]))
+		var pts []vec.Vector2D
+		for y := 40; y < 200; y += 20 {
+			for x := 40; x < 280; x += 20 {
+				pts = append(pts, vec.Vector2D{float32(x), float32(y)})
+			}
+		}
+		out, status, err := NewLK().Track(prev, next, pts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, p := range pts {
+			want := vec.Vector2D{p[0] + float32(shift[0]), p[1] + float32(shift[1])}
+			if !status[i] || out[i].Distance(want) > 0.05 {
+				t.Errorf("shift %v: %v tracked to %v (%v), want %v", shift, p, out[i], status[i], want)
+			}
+		}
+		d, n, ok := MedianDisplacement(pts, out, status)
+		if !ok || n != len(pts) || math.Abs(float64(d[0])-shift[0]) > 0.02 || math.Abs(float64(d[1])-shift[1]) > 0.02 {
+			t.Errorf("shift %v: median %v of %d", shift, d, n)
+		}
+	}
+}
+
+func TestLKFlat(t *testing.T) {
+	flat := tensor.New(types.UINT8, tensor.NewShape(100, 100))
+	_, status, err := NewLK().Track(flat, flat, []vec.Vector2D{{50, 50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status[0] {
+		t.Error("tracked a point on a flat image")
+	}
+	if _, _, err := NewLK().Track(flat, tensor.New(types.UINT8, tensor.NewShape(90, 100)), nil); err == nil {
+		t.Error("tracked between frames of different sizes")
+	}
+}
+
+func TestFarneback(t *testing.T) {
+	tex := newTexture(2)
+	const w, h = 240, 180
+	prev := tex.frame(w, h, shifted(0, 0))
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		// tol is the largest mean error; the quadratics of a rotated
+		// texture change, which Farneback does not model
+		tol float64
+		// warp maps next frame coordinates to texture, flow is the
+		// displacement of a prev pixel
+		warp func(x, y float64) (float64, float64)
+		flow func(x, y float64) (float64, float64)
+	}{
+		{"shift", nil, 0.05, shifted(2.6, -1.4), func(x, y float64) (float64, float64) { return 2.6, -1.4 }},
+		{"large shift", []Option{WithGaussianWindow()}, 0.05, shifted(-7.3, 5.1), func(x, y float64) (float64, float64) { return -7.3, 5.1 }},
+		{
+			"rotation", nil, 0.2,
+			// next(R p) = prev(p) about the centre, R a 2 degree turn
+			func(x, y float64) (float64, float64) {
+				c, s := math.Cos(-0.035), math.Sin(-0.035)
+				x, y = x-w/2, y-h/2
+				return c*x - s*y + w/2, s*x + c*y + h/2
+			},
+			func(x, y float64) (float64, float64) {
+				c, s := math.Cos(0.035), math.Sin(0.035)
+				x, y = x-w/2, y-h/2
+				return c*x - s*y - x, s*x + c*y - y
+			},
+		},
+	} {
+		next := tex.frame(w, h, tc.warp)
+		flow, err := NewFarneback(tc.opts...).Compute(prev, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := flow.Shape(); s[0] != h || s[1] != w || s[2] != 2 {
+			t.Fatalf("%s: flow shape %v", tc.name, s)
+		}
+		data := flow.Data().([]float32)
+		var sum float64
+		bad, n := 0, 0
+		for y := 20; y < h-20; y++ {
+			for x := 20; x < w-20; x++ {
+				dx, dy := tc.flow(float64(x), float64(y))
+				i := 2 * (y*w + x)
+				e := math.Hypot(float64(data[i])-dx, float64(data[i+1])-dy)
+				sum += e
+				if e > 0.5 {
+					bad++
+				}
+				n++
+			}
+		}
+		if mean := sum / float64(n); mean > tc.tol || bad > n/50 {
+			t.Errorf("%s: mean error %.3f px, %d of %d pixels off by more than 0.5 px", tc.name, mean, bad, n)
+		}
+	}
+}
+
+func TestMedianFlow(t *testing.T) {
+	data := []float32{1, 2, 1, 2, 9, -9, 1, 2, 0, 0, 1.5, 2}
+	m, err := MedianFlow(tensor.FromArray(tensor.NewShape(2, 3, 2), data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != (vec.Vector2D{1, 2}) {
+		t.Errorf("median %v", m)
+	}
+	if _, err := MedianFlow(tensor.FromArray(tensor.NewShape(2, 6), data)); err == nil {
+		t.Error("accepted a [H, W] tensor")
+	}
+}
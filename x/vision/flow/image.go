@@ -0,0 +1,54 @@
+// Package flow estimates optical flow between consecutive frames in plain
+// Go: sparse pyramidal Lucas-Kanade tracking of feature points (Bouguet
+// 2000) and dense polynomial expansion flow (Farnebäck 2003), following the
+// conventions of OpenCV calcOpticalFlowPyrLK and calcOpticalFlowFarneback.
+//
+// Images are single channel UINT8 or FP32 tensors of shape [H, W] or
+// [H, W, 1]. The velocity subpackage turns image flow, or the motion counts
+// of an optical flow sensor, into metric ground velocity.
+package flow
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// pair converts two frames of the same size.
+func pair(prev, next types.Tensor) (gray.Image, gray.Image, error) {
+	p, err := gray.FromTensor(prev)
+	if err != nil {
+		return gray.Image{}, gray.Image{}, err
+	}
+	n, err := gray.FromTensor(next)
+	if err != nil {
+		return gray.Image{}, gray.Image{}, err
+	}
+	if p.W != n.W || p.H != n.H {
+		return gray.Image{}, gray.Image{}, fmt.Errorf("flow: frame sizes differ, %dx%d and %dx%d", p.W, p.H, n.W, n.H)
+	}
+	return p, n, nil
+}
+
+// pyrKernel is the 5-tap binomial kernel of OpenCV pyrDown.
+var pyrKernel = []float32{1.0 / 16, 4.0 / 16, 6.0 / 16, 4.0 / 16, 1.0 / 16}
+
+// pyrDown blurs with pyrKernel and drops every other row and column, to
+// (w+1)/2 x (h+1)/2 pixels.
+func pyrDown(g gray.Image) (gray.Image, error) {
+	blurred, err := imgproc.SepFilter2D(g.Tensor(), imgproc.HWC, pyrKernel, pyrKernel)
+	if err != nil {
+		return gray.Image{}, err
+	}
+	b := gray.Wrap(blurred)
+	out := gray.Image{W: (g.W + 1) / 2, H: (g.H + 1) / 2}
+	out.Pix = make([]float32, out.W*out.H)
+	for y := range out.H {
+		for x := range out.W {
+			out.Pix[y*out.W+x] = b.Pix[2*y*b.W+2*x]
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,160 @@
+package flow
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/internal/concurrency"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// LK tracks sparse points between frames with pyramidal Lucas-Kanade.
+type LK struct {
+	cfg config
+}
+
+// NewLK creates a Lucas-Kanade tracker; see WithWindow, WithLevels,
+// WithIterations, WithEpsilon and WithMinEigen.
+func NewLK(opts ...Option) *LK {
+	return &LK{cfg: newConfig(config{
+		window:     21,
+		levels:     3,
+		iterations: 30,
+		epsilon:    0.01,
+		minEigen:   0.1,
+	}, opts)}
+}
+
+// level is one pyramid level of the previous frame with its gradients and
+// the next frame.
+type level struct {
+	prev, gx, gy, next gray.Image
+}
+
+// Track finds where the points of prev moved to in next. A point is lost,
+// its status false, when its window is too flat to track, leaves the image
+// or the iterations diverge; its position is then the last estimate.
+func (lk *LK) Track(prev, next types.Tensor, points []vec.Vector2D) ([]vec.Vector2D, []bool, error) {
+	p, n, err := pair(prev, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	pyr, err := lk.pyramid(p, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make([]vec.Vector2D, len(points))
+	status := make([]bool, len(points))
+	concurrency.Parallel(len(points), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			out[i], status[i] = lk.track(pyr, points[i])
+		}
+	})
+	return out, status, nil
+}
+
+// pyramid builds the levels, stopping before a level gets smaller than
+// the window.
+func (lk *LK) pyramid(p, n gray.Image) ([]level, error) {
+	var pyr []level
+	for l := 0; l <= lk.cfg.levels; l++ {
+		if l > 0 {
+			if (p.W+1)/2 < lk.cfg.window || (p.H+1)/2 < lk.cfg.window {
+				break
+			}
+			var err error
+			if p, err = pyrDown(p); err != nil {
+				return nil, err
+			}
+			if n, err = pyrDown(n); err != nil {
+				return nil, err
+			}
+		}
+		gx, err := imgproc.Scharr(p.Tensor(), imgproc.HWC, 1, 0)
+		if err != nil {
+			return nil, err
+		}
+		gy, err := imgproc.Scharr(p.Tensor(), imgproc.HWC, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		lv := level{prev: p, gx: gray.Wrap(gx), gy: gray.Wrap(gy), next: n}
+		// the Scharr kernels weigh a unit gradient by 32
+		for i := range lv.gx.Pix {
+			lv.gx.Pix[i] /= 32
+			lv.gy.Pix[i] /= 32
+		}
+		pyr = append(pyr, lv)
+	}
+	return pyr, nil
+}
+
+// track follows one point from the top of the pyramid down: at every level
+// the displacement is refined by Newton steps on the window residual and
+// doubled as the guess of the finer level.
+func (lk *LK) track(pyr []level, pt vec.Vector2D) (vec.Vector2D, bool) {
+	half := lk.cfg.window / 2
+	area := float64(lk.cfg.window * lk.cfg.window)
+	tmpl := make([]float64, 3*lk.cfg.window*lk.cfg.window)
+	var gx, gy float64 // the guess carried between levels
+	for l := len(pyr) - 1; l >= 0; l-- {
+		lv := pyr[l]
+		scale := 1 / float64(int(1)<<l)
+		px, py := float64(pt[0])*scale, float64(pt[1])*scale
+
+		// template intensities, gradients and the gradient matrix
+		var g11, g12, g22 float64
+		k := 0
+		for j := -half; j <= half; j++ {
+			for i := -half; i <= half; i++ {
+				x, y := px+float64(i), py+float64(j)
+				ix, iy := lv.gx.Sample(x, y), lv.gy.Sample(x, y)
+				tmpl[k], tmpl[k+1], tmpl[k+2] = lv.prev.Sample(x, y), ix, iy
+				g11 += ix * ix
+				g12 += ix * iy
+				g22 += iy * iy
+				k += 3
+			}
+		}
+		minEigen := (g11 + g22 - math.Sqrt((g11-g22)*(g11-g22)+4*g12*g12)) / 2 / area
+		det := g11*g22 - g12*g12
+		if minEigen < lk.cfg.minEigen || det < 1e-9 {
+			return vec.Vector2D{float32((px + gx) / scale), float32((py + gy) / scale)}, false
+		}
+
+		var vx, vy float64
+		for range lk.cfg.iterations {
+			qx, qy := px+gx+vx, py+gy+vy
+			if qx < -float64(half) || qy < -float64(half) || qx > float64(lv.next.W+half) || qy > float64(lv.next.H+half) {
+				return vec.Vector2D{float32(qx / scale), float32(qy / scale)}, false
+			}
+			var b1, b2 float64
+			k := 0
+			for j := -half; j <= half; j++ {
+				for i := -half; i <= half; i++ {
+					d := tmpl[k] - lv.next.Sample(qx+float64(i), qy+float64(j))
+					b1 += d * tmpl[k+1]
+					b2 += d * tmpl[k+2]
+					k += 3
+				}
+			}
+			ex, ey := (g22*b1-g12*b2)/det, (g11*b2-g12*b1)/det
+			vx += ex
+			vy += ey
+			if ex*ex+ey*ey < lk.cfg.epsilon*lk.cfg.epsilon {
+				break
+			}
+		}
+		if l > 0 {
+			gx, gy = 2*(gx+vx), 2*(gy+vy)
+		} else {
+			gx, gy = gx+vx, gy+vy
+		}
+	}
+	x, y := float64(pt[0])+gx, float64(pt[1])+gy
+	w, h := pyr[0].next.W, pyr[0].next.H
+	ok := !math.IsNaN(x) && !math.IsNaN(y) && x >= 0 && y >= 0 && x <= float64(w-1) && y <= float64(h-1)
+	return vec.Vector2D{float32(x), float32(y)}, ok
+}
@@ -0,0 +1,55 @@
+package flow
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// MedianFlow returns the per-axis median of a dense [H, W, 2] flow, the
+// motion of the dominant surface such as the ground below a downward
+// camera, robust to a minority of moving objects.
+func MedianFlow(flow types.Tensor) (vec.Vector2D, error) {
+	s := flow.Shape()
+	data, ok := flow.Data().([]float32)
+	if len(s) != 3 || s[2] != 2 || !ok || !flow.IsContiguous() {
+		return vec.Vector2D{}, fmt.Errorf("flow: flow must be a contiguous FP32 [H, W, 2] tensor, got %v %v", flow.DataType(), s)
+	}
+	n := s[0] * s[1]
+	if n == 0 {
+		return vec.Vector2D{}, fmt.Errorf("flow: empty flow")
+	}
+	xs, ys := make([]float32, n), make([]float32, n)
+	for i := range n {
+		xs[i], ys[i] = data[2*i], data[2*i+1]
+	}
+	return vec.Vector2D{median(xs), median(ys)}, nil
+}
+
+// MedianDisplacement returns the per-axis median displacement of the
+// points tracked by LK.Track and the number of tracked points; it is false
+// when none were.
+func MedianDisplacement(prev, next []vec.Vector2D, status []bool) (vec.Vector2D, int, bool) {
+	var xs, ys []float32
+	for i := range prev {
+		if i < len(next) && i < len(status) && status[i] {
+			xs = append(xs, next[i][0]-prev[i][0])
+			ys = append(ys, next[i][1]-prev[i][1])
+		}
+	}
+	if len(xs) == 0 {
+		return vec.Vector2D{}, 0, false
+	}
+	return vec.Vector2D{median(xs), median(ys)}, len(xs), true
+}
+
+// median sorts v in place.
+func median(v []float32) float32 {
+	slices.Sort(v)
+	if len(v)%2 == 1 {
+		return v[len(v)/2]
+	}
+	return (v[len(v)/2-1] + v[len(v)/2]) / 2
+}
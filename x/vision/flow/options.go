@@ -0,0 +1,109 @@
+package flow
+
+// config holds the parameters of both flow methods; each reads the ones it
+// needs.
+type config struct {
+	window     int
+	levels     int
+	iterations int
+	epsilon    float64
+	minEigen   float64
+	pyrScale   float64
+	polyN      int
+	polySigma  float64
+	gaussian   bool
+}
+
+// Option configures LK or Farneback.
+type Option func(*config)
+
+// WithWindow sets the odd side of the search window of Lucas-Kanade
+// (default: 21) or of the averaging window of Farneback (default: 15).
+// Larger windows tolerate noise and faster motion but blur motion
+// boundaries.
+func WithWindow(px int) Option {
+	return func(c *config) {
+		if px < 3 || px%2 == 0 {
+			panic("flow: window must be odd and at least 3")
+		}
+		c.window = px
+	}
+}
+
+// WithLevels sets the number of pyramid levels above the full resolution
+// image (default: 3); 0 disables the pyramid. Each level doubles the
+// largest motion tracked.
+func WithLevels(n int) Option {
+	return func(c *config) {
+		if n < 0 {
+			panic("flow: levels must not be negative")
+		}
+		c.levels = n
+	}
+}
+
+// WithIterations sets the iterations per pyramid level (default: 30 for
+// Lucas-Kanade, 3 for Farneback).
+func WithIterations(n int) Option {
+	return func(c *config) {
+		if n < 1 {
+			panic("flow: iterations must be positive")
+		}
+		c.iterations = n
+	}
+}
+
+// WithEpsilon stops the Lucas-Kanade iterations once the update is
+// shorter, in pixels (default: 0.01).
+func WithEpsilon(px float64) Option {
+	return func(c *config) {
+		c.epsilon = px
+	}
+}
+
+// WithMinEigen sets the least smaller eigenvalue of the Lucas-Kanade
+// gradient matrix divided by the window area, in (intensity/pixel)²
+// (default: 0.1). Points on flat or edge-only patches below it are lost.
+func WithMinEigen(v float64) Option {
+	return func(c *config) {
+		c.minEigen = v
+	}
+}
+
+// WithPyrScale sets the scale between Farneback pyramid levels, in (0, 1)
+// (default: 0.5, the classic pyramid).
+func WithPyrScale(s float64) Option {
+	return func(c *config) {
+		if s <= 0 || s >= 1 {
+			panic("flow: pyramid scale must be in (0, 1)")
+		}
+		c.pyrScale = s
+	}
+}
+
+// WithPolyN sets the neighbourhood of the Farneback polynomial expansion,
+// (2n+1)² pixels weighted by a Gaussian of sigma (default: 5 and 1.1; 7
+// goes with 1.5). Larger neighbourhoods give smoother, blurrier flow.
+func WithPolyN(n int, sigma float64) Option {
+	return func(c *config) {
+		if n < 1 || sigma <= 0 {
+			panic("flow: polynomial neighbourhood and sigma must be positive")
+		}
+		c.polyN, c.polySigma = n, sigma
+	}
+}
+
+// WithGaussianWindow averages Farneback constraints with a Gaussian rather
+// than a box window (OPTFLOW_FARNEBACK_GAUSSIAN): slower, more accurate.
+func WithGaussianWindow() Option {
+	return func(c *config) {
+		c.gaussian = true
+	}
+}
+
+func newConfig(defaults config, opts []Option) config {
+	for _, opt := range opts {
+		opt(&defaults)
+	}
+	return defaults
+}
@@ -0,0 +1,203 @@
+// Package velocity turns the apparent motion of the ground below a
+// downward looking camera or optical flow sensor, such as the ADNS3080,
+// into metric velocity using the height from a rangefinder such as the
+// VL53L0X.
+//
+// A point of the ground at depth Z seen at the image centre moves by
+//
+//	ẋ = -Vx/Z - ωy,  ẏ = -Vy/Z + ωx
+//
+// in normalized image coordinates per second when the camera moves with
+// velocity V and turns with rate ω, both in the camera frame. The
+// Estimator inverts this with the flow divided by the focal length and the
+// range along the optical axis, subtracting the rotation measured by a
+// gyro. The result is in the camera frame, x right and y down in the
+// image; rotate it to the body frame with the mounting of the camera.
+//
+// Velocity.V with Velocity.Variance as the diagonal of the noise
+// covariance is a direct measurement of horizontal velocity for the
+// filters of x/math/filter, e.g. an ekalman filter of a drone or rover.
+package velocity
+
+import (
+	"math"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices/adns3080"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// Flow is the motion of the ground in the image over an interval.
+type Flow struct {
+	// Delta is the displacement of the ground in the image, in pixels or
+	// sensor counts, the units of the focal length of the Estimator.
+	Delta vec.Vector2D
+	// Dt is the interval.
+	Dt time.Duration
+	// Rate is the mean angular rate of the camera about its x and y axes
+	// over the interval in rad/s, from a gyro; zero skips derotation.
+	Rate vec.Vector2D
+	// Quality is the confidence in the flow in (0, 1], such as the
+	// fraction of points tracked; 0 marks invalid flow.
+	Quality float32
+}
+
+// Velocity is a ground velocity estimate.
+type Velocity struct {
+	// V is the velocity over the ground in m/s, camera x and y.
+	V vec.Vector2D
+	// Variance is the variance of V per axis in (m/s)².
+	Variance vec.Vector2D
+	// Height is the range to the ground used, in meters.
+	Height float32
+}
+
+// Estimator fuses flow and range into velocity.
+type Estimator struct {
+	fx, fy     float64
+	minRange   float64
+	maxRange   float64
+	maxAge     time.Duration
+	minQuality float32
+	flowNoise  float64
+	rangeNoise float64
+
+	height float64
+	at     time.Time
+	valid  bool
+}
+
+// Option configures an Estimator.
+type Option func(*Estimator)
+
+// WithRangeLimits sets the range in meters outside which the rangefinder
+// is ignored (default: 0.03 to 2, the VL53L0X in its default mode).
+func WithRangeLimits(min, max float64) Option {
+	return func(e *Estimator) {
+		if min <= 0 || max <= min {
+			panic("velocity: invalid range limits")
+		}
+		e.minRange, e.maxRange = min, max
+	}
+}
+
+// WithMaxRangeAge sets how old the last range may be when flow arrives
+// (default: 200ms).
+func WithMaxRangeAge(d time.Duration) Option {
+	return func(e *Estimator) {
+		e.maxAge = d
+	}
+}
+
+// WithMinQuality rejects flow of at most this quality (default: 0).
+func WithMinQuality(q float32) Option {
+	return func(e *Estimator) {
+		e.minQuality = q
+	}
+}
+
+// WithNoise sets the standard deviation of the flow in image units at
+// quality 1 and of the range as a fraction of it (default: 0.5 and 0.03),
+// which make up Velocity.Variance.
+func WithNoise(flow, rangeFraction float64) Option {
+	return func(e *Estimator) {
+		e.flowNoise, e.rangeNoise = flow, rangeFraction
+	}
+}
+
+// NewEstimator creates an estimator for a camera or sensor with focal
+// lengths fx and fy in image units per radian; see FocalLength.
+func NewEstimator(fx, fy float64, opts ...Option) *Estimator {
+	if fx <= 0 || fy <= 0 {
+		panic("velocity: focal lengths must be positive")
+	}
+	e := &Estimator{
+		fx:         fx,
+		fy:         fy,
+		minRange:   0.03,
+		maxRange:   2,
+		maxAge:     200 * time.Millisecond,
+		flowNoise:  0.5,
+		rangeNoise: 0.03,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// UpdateRange records the range to the ground along the optical axis in
+// meters, measured at the given time. It reports whether the range is
+// within the limits; other ranges are ignored.
+func (e *Estimator) UpdateRange(meters float64, at time.Time) bool {
+	if math.IsNaN(meters) || meters < e.minRange || meters > e.maxRange {
+		return false
+	}
+	e.height, e.at, e.valid = meters, at, true
+	return true
+}
+
+// Update converts flow ending at the given time to velocity. It is false
+// without a recent range, for an empty interval or for flow of too low
+// quality.
+func (e *Estimator) Update(f Flow, at time.Time) (Velocity, bool) {
+	if !e.valid || at.Sub(e.at) > e.maxAge || e.at.Sub(at) > e.maxAge {
+		return Velocity{}, false
+	}
+	if f.Dt <= 0 || f.Quality <= e.minQuality || f.Quality <= 0 {
+		return Velocity{}, false
+	}
+	dt := f.Dt.Seconds()
+	z := e.height
+	vx := -z * (float64(f.Delta[0])/(e.fx*dt) + float64(f.Rate[1]))
+	vy := -z * (float64(f.Delta[1])/(e.fy*dt) - float64(f.Rate[0]))
+
+	sigma := e.flowNoise / float64(min(f.Quality, 1))
+	variance := func(v, focal float64) float32 {
+		fromFlow := z * sigma / (focal * dt)
+		fromRange := v * e.rangeNoise
+		return float32(fromFlow*fromFlow + fromRange*fromRange)
+	}
+	return Velocity{
+		V:        vec.Vector2D{float32(vx), float32(vy)},
+		Variance: vec.Vector2D{variance(vx, e.fx), variance(vy, e.fy)},
+		Height:   float32(z),
+	}, true
+}
+
+// FocalLength returns the focal length in image units per radian of a
+// camera whose field of view of fov radians spans n pixels. Optical flow
+// sensors are best calibrated instead by turning them over a distant
+// texture: the counts reported divided by the angle turned.
+func FocalLength(n int, fov float64) float64 {
+	return float64(n) / 2 / math.Tan(fov/2)
+}
+
+// motionOverflow is the OVF bit of the ADNS3080 Motion register, set when
+// the motion counters overflowed since the last read.
+const motionOverflow = 0x10
+
+// ADNS3080 converts a motion burst read dt after the previous one to flow
+// in sensor counts along the sensor axes. The sensor reports its own
+// motion over the surface, opposite to the motion of the surface image.
+// The quality is SQUAL scaled to 0..1, 0 when the counters overflowed.
+func ADNS3080(m *adns3080.MotionData, dt time.Duration) Flow {
+	f := Flow{
+		Delta:   vec.Vector2D{-float32(m.DeltaX), -float32(m.DeltaY)},
+		Dt:      dt,
+		Quality: float32(m.SQUAL) / 255,
+	}
+	if m.Motion&motionOverflow != 0 {
+		f.Quality = 0
+	}
+	return f
+}
+
+// VL53L0X converts a range in millimeters read from the VL53L0X to meters;
+// it is false for the out of range readings of 8190 mm and above.
+func VL53L0X(mm uint16) (float64, bool) {
+	if mm == 0 || mm >= 8190 {
+		return 0, false
+	}
+	return float64(mm) / 1000, true
+}
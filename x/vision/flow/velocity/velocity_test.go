@@ -0,0 +1,92 @@
+package velocity
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/itohio/EasyRobot/x/devices/adns3080"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+func TestEstimator(t *testing.T) {
+	const fx, fy = 400.0, 410.0
+	e := NewEstimator(fx, fy)
+	now := time.Unix(100, 0)
+	if _, ok := e.Update(Flow{Delta: vec.Vector2D{1, 1}, Dt: 20 * time.Millisecond, Quality: 1}, now); ok {
+		t.Error("velocity without a range")
+	}
+	if !e.UpdateRange(1.2, now) {
+		t.Fatal("range rejected")
+	}
+
+	// image motion of a camera at 1.2 m moving at (0.5, -0.2) m/s while
+	// turning at (0.1, -0.05) rad/s
+	v, rate, dt := vec.Vector2D{0.5, -0.2}, vec.Vector2D{0.1, -0.05}, 0.02
+	delta := vec.Vector2D{
+		float32(fx * dt * (-float64(v[0])/1.2 - float64(rate[1]))),
+		float32(fy * dt * (-float64(v[1])/1.2 + float64(rate[0]))),
+	}
+	got, ok := e.Update(Flow{Delta: delta, Dt: 20 * time.Millisecond, Rate: rate, Quality: 1}, now.Add(10*time.Millisecond))
+	if !ok {
+		t.Fatal("no velocity")
+	}
+	for i := range 2 {
+		if math.Abs(float64(got.V[i]-v[i])) > 1e-4 {
+			t.Errorf("velocity %v, want %v", got.V, v)
+		}
+		if got.Variance[i] <= 0 {
+			t.Errorf("variance %v", got.Variance)
+		}
+	}
+	if got.Height != 1.2 {
+		t.Errorf("height %v", got.Height)
+	}
+
+	worse, _ := e.Update(Flow{Delta: delta, Dt: 20 * time.Millisecond, Rate: rate, Quality: 0.5}, now)
+	if worse.Variance[0] <= got.Variance[0] {
+		t.Errorf("variance %v at half quality, %v at full", worse.Variance, got.Variance)
+	}
+	if _, ok := e.Update(Flow{Delta: delta, Dt: 20 * time.Millisecond}, now); ok {
+		t.Error("velocity from flow of zero quality")
+	}
+	if _, ok := e.Update(Flow{Delta: delta, Dt: 20 * time.Millisecond, Quality: 1}, now.Add(time.Second)); ok {
+		t.Error("velocity from a stale range")
+	}
+	if e.UpdateRange(5, now) {
+		t.Error("range beyond the limits accepted")
+	}
+}
+
+func TestADNS3080(t *testing.T) {
+	f := ADNS3080(&adns3080.MotionData{Motion: 0x80, DeltaX: 5, DeltaY: -3, SQUAL: 51}, 10*time.Millisecond)
+	if f.Delta != (vec.Vector2D{-5, 3}) || f.Dt != 10*time.Millisecond || f.Quality != 0.2 {
+		t.Errorf("flow %+v", f)
+	}
+	if f := ADNS3080(&adns3080.MotionData{Motion: 0x90, DeltaX: 127, SQUAL: 51}, time.Millisecond); f.Quality != 0 {
+		t.Errorf("overflowed flow of quality %v", f.Quality)
+	}
+
+	// the sensor moving along +x over the ground reads positive counts
+	e := NewEstimator(100, 100)
+	e.UpdateRange(0.5, time.Time{})
+	got, ok := e.Update(f, time.Time{})
+	if !ok || got.V[0] <= 0 || got.V[1] >= 0 {
+		t.Errorf("velocity %+v, %v", got, ok)
+	}
+}
+
+func TestVL53L0X(t *testing.T) {
+	if m, ok := VL53L0X(1234); !ok || m != 1.234 {
+		t.Errorf("1234 mm is %v m, %v", m, ok)
+	}
+	if _, ok := VL53L0X(8190); ok {
+		t.Error("out of range reading accepted")
+	}
+}
+
+func TestFocalLength(t *testing.T) {
+	if f := FocalLength(640, math.Pi/2); math.Abs(f-320) > 1e-9 {
+		t.Errorf("focal length %v", f)
+	}
+}
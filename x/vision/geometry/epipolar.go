@@ -0,0 +1,142 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// Fundamental returns the fundamental matrix F of the correspondences,
+// p2^T F p1 = 0, by the normalized 8-point algorithm over all of them (at
+// least 8), with rank 2 enforced.
+func Fundamental(p1, p2 []vec.Vector2D) (mat.Matrix3x3, error) {
+	if len(p1) != len(p2) || len(p1) < 8 {
+		return mat.Matrix3x3{}, ErrTooFewPoints
+	}
+	f, ok := fundamental(p1, p2, indices(len(p1)))
+	if !ok {
+		return mat.Matrix3x3{}, ErrDegenerate
+	}
+	return f.toMat(), nil
+}
+
+// FindFundamental robustly estimates the fundamental matrix with RANSAC
+// over 8-point samples; an inlier is closer than the threshold to both of
+// its epipolar lines. It returns F and the inlier mask.
+func FindFundamental(p1, p2 []vec.Vector2D, opts ...RansacOption) (mat.Matrix3x3, []bool, error) {
+	if len(p1) != len(p2) {
+		return mat.Matrix3x3{}, nil, ErrTooFewPoints
+	}
+	f, mask, err := Ransac(len(p1), 8,
+		func(idx []int) (m3, bool) { return fundamental(p1, p2, idx) },
+		func(f m3, i int) float64 { return epipolarError(f, p1[i], p2[i]) },
+		opts...)
+	if err != nil {
+		return mat.Matrix3x3{}, nil, err
+	}
+	return f.toMat(), mask, nil
+}
+
+// FindEssential robustly estimates the essential matrix of pixel
+// correspondences of a camera with intrinsic matrix k, E = K^T F K. The
+// points are normalized with K and E is fitted by the 8-point algorithm
+// with its singular values forced to (1, 1, 0). The threshold stays in
+// pixels. It returns E and the inlier mask.
+func FindEssential(p1, p2 []vec.Vector2D, k mat.Matrix3x3, opts ...RansacOption) (mat.Matrix3x3, []bool, error) {
+	if len(p1) != len(p2) {
+		return mat.Matrix3x3{}, nil, ErrTooFewPoints
+	}
+	km := fromMat(k)
+	kInv, ok := km.inverse()
+	if !ok {
+		return mat.Matrix3x3{}, nil, ErrDegenerate
+	}
+	n1, n2 := normalizePoints(kInv, p1), normalizePoints(kInv, p2)
+
+	// residuals are in normalized units: scale the pixel threshold
+	cfg := newRansacConfig(opts)
+	focal := (math.Abs(km[0][0]) + math.Abs(km[1][1])) / 2
+	opts = append(opts[:len(opts):len(opts)], WithThreshold(float32(cfg.threshold/focal)))
+
+	e, mask, err := Ransac(len(p1), 8,
+		func(idx []int) (m3, bool) { return essential(n1, n2, idx) },
+		func(e m3, i int) float64 { return epipolarError(e, n1[i], n2[i]) },
+		opts...)
+	if err != nil {
+		return mat.Matrix3x3{}, nil, err
+	}
+	return e.toMat(), mask, nil
+}
+
+// normalizePoints maps pixels to normalized image coordinates with kInv.
+func normalizePoints(kInv m3, points []vec.Vector2D) []vec.Vector2D {
+	out := make([]vec.Vector2D, len(points))
+	for i, p := range points {
+		x, y, w := kInv.apply(float64(p[0]), float64(p[1]))
+		out[i] = vec.Vector2D{float32(x / w), float32(y / w)}
+	}
+	return out
+}
+
+// eightPoint solves p2^T F p1 = 0 on Hartley normalized points and returns
+// the normalized solution with both normalizations.
+func eightPoint(p1, p2 []vec.Vector2D, idx []int) (f, t1, t2 m3, ok bool) {
+	t1, ok1 := normalization(p1, idx)
+	t2, ok2 := normalization(p2, idx)
+	if !ok1 || !ok2 {
+		return m3{}, m3{}, m3{}, false
+	}
+	rows := make([][9]float64, 0, len(idx))
+	for _, i := range idx {
+		x1, y1, _ := t1.apply(float64(p1[i][0]), float64(p1[i][1]))
+		x2, y2, _ := t2.apply(float64(p2[i][0]), float64(p2[i][1]))
+		rows = append(rows, [9]float64{x2 * x1, x2 * y1, x2, y2 * x1, y2 * y1, y2, x1, y1, 1})
+	}
+	null, ok := nullVector(rows)
+	if !ok {
+		return m3{}, m3{}, m3{}, false
+	}
+	return fromNull(null), t1, t2, true
+}
+
+func fundamental(p1, p2 []vec.Vector2D, idx []int) (m3, bool) {
+	f, t1, t2, ok := eightPoint(p1, p2, idx)
+	if !ok {
+		return m3{}, false
+	}
+	u, s, vt, ok := svd3(f)
+	if !ok {
+		return m3{}, false
+	}
+	s[2] = 0
+	return t2.t().mul(compose(u, s, vt)).mul(t1).normalized(), true
+}
+
+func essential(p1, p2 []vec.Vector2D, idx []int) (m3, bool) {
+	f, t1, t2, ok := eightPoint(p1, p2, idx)
+	if !ok {
+		return m3{}, false
+	}
+	e := t2.t().mul(f).mul(t1)
+	u, _, vt, ok := svd3(e)
+	if !ok {
+		return m3{}, false
+	}
+	return compose(u, [3]float64{1, 1, 0}, vt).normalized(), true
+}
+
+// epipolarError returns the larger distance of the points to the epipolar
+// lines of each other, F p1 in the second image and F^T p2 in the first.
+func epipolarError(f m3, p1, p2 vec.Vector2D) float64 {
+	x1, y1 := float64(p1[0]), float64(p1[1])
+	x2, y2 := float64(p2[0]), float64(p2[1])
+	a2, b2, c2 := f.apply(x1, y1)
+	a1, b1, _ := f.t().apply(x2, y2)
+	d := a2*x2 + b2*y2 + c2
+	n2, n1 := math.Hypot(a2, b2), math.Hypot(a1, b1)
+	if n1 == 0 || n2 == 0 {
+		return math.Inf(1)
+	}
+	return max(math.Abs(d)/n2, math.Abs(d)/n1)
+}
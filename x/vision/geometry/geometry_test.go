@@ -0,0 +1,263 @@
+package geometry
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+func TestRansacIterations(t *testing.T) {
+	if n := adaptiveIterations(1, 4, 0.99); n != 1 {
+		t.Errorf("all inliers: %d iterations", n)
+	}
+	// log(0.01) / log(1 - 0.5^4) = 71.3
+	if n := adaptiveIterations(0.5, 4, 0.99); n != 72 {
+		t.Errorf("half inliers: %d iterations, want 72", n)
+	}
+	if _, _, err := Ransac(3, 4, func([]int) (int, bool) { return 0, true }, func(int, int) float64 { return 0 }); err != ErrTooFewPoints {
+		t.Errorf("err = %v, want ErrTooFewPoints", err)
+	}
+}
+
+// TestRansacLine fits a line to points with outliers through the generic
+// interface.
+func TestRansacLine(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	type line struct{ a, b float64 }
+	xs, ys := make([]float64, 100), make([]float64, 100)
+	for i := range xs {
+		xs[i] = float64(i)
+		ys[i] = 2*xs[i] + 1 + r.Float64()*0.2 - 0.1
+		if i%4 == 0 {
+			ys[i] = r.Float64() * 300
+		}
+	}
+	fit := func(idx []int) (line, bool) {
+		// least squares
+		var sx, sy, sxx, sxy float64
+		for _, i := range idx {
+			sx, sy, sxx, sxy = sx+xs[i], sy+ys[i], sxx+xs[i]*xs[i], sxy+xs[i]*ys[i]
+		}
+		n := float64(len(idx))
+		d := n*sxx - sx*sx
+		if d == 0 {
+			return line{}, false
+		}
+		a := (n*sxy - sx*sy) / d
+		return line{a, (sy - a*sx) / n}, true
+	}
+	l, mask, err := Ransac(len(xs), 2, fit, func(l line, i int) float64 {
+		return math.Abs(l.a*xs[i] + l.b - ys[i])
+	}, WithThreshold(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(l.a-2) > 0.01 || math.Abs(l.b-1) > 0.5 {
+		t.Errorf("line %+v, want 2x + 1", l)
+	}
+	for i, in := range mask {
+		if in && i%4 == 0 && math.Abs(ys[i]-2*xs[i]-1) > 0.5 {
+			t.Errorf("outlier %d is an inlier", i)
+		}
+	}
+}
+
+func TestHomography(t *testing.T) {
+	want := mat.Matrix3x3{{1.1, 0.05, 20}, {-0.03, 0.95, -10}, {0.0004, -0.0002, 1}}
+	r := rand.New(rand.NewSource(1))
+	var src, dst []vec.Vector2D
+	for i := range 60 {
+		p := vec.Vector2D{r.Float32() * 640, r.Float32() * 480}
+		q := PerspectiveTransform(want, p)
+		if i%5 == 0 {
+			// outliers
+			q = vec.Vector2D{r.Float32() * 640, r.Float32() * 480}
+		} else {
+			q[0] += float32(r.NormFloat64() * 0.3)
+			q[1] += float32(r.NormFloat64() * 0.3)
+		}
+		src, dst = append(src, p), append(dst, q)
+	}
+
+	h, mask, err := FindHomography(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, in := range mask {
+		if in == (i%5 == 0) {
+			t.Errorf("point %d: inlier %v", i, in)
+		}
+	}
+	for _, p := range []vec.Vector2D{{0, 0}, {320, 240}, {640, 480}} {
+		got, exp := PerspectiveTransform(h, p), PerspectiveTransform(want, p)
+		if d := math.Hypot(float64(got[0]-exp[0]), float64(got[1]-exp[1])); d > 1 {
+			t.Errorf("%v maps to %v, want %v", p, got, exp)
+		}
+	}
+
+	// exact solution from four points
+	h, err = Homography(src[1:5], dst[1:5])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 5; i++ {
+		got := PerspectiveTransform(h, src[i])
+		if d := math.Hypot(float64(got[0]-dst[i][0]), float64(got[1]-dst[i][1])); d > 0.05 {
+			t.Errorf("point %d maps to %v, want %v", i, got, dst[i])
+		}
+	}
+
+	collinear := []vec.Vector2D{{0, 0}, {1, 1}, {2, 2}, {3, 3}}
+	if _, err := Homography(collinear, collinear); err == nil {
+		t.Error("collinear points accepted")
+	}
+	if _, err := Homography(src[:3], dst[:3]); err != ErrTooFewPoints {
+		t.Errorf("err = %v, want ErrTooFewPoints", err)
+	}
+}
+
+// stereoScene projects random points in front of two cameras; the second
+// is rotated about y and translated by t.
+func stereoScene(r *rand.Rand, n int, k m3, angle float64, t [3]float64) (p1, p2 []vec.Vector2D, e m3) {
+	s, c := math.Sincos(angle)
+	rot := m3{{c, 0, s}, {0, 1, 0}, {-s, 0, c}}
+	project := func(x, y, z float64) vec.Vector2D {
+		u, v, w := k.apply(x/z, y/z)
+		return vec.Vector2D{float32(u / w), float32(v / w)}
+	}
+	for range n {
+		x, y, z := r.Float64()*4-2, r.Float64()*3-1.5, 4+r.Float64()*6
+		x2 := rot[0][0]*x + rot[0][1]*y + rot[0][2]*z + t[0]
+		y2 := rot[1][0]*x + rot[1][1]*y + rot[1][2]*z + t[1]
+		z2 := rot[2][0]*x + rot[2][1]*y + rot[2][2]*z + t[2]
+		p1 = append(p1, project(x, y, z))
+		p2 = append(p2, project(x2, y2, z2))
+	}
+	tx := m3{{0, -t[2], t[1]}, {t[2], 0, -t[0]}, {-t[1], t[0], 0}}
+	return p1, p2, tx.mul(rot).normalized()
+}
+
+func TestFundamental(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	k := m3{{500, 0, 320}, {0, 500, 240}, {0, 0, 1}}
+	p1, p2, _ := stereoScene(r, 80, k, 0.1, [3]float64{-0.5, 0.05, 0.1})
+	for i := 0; i < len(p2); i += 6 {
+		p2[i] = vec.Vector2D{r.Float32() * 640, r.Float32() * 480}
+	}
+
+	f, mask, err := FindFundamental(p1, p2, WithThreshold(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outliers := 0
+	for i, in := range mask {
+		if !in {
+			outliers++
+			continue
+		}
+		if d := epipolarError(fromMat(f), p1[i], p2[i]); d > 1 {
+			t.Errorf("inlier %d is %v px from its epipolar line", i, d)
+		}
+	}
+	if outliers < 10 || outliers > 16 {
+		t.Errorf("%d outliers, want 14", outliers)
+	}
+	_, s, _, _ := svd3(fromMat(f))
+	if s[2] > 1e-6*s[0] {
+		t.Errorf("F has rank 3: singular values %v", s)
+	}
+
+	if _, err := Fundamental(p1[:7], p2[:7]); err != ErrTooFewPoints {
+		t.Errorf("err = %v, want ErrTooFewPoints", err)
+	}
+}
+
+func TestEssential(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	k := m3{{450, 0, 320}, {0, 460, 240}, {0, 0, 1}}
+	p1, p2, want := stereoScene(r, 60, k, -0.15, [3]float64{0.4, 0, 0.2})
+	p2[3] = vec.Vector2D{10, 10}
+
+	e, mask, err := FindEssential(p1, p2, k.toMat(), WithThreshold(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mask[3] {
+		t.Error("outlier accepted")
+	}
+	got := fromMat(e)
+	// equal up to sign
+	var plus, minus float64
+	for i := range 3 {
+		for j := range 3 {
+			plus += math.Abs(got[i][j] - want[i][j])
+			minus += math.Abs(got[i][j] + want[i][j])
+		}
+	}
+	if min(plus, minus) > 0.02 {
+		t.Errorf("E = %v, want %v", got, want)
+	}
+	_, s, _, _ := svd3(got)
+	if math.Abs(s[0]-s[1]) > 1e-3 || s[2] > 1e-3 {
+		t.Errorf("singular values %v, want (s, s, 0)", s)
+	}
+}
+
+func TestRecoverPose(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	k := m3{{450, 0, 320}, {0, 460, 240}, {0, 0, 1}}
+	tr := [3]float64{0.6, -0.1, 0.8}
+	p1, p2, _ := stereoScene(r, 60, k, 0.12, tr)
+	e, mask, err := FindEssential(p1, p2, k.toMat(), WithThreshold(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rot, tt, inliers, err := RecoverPose(e, p1, p2, k.toMat(), mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, c := math.Sincos(0.12)
+	want := m3{{c, 0, s}, {0, 1, 0}, {-s, 0, c}}
+	for i := range 3 {
+		for j := range 3 {
+			if d := math.Abs(float64(rot[i][j]) - want[i][j]); d > 1e-3 {
+				t.Errorf("R = %v, want %v", rot, want)
+			}
+		}
+	}
+	n := math.Sqrt(tr[0]*tr[0] + tr[1]*tr[1] + tr[2]*tr[2])
+	for i := range 3 {
+		if d := math.Abs(float64(tt[i]) - tr[i]/n); d > 1e-3 {
+			t.Errorf("t = %v, want %v", tt, tr)
+		}
+	}
+	count := 0
+	for _, in := range inliers {
+		if in {
+			count++
+		}
+	}
+	if count < 58 {
+		t.Errorf("%d points pass the cheirality check", count)
+	}
+
+	// triangulation in first camera coordinates, scaled by the baseline
+	kInv, _ := k.inverse()
+	n1, n2 := normalizePoints(kInv, p1[:1]), normalizePoints(kInv, p2[:1])
+	p, ok := Triangulate(rot, tt, n1[0], n2[0])
+	if !ok {
+		t.Fatal("point behind the cameras")
+	}
+	u, v, w := k.apply(float64(p[0]/p[2]), float64(p[1]/p[2]))
+	if math.Hypot(u/w-float64(p1[0][0]), v/w-float64(p1[0][1])) > 0.01 {
+		t.Errorf("triangulated %v projects off %v", p, p1[0])
+	}
+	// parallel rays
+	eye := mat.Matrix3x3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	if _, ok := Triangulate(eye, vec.Vector3D{1, 0, 0}, n1[0], n1[0]); ok {
+		t.Error("triangulated parallel rays")
+	}
+}
@@ -0,0 +1,146 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// Homography returns the homography H mapping src to dst, dst ~ H src, as
+// the least squares solution of the direct linear transform over all
+// correspondences (at least 4). H is scaled to unit norm.
+func Homography(src, dst []vec.Vector2D) (mat.Matrix3x3, error) {
+	if len(src) != len(dst) || len(src) < 4 {
+		return mat.Matrix3x3{}, ErrTooFewPoints
+	}
+	h, ok := homography(src, dst, indices(len(src)))
+	if !ok {
+		return mat.Matrix3x3{}, ErrDegenerate
+	}
+	return h.toMat(), nil
+}
+
+// FindHomography robustly estimates the homography mapping src to dst
+// with RANSAC; an inlier's transfer error |dst - H src| is below the
+// threshold. It returns the homography and the inlier mask.
+func FindHomography(src, dst []vec.Vector2D, opts ...RansacOption) (mat.Matrix3x3, []bool, error) {
+	if len(src) != len(dst) {
+		return mat.Matrix3x3{}, nil, ErrTooFewPoints
+	}
+	h, mask, err := Ransac(len(src), 4,
+		func(idx []int) (m3, bool) { return homography(src, dst, idx) },
+		func(h m3, i int) float64 { return transferError(h, src[i], dst[i]) },
+		opts...)
+	if err != nil {
+		return mat.Matrix3x3{}, nil, err
+	}
+	return h.toMat(), mask, nil
+}
+
+// PerspectiveTransform maps p by the homography h.
+func PerspectiveTransform(h mat.Matrix3x3, p vec.Vector2D) vec.Vector2D {
+	x, y, w := fromMat(h).apply(float64(p[0]), float64(p[1]))
+	return vec.Vector2D{float32(x / w), float32(y / w)}
+}
+
+func homography(src, dst []vec.Vector2D, idx []int) (m3, bool) {
+	if degenerate(src, idx) || degenerate(dst, idx) {
+		return m3{}, false
+	}
+	ts, ok1 := normalization(src, idx)
+	td, ok2 := normalization(dst, idx)
+	if !ok1 || !ok2 {
+		return m3{}, false
+	}
+	rows := make([][9]float64, 0, 2*len(idx))
+	for _, i := range idx {
+		x, y, _ := ts.apply(float64(src[i][0]), float64(src[i][1]))
+		u, v, _ := td.apply(float64(dst[i][0]), float64(dst[i][1]))
+		rows = append(rows,
+			[9]float64{-x, -y, -1, 0, 0, 0, u * x, u * y, u},
+			[9]float64{0, 0, 0, -x, -y, -1, v * x, v * y, v},
+		)
+	}
+	null, ok := nullVector(rows)
+	if !ok {
+		return m3{}, false
+	}
+	tdInv, ok := td.inverse()
+	if !ok {
+		return m3{}, false
+	}
+	h := tdInv.mul(fromNull(null)).mul(ts).normalized()
+
+	// a homography must be invertible and keep every point in front
+	if _, ok := h.inverse(); !ok {
+		return m3{}, false
+	}
+	sign := 0.0
+	for _, i := range idx {
+		_, _, w := h.apply(float64(src[i][0]), float64(src[i][1]))
+		switch {
+		case sign == 0:
+			sign = w
+		case sign*w <= 0:
+			return m3{}, false
+		}
+	}
+	return h, true
+}
+
+func transferError(h m3, src, dst vec.Vector2D) float64 {
+	x, y, w := h.apply(float64(src[0]), float64(src[1]))
+	if w == 0 {
+		return math.Inf(1)
+	}
+	return math.Hypot(x/w-float64(dst[0]), y/w-float64(dst[1]))
+}
+
+func indices(n int) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
+
+// degenerate reports whether a minimal sample has three collinear points,
+// or a larger set lies on a line.
+func degenerate(points []vec.Vector2D, idx []int) bool {
+	cross := func(a, b, c vec.Vector2D) float64 {
+		abx, aby := float64(b[0]-a[0]), float64(b[1]-a[1])
+		acx, acy := float64(c[0]-a[0]), float64(c[1]-a[1])
+		return abx*acy - aby*acx
+	}
+	if len(idx) == 4 {
+		for skip := range 4 {
+			var tri []vec.Vector2D
+			for j, i := range idx {
+				if j != skip {
+					tri = append(tri, points[i])
+				}
+			}
+			a, b, c := tri[0], tri[1], tri[2]
+			scale := max(a.DistanceSqr(b), a.DistanceSqr(c), b.DistanceSqr(c))
+			if math.Abs(cross(a, b, c)) <= 1e-6*float64(scale) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the scatter matrix of points on a line is singular
+	var cx, cy float64
+	for _, i := range idx {
+		cx += float64(points[i][0])
+		cy += float64(points[i][1])
+	}
+	cx, cy = cx/float64(len(idx)), cy/float64(len(idx))
+	var sxx, syy, sxy float64
+	for _, i := range idx {
+		dx, dy := float64(points[i][0])-cx, float64(points[i][1])-cy
+		sxx, syy, sxy = sxx+dx*dx, syy+dy*dy, sxy+dx*dy
+	}
+	return sxx*syy-sxy*sxy <= 1e-9*(sxx+syy)*(sxx+syy)
+}
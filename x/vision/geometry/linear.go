@@ -0,0 +1,264 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// m3 is a 3x3 matrix in float64 for intermediate results.
+type m3 [3][3]float64
+
+func fromMat(m mat.Matrix3x3) m3 {
+	var out m3
+	for i := range 3 {
+		for j := range 3 {
+			out[i][j] = float64(m[i][j])
+		}
+	}
+	return out
+}
+
+func (m m3) toMat() mat.Matrix3x3 {
+	var out mat.Matrix3x3
+	for i := range 3 {
+		for j := range 3 {
+			out[i][j] = float32(m[i][j])
+		}
+	}
+	return out
+}
+
+func (m m3) mul(b m3) m3 {
+	var out m3
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				out[i][j] += m[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func (m m3) t() m3 {
+	var out m3
+	for i := range 3 {
+		for j := range 3 {
+			out[i][j] = m[j][i]
+		}
+	}
+	return out
+}
+
+// apply returns m * (x, y, 1).
+func (m m3) apply(x, y float64) (float64, float64, float64) {
+	return m[0][0]*x + m[0][1]*y + m[0][2],
+		m[1][0]*x + m[1][1]*y + m[1][2],
+		m[2][0]*x + m[2][1]*y + m[2][2]
+}
+
+func (m m3) det() float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+func (m m3) scale(s float64) m3 {
+	for i := range 3 {
+		for j := range 3 {
+			m[i][j] *= s
+		}
+	}
+	return m
+}
+
+func (m m3) inverse() (m3, bool) {
+	det := m.det()
+	if det == 0 || math.IsNaN(det) {
+		return m3{}, false
+	}
+	var out m3
+	for i := range 3 {
+		for j := range 3 {
+			a, b := (j+1)%3, (j+2)%3
+			c, d := (i+1)%3, (i+2)%3
+			out[i][j] = (m[a][c]*m[b][d] - m[a][d]*m[b][c]) / det
+		}
+	}
+	return out, true
+}
+
+// normalized scales m to unit Frobenius norm with a non-negative last
+// element.
+func (m m3) normalized() m3 {
+	var n float64
+	for i := range 3 {
+		for j := range 3 {
+			n += m[i][j] * m[i][j]
+		}
+	}
+	n = math.Sqrt(n)
+	if m[2][2] < 0 {
+		n = -n
+	}
+	if n == 0 {
+		return m
+	}
+	for i := range 3 {
+		for j := range 3 {
+			m[i][j] /= n
+		}
+	}
+	return m
+}
+
+// normalization returns the similarity moving the centroid of points to
+// the origin with a mean distance of sqrt(2) (Hartley).
+func normalization(points []vec.Vector2D, idx []int) (m3, bool) {
+	var cx, cy float64
+	for _, i := range idx {
+		cx += float64(points[i][0])
+		cy += float64(points[i][1])
+	}
+	n := float64(len(idx))
+	cx, cy = cx/n, cy/n
+	var d float64
+	for _, i := range idx {
+		d += math.Hypot(float64(points[i][0])-cx, float64(points[i][1])-cy)
+	}
+	d /= n
+	if d < 1e-12 {
+		return m3{}, false
+	}
+	s := math.Sqrt2 / d
+	return m3{{s, 0, -s * cx}, {0, s, -s * cy}, {0, 0, 1}}, true
+}
+
+// nullVector returns the unit vector minimising |A v| for the rows of A: the
+// eigenvector of the smallest eigenvalue of AᵀA. The normal equations are
+// accumulated in float64, which is accurate enough for normalized points and
+// keeps the cost independent of the number of rows.
+func nullVector(rows [][9]float64) ([9]float64, bool) {
+	var ata [9][9]float64
+	for _, r := range rows {
+		for i := range 9 {
+			for j := i; j < 9; j++ {
+				ata[i][j] += r[i] * r[j]
+			}
+		}
+	}
+	for i := range 9 {
+		for j := range i {
+			ata[i][j] = ata[j][i]
+		}
+	}
+	values, vectors, ok := symmetricEigen(ata)
+	if !ok {
+		return [9]float64{}, false
+	}
+	smallest := 0
+	for i, v := range values {
+		if v < values[smallest] {
+			smallest = i
+		}
+	}
+	var out [9]float64
+	for j := range out {
+		out[j] = vectors[j][smallest]
+	}
+	return out, true
+}
+
+// symmetricEigen diagonalises the symmetric matrix a with cyclic Jacobi
+// rotations. The columns of vectors are the eigenvectors.
+func symmetricEigen(a [9][9]float64) (values [9]float64, vectors [9][9]float64, ok bool) {
+	const n = 9
+	for i := range n {
+		vectors[i][i] = 1
+	}
+	for range 50 {
+		var off, diag float64
+		for i := range n {
+			diag += a[i][i] * a[i][i]
+			for j := i + 1; j < n; j++ {
+				off += a[i][j] * a[i][j]
+			}
+		}
+		if off <= 1e-30*diag || off == 0 {
+			for i := range n {
+				values[i] = a[i][i]
+			}
+			return values, vectors, true
+		}
+		for p := range n {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := range n {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := range n {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+				for k := range n {
+					vkp, vkq := vectors[k][p], vectors[k][q]
+					vectors[k][p], vectors[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+	return values, vectors, false
+}
+
+// svd3 decomposes m = U diag(s) Vt with singular values in descending order.
+func svd3(m m3) (u m3, s [3]float64, vt m3, ok bool) {
+	a := mat.New(3, 3)
+	for i := range 3 {
+		for j := range 3 {
+			a[i][j] = float32(m[i][j])
+		}
+	}
+	var res mat.SVDResult
+	if err := a.SVD(&res); err != nil {
+		return m3{}, s, m3{}, false
+	}
+	sv := res.S.View().(vec.Vector)
+	um, vm := res.U.View().(mat.Matrix), res.Vt.View().(mat.Matrix)
+	order := []int{0, 1, 2}
+	for i := range 3 {
+		for j := i + 1; j < 3; j++ {
+			if sv[order[j]] > sv[order[i]] {
+				order[i], order[j] = order[j], order[i]
+			}
+		}
+	}
+	for k, o := range order {
+		s[k] = float64(sv[o])
+		for i := range 3 {
+			u[i][k] = float64(um[i][o])
+			vt[k][i] = float64(vm[o][i])
+		}
+	}
+	return u, s, vt, true
+}
+
+func compose(u m3, s [3]float64, vt m3) m3 {
+	var d m3
+	for i := range 3 {
+		d[i][i] = s[i]
+	}
+	return u.mul(d).mul(vt)
+}
+
+func fromNull(v [9]float64) m3 {
+	return m3{{v[0], v[1], v[2]}, {v[3], v[4], v[5]}, {v[6], v[7], v[8]}}
+}
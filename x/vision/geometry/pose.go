@@ -0,0 +1,132 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// DecomposeEssential returns the two rotations and the unit translation an
+// essential matrix E = [t]x R can be made of; the pose is one of (r1, t),
+// (r1, -t), (r2, t) and (r2, -t). RecoverPose picks it.
+func DecomposeEssential(e mat.Matrix3x3) (r1, r2 mat.Matrix3x3, t vec.Vector3D, err error) {
+	a, b, tt, err := decomposeEssential(fromMat(e))
+	if err != nil {
+		return r1, r2, t, err
+	}
+	return a.toMat(), b.toMat(), vec.Vector3D{float32(tt[0]), float32(tt[1]), float32(tt[2])}, nil
+}
+
+func decomposeEssential(e m3) (r1, r2 m3, t [3]float64, err error) {
+	u, _, vt, ok := svd3(e)
+	if !ok {
+		return m3{}, m3{}, t, ErrDegenerate
+	}
+	if u.det() < 0 {
+		u = u.scale(-1)
+	}
+	if vt.det() < 0 {
+		vt = vt.scale(-1)
+	}
+	w := m3{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}}
+	return u.mul(w).mul(vt), u.mul(w.t()).mul(vt), [3]float64{u[0][2], u[1][2], u[2][2]}, nil
+}
+
+// maxDepth is the distance, in units of the baseline, beyond which
+// triangulated points do not vote in RecoverPose: far points are in front
+// of the cameras under every decomposition.
+const maxDepth = 50
+
+// RecoverPose picks the decomposition of an essential matrix, e.g. from
+// FindEssential, that puts the most correspondences in front of both
+// cameras (the cheirality check). The pixels are of a camera with
+// intrinsic matrix k and mask, when not nil, selects the points to test.
+// The pose maps first camera coordinates to second camera coordinates,
+// X2 = R X1 + t, with |t| = 1. It also returns the points of the mask that
+// pass the check.
+func RecoverPose(e mat.Matrix3x3, p1, p2 []vec.Vector2D, k mat.Matrix3x3, mask []bool) (r mat.Matrix3x3, t vec.Vector3D, inliers []bool, err error) {
+	if len(p1) != len(p2) || (mask != nil && len(mask) != len(p1)) {
+		return r, t, nil, ErrTooFewPoints
+	}
+	kInv, ok := fromMat(k).inverse()
+	if !ok {
+		return r, t, nil, ErrDegenerate
+	}
+	n1, n2 := normalizePoints(kInv, p1), normalizePoints(kInv, p2)
+	r1, r2, tt, err := decomposeEssential(fromMat(e))
+	if err != nil {
+		return r, t, nil, err
+	}
+
+	best := 0
+	for _, cand := range [4]struct {
+		r m3
+		t [3]float64
+	}{{r1, tt}, {r1, neg(tt)}, {r2, tt}, {r2, neg(tt)}} {
+		in := make([]bool, len(p1))
+		count := 0
+		for i := range n1 {
+			if mask != nil && !mask[i] {
+				continue
+			}
+			if p, ok := triangulate(cand.r, cand.t, n1[i], n2[i]); ok && p[2] < maxDepth {
+				in[i] = true
+				count++
+			}
+		}
+		if count > best {
+			best, inliers = count, in
+			r, t = cand.r.toMat(), vec.Vector3D{float32(cand.t[0]), float32(cand.t[1]), float32(cand.t[2])}
+		}
+	}
+	if best == 0 {
+		return mat.Matrix3x3{}, vec.Vector3D{}, nil, ErrDegenerate
+	}
+	return r, t, inliers, nil
+}
+
+// Triangulate returns the point seen at normalized image coordinates x1 in
+// the first camera and x2 in the second, X2 = R X1 + t, in first camera
+// coordinates: the midpoint of the closest points of the two rays. It is
+// false when the rays are parallel or the point is behind either camera.
+func Triangulate(r mat.Matrix3x3, t vec.Vector3D, x1, x2 vec.Vector2D) (vec.Vector3D, bool) {
+	p, ok := triangulate(fromMat(r), [3]float64{float64(t[0]), float64(t[1]), float64(t[2])}, x1, x2)
+	return vec.Vector3D{float32(p[0]), float32(p[1]), float32(p[2])}, ok
+}
+
+func triangulate(r m3, t [3]float64, x1, x2 vec.Vector2D) ([3]float64, bool) {
+	// z2 b - z1 a = t with a = R x1 and b = x2, in the least squares sense
+	a0, a1, a2 := r.apply(float64(x1[0]), float64(x1[1]))
+	a := [3]float64{a0, a1, a2}
+	b := [3]float64{float64(x2[0]), float64(x2[1]), 1}
+	aa, bb, ab := dot3(a, a), dot3(b, b), dot3(a, b)
+	at, bt := dot3(a, t), dot3(b, t)
+	det := ab*ab - aa*bb
+	if math.Abs(det) < 1e-12*aa*bb {
+		return [3]float64{}, false
+	}
+	z1 := (bb*at - ab*bt) / det
+	z2 := (ab*at - aa*bt) / det
+	if z1 <= 0 || z2 <= 0 {
+		return [3]float64{}, false
+	}
+	// the midpoint, mapped back to the first camera
+	var mid [3]float64
+	for i := range 3 {
+		mid[i] = (z1*a[i] + z2*b[i] - t[i]) / 2
+	}
+	rt := r.t()
+	m0, m1, m2 := rt[0][0]*mid[0]+rt[0][1]*mid[1]+rt[0][2]*mid[2],
+		rt[1][0]*mid[0]+rt[1][1]*mid[1]+rt[1][2]*mid[2],
+		rt[2][0]*mid[0]+rt[2][1]*mid[1]+rt[2][2]*mid[2]
+	return [3]float64{m0, m1, m2}, true
+}
+
+func dot3(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func neg(v [3]float64) [3]float64 {
+	return [3]float64{-v[0], -v[1], -v[2]}
+}
@@ -0,0 +1,185 @@
+// Package geometry estimates multiple view geometry from point
+// correspondences: homographies and fundamental and essential matrices,
+// robustly with RANSAC. Linear solutions use the SVD of x/math/mat on
+// Hartley normalized points.
+package geometry
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+var (
+	// ErrTooFewPoints is returned when there are fewer correspondences than
+	// the model needs.
+	ErrTooFewPoints = errors.New("geometry: too few points")
+	// ErrDegenerate is returned when no model fits the points, e.g. when
+	// they are collinear or fewer than the minimal sample are inliers.
+	ErrDegenerate = errors.New("geometry: degenerate configuration")
+)
+
+// RansacOption configures robust estimation.
+type RansacOption func(*ransacConfig)
+
+type ransacConfig struct {
+	threshold     float64
+	confidence    float64
+	maxIterations int
+	rng           *rand.Rand
+}
+
+// WithThreshold sets the largest residual of an inlier in pixels
+// (default: 3).
+func WithThreshold(px float32) RansacOption {
+	return func(c *ransacConfig) {
+		if px <= 0 {
+			panic("geometry: RANSAC threshold must be positive")
+		}
+		c.threshold = float64(px)
+	}
+}
+
+// WithConfidence sets the probability of drawing at least one outlier free
+// sample, which bounds the number of iterations (default: 0.99).
+func WithConfidence(p float32) RansacOption {
+	return func(c *ransacConfig) {
+		if p <= 0 || p >= 1 {
+			panic("geometry: RANSAC confidence must be in (0, 1)")
+		}
+		c.confidence = float64(p)
+	}
+}
+
+// WithMaxIterations caps the number of samples (default: 2000).
+func WithMaxIterations(n int) RansacOption {
+	return func(c *ransacConfig) {
+		if n <= 0 {
+			panic("geometry: RANSAC iterations must be positive")
+		}
+		c.maxIterations = n
+	}
+}
+
+// WithRand sets the random source for reproducible results (default: a
+// fixed seed).
+func WithRand(r *rand.Rand) RansacOption {
+	return func(c *ransacConfig) {
+		c.rng = r
+	}
+}
+
+func newRansacConfig(opts []RansacOption) ransacConfig {
+	c := ransacConfig{threshold: 3, confidence: 0.99, maxIterations: 2000}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.rng == nil {
+		c.rng = rand.New(rand.NewSource(1))
+	}
+	return c
+}
+
+// Ransac robustly fits a model to n data points. fit estimates a model from
+// the points with the given indices and reports false for a degenerate
+// sample; residual returns the error of point i under a model, compared
+// with the threshold. The best model is refitted to all of its inliers.
+// It returns the model and the inlier mask.
+func Ransac[M any](n, sampleSize int, fit func(idx []int) (M, bool), residual func(m M, i int) float64, opts ...RansacOption) (M, []bool, error) {
+	var zero M
+	if sampleSize <= 0 || n < sampleSize {
+		return zero, nil, ErrTooFewPoints
+	}
+	cfg := newRansacConfig(opts)
+
+	inliers := func(m M, mask []bool) int {
+		count := 0
+		for i := range n {
+			mask[i] = residual(m, i) < cfg.threshold
+			if mask[i] {
+				count++
+			}
+		}
+		return count
+	}
+
+	var best M
+	bestMask, mask := make([]bool, n), make([]bool, n)
+	bestCount := 0
+	sample := make([]int, sampleSize)
+	iterations := cfg.maxIterations
+	for it := 0; it < iterations; it++ {
+		drawSample(cfg.rng, n, sample)
+		m, ok := fit(sample)
+		if !ok {
+			continue
+		}
+		if count := inliers(m, mask); count > bestCount {
+			best, bestCount = m, count
+			bestMask, mask = mask, bestMask
+			iterations = min(iterations, adaptiveIterations(float64(count)/float64(n), sampleSize, cfg.confidence))
+		}
+	}
+	if bestCount < sampleSize {
+		return zero, nil, ErrDegenerate
+	}
+
+	// refit to all inliers while that does not lose support
+	for range 3 {
+		idx := make([]int, 0, bestCount)
+		for i, in := range bestMask {
+			if in {
+				idx = append(idx, i)
+			}
+		}
+		m, ok := fit(idx)
+		if !ok {
+			break
+		}
+		count := inliers(m, mask)
+		if count < bestCount {
+			break
+		}
+		improved := count > bestCount
+		best, bestCount = m, count
+		bestMask, mask = mask, bestMask
+		if !improved {
+			break
+		}
+	}
+	return best, bestMask, nil
+}
+
+// drawSample fills sample with distinct random indices below n.
+func drawSample(r *rand.Rand, n int, sample []int) {
+	for i := range sample {
+	draw:
+		for {
+			v := r.Intn(n)
+			for _, s := range sample[:i] {
+				if s == v {
+					continue draw
+				}
+			}
+			sample[i] = v
+			break
+		}
+	}
+}
+
+// adaptiveIterations returns the number of samples needed to draw an
+// outlier free one with the given confidence at an inlier ratio w.
+func adaptiveIterations(w float64, sampleSize int, confidence float64) int {
+	p := math.Pow(w, float64(sampleSize))
+	switch {
+	case p >= 1:
+		return 1
+	case p <= 0:
+		return math.MaxInt
+	}
+	n := math.Log(1-confidence) / math.Log(1-p)
+	if n > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(math.Ceil(n))
+}
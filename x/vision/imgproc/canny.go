@@ -0,0 +1,124 @@
+package imgproc
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Canny finds edges in a single channel image (cv::Canny). Gradients come
+// from a ksize Sobel aperture (3, 5 or 7); l2 selects the Euclidean instead
+// of the L1 gradient magnitude. Pixels with a magnitude above high are
+// edges, and those above low are edges when connected to one. The result is
+// a UINT8 [H, W] tensor of 0 and 255.
+func Canny(src types.Tensor, low, high float32, ksize int, l2 bool) (types.Tensor, error) {
+	if ksize != 3 && ksize != 5 && ksize != 7 {
+		return nil, fmt.Errorf("imgproc: Canny aperture must be 3, 5 or 7, got %d", ksize)
+	}
+	if low > high {
+		low, high = high, low
+	}
+	p, err := load(src, HWC)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.c) != 1 {
+		return nil, fmt.Errorf("imgproc: Canny needs a single channel image")
+	}
+	deriv, smooth := derivKernel(1, ksize, false), derivKernel(0, ksize, false)
+	gx := separable(p, deriv, smooth, replicate).c[0]
+	gy := separable(p, smooth, deriv, replicate).c[0]
+
+	w, h := p.w, p.h
+	mag := make([]float32, w*h)
+	for i := range mag {
+		if l2 {
+			mag[i] = float32(math.Hypot(float64(gx[i]), float64(gy[i])))
+		} else {
+			mag[i] = abs(gx[i]) + abs(gy[i])
+		}
+	}
+
+	// non-maximum suppression along the gradient direction, quantised to
+	// 0, 45, 90 and 135 degrees with OpenCV's Q15 tan(22.5); tan(67.5) is
+	// tan(22.5) + 2
+	const tan22 = 13573.0 / (1 << 15)
+	const (
+		none = iota
+		weak
+		strong
+	)
+	state := make([]uint8, w*h)
+	var stack []int
+	at := func(x, y int) float32 {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 0
+		}
+		return mag[y*w+x]
+	}
+	for y := range h {
+		for x := range w {
+			i := y*w + x
+			m := mag[i]
+			if m <= low {
+				continue
+			}
+			ax, ay := abs(gx[i]), abs(gy[i])
+			var keep bool
+			// ties break towards the upper left along the axes and are
+			// suppressed along the diagonals, as in OpenCV
+			switch {
+			case ay < ax*tan22: // horizontal gradient
+				keep = m > at(x-1, y) && m >= at(x+1, y)
+			case ay > ax*(tan22+2): // vertical gradient
+				keep = m > at(x, y-1) && m >= at(x, y+1)
+			case (gx[i] < 0) != (gy[i] < 0):
+				keep = m > at(x+1, y-1) && m > at(x-1, y+1)
+			default:
+				keep = m > at(x-1, y-1) && m > at(x+1, y+1)
+			}
+			if !keep {
+				continue
+			}
+			if m > high {
+				state[i] = strong
+				stack = append(stack, i)
+			} else {
+				state[i] = weak
+			}
+		}
+	}
+
+	// hysteresis: grow strong edges through connected weak pixels
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := i%w, i/w
+		for ny := max(y-1, 0); ny <= min(y+1, h-1); ny++ {
+			for nx := max(x-1, 0); nx <= min(x+1, w-1); nx++ {
+				if j := ny*w + nx; state[j] == weak {
+					state[j] = strong
+					stack = append(stack, j)
+				}
+			}
+		}
+	}
+
+	out := tensor.New(types.UINT8, tensor.NewShape(h, w))
+	data := out.Data().([]uint8)
+	for i, s := range state {
+		if s == strong {
+			data[i] = 255
+		}
+	}
+	return out, nil
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
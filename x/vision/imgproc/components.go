@@ -0,0 +1,137 @@
+package imgproc
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Component describes a connected component found by
+// ConnectedComponentsWithStats.
+type Component struct {
+	// Left, Top, Width and Height bound the component.
+	Left, Top, Width, Height int
+	// Area is the number of pixels.
+	Area int
+	// CX and CY is the centroid.
+	CX, CY float64
+}
+
+// ConnectedComponents labels the connected regions of non-zero pixels of a
+// single channel image (cv::connectedComponents). connectivity is 4 or 8.
+// Labels is an INT32 [H, W] tensor with 0 for the background and 1..n-1 for
+// the components in raster order of their first pixel; n counts the
+// background.
+func ConnectedComponents(src types.Tensor, connectivity int) (labels types.Tensor, n int, err error) {
+	labels, stats, err := ConnectedComponentsWithStats(src, connectivity)
+	return labels, len(stats), err
+}
+
+// ConnectedComponentsWithStats is ConnectedComponents that also returns the
+// statistics of every label, the background first.
+func ConnectedComponentsWithStats(src types.Tensor, connectivity int) (types.Tensor, []Component, error) {
+	if connectivity != 4 && connectivity != 8 {
+		return nil, nil, fmt.Errorf("imgproc: connectivity must be 4 or 8, got %d", connectivity)
+	}
+	p, err := load(src, HWC)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(p.c) != 1 {
+		return nil, nil, fmt.Errorf("imgproc: connected components need a single channel image")
+	}
+	w, h := p.w, p.h
+	in := p.c[0]
+
+	// first pass: provisional labels with union-find over their equivalences
+	parent := []int32{0}
+	find := func(l int32) int32 {
+		for parent[l] != l {
+			parent[l] = parent[parent[l]]
+			l = parent[l]
+		}
+		return l
+	}
+	union := func(a, b int32) int32 {
+		a, b = find(a), find(b)
+		if a < b {
+			parent[b] = a
+			return a
+		}
+		parent[a] = b
+		return b
+	}
+
+	provisional := make([]int32, w*h)
+	for y := range h {
+		for x := range w {
+			i := y*w + x
+			if in[i] == 0 {
+				continue
+			}
+			var l int32
+			join := func(nx, ny int) {
+				if nx < 0 || nx >= w || ny < 0 {
+					return
+				}
+				if n := provisional[ny*w+nx]; n != 0 {
+					if l == 0 {
+						l = find(n)
+					} else {
+						l = union(l, n)
+					}
+				}
+			}
+			join(x-1, y)
+			join(x, y-1)
+			if connectivity == 8 {
+				join(x-1, y-1)
+				join(x+1, y-1)
+			}
+			if l == 0 {
+				l = int32(len(parent))
+				parent = append(parent, l)
+			}
+			provisional[i] = l
+		}
+	}
+
+	// second pass: final labels in raster order and statistics
+	final := make([]int32, len(parent))
+	stats := []Component{{}}
+	var sums [][2]float64
+	sums = append(sums, [2]float64{})
+	for y := range h {
+		for x := range w {
+			i := y*w + x
+			var l int32
+			if r := find(provisional[i]); r != 0 {
+				if final[r] == 0 {
+					final[r] = int32(len(stats))
+					stats = append(stats, Component{Left: x, Top: y, Width: 1, Height: 1})
+					sums = append(sums, [2]float64{})
+				}
+				l = final[r]
+			}
+			provisional[i] = l
+
+			s := &stats[l]
+			if s.Area == 0 {
+				s.Left, s.Top, s.Width, s.Height = x, y, 1, 1
+			}
+			right, bottom := max(s.Left+s.Width, x+1), max(s.Top+s.Height, y+1)
+			s.Left, s.Top = min(s.Left, x), min(s.Top, y)
+			s.Width, s.Height = right-s.Left, bottom-s.Top
+			s.Area++
+			sums[l][0] += float64(x)
+			sums[l][1] += float64(y)
+		}
+	}
+	for l := range stats {
+		if a := float64(stats[l].Area); a > 0 {
+			stats[l].CX, stats[l].CY = sums[l][0]/a, sums[l][1]/a
+		}
+	}
+	return tensor.FromArray(tensor.NewShape(h, w), provisional), stats, nil
+}
@@ -0,0 +1,176 @@
+package imgproc
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/filter/gaussian"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Filter2D correlates every channel of src with kernel, a row-major
+// [rows][cols] matrix of odd size anchored at its center (cv::filter2D).
+func Filter2D(src types.Tensor, layout Layout, kernel [][]float32) (types.Tensor, error) {
+	k, kh, kw, err := flatten(kernel)
+	if err != nil {
+		return nil, err
+	}
+	return apply(src, layout, func(p planes) (planes, error) {
+		return correlate(p, k, kh, kw, reflect101), nil
+	})
+}
+
+// SepFilter2D filters the rows of every channel of src with kx and then the
+// columns with ky (cv::sepFilter2D). Both kernels must have odd length.
+func SepFilter2D(src types.Tensor, layout Layout, kx, ky []float32) (types.Tensor, error) {
+	if len(kx)%2 == 0 || len(ky)%2 == 0 {
+		return nil, fmt.Errorf("imgproc: separable kernels must have odd length, got %d and %d", len(kx), len(ky))
+	}
+	return apply(src, layout, func(p planes) (planes, error) {
+		return separable(p, kx, ky, reflect101), nil
+	})
+}
+
+// GaussianBlur smooths src with a ksize x ksize Gaussian. Like OpenCV, a
+// ksize of 0 is derived from sigma, and a sigma <= 0 from ksize.
+func GaussianBlur(src types.Tensor, layout Layout, ksize int, sigma float32) (types.Tensor, error) {
+	k, err := gaussianKernel(ksize, sigma)
+	if err != nil {
+		return nil, err
+	}
+	return apply(src, layout, func(p planes) (planes, error) {
+		return separable(p, k, k, reflect101), nil
+	})
+}
+
+// gaussianKernel returns the normalized 1-D Gaussian of cv::getGaussianKernel.
+func gaussianKernel(ksize int, sigma float32) ([]float32, error) {
+	if ksize == 0 && sigma > 0 {
+		ksize = int(math.Round(float64(sigma)*8+1)) | 1
+	}
+	if err := checkKernelSize("Gaussian", ksize); err != nil {
+		return nil, err
+	}
+	if sigma <= 0 {
+		if k, ok := smallGaussian[ksize]; ok {
+			return slices.Clone(k), nil
+		}
+		sigma = 0.3*(float32(ksize-1)*0.5-1) + 0.8
+	}
+	return gaussian.Kernel(sigma, ksize), nil
+}
+
+// smallGaussian holds the fixed kernels OpenCV uses for small apertures
+// without an explicit sigma.
+var smallGaussian = map[int][]float32{
+	1: {1},
+	3: {0.25, 0.5, 0.25},
+	5: {0.0625, 0.25, 0.375, 0.25, 0.0625},
+	7: {0.03125, 0.109375, 0.21875, 0.28125, 0.21875, 0.109375, 0.03125},
+}
+
+// BoxBlur replaces every pixel of src by the mean of its ksize x ksize
+// neighbourhood (cv::blur).
+func BoxBlur(src types.Tensor, layout Layout, ksize int) (types.Tensor, error) {
+	if err := checkKernelSize("box", ksize); err != nil {
+		return nil, err
+	}
+	k := make([]float32, ksize)
+	for i := range k {
+		k[i] = 1 / float32(ksize)
+	}
+	return apply(src, layout, func(p planes) (planes, error) {
+		return separable(p, k, k, reflect101), nil
+	})
+}
+
+// MedianBlur replaces every pixel of src by the median of its ksize x ksize
+// neighbourhood, replicating the border (cv::medianBlur).
+func MedianBlur(src types.Tensor, layout Layout, ksize int) (types.Tensor, error) {
+	if err := checkKernelSize("median", ksize); err != nil {
+		return nil, err
+	}
+	r := ksize / 2
+	return apply(src, layout, func(p planes) (planes, error) {
+		out := p.like()
+		window := make([]float32, 0, ksize*ksize)
+		for ch, in := range p.c {
+			for y := range p.h {
+				for x := range p.w {
+					window = window[:0]
+					for dy := -r; dy <= r; dy++ {
+						row := replicate(y+dy, p.h) * p.w
+						for dx := -r; dx <= r; dx++ {
+							window = append(window, in[row+replicate(x+dx, p.w)])
+						}
+					}
+					slices.Sort(window)
+					out.c[ch][y*p.w+x] = window[len(window)/2]
+				}
+			}
+		}
+		return out, nil
+	})
+}
+
+func flatten(kernel [][]float32) ([]float32, int, int, error) {
+	kh := len(kernel)
+	if kh == 0 || kh%2 == 0 {
+		return nil, 0, 0, fmt.Errorf("imgproc: kernel must have an odd number of rows, got %d", kh)
+	}
+	kw := len(kernel[0])
+	if kw%2 == 0 {
+		return nil, 0, 0, fmt.Errorf("imgproc: kernel must have an odd number of columns, got %d", kw)
+	}
+	k := make([]float32, 0, kh*kw)
+	for _, row := range kernel {
+		if len(row) != kw {
+			return nil, 0, 0, fmt.Errorf("imgproc: ragged kernel")
+		}
+		k = append(k, row...)
+	}
+	return k, kh, kw, nil
+}
+
+// separable correlates p with the row kernel kx and then the column kernel ky.
+func separable(p planes, kx, ky []float32, border func(i, n int) int) planes {
+	return correlate(correlate(p, kx, 1, len(kx), border), ky, len(ky), 1, border)
+}
+
+// correlate runs a kh x kw kernel, row-major and centered, over every plane
+// of p with the tensor depthwise convolution, after padding the planes with
+// border.
+func correlate(p planes, kernel []float32, kh, kw int, border func(i, n int) int) planes {
+	ry, rx := kh/2, kw/2
+	pw, ph := p.w+2*rx, p.h+2*ry
+	channels := len(p.c)
+
+	padded := make([]float32, channels*pw*ph)
+	for ch, in := range p.c {
+		plane := padded[ch*pw*ph:]
+		for y := range ph {
+			row := border(y-ry, p.h) * p.w
+			for x := range pw {
+				plane[y*pw+x] = in[row+border(x-rx, p.w)]
+			}
+		}
+	}
+	weights := make([]float32, 0, channels*kh*kw)
+	for range channels {
+		weights = append(weights, kernel...)
+	}
+
+	input := tensor.FromArray(tensor.NewShape(1, channels, ph, pw), padded)
+	filter := tensor.FromArray(tensor.NewShape(channels, kh, kw), weights)
+	result := input.DepthwiseConv2D(filter, nil, []int{1, 1}, []int{0, 0})
+	data := result.Data().([]float32)
+
+	out := planes{w: p.w, h: p.h, c: make([][]float32, channels)}
+	n := p.w * p.h
+	for ch := range out.c {
+		out.c[ch] = data[ch*n : (ch+1)*n]
+	}
+	return out
+}
@@ -0,0 +1,570 @@
+package imgproc
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+func readGray(t *testing.T, path string) types.Tensor {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, ok := img.(*image.Gray)
+	if !ok {
+		t.Fatalf("%s is %T, want grayscale", path, img)
+	}
+	w, h := g.Rect.Dx(), g.Rect.Dy()
+	out := tensor.New(types.UINT8, tensor.NewShape(h, w))
+	data := out.Data().([]uint8)
+	for y := range h {
+		copy(data[y*w:(y+1)*w], g.Pix[y*g.Stride:])
+	}
+	return out
+}
+
+func writeGray(t *testing.T, path string, img types.Tensor) {
+	t.Helper()
+	shape := img.Shape()
+	g := image.NewGray(image.Rect(0, 0, shape[1], shape[0]))
+	copy(g.Pix, img.Data().([]uint8))
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, g); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// toUint8 scales an FP32 result into a viewable UINT8 image.
+func toUint8(img types.Tensor, scale, offset float32) types.Tensor {
+	shape := img.Shape()
+	out := tensor.New(types.UINT8, tensor.NewShape(shape[0], shape[1]))
+	data := out.Data().([]uint8)
+	for i, v := range img.Data().([]float32) {
+		data[i] = saturate(v*scale + offset)
+	}
+	return out
+}
+
+// TestGolden compares every operation on testdata/input.png with the images
+// in testdata, within one gray level. Run with -update to regenerate them
+// after an intended change.
+func TestGolden(t *testing.T) {
+	src := readGray(t, filepath.Join("testdata", "input.png"))
+	ellipse, err := StructuringElement(MorphEllipse, 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otsu, err := Otsu(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		op   func() (types.Tensor, error)
+	}{
+		{"resize_bilinear", func() (types.Tensor, error) { return Resize(src, HWC, 237, 141, Bilinear) }},
+		{"resize_area", func() (types.Tensor, error) { return Resize(src, HWC, 67, 41, Area) }},
+		{"resize_nearest", func() (types.Tensor, error) { return Resize(src, HWC, 80, 48, Nearest) }},
+		{"gaussian", func() (types.Tensor, error) { return GaussianBlur(src, HWC, 7, 1.5) }},
+		{"box", func() (types.Tensor, error) { return BoxBlur(src, HWC, 5) }},
+		{"median", func() (types.Tensor, error) { return MedianBlur(src, HWC, 5) }},
+		{"sobel_x", func() (types.Tensor, error) {
+			g, err := Sobel(src, HWC, 1, 0, 3)
+			if err != nil {
+				return nil, err
+			}
+			return toUint8(g, 0.25, 128), nil
+		}},
+		{"scharr_y", func() (types.Tensor, error) {
+			g, err := Scharr(src, HWC, 0, 1)
+			if err != nil {
+				return nil, err
+			}
+			return toUint8(g, 1.0/16, 128), nil
+		}},
+		{"erode", func() (types.Tensor, error) { return Erode(src, HWC, ellipse, 1) }},
+		{"dilate", func() (types.Tensor, error) { return Dilate(src, HWC, ellipse, 1) }},
+		{"open", func() (types.Tensor, error) { return MorphologyEx(src, HWC, MorphOpen, ellipse, 1) }},
+		{"close", func() (types.Tensor, error) { return MorphologyEx(src, HWC, MorphClose, ellipse, 1) }},
+		{"gradient", func() (types.Tensor, error) { return MorphologyEx(src, HWC, MorphGradient, ellipse, 1) }},
+		{"tophat", func() (types.Tensor, error) { return MorphologyEx(src, HWC, MorphTopHat, ellipse, 1) }},
+		{"otsu", func() (types.Tensor, error) { return Threshold(src, HWC, otsu, 255, ThreshBinary) }},
+		{"adaptive", func() (types.Tensor, error) {
+			return AdaptiveThreshold(src, 255, AdaptiveGaussian, ThreshBinary, 11, 2)
+		}},
+		{"canny", func() (types.Tensor, error) { return Canny(src, 50, 150, 3, false) }},
+		{"components", func() (types.Tensor, error) {
+			edges, err := Canny(src, 50, 150, 3, false)
+			if err != nil {
+				return nil, err
+			}
+			labels, _, err := ConnectedComponents(edges, 8)
+			if err != nil {
+				return nil, err
+			}
+			out := tensor.New(types.UINT8, tensor.NewShape(labels.Shape()...))
+			data := out.Data().([]uint8)
+			for i, l := range labels.Data().([]int32) {
+				if l != 0 {
+					data[i] = uint8(64 + l*37%192)
+				}
+			}
+			return out, nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op()
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", tt.name+".png")
+			if *update {
+				writeGray(t, path, got)
+				return
+			}
+			want := readGray(t, path)
+			if !got.Shape().Equal(want.Shape()) {
+				t.Fatalf("shape %v, want %v", got.Shape(), want.Shape())
+			}
+			wantData := want.Data().([]uint8)
+			bad := 0
+			for i, v := range got.Data().([]uint8) {
+				if d := int(v) - int(wantData[i]); d < -1 || d > 1 {
+					bad++
+				}
+			}
+			if bad > 0 {
+				t.Errorf("%d pixels differ from %s", bad, path)
+			}
+		})
+	}
+}
+
+// The reference below evaluates the OpenCV definitions directly, in float64
+// and without any code of this package, to keep the golden images honest.
+
+// refKernelGaussian is cv::getGaussianKernel for an explicit sigma.
+func refKernelGaussian(ksize int, sigma float64) []float64 {
+	k := make([]float64, ksize)
+	var sum float64
+	for i := range k {
+		x := float64(i - (ksize-1)/2)
+		k[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += k[i]
+	}
+	for i := range k {
+		k[i] /= sum
+	}
+	return k
+}
+
+// refAt reads src with BORDER_REFLECT_101, the cv::BORDER_DEFAULT.
+func refAt(src types.Tensor, x, y int) float64 {
+	shape := src.Shape()
+	h, w := shape[0], shape[1]
+	mirror := func(i, n int) int {
+		for i < 0 || i >= n {
+			if i < 0 {
+				i = -i
+			} else {
+				i = 2*n - 2 - i
+			}
+		}
+		return i
+	}
+	return float64(src.Data().([]uint8)[mirror(y, h)*w+mirror(x, w)])
+}
+
+// refCorrelate applies the separable kernel kx ⊗ ky to src at (x, y).
+func refCorrelate(src types.Tensor, x, y int, kx, ky []float64) float64 {
+	var sum float64
+	for j, wy := range ky {
+		for i, wx := range kx {
+			sum += wx * wy * refAt(src, x+i-len(kx)/2, y+j-len(ky)/2)
+		}
+	}
+	return sum
+}
+
+func refRound(v float64) float64 {
+	return math.Max(0, math.Min(255, math.Floor(v+0.5)))
+}
+
+// refResize samples src at destination pixel (x, y) of a width x height
+// cv::resize.
+func refResize(src types.Tensor, x, y, width, height int, interp Interpolation) float64 {
+	shape := src.Shape()
+	h, w := shape[0], shape[1]
+	sx, sy := float64(w)/float64(width), float64(h)/float64(height)
+	switch interp {
+	case Nearest:
+		// INTER_NEAREST floors without pixel centers
+		return refAt(src, min(int(float64(x)*sx), w-1), min(int(float64(y)*sy), h-1))
+	case Area:
+		// INTER_AREA weighs every source pixel by its overlap
+		var sum float64
+		for v := int(float64(y) * sy); float64(v) < float64(y+1)*sy && v < h; v++ {
+			oy := math.Min(float64(y+1)*sy, float64(v+1)) - math.Max(float64(y)*sy, float64(v))
+			for u := int(float64(x) * sx); float64(u) < float64(x+1)*sx && u < w; u++ {
+				ox := math.Min(float64(x+1)*sx, float64(u+1)) - math.Max(float64(x)*sx, float64(u))
+				sum += ox * oy * refAt(src, u, v)
+			}
+		}
+		return sum / (sx * sy)
+	}
+	// INTER_LINEAR maps pixel centers and clamps at the border
+	fx := math.Max((float64(x)+0.5)*sx-0.5, 0)
+	fy := math.Max((float64(y)+0.5)*sy-0.5, 0)
+	x0, y0 := min(int(fx), w-1), min(int(fy), h-1)
+	x1, y1 := min(x0+1, w-1), min(y0+1, h-1)
+	ax, ay := fx-float64(x0), fy-float64(y0)
+	if x0 == w-1 {
+		ax = 0
+	}
+	if y0 == h-1 {
+		ay = 0
+	}
+	top := refAt(src, x0, y0)*(1-ax) + refAt(src, x1, y0)*ax
+	bottom := refAt(src, x0, y1)*(1-ax) + refAt(src, x1, y1)*ax
+	return top*(1-ay) + bottom*ay
+}
+
+// refGrid is a reference image for the operations evaluated over the whole
+// image at once.
+type refGrid struct {
+	w, h int
+	pix  []float64
+}
+
+func refGridOf(src types.Tensor) refGrid {
+	shape := src.Shape()
+	g := refGrid{w: shape[1], h: shape[0], pix: make([]float64, 0, shape[0]*shape[1])}
+	for _, v := range src.Data().([]uint8) {
+		g.pix = append(g.pix, float64(v))
+	}
+	return g
+}
+
+func (g refGrid) at(x, y int) float64 {
+	return g.pix[y*g.w+x]
+}
+
+// replicated reads g with BORDER_REPLICATE.
+func (g refGrid) replicated(x, y int) float64 {
+	return g.at(max(0, min(x, g.w-1)), max(0, min(y, g.h-1)))
+}
+
+// refMedian is cv::medianBlur, which replicates the border.
+func refMedian(g refGrid, x, y, ksize int) float64 {
+	window := make([]float64, 0, ksize*ksize)
+	for dy := -ksize / 2; dy <= ksize/2; dy++ {
+		for dx := -ksize / 2; dx <= ksize/2; dx++ {
+			window = append(window, g.replicated(x+dx, y+dy))
+		}
+	}
+	sort.Float64s(window)
+	return window[len(window)/2]
+}
+
+// refEllipse5 is cv::getStructuringElement(MORPH_ELLIPSE, Size(5, 5)).
+var refEllipse5 = []string{
+	"..#..",
+	"#####",
+	"#####",
+	"#####",
+	"..#..",
+}
+
+// refMorph is cv::erode, or cv::dilate, with refEllipse5 and the default
+// border value, which never wins the minimum or the maximum.
+func refMorph(g refGrid, dilate bool) refGrid {
+	out := refGrid{w: g.w, h: g.h, pix: make([]float64, len(g.pix))}
+	for y := range g.h {
+		for x := range g.w {
+			v := math.Inf(1)
+			if dilate {
+				v = math.Inf(-1)
+			}
+			for j, row := range refEllipse5 {
+				for i, on := range row {
+					sx, sy := x+i-len(row)/2, y+j-len(refEllipse5)/2
+					if on != '#' || sx < 0 || sy < 0 || sx >= g.w || sy >= g.h {
+						continue
+					}
+					if dilate {
+						v = math.Max(v, g.at(sx, sy))
+					} else {
+						v = math.Min(v, g.at(sx, sy))
+					}
+				}
+			}
+			out.pix[y*g.w+x] = v
+		}
+	}
+	return out
+}
+
+func refSubtract(a, b refGrid) refGrid {
+	out := refGrid{w: a.w, h: a.h, pix: make([]float64, len(a.pix))}
+	for i := range out.pix {
+		out.pix[i] = a.pix[i] - b.pix[i]
+	}
+	return out
+}
+
+// refOtsu returns the threshold t maximising the between-class variance
+// q1 q2 (mu1 - mu2)^2 of the pixels at or below t and those above it.
+func refOtsu(g refGrid) float64 {
+	var best, bestVar float64
+	for t := range 255 {
+		var n1, n2, s1, s2 float64
+		for _, v := range g.pix {
+			if v <= float64(t) {
+				n1, s1 = n1+1, s1+v
+			} else {
+				n2, s2 = n2+1, s2+v
+			}
+		}
+		if n1 == 0 || n2 == 0 {
+			continue
+		}
+		q1, q2 := n1/float64(len(g.pix)), n2/float64(len(g.pix))
+		mu1, mu2 := s1/n1, s2/n2
+		if v := q1 * q2 * (mu1 - mu2) * (mu1 - mu2); v > bestVar {
+			best, bestVar = float64(t), v
+		}
+	}
+	return best
+}
+
+// refAdaptive is cv::adaptiveThreshold with ADAPTIVE_THRESH_GAUSSIAN_C and
+// THRESH_BINARY: 255 where the pixel exceeds its replicated border Gaussian
+// mean less c. OpenCV rounds the mean in fixed point, so pixels within one
+// gray level of the threshold are NaN.
+func refAdaptive(g refGrid, x, y int, kernel []float64, c float64) float64 {
+	var mean float64
+	for j, wy := range kernel {
+		for i, wx := range kernel {
+			mean += wx * wy * g.replicated(x+i-len(kernel)/2, y+j-len(kernel)/2)
+		}
+	}
+	d := g.at(x, y) - (mean - c)
+	switch {
+	case math.Abs(d) <= 1:
+		return math.NaN()
+	case d > 0:
+		return 255
+	}
+	return 0
+}
+
+// refCanny is cv::Canny with a 3x3 aperture and the L1 norm. The gradient
+// direction sectors use OpenCV's Q15 tan(22.5), and ties are broken as in
+// its non-maximum suppression.
+func refCanny(g refGrid, low, high float64) refGrid {
+	const tg22 = 13573
+	w, h := g.w, g.h
+	dx, dy, mag := make([]float64, w*h), make([]float64, w*h), make([]float64, w*h)
+	for y := range h {
+		for x := range w {
+			p := func(i, j int) float64 { return g.replicated(x+i, y+j) }
+			i := y*w + x
+			dx[i] = p(1, -1) + 2*p(1, 0) + p(1, 1) - p(-1, -1) - 2*p(-1, 0) - p(-1, 1)
+			dy[i] = p(-1, 1) + 2*p(0, 1) + p(1, 1) - p(-1, -1) - 2*p(0, -1) - p(1, -1)
+			mag[i] = math.Abs(dx[i]) + math.Abs(dy[i])
+		}
+	}
+	m := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 0
+		}
+		return mag[y*w+x]
+	}
+
+	candidate := make([]bool, w*h)
+	var strong []int
+	for y := range h {
+		for x := range w {
+			i := y*w + x
+			v, ax, ay := mag[i], math.Abs(dx[i])*tg22, math.Abs(dy[i])*(1<<15)
+			if v <= low {
+				continue
+			}
+			var keep bool
+			switch {
+			case ay < ax:
+				keep = v > m(x-1, y) && v >= m(x+1, y)
+			case ay > ax+math.Abs(dx[i])*(1<<16):
+				keep = v > m(x, y-1) && v >= m(x, y+1)
+			default:
+				s := 1
+				if (dx[i] < 0) != (dy[i] < 0) {
+					s = -1
+				}
+				keep = v > m(x-s, y-1) && v > m(x+s, y+1)
+			}
+			candidate[i] = keep
+			if keep && v > high {
+				strong = append(strong, i)
+			}
+		}
+	}
+
+	out := refGrid{w: w, h: h, pix: make([]float64, w*h)}
+	for len(strong) > 0 {
+		i := strong[len(strong)-1]
+		strong = strong[:len(strong)-1]
+		if out.pix[i] != 0 {
+			continue
+		}
+		out.pix[i] = 255
+		for ny := max(i/w-1, 0); ny <= min(i/w+1, h-1); ny++ {
+			for nx := max(i%w-1, 0); nx <= min(i%w+1, w-1); nx++ {
+				if j := ny*w + nx; candidate[j] && out.pix[j] == 0 {
+					strong = append(strong, j)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// refComponents flood fills the 8-connected components of the non-zero
+// pixels of g. They are numbered in the raster order of their first pixel,
+// as OpenCV's CCL_WU does, and coloured as in TestGolden.
+func refComponents(g refGrid) refGrid {
+	out := refGrid{w: g.w, h: g.h, pix: make([]float64, len(g.pix))}
+	labelled := make([]bool, len(g.pix))
+	label := 0
+	for start, v := range g.pix {
+		if v == 0 || labelled[start] {
+			continue
+		}
+		label++
+		labelled[start] = true
+		for stack := []int{start}; len(stack) > 0; {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			out.pix[i] = float64(64 + label*37%192)
+			for ny := max(i/g.w-1, 0); ny <= min(i/g.w+1, g.h-1); ny++ {
+				for nx := max(i%g.w-1, 0); nx <= min(i%g.w+1, g.w-1); nx++ {
+					if j := ny*g.w + nx; g.pix[j] != 0 && !labelled[j] {
+						labelled[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// TestGoldenReference checks every golden image against the reference
+// evaluation of the OpenCV definitions, within the one gray level of
+// OpenCV's fixed point arithmetic. Pixels the reference cannot decide are
+// skipped.
+func TestGoldenReference(t *testing.T) {
+	// cv::getGaussianKernel(5, 1)
+	for i, want := range []float64{0.05448868, 0.24420134, 0.40261995, 0.24420134, 0.05448868} {
+		if got := refKernelGaussian(5, 1)[i]; math.Abs(got-want) > 1e-7 {
+			t.Fatalf("reference Gaussian tap %d = %v, want %v", i, got, want)
+		}
+	}
+
+	src := readGray(t, filepath.Join("testdata", "input.png"))
+	gauss := refKernelGaussian(7, 1.5)
+	box := []float64{0.2, 0.2, 0.2, 0.2, 0.2}
+	grid := refGridOf(src)
+	erode, dilate := refMorph(grid, false), refMorph(grid, true)
+	open, closed := refMorph(erode, true), refMorph(dilate, false)
+	gradient, tophat := refSubtract(dilate, erode), refSubtract(grid, open)
+	otsu := refOtsu(grid)
+	// cv::getGaussianKernel(11, 0) derives sigma from the size
+	adaptive := refKernelGaussian(11, 0.3*((11-1)*0.5-1)+0.8)
+	canny := refCanny(grid, 50, 150)
+	components := refComponents(refGridOf(readGray(t, filepath.Join("testdata", "canny.png"))))
+
+	if got, err := Otsu(src); err != nil || float64(got) != otsu {
+		t.Errorf("Otsu() = %v, %v; reference %v", got, err, otsu)
+	}
+	tests := []struct {
+		name string
+		ref  func(x, y int) float64
+	}{
+		{"gaussian", func(x, y int) float64 {
+			return refCorrelate(src, x, y, gauss, gauss)
+		}},
+		{"sobel_x", func(x, y int) float64 {
+			return refCorrelate(src, x, y, []float64{-1, 0, 1}, []float64{1, 2, 1})*0.25 + 128
+		}},
+		{"resize_bilinear", func(x, y int) float64 { return refResize(src, x, y, 237, 141, Bilinear) }},
+		{"resize_area", func(x, y int) float64 { return refResize(src, x, y, 67, 41, Area) }},
+		{"resize_nearest", func(x, y int) float64 { return refResize(src, x, y, 80, 48, Nearest) }},
+		{"box", func(x, y int) float64 { return refCorrelate(src, x, y, box, box) }},
+		{"median", func(x, y int) float64 { return refMedian(grid, x, y, 5) }},
+		{"erode", erode.at},
+		{"dilate", dilate.at},
+		{"open", open.at},
+		{"close", closed.at},
+		{"gradient", gradient.at},
+		{"tophat", tophat.at},
+		{"otsu", func(x, y int) float64 {
+			if grid.at(x, y) > otsu {
+				return 255
+			}
+			return 0
+		}},
+		{"adaptive", func(x, y int) float64 { return refAdaptive(grid, x, y, adaptive, 2) }},
+		{"canny", canny.at},
+		{"components", components.at},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			golden := readGray(t, filepath.Join("testdata", tt.name+".png"))
+			shape := golden.Shape()
+			bad := 0
+			for y := range shape[0] {
+				for x := range shape[1] {
+					want := tt.ref(x, y)
+					if math.IsNaN(want) {
+						continue
+					}
+					want = refRound(want)
+					if got := pixel(golden, x, y); math.Abs(got-want) > 1 {
+						if bad == 0 {
+							t.Errorf("pixel (%d, %d) = %v, reference %v", x, y, got, want)
+						}
+						bad++
+					}
+				}
+			}
+			if bad > 0 {
+				t.Errorf("%d pixels differ from the reference", bad)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package imgproc
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Sobel returns the dx-th x and dy-th y derivative of src as an FP32 tensor
+// of the same layout, using the ksize aperture of cv::Sobel (1, 3, 5 or 7;
+// 1 means a 3-tap derivative without smoothing).
+func Sobel(src types.Tensor, layout Layout, dx, dy, ksize int) (types.Tensor, error) {
+	kx, ky, err := sobelKernels(dx, dy, ksize)
+	if err != nil {
+		return nil, err
+	}
+	return gradient(src, layout, kx, ky, reflect101)
+}
+
+// Scharr returns the first x (dx=1, dy=0) or y (dx=0, dy=1) derivative of
+// src with the 3x3 Scharr operator, more rotation invariant than Sobel.
+func Scharr(src types.Tensor, layout Layout, dx, dy int) (types.Tensor, error) {
+	deriv, smooth := []float32{-1, 0, 1}, []float32{3, 10, 3}
+	switch {
+	case dx == 1 && dy == 0:
+		return gradient(src, layout, deriv, smooth, reflect101)
+	case dx == 0 && dy == 1:
+		return gradient(src, layout, smooth, deriv, reflect101)
+	}
+	return nil, fmt.Errorf("imgproc: Scharr needs dx, dy of 1, 0 or 0, 1, got %d, %d", dx, dy)
+}
+
+func gradient(src types.Tensor, layout Layout, kx, ky []float32, border func(i, n int) int) (types.Tensor, error) {
+	p, err := load(src, layout)
+	if err != nil {
+		return nil, err
+	}
+	return separable(p, kx, ky, border).store(types.FP32, layout, len(src.Shape())), nil
+}
+
+// sobelKernels returns the row and column kernels of cv::getDerivKernels.
+func sobelKernels(dx, dy, ksize int) (kx, ky []float32, err error) {
+	if dx < 0 || dy < 0 || dx+dy == 0 {
+		return nil, nil, fmt.Errorf("imgproc: invalid derivative order %d, %d", dx, dy)
+	}
+	if ksize != 1 && ksize != 3 && ksize != 5 && ksize != 7 {
+		return nil, nil, fmt.Errorf("imgproc: Sobel kernel size must be 1, 3, 5 or 7, got %d", ksize)
+	}
+	if ksize == 1 {
+		if dx > 2 || dy > 2 {
+			return nil, nil, fmt.Errorf("imgproc: Sobel kernel size 1 supports orders up to 2")
+		}
+		return derivKernel(dx, 1, true), derivKernel(dy, 1, true), nil
+	}
+	if dx >= ksize || dy >= ksize {
+		return nil, nil, fmt.Errorf("imgproc: derivative order must be below the kernel size %d", ksize)
+	}
+	return derivKernel(dx, ksize, false), derivKernel(dy, ksize, false), nil
+}
+
+// derivKernel builds the ksize tap kernel of the given order by convolving
+// binomial smoothing [1 1] with differences [-1 1]. Aperture 1 takes the
+// unsmoothed 3-tap kernels.
+func derivKernel(order, ksize int, aperture1 bool) []float32 {
+	if aperture1 {
+		switch order {
+		case 0:
+			return []float32{1}
+		case 1:
+			return []float32{-1, 0, 1}
+		}
+		return []float32{1, -2, 1}
+	}
+	k := []float32{1}
+	for range ksize - order - 1 {
+		k = convolve(k, []float32{1, 1})
+	}
+	for range order {
+		k = convolve(k, []float32{-1, 1})
+	}
+	return k
+}
+
+func convolve(a, b []float32) []float32 {
+	out := make([]float32, len(a)+len(b)-1)
+	for i, x := range a {
+		for j, y := range b {
+			out[i+j] += x * y
+		}
+	}
+	return out
+}
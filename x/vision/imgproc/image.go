@@ -0,0 +1,247 @@
+// Package imgproc implements image processing on tensors in plain Go, for
+// builds without OpenCV (TinyGo, minimal Raspberry Pi images).
+//
+// Images are UINT8 or FP32 tensors of shape [H, W] for a single channel,
+// or [H, W, C] (HWC) or [C, H, W] (CHW) as given by a Layout. Operations
+// return new tensors of the input layout and data type unless documented
+// otherwise; UINT8 results are rounded and saturated. Borders are handled
+// as in OpenCV: reflected without repeating the edge pixel (BORDER_REFLECT_101)
+// for linear filters and replicated for median, morphology and resizing.
+package imgproc
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Layout tells how a 3-D image tensor stores its channels.
+type Layout int
+
+const (
+	// HWC stores interleaved channels, [H, W, C] (image.Image, GoCV order).
+	HWC Layout = iota
+	// CHW stores channel planes, [C, H, W] (neural network order).
+	CHW
+)
+
+func (l Layout) String() string {
+	if l == CHW {
+		return "CHW"
+	}
+	return "HWC"
+}
+
+// planes holds an image as one float32 plane per channel, row-major.
+type planes struct {
+	w, h int
+	c    [][]float32
+}
+
+func newPlanes(w, h, channels int) planes {
+	p := planes{w: w, h: h, c: make([][]float32, channels)}
+	for i := range p.c {
+		p.c[i] = make([]float32, w*h)
+	}
+	return p
+}
+
+// like returns zeroed planes of the same size.
+func (p planes) like() planes {
+	return newPlanes(p.w, p.h, len(p.c))
+}
+
+// geometry returns the height, width and channels of an image tensor.
+func geometry(t types.Tensor, layout Layout) (h, w, c int, err error) {
+	if t == nil || tensor.IsNil(t) {
+		return 0, 0, 0, fmt.Errorf("imgproc: nil image")
+	}
+	shape := t.Shape()
+	switch {
+	case len(shape) == 2:
+		h, w, c = shape[0], shape[1], 1
+	case len(shape) == 3 && layout == CHW:
+		c, h, w = shape[0], shape[1], shape[2]
+	case len(shape) == 3:
+		h, w, c = shape[0], shape[1], shape[2]
+	default:
+		return 0, 0, 0, fmt.Errorf("imgproc: image must be [H, W] or 3-D %v, got %v", layout, shape)
+	}
+	if h <= 0 || w <= 0 || c <= 0 {
+		return 0, 0, 0, fmt.Errorf("imgproc: empty image %v", shape)
+	}
+	if dt := t.DataType(); dt != types.UINT8 && dt != types.FP32 {
+		return 0, 0, 0, fmt.Errorf("imgproc: unsupported data type %v, want UINT8 or FP32", dt)
+	}
+	return h, w, c, nil
+}
+
+// load copies an image tensor into planes.
+func load(t types.Tensor, layout Layout) (planes, error) {
+	h, w, c, err := geometry(t, layout)
+	if err != nil {
+		return planes{}, err
+	}
+	p := newPlanes(w, h, c)
+	n := w * h
+	chw := layout == CHW || c == 1
+
+	if t.IsContiguous() {
+		switch data := t.Data().(type) {
+		case []uint8:
+			if len(data) == n*c {
+				for ch, plane := range p.c {
+					for i := range plane {
+						if chw {
+							plane[i] = float32(data[ch*n+i])
+						} else {
+							plane[i] = float32(data[i*c+ch])
+						}
+					}
+				}
+				return p, nil
+			}
+		case []float32:
+			if len(data) == n*c {
+				for ch, plane := range p.c {
+					if chw {
+						copy(plane, data[ch*n:(ch+1)*n])
+						continue
+					}
+					for i := range plane {
+						plane[i] = data[i*c+ch]
+					}
+				}
+				return p, nil
+			}
+		}
+	}
+
+	// views; At reads FP32 only
+	if t.DataType() != types.FP32 {
+		return planes{}, fmt.Errorf("imgproc: %v views are not supported, copy the tensor first", t.DataType())
+	}
+	rank := len(t.Shape())
+	for ch, plane := range p.c {
+		for y := range h {
+			for x := range w {
+				var v float64
+				switch {
+				case rank == 2:
+					v = t.At(y, x)
+				case layout == CHW:
+					v = t.At(ch, y, x)
+				default:
+					v = t.At(y, x, ch)
+				}
+				plane[y*w+x] = float32(v)
+			}
+		}
+	}
+	return p, nil
+}
+
+// store converts planes to a tensor of the given type, shaped like the
+// input: [H, W] when rank is 2, otherwise 3-D in layout.
+func (p planes) store(dt types.DataType, layout Layout, rank int) types.Tensor {
+	c := len(p.c)
+	n := p.w * p.h
+	var shape types.Shape
+	switch {
+	case rank == 2 && c == 1:
+		shape = tensor.NewShape(p.h, p.w)
+	case layout == CHW:
+		shape = tensor.NewShape(c, p.h, p.w)
+	default:
+		shape = tensor.NewShape(p.h, p.w, c)
+	}
+	chw := layout == CHW || c == 1
+
+	if dt == types.UINT8 {
+		t := tensor.New(types.UINT8, shape)
+		data := t.Data().([]uint8)
+		for ch, plane := range p.c {
+			for i, v := range plane {
+				if chw {
+					data[ch*n+i] = saturate(v)
+				} else {
+					data[i*c+ch] = saturate(v)
+				}
+			}
+		}
+		return t
+	}
+	data := make([]float32, n*c)
+	for ch, plane := range p.c {
+		if chw {
+			copy(data[ch*n:], plane)
+			continue
+		}
+		for i, v := range plane {
+			data[i*c+ch] = v
+		}
+	}
+	return tensor.FromArray(shape, data)
+}
+
+// saturate rounds half away from zero to the uint8 range.
+func saturate(v float32) uint8 {
+	switch {
+	case v <= 0 || v != v:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// apply runs fn on every plane of src and stores the result in the type and
+// layout of src.
+func apply(src types.Tensor, layout Layout, fn func(planes) (planes, error)) (types.Tensor, error) {
+	p, err := load(src, layout)
+	if err != nil {
+		return nil, err
+	}
+	out, err := fn(p)
+	if err != nil {
+		return nil, err
+	}
+	return out.store(src.DataType(), layout, len(src.Shape())), nil
+}
+
+// Border index mapping.
+
+// reflect101 maps i into [0, n) as gfedcb|abcdefgh|gfedcba.
+func reflect101(i, n int) int {
+	if n == 1 {
+		return 0
+	}
+	for i < 0 || i >= n {
+		if i < 0 {
+			i = -i
+		} else {
+			i = 2*n - 2 - i
+		}
+	}
+	return i
+}
+
+// replicate maps i into [0, n) as aaaaaa|abcdefgh|hhhhhhh.
+func replicate(i, n int) int {
+	return min(max(i, 0), n-1)
+}
+
+// checkKernelSize validates an odd, positive aperture.
+func checkKernelSize(op string, ksize int) error {
+	if ksize <= 0 || ksize%2 == 0 {
+		return fmt.Errorf("imgproc: %s kernel size must be odd and positive, got %d", op, ksize)
+	}
+	return nil
+}
+
+// round rounds half away from zero.
+func round(v float32) float32 {
+	return float32(math.Round(float64(v)))
+}
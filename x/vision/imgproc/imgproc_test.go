@@ -0,0 +1,612 @@
+package imgproc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+func gray(w, h int, fn func(x, y int) uint8) types.Tensor {
+	t := tensor.New(types.UINT8, tensor.NewShape(h, w))
+	data := t.Data().([]uint8)
+	for y := range h {
+		for x := range w {
+			data[y*w+x] = fn(x, y)
+		}
+	}
+	return t
+}
+
+func grayF(w, h int, fn func(x, y int) float32) types.Tensor {
+	data := make([]float32, w*h)
+	for y := range h {
+		for x := range w {
+			data[y*w+x] = fn(x, y)
+		}
+	}
+	return tensor.FromArray(tensor.NewShape(h, w), data)
+}
+
+// pixel reads an [H, W] image of any type.
+func pixel(t types.Tensor, x, y int) float64 {
+	i := y*t.Shape()[1] + x
+	switch data := t.Data().(type) {
+	case []uint8:
+		return float64(data[i])
+	case []int32:
+		return float64(data[i])
+	case []float32:
+		return float64(data[i])
+	}
+	panic("unsupported image type")
+}
+
+func near(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func TestLayouts(t *testing.T) {
+	// 2x3 image, 2 channels
+	hwc := tensor.FromArray(tensor.NewShape(2, 3, 2), []float32{
+		1, 10, 2, 20, 3, 30,
+		4, 40, 5, 50, 6, 60,
+	})
+	chw := tensor.FromArray(tensor.NewShape(2, 2, 3), []float32{
+		1, 2, 3, 4, 5, 6,
+		10, 20, 30, 40, 50, 60,
+	})
+	a, err := load(hwc, HWC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := load(chw, CHW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ch := range a.c {
+		for i := range a.c[ch] {
+			if a.c[ch][i] != b.c[ch][i] {
+				t.Fatalf("channel %d pixel %d: HWC %v, CHW %v", ch, i, a.c[ch][i], b.c[ch][i])
+			}
+		}
+	}
+
+	out := a.store(types.FP32, HWC, 3)
+	for i, v := range hwc.Data().([]float32) {
+		if got := out.Data().([]float32)[i]; got != v {
+			t.Fatalf("element %d = %v, want %v", i, got, v)
+		}
+	}
+	if shape := b.store(types.UINT8, CHW, 3).Shape(); shape[0] != 2 || shape[1] != 2 || shape[2] != 3 {
+		t.Errorf("CHW shape %v", shape)
+	}
+
+	if _, err := load(tensor.New(types.INT32, tensor.NewShape(2, 2)), HWC); err == nil {
+		t.Error("INT32 image accepted")
+	}
+	if _, err := load(tensor.New(types.UINT8, tensor.NewShape(2)), HWC); err == nil {
+		t.Error("1-D image accepted")
+	}
+}
+
+func TestSaturate(t *testing.T) {
+	for _, tt := range []struct {
+		in   float32
+		want uint8
+	}{{-3, 0}, {0.49, 0}, {0.5, 1}, {254.6, 255}, {300, 255}} {
+		if got := saturate(tt.in); got != tt.want {
+			t.Errorf("saturate(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	if got := reflect101(-2, 5); got != 2 {
+		t.Errorf("reflect101(-2, 5) = %d", got)
+	}
+	if got := reflect101(6, 5); got != 2 {
+		t.Errorf("reflect101(6, 5) = %d", got)
+	}
+}
+
+func TestResize(t *testing.T) {
+	src := grayF(2, 2, func(x, y int) float32 { return float32(10*x + 20*y) })
+	got, err := Resize(src, HWC, 4, 4, Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// half pixel centers as cv::resize INTER_LINEAR
+	want := []float32{0, 2.5, 7.5, 10}
+	for x, v := range want {
+		if p := pixel(got, x, 0); !near(p, float64(v), 1e-5) {
+			t.Errorf("row 0 pixel %d = %v, want %v", x, p, v)
+		}
+	}
+
+	src = gray(4, 4, func(x, y int) uint8 { return uint8(x * 10) })
+	got, err = Resize(src, HWC, 2, 2, Area)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := pixel(got, 0, 0); p != 5 {
+		t.Errorf("area pixel 0 = %v, want 5", p)
+	}
+	if p := pixel(got, 1, 1); p != 25 {
+		t.Errorf("area pixel 1 = %v, want 25", p)
+	}
+	got, err = Resize(src, HWC, 3, 2, Area)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the middle pixel covers two thirds of columns 1 and 2
+	if p := pixel(got, 1, 0); p != 15 {
+		t.Errorf("fractional area pixel = %v, want 15", p)
+	}
+
+	got, err = Resize(src, HWC, 8, 8, Nearest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := pixel(got, 7, 7); p != 30 {
+		t.Errorf("nearest pixel = %v, want 30", p)
+	}
+	if _, err := Resize(src, HWC, 0, 8, Nearest); err == nil {
+		t.Error("empty size accepted")
+	}
+}
+
+func TestRemap(t *testing.T) {
+	src := grayF(4, 3, func(x, y int) float32 { return float32(10*x + 100*y) })
+	m := NewMap(3, 2)
+	for i := range m.X {
+		m.X[i] += 0.25
+		m.Y[i] += 0.5
+	}
+	got, err := Remap(src, HWC, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := got.Shape(); s[0] != 2 || s[1] != 3 {
+		t.Fatalf("shape %v, want [2 3]", s)
+	}
+	// the image is linear, so bilinear sampling is exact inside it
+	for y := range 2 {
+		for x := range 3 {
+			if p, want := pixel(got, x, y), 10*(float64(x)+0.25)+100*(float64(y)+0.5); !near(p, want, 1e-4) {
+				t.Errorf("pixel %d,%d = %v, want %v", x, y, p, want)
+			}
+		}
+	}
+
+	// outside pixels are zero and fade in over the border pixel
+	m.X[0], m.Y[0] = -0.5, 1
+	m.X[1], m.Y[1] = -3, 1
+	if got, err = Remap(src, HWC, m); err != nil {
+		t.Fatal(err)
+	}
+	if pixel(got, 0, 0) != 50 || pixel(got, 1, 0) != 0 {
+		t.Errorf("border pixels = %v, %v, want 50, 0", pixel(got, 0, 0), pixel(got, 1, 0))
+	}
+	if _, err := Remap(src, HWC, &Map{Width: 2, Height: 2}); err == nil {
+		t.Error("empty map accepted")
+	}
+}
+
+func TestBlur(t *testing.T) {
+	flat := gray(9, 7, func(x, y int) uint8 { return 77 })
+	impulse := grayF(7, 7, func(x, y int) float32 {
+		if x == 3 && y == 3 {
+			return 9
+		}
+		return 0
+	})
+
+	for name, blur := range map[string]func(types.Tensor) (types.Tensor, error){
+		"gaussian": func(t types.Tensor) (types.Tensor, error) { return GaussianBlur(t, HWC, 5, 1.2) },
+		"box":      func(t types.Tensor) (types.Tensor, error) { return BoxBlur(t, HWC, 3) },
+		"median":   func(t types.Tensor) (types.Tensor, error) { return MedianBlur(t, HWC, 3) },
+	} {
+		got, err := blur(flat)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i, v := range got.Data().([]uint8) {
+			if v != 77 {
+				t.Fatalf("%s: flat pixel %d = %d", name, i, v)
+			}
+		}
+
+		got, err = blur(impulse)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var sum float32
+		for _, v := range got.Data().([]float32) {
+			sum += v
+		}
+		want := float32(9)
+		if name == "median" {
+			want = 0
+		}
+		if !near(float64(sum), float64(want), 1e-4) {
+			t.Errorf("%s: impulse energy %v, want %v", name, sum, want)
+		}
+	}
+
+	got, err := BoxBlur(impulse, HWC, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := pixel(got, 2, 2); !near(p, 1, 1e-6) {
+		t.Errorf("box pixel = %v, want 1", p)
+	}
+
+	// cv::getGaussianKernel(3, 0) is [0.25 0.5 0.25]
+	k, err := gaussianKernel(3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range []float32{0.25, 0.5, 0.25} {
+		if k[i] != v {
+			t.Errorf("kernel %v", k)
+		}
+	}
+
+	if _, err := GaussianBlur(flat, HWC, 4, 1); err == nil {
+		t.Error("even kernel accepted")
+	}
+}
+
+func TestFilter2D(t *testing.T) {
+	src := grayF(5, 5, func(x, y int) float32 { return float32(x + 5*y) })
+	// picks the right neighbour; the last column reflects to x=3
+	got, err := Filter2D(src, HWC, [][]float32{{0, 0, 0}, {0, 0, 1}, {0, 0, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := pixel(got, 1, 1); p != 7 {
+		t.Errorf("pixel (1, 1) = %v, want 7", p)
+	}
+	if p := pixel(got, 4, 0); p != 3 {
+		t.Errorf("pixel (4, 0) = %v, want 3", p)
+	}
+	if _, err := Filter2D(src, HWC, [][]float32{{1, 1}}); err == nil {
+		t.Error("even kernel accepted")
+	}
+}
+
+func TestGradients(t *testing.T) {
+	ramp := gray(8, 8, func(x, y int) uint8 { return uint8(3*x + 5*y) })
+
+	gx, err := Sobel(ramp, HWC, 1, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gy, err := Sobel(ramp, HWC, 0, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gx.DataType() != types.FP32 {
+		t.Fatalf("Sobel returned %v", gx.DataType())
+	}
+	// [-1 0 1] x [1 2 1]: 2 * slope * 4
+	if p := pixel(gx, 4, 4); p != 24 {
+		t.Errorf("Sobel dx = %v, want 24", p)
+	}
+	if p := pixel(gy, 4, 4); p != 40 {
+		t.Errorf("Sobel dy = %v, want 40", p)
+	}
+	// reflected borders cancel the gradient
+	if p := pixel(gx, 0, 4); p != 0 {
+		t.Errorf("Sobel dx at the border = %v, want 0", p)
+	}
+
+	gx, err = Scharr(ramp, HWC, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := pixel(gx, 4, 4); p != 96 {
+		t.Errorf("Scharr dx = %v, want 96", p)
+	}
+
+	parabola := grayF(9, 3, func(x, y int) float32 { return float32(x * x) })
+	for _, ksize := range []int{1, 3, 5, 7} {
+		g, err := Sobel(parabola, HWC, 2, 0, ksize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the second difference of x^2 is 2, scaled by the smoothing
+		// weights of the aperture
+		scale := 1.0
+		if ksize > 1 {
+			scale = math.Pow(2, float64(2*(ksize-1)-2))
+		}
+		if p := pixel(g, 4, 1); !near(p, 2*scale, 1e-3) {
+			t.Errorf("ksize %d: d2x = %v, want %v", ksize, p, 2*scale)
+		}
+	}
+
+	for _, bad := range [][3]int{{0, 0, 3}, {1, 0, 4}, {3, 0, 3}} {
+		if _, err := Sobel(ramp, HWC, bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("Sobel%v accepted", bad)
+		}
+	}
+}
+
+func TestMorphology(t *testing.T) {
+	dot := gray(7, 7, func(x, y int) uint8 {
+		if x == 3 && y == 3 {
+			return 255
+		}
+		return 0
+	})
+	rect, _ := StructuringElement(MorphRect, 3, 3)
+	cross, _ := StructuringElement(MorphCross, 3, 3)
+
+	got, err := Dilate(dot, HWC, rect, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count(got) != 9 {
+		t.Errorf("dilated dot covers %d pixels, want 9", count(got))
+	}
+	got, err = Dilate(dot, HWC, cross, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count(got) != 13 {
+		t.Errorf("twice dilated dot covers %d pixels, want 13", count(got))
+	}
+	got, err = MorphologyEx(dot, HWC, MorphOpen, rect, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count(got) != 0 {
+		t.Errorf("opening left %d pixels", count(got))
+	}
+	got, err = MorphologyEx(dot, HWC, MorphTopHat, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count(got) != 1 {
+		t.Errorf("top hat found %d pixels, want 1", count(got))
+	}
+
+	block := gray(7, 7, func(x, y int) uint8 {
+		if x >= 1 && x <= 5 && y >= 1 && y <= 5 {
+			return 255
+		}
+		return 0
+	})
+	got, err = MorphologyEx(block, HWC, MorphGradient, rect, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the ring between the dilated 7x7 and eroded 3x3 blocks
+	if count(got) != 49-9 {
+		t.Errorf("gradient covers %d pixels, want 40", count(got))
+	}
+
+	ellipse, err := StructuringElement(MorphEllipse, 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cv::getStructuringElement(MORPH_ELLIPSE, (5, 5))
+	want := []string{"..#..", "#####", "#####", "#####", "..#.."}
+	for y, row := range ellipse {
+		for x, on := range row {
+			if on != (want[y][x] == '#') {
+				t.Fatalf("ellipse row %d = %v, want %s", y, row, want[y])
+			}
+		}
+	}
+	if _, err := StructuringElement(MorphRect, 2, 3); err == nil {
+		t.Error("even element accepted")
+	}
+}
+
+func count(t types.Tensor) int {
+	n := 0
+	for _, v := range t.Data().([]uint8) {
+		if v != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestThreshold(t *testing.T) {
+	src := grayF(5, 1, func(x, y int) float32 { return float32(x * 50) })
+	for _, tt := range []struct {
+		typ  ThresholdType
+		want []float32
+	}{
+		{ThreshBinary, []float32{0, 0, 0, 9, 9}},
+		{ThreshBinaryInv, []float32{9, 9, 9, 0, 0}},
+		{ThreshTrunc, []float32{0, 50, 100, 100, 100}},
+		{ThreshToZero, []float32{0, 0, 0, 150, 200}},
+		{ThreshToZeroInv, []float32{0, 50, 100, 0, 0}},
+	} {
+		got, err := Threshold(src, HWC, 100, 9, tt.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range got.Data().([]float32) {
+			if v != tt.want[i] {
+				t.Errorf("type %d: %v, want %v", tt.typ, got.Data(), tt.want)
+				break
+			}
+		}
+	}
+
+	bimodal := gray(20, 10, func(x, y int) uint8 {
+		if x < 8 {
+			return uint8(40 + y)
+		}
+		return uint8(180 + y)
+	})
+	th, err := Otsu(bimodal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th < 49 || th >= 180 {
+		t.Errorf("Otsu threshold %v does not separate the modes", th)
+	}
+
+	// a dark blob on a bright gradient is found everywhere
+	shaded := gray(40, 40, func(x, y int) uint8 {
+		if x >= 10 && x < 14 && y >= 20 && y < 24 {
+			return uint8(x * 2)
+		}
+		return uint8(100 + 3*x)
+	})
+	for _, method := range []AdaptiveMethod{AdaptiveMean, AdaptiveGaussian} {
+		got, err := AdaptiveThreshold(shaded, 255, method, ThreshBinaryInv, 11, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := count(got); n != 16 {
+			t.Errorf("method %d: %d dark pixels, want 16", method, n)
+		}
+	}
+}
+
+func TestCanny(t *testing.T) {
+	square := gray(32, 32, func(x, y int) uint8 {
+		if x >= 8 && x < 24 && y >= 8 && y < 24 {
+			return 200
+		}
+		return 20
+	})
+	got, err := Canny(square, 50, 150, 3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shape := got.Shape(); got.DataType() != types.UINT8 || shape[0] != 32 || shape[1] != 32 {
+		t.Fatalf("Canny returned %v %v", got.DataType(), shape)
+	}
+	// one pixel wide edges on the square outline only
+	edges := 0
+	for y := range 32 {
+		for x := range 32 {
+			if pixel(got, x, y) == 0 {
+				continue
+			}
+			edges++
+			onOutline := (x >= 7 && x <= 24 && (y >= 7 && y <= 8 || y >= 23 && y <= 24)) ||
+				(y >= 7 && y <= 24 && (x >= 7 && x <= 8 || x >= 23 && x <= 24))
+			if !onOutline {
+				t.Fatalf("edge at %d, %d", x, y)
+			}
+		}
+	}
+	if edges < 4*14 || edges > 4*18 {
+		t.Errorf("%d edge pixels", edges)
+	}
+
+	flat := gray(16, 16, func(x, y int) uint8 { return 128 })
+	got, err = Canny(flat, 10, 20, 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count(got) != 0 {
+		t.Error("edges in a flat image")
+	}
+}
+
+func TestConnectedComponents(t *testing.T) {
+	rows := []string{
+		"#..##",
+		".#..#",
+		"....#",
+		"##...",
+	}
+	src := gray(5, 4, func(x, y int) uint8 {
+		if rows[y][x] == '#' {
+			return 1
+		}
+		return 0
+	})
+
+	_, n, err := ConnectedComponents(src, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("4-connected: %d labels, want 5", n)
+	}
+
+	labels, stats, err := ConnectedComponentsWithStats(src, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 4 {
+		t.Fatalf("8-connected: %d labels, want 4", len(stats))
+	}
+	if labels.DataType() != types.INT32 {
+		t.Fatalf("labels are %v", labels.DataType())
+	}
+	want := []int32{
+		1, 0, 0, 2, 2,
+		0, 1, 0, 0, 2,
+		0, 0, 0, 0, 2,
+		3, 3, 0, 0, 0,
+	}
+	for i, l := range labels.Data().([]int32) {
+		if l != want[i] {
+			t.Fatalf("labels %v, want %v", labels.Data(), want)
+		}
+	}
+	if s := stats[2]; s.Left != 3 || s.Top != 0 || s.Width != 2 || s.Height != 3 || s.Area != 4 || !near(s.CX, 3.75, 1e-9) || !near(s.CY, 0.75, 1e-9) {
+		t.Errorf("component 2 = %+v", s)
+	}
+	if stats[0].Area != 20-8 {
+		t.Errorf("background area %d", stats[0].Area)
+	}
+	if _, _, err := ConnectedComponents(src, 6); err == nil {
+		t.Error("connectivity 6 accepted")
+	}
+}
+
+func TestChannels(t *testing.T) {
+	src := readGray(t, "testdata/input.png")
+	want, err := GaussianBlur(src, HWC, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, w := src.Shape()[0], src.Shape()[1]
+	n := w * h
+
+	// the same image in every channel gives the same result per channel
+	hwc := tensor.New(types.UINT8, tensor.NewShape(h, w, 3))
+	chw := tensor.New(types.UINT8, tensor.NewShape(3, h, w))
+	for i, v := range src.Data().([]uint8) {
+		for ch := range 3 {
+			hwc.Data().([]uint8)[i*3+ch] = v
+			chw.Data().([]uint8)[ch*n+i] = v
+		}
+	}
+	for _, tt := range []struct {
+		layout Layout
+		img    types.Tensor
+		at     func(i, ch int) int
+	}{
+		{HWC, hwc, func(i, ch int) int { return i*3 + ch }},
+		{CHW, chw, func(i, ch int) int { return ch*n + i }},
+	} {
+		got, err := GaussianBlur(tt.img, tt.layout, 5, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Shape().Equal(tt.img.Shape()) {
+			t.Fatalf("%v: shape %v", tt.layout, got.Shape())
+		}
+		data := got.Data().([]uint8)
+		for i, v := range want.Data().([]uint8) {
+			for ch := range 3 {
+				if data[tt.at(i, ch)] != v {
+					t.Fatalf("%v: pixel %d channel %d = %d, want %d", tt.layout, i, ch, data[tt.at(i, ch)], v)
+				}
+			}
+		}
+	}
+}
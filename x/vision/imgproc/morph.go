@@ -0,0 +1,192 @@
+package imgproc
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// MorphShape selects a structuring element of StructuringElement.
+type MorphShape int
+
+const (
+	// MorphRect is a filled rectangle.
+	MorphRect MorphShape = iota
+	// MorphCross is a horizontal and a vertical line through the center.
+	MorphCross
+	// MorphEllipse is a filled ellipse inscribed in the rectangle.
+	MorphEllipse
+)
+
+// Kernel is a structuring element; true marks the pixels taking part. Its
+// size is odd and it is anchored at the center.
+type Kernel [][]bool
+
+// StructuringElement returns a width x height element of the given shape
+// (cv::getStructuringElement).
+func StructuringElement(shape MorphShape, width, height int) (Kernel, error) {
+	if width <= 0 || height <= 0 || width%2 == 0 || height%2 == 0 {
+		return nil, fmt.Errorf("imgproc: structuring element size must be odd and positive, got %dx%d", width, height)
+	}
+	k := make(Kernel, height)
+	r, c := height/2, width/2
+	for y := range k {
+		k[y] = make([]bool, width)
+		for x := range k[y] {
+			switch shape {
+			case MorphRect:
+				k[y][x] = true
+			case MorphCross:
+				k[y][x] = x == c || y == r
+			case MorphEllipse:
+				// the row span of cv::getStructuringElement
+				dy := float64(y - r)
+				dx := c
+				if r > 0 {
+					dx = int(float64(c)*math.Sqrt(max(float64(r*r)-dy*dy, 0))/float64(r) + 0.5)
+				}
+				k[y][x] = x >= c-dx && x <= c+dx
+			default:
+				return nil, fmt.Errorf("imgproc: unknown structuring element shape %d", shape)
+			}
+		}
+	}
+	return k, nil
+}
+
+// MorphOp selects the operation of MorphologyEx.
+type MorphOp int
+
+const (
+	// MorphErode takes the neighbourhood minimum.
+	MorphErode MorphOp = iota
+	// MorphDilate takes the neighbourhood maximum.
+	MorphDilate
+	// MorphOpen erodes, then dilates; it removes small bright specks.
+	MorphOpen
+	// MorphClose dilates, then erodes; it fills small dark holes.
+	MorphClose
+	// MorphGradient is the dilation minus the erosion, an outline.
+	MorphGradient
+	// MorphTopHat is the image minus its opening.
+	MorphTopHat
+	// MorphBlackHat is the closing minus the image.
+	MorphBlackHat
+)
+
+// Erode takes the minimum over kernel around every pixel, iterations times.
+// Pixels outside the image are ignored.
+func Erode(src types.Tensor, layout Layout, kernel Kernel, iterations int) (types.Tensor, error) {
+	return MorphologyEx(src, layout, MorphErode, kernel, iterations)
+}
+
+// Dilate takes the maximum over kernel around every pixel, iterations times.
+// Pixels outside the image are ignored.
+func Dilate(src types.Tensor, layout Layout, kernel Kernel, iterations int) (types.Tensor, error) {
+	return MorphologyEx(src, layout, MorphDilate, kernel, iterations)
+}
+
+// MorphologyEx applies op with kernel, repeating each erosion and dilation
+// iterations times (cv::morphologyEx). A nil kernel is a 3x3 rectangle.
+func MorphologyEx(src types.Tensor, layout Layout, op MorphOp, kernel Kernel, iterations int) (types.Tensor, error) {
+	if kernel == nil {
+		kernel, _ = StructuringElement(MorphRect, 3, 3)
+	}
+	if err := kernel.validate(); err != nil {
+		return nil, err
+	}
+	if iterations < 1 {
+		iterations = 1
+	}
+	erode := func(p planes) planes { return morph(p, kernel, iterations, false) }
+	dilate := func(p planes) planes { return morph(p, kernel, iterations, true) }
+
+	return apply(src, layout, func(p planes) (planes, error) {
+		switch op {
+		case MorphErode:
+			return erode(p), nil
+		case MorphDilate:
+			return dilate(p), nil
+		case MorphOpen:
+			return dilate(erode(p)), nil
+		case MorphClose:
+			return erode(dilate(p)), nil
+		case MorphGradient:
+			return subtract(dilate(p), erode(p)), nil
+		case MorphTopHat:
+			return subtract(p, dilate(erode(p))), nil
+		case MorphBlackHat:
+			return subtract(erode(dilate(p)), p), nil
+		}
+		return planes{}, fmt.Errorf("imgproc: unknown morphology operation %d", op)
+	})
+}
+
+func (k Kernel) validate() error {
+	if len(k) == 0 || len(k)%2 == 0 || len(k[0])%2 == 0 {
+		return fmt.Errorf("imgproc: structuring element size must be odd")
+	}
+	for _, row := range k {
+		if len(row) != len(k[0]) {
+			return fmt.Errorf("imgproc: ragged structuring element")
+		}
+	}
+	return nil
+}
+
+// offset is a structuring element pixel relative to the anchor.
+type offset struct{ dx, dy int }
+
+func (k Kernel) offsets() []offset {
+	var out []offset
+	r, c := len(k)/2, len(k[0])/2
+	for y, row := range k {
+		for x, on := range row {
+			if on {
+				out = append(out, offset{x - c, y - r})
+			}
+		}
+	}
+	return out
+}
+
+func morph(p planes, kernel Kernel, iterations int, dilate bool) planes {
+	offsets := kernel.offsets()
+	for range iterations {
+		out := p.like()
+		for ch, in := range p.c {
+			for y := range p.h {
+				for x := range p.w {
+					v, found := float32(0), false
+					for _, o := range offsets {
+						sx, sy := x+o.dx, y+o.dy
+						if sx < 0 || sy < 0 || sx >= p.w || sy >= p.h {
+							continue
+						}
+						s := in[sy*p.w+sx]
+						if !found || (dilate && s > v) || (!dilate && s < v) {
+							v, found = s, true
+						}
+					}
+					if !found {
+						v = in[y*p.w+x]
+					}
+					out.c[ch][y*p.w+x] = v
+				}
+			}
+		}
+		p = out
+	}
+	return p
+}
+
+func subtract(a, b planes) planes {
+	out := a.like()
+	for ch := range out.c {
+		for i := range out.c[ch] {
+			out.c[ch][i] = a.c[ch][i] - b.c[ch][i]
+		}
+	}
+	return out
+}
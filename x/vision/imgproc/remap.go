@@ -0,0 +1,75 @@
+package imgproc
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Map is a precomputed geometric transform for Remap: the destination pixel
+// (x, y) takes the source value at (X[y*Width+x], Y[y*Width+x]). Build it
+// once, for example from a camera calibration, and remap every frame with it.
+type Map struct {
+	Width, Height int
+	X, Y          []float32
+}
+
+// NewMap returns an identity map of width x height pixels.
+func NewMap(width, height int) *Map {
+	m := &Map{Width: width, Height: height, X: make([]float32, width*height), Y: make([]float32, width*height)}
+	for y := range height {
+		for x := range width {
+			m.X[y*width+x], m.Y[y*width+x] = float32(x), float32(y)
+		}
+	}
+	return m
+}
+
+// Remap resamples src through m with bilinear interpolation into an image of
+// m.Width x m.Height pixels. Source pixels outside the image are zero, as
+// with OpenCV BORDER_CONSTANT.
+func Remap(src types.Tensor, layout Layout, m *Map) (types.Tensor, error) {
+	if m == nil || m.Width <= 0 || m.Height <= 0 || len(m.X) != m.Width*m.Height || len(m.Y) != len(m.X) {
+		return nil, fmt.Errorf("imgproc: invalid remap table")
+	}
+	return apply(src, layout, func(p planes) (planes, error) {
+		return remapBilinear(p, m), nil
+	})
+}
+
+func remapBilinear(p planes, m *Map) planes {
+	out := newPlanes(m.Width, m.Height, len(p.c))
+	for i := range m.X {
+		fx, fy := float64(m.X[i]), float64(m.Y[i])
+		// NaN and far away coordinates stay zero
+		if !(fx > -1 && fx < float64(p.w) && fy > -1 && fy < float64(p.h)) {
+			continue
+		}
+		x0, y0 := int(math.Floor(fx)), int(math.Floor(fy))
+		wx, wy := float32(fx-float64(x0)), float32(fy-float64(y0))
+		// weights of the four neighbours, zero outside the image
+		var w [4]float32
+		var idx [4]int
+		for k := range 4 {
+			x, y := x0+k&1, y0+k>>1
+			if x < 0 || y < 0 || x >= p.w || y >= p.h {
+				continue
+			}
+			ww := wx
+			if k&1 == 0 {
+				ww = 1 - wx
+			}
+			if k>>1 == 0 {
+				ww *= 1 - wy
+			} else {
+				ww *= wy
+			}
+			w[k], idx[k] = ww, y*p.w+x
+		}
+		for ch, in := range p.c {
+			out.c[ch][i] = in[idx[0]]*w[0] + in[idx[1]]*w[1] + in[idx[2]]*w[2] + in[idx[3]]*w[3]
+		}
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+package imgproc
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Interpolation selects the resampling method of Resize.
+type Interpolation int
+
+const (
+	// Bilinear interpolates between the four nearest pixels, using pixel
+	// centers as OpenCV INTER_LINEAR does.
+	Bilinear Interpolation = iota
+	// Nearest picks the nearest pixel.
+	Nearest
+	// Area averages the source pixels covered by each destination pixel,
+	// the choice for shrinking without aliasing. Enlarging falls back to
+	// Bilinear.
+	Area
+)
+
+// Resize scales src to width x height pixels.
+func Resize(src types.Tensor, layout Layout, width, height int, interp Interpolation) (types.Tensor, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("imgproc: invalid size %dx%d", width, height)
+	}
+	return apply(src, layout, func(p planes) (planes, error) {
+		switch interp {
+		case Nearest:
+			return resizeNearest(p, width, height), nil
+		case Area:
+			if width <= p.w && height <= p.h {
+				return resizeArea(p, width, height), nil
+			}
+			return resizeBilinear(p, width, height), nil
+		case Bilinear:
+			return resizeBilinear(p, width, height), nil
+		}
+		return planes{}, fmt.Errorf("imgproc: unknown interpolation %d", interp)
+	})
+}
+
+func resizeNearest(p planes, width, height int) planes {
+	out := newPlanes(width, height, len(p.c))
+	sx, sy := float64(p.w)/float64(width), float64(p.h)/float64(height)
+	for ch, in := range p.c {
+		for y := range height {
+			row := min(int(float64(y)*sy), p.h-1) * p.w
+			for x := range width {
+				out.c[ch][y*width+x] = in[row+min(int(float64(x)*sx), p.w-1)]
+			}
+		}
+	}
+	return out
+}
+
+// tap is a pair of source indices and the weight of the second.
+type tap struct {
+	i0, i1 int
+	w      float32
+}
+
+// linearTaps maps every destination index to its two source neighbours.
+func linearTaps(dst, src int) []tap {
+	scale := float64(src) / float64(dst)
+	taps := make([]tap, dst)
+	for i := range taps {
+		f := (float64(i)+0.5)*scale - 0.5
+		i0 := int(math.Floor(f))
+		w := float32(f - float64(i0))
+		switch {
+		case i0 < 0:
+			taps[i] = tap{0, 0, 0}
+		case i0 >= src-1:
+			taps[i] = tap{src - 1, src - 1, 0}
+		default:
+			taps[i] = tap{i0, i0 + 1, w}
+		}
+	}
+	return taps
+}
+
+func resizeBilinear(p planes, width, height int) planes {
+	out := newPlanes(width, height, len(p.c))
+	xs, ys := linearTaps(width, p.w), linearTaps(height, p.h)
+	for ch, in := range p.c {
+		for y, ty := range ys {
+			r0, r1 := in[ty.i0*p.w:], in[ty.i1*p.w:]
+			for x, tx := range xs {
+				top := r0[tx.i0] + (r0[tx.i1]-r0[tx.i0])*tx.w
+				bottom := r1[tx.i0] + (r1[tx.i1]-r1[tx.i0])*tx.w
+				out.c[ch][y*width+x] = top + (bottom-top)*ty.w
+			}
+		}
+	}
+	return out
+}
+
+// span is a source index and its share of a destination pixel.
+type span struct {
+	i int
+	w float32
+}
+
+// areaSpans lists the source indices covering every destination index when
+// shrinking src to dst, weighted by overlap.
+func areaSpans(dst, src int) [][]span {
+	scale := float64(src) / float64(dst)
+	spans := make([][]span, dst)
+	for i := range spans {
+		lo, hi := float64(i)*scale, float64(i+1)*scale
+		for s := int(math.Floor(lo)); s < int(math.Ceil(hi)) && s < src; s++ {
+			overlap := math.Min(hi, float64(s+1)) - math.Max(lo, float64(s))
+			if overlap > 1e-9 {
+				spans[i] = append(spans[i], span{s, float32(overlap / scale)})
+			}
+		}
+	}
+	return spans
+}
+
+func resizeArea(p planes, width, height int) planes {
+	out := newPlanes(width, height, len(p.c))
+	xs, ys := areaSpans(width, p.w), areaSpans(height, p.h)
+	for ch, in := range p.c {
+		for y, sy := range ys {
+			for x, sx := range xs {
+				var sum float32
+				for _, v := range sy {
+					row := in[v.i*p.w:]
+					var acc float32
+					for _, u := range sx {
+						acc += row[u.i] * u.w
+					}
+					sum += acc * v.w
+				}
+				out.c[ch][y*width+x] = sum
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package imgproc
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// ThresholdType selects how Threshold maps pixels against the threshold.
+type ThresholdType int
+
+const (
+	// ThreshBinary sets pixels above the threshold to maxval, others to 0.
+	ThreshBinary ThresholdType = iota
+	// ThreshBinaryInv sets pixels above the threshold to 0, others to maxval.
+	ThreshBinaryInv
+	// ThreshTrunc clips pixels above the threshold to it.
+	ThreshTrunc
+	// ThreshToZero zeroes pixels not above the threshold.
+	ThreshToZero
+	// ThreshToZeroInv zeroes pixels above the threshold.
+	ThreshToZeroInv
+)
+
+// Threshold applies a fixed threshold to every channel of src
+// (cv::threshold).
+func Threshold(src types.Tensor, layout Layout, thresh, maxval float32, typ ThresholdType) (types.Tensor, error) {
+	if typ < ThreshBinary || typ > ThreshToZeroInv {
+		return nil, fmt.Errorf("imgproc: unknown threshold type %d", typ)
+	}
+	return apply(src, layout, func(p planes) (planes, error) {
+		out := p.like()
+		for ch, in := range p.c {
+			for i, v := range in {
+				out.c[ch][i] = thresholdPixel(v, thresh, maxval, typ)
+			}
+		}
+		return out, nil
+	})
+}
+
+func thresholdPixel(v, thresh, maxval float32, typ ThresholdType) float32 {
+	above := v > thresh
+	switch typ {
+	case ThreshBinary:
+		if above {
+			return maxval
+		}
+		return 0
+	case ThreshBinaryInv:
+		if above {
+			return 0
+		}
+		return maxval
+	case ThreshTrunc:
+		return min(v, thresh)
+	case ThreshToZero:
+		if above {
+			return v
+		}
+		return 0
+	}
+	if above {
+		return 0
+	}
+	return v
+}
+
+// Otsu returns the threshold of a single channel UINT8 image that best
+// separates its histogram into two classes, for use with Threshold.
+func Otsu(src types.Tensor) (float32, error) {
+	p, err := load(src, HWC)
+	if err != nil {
+		return 0, err
+	}
+	if len(p.c) != 1 || src.DataType() != types.UINT8 {
+		return 0, fmt.Errorf("imgproc: Otsu needs a single channel UINT8 image")
+	}
+	var hist [256]float64
+	for _, v := range p.c[0] {
+		hist[int(v)]++
+	}
+	total := float64(len(p.c[0]))
+	var mean float64
+	for i, n := range hist {
+		mean += float64(i) * n / total
+	}
+
+	// maximise the between-class variance
+	var best, bestVar, w0, sum0 float64
+	for i, n := range hist {
+		w0 += n / total
+		sum0 += float64(i) * n / total
+		if w0 < 1e-12 || 1-w0 < 1e-12 {
+			continue
+		}
+		mu0, mu1 := sum0/w0, (mean-sum0)/(1-w0)
+		if v := w0 * (1 - w0) * (mu0 - mu1) * (mu0 - mu1); v > bestVar {
+			best, bestVar = float64(i), v
+		}
+	}
+	return float32(best), nil
+}
+
+// AdaptiveMethod selects the neighbourhood mean of AdaptiveThreshold.
+type AdaptiveMethod int
+
+const (
+	// AdaptiveMean uses the plain blockSize x blockSize mean.
+	AdaptiveMean AdaptiveMethod = iota
+	// AdaptiveGaussian uses a Gaussian weighted mean.
+	AdaptiveGaussian
+)
+
+// AdaptiveThreshold thresholds every pixel of a single channel image
+// against the mean of its blockSize x blockSize neighbourhood minus c
+// (cv::adaptiveThreshold). typ is ThreshBinary or ThreshBinaryInv.
+func AdaptiveThreshold(src types.Tensor, maxval float32, method AdaptiveMethod, typ ThresholdType, blockSize int, c float32) (types.Tensor, error) {
+	if typ != ThreshBinary && typ != ThreshBinaryInv {
+		return nil, fmt.Errorf("imgproc: adaptive threshold type must be binary")
+	}
+	if blockSize < 3 || blockSize%2 == 0 {
+		return nil, fmt.Errorf("imgproc: block size must be odd and at least 3, got %d", blockSize)
+	}
+	var k []float32
+	switch method {
+	case AdaptiveMean:
+		k = make([]float32, blockSize)
+		for i := range k {
+			k[i] = 1 / float32(blockSize)
+		}
+	case AdaptiveGaussian:
+		k, _ = gaussianKernel(blockSize, 0)
+	default:
+		return nil, fmt.Errorf("imgproc: unknown adaptive method %d", method)
+	}
+	integral := src != nil && src.DataType() == types.UINT8
+
+	return apply(src, HWC, func(p planes) (planes, error) {
+		if len(p.c) != 1 {
+			return planes{}, fmt.Errorf("imgproc: adaptive threshold needs a single channel image")
+		}
+		mean := separable(p, k, k, replicate)
+		out := p.like()
+		for i, v := range p.c[0] {
+			m := mean.c[0][i]
+			if integral {
+				m = round(m)
+			}
+			out.c[0][i] = thresholdPixel(v, m-c, maxval, typ)
+		}
+		return out, nil
+	})
+}
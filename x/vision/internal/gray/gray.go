@@ -0,0 +1,77 @@
+// Package gray holds the single channel float image the vision packages
+// work on internally, converted from UINT8 or FP32 image tensors.
+package gray
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+)
+
+// Image is a single channel image with values in the 0..255 range.
+type Image struct {
+	W, H int
+	Pix  []float32
+}
+
+// FromTensor copies a single channel UINT8 or FP32 image of shape [H, W] or
+// [H, W, 1].
+func FromTensor(t types.Tensor) (Image, error) {
+	if t == nil || tensor.IsNil(t) {
+		return Image{}, fmt.Errorf("vision: nil image")
+	}
+	shape := t.Shape()
+	if len(shape) == 3 && shape[2] == 1 {
+		shape = shape[:2]
+	}
+	if len(shape) != 2 || shape[0] <= 0 || shape[1] <= 0 {
+		return Image{}, fmt.Errorf("vision: image must be [H, W] or [H, W, 1], got %v", t.Shape())
+	}
+	if !t.IsContiguous() {
+		return Image{}, fmt.Errorf("vision: image views are not supported, copy the tensor first")
+	}
+	g := Image{W: shape[1], H: shape[0], Pix: make([]float32, shape[0]*shape[1])}
+	switch data := t.Data().(type) {
+	case []uint8:
+		for i := range g.Pix {
+			g.Pix[i] = float32(data[i])
+		}
+	case []float32:
+		copy(g.Pix, data)
+	default:
+		return Image{}, fmt.Errorf("vision: unsupported data type %v, want UINT8 or FP32", t.DataType())
+	}
+	return g, nil
+}
+
+// Wrap shares the data of an FP32 [H, W] tensor, such as those returned by
+// imgproc.
+func Wrap(t types.Tensor) Image {
+	s := t.Shape()
+	return Image{W: s[1], H: s[0], Pix: t.Data().([]float32)}
+}
+
+// Tensor wraps the pixels of g without copying.
+func (g Image) Tensor() types.Tensor {
+	return tensor.FromArray(tensor.NewShape(g.H, g.W), g.Pix)
+}
+
+// At returns the pixel at x, y, replicating the border.
+func (g Image) At(x, y int) float32 {
+	x = min(max(x, 0), g.W-1)
+	y = min(max(y, 0), g.H-1)
+	return g.Pix[y*g.W+x]
+}
+
+// Sample interpolates bilinearly at pixel centre coordinates, replicating
+// the border.
+func (g Image) Sample(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+	a := float64(g.At(ix, iy))*(1-fx) + float64(g.At(ix+1, iy))*fx
+	b := float64(g.At(ix, iy+1))*(1-fx) + float64(g.At(ix+1, iy+1))*fx
+	return a*(1-fy) + b*fy
+}
@@ -0,0 +1,122 @@
+package stereo
+
+import "github.com/itohio/EasyRobot/internal/concurrency"
+
+// aggregate sums the path costs
+//
+//	L(p, d) = C(p, d) + min(L(p-r, d), L(p-r, d±1) + P1, min L(p-r) + P2) - min L(p-r)
+//
+// over the horizontal and vertical paths, plus the diagonals for 8 paths.
+// Rows are independent along horizontal paths; the other paths sweep the
+// image down and up, a row at a time, splitting each row between workers.
+func (s *SGBM) aggregate(c volume) volume {
+	agg := newVolume(c.w, c.h, c.d)
+	concurrency.Parallel(c.h, func(lo, hi int) {
+		prev, cur := make([]uint16, c.d), make([]uint16, c.d)
+		for y := lo; y < hi; y++ {
+			for _, dir := range [2]int{1, -1} {
+				x := 0
+				if dir < 0 {
+					x = c.w - 1
+				}
+				var prevMin uint16
+				for n := range c.w {
+					i := y*c.w + x
+					costs := c.v[i*c.d : (i+1)*c.d]
+					if n == 0 {
+						copy(cur, costs)
+						prevMin = minOf(cur)
+					} else {
+						prevMin = s.step(costs, prev, prevMin, cur)
+					}
+					accumulate(agg.v[i*c.d:(i+1)*c.d], cur)
+					prev, cur = cur, prev
+					x += dir
+				}
+			}
+		}
+	})
+
+	dxs := []int{0}
+	if s.paths == 8 {
+		dxs = []int{-1, 0, 1}
+	}
+	for _, dy := range [2]int{1, -1} {
+		s.sweep(c, agg, dy, dxs)
+	}
+	return agg
+}
+
+// sweep aggregates the paths entering every pixel from the previous row,
+// dy = 1 for paths going down the image, with horizontal steps dxs.
+func (s *SGBM) sweep(c, agg volume, dy int, dxs []int) {
+	prev := make([][]uint16, len(dxs))
+	cur := make([][]uint16, len(dxs))
+	prevMin := make([][]uint16, len(dxs))
+	curMin := make([][]uint16, len(dxs))
+	for p := range dxs {
+		prev[p], cur[p] = make([]uint16, c.w*c.d), make([]uint16, c.w*c.d)
+		prevMin[p], curMin[p] = make([]uint16, c.w), make([]uint16, c.w)
+	}
+	y := 0
+	if dy < 0 {
+		y = c.h - 1
+	}
+	for n := range c.h {
+		concurrency.Parallel(c.w, func(lo, hi int) {
+			for x := lo; x < hi; x++ {
+				i := y*c.w + x
+				costs := c.v[i*c.d : (i+1)*c.d]
+				sum := agg.v[i*c.d : (i+1)*c.d]
+				for p, dx := range dxs {
+					out := cur[p][x*c.d : (x+1)*c.d]
+					if px := x - dx; n == 0 || px < 0 || px >= c.w {
+						copy(out, costs)
+						curMin[p][x] = minOf(out)
+					} else {
+						curMin[p][x] = s.step(costs, prev[p][px*c.d:(px+1)*c.d], prevMin[p][px], out)
+					}
+					accumulate(sum, out)
+				}
+			}
+		})
+		prev, cur = cur, prev
+		prevMin, curMin = curMin, prevMin
+		y += dy
+	}
+}
+
+// step computes the path costs of a pixel from those of its predecessor and
+// returns their minimum.
+func (s *SGBM) step(costs, prev []uint16, prevMin uint16, out []uint16) uint16 {
+	p1, jump := uint16(s.p1), prevMin+uint16(s.p2)
+	best := uint16(0xFFFF)
+	last := len(costs) - 1
+	for k, c := range costs {
+		m := min(prev[k], jump)
+		if k > 0 {
+			m = min(m, prev[k-1]+p1)
+		}
+		if k < last {
+			m = min(m, prev[k+1]+p1)
+		}
+		v := c + m - prevMin
+		out[k] = v
+		best = min(best, v)
+	}
+	return best
+}
+
+func accumulate(sum, l []uint16) {
+	for k, v := range l {
+		sum[k] += v
+	}
+}
+
+func minOf(v []uint16) uint16 {
+	m := v[0]
+	for _, x := range v[1:] {
+		m = min(m, x)
+	}
+	return m
+}
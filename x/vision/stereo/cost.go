@@ -0,0 +1,108 @@
+package stereo
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/itohio/EasyRobot/internal/concurrency"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// census returns the census transform of g: one bit per block pixel, set
+// when the pixel is darker than the centre. Borders are replicated.
+func census(g gray.Image, block int) []uint64 {
+	r := block / 2
+	out := make([]uint64, g.W*g.H)
+	concurrency.Parallel(g.H, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := range g.W {
+				c := g.Pix[y*g.W+x]
+				var v uint64
+				for dy := -r; dy <= r; dy++ {
+					row := min(max(y+dy, 0), g.H-1) * g.W
+					for dx := -r; dx <= r; dx++ {
+						if dx == 0 && dy == 0 {
+							continue
+						}
+						v <<= 1
+						if g.Pix[row+min(max(x+dx, 0), g.W-1)] < c {
+							v |= 1
+						}
+					}
+				}
+				out[y*g.W+x] = v
+			}
+		}
+	})
+	return out
+}
+
+// censusCost returns the Hamming distances of the census transforms. Right
+// pixels left of the image cost as much as all bits differing.
+func (s *SGBM) censusCost(l, r gray.Image) volume {
+	cl, cr := census(l, s.blockSize), census(r, s.blockSize)
+	worst := uint16(s.blockSize*s.blockSize - 1)
+	c := newVolume(l.W, l.H, s.numDisparities)
+	concurrency.Parallel(l.H, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := range l.W {
+				i := y*l.W + x
+				costs := c.v[i*c.d : (i+1)*c.d]
+				for k := range costs {
+					xr := x - s.minDisparity - k
+					if xr < 0 {
+						costs[k] = worst
+						continue
+					}
+					costs[k] = uint16(bits.OnesCount64(cl[i] ^ cr[y*l.W+xr]))
+				}
+			}
+		}
+	})
+	return c
+}
+
+// sadCost returns the mean absolute differences over the blocks, windows
+// clipped at the image border. Right pixels left of the image cost 255.
+func (s *SGBM) sadCost(l, r gray.Image) volume {
+	c := newVolume(l.W, l.H, s.numDisparities)
+	rad := s.blockSize / 2
+	concurrency.Parallel(c.d, func(lo, hi int) {
+		diff := make([]float64, l.W*l.H)
+		rows := make([]float64, l.W*l.H)
+		prefix := make([]float64, max(l.W, l.H)+1)
+		for k := lo; k < hi; k++ {
+			shift := s.minDisparity + k
+			for y := range l.H {
+				for x := range l.W {
+					i := y*l.W + x
+					if x < shift {
+						diff[i] = 255
+						continue
+					}
+					diff[i] = math.Abs(float64(l.Pix[i] - r.Pix[i-shift]))
+				}
+			}
+			// box sums along rows, then along columns
+			for y := range l.H {
+				for x := range l.W {
+					prefix[x+1] = prefix[x] + diff[y*l.W+x]
+				}
+				for x := range l.W {
+					a, b := max(x-rad, 0), min(x+rad+1, l.W)
+					rows[y*l.W+x] = (prefix[b] - prefix[a]) / float64(b-a)
+				}
+			}
+			for x := range l.W {
+				for y := range l.H {
+					prefix[y+1] = prefix[y] + rows[y*l.W+x]
+				}
+				for y := range l.H {
+					a, b := max(y-rad, 0), min(y+rad+1, l.H)
+					c.v[(y*l.W+x)*c.d+k] = uint16(math.Round((prefix[b] - prefix[a]) / float64(b-a)))
+				}
+			}
+		}
+	})
+	return c
+}
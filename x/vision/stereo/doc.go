@@ -0,0 +1,9 @@
+// Package stereo computes dense depth from rectified stereo pairs in plain
+// Go: semi-global block matching (Hirschmüller 2008) with census or SAD
+// costs, left-right consistency and speckle filtering, and reprojection of
+// disparities to 3-D points with the Q matrix of the rectification.
+//
+// Images are single channel UINT8 or FP32 tensors of shape [H, W] or
+// [H, W, 1], rectified so that corresponding points share a row, e.g. by
+// the rectify transform.
+package stereo
@@ -0,0 +1,55 @@
+package stereo
+
+// filterSpeckles invalidates connected regions of fewer than window pixels,
+// neighbours belonging to a region when their disparities differ by at most
+// rng, as cv::filterSpeckles does.
+func filterSpeckles(disp []float32, w, h, window int, rng float32) {
+	label := make([]int32, len(disp))
+	var stack, region []int
+	next := int32(0)
+	for start, d := range disp {
+		if label[start] != 0 || d == Invalid {
+			continue
+		}
+		next++
+		label[start] = next
+		stack = append(stack[:0], start)
+		region = region[:0]
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			region = append(region, i)
+			x, y := i%w, i/w
+			for _, n := range [4][3]int{{x - 1, y, i - 1}, {x + 1, y, i + 1}, {x, y - 1, i - w}, {x, y + 1, i + w}} {
+				if n[0] < 0 || n[0] >= w || n[1] < 0 || n[1] >= h {
+					continue
+				}
+				j := n[2]
+				if label[j] != 0 || disp[j] == Invalid || abs32(disp[j]-disp[i]) > rng {
+					continue
+				}
+				label[j] = next
+				stack = append(stack, j)
+			}
+		}
+		if len(region) < window {
+			for _, i := range region {
+				disp[i] = Invalid
+			}
+		}
+	}
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
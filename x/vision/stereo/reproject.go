@@ -0,0 +1,69 @@
+package stereo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/internal/concurrency"
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// ReprojectImageTo3D maps a disparity image to an FP32 [H, W, 3] tensor of
+// points (X, Y, Z) / W with (X, Y, Z, W) = Q (x, y, d, 1), as
+// cv::reprojectImageTo3D does. With the Q of calib.Stereo.Rectify the points
+// are in the rectified left camera frame in the units of the calibration.
+// Invalid disparities give NaN points.
+func ReprojectImageTo3D(disparity types.Tensor, q mat.Matrix4x4) (types.Tensor, error) {
+	d, err := gray.FromTensor(disparity)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float32, 3*d.W*d.H)
+	nan := float32(math.NaN())
+	concurrency.Parallel(d.H, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			for x := range d.W {
+				i := y*d.W + x
+				p := out[3*i : 3*i+3]
+				v := d.Pix[i]
+				if v < 0 {
+					p[0], p[1], p[2] = nan, nan, nan
+					continue
+				}
+				var h [4]float32
+				for r := range 4 {
+					h[r] = q[r][0]*float32(x) + q[r][1]*float32(y) + q[r][2]*v + q[r][3]
+				}
+				if h[3] == 0 {
+					p[0], p[1], p[2] = nan, nan, nan
+					continue
+				}
+				p[0], p[1], p[2] = h[0]/h[3], h[1]/h[3], h[2]/h[3]
+			}
+		}
+	})
+	return tensor.FromArray(tensor.NewShape(d.H, d.W, 3), out), nil
+}
+
+// Points returns the valid points of a [H, W, 3] point tensor as an
+// [N, 3] point cloud, skipping NaN points and those farther than maxDepth
+// when it is positive.
+func Points(points types.Tensor, maxDepth float32) (types.Tensor, error) {
+	shape := points.Shape()
+	if len(shape) != 3 || shape[2] != 3 || points.DataType() != types.FP32 || !points.IsContiguous() {
+		return nil, fmt.Errorf("stereo: points must be a contiguous FP32 [H, W, 3] tensor, got %v %v", points.DataType(), shape)
+	}
+	data := points.Data().([]float32)
+	var out []float32
+	for i := 0; i < len(data); i += 3 {
+		z := data[i+2]
+		if z != z || (maxDepth > 0 && z > maxDepth) {
+			continue
+		}
+		out = append(out, data[i:i+3]...)
+	}
+	return tensor.FromArray(tensor.NewShape(len(out)/3, 3), out), nil
+}
@@ -0,0 +1,275 @@
+package stereo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itohio/EasyRobot/internal/concurrency"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// Invalid marks pixels without a disparity.
+const Invalid = -1
+
+// Cost selects the matching cost of SGBM.
+type Cost int
+
+const (
+	// Census compares the census transforms of the blocks by Hamming
+	// distance; robust to gain and exposure differences between cameras.
+	Census Cost = iota
+	// SAD is the mean absolute intensity difference over the block.
+	SAD
+)
+
+func (c Cost) String() string {
+	if c == SAD {
+		return "SAD"
+	}
+	return "census"
+}
+
+// SGBM computes disparities by semi-global block matching: block costs are
+// aggregated along 4 or 8 image paths with penalties P1 for disparity steps
+// of one and P2 for larger jumps, the winner takes all with sub-pixel
+// parabola fitting, and mismatches are removed by the uniqueness, left-right
+// and speckle checks, as cv::StereoSGBM does.
+type SGBM struct {
+	minDisparity, numDisparities int
+	blockSize                    int
+	cost                         Cost
+	p1, p2                       int
+	paths                        int
+	uniqueness                   int
+	disp12MaxDiff                int
+	speckleWindow                int
+	speckleRange                 float32
+}
+
+// Option configures SGBM.
+type Option func(*SGBM)
+
+// WithDisparities sets the disparity search range [min, min+num)
+// (default: 0, 64).
+func WithDisparities(min, num int) Option {
+	return func(s *SGBM) {
+		if min < 0 || num <= 0 {
+			panic("stereo: disparity range must be non-negative and not empty")
+		}
+		s.minDisparity, s.numDisparities = min, num
+	}
+}
+
+// WithBlockSize sets the odd side of the matching block (default: 5).
+// Census blocks are at most 7 pixels wide.
+func WithBlockSize(n int) Option {
+	return func(s *SGBM) {
+		if n <= 0 || n%2 == 0 {
+			panic("stereo: block size must be odd")
+		}
+		s.blockSize = n
+	}
+}
+
+// WithCost sets the matching cost (default: Census).
+func WithCost(c Cost) Option {
+	return func(s *SGBM) {
+		s.cost = c
+	}
+}
+
+// WithPenalties sets the smoothness penalties for disparity changes of one
+// (P1) and more (P2) between neighbours, in cost units (default: 8 and 64
+// for census, 6 and 48 for SAD). P2 must exceed P1 and stay below 4096.
+func WithPenalties(p1, p2 int) Option {
+	return func(s *SGBM) {
+		if p1 < 0 || p2 <= p1 || p2 >= 4096 {
+			panic("stereo: penalties must satisfy 0 <= P1 < P2 < 4096")
+		}
+		s.p1, s.p2 = p1, p2
+	}
+}
+
+// WithPaths sets the number of aggregation paths, 4 or 8 (default: 8).
+func WithPaths(n int) Option {
+	return func(s *SGBM) {
+		if n != 4 && n != 8 {
+			panic("stereo: paths must be 4 or 8")
+		}
+		s.paths = n
+	}
+}
+
+// WithUniqueness sets the margin in percent by which the best cost must
+// beat any other disparity but its neighbours (default: 10, 0 disables).
+func WithUniqueness(percent int) Option {
+	return func(s *SGBM) {
+		s.uniqueness = percent
+	}
+}
+
+// WithDisp12MaxDiff sets the largest difference between the left and right
+// disparities of a pixel (default: 1, negative disables the check).
+func WithDisp12MaxDiff(n int) Option {
+	return func(s *SGBM) {
+		s.disp12MaxDiff = n
+	}
+}
+
+// WithSpeckle removes connected regions of fewer than window pixels whose
+// disparities vary by at most rng between neighbours (default: 100, 2;
+// a window of 0 disables the filter).
+func WithSpeckle(window int, rng float32) Option {
+	return func(s *SGBM) {
+		s.speckleWindow, s.speckleRange = window, rng
+	}
+}
+
+// NewSGBM creates a semi-global block matcher.
+func NewSGBM(opts ...Option) *SGBM {
+	s := &SGBM{
+		numDisparities: 64,
+		blockSize:      5,
+		paths:          8,
+		uniqueness:     10,
+		disp12MaxDiff:  1,
+		speckleWindow:  100,
+		speckleRange:   2,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.cost == Census && s.blockSize > 7 {
+		panic("stereo: census blocks are at most 7 pixels wide")
+	}
+	if s.p2 == 0 {
+		s.p1, s.p2 = 8, 64
+		if s.cost == SAD {
+			s.p1, s.p2 = 6, 48
+		}
+	}
+	return s
+}
+
+// Compute returns the disparities of the left image as an FP32 [H, W]
+// tensor: the left pixel (x, y) matches the right pixel (x - d, y). Pixels
+// without a reliable match are Invalid.
+func (s *SGBM) Compute(left, right types.Tensor) (types.Tensor, error) {
+	l, err := gray.FromTensor(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := gray.FromTensor(right)
+	if err != nil {
+		return nil, err
+	}
+	if l.W != r.W || l.H != r.H {
+		return nil, fmt.Errorf("stereo: images are %dx%d and %dx%d", l.W, l.H, r.W, r.H)
+	}
+
+	var c volume
+	if s.cost == SAD {
+		c = s.sadCost(l, r)
+	} else {
+		c = s.censusCost(l, r)
+	}
+	agg := s.aggregate(c)
+	disp := s.selectDisparities(agg)
+	if s.speckleWindow > 0 {
+		filterSpeckles(disp, l.W, l.H, s.speckleWindow, s.speckleRange)
+	}
+	return tensor.FromArray(tensor.NewShape(l.H, l.W), disp), nil
+}
+
+// volume is a cost per pixel and disparity, indexed (y*w+x)*d + disparity.
+type volume struct {
+	w, h, d int
+	v       []uint16
+}
+
+func newVolume(w, h, d int) volume {
+	return volume{w: w, h: h, d: d, v: make([]uint16, w*h*d)}
+}
+
+// selectDisparities picks the disparity of least aggregated cost per pixel,
+// refines it with a parabola and applies the uniqueness and left-right
+// checks.
+func (s *SGBM) selectDisparities(agg volume) []float32 {
+	w, d := agg.w, agg.d
+	disp := make([]float32, w*agg.h)
+	concurrency.Parallel(agg.h, func(lo, hi int) {
+		right := make([]int, w)
+		rightCost := make([]uint16, w)
+		for y := lo; y < hi; y++ {
+			for x := range w {
+				right[x], rightCost[x] = -1, math.MaxUint16
+			}
+			for x := range w {
+				i := y*w + x
+				costs := agg.v[i*d : (i+1)*d]
+				best := 0
+				for k, v := range costs {
+					if v < costs[best] {
+						best = k
+					}
+					// the best disparity of the right pixel x - k runs along
+					// the diagonal of the volume
+					if xr := x - s.minDisparity - k; xr >= 0 && v < rightCost[xr] {
+						right[xr], rightCost[xr] = k, v
+					}
+				}
+				disp[i] = Invalid
+				if x-s.minDisparity-best < 0 || !s.unique(costs, best) {
+					continue
+				}
+				f := float32(best)
+				if best > 0 && best < d-1 {
+					a, b, c := float32(costs[best-1]), float32(costs[best]), float32(costs[best+1])
+					if den := a + c - 2*b; den > 0 {
+						f += (a - c) / (2 * den)
+					}
+				}
+				disp[i] = float32(s.minDisparity) + f
+			}
+			if s.disp12MaxDiff < 0 {
+				continue
+			}
+			for x := range w {
+				i := y*w + x
+				if disp[i] == Invalid {
+					continue
+				}
+				// the match may fall between two right pixels, either will do
+				k := float64(disp[i]) - float64(s.minDisparity)
+				consistent := false
+				for _, kk := range [2]int{int(math.Floor(k)), int(math.Ceil(k))} {
+					xr := x - s.minDisparity - kk
+					if xr >= 0 && right[xr] >= 0 && abs(kk-right[xr]) <= s.disp12MaxDiff {
+						consistent = true
+					}
+				}
+				if !consistent {
+					disp[i] = Invalid
+				}
+			}
+		}
+	})
+	return disp
+}
+
+// unique reports whether the best cost beats all disparities but its
+// neighbours by the uniqueness margin.
+func (s *SGBM) unique(costs []uint16, best int) bool {
+	if s.uniqueness <= 0 {
+		return true
+	}
+	limit := int(costs[best]) * 100
+	for k, v := range costs {
+		if (k < best-1 || k > best+1) && int(v)*(100-s.uniqueness) < limit {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package stereo
+
+import (
+	"image"
+	"image/draw"
+	_ "image/png"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+)
+
+func loadGray(t *testing.T, path string) types.Tensor {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := image.NewGray(img.Bounds())
+	draw.Draw(g, g.Rect, img, img.Bounds().Min, draw.Src)
+	out := tensor.New(types.UINT8, tensor.NewShape(g.Rect.Dy(), g.Rect.Dx()))
+	copy(out.Data().([]uint8), g.Pix)
+	return out
+}
+
+// texture returns smooth value noise with features of a few pixels.
+func texture(seed int64) func(x, y float64) float64 {
+	r := rand.New(rand.NewSource(seed))
+	const n = 256
+	grid := make([]float64, n*n)
+	for i := range grid {
+		grid[i] = r.Float64() * 255
+	}
+	at := func(i, j int) float64 { return grid[(j&(n-1))*n+i&(n-1)] }
+	return func(x, y float64) float64 {
+		x, y = x/2.5+64, y/2.5+64
+		i, j := int(math.Floor(x)), int(math.Floor(y))
+		fx, fy := x-float64(i), y-float64(j)
+		top := at(i, j) + (at(i+1, j)-at(i, j))*fx
+		bottom := at(i, j+1) + (at(i+1, j+1)-at(i, j+1))*fx
+		return top + (bottom-top)*fy
+	}
+}
+
+// scene renders a textured background at disparity 8 behind a square at
+// disparity 20.
+func scene(w, h int) (left, right types.Tensor, truth []float32) {
+	bg, fg := texture(1), texture(2)
+	inside := func(x, y float64) bool { return x >= 60 && x < 120 && y >= 30 && y < 90 }
+	left, right = tensor.New(types.UINT8, tensor.NewShape(h, w)), tensor.New(types.UINT8, tensor.NewShape(h, w))
+	l, r := left.Data().([]uint8), right.Data().([]uint8)
+	truth = make([]float32, w*h)
+	for y := range h {
+		for x := range w {
+			fx, fy := float64(x), float64(y)
+			i := y*w + x
+			l[i], truth[i] = uint8(bg(fx, fy)), 8
+			if inside(fx, fy) {
+				l[i], truth[i] = uint8(fg(fx, fy)), 20
+			}
+			// the right camera sees the square 20 pixels and the
+			// background 8 pixels to the left
+			r[i] = uint8(bg(fx+8, fy))
+			if inside(fx+20, fy) {
+				r[i] = uint8(fg(fx+20, fy))
+			}
+		}
+	}
+	return left, right, truth
+}
+
+func TestSGBM(t *testing.T) {
+	const w, h = 180, 120
+	left, right, truth := scene(w, h)
+	for _, cost := range []Cost{Census, SAD} {
+		disp, err := NewSGBM(WithDisparities(0, 32), WithCost(cost)).Compute(left, right)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := disp.Shape(); s[0] != h || s[1] != w {
+			t.Fatalf("%v: shape %v", cost, s)
+		}
+		d := disp.Data().([]float32)
+		var good, valid, visible, occludedValid int
+		for y := range h {
+			for x := 32; x < w; x++ {
+				i := y*w + x
+				// background left of the square is hidden from the right camera
+				occluded := truth[i] == 8 && y >= 30 && y < 90 && x >= 48 && x < 60
+				if occluded {
+					if d[i] != Invalid {
+						occludedValid++
+					}
+					continue
+				}
+				visible++
+				if d[i] == Invalid {
+					continue
+				}
+				valid++
+				if math.Abs(float64(d[i]-truth[i])) < 0.5 {
+					good++
+				}
+			}
+		}
+		if float64(valid) < 0.9*float64(visible) || float64(good) < 0.99*float64(valid) {
+			t.Errorf("%v: %d of %d visible pixels valid, %d correct", cost, valid, visible, good)
+		}
+		if occludedValid > 12*60/4 {
+			t.Errorf("%v: %d occluded pixels kept", cost, occludedValid)
+		}
+	}
+
+	if _, err := NewSGBM().Compute(left, tensor.New(types.UINT8, tensor.NewShape(h, w-1))); err == nil {
+		t.Error("images of different size accepted")
+	}
+}
+
+// TestKITTI matches the first pair of KITTI odometry sequence 00 at half
+// resolution and checks the road against the ground plane 1.65 m below the
+// camera.
+func TestKITTI(t *testing.T) {
+	half := func(path string) types.Tensor {
+		img, err := imgproc.Resize(loadGray(t, path), imgproc.HWC, 620, 188, imgproc.Area)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img
+	}
+	left := half("../../marshaller/testdata/img1/000000.png")
+	right := half("../../marshaller/testdata/img2/000000.png")
+	disp, err := NewSGBM(WithDisparities(0, 48)).Compute(left, right)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// calibration of sequence 00 at half resolution
+	const f, cx, cy, baseline, height = 718.856 / 2, 607.1928/2 - 0.25, 185.2157/2 - 0.25, 0.5372, 1.65
+	q := mat.Matrix4x4{{1, 0, 0, -cx}, {0, 1, 0, -cy}, {0, 0, 0, f}, {0, 0, 1 / baseline, 0}}
+	points, err := ReprojectImageTo3D(disp, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := disp.Data().([]float32)
+	p := points.Data().([]float32)
+	var errs, heights []float64
+	total := 0
+	for y := 150; y < 185; y++ {
+		for x := 200; x < 320; x++ {
+			total++
+			i := y*620 + x
+			if d[i] == Invalid {
+				continue
+			}
+			want := baseline * (float64(y) - cy) / height
+			errs = append(errs, math.Abs(float64(d[i])-want)/want)
+			heights = append(heights, float64(p[3*i+1]))
+		}
+	}
+	if len(errs) < total*3/4 {
+		t.Fatalf("%d of %d road pixels matched", len(errs), total)
+	}
+	slices.Sort(errs)
+	slices.Sort(heights)
+	if e := errs[len(errs)/2]; e > 0.05 {
+		t.Errorf("median road disparity error %.1f%%", 100*e)
+	}
+	if y := heights[len(heights)/2]; math.Abs(y-height) > 0.15 {
+		t.Errorf("road %v m below the camera, want %v", y, height)
+	}
+
+	cloud, err := Points(points, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := cloud.Shape()[0]; n < 620*188/3 {
+		t.Errorf("%d points", n)
+	}
+}
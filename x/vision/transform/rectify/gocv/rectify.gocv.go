@@ -0,0 +1,128 @@
+// Package gocv is the OpenCV backend of the rectify steps. It shares the
+// options and remap tables of package rectify and remaps Mats with
+// cv::remap.
+package gocv
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/itohio/EasyRobot/x/backend"
+	"github.com/itohio/EasyRobot/x/options"
+	"github.com/itohio/EasyRobot/x/pipeline"
+	"github.com/itohio/EasyRobot/x/pipeline/steps"
+	"github.com/itohio/EasyRobot/x/store"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+	"github.com/itohio/EasyRobot/x/vision/transform/rectify"
+
+	cv "gocv.io/x/gocv"
+)
+
+const (
+	UNDISTORT_NAME = "undistort_mat"
+	RECTIFY_NAME   = "rectify_mat"
+)
+
+func init() {
+	pipeline.Register(UNDISTORT_NAME, NewUndistort)
+	pipeline.Register(RECTIFY_NAME, NewRectify)
+}
+
+// maps is a remap table as a pair of CV_32FC1 Mats.
+type maps struct {
+	x, y cv.Mat
+}
+
+func newMaps(m *imgproc.Map) maps {
+	out := maps{
+		x: cv.NewMatWithSize(m.Height, m.Width, cv.MatTypeCV32F),
+		y: cv.NewMatWithSize(m.Height, m.Width, cv.MatTypeCV32F),
+	}
+	for row := range m.Height {
+		for col := range m.Width {
+			i := row*m.Width + col
+			out.x.SetFloatAt(row, col, m.X[i])
+			out.y.SetFloatAt(row, col, m.Y[i])
+		}
+	}
+	return out
+}
+
+func (m maps) Close() {
+	m.x.Close()
+	m.y.Close()
+}
+
+// NewUndistort returns a step that removes the lens distortion of Mats, with
+// the options of package rectify.
+func NewUndistort(opts ...options.Option) (pipeline.Step, error) {
+	u, err := rectify.NewUndistortion(opts...)
+	if err != nil {
+		return nil, err
+	}
+	m := newMaps(u.Map)
+	newOpts := opts
+	newOpts = append(newOpts,
+		steps.WithNamedProcessorFunc(UNDISTORT_NAME, func(src, dst store.Store) error {
+			return remap(src, dst, u.Src, u.Dst, m)
+		}),
+		steps.WithCloseFunc(m.Close),
+	)
+	return steps.NewProcessor(newOpts...)
+}
+
+// NewRectify returns a step that rectifies the Mats of a stereo pair, with
+// the options of package rectify.
+func NewRectify(opts ...options.Option) (pipeline.Step, error) {
+	r, err := rectify.NewRectification(opts...)
+	if err != nil {
+		return nil, err
+	}
+	left, right := newMaps(r.LeftMap), newMaps(r.RightMap)
+	newOpts := opts
+	newOpts = append(newOpts,
+		steps.WithNamedProcessorFunc(RECTIFY_NAME, func(src, dst store.Store) error {
+			if err := remap(src, dst, r.Left, r.Left, left); err != nil {
+				return err
+			}
+			return remap(src, dst, r.Right, r.Right, right)
+		}),
+		steps.WithCloseFunc(func() {
+			left.Close()
+			right.Close()
+		}),
+	)
+	return steps.NewProcessor(newOpts...)
+}
+
+func remap(src, dst store.Store, srcKey, dstKey store.FQDNType, m maps) error {
+	val, ok := src.Get(srcKey)
+	if !ok {
+		return nil
+	}
+
+	var mat cv.Mat
+	switch v := val.(type) {
+	case *cv.Mat:
+		mat = *v
+	case cv.Mat:
+		mat = v
+	default:
+		return nil
+	}
+	if mat.Empty() {
+		return nil
+	}
+	if mat.Cols() != m.x.Cols() || mat.Rows() != m.x.Rows() {
+		return fmt.Errorf("rectify: image is %dx%d, calibrated for %dx%d", mat.Cols(), mat.Rows(), m.x.Cols(), m.x.Rows())
+	}
+
+	result := backend.NewGoCVMat()
+	if err := cv.Remap(mat, result, &m.x, &m.y, cv.InterpolationLinear, cv.BorderConstant, color.RGBA{}); err != nil {
+		return err
+	}
+
+	dst.Set(dstKey, result)
+
+	return nil
+}
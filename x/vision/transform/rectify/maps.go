@@ -0,0 +1,107 @@
+// Package rectify provides pipeline steps that undistort camera frames and
+// rectify stereo pairs with the calibration written by cmd/calib_mono and
+// cmd/calib_stereo. The remap tables are computed once when a step is
+// created; every frame is then resampled bilinearly. The steps of this
+// package work on UINT8 and FP32 tensors in plain Go, package gocv holds the
+// OpenCV backend for Mats.
+package rectify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/options"
+	"github.com/itohio/EasyRobot/x/store"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+)
+
+// ErrNoCalibration is returned when a step is created without a calibration.
+var ErrNoCalibration = errors.New("rectify: no calibration")
+
+// Undistortion is the remap table of a camera and the image keys it applies to.
+type Undistortion struct {
+	Src, Dst store.FQDNType
+	Layout   imgproc.Layout
+	Camera   *calib.Camera
+	Map      *imgproc.Map
+}
+
+// NewUndistortion loads the calibration given by the options and computes
+// its remap table.
+func NewUndistortion(opts ...options.Option) (*Undistortion, error) {
+	o := newOptions(opts)
+	cam := o.camera
+	if cam == nil && o.path != "" {
+		var f calib.MonoFile
+		if err := calib.Load(o.path, &f); err != nil {
+			return nil, err
+		}
+		var err error
+		if cam, err = f.Camera(); err != nil {
+			return nil, err
+		}
+	}
+	if cam == nil {
+		return nil, ErrNoCalibration
+	}
+	if cam.Width <= 0 || cam.Height <= 0 {
+		return nil, fmt.Errorf("rectify: invalid image size %dx%d", cam.Width, cam.Height)
+	}
+	return &Undistortion{Src: o.src, Dst: o.dst, Layout: o.layout, Camera: cam, Map: cam.UndistortMap()}, nil
+}
+
+// Rectification holds the remap tables of a stereo pair and the keys of its
+// images. Q of the calibration rectification turns disparities into depth.
+type Rectification struct {
+	Left, Right       store.FQDNType
+	Layout            imgproc.Layout
+	Stereo            *calib.Stereo
+	Rectification     *calib.Rectification
+	LeftMap, RightMap *imgproc.Map
+}
+
+// NewRectification loads the stereo calibration given by the options and
+// computes the remap tables of both cameras. A rectification stored in the
+// calibration file is used as is.
+func NewRectification(opts ...options.Option) (*Rectification, error) {
+	o := newOptions(opts)
+	s := o.stereo
+	var rect *calib.Rectification
+	if s == nil && o.path != "" {
+		var f calib.StereoFile
+		if err := calib.Load(o.path, &f); err != nil {
+			return nil, err
+		}
+		var err error
+		if s, err = f.Stereo(); err != nil {
+			return nil, err
+		}
+		if rect, err = f.Rectification(); err != nil {
+			return nil, err
+		}
+	}
+	if s == nil {
+		return nil, ErrNoCalibration
+	}
+	if s.Left.Width <= 0 || s.Left.Height <= 0 {
+		return nil, fmt.Errorf("rectify: invalid image size %dx%d", s.Left.Width, s.Left.Height)
+	}
+	if rect == nil {
+		rect = s.Rectify()
+	}
+	r := &Rectification{Left: o.left, Right: o.right, Layout: o.layout, Stereo: s, Rectification: rect}
+	r.LeftMap, r.RightMap = rect.Maps(s)
+	return r, nil
+}
+
+func newOptions(opts []options.Option) Options {
+	o := Options{
+		src:   store.IMAGE,
+		dst:   store.IMAGE,
+		left:  store.STEREO_LEFT,
+		right: store.STEREO_RIGHT,
+	}
+	options.ApplyOptions(&o, opts...)
+	return o
+}
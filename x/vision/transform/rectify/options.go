@@ -0,0 +1,77 @@
+package rectify
+
+import (
+	"github.com/itohio/EasyRobot/x/options"
+	"github.com/itohio/EasyRobot/x/store"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+)
+
+type Options struct {
+	src    store.FQDNType
+	dst    store.FQDNType
+	left   store.FQDNType
+	right  store.FQDNType
+	path   string
+	camera *calib.Camera
+	stereo *calib.Stereo
+	layout imgproc.Layout
+}
+
+// WithKey sets the image keys of the undistort step.
+func WithKey(src, dst store.FQDNType) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.src = src
+			opt.dst = dst
+		}
+	}
+}
+
+// WithStereoKey sets the keys of the left and right images of the rectify
+// step. The images are replaced by their rectified versions.
+func WithStereoKey(left, right store.FQDNType) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.left = left
+			opt.right = right
+		}
+	}
+}
+
+// WithCalibration loads the calibration from a file written by
+// cmd/calib_mono (undistort) or cmd/calib_stereo (rectify).
+func WithCalibration(path string) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.path = path
+		}
+	}
+}
+
+// WithCamera undistorts with a calibrated camera instead of a file.
+func WithCamera(c *calib.Camera) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.camera = c
+		}
+	}
+}
+
+// WithStereo rectifies with a calibrated stereo pair instead of a file.
+func WithStereo(s *calib.Stereo) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.stereo = s
+		}
+	}
+}
+
+// WithLayout sets the channel layout of 3-D image tensors, HWC by default.
+func WithLayout(layout imgproc.Layout) options.Option {
+	return func(o interface{}) {
+		if opt, ok := o.(*Options); ok {
+			opt.layout = layout
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package rectify
+
+import (
+	"fmt"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/options"
+	"github.com/itohio/EasyRobot/x/pipeline"
+	"github.com/itohio/EasyRobot/x/pipeline/steps"
+	"github.com/itohio/EasyRobot/x/store"
+	"github.com/itohio/EasyRobot/x/vision/imgproc"
+)
+
+const (
+	UNDISTORT_NAME = "undistort"
+	RECTIFY_NAME   = "rectify"
+)
+
+func init() {
+	pipeline.Register(UNDISTORT_NAME, NewUndistort)
+	pipeline.Register(RECTIFY_NAME, NewRectify)
+}
+
+// NewUndistort returns a step that removes the lens distortion of tensor
+// images.
+func NewUndistort(opts ...options.Option) (pipeline.Step, error) {
+	u, err := NewUndistortion(opts...)
+	if err != nil {
+		return nil, err
+	}
+	newOpts := opts
+	newOpts = append(newOpts, steps.WithNamedProcessorFunc(UNDISTORT_NAME, undistort(u)))
+	return steps.NewProcessor(newOpts...)
+}
+
+// NewRectify returns a step that rectifies the tensor images of a stereo
+// pair.
+func NewRectify(opts ...options.Option) (pipeline.Step, error) {
+	r, err := NewRectification(opts...)
+	if err != nil {
+		return nil, err
+	}
+	newOpts := opts
+	newOpts = append(newOpts, steps.WithNamedProcessorFunc(RECTIFY_NAME, rectify(r)))
+	return steps.NewProcessor(newOpts...)
+}
+
+func undistort(u *Undistortion) func(src, dst store.Store) error {
+	return func(src, dst store.Store) error {
+		return remap(src, dst, u.Src, u.Dst, u.Layout, u.Map)
+	}
+}
+
+func rectify(r *Rectification) func(src, dst store.Store) error {
+	return func(src, dst store.Store) error {
+		if err := remap(src, dst, r.Left, r.Left, r.Layout, r.LeftMap); err != nil {
+			return err
+		}
+		return remap(src, dst, r.Right, r.Right, r.Layout, r.RightMap)
+	}
+}
+
+func remap(src, dst store.Store, srcKey, dstKey store.FQDNType, layout imgproc.Layout, m *imgproc.Map) error {
+	val, ok := src.Get(srcKey)
+	if !ok {
+		return nil
+	}
+
+	img, ok := val.(types.Tensor)
+	if !ok {
+		return nil
+	}
+
+	if w, h := size(img, layout); w != m.Width || h != m.Height {
+		return fmt.Errorf("rectify: image is %dx%d, calibrated for %dx%d", w, h, m.Width, m.Height)
+	}
+	out, err := imgproc.Remap(img, layout, m)
+	if err != nil {
+		return err
+	}
+
+	dst.Set(dstKey, out)
+
+	return nil
+}
+
+// size returns the width and height of an [H, W] or 3-D image tensor.
+func size(t types.Tensor, layout imgproc.Layout) (w, h int) {
+	shape := t.Shape()
+	switch {
+	case len(shape) == 2:
+		return shape[1], shape[0]
+	case len(shape) == 3 && layout == imgproc.CHW:
+		return shape[2], shape[1]
+	case len(shape) == 3:
+		return shape[1], shape[0]
+	}
+	return 0, 0
+}
@@ -0,0 +1,173 @@
+package rectify
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/store"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+)
+
+// pair is a stereo rig with barrel distortion, a slightly rotated right
+// camera and a 6 cm baseline.
+func pair() *calib.Stereo {
+	return &calib.Stereo{
+		Left:  calib.Camera{Width: 320, Height: 240, Fx: 260, Fy: 262, Cx: 163, Cy: 118, Distortion: []float64{-0.2, 0.05, 0.001, 0, 0}},
+		Right: calib.Camera{Width: 320, Height: 240, Fx: 265, Fy: 264, Cx: 158, Cy: 123, Distortion: []float64{-0.18, 0.04, 0, 0.001, 0}},
+		R:     calib.Rodrigues(vec.Vector3D{0.02, -0.03, 0.015}),
+		T:     vec.Vector3D{-0.06, 0.002, 0.001},
+	}
+}
+
+// scene is a grid of points in front of the left camera.
+func scene() []vec.Vector3D {
+	var pts []vec.Vector3D
+	for y := -2; y <= 2; y++ {
+		for x := -3; x <= 3; x++ {
+			z := 1 + 0.1*float32(x+y+5)
+			pts = append(pts, vec.Vector3D{0.13 * float32(x) * z, 0.13 * float32(y) * z, z})
+		}
+	}
+	return pts
+}
+
+// spots renders a Gaussian spot at every point.
+func spots(w, h int, at []vec.Vector2D) types.Tensor {
+	img := tensor.New(types.UINT8, tensor.NewShape(h, w))
+	data := img.Data().([]uint8)
+	for y := range h {
+		for x := range w {
+			var v float64
+			for _, p := range at {
+				dx, dy := float64(x)-float64(p[0]), float64(y)-float64(p[1])
+				v += 250 * math.Exp(-(dx*dx+dy*dy)/(2*1.5*1.5))
+			}
+			data[y*w+x] = uint8(math.Min(255, math.Round(v)))
+		}
+	}
+	return img
+}
+
+// centroid returns the weighted centre of the spot near p.
+func centroid(img types.Tensor, p vec.Vector2D) vec.Vector2D {
+	w := img.Shape()[1]
+	data := img.Data().([]uint8)
+	var sx, sy, sum float64
+	x0, y0 := int(math.Round(float64(p[0]))), int(math.Round(float64(p[1])))
+	for y := y0 - 5; y <= y0+5; y++ {
+		for x := x0 - 5; x <= x0+5; x++ {
+			v := float64(data[y*w+x])
+			sx += v * float64(x)
+			sy += v * float64(y)
+			sum += v
+		}
+	}
+	return vec.Vector2D{float32(sx / sum), float32(sy / sum)}
+}
+
+// apply projects a point in camera coordinates with R and P.
+func apply(r mat.Matrix3x3, p mat.Matrix3x4, x vec.Vector3D) vec.Vector2D {
+	var q [3]float32
+	for i := range 3 {
+		q[i] = r[i][0]*x[0] + r[i][1]*x[1] + r[i][2]*x[2]
+	}
+	return vec.Vector2D{p[0][0]*q[0]/q[2] + p[0][2], p[1][1]*q[1]/q[2] + p[1][2]}
+}
+
+func TestRectify(t *testing.T) {
+	s := pair()
+	pts := scene()
+	var left, right []vec.Vector2D
+	for _, p := range pts {
+		left = append(left, s.Left.Project(p))
+		var q vec.Vector3D
+		for i := range 3 {
+			q[i] = s.R[i][0]*p[0] + s.R[i][1]*p[1] + s.R[i][2]*p[2] + s.T[i]
+		}
+		right = append(right, s.Right.Project(q))
+	}
+
+	// through a calibration file, as cmd/calib_stereo writes it
+	path := filepath.Join(t.TempDir(), "stereo.yaml")
+	if err := calib.Save(path, s.File(9, 6)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRectification(WithCalibration(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := store.New()
+	in.Set(store.STEREO_LEFT, spots(320, 240, left))
+	in.Set(store.STEREO_RIGHT, spots(320, 240, right))
+	out := store.New()
+	if err := rectify(r)(in, out); err != nil {
+		t.Fatal(err)
+	}
+	lv, _ := out.Get(store.STEREO_LEFT)
+	rv, _ := out.Get(store.STEREO_RIGHT)
+	lt, rt := lv.(types.Tensor), rv.(types.Tensor)
+
+	rect := r.Rectification
+	for i, p := range pts {
+		l := centroid(lt, apply(rect.R1, rect.P1, p))
+		var q vec.Vector3D
+		for k := range 3 {
+			q[k] = s.R[k][0]*p[0] + s.R[k][1]*p[1] + s.R[k][2]*p[2] + s.T[k]
+		}
+		rp := centroid(rt, apply(rect.R2, rect.P2, q))
+		// the epipolar lines are the image rows
+		if d := math.Abs(float64(l[1] - rp[1])); d > 0.2 {
+			t.Errorf("point %d: rows %v and %v", i, l[1], rp[1])
+		}
+		// and the disparity gives the depth of the point
+		z := -float64(rect.P2[0][3]) / float64(l[0]-rp[0])
+		if math.Abs(z-float64(p[2]))/float64(p[2]) > 0.03 {
+			t.Errorf("point %d: depth %v, want %v", i, z, p[2])
+		}
+	}
+}
+
+func TestUndistort(t *testing.T) {
+	cam := pair().Left
+	var ideal, seen []vec.Vector2D
+	for _, p := range scene() {
+		ideal = append(ideal, vec.Vector2D{float32(cam.Fx)*p[0]/p[2] + float32(cam.Cx), float32(cam.Fy)*p[1]/p[2] + float32(cam.Cy)})
+		seen = append(seen, cam.Project(p))
+	}
+	if _, err := NewUndistort(WithCamera(&cam)); err != nil {
+		t.Fatal(err)
+	}
+	u, err := NewUndistortion(WithCamera(&cam), WithKey(store.IMAGE, store.IMAGE_TRANSFORMED))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, out := store.New(), store.New()
+	in.Set(store.IMAGE, spots(320, 240, seen))
+	if err := undistort(u)(in, out); err != nil {
+		t.Fatal(err)
+	}
+	v, ok := out.Get(store.IMAGE_TRANSFORMED)
+	if !ok {
+		t.Fatal("no undistorted image")
+	}
+	img := v.(types.Tensor)
+	for i, p := range ideal {
+		if got := centroid(img, p); got.Distance(p) > 0.2 {
+			t.Errorf("spot %d at %v, want %v", i, got, p)
+		}
+	}
+
+	in.Set(store.IMAGE, spots(160, 120, nil))
+	if err := undistort(u)(in, out); err == nil {
+		t.Error("image of the wrong size accepted")
+	}
+	if _, err := NewUndistort(); err != ErrNoCalibration {
+		t.Errorf("got %v, want ErrNoCalibration", err)
+	}
+}
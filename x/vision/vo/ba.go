@@ -0,0 +1,422 @@
+package vo
+
+import (
+	"math"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// pinhole is the ideal camera of undistorted observations. baseline is the
+// distance to the right camera of a rectified stereo pair, 0 for a single
+// camera.
+type pinhole struct {
+	fx, fy, cx, cy, baseline float64
+}
+
+// observation is a point seen by a frame: the undistorted pixel u, v and
+// for stereo the x of the right image, ur, or NaN.
+type observation struct {
+	frame, point int
+	u, v, ur     float64
+}
+
+// problem is a bundle adjustment problem over camera to world poses of
+// frames and world points. The normal equations are block sparse: a 6x6
+// block per frame, a 3x3 block per point and a 6x3 block per observation.
+// The points are eliminated with the Schur complement and the reduced
+// camera system, block sparse too, is solved with a block Cholesky
+// decomposition (see reduced).
+type problem struct {
+	cam    pinhole
+	frames []mat.Matrix4x4
+	// fixed frames are not optimised; at least one fixes the gauge.
+	fixed     []bool
+	points    []vec.Vector3D
+	fixPoints bool
+	obs       []observation
+	// huber is the residual in pixels beyond which the cost grows linearly.
+	huber float64
+}
+
+// residual writes the reprojection error of an observation to e and
+// returns the point in camera coordinates and the number of residuals, 0
+// when the point is behind the camera.
+func (c pinhole) residual(o observation, pose mat.Matrix4x4, p vec.Vector3D, e *[3]float64) ([3]float64, int) {
+	// xc = Rᵀ (p - t)
+	var d, xc [3]float64
+	for i := range 3 {
+		d[i] = float64(p[i]) - float64(pose[i][3])
+	}
+	for i := range 3 {
+		xc[i] = float64(pose[0][i])*d[0] + float64(pose[1][i])*d[1] + float64(pose[2][i])*d[2]
+	}
+	if xc[2] < 1e-6 {
+		return xc, 0
+	}
+	iz := 1 / xc[2]
+	e[0] = c.fx*xc[0]*iz + c.cx - o.u
+	e[1] = c.fy*xc[1]*iz + c.cy - o.v
+	if math.IsNaN(o.ur) {
+		return xc, 2
+	}
+	e[2] = c.fx*(xc[0]-c.baseline)*iz + c.cx - o.ur
+	return xc, 3
+}
+
+// jacobians returns the derivatives of the n residuals of an observation
+// with respect to the right perturbation of the pose and to the point.
+func (c pinhole) jacobians(pose mat.Matrix4x4, xc [3]float64, n int) (jf [3][6]float64, jp [3][3]float64) {
+	iz := 1 / xc[2]
+	d := [3][3]float64{
+		{c.fx * iz, 0, -c.fx * xc[0] * iz * iz},
+		{0, c.fy * iz, -c.fy * xc[1] * iz * iz},
+		{c.fx * iz, 0, -c.fx * (xc[0] - c.baseline) * iz * iz},
+	}
+	// xc moves by [xc]x phi - rho under the pose and by R^T under the point
+	skew := [3][3]float64{{0, -xc[2], xc[1]}, {xc[2], 0, -xc[0]}, {-xc[1], xc[0], 0}}
+	for k := range n {
+		for j := range 3 {
+			jf[k][j] = d[k][0]*skew[0][j] + d[k][1]*skew[1][j] + d[k][2]*skew[2][j]
+			jf[k][3+j] = -d[k][j]
+			jp[k][j] = d[k][0]*float64(pose[j][0]) + d[k][1]*float64(pose[j][1]) + d[k][2]*float64(pose[j][2])
+		}
+	}
+	return jf, jp
+}
+
+// robust returns the Huber cost of a squared residual norm and the weight
+// of the residual in the normal equations.
+func (p *problem) robust(sq float64) (cost, weight float64) {
+	if p.huber <= 0 || sq <= p.huber*p.huber {
+		return sq, 1
+	}
+	s := math.Sqrt(sq)
+	return 2*p.huber*s - p.huber*p.huber, p.huber / s
+}
+
+// behindCost is the cost of an observation of a point behind its camera.
+const behindCost = 1e6
+
+// cost returns the robust cost of a state.
+func (p *problem) cost(frames []mat.Matrix4x4, points []vec.Vector3D) float64 {
+	var sum float64
+	var e [3]float64
+	for _, o := range p.obs {
+		_, n := p.cam.residual(o, frames[o.frame], points[o.point], &e)
+		if n == 0 {
+			sum += behindCost
+			continue
+		}
+		var sq float64
+		for k := range n {
+			sq += e[k] * e[k]
+		}
+		c, _ := p.robust(sq)
+		sum += c
+	}
+	return sum
+}
+
+// errors returns the reprojection error norm of every observation, +Inf
+// behind the camera.
+func (p *problem) errors() []float64 {
+	out := make([]float64, len(p.obs))
+	var e [3]float64
+	for i, o := range p.obs {
+		_, n := p.cam.residual(o, p.frames[o.frame], p.points[o.point], &e)
+		if n == 0 {
+			out[i] = math.Inf(1)
+			continue
+		}
+		var sq float64
+		for k := range n {
+			sq += e[k] * e[k]
+		}
+		out[i] = math.Sqrt(sq)
+	}
+	return out
+}
+
+// system holds the normal equations of a linearisation.
+type system struct {
+	u  []block
+	g  [][6]float64
+	v  [][3][3]float64
+	gp [][3]float64
+	w  [][6][3]float64
+}
+
+// linearize accumulates the weighted normal equations at the current
+// state; frame and point map to the indices of the free ones, or -1.
+func (p *problem) linearize(frame, point []int, nf, np int) system {
+	s := system{
+		u:  make([]block, nf),
+		g:  make([][6]float64, nf),
+		v:  make([][3][3]float64, np),
+		gp: make([][3]float64, np),
+		w:  make([][6][3]float64, len(p.obs)),
+	}
+	var e [3]float64
+	for oi, o := range p.obs {
+		pose := p.frames[o.frame]
+		xc, n := p.cam.residual(o, pose, p.points[o.point], &e)
+		if n == 0 {
+			continue
+		}
+		var sq float64
+		for k := range n {
+			sq += e[k] * e[k]
+		}
+		_, w := p.robust(sq)
+		jf, jp := p.cam.jacobians(pose, xc, n)
+		fi, pj := frame[o.frame], point[o.point]
+		for k := range n {
+			if fi >= 0 {
+				for a := range 6 {
+					s.g[fi][a] += w * jf[k][a] * e[k]
+					for b := range 6 {
+						s.u[fi][a][b] += w * jf[k][a] * jf[k][b]
+					}
+				}
+			}
+			if pj >= 0 {
+				for a := range 3 {
+					s.gp[pj][a] += w * jp[k][a] * e[k]
+					for b := range 3 {
+						s.v[pj][a][b] += w * jp[k][a] * jp[k][b]
+					}
+				}
+			}
+			if fi >= 0 && pj >= 0 {
+				for a := range 6 {
+					for b := range 3 {
+						s.w[oi][a][b] += w * jf[k][a] * jp[k][b]
+					}
+				}
+			}
+		}
+	}
+	return s
+}
+
+// solve minimises the cost with Levenberg-Marquardt and returns it.
+func (p *problem) solve(iterations int) float64 {
+	frame := make([]int, len(p.frames))
+	nf := 0
+	for i := range p.frames {
+		frame[i] = -1
+		if !p.fixed[i] {
+			frame[i] = nf
+			nf++
+		}
+	}
+	point := make([]int, len(p.points))
+	np := 0
+	for j := range p.points {
+		point[j] = -1
+		if !p.fixPoints {
+			point[j] = np
+			np++
+		}
+	}
+	// observations of every free point
+	byPoint := make([][]int, np)
+	for oi, o := range p.obs {
+		if pj := point[o.point]; pj >= 0 {
+			byPoint[pj] = append(byPoint[pj], oi)
+		}
+	}
+
+	cost := p.cost(p.frames, p.points)
+	lambda := 1e-4
+	for range iterations {
+		s := p.linearize(frame, point, nf, np)
+		improved := false
+		for range 10 {
+			dc, dp, ok := s.step(p, frame, byPoint, nf, np, lambda)
+			if !ok {
+				lambda *= 10
+				continue
+			}
+			frames := make([]mat.Matrix4x4, len(p.frames))
+			copy(frames, p.frames)
+			for i, fi := range frame {
+				if fi >= 0 {
+					frames[i] = retract(frames[i], dc[6*fi:6*fi+6])
+				}
+			}
+			points := make([]vec.Vector3D, len(p.points))
+			copy(points, p.points)
+			for j, pj := range point {
+				if pj >= 0 {
+					d := vec.Vector3D{float32(dp[3*pj]), float32(dp[3*pj+1]), float32(dp[3*pj+2])}
+					points[j] = points[j].Add(d).(vec.Vector3D)
+				}
+			}
+			next := p.cost(frames, points)
+			if next < cost {
+				p.frames, p.points = frames, points
+				improved = cost-next > 1e-9*cost
+				cost = next
+				lambda = max(lambda/10, 1e-9)
+				break
+			}
+			lambda *= 10
+		}
+		if !improved {
+			break
+		}
+	}
+	return cost
+}
+
+// step solves the damped normal equations for the frame and point updates.
+func (s system) step(p *problem, frame []int, byPoint [][]int, nf, np int, lambda float64) (dc, dp []float64, ok bool) {
+	vinv := make([][3][3]float64, np)
+	for j := range np {
+		v := s.v[j]
+		for a := range 3 {
+			v[a][a] = v[a][a]*(1+lambda) + 1e-9
+		}
+		if vinv[j], ok = inverse3(v); !ok {
+			return nil, nil, false
+		}
+	}
+
+	rs := newReduced(nf)
+	for fi := range nf {
+		d := rs.at(fi, fi)
+		*d = s.u[fi]
+		for a := range 6 {
+			rs.rhs[6*fi+a] = -s.g[fi][a]
+			d[a][a] += lambda*s.u[fi][a][a] + 1e-9
+		}
+	}
+	// eliminate the points: S -= W V^-1 W^T, rhs += W V^-1 gp, into the
+	// blocks of the frame pairs that see the point
+	for j, list := range byPoint {
+		for _, oa := range list {
+			fa := frame[p.obs[oa].frame]
+			if fa < 0 {
+				continue
+			}
+			var y [6][3]float64 // W_a V^-1
+			for a := range 6 {
+				for b := range 3 {
+					for k := range 3 {
+						y[a][b] += s.w[oa][a][k] * vinv[j][k][b]
+					}
+				}
+				for k := range 3 {
+					rs.rhs[6*fa+a] += y[a][k] * s.gp[j][k]
+				}
+			}
+			for _, ob := range list {
+				fb := frame[p.obs[ob].frame]
+				if fb < 0 || fb > fa {
+					continue
+				}
+				blk := rs.at(fa, fb)
+				for a := range 6 {
+					for b := range 6 {
+						var sum float64
+						for k := range 3 {
+							sum += y[a][k] * s.w[ob][b][k]
+						}
+						blk[a][b] -= sum
+					}
+				}
+			}
+		}
+	}
+	if !rs.factor() {
+		return nil, nil, false
+	}
+	dc = rs.solve()
+	for _, x := range dc {
+		if math.IsNaN(x) {
+			return nil, nil, false
+		}
+	}
+
+	// back substitute: dp = V^-1 (-gp - W^T dc)
+	dp = make([]float64, 3*np)
+	for j, list := range byPoint {
+		r := [3]float64{-s.gp[j][0], -s.gp[j][1], -s.gp[j][2]}
+		for _, o := range list {
+			fi := frame[p.obs[o].frame]
+			if fi < 0 {
+				continue
+			}
+			for b := range 3 {
+				for a := range 6 {
+					r[b] -= s.w[o][a][b] * dc[6*fi+a]
+				}
+			}
+		}
+		for a := range 3 {
+			dp[3*j+a] = vinv[j][a][0]*r[0] + vinv[j][a][1]*r[1] + vinv[j][a][2]*r[2]
+		}
+	}
+	return dc, dp, true
+}
+
+// covariance returns the covariance of the right perturbation of a frame,
+// rotation vector then translation, with the points and the other frames
+// held fixed: sigma2 (JᵀJ)^-1 over its observations.
+func (p *problem) covariance(f int, sigma2 float64) mat.Matrix {
+	frame := make([]int, len(p.frames))
+	for i := range frame {
+		frame[i] = -1
+	}
+	frame[f] = 0
+	point := make([]int, len(p.points))
+	for j := range point {
+		point[j] = -1
+	}
+	s := p.linearize(frame, point, 1, 0)
+	// scale to a unit diagonal for the float32 inverse
+	var d [6]float64
+	h := mat.New(6, 6)
+	for a := range 6 {
+		d[a] = 1 / math.Sqrt(max(s.u[0][a][a], 1e-12))
+	}
+	for a := range 6 {
+		for b := range 6 {
+			h[a][b] = float32(s.u[0][a][b] * d[a] * d[b])
+		}
+	}
+	cov := mat.New(6, 6)
+	if err := h.Inverse(cov); err != nil {
+		for a := range 6 {
+			cov[a][a] = float32(math.Inf(1))
+		}
+		return cov
+	}
+	for a := range 6 {
+		for b := range 6 {
+			cov[a][b] = float32(float64(cov[a][b]) * d[a] * d[b] * sigma2)
+		}
+	}
+	return cov
+}
+
+// inverse3 inverts a 3x3 matrix.
+func inverse3(m [3][3]float64) ([3][3]float64, bool) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if det == 0 || math.IsNaN(det) {
+		return [3][3]float64{}, false
+	}
+	var out [3][3]float64
+	for i := range 3 {
+		for j := range 3 {
+			a, b := (j+1)%3, (j+2)%3
+			c, d := (i+1)%3, (i+2)%3
+			out[i][j] = (m[a][c]*m[b][d] - m[a][d]*m[b][c]) / det
+		}
+	}
+	return out, true
+}
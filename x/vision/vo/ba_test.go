@@ -0,0 +1,77 @@
+package vo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+)
+
+// scene returns frames moving forward, points in front of them and the
+// exact observations of the points by the frames.
+func scene(r *rand.Rand, cam pinhole) (frames []mat.Matrix4x4, points []vec.Vector3D, obs []observation) {
+	for i := range 5 {
+		f := float64(i)
+		rot := calib.Rodrigues(vec.Vector3D{float32(0.01 * f), float32(0.02 * f), 0})
+		frames = append(frames, homogeneous(rot, vec.Vector3D{float32(0.1 * f), 0, float32(0.3 * f)}))
+	}
+	for range 100 {
+		points = append(points, vec.Vector3D{float32(r.Float64()*8 - 4), float32(r.Float64()*6 - 3), float32(4 + r.Float64()*10)})
+	}
+	for f, pose := range frames {
+		for j, p := range points {
+			q := transform(invert(pose), p)
+			xc := [3]float64{float64(q[0]), float64(q[1]), float64(q[2])}
+			ob := observation{
+				frame: f,
+				point: j,
+				u:     cam.fx*xc[0]/xc[2] + cam.cx,
+				v:     cam.fy*xc[1]/xc[2] + cam.cy,
+				ur:    math.NaN(),
+			}
+			if cam.baseline > 0 {
+				ob.ur = cam.fx*(xc[0]-cam.baseline)/xc[2] + cam.cx
+			}
+			obs = append(obs, ob)
+		}
+	}
+	return frames, points, obs
+}
+
+func TestBundleAdjust(t *testing.T) {
+	for _, baseline := range []float64{0, 0.2} {
+		r := rand.New(rand.NewSource(1))
+		cam := pinhole{fx: 300, fy: 300, cx: 160, cy: 120, baseline: baseline}
+		frames, points, obs := scene(r, cam)
+		p := &problem{
+			cam:   cam,
+			fixed: []bool{true, baseline == 0, false, false, false},
+			obs:   obs,
+			huber: 2,
+		}
+		for i, f := range frames {
+			if !p.fixed[i] {
+				f = retract(f, []float64{0.01, -0.01, 0.02, 0.05, -0.03, 0.04})
+			}
+			p.frames = append(p.frames, f)
+		}
+		for _, q := range points {
+			noise := vec.Vector3D{float32(r.NormFloat64() * 0.1), float32(r.NormFloat64() * 0.1), float32(r.NormFloat64() * 0.3)}
+			p.points = append(p.points, q.Add(noise).(vec.Vector3D))
+		}
+		if c := p.solve(20); c > 1e-6 {
+			t.Errorf("baseline %v: cost %g", baseline, c)
+		}
+		for i, f := range p.frames {
+			if d := translation(f).Distance(translation(frames[i])); d > 1e-3 {
+				t.Errorf("baseline %v: frame %d off by %g", baseline, i, d)
+			}
+			if a := angle(rotation(compose(invert(f), frames[i]))); a > 1e-4 {
+				t.Errorf("baseline %v: frame %d rotated by %g", baseline, i, a)
+			}
+		}
+	}
+}
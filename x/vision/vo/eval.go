@@ -0,0 +1,287 @@
+package vo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+)
+
+// Trajectory is a sequence of camera to world poses.
+type Trajectory []mat.Matrix4x4
+
+// Stamped is a pose with a timestamp in seconds.
+type Stamped struct {
+	Time float64
+	Pose mat.Matrix4x4
+}
+
+// ReadKITTI reads poses in the format of the KITTI odometry benchmark: a
+// line per frame with the 12 values of the top 3x4 rows, row major.
+func ReadKITTI(r io.Reader) (Trajectory, error) {
+	var out Trajectory
+	err := scanLines(r, func(n int, fields []float64) error {
+		if len(fields) != 12 {
+			return fmt.Errorf("vo: line %d: %d values, want 12", n, len(fields))
+		}
+		var m mat.Matrix4x4
+		for i := range 3 {
+			for j := range 4 {
+				m[i][j] = float32(fields[i*4+j])
+			}
+		}
+		m[3][3] = 1
+		out = append(out, m)
+		return nil
+	})
+	return out, err
+}
+
+// WriteKITTI writes poses in the format of the KITTI odometry benchmark.
+func WriteKITTI(w io.Writer, t Trajectory) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range t {
+		for i := range 3 {
+			for j := range 4 {
+				if i > 0 || j > 0 {
+					bw.WriteByte(' ')
+				}
+				bw.WriteString(strconv.FormatFloat(float64(m[i][j]), 'e', 9, 32))
+			}
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// ReadTUM reads poses in the format of the TUM RGB-D benchmark: a line per
+// pose with the timestamp, the position and the orientation quaternion,
+// "t tx ty tz qx qy qz qw". Lines starting with # are comments.
+func ReadTUM(r io.Reader) ([]Stamped, error) {
+	var out []Stamped
+	err := scanLines(r, func(n int, fields []float64) error {
+		if len(fields) != 8 {
+			return fmt.Errorf("vo: line %d: %d values, want 8", n, len(fields))
+		}
+		x, y, z, w := fields[4], fields[5], fields[6], fields[7]
+		q := math.Sqrt(x*x + y*y + z*z + w*w)
+		if q == 0 {
+			return fmt.Errorf("vo: line %d: zero quaternion", n)
+		}
+		r := mat.Matrix3x3{}.Orientation(vec.Quaternion{float32(x / q), float32(y / q), float32(z / q), float32(w / q)})
+		pose := homogeneous(r.(mat.Matrix3x3), vec.Vector3D{float32(fields[1]), float32(fields[2]), float32(fields[3])})
+		out = append(out, Stamped{Time: fields[0], Pose: pose})
+		return nil
+	})
+	return out, err
+}
+
+func scanLines(r io.Reader, fn func(n int, fields []float64) error) error {
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		words := strings.Fields(line)
+		fields := make([]float64, len(words))
+		for i, w := range words {
+			v, err := strconv.ParseFloat(w, 64)
+			if err != nil {
+				return fmt.Errorf("vo: line %d: %w", n, err)
+			}
+			fields[i] = v
+		}
+		if err := fn(n, fields); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// Associate pairs the poses of two timestamped trajectories whose times
+// differ by at most maxDiff seconds, nearest first, each pose at most
+// once, and returns the pairs in time order. gt must be sorted by time.
+func Associate(est, gt []Stamped, maxDiff float64) (Trajectory, Trajectory) {
+	type pair struct {
+		i, j int
+		d    float64
+	}
+	var pairs []pair
+	for i, e := range est {
+		// gt is searched around the insertion point of the timestamp
+		k := sort.Search(len(gt), func(k int) bool { return gt[k].Time >= e.Time-maxDiff })
+		for j := k; j < len(gt) && gt[j].Time <= e.Time+maxDiff; j++ {
+			pairs = append(pairs, pair{i, j, math.Abs(gt[j].Time - e.Time)})
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].d < pairs[b].d })
+	usedE, usedG := make([]bool, len(est)), make([]bool, len(gt))
+	var kept []pair
+	for _, p := range pairs {
+		if !usedE[p.i] && !usedG[p.j] {
+			usedE[p.i], usedG[p.j] = true, true
+			kept = append(kept, p)
+		}
+	}
+	sort.Slice(kept, func(a, b int) bool { return est[kept[a].i].Time < est[kept[b].i].Time })
+	e, g := make(Trajectory, len(kept)), make(Trajectory, len(kept))
+	for k, p := range kept {
+		e[k], g[k] = est[p.i].Pose, gt[p.j].Pose
+	}
+	return e, g
+}
+
+// Align returns the rigid transform, and with scale the similarity, that
+// best maps the positions of est onto those of gt in the least squares
+// sense (Umeyama): gt ≈ s R est + t. The scale is 1 without scale.
+func Align(est, gt Trajectory, scale bool) (mat.Matrix4x4, float64, error) {
+	n := len(est)
+	if n != len(gt) {
+		return mat.Matrix4x4{}, 0, fmt.Errorf("vo: %d estimated and %d ground truth poses", n, len(gt))
+	}
+	if n < 2 {
+		return mat.Matrix4x4{}, 0, fmt.Errorf("vo: at least 2 poses are needed to align")
+	}
+	var me, mg vec.Vector3D
+	for i := range n {
+		me = me.Add(translation(est[i])).(vec.Vector3D)
+		mg = mg.Add(translation(gt[i])).(vec.Vector3D)
+	}
+	me, mg = me.DivC(float32(n)).(vec.Vector3D), mg.DivC(float32(n)).(vec.Vector3D)
+	cov := mat.New(3, 3)
+	var ve float64
+	for i := range n {
+		e := translation(est[i]).Sub(me).(vec.Vector3D)
+		g := translation(gt[i]).Sub(mg).(vec.Vector3D)
+		ve += float64(e.Dot(e))
+		for r := range 3 {
+			for c := range 3 {
+				cov[r][c] += g[r] * e[c]
+			}
+		}
+	}
+	var res mat.SVDResult
+	if err := cov.SVD(&res); err != nil {
+		return mat.Matrix4x4{}, 0, fmt.Errorf("vo: alignment failed: %w", err)
+	}
+	d := res.S.View().(vec.Vector)
+	u, vt := res.U.View().(mat.Matrix), res.Vt.View().(mat.Matrix)
+	// a reflection is undone on the least singular value
+	sign := vec.Vector{1, 1, 1}
+	if u.Det()*vt.Det() < 0 {
+		least := 0
+		for k := 1; k < 3; k++ {
+			if d[k] < d[least] {
+				least = k
+			}
+		}
+		sign[least] = -1
+	}
+	us, rm := mat.New(3, 3), mat.New(3, 3)
+	us.MulDiag(u, sign)
+	rm.Mul(us, vt)
+	var r mat.Matrix3x3
+	for i := range 3 {
+		copy(r[i][:], rm[i])
+	}
+	s := 1.0
+	if scale {
+		if ve == 0 {
+			return mat.Matrix4x4{}, 0, fmt.Errorf("vo: estimated trajectory does not move")
+		}
+		s = float64(d.Dot(sign)) / ve
+	}
+	t := mg.Sub(r.MulVec(me, nil).(vec.Vector3D).MulC(float32(s))).(vec.Vector3D)
+	return homogeneous(r, t), s, nil
+}
+
+// Metrics are the errors of an estimated trajectory.
+type Metrics struct {
+	// ATE is the root mean square of the absolute position errors after
+	// alignment.
+	ATE float64
+	// RPETranslation and RPERotation are the root mean squares of the
+	// relative pose errors over the delta of frames, in the units of the
+	// ground truth and in radians.
+	RPETranslation float64
+	RPERotation    float64
+	// Scale is the scale of the alignment, 1 unless aligned with scale.
+	Scale float64
+	// Poses is the number of poses compared.
+	Poses int
+}
+
+type evalConfig struct {
+	scale bool
+	delta int
+}
+
+// EvalOption configures Evaluate.
+type EvalOption func(*evalConfig)
+
+// WithScale aligns with a similarity, for monocular odometry whose scale is
+// arbitrary.
+func WithScale() EvalOption {
+	return func(c *evalConfig) {
+		c.scale = true
+	}
+}
+
+// WithDelta sets the frames between the poses of relative errors (default:
+// 1).
+func WithDelta(n int) EvalOption {
+	return func(c *evalConfig) {
+		if n < 1 {
+			panic("vo: delta must be positive")
+		}
+		c.delta = n
+	}
+}
+
+// Evaluate compares an estimated trajectory with ground truth of the same
+// frames: the absolute trajectory error after alignment and the relative
+// pose error, which does not accumulate drift.
+func Evaluate(est, gt Trajectory, opts ...EvalOption) (Metrics, error) {
+	cfg := evalConfig{delta: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	a, s, err := Align(est, gt, cfg.scale)
+	if err != nil {
+		return Metrics{}, err
+	}
+	m := Metrics{Scale: s, Poses: len(est)}
+	r, t := rotation(a), translation(a)
+	for i := range est {
+		p := r.MulVec(translation(est[i]), nil).(vec.Vector3D).MulC(float32(s)).(vec.Vector3D).Add(t).(vec.Vector3D)
+		e := float64(p.Distance(translation(gt[i])))
+		m.ATE += e * e
+	}
+	m.ATE = math.Sqrt(m.ATE / float64(len(est)))
+
+	n := 0
+	for i := 0; i+cfg.delta < len(est); i++ {
+		j := i + cfg.delta
+		de := compose(invert(est[i]), est[j])
+		de.SetTranslation(translation(de).MulC(float32(s)).(vec.Vector3D))
+		dg := compose(invert(gt[i]), gt[j])
+		e := compose(invert(dg), de)
+		dt := translation(e)
+		m.RPETranslation += float64(dt.Dot(dt))
+		a := angle(rotation(e))
+		m.RPERotation += a * a
+		n++
+	}
+	if n > 0 {
+		m.RPETranslation = math.Sqrt(m.RPETranslation / float64(n))
+		m.RPERotation = math.Sqrt(m.RPERotation / float64(n))
+	}
+	return m, nil
+}
@@ -0,0 +1,116 @@
+package vo
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+)
+
+func TestKITTIFormat(t *testing.T) {
+	var traj Trajectory
+	for i := range 5 {
+		traj = append(traj, path(i))
+	}
+	var buf bytes.Buffer
+	if err := WriteKITTI(&buf, traj); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadKITTI(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != len(traj) {
+		t.Fatalf("%d poses, want %d", len(back), len(traj))
+	}
+	for i := range traj {
+		for r := range 4 {
+			for c := range 4 {
+				if math.Abs(float64(back[i][r][c]-traj[i][r][c])) > 1e-6 {
+					t.Fatalf("pose %d: %v, want %v", i, back[i], traj[i])
+				}
+			}
+		}
+	}
+	if _, err := ReadKITTI(strings.NewReader("1 0 0 0 0 1 0 0 0 0 1\n")); err == nil {
+		t.Error("short line accepted")
+	}
+}
+
+func TestTUMFormat(t *testing.T) {
+	// a quarter turn about z at (1, 2, 3)
+	in := "# timestamp tx ty tz qx qy qz qw\n1.5 1 2 3 0 0 " +
+		"0.7071067811865476 0.7071067811865476\n"
+	poses, err := ReadTUM(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(poses) != 1 || poses[0].Time != 1.5 {
+		t.Fatalf("poses %v", poses)
+	}
+	if got := transform(poses[0].Pose, vec.Vector3D{1, 0, 0}); got.Distance(vec.Vector3D{1, 3, 3}) > 1e-6 {
+		t.Errorf("x axis maps to %v", got)
+	}
+}
+
+func TestAssociate(t *testing.T) {
+	var est, gt []Stamped
+	for i := range 10 {
+		gt = append(gt, Stamped{Time: float64(i) * 0.1, Pose: path(i)})
+		if i%2 == 0 {
+			est = append(est, Stamped{Time: float64(i)*0.1 + 0.01, Pose: path(i)})
+		}
+	}
+	e, g := Associate(est, gt, 0.02)
+	if len(e) != 5 || len(g) != 5 {
+		t.Fatalf("%d and %d poses, want 5", len(e), len(g))
+	}
+	for i := range e {
+		if e[i] != g[i] {
+			t.Errorf("pair %d differs", i)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	// the estimate is the ground truth seen from elsewhere at half the scale
+	// with noise on the positions
+	offset := homogeneous(calib.Rodrigues(vec.Vector3D{0.1, -0.2, 0.3}), vec.Vector3D{1, 2, 3})
+	var est, gt Trajectory
+	for i := range 20 {
+		p := path(i)
+		gt = append(gt, p)
+		q := compose(offset, p)
+		noise := vec.Vector3D{float32(0.01 * math.Sin(float64(i))), 0, 0}
+		q.SetTranslation(translation(q).MulC(0.5).(vec.Vector3D).Add(noise).(vec.Vector3D))
+		est = append(est, q)
+	}
+	m, err := Evaluate(est, gt, WithScale())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.Scale-2) > 0.01 || m.ATE > 0.03 || m.RPERotation > 1e-3 || m.Poses != 20 {
+		t.Errorf("metrics %+v", m)
+	}
+	a, s, err := Align(est, gt, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := angle(rotation(compose(a, offset))); d > 0.01 || math.Abs(s-2) > 0.01 {
+		t.Errorf("aligned by %v at scale %v", a, s)
+	}
+
+	m, err = Evaluate(gt, gt, WithDelta(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ATE > 1e-5 || m.RPETranslation > 1e-5 || m.RPERotation > 1e-3 || m.Scale != 1 {
+		t.Errorf("identical trajectories: %+v", m)
+	}
+	if _, err := Evaluate(est[:3], gt); err == nil {
+		t.Error("trajectories of different lengths accepted")
+	}
+}
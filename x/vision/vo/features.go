@@ -0,0 +1,188 @@
+package vo
+
+import (
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/extract/keypoints"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// feature is a point tracked from frame to frame.
+type feature struct {
+	// pos is the pixel in the current frame and und its undistorted pixel.
+	pos vec.Vector2D
+	und [2]float64
+	// landmark is the map point the feature is an image of, or -1.
+	landmark int
+	// origin is the keyframe the feature was first seen in, at originUnd;
+	// monocular landmarks are triangulated between it and later
+	// keyframes.
+	origin    int
+	originUnd [2]float64
+}
+
+// border keeps new features away from the image edges, where the tracking
+// window leaves the image.
+const border = 12
+
+// detect adds FAST corners in the grid cells that hold no feature, the
+// strongest per cell, up to the feature budget.
+func (o *Odometry) detect(img types.Tensor, w, h int, features []feature, kf int) ([]feature, error) {
+	if len(features) >= o.cfg.maxFeatures {
+		return features, nil
+	}
+	corners, err := keypoints.FAST(img, o.cfg.fastThreshold, true)
+	if err != nil {
+		return nil, err
+	}
+	cell := max(8, int(math.Sqrt(float64(w*h)/float64(o.cfg.maxFeatures))))
+	cols, rows := (w+cell-1)/cell, (h+cell-1)/cell
+	taken := make([]bool, cols*rows)
+	for _, f := range features {
+		x, y := int(f.pos[0])/cell, int(f.pos[1])/cell
+		if x >= 0 && y >= 0 && x < cols && y < rows {
+			taken[y*cols+x] = true
+		}
+	}
+	slices.SortFunc(corners, func(a, b keypoints.KeyPoint) int {
+		switch {
+		case a.Response > b.Response:
+			return -1
+		case a.Response < b.Response:
+			return 1
+		}
+		return 0
+	})
+	for _, c := range corners {
+		if len(features) >= o.cfg.maxFeatures {
+			break
+		}
+		if c.X < border || c.Y < border || c.X >= float32(w-border) || c.Y >= float32(h-border) {
+			continue
+		}
+		i := int(c.Y)/cell*cols + int(c.X)/cell
+		if taken[i] {
+			continue
+		}
+		taken[i] = true
+		f := feature{pos: vec.Vector2D{c.X, c.Y}, landmark: -1, origin: kf}
+		f.und = o.undistort(f.pos)
+		f.originUnd = f.und
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+// fbThreshold is the largest distance in pixels between a feature and
+// where it is tracked back to from the next frame.
+const fbThreshold = 1
+
+// track follows the features to the next frame with Lucas-Kanade and a
+// forward-backward check and drops the lost ones.
+func (o *Odometry) track(prev, next types.Tensor, features []feature) ([]feature, error) {
+	if len(features) == 0 {
+		return features, nil
+	}
+	pts := make([]vec.Vector2D, len(features))
+	for i, f := range features {
+		pts[i] = f.pos
+	}
+	fwd, ok1, err := o.cfg.tracker.Track(prev, next, pts)
+	if err != nil {
+		return nil, err
+	}
+	back, ok2, err := o.cfg.tracker.Track(next, prev, fwd)
+	if err != nil {
+		return nil, err
+	}
+	out := features[:0]
+	for i, f := range features {
+		p := fwd[i]
+		if !ok1[i] || !ok2[i] || back[i].Distance(pts[i]) > fbThreshold ||
+			p[0] < 0 || p[1] < 0 || p[0] > float32(o.cam.Width-1) || p[1] > float32(o.cam.Height-1) {
+			continue
+		}
+		f.pos = p
+		f.und = o.undistort(p)
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// undistort maps a pixel to the pixel of the ideal pinhole camera.
+func (o *Odometry) undistort(p vec.Vector2D) [2]float64 {
+	n := o.cam.Normalize(p)
+	return [2]float64{o.pin.fx*float64(n[0]) + o.pin.cx, o.pin.fy*float64(n[1]) + o.pin.cy}
+}
+
+// stereoPatch is the half size of the block matched along the rows of a
+// rectified pair.
+const stereoPatch = 4
+
+// match finds the x of a left image pixel in the rectified right image by
+// the zero mean sum of absolute differences of a block along the row, with
+// a uniqueness check and parabolic subpixel refinement. It is NaN when no
+// disparity in the range is distinct.
+func (o *Odometry) match(left, right gray.Image, p vec.Vector2D) float64 {
+	x, y := int(math.Round(float64(p[0]))), int(math.Round(float64(p[1])))
+	r := stereoPatch
+	if y < r || y >= left.H-r || x < r || x >= left.W-r {
+		return math.NaN()
+	}
+	mean := func(g gray.Image, cx int) float32 {
+		var s float32
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				s += g.Pix[(y+dy)*g.W+cx+dx]
+			}
+		}
+		return s / float32((2*r+1)*(2*r+1))
+	}
+	ml := mean(left, x)
+	lo, hi := max(0, o.cfg.minDisparity), min(o.cfg.maxDisparity, x-r)
+	if hi <= lo+1 {
+		return math.NaN()
+	}
+	costs := make([]float32, hi-lo+1)
+	best := -1
+	for d := lo; d <= hi; d++ {
+		mr := mean(right, x-d)
+		var sad float32
+		for dy := -r; dy <= r; dy++ {
+			row := (y + dy) * left.W
+			for dx := -r; dx <= r; dx++ {
+				sad += abs(left.Pix[row+x+dx] - ml - right.Pix[row+x-d+dx] + mr)
+			}
+		}
+		costs[d-lo] = sad
+		if best < 0 || sad < costs[best] {
+			best = d - lo
+		}
+	}
+	// unique: no other minimum within 10%, away from the best
+	for i, c := range costs {
+		if (i < best-1 || i > best+1) && c < costs[best]*1.1 {
+			return math.NaN()
+		}
+	}
+	if best == 0 || best == len(costs)-1 {
+		return math.NaN()
+	}
+	c0, c1, c2 := float64(costs[best-1]), float64(costs[best]), float64(costs[best+1])
+	sub := 0.0
+	if den := c0 - 2*c1 + c2; den > 0 {
+		sub = 0.5 * (c0 - c2) / den
+	}
+	d := float64(lo+best) + sub
+	return float64(p[0]) - d
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,140 @@
+package vo
+
+import (
+	"github.com/itohio/EasyRobot/x/vision/flow"
+)
+
+type config struct {
+	maxFeatures      int
+	fastThreshold    float32
+	window           int
+	keyframeInterval int
+	keyframeRatio    float64
+	minParallax      float64
+	minDisparity     int
+	maxDisparity     int
+	maxDepth         float64
+	pixelNoise       float64
+	iterations       int
+	tracker          *flow.LK
+}
+
+// Option configures an Odometry.
+type Option func(*config)
+
+// WithMaxFeatures sets the number of features tracked (default: 300).
+func WithMaxFeatures(n int) Option {
+	return func(c *config) {
+		if n < 20 {
+			panic("vo: at least 20 features are needed")
+		}
+		c.maxFeatures = n
+	}
+}
+
+// WithFastThreshold sets the FAST threshold of new features (default: 20).
+func WithFastThreshold(t float32) Option {
+	return func(c *config) {
+		c.fastThreshold = t
+	}
+}
+
+// WithWindow sets the number of keyframes of the bundle adjustment window
+// (default: 7).
+func WithWindow(n int) Option {
+	return func(c *config) {
+		if n < 3 {
+			panic("vo: window must hold at least 3 keyframes")
+		}
+		c.window = n
+	}
+}
+
+// WithKeyframes sets when a frame becomes a keyframe: when the map points
+// tracked fall below ratio of those of the last keyframe or after interval
+// frames (default: 0.7 and 10).
+func WithKeyframes(ratio float64, interval int) Option {
+	return func(c *config) {
+		if ratio <= 0 || ratio > 1 || interval < 1 {
+			panic("vo: invalid keyframe policy")
+		}
+		c.keyframeRatio, c.keyframeInterval = ratio, interval
+	}
+}
+
+// WithMinParallax sets the median feature motion in pixels needed to
+// initialise monocular odometry from two views (default: 20).
+func WithMinParallax(px float64) Option {
+	return func(c *config) {
+		c.minParallax = px
+	}
+}
+
+// WithDisparities sets the disparity range of stereo matching in pixels
+// (default: 1 to 128).
+func WithDisparities(min, max int) Option {
+	return func(c *config) {
+		if min < 0 || max <= min+2 {
+			panic("vo: invalid disparity range")
+		}
+		c.minDisparity, c.maxDisparity = min, max
+	}
+}
+
+// WithMaxDepth sets the farthest stereo point used, in baselines
+// (default: 40); depth from disparity degrades quadratically.
+func WithMaxDepth(baselines float64) Option {
+	return func(c *config) {
+		c.maxDepth = baselines
+	}
+}
+
+// WithPixelNoise sets the standard deviation of feature positions in
+// pixels (default: 1), the least noise assumed by Pose.Covariance.
+func WithPixelNoise(px float64) Option {
+	return func(c *config) {
+		c.pixelNoise = px
+	}
+}
+
+// WithIterations sets the Levenberg-Marquardt iterations of bundle
+// adjustment (default: 10).
+func WithIterations(n int) Option {
+	return func(c *config) {
+		if n < 1 {
+			panic("vo: iterations must be positive")
+		}
+		c.iterations = n
+	}
+}
+
+// WithTracker sets the Lucas-Kanade tracker of the features (default:
+// flow.NewLK()).
+func WithTracker(lk *flow.LK) Option {
+	return func(c *config) {
+		c.tracker = lk
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		maxFeatures:      300,
+		fastThreshold:    20,
+		window:           7,
+		keyframeInterval: 10,
+		keyframeRatio:    0.7,
+		minParallax:      20,
+		minDisparity:     1,
+		maxDisparity:     128,
+		maxDepth:         40,
+		pixelNoise:       1,
+		iterations:       10,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.tracker == nil {
+		c.tracker = flow.NewLK()
+	}
+	return c
+}
@@ -0,0 +1,82 @@
+package vo
+
+import (
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+)
+
+// Poses are homogeneous camera to world transforms and points world
+// coordinates; the bundle adjustment linearises them in float64.
+
+func identity() mat.Matrix4x4 {
+	return mat.Matrix4x4{}.Eye().(mat.Matrix4x4)
+}
+
+// compose returns a after b.
+func compose(a, b mat.Matrix4x4) mat.Matrix4x4 {
+	return a.Mul(a, b).(mat.Matrix4x4)
+}
+
+func invert(a mat.Matrix4x4) mat.Matrix4x4 {
+	r := rotation(a)
+	rt := r.Transpose(r).(mat.Matrix3x3)
+	return homogeneous(rt, rt.MulVec(translation(a), nil).(vec.Vector3D).Neg().(vec.Vector3D))
+}
+
+// transform maps p by a.
+func transform(a mat.Matrix4x4, p vec.Vector3D) vec.Vector3D {
+	q := a.MulVec(vec.Vector4D{p[0], p[1], p[2], 1}, nil).(vec.Vector4D)
+	return vec.Vector3D{q[0], q[1], q[2]}
+}
+
+func homogeneous(r mat.Matrix3x3, t vec.Vector3D) mat.Matrix4x4 {
+	var out mat.Matrix4x4
+	out.Homogenous(&r, t)
+	return out
+}
+
+func rotation(a mat.Matrix4x4) mat.Matrix3x3 {
+	var r mat.Matrix3x3
+	a.GetRotation(&r)
+	return r
+}
+
+func translation(a mat.Matrix4x4) vec.Vector3D {
+	return a.GetTranslation(vec.Vector3D{})
+}
+
+// retract perturbs a camera to world transform on the right, in the camera
+// frame: the rotation by exp(phi) and the position by r rho, with
+// xi = (phi, rho).
+func retract(a mat.Matrix4x4, xi []float64) mat.Matrix4x4 {
+	phi := vec.Vector3D{float32(xi[0]), float32(xi[1]), float32(xi[2])}
+	rho := vec.Vector3D{float32(xi[3]), float32(xi[4]), float32(xi[5])}
+	return orthonormalized(compose(a, homogeneous(calib.Rodrigues(phi), rho)))
+}
+
+// orthonormalized replaces the rotation of a by the nearest rotation, U Vt
+// of its SVD; products of rotations lose orthogonality to rounding, and
+// the transpose is the inverse only of a rotation.
+func orthonormalized(a mat.Matrix4x4) mat.Matrix4x4 {
+	m := mat.New(3, 3)
+	for i := range 3 {
+		copy(m[i], a[i][:3])
+	}
+	var res mat.SVDResult
+	if err := m.SVD(&res); err != nil {
+		return a
+	}
+	u, vt := res.U.View().(mat.Matrix), res.Vt.View().(mat.Matrix)
+	r := mat.New(3, 3)
+	r.Mul(u, vt)
+	for i := range 3 {
+		copy(a[i][:3], r[i])
+	}
+	return a
+}
+
+// angle is the rotation angle of r in radians.
+func angle(r mat.Matrix3x3) float64 {
+	return float64(calib.RotationVector(r).Magnitude())
+}
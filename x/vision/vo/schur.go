@@ -0,0 +1,166 @@
+package vo
+
+import "math"
+
+// block is a 6x6 block of the reduced camera system.
+type block [6][6]float64
+
+// reduced is the reduced camera system S dc = rhs that is left when the
+// points are eliminated from the normal equations. S is block sparse: it
+// has a block for each pair of frames that see a common point, and the
+// lower triangle is kept, rows[i][j] for j <= i, with nil for the pairs
+// that share none.
+type reduced struct {
+	rows [][]*block
+	rhs  []float64
+}
+
+func newReduced(nf int) *reduced {
+	s := &reduced{rows: make([][]*block, nf), rhs: make([]float64, 6*nf)}
+	for i := range nf {
+		s.rows[i] = make([]*block, i+1)
+	}
+	return s
+}
+
+// at returns the block of frames i >= j, allocating it.
+func (s *reduced) at(i, j int) *block {
+	if s.rows[i][j] == nil {
+		s.rows[i][j] = new(block)
+	}
+	return s.rows[i][j]
+}
+
+// factor replaces S by its block Cholesky factor L, S = L Lᵀ, and reports
+// whether S is positive definite. A block of L is allocated only where S
+// has one or where the factorisation fills it in: frames i and j that both
+// share points with an earlier frame.
+func (s *reduced) factor() bool {
+	for i, row := range s.rows {
+		for j := range row {
+			b := row[j]
+			for k := range j {
+				if row[k] == nil || s.rows[j][k] == nil {
+					continue
+				}
+				if b == nil {
+					b = s.at(i, j)
+				}
+				b.subMulT(row[k], s.rows[j][k])
+			}
+			switch {
+			case b == nil:
+			case j == i:
+				if !b.cholesky() {
+					return false
+				}
+			default:
+				// L_ij = (S_ij - Σ L_ik L_jkᵀ) L_jj⁻ᵀ, a row at a time
+				for r := range 6 {
+					s.rows[j][j].solveLower(b[r][:])
+				}
+			}
+		}
+	}
+	return true
+}
+
+// solve returns dc after factor, by forward and back substitution.
+func (s *reduced) solve() []float64 {
+	n := len(s.rows)
+	x := append([]float64(nil), s.rhs...)
+	for i, row := range s.rows {
+		xi := x[6*i : 6*i+6]
+		for k := range i {
+			if row[k] != nil {
+				row[k].subMulVec(xi, x[6*k:6*k+6])
+			}
+		}
+		row[i].solveLower(xi)
+	}
+	for i := n - 1; i >= 0; i-- {
+		xi := x[6*i : 6*i+6]
+		for k := i + 1; k < n; k++ {
+			if b := s.rows[k][i]; b != nil {
+				b.subMulTVec(xi, x[6*k:6*k+6])
+			}
+		}
+		s.rows[i][i].solveUpper(xi)
+	}
+	return x
+}
+
+// subMulT subtracts a cᵀ.
+func (b *block) subMulT(a, c *block) {
+	for r := range 6 {
+		for q := range 6 {
+			var sum float64
+			for k := range 6 {
+				sum += a[r][k] * c[q][k]
+			}
+			b[r][q] -= sum
+		}
+	}
+}
+
+// cholesky replaces a symmetric positive definite block by its lower
+// triangular factor.
+func (b *block) cholesky() bool {
+	for j := range 6 {
+		d := b[j][j]
+		for k := range j {
+			d -= b[j][k] * b[j][k]
+		}
+		if !(d > 0) {
+			return false
+		}
+		b[j][j] = math.Sqrt(d)
+		for i := j + 1; i < 6; i++ {
+			v := b[i][j]
+			for k := range j {
+				v -= b[i][k] * b[j][k]
+			}
+			b[i][j] = v / b[j][j]
+			b[j][i] = 0
+		}
+	}
+	return true
+}
+
+// solveLower solves L x = v in place for a lower triangular block.
+func (b *block) solveLower(v []float64) {
+	for i := range 6 {
+		for k := range i {
+			v[i] -= b[i][k] * v[k]
+		}
+		v[i] /= b[i][i]
+	}
+}
+
+// solveUpper solves Lᵀ x = v in place for a lower triangular block.
+func (b *block) solveUpper(v []float64) {
+	for i := 5; i >= 0; i-- {
+		for k := i + 1; k < 6; k++ {
+			v[i] -= b[k][i] * v[k]
+		}
+		v[i] /= b[i][i]
+	}
+}
+
+// subMulVec subtracts b x from y.
+func (b *block) subMulVec(y, x []float64) {
+	for r := range 6 {
+		for k := range 6 {
+			y[r] -= b[r][k] * x[k]
+		}
+	}
+}
+
+// subMulTVec subtracts bᵀ x from y.
+func (b *block) subMulTVec(y, x []float64) {
+	for r := range 6 {
+		for k := range 6 {
+			y[r] -= b[k][r] * x[k]
+		}
+	}
+}
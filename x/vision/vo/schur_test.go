@@ -0,0 +1,73 @@
+package vo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestReducedSolve solves a block sparse system whose factor fills in:
+// frames 1 and 2 share points with frame 0 but not with each other.
+func TestReducedSolve(t *testing.T) {
+	const nf = 5
+	r := rand.New(rand.NewSource(1))
+	pairs := [][2]int{{1, 0}, {2, 0}, {3, 2}, {4, 3}, {4, 1}}
+	dense := make([][]float64, 6*nf)
+	for i := range dense {
+		dense[i] = make([]float64, 6*nf)
+	}
+	// a sum of outer products of vectors on the pattern is positive definite
+	for range 200 {
+		pr := pairs[r.Intn(len(pairs))]
+		var v [6 * nf]float64
+		for _, f := range pr {
+			for a := range 6 {
+				v[6*f+a] = r.NormFloat64()
+			}
+		}
+		for i := range v {
+			for j := range v {
+				dense[i][j] += v[i] * v[j]
+			}
+		}
+	}
+	s := newReduced(nf)
+	for i := range nf {
+		for j := 0; j <= i; j++ {
+			var b block
+			zero := true
+			for a := range 6 {
+				for c := range 6 {
+					b[a][c] = dense[6*i+a][6*j+c]
+					zero = zero && b[a][c] == 0
+				}
+			}
+			if !zero {
+				*s.at(i, j) = b
+			}
+		}
+	}
+	want := make([]float64, 6*nf)
+	for i := range want {
+		want[i] = r.NormFloat64()
+	}
+	for i := range want {
+		for j := range want {
+			s.rhs[i] += dense[i][j] * want[j]
+		}
+	}
+	if s.rows[2][1] != nil || s.rows[3][0] != nil {
+		t.Fatal("blocks of frames without common points")
+	}
+	if !s.factor() {
+		t.Fatal("not positive definite")
+	}
+	if s.rows[2][1] == nil || s.rows[3][0] != nil {
+		t.Error("fill-in of frames 2 and 1 only expected")
+	}
+	for i, x := range s.solve() {
+		if math.Abs(x-want[i]) > 1e-6 {
+			t.Fatalf("x[%d] = %g, want %g", i, x, want[i])
+		}
+	}
+}
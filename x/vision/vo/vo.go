@@ -0,0 +1,706 @@
+// Package vo estimates the motion of a camera from its images, monocular
+// or stereo visual odometry in plain Go. Features are FAST corners spread
+// over a grid and tracked from frame to frame with pyramidal Lucas-Kanade.
+// Every frame is located against the map by an iterative, robust PnP from
+// a constant velocity prediction. Keyframes add map points, by stereo
+// matching along the rows of a rectified pair or by triangulation against
+// earlier keyframes, and a sliding window of keyframes is refined by bundle
+// adjustment: sparse Levenberg-Marquardt with the points eliminated by the
+// Schur complement.
+//
+// Monocular odometry starts from two views related by an essential matrix
+// once the features moved far enough; its scale is the distance between
+// those views and drifts. Stereo odometry is metric.
+//
+// Poses are incremental: every Pose holds the motion since the previous
+// one with its covariance, a measurement for the filters of x/math/filter
+// such as ekalman. Evaluate compares trajectories with ground truth.
+//
+// Images are single channel UINT8 or FP32 tensors of shape [H, W] or
+// [H, W, 1].
+package vo
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+	"github.com/itohio/EasyRobot/x/vision/geometry"
+	"github.com/itohio/EasyRobot/x/vision/internal/gray"
+)
+
+// Frame is one camera frame.
+type Frame struct {
+	// Image is the image of the camera, the left one of a stereo pair.
+	Image types.Tensor
+	// Right is the right image of a rectified stereo pair; stereo odometry
+	// needs it.
+	Right types.Tensor
+}
+
+// Pose is the estimated pose of a frame.
+type Pose struct {
+	// Index counts the frames given to Track from 0.
+	Index int
+	// Keyframe reports whether the frame became a keyframe.
+	Keyframe bool
+	// Transform maps camera coordinates to the coordinates of the first
+	// camera, x right, y down and z forward.
+	Transform mat.Matrix4x4
+	// Delta maps camera coordinates to those of the previous Pose, the
+	// motion since it: Transform is the previous Transform times Delta.
+	Delta mat.Matrix4x4
+	// Covariance is the 6x6 covariance of a perturbation of Delta on the
+	// right, rotation vector then translation in the camera frame, from the
+	// reprojection errors with the map held fixed. It does not include the
+	// drift of the map, and it is zero for the first frame and for the frame
+	// that initialises monocular odometry, whose motion sets the scale.
+	Covariance mat.Matrix
+	// Tracked is the number of map points the frame was located with.
+	Tracked int
+}
+
+// ErrNoStereo is returned when stereo odometry is given a frame without the
+// right image.
+var ErrNoStereo = errors.New("vo: stereo odometry needs the right image")
+
+// keyframe is a frame of the bundle adjustment window; pose maps camera to
+// world coordinates.
+type keyframe struct {
+	id   int
+	pose mat.Matrix4x4
+}
+
+// landmark is a map point and its observations by window keyframes.
+type landmark struct {
+	p   vec.Vector3D
+	obs []sighting
+}
+
+// sighting is an observation of a landmark by a keyframe.
+type sighting struct {
+	kf       int
+	u, v, ur float64
+}
+
+// Odometry tracks the pose of a camera from frame to frame.
+type Odometry struct {
+	cfg    config
+	cam    *calib.Camera
+	pin    pinhole
+	stereo bool
+
+	index     int
+	prev      types.Tensor
+	features  []feature
+	window    []*keyframe
+	nextKF    int
+	landmarks map[int]*landmark
+	nextLM    int
+
+	initialized bool
+	// pose and last are the camera to world transforms of the current and
+	// the previous frame; reported is that of the last Pose.
+	pose, last, reported mat.Matrix4x4
+	sinceKF              int
+	kfTracked            int
+}
+
+// New creates monocular odometry for a calibrated camera; the images may
+// be distorted.
+func New(cam *calib.Camera, opts ...Option) *Odometry {
+	return newOdometry(cam, 0, opts)
+}
+
+// NewStereo creates stereo odometry for a rectified pair: cam is the
+// rectified left camera, without distortion, and baseline the distance to
+// the right camera, which sets the unit of the translations. See
+// RectifiedCamera.
+func NewStereo(cam *calib.Camera, baseline float64, opts ...Option) *Odometry {
+	if baseline <= 0 {
+		panic("vo: baseline must be positive")
+	}
+	return newOdometry(cam, baseline, opts)
+}
+
+func newOdometry(cam *calib.Camera, baseline float64, opts []Option) *Odometry {
+	if cam == nil || cam.Fx <= 0 || cam.Fy <= 0 || cam.Width <= 0 || cam.Height <= 0 {
+		panic("vo: invalid camera")
+	}
+	return &Odometry{
+		cfg:       newConfig(opts),
+		cam:       cam,
+		pin:       pinhole{fx: cam.Fx, fy: cam.Fy, cx: cam.Cx, cy: cam.Cy, baseline: baseline},
+		stereo:    baseline > 0,
+		landmarks: map[int]*landmark{},
+		pose:      identity(),
+		last:      identity(),
+		reported:  identity(),
+	}
+}
+
+// RectifiedCamera returns the rectified left camera and the baseline of a
+// horizontal stereo rectification for images of the given size.
+func RectifiedCamera(r *calib.Rectification, width, height int) (*calib.Camera, float64, error) {
+	if r.P2[1][3] != 0 || r.P2[0][3] == 0 {
+		return nil, 0, fmt.Errorf("vo: only horizontal stereo pairs are supported")
+	}
+	cam := &calib.Camera{
+		Width:  width,
+		Height: height,
+		Fx:     float64(r.P1[0][0]),
+		Fy:     float64(r.P1[1][1]),
+		Cx:     float64(r.P1[0][2]),
+		Cy:     float64(r.P1[1][2]),
+	}
+	return cam, -float64(r.P2[0][3]) / float64(r.P2[0][0]), nil
+}
+
+// Track estimates the pose of the next frame. It is false while monocular
+// odometry initialises and when tracking is lost, after which the map is
+// rebuilt from the frame; monocular scale changes then.
+func (o *Odometry) Track(f Frame) (Pose, bool, error) {
+	if f.Image == nil {
+		return Pose{}, false, fmt.Errorf("vo: nil image")
+	}
+	s := f.Image.Shape()
+	if len(s) < 2 || s[0] != o.cam.Height || s[1] != o.cam.Width {
+		return Pose{}, false, fmt.Errorf("vo: image %v does not match the %dx%d camera", s, o.cam.Width, o.cam.Height)
+	}
+	if o.stereo && f.Right == nil {
+		return Pose{}, false, ErrNoStereo
+	}
+	defer func() {
+		o.prev = f.Image
+		o.index++
+	}()
+
+	if o.prev == nil {
+		if err := o.restart(f); err != nil {
+			return Pose{}, false, err
+		}
+		return o.report(mat.New(6, 6), true, len(o.landmarks)), true, nil
+	}
+
+	var err error
+	if o.features, err = o.track(o.prev, f.Image, o.features); err != nil {
+		return Pose{}, false, err
+	}
+	if !o.initialized {
+		ok, err := o.initialize(f)
+		if err != nil || !ok {
+			return Pose{}, false, err
+		}
+		return o.report(mat.New(6, 6), true, o.kfTracked), true, nil
+	}
+
+	cov, tracked, ok := o.locate()
+	if !ok {
+		o.pose = o.predict()
+		return Pose{}, false, o.restart(f)
+	}
+	o.sinceKF++
+	keyframe := tracked < int(o.cfg.keyframeRatio*float64(o.kfTracked)) ||
+		o.sinceKF >= o.cfg.keyframeInterval ||
+		len(o.features) < o.cfg.maxFeatures/2
+	if keyframe {
+		if err := o.addKeyframe(f); err != nil {
+			return Pose{}, false, err
+		}
+	}
+	return o.report(cov, keyframe, tracked), true, nil
+}
+
+func (o *Odometry) report(cov mat.Matrix, keyframe bool, tracked int) Pose {
+	delta := compose(invert(o.reported), o.pose)
+	o.reported = o.pose
+	return Pose{
+		Index:      o.index,
+		Keyframe:   keyframe,
+		Transform:  o.pose,
+		Delta:      delta,
+		Covariance: cov,
+		Tracked:    tracked,
+	}
+}
+
+// restart drops the map and starts a new one at the current pose from a
+// frame: a stereo map from the pair, a monocular one once initialised.
+func (o *Odometry) restart(f Frame) error {
+	o.window = o.window[:0]
+	o.landmarks = map[int]*landmark{}
+	o.last = o.pose
+	kf := &keyframe{id: o.nextKF, pose: o.pose}
+	o.nextKF++
+	o.window = append(o.window, kf)
+	for i := range o.features {
+		o.features[i].landmark = -1
+		o.features[i].origin, o.features[i].originUnd = kf.id, o.features[i].und
+	}
+	var err error
+	if o.features, err = o.detect(f.Image, o.cam.Width, o.cam.Height, o.features, kf.id); err != nil {
+		return err
+	}
+	o.initialized = o.stereo
+	o.sinceKF = 0
+	if !o.stereo {
+		return nil
+	}
+	left, right, err := o.pair(f)
+	if err != nil {
+		return err
+	}
+	for i := range o.features {
+		o.addStereo(kf, left, right, &o.features[i])
+	}
+	o.kfTracked = len(o.landmarks)
+	return nil
+}
+
+func (o *Odometry) pair(f Frame) (gray.Image, gray.Image, error) {
+	left, err := gray.FromTensor(f.Image)
+	if err != nil {
+		return gray.Image{}, gray.Image{}, err
+	}
+	right, err := gray.FromTensor(f.Right)
+	if err != nil {
+		return gray.Image{}, gray.Image{}, err
+	}
+	if left.W != right.W || left.H != right.H {
+		return gray.Image{}, gray.Image{}, fmt.Errorf("vo: stereo images differ in size")
+	}
+	return left, right, nil
+}
+
+// addStereo matches a feature in the right image at a keyframe: its
+// landmark gains the observation, or a feature without one gets a new
+// landmark from the disparity. It reports whether the feature matched.
+func (o *Odometry) addStereo(kf *keyframe, left, right gray.Image, f *feature) bool {
+	ur := o.match(left, right, f.pos)
+	if math.IsNaN(ur) {
+		if f.landmark >= 0 {
+			o.observe(f.landmark, kf, f.und, ur)
+		}
+		return false
+	}
+	if f.landmark >= 0 {
+		o.observe(f.landmark, kf, f.und, ur)
+		return true
+	}
+	d := f.und[0] - ur
+	z := o.pin.fx * o.pin.baseline / d
+	if d <= 0 || z > o.cfg.maxDepth*o.pin.baseline {
+		return false
+	}
+	p := vec.Vector3D{float32((f.und[0] - o.pin.cx) * z / o.pin.fx), float32((f.und[1] - o.pin.cy) * z / o.pin.fy), float32(z)}
+	f.landmark = o.newLandmark(transform(kf.pose, p), sighting{kf: kf.id, u: f.und[0], v: f.und[1], ur: ur})
+	return true
+}
+
+func (o *Odometry) newLandmark(p vec.Vector3D, obs ...sighting) int {
+	id := o.nextLM
+	o.nextLM++
+	o.landmarks[id] = &landmark{p: p, obs: obs}
+	return id
+}
+
+func (o *Odometry) observe(id int, kf *keyframe, und [2]float64, ur float64) {
+	if lm := o.landmarks[id]; lm != nil {
+		lm.obs = append(lm.obs, sighting{kf: kf.id, u: und[0], v: und[1], ur: ur})
+	}
+}
+
+// minTracked is the fewest map points a frame is located with.
+const minTracked = 15
+
+// initialize tries to start monocular odometry from the first keyframe and
+// the current frame: the essential matrix of the features seen in both
+// gives the relative pose with a unit baseline, and the inliers are
+// triangulated into the map.
+func (o *Odometry) initialize(f Frame) (bool, error) {
+	kf0 := o.window[0]
+	var p1, p2 []vec.Vector2D
+	var idx []int
+	var motion []float64
+	for i, ft := range o.features {
+		if ft.origin != kf0.id {
+			continue
+		}
+		p1 = append(p1, vec.Vector2D{float32(ft.originUnd[0]), float32(ft.originUnd[1])})
+		p2 = append(p2, vec.Vector2D{float32(ft.und[0]), float32(ft.und[1])})
+		idx = append(idx, i)
+		motion = append(motion, math.Hypot(ft.und[0]-ft.originUnd[0], ft.und[1]-ft.originUnd[1]))
+	}
+	if len(idx) < 2*minTracked {
+		// too few features survived: start over from this frame
+		return false, o.restart(f)
+	}
+	slices.Sort(motion)
+	if motion[len(motion)/2] < o.cfg.minParallax {
+		return false, nil
+	}
+	k := o.pinholeMatrix()
+	e, mask, err := geometry.FindEssential(p1, p2, k, geometry.WithThreshold(float32(o.cfg.pixelNoise)))
+	if err != nil {
+		return false, nil
+	}
+	r, t, inliers, err := geometry.RecoverPose(e, p1, p2, k, mask)
+	if err != nil {
+		return false, nil
+	}
+	rel := orthonormalized(homogeneous(r, t)) // first camera to current camera
+	kf1 := &keyframe{id: o.nextKF, pose: compose(kf0.pose, invert(rel))}
+
+	type candidate struct {
+		feature int
+		p       vec.Vector3D
+	}
+	var found []candidate
+	for j, i := range idx {
+		if !inliers[j] {
+			continue
+		}
+		if p, ok := o.triangulate(kf0, kf1, o.features[i].originUnd, o.features[i].und); ok {
+			found = append(found, candidate{i, p})
+		}
+	}
+	if len(found) < 2*minTracked {
+		return false, nil
+	}
+	o.nextKF++
+	o.window = append(o.window, kf1)
+	for _, c := range found {
+		ft := &o.features[c.feature]
+		ft.landmark = o.newLandmark(c.p,
+			sighting{kf: kf0.id, u: ft.originUnd[0], v: ft.originUnd[1], ur: math.NaN()},
+			sighting{kf: kf1.id, u: ft.und[0], v: ft.und[1], ur: math.NaN()})
+	}
+	// the second keyframe is refined too, as the relative pose of the
+	// essential matrix is least accurate in rotation, and the unit
+	// baseline is restored afterwards
+	o.adjust(1)
+	origin := translation(kf0.pose)
+	if b := translation(kf1.pose).Distance(origin); b > 0 {
+		rescale := func(p vec.Vector3D) vec.Vector3D {
+			return p.Sub(origin).(vec.Vector3D).DivC(b).(vec.Vector3D).Add(origin).(vec.Vector3D)
+		}
+		kf1.pose.SetTranslation(rescale(translation(kf1.pose)))
+		for _, lm := range o.landmarks {
+			lm.p = rescale(lm.p)
+		}
+	}
+	o.pose, o.last = kf1.pose, kf1.pose
+	o.initialized = true
+	o.sinceKF = 0
+	o.kfTracked = o.trackedLandmarks()
+	o.features, err = o.detect(f.Image, o.cam.Width, o.cam.Height, o.features, kf1.id)
+	return err == nil, err
+}
+
+// minParallax is the least angle between the rays of a triangulated point.
+var minParallax = math.Cos(1 * math.Pi / 180)
+
+// triangulate returns the world point seen at the undistorted pixels a in
+// keyframe ka and b in kb, if it is in front of both with enough parallax
+// and reprojects within the noise.
+func (o *Odometry) triangulate(ka, kb *keyframe, a, b [2]float64) (vec.Vector3D, bool) {
+	rel := compose(invert(kb.pose), ka.pose)
+	x1 := o.normalize(a)
+	x2 := o.normalize(b)
+	pc, ok := geometry.Triangulate(rotation(rel), translation(rel), x1, x2)
+	if !ok {
+		return vec.Vector3D{}, false
+	}
+	ra := rotation(ka.pose).MulVec(vec.Vector3D{x1[0], x1[1], 1}, nil).(vec.Vector3D)
+	rb := rotation(kb.pose).MulVec(vec.Vector3D{x2[0], x2[1], 1}, nil).(vec.Vector3D)
+	if float64(ra.Dot(rb)/(ra.Magnitude()*rb.Magnitude())) > minParallax {
+		return vec.Vector3D{}, false
+	}
+	p := transform(ka.pose, pc)
+	limit := o.huber()
+	var e [3]float64
+	for _, s := range []struct {
+		kf *keyframe
+		px [2]float64
+	}{{ka, a}, {kb, b}} {
+		if _, n := o.pin.residual(observation{u: s.px[0], v: s.px[1], ur: math.NaN()}, s.kf.pose, p, &e); n == 0 || math.Hypot(e[0], e[1]) > limit {
+			return vec.Vector3D{}, false
+		}
+	}
+	return p, true
+}
+
+func (o *Odometry) normalize(p [2]float64) vec.Vector2D {
+	return vec.Vector2D{float32((p[0] - o.pin.cx) / o.pin.fx), float32((p[1] - o.pin.cy) / o.pin.fy)}
+}
+
+func (o *Odometry) pinholeMatrix() mat.Matrix3x3 {
+	return mat.Matrix3x3{
+		{float32(o.pin.fx), 0, float32(o.pin.cx)},
+		{0, float32(o.pin.fy), float32(o.pin.cy)},
+		{0, 0, 1},
+	}
+}
+
+// huber is the robust kernel width and the outlier threshold in pixels,
+// the 95% quantile of the reprojection error of a monocular observation.
+func (o *Odometry) huber() float64 {
+	return math.Sqrt(5.991) * o.cfg.pixelNoise
+}
+
+// locate estimates the pose of the current frame from the map points of
+// its features, starting from a constant velocity prediction. Outliers are
+// classified in rounds, as ORB-SLAM does, and their features lose the
+// landmark. It returns the covariance of the pose and the number of
+// inliers.
+func (o *Odometry) locate() (mat.Matrix, int, bool) {
+	p := &problem{
+		cam:       o.pin,
+		frames:    []mat.Matrix4x4{o.predict()},
+		fixed:     []bool{false},
+		fixPoints: true,
+		huber:     o.huber(),
+	}
+	var all []observation
+	var owner []int
+	for i, ft := range o.features {
+		lm := o.landmarks[ft.landmark]
+		if ft.landmark < 0 || lm == nil {
+			continue
+		}
+		all = append(all, observation{frame: 0, point: len(p.points), u: ft.und[0], v: ft.und[1], ur: math.NaN()})
+		p.points = append(p.points, lm.p)
+		owner = append(owner, i)
+	}
+	if len(all) < minTracked {
+		return nil, 0, false
+	}
+
+	inlier := make([]bool, len(all))
+	for i := range inlier {
+		inlier[i] = true
+	}
+	limit := o.huber()
+	var errs []float64
+	for range 4 {
+		p.obs = make([]observation, 0, len(all))
+		for i, ob := range all {
+			if inlier[i] {
+				p.obs = append(p.obs, ob)
+			}
+		}
+		if len(p.obs) < minTracked {
+			return nil, 0, false
+		}
+		p.solve(o.cfg.iterations)
+		p.obs = all
+		errs = p.errors()
+		for i, e := range errs {
+			inlier[i] = e <= limit
+		}
+	}
+
+	p.obs = nil
+	var sq float64
+	for i, ob := range all {
+		if inlier[i] {
+			p.obs = append(p.obs, ob)
+			sq += errs[i] * errs[i]
+		} else {
+			o.features[owner[i]].landmark = -1
+		}
+	}
+	n := len(p.obs)
+	if n < minTracked {
+		return nil, 0, false
+	}
+	sigma2 := max(sq/float64(2*n-6), o.cfg.pixelNoise*o.cfg.pixelNoise)
+	cov := p.covariance(0, sigma2)
+	o.last, o.pose = o.pose, p.frames[0]
+	return cov, n, true
+}
+
+// predict extrapolates the pose of the current frame at constant velocity.
+func (o *Odometry) predict() mat.Matrix4x4 {
+	return orthonormalized(compose(o.pose, compose(invert(o.last), o.pose)))
+}
+
+// addKeyframe makes the current frame a keyframe: its features observe
+// their landmarks, new landmarks come from stereo or triangulation, new
+// features fill the image, the window slides and is bundle adjusted.
+func (o *Odometry) addKeyframe(f Frame) error {
+	kf := &keyframe{id: o.nextKF, pose: o.pose}
+	o.nextKF++
+	o.window = append(o.window, kf)
+
+	var left, right gray.Image
+	if o.stereo {
+		var err error
+		if left, right, err = o.pair(f); err != nil {
+			return err
+		}
+	}
+	byID := make(map[int]*keyframe, len(o.window))
+	for _, k := range o.window {
+		byID[k.id] = k
+	}
+	for i := range o.features {
+		ft := &o.features[i]
+		switch {
+		case o.stereo:
+			o.addStereo(kf, left, right, ft)
+		case ft.landmark >= 0:
+			o.observe(ft.landmark, kf, ft.und, math.NaN())
+		default:
+			origin := byID[ft.origin]
+			if origin == nil || origin == kf {
+				continue
+			}
+			if p, ok := o.triangulate(origin, kf, ft.originUnd, ft.und); ok {
+				ft.landmark = o.newLandmark(p,
+					sighting{kf: origin.id, u: ft.originUnd[0], v: ft.originUnd[1], ur: math.NaN()},
+					sighting{kf: kf.id, u: ft.und[0], v: ft.und[1], ur: math.NaN()})
+			}
+		}
+	}
+
+	n := len(o.features)
+	var err error
+	if o.features, err = o.detect(f.Image, o.cam.Width, o.cam.Height, o.features, kf.id); err != nil {
+		return err
+	}
+	if o.stereo {
+		for i := n; i < len(o.features); i++ {
+			o.addStereo(kf, left, right, &o.features[i])
+		}
+	}
+
+	if len(o.window) > o.cfg.window {
+		o.drop(o.window[0])
+		o.window = o.window[1:]
+	}
+	if o.stereo {
+		o.adjust(1)
+	} else {
+		o.adjust(2)
+	}
+	o.pose = kf.pose
+	o.sinceKF = 0
+	o.kfTracked = o.trackedLandmarks()
+	return nil
+}
+
+// drop removes a keyframe leaving the window: its observations, the
+// landmarks left without any and the origin of features first seen in it.
+func (o *Odometry) drop(old *keyframe) {
+	for id, lm := range o.landmarks {
+		lm.obs = slices.DeleteFunc(lm.obs, func(s sighting) bool { return s.kf == old.id })
+		if len(lm.obs) == 0 {
+			delete(o.landmarks, id)
+		}
+	}
+	for i := range o.features {
+		ft := &o.features[i]
+		if o.landmarks[ft.landmark] == nil {
+			ft.landmark = -1
+		}
+		if ft.origin == old.id {
+			ft.origin, ft.originUnd = o.window[len(o.window)-1].id, ft.und
+		}
+	}
+}
+
+// usable reports whether a landmark is constrained in the window: seen by
+// two keyframes, or once in stereo.
+func usable(lm *landmark) bool {
+	if len(lm.obs) >= 2 {
+		return true
+	}
+	return len(lm.obs) == 1 && !math.IsNaN(lm.obs[0].ur)
+}
+
+// adjust bundle adjusts the window with the oldest keyframes fixed: one
+// fixes the gauge, for a single camera a second one the scale.
+// Observations off by more than the outlier threshold are removed and the
+// window is adjusted again.
+func (o *Odometry) adjust(fixed int) {
+	index := make(map[int]int, len(o.window))
+	p := &problem{cam: o.pin, huber: o.huber()}
+	if o.stereo {
+		p.huber = math.Sqrt(7.815) * o.cfg.pixelNoise
+	}
+	for i, kf := range o.window {
+		index[kf.id] = i
+		p.frames = append(p.frames, kf.pose)
+		p.fixed = append(p.fixed, i < fixed)
+	}
+	ids := slices.Sorted(maps.Keys(o.landmarks))
+	var used []int
+	for _, id := range ids {
+		lm := o.landmarks[id]
+		if !usable(lm) {
+			continue
+		}
+		for _, s := range lm.obs {
+			p.obs = append(p.obs, observation{frame: index[s.kf], point: len(p.points), u: s.u, v: s.v, ur: s.ur})
+		}
+		p.points = append(p.points, lm.p)
+		used = append(used, id)
+	}
+	if len(p.points) == 0 {
+		return
+	}
+	p.solve(o.cfg.iterations)
+
+	// drop the outlying observations and adjust again
+	var keep []observation
+	for i, e := range p.errors() {
+		if e <= p.huber {
+			keep = append(keep, p.obs[i])
+		}
+	}
+	if len(keep) < len(p.obs) {
+		p.obs = keep
+		p.solve(o.cfg.iterations)
+	}
+
+	for i, kf := range o.window {
+		kf.pose = p.frames[i]
+	}
+	for j, id := range used {
+		lm := o.landmarks[id]
+		lm.p = p.points[j]
+		lm.obs = lm.obs[:0]
+	}
+	for _, ob := range p.obs {
+		lm := o.landmarks[used[ob.point]]
+		lm.obs = append(lm.obs, sighting{kf: o.window[ob.frame].id, u: ob.u, v: ob.v, ur: ob.ur})
+	}
+	for _, id := range used {
+		if lm := o.landmarks[id]; !usable(lm) && len(lm.obs) == 0 {
+			delete(o.landmarks, id)
+		}
+	}
+	for i := range o.features {
+		if o.landmarks[o.features[i].landmark] == nil {
+			o.features[i].landmark = -1
+		}
+	}
+}
+
+// trackedLandmarks counts the features with a landmark.
+func (o *Odometry) trackedLandmarks() int {
+	n := 0
+	for _, ft := range o.features {
+		if ft.landmark >= 0 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,219 @@
+package vo
+
+import (
+	"image"
+	"image/draw"
+	_ "image/png"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/itohio/EasyRobot/x/math/mat"
+	"github.com/itohio/EasyRobot/x/math/tensor"
+	"github.com/itohio/EasyRobot/x/math/tensor/types"
+	"github.com/itohio/EasyRobot/x/math/vec"
+	"github.com/itohio/EasyRobot/x/vision/calib"
+)
+
+const (
+	width, height = 320, 240
+	focal         = 300.0
+)
+
+func camera() *calib.Camera {
+	return &calib.Camera{Width: width, Height: height, Fx: focal, Fy: focal, Cx: width/2 - 0.5, Cy: height/2 - 0.5}
+}
+
+// shade is the random gray of a texel of a wall.
+func shade(wall, i, j int) float64 {
+	h := uint32(wall*73856093) ^ uint32(i*19349663) ^ uint32(j*83492791)
+	h ^= h >> 13
+	h *= 0x5bd1e995
+	h ^= h >> 15
+	return 30 + float64(h%196)
+}
+
+// texture interpolates the texels of a wall bilinearly.
+func texture(wall int, x, y float64) float64 {
+	i, j := math.Floor(x), math.Floor(y)
+	fx, fy := x-i, y-j
+	a, b := int(i), int(j)
+	top := shade(wall, a, b) + (shade(wall, a+1, b)-shade(wall, a, b))*fx
+	bottom := shade(wall, a, b+1) + (shade(wall, a+1, b+1)-shade(wall, a, b+1))*fx
+	return top + (bottom-top)*fy
+}
+
+// render ray casts a corridor 4 m wide and 3 m high with walls of smooth
+// random texture from a camera to world pose, supersampled 2x2.
+func render(pose mat.Matrix4x4) types.Tensor {
+	const cell = 0.25
+	walls := []struct {
+		axis int
+		at   float64
+	}{{0, -2}, {0, 2}, {1, -1.5}, {1, 1.5}, {2, 60}}
+	var origin [3]float64
+	for i := range 3 {
+		origin[i] = float64(pose[i][3])
+	}
+	img := tensor.New(types.UINT8, tensor.NewShape(height, width))
+	pix := img.Data().([]uint8)
+	for y := range height {
+		for x := range width {
+			var sum float64
+			for s := range 4 {
+				u := float64(x) + 0.25 + 0.5*float64(s%2)
+				v := float64(y) + 0.25 + 0.5*float64(s/2)
+				ray := [3]float64{(u - width/2) / focal, (v - height/2) / focal, 1}
+				var d [3]float64
+				for i := range 3 {
+					d[i] = float64(pose[i][0])*ray[0] + float64(pose[i][1])*ray[1] + float64(pose[i][2])*ray[2]
+				}
+				best, wall := math.Inf(1), 0
+				for k, w := range walls {
+					if d[w.axis] == 0 {
+						continue
+					}
+					if t := (w.at - origin[w.axis]) / d[w.axis]; t > 0 && t < best {
+						best, wall = t, k
+					}
+				}
+				a, b := (walls[wall].axis+1)%3, (walls[wall].axis+2)%3
+				pa, pb := origin[a]+d[a]*best, origin[b]+d[b]*best
+				sum += texture(wall, pa/cell, pb/cell)
+			}
+			pix[y*width+x] = uint8(sum / 4)
+		}
+	}
+	return img
+}
+
+// path is a camera moving down the corridor, weaving and turning slightly.
+func path(i int) mat.Matrix4x4 {
+	t := float64(i)
+	yaw := 0.05 * math.Sin(t/4)
+	r := mat.Matrix3x3{}.RotationY(float32(yaw)).(mat.Matrix3x3)
+	return homogeneous(r, vec.Vector3D{float32(0.4 * math.Sin(t/5)), float32(0.1 * math.Sin(t/3)), float32(0.25 * t)})
+}
+
+func TestStereoCorridor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("renders 60 frames")
+	}
+	const baseline = 0.3
+	o := NewStereo(camera(), baseline)
+	right := homogeneous(mat.FromDiagonal3x3(1, 1, 1), vec.Vector3D{baseline, 0, 0})
+	var est, gt Trajectory
+	for i := range 30 {
+		pose := path(i)
+		p, ok, err := o.Track(Frame{Image: render(pose), Right: render(compose(pose, right))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("frame %d: lost", i)
+		}
+		est = append(est, p.Transform)
+		gt = append(gt, compose(invert(path(0)), pose))
+		if i > 0 && (p.Covariance[5][5] <= 0 || p.Covariance[5][5] > 0.01) {
+			t.Errorf("frame %d: forward variance %g", i, p.Covariance[5][5])
+		}
+	}
+	m, err := Evaluate(est, gt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%+v", m)
+	if m.ATE > 0.05 || m.RPETranslation > 0.03 || m.RPERotation > 0.005 {
+		t.Errorf("metrics %+v", m)
+	}
+	// unaligned, the drift of the last pose
+	last := translation(est[len(est)-1]).Distance(translation(gt[len(gt)-1]))
+	if last > 0.15 {
+		t.Errorf("final position off by %.3f m", last)
+	}
+}
+
+func TestMonoCorridor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("renders 40 frames")
+	}
+	o := New(camera())
+	var est, gt Trajectory
+	started := -1
+	for i := range 40 {
+		pose := path(i)
+		p, ok, err := o.Track(Frame{Image: render(pose)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case ok && i > 0 && started < 0:
+			started = i
+		case !ok && started > 0:
+			t.Fatalf("frame %d: lost", i)
+		}
+		if ok {
+			est = append(est, p.Transform)
+			gt = append(gt, compose(invert(path(0)), pose))
+		}
+	}
+	if started < 0 || started > 10 {
+		t.Fatalf("initialised at frame %d", started)
+	}
+	m, err := Evaluate(est, gt, WithScale())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("initialised at frame %d: %+v", started, m)
+	if m.ATE > 0.1 || m.RPERotation > 0.005 {
+		t.Errorf("metrics %+v", m)
+	}
+}
+
+func loadGray(t *testing.T, path string) types.Tensor {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := image.NewGray(img.Bounds())
+	draw.Draw(g, g.Rect, img, img.Bounds().Min, draw.Src)
+	out := tensor.New(types.UINT8, tensor.NewShape(g.Rect.Dy(), g.Rect.Dx()))
+	copy(out.Data().([]uint8), g.Pix)
+	return out
+}
+
+// TestKITTI tracks three frames of the KITTI odometry dataset, where the
+// car drives straight ahead at a steady speed; the testdata does not name
+// the sequence, so there is no ground truth to compare with.
+func TestKITTI(t *testing.T) {
+	const dir = "../../marshaller/testdata/"
+	cam := &calib.Camera{Width: 1241, Height: 376, Fx: 718.856, Fy: 718.856, Cx: 607.1928, Cy: 185.2157}
+	o := NewStereo(cam, 0.5372)
+	var pos []vec.Vector3D
+	for i, name := range []string{"000000.png", "000001.png", "000002.png"} {
+		p, ok, err := o.Track(Frame{Image: loadGray(t, dir+"img1/"+name), Right: loadGray(t, dir+"img2/"+name)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("frame %d: lost", i)
+		}
+		if a := angle(rotation(p.Transform)); a > 0.01 {
+			t.Errorf("frame %d: rotation %g rad", i, a)
+		}
+		pos = append(pos, translation(p.Transform))
+	}
+	step := pos[1].Sub(pos[0]).(vec.Vector3D)
+	if step[2] < 0.5 || step[2] > 1.2 || math.Hypot(float64(step[0]), float64(step[1])) > 0.1*float64(step[2]) {
+		t.Errorf("first step %v, want straight ahead", step)
+	}
+	if d := pos[2].Sub(pos[1]).(vec.Vector3D).Distance(step); d > 0.1*step.Magnitude() {
+		t.Errorf("steps %v and %v differ", step, pos[2].Sub(pos[1]))
+	}
+}